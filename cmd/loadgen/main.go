@@ -0,0 +1,306 @@
+// Command loadgen drives checkout traffic against an OSPay instance and reports latency
+// percentiles and error rates per operation, so capacity changes (database, worker counts,
+// settings) can be measured against the same load.
+//
+// Each worker repeats what a checkout does: create an order, poll it like a checkout page, report
+// a payment for it and poll until it is PAID. Payments use random transaction hashes and are only
+// credited for orders the server does not verify on-chain, so the default -asset TEST -chain
+// sandbox keeps the run off the chain RPC; with USDT on BSC the payments fail verification and
+// measure that path instead.
+//
+//	loadgen -url http://localhost:8080 -concurrency 20 -duration 1m
+package main
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/client"
+)
+
+func main() {
+	baseURL := flag.String("url", envOr("OSPAY_URL", "http://localhost:8080"), "server URL")
+	apiKey := flag.String("key", os.Getenv("OSPAY_API_KEY"), "merchant API key (empty creates a merchant for the run)")
+	wallet := flag.String("wallet", "0x9a3f2b1c4d5e6f708192a3b4c5d6e7f8091a2b3c", "payout wallet of the created merchant")
+	concurrency := flag.Int("concurrency", 10, "concurrent checkouts")
+	duration := flag.Duration("duration", 30*time.Second, "how long to generate load")
+	rate := flag.Float64("rate", 0, "orders per second across all workers (0 is as fast as the workers go)")
+	polls := flag.Int("polls", 3, "status polls of each order before it is paid")
+	pollInterval := flag.Duration("poll-interval", 200*time.Millisecond, "pause between polls")
+	payRatio := flag.Float64("pay", 1, "fraction of orders that get paid")
+	paidTimeout := flag.Duration("paid-timeout", 10*time.Second, "how long to poll a paid order for PAID")
+	asset := flag.String("asset", "TEST", "order asset")
+	chain := flag.String("chain", "sandbox", "order chain")
+	amount := flag.String("amount", "1000000", "order amount in minor units")
+	jsonOut := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	// Every call is timed as one attempt: the client's retries would hide errors and latency.
+	opts := []client.Option{client.WithRetries(0, 0), client.WithHTTPClient(&http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency, IdleConnTimeout: time.Minute},
+	})}
+	if *apiKey == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		m, err := client.New(*baseURL, "", opts...).CreateMerchant(ctx, client.CreateMerchantRequest{
+			Name: "loadgen " + time.Now().UTC().Format(time.RFC3339), MerchantWalletAddress: *wallet,
+		})
+		cancel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "create merchant:", err)
+			os.Exit(1)
+		}
+		*apiKey = m.APIKey
+		fmt.Fprintln(os.Stderr, "created merchant", m.ID)
+	}
+
+	r := &run{
+		c:     client.New(*baseURL, *apiKey, opts...),
+		stats: newStats(),
+		order: client.CreateOrderRequest{AmountMinor: *amount, Asset: *asset, Chain: *chain},
+		polls: *polls, pollInterval: *pollInterval, payRatio: *payRatio, paidTimeout: *paidTimeout,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	var ticks <-chan time.Time
+	if *rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+		defer t.Stop()
+		ticks = t.C
+	}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if ticks != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticks:
+					}
+				}
+				r.checkout(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+	rep := r.stats.report(time.Since(start))
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+		return
+	}
+	rep.print()
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// run is the shared state of the workers.
+type run struct {
+	c            *client.Client
+	stats        *stats
+	order        client.CreateOrderRequest
+	polls        int
+	pollInterval time.Duration
+	payRatio     float64
+	paidTimeout  time.Duration
+}
+
+// checkout plays one customer: create, poll, pay, poll until PAID. Calls cut off by the end of
+// the run are not counted.
+func (r *run) checkout(ctx context.Context) {
+	var o *client.CreatedOrder
+	if !r.time(ctx, "create_order", func() (err error) { o, err = r.c.CreateOrder(ctx, r.order); return err }) {
+		return
+	}
+	for i := 0; i < r.polls; i++ {
+		if !sleep(ctx, r.pollInterval) || !r.time(ctx, "get_order", func() error { _, err := r.c.GetOrder(ctx, o.OrderID); return err }) {
+			return
+		}
+	}
+	if rand.Float64() >= r.payRatio {
+		return
+	}
+	paidAt := time.Now()
+	if !r.time(ctx, "report_payment", func() error {
+		_, err := r.c.ReportPayment(ctx, client.PaymentEvent{OrderID: o.OrderID, TxHash: randomTxHash()})
+		return err
+	}) {
+		return
+	}
+	deadline := paidAt.Add(r.paidTimeout)
+	for time.Now().Before(deadline) {
+		var status string
+		if !r.time(ctx, "get_order", func() error {
+			got, err := r.c.GetOrder(ctx, o.OrderID)
+			if err == nil {
+				status = got.Status
+			}
+			return err
+		}) {
+			return
+		}
+		if status == "PAID" || status == "CONFIRMING" {
+			r.stats.add("time_to_paid", time.Since(paidAt), "")
+			return
+		}
+		if status != "PENDING" {
+			r.stats.add("time_to_paid", time.Since(paidAt), status)
+			return
+		}
+		if !sleep(ctx, r.pollInterval) {
+			return
+		}
+	}
+	r.stats.add("time_to_paid", time.Since(paidAt), "timeout")
+}
+
+// time runs one API call and records it under op. It reports whether the checkout should go on.
+func (r *run) time(ctx context.Context, op string, call func() error) bool {
+	start := time.Now()
+	err := call()
+	if ctx.Err() != nil {
+		return false
+	}
+	r.stats.add(op, time.Since(start), errorKind(err))
+	return err == nil
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// errorKind names a failure for the report: the HTTP status and error code, or "network".
+func errorKind(err error) string {
+	var apiErr *client.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &apiErr):
+		return strconv.Itoa(apiErr.StatusCode) + " " + apiErr.Code
+	default:
+		return "network"
+	}
+}
+
+func randomTxHash() string {
+	b := make([]byte, 32)
+	_, _ = crand.Read(b)
+	return "0x" + hex.EncodeToString(b)
+}
+
+// stats collects the latencies and failures of each operation.
+type stats struct {
+	mu     sync.Mutex
+	ops    map[string][]time.Duration
+	errors map[string]map[string]int
+}
+
+func newStats() *stats {
+	return &stats{ops: map[string][]time.Duration{}, errors: map[string]map[string]int{}}
+}
+
+func (s *stats) add(op string, d time.Duration, errKind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops[op] = append(s.ops[op], d)
+	if errKind != "" {
+		if s.errors[op] == nil {
+			s.errors[op] = map[string]int{}
+		}
+		s.errors[op][errKind]++
+	}
+}
+
+// opReport summarises one operation; latencies are in milliseconds.
+type opReport struct {
+	Op        string         `json:"op"`
+	Count     int            `json:"count"`
+	PerSecond float64        `json:"per_second"`
+	Errors    int            `json:"errors"`
+	ErrorRate float64        `json:"error_rate"`
+	P50       float64        `json:"p50_ms"`
+	P90       float64        `json:"p90_ms"`
+	P99       float64        `json:"p99_ms"`
+	Max       float64        `json:"max_ms"`
+	ByError   map[string]int `json:"by_error,omitempty"`
+}
+
+type report struct {
+	Seconds float64    `json:"seconds"`
+	Ops     []opReport `json:"ops"`
+}
+
+func (s *stats) report(elapsed time.Duration) report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rep := report{Seconds: elapsed.Seconds()}
+	for op, ds := range s.ops {
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		or := opReport{Op: op, Count: len(ds), PerSecond: float64(len(ds)) / elapsed.Seconds(), ByError: s.errors[op],
+			P50: percentile(ds, 50), P90: percentile(ds, 90), P99: percentile(ds, 99), Max: ms(ds[len(ds)-1])}
+		for _, n := range or.ByError {
+			or.Errors += n
+		}
+		or.ErrorRate = float64(or.Errors) / float64(or.Count)
+		rep.Ops = append(rep.Ops, or)
+	}
+	sort.Slice(rep.Ops, func(i, j int) bool { return rep.Ops[i].Op < rep.Ops[j].Op })
+	return rep
+}
+
+// percentile is the nearest-rank percentile of sorted durations, in milliseconds.
+func percentile(sorted []time.Duration, p int) float64 {
+	i := (len(sorted)*p + 99) / 100
+	if i < 1 {
+		i = 1
+	}
+	return ms(sorted[i-1])
+}
+
+func ms(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+
+func (rep report) print() {
+	fmt.Printf("%.1fs\n\n%-15s %8s %8s %7s %9s %9s %9s %9s\n", rep.Seconds, "op", "count", "per_s", "errors", "p50_ms", "p90_ms", "p99_ms", "max_ms")
+	for _, o := range rep.Ops {
+		fmt.Printf("%-15s %8d %8.1f %6.2f%% %9.1f %9.1f %9.1f %9.1f\n", o.Op, o.Count, o.PerSecond, 100*o.ErrorRate, o.P50, o.P90, o.P99, o.Max)
+	}
+	fmt.Println()
+	for _, o := range rep.Ops {
+		kinds := make([]string, 0, len(o.ByError))
+		for k := range o.ByError {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		for _, k := range kinds {
+			fmt.Printf("%s: %d× %s\n", o.Op, o.ByError[k], k)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Command ospay is a command-line client for the OSPay API, for merchants and operators. Results
+// are printed as JSON so the commands compose with jq and shell scripts.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/client"
+)
+
+const usage = `usage: ospay [-url URL] [-key API_KEY] [-admin-key KEY] <command> [flags]
+
+commands:
+  merchant create -name NAME -wallet ADDRESS
+  order create -amount MINOR -asset ASSET -chain CHAIN [-idempotency-key K] [-email E] [-customer-wallet W] [-metadata JSON]
+  order get ID
+  order extend ID [-minutes N]
+  order list [-status S] [-limit N] [-cursor C] [-all]
+  refund ORDER_ID [-amount MINOR] [-tx HASH] [-idempotency-key K]
+  webhook test [-event TYPE]
+  webhook replay [-order ID] [-from RFC3339] [-to RFC3339]
+  settle [-merchant ID]        (admin key)
+
+Defaults come from OSPAY_URL (http://localhost:8080), OSPAY_API_KEY and OSPAY_ADMIN_KEY.`
+
+func main() {
+	fs := flag.NewFlagSet("ospay", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	baseURL := fs.String("url", envOr("OSPAY_URL", "http://localhost:8080"), "server URL")
+	apiKey := fs.String("key", os.Getenv("OSPAY_API_KEY"), "merchant API key")
+	adminKey := fs.String("admin-key", os.Getenv("OSPAY_ADMIN_KEY"), "operator key for admin commands")
+	timeout := fs.Duration("timeout", time.Minute, "overall timeout")
+	_ = fs.Parse(os.Args[1:])
+	args := fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	c := client.New(*baseURL, *apiKey, client.WithAdminKey(*adminKey))
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var (
+		out any
+		err error
+	)
+	switch cmd := args[0]; {
+	case cmd == "merchant" && len(args) > 1 && args[1] == "create":
+		out, err = merchantCreate(ctx, c, args[2:])
+	case cmd == "order" && len(args) > 1 && args[1] == "create":
+		out, err = orderCreate(ctx, c, args[2:])
+	case cmd == "order" && len(args) == 3 && args[1] == "get":
+		out, err = c.GetOrder(ctx, args[2])
+	case cmd == "order" && len(args) > 2 && args[1] == "extend":
+		out, err = orderExtend(ctx, c, args[2], args[3:])
+	case cmd == "order" && len(args) > 1 && args[1] == "list":
+		out, err = orderList(ctx, c, args[2:])
+	case cmd == "refund" && len(args) > 1:
+		out, err = refund(ctx, c, args[1], args[2:])
+	case cmd == "webhook" && len(args) > 1 && args[1] == "test":
+		out, err = webhookTest(ctx, c, args[2:])
+	case cmd == "webhook" && len(args) > 1 && args[1] == "replay":
+		out, err = webhookReplay(ctx, c, args[2:])
+	case cmd == "settle":
+		out, err = settle(ctx, c, args[1:])
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(out)
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// parse parses a subcommand's flags and exits with usage when required ones are missing.
+func parse(fs *flag.FlagSet, args []string, required ...*string) {
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	_ = fs.Parse(args)
+	for _, r := range required {
+		if *r == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+	}
+}
+
+func merchantCreate(ctx context.Context, c *client.Client, args []string) (any, error) {
+	fs := flag.NewFlagSet("merchant create", flag.ExitOnError)
+	name := fs.String("name", "", "merchant name")
+	wallet := fs.String("wallet", "", "merchant wallet address")
+	parse(fs, args, name, wallet)
+	return c.CreateMerchant(ctx, client.CreateMerchantRequest{Name: *name, MerchantWalletAddress: *wallet})
+}
+
+func orderCreate(ctx context.Context, c *client.Client, args []string) (any, error) {
+	fs := flag.NewFlagSet("order create", flag.ExitOnError)
+	amount := fs.String("amount", "", "amount in minor units")
+	asset := fs.String("asset", "", "asset, e.g. USDT")
+	chain := fs.String("chain", "", "chain, e.g. bsc")
+	key := fs.String("idempotency-key", "", "idempotency key (random when empty)")
+	email := fs.String("email", "", "customer email")
+	wallet := fs.String("customer-wallet", "", "customer wallet address")
+	metadata := fs.String("metadata", "", "metadata JSON object")
+	parse(fs, args, amount, asset, chain)
+	req := client.CreateOrderRequest{
+		AmountMinor: *amount, Asset: *asset, Chain: *chain, IdempotencyKey: *key,
+		CustomerEmail: *email, CustomerWalletAddress: *wallet,
+	}
+	if *metadata != "" {
+		req.Metadata = json.RawMessage(*metadata)
+	}
+	return c.CreateOrder(ctx, req)
+}
+
+func orderList(ctx context.Context, c *client.Client, args []string) (any, error) {
+	fs := flag.NewFlagSet("order list", flag.ExitOnError)
+	status := fs.String("status", "", "only orders in this status")
+	limit := fs.Int("limit", 0, "page size")
+	cursor := fs.String("cursor", "", "next_cursor of a previous page")
+	all := fs.Bool("all", false, "follow cursors and print every page as one list")
+	parse(fs, args)
+	p := client.ListOrdersParams{Status: *status, Limit: *limit, Cursor: *cursor}
+	if !*all {
+		return c.ListOrders(ctx, p)
+	}
+	orders := []client.Order{}
+	for {
+		page, err := c.ListOrders(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, page.Orders...)
+		if page.NextCursor == "" {
+			return orders, nil
+		}
+		p.Cursor = page.NextCursor
+	}
+}
+
+func refund(ctx context.Context, c *client.Client, orderID string, args []string) (any, error) {
+	fs := flag.NewFlagSet("refund", flag.ExitOnError)
+	amount := fs.String("amount", "", "amount in minor units (default: the remaining balance)")
+	tx := fs.String("tx", "", "on-chain refund transaction hash")
+	key := fs.String("idempotency-key", "", "idempotency key (random when empty)")
+	parse(fs, args)
+	req := client.RefundRequest{RefundTxHash: *tx, IdempotencyKey: *key}
+	if *amount != "" {
+		req.AmountMinor = amount
+	}
+	return c.Refund(ctx, orderID, req)
+}
+
+func orderExtend(ctx context.Context, c *client.Client, id string, args []string) (any, error) {
+	fs := flag.NewFlagSet("order extend", flag.ExitOnError)
+	minutes := fs.Int("minutes", 0, "minutes to add (default 30)")
+	parse(fs, args)
+	return c.ExtendOrder(ctx, id, *minutes)
+}
+
+func webhookTest(ctx context.Context, c *client.Client, args []string) (any, error) {
+	fs := flag.NewFlagSet("webhook test", flag.ExitOnError)
+	event := fs.String("event", "", "event type to sample (default order.paid)")
+	parse(fs, args)
+	return c.TestWebhook(ctx, *event)
+}
+
+func webhookReplay(ctx context.Context, c *client.Client, args []string) (any, error) {
+	fs := flag.NewFlagSet("webhook replay", flag.ExitOnError)
+	order := fs.String("order", "", "replay this order's events")
+	from := fs.String("from", "", "replay events created at or after this time (RFC 3339)")
+	to := fs.String("to", "", "replay events created before this time (RFC 3339)")
+	parse(fs, args)
+	req := client.ReplayRequest{OrderID: *order}
+	var err error
+	if *from != "" {
+		if req.From, err = time.Parse(time.RFC3339, *from); err != nil {
+			return nil, fmt.Errorf("-from: %w", err)
+		}
+	}
+	if *to != "" {
+		if req.To, err = time.Parse(time.RFC3339, *to); err != nil {
+			return nil, fmt.Errorf("-to: %w", err)
+		}
+	}
+	return c.ReplayEvents(ctx, req)
+}
+
+func settle(ctx context.Context, c *client.Client, args []string) (any, error) {
+	fs := flag.NewFlagSet("settle", flag.ExitOnError)
+	merchant := fs.String("merchant", "", "only settle this merchant")
+	parse(fs, args)
+	return c.RunSettlement(ctx, *merchant)
+}
@@ -0,0 +1,90 @@
+// Command sdkgen generates the typed TypeScript and Python clients in sdk/ from the OpenAPI
+// document swag writes (docs/swagger.json), the route table of cmd/server, which gives the /v1
+// paths the document still lists under their legacy names, and the request and response structs
+// of pkg/api, which tell which fields a response always carries. Run it from the repository root
+// after regenerating the docs:
+//
+//	go run ./cmd/sdkgen
+//
+// Only the files it generates are written; the transport and webhook signature helpers of each
+// package are maintained by hand next to them. -check writes nothing and fails if a generated file
+// is out of date, for CI.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func main() {
+	specFile := flag.String("spec", "docs/swagger.json", "OpenAPI document")
+	routesFile := flag.String("routes", "cmd/server/routes.go", "source of the route table")
+	apiDir := flag.String("api", "pkg/api", "package declaring the request and response structs")
+	out := flag.String("out", "sdk", "directory of the SDK packages")
+	check := flag.Bool("check", false, "only report generated files that are out of date")
+	flag.Parse()
+
+	sp, err := loadSpec(*specFile)
+	if err != nil {
+		fatal(err)
+	}
+	routes, err := loadRoutes(*routesFile)
+	if err != nil {
+		fatal(err)
+	}
+	structs, err := loadStructs(*apiDir)
+	if err != nil {
+		fatal(err)
+	}
+	resolveFields(sp, structs, requestDefs(sp))
+	ops, warnings := buildOperations(sp, routes)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "sdkgen:", w)
+	}
+
+	files := map[string][]byte{}
+	for name, content := range typescriptFiles(sp, ops) {
+		files[filepath.Join(*out, "typescript", name)] = content
+	}
+	for name, content := range pythonFiles(sp, ops) {
+		files[filepath.Join(*out, "python", name)] = content
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stale := 0
+	for _, name := range names {
+		if *check {
+			if cur, err := os.ReadFile(name); err != nil || !bytes.Equal(cur, files[name]) {
+				fmt.Fprintln(os.Stderr, "sdkgen: out of date:", name)
+				stale++
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(name, files[name], 0o644); err != nil {
+			fatal(err)
+		}
+	}
+	if stale > 0 {
+		fmt.Fprintln(os.Stderr, "sdkgen: run go run ./cmd/sdkgen")
+		os.Exit(1)
+	}
+	if !*check {
+		fmt.Printf("sdkgen: %d operations, %d types, %d files\n", len(ops), len(sp.Definitions), len(names))
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "sdkgen:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pythonFiles renders the generated files of the Python package, by path within it.
+func pythonFiles(sp *spec, ops []operation) map[string][]byte {
+	return map[string][]byte{
+		"ospay/models.py": pyModels(sp),
+		"ospay/client.py": pyClient(ops),
+	}
+}
+
+// Models are evaluated when the module loads, so references between them are quoted and may
+// point forward.
+func pyModels(sp *spec) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", generatedNotice)
+	b.WriteString(`"""Request and response bodies of the OSPay API.
+
+Bodies are plain dicts decoded from JSON; these TypedDicts describe them for type checkers.
+"""
+
+from typing import Any, Dict, List, Literal, NotRequired, Optional, TypedDict
+`)
+	for _, ref := range sortedDefs(sp) {
+		def := sp.Definitions[ref]
+		name := defName(ref)
+		b.WriteString("\n\n")
+		switch {
+		case len(def.fields) > 0 && pyClassFields(def.fields):
+			fmt.Fprintf(&b, "class %s(TypedDict):\n", name)
+			if def.Description != "" {
+				pyDocstring(&b, "    ", def.Description)
+			}
+			for _, f := range def.fields {
+				for _, line := range wrap(strings.Join(strings.Fields(describe(f.schema)), " "), 94) {
+					fmt.Fprintf(&b, "    # %s\n", line)
+				}
+				fmt.Fprintf(&b, "    %s: %s\n", f.name, pyFieldType(f))
+			}
+		case len(def.fields) > 0:
+			// A field is named with a Python keyword, which only the functional syntax allows.
+			fmt.Fprintf(&b, "%s = TypedDict(\n    %q,\n    {\n", name, name)
+			for _, f := range def.fields {
+				fmt.Fprintf(&b, "        %q: %s,\n", f.name, pyFieldType(f))
+			}
+			b.WriteString("    },\n)\n")
+		case len(def.Enum) > 0:
+			fmt.Fprintf(&b, "%s = Literal[\n", name)
+			for _, v := range def.Enum {
+				lit, _ := json.Marshal(v)
+				fmt.Fprintf(&b, "    %s,\n", lit)
+			}
+			b.WriteString("]\n")
+		default:
+			fmt.Fprintf(&b, "%s = %s\n", name, pyType(def, ""))
+		}
+	}
+	return b.Bytes()
+}
+
+func pyFieldType(f field) string {
+	typ := pyType(f.schema, "")
+	if f.nullable {
+		typ = "Optional[" + typ + "]"
+	}
+	if f.optional {
+		typ = "NotRequired[" + typ + "]"
+	}
+	return typ
+}
+
+func pyClassFields(fields []field) bool {
+	for _, f := range fields {
+		if !pyIdent.MatchString(f.name) || pyKeywords[f.name] {
+			return false
+		}
+	}
+	return true
+}
+
+// pyType is the Python type of s. Names of definitions are qualified with ns, or quoted if ns
+// is empty.
+func pyType(s *schema, ns string) string {
+	switch {
+	case s == nil:
+		return "Any"
+	case s.Ref != "":
+		if ns == "" {
+			return `"` + refName(s.Ref) + `"`
+		}
+		return ns + refName(s.Ref)
+	case len(s.AllOf) > 0:
+		return pyType(s.AllOf[0], ns)
+	case len(s.Enum) > 0:
+		lits := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			b, _ := json.Marshal(v)
+			lits[i] = string(b)
+		}
+		return "Literal[" + strings.Join(lits, ", ") + "]"
+	}
+	switch s.Type {
+	case "string":
+		return "str"
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "List[" + pyType(s.Items, ns) + "]"
+	case "object":
+		if v, ok := s.valueSchema(); ok && v != nil {
+			return "Dict[str, " + pyType(v, ns) + "]"
+		}
+		return "Dict[str, Any]"
+	}
+	return "Any"
+}
+
+func pyClient(ops []operation) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", generatedNotice)
+	b.WriteString(`"""The OSPay API client, with a method per /v1 endpoint."""
+
+from __future__ import annotations
+
+from typing import Any, Dict, List, Literal, Optional
+from urllib.parse import quote
+
+from . import models as m
+from ._http import Transport
+
+
+class Client(Transport):
+    """A typed client for the OSPay API.
+
+    The constructor takes the server URL and the credentials; see Transport for retries and
+    timeouts::
+
+        client = Client("https://pay.example.com", api_key=os.environ["OSPAY_API_KEY"])
+        order = client.create_order({"amount_minor": "1000000", "asset": "USDT", "chain": "BSC"})
+    """
+`)
+	for _, op := range ops {
+		var (
+			params, kwargs, call []string
+			query, form          []string
+		)
+		path := op.path
+		for _, p := range op.pathParams {
+			name := pyName(p)
+			params = append(params, name+": str")
+			path = strings.ReplaceAll(path, "{"+p+"}", "{quote("+name+", safe='')}")
+		}
+		if len(op.pathParams) > 0 {
+			path = "f\"" + path + "\""
+		} else {
+			path = "\"" + path + "\""
+		}
+		switch {
+		case op.body != nil && op.bodyOpt:
+			params = append(params, "body: Optional["+pyType(op.body, "m.")+"] = None")
+			call = append(call, "body=body")
+		case op.body != nil:
+			params = append(params, "body: "+pyType(op.body, "m."))
+			call = append(call, "body=body")
+		}
+		for _, p := range op.form {
+			name := pyName(p.Name)
+			kwargs = append(kwargs, pyParam(p, name))
+			form = append(form, fmt.Sprintf("%q: %s", p.Name, name))
+		}
+		for _, p := range op.query {
+			key := p.Name
+			if prefix, ok := strings.CutSuffix(p.Name, ".key"); ok {
+				key = prefix
+				kwargs = append(kwargs, pyName(prefix)+": Optional[Dict[str, str]] = None")
+			} else {
+				kwargs = append(kwargs, pyParam(p, pyName(p.Name)))
+			}
+			query = append(query, fmt.Sprintf("%q: %s", key, pyName(key)))
+		}
+		if op.method == "POST" {
+			kwargs = append(kwargs, "idempotency_key: Optional[str] = None")
+			call = append(call, "idempotency_key=idempotency_key")
+		}
+		if op.keyField != "" {
+			call = append(call, fmt.Sprintf("idempotency_field=%q", op.keyField))
+		}
+		// Required keyword arguments go first, for readability
+		sort.SliceStable(kwargs, func(i, j int) bool {
+			return !strings.Contains(kwargs[i], " = ") && strings.Contains(kwargs[j], " = ")
+		})
+		if len(form) > 0 {
+			call = append([]string{pyDict("form", form)}, call...)
+		}
+		if len(query) > 0 {
+			call = append([]string{pyDict("query", query)}, call...)
+		}
+		all := append([]string{"self"}, params...)
+		if len(kwargs) > 0 {
+			all = append(append(all, "*"), kwargs...)
+		}
+		result := "None"
+		if op.result != nil {
+			result = pyType(op.result, "m.")
+		}
+
+		b.WriteString("\n")
+		name := pySnake(op.words)
+		sig := fmt.Sprintf("    def %s(%s) -> %s:\n", name, strings.Join(all, ", "), result)
+		if len(sig) > 101 {
+			sig = fmt.Sprintf("    def %s(\n        %s,\n    ) -> %s:\n", name, strings.Join(all, ",\n        "), result)
+		}
+		b.WriteString(sig)
+		pyDocstring(&b, "        ", op.summary+"\n\n"+op.doc)
+		ret := fmt.Sprintf("        return self._request(%q, %s", op.method, path)
+		if len(call) > 0 {
+			ret += ", " + strings.Join(call, ", ")
+		}
+		ret += ")\n"
+		if len(ret) > 101 || strings.Contains(ret[:len(ret)-1], "\n") {
+			ret = fmt.Sprintf("        return self._request(\n            %q,\n            %s,\n", op.method, path)
+			for _, c := range call {
+				ret += "            " + c + ",\n"
+			}
+			ret += "        )\n"
+		}
+		b.WriteString(ret)
+	}
+	return b.Bytes()
+}
+
+// pyDict renders a keyword argument taking a dict, one entry per line if it is long.
+func pyDict(name string, entries []string) string {
+	if line := name + "={" + strings.Join(entries, ", ") + "}"; len(line) <= 86 {
+		return line
+	}
+	return name + "={\n                " + strings.Join(entries, ",\n                ") + ",\n            }"
+}
+
+func pyParam(p parameter, name string) string {
+	typ := pyType(p.schema(), "m.")
+	if p.Required {
+		return name + ": " + typ
+	}
+	return name + ": Optional[" + typ + "] = None"
+}
+
+// pyDocstring writes text as a docstring, wrapped, paragraphs kept.
+func pyDocstring(b *bytes.Buffer, indent, text string) {
+	paras := paragraphs(text)
+	if len(paras) == 0 {
+		return
+	}
+	esc := strings.NewReplacer(`\`, `\\`, `"""`, `\"\"\"`)
+	if len(paras) == 1 && len(indent)+len(paras[0])+6 <= 100 {
+		fmt.Fprintf(b, "%s\"\"\"%s\"\"\"\n", indent, esc.Replace(paras[0]))
+		return
+	}
+	for i, p := range paras {
+		lines := wrap(esc.Replace(p), 100-len(indent))
+		if i == 0 {
+			lines = wrap(esc.Replace(p), 100-len(indent)-3)
+			lines[0] = `"""` + lines[0]
+		} else {
+			b.WriteString("\n")
+		}
+		for _, line := range lines {
+			fmt.Fprintf(b, "%s%s\n", indent, line)
+		}
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
+
+var pyIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var pyKeywords = map[string]bool{}
+
+func init() {
+	for _, kw := range strings.Fields(`False None True and as assert async await break class continue
+		def del elif else except finally for from global if import in is lambda nonlocal not or pass
+		raise return try while with yield`) {
+		pyKeywords[kw] = true
+	}
+}
+
+// pyName turns a wire name into a Python parameter name; keywords get a trailing underscore.
+func pyName(s string) string {
+	s = strings.NewReplacer(".", "_", "-", "_").Replace(s)
+	if pyKeywords[s] || s == "self" || s == "body" {
+		return s + "_"
+	}
+	return s
+}
+
+// pySnake joins words into a method name: create_order, list_api_keys, oauth_token.
+func pySnake(words []string) string {
+	out := make([]string, len(words))
+	for i, w := range words {
+		out[i] = strings.ToLower(w)
+	}
+	return strings.Join(out, "_")
+}
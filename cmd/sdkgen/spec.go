@@ -0,0 +1,513 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// spec is the part of the OpenAPI 2.0 document swag writes that the clients are generated from.
+type spec struct {
+	Info struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths       map[string]map[string]*specOp `json:"paths"`
+	Definitions map[string]*schema            `json:"definitions"`
+}
+
+type specOp struct {
+	Summary     string      `json:"summary"`
+	Description string      `json:"description"`
+	Parameters  []parameter `json:"parameters"`
+	Produces    []string    `json:"produces"`
+	Responses   map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Type        string  `json:"type"`
+	Enum        []any   `json:"enum"`
+	Items       *schema `json:"items"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+func (p parameter) schema() *schema {
+	if p.Schema != nil {
+		return p.Schema
+	}
+	return &schema{Type: p.Type, Enum: p.Enum, Items: p.Items}
+}
+
+type schema struct {
+	Ref         string             `json:"$ref"`
+	Type        string             `json:"type"`
+	Description string             `json:"description"`
+	Enum        []any              `json:"enum"`
+	Items       *schema            `json:"items"`
+	Properties  map[string]*schema `json:"properties"`
+	Required    []string           `json:"required"`
+	AllOf       []*schema          `json:"allOf"`
+	// AdditionalProperties is a schema, or true for any value
+	AdditionalProperties json.RawMessage `json:"additionalProperties"`
+
+	fields []field // the properties in declaration order, set for definitions
+}
+
+// valueSchema returns the schema of the values of a map, and whether s is one.
+func (s *schema) valueSchema() (*schema, bool) {
+	if len(s.AdditionalProperties) == 0 {
+		return nil, false
+	}
+	var v schema
+	if json.Unmarshal(s.AdditionalProperties, &v) != nil {
+		return nil, true
+	}
+	return &v, true
+}
+
+// field is one property of a definition as the clients declare it.
+type field struct {
+	name     string
+	schema   *schema
+	optional bool // may be left out
+	nullable bool // may be null
+}
+
+// defName is the type name a definition ("api.orderCreateReq") gets in the clients.
+func defName(ref string) string {
+	name := ref[strings.LastIndex(ref, ".")+1:]
+	return string(unicode.ToUpper(rune(name[0]))) + name[1:]
+}
+
+func refName(ref string) string { return defName(strings.TrimPrefix(ref, "#/definitions/")) }
+
+// route is an entry of the server's route table.
+type route struct {
+	method, path string // of the /v1 pattern
+	legacy       string
+	handler      string // the api handler it ends in, e.g. "CreateOrderHandler"
+}
+
+// loadRoutes reads the route table from the source of cmd/server.
+func loadRoutes(file string) ([]route, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var routes []route
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != "routes" || len(spec.Values) != 1 {
+			return true
+		}
+		table, ok := spec.Values[0].(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, elt := range table.Elts {
+			entry, ok := elt.(*ast.CompositeLit)
+			if !ok || len(entry.Elts) != 3 {
+				continue
+			}
+			pattern, legacy := stringLit(entry.Elts[0]), stringLit(entry.Elts[1])
+			method, path, _ := strings.Cut(pattern, " ")
+			rt := route{method: method, path: path, legacy: legacy}
+			ast.Inspect(entry.Elts[2], func(n ast.Node) bool {
+				if sel, ok := n.(*ast.SelectorExpr); ok && strings.HasSuffix(sel.Sel.Name, "Handler") {
+					rt.handler = sel.Sel.Name
+				}
+				return true
+			})
+			routes = append(routes, rt)
+		}
+		return false
+	})
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("%s: no route table", file)
+	}
+	return routes, nil
+}
+
+func stringLit(e ast.Expr) string {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	s, _ := strconv.Unquote(lit.Value)
+	return s
+}
+
+// goField is what the Go declaration of a struct field tells beyond the OpenAPI document.
+type goField struct {
+	name      string
+	omitempty bool
+	pointer   bool
+}
+
+// loadStructs reads the JSON fields of the struct types declared in the Go package dir, in
+// declaration order, with embedded structs of the package flattened.
+func loadStructs(dir string) (map[string][]goField, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	decls := map[string]*ast.StructType{}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if ts, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					decls[ts.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+	var fieldsOf func(st *ast.StructType, depth int) []goField
+	fieldsOf = func(st *ast.StructType, depth int) []goField {
+		var out []goField
+		for _, f := range st.Fields.List {
+			tag := ""
+			if f.Tag != nil {
+				tag, _ = strconv.Unquote(f.Tag.Value)
+			}
+			name, opts, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if len(f.Names) == 0 {
+				if id, ok := f.Type.(*ast.Ident); ok && name == "" && decls[id.Name] != nil && depth < 5 {
+					out = append(out, fieldsOf(decls[id.Name], depth+1)...)
+				}
+				continue
+			}
+			_, pointer := f.Type.(*ast.StarExpr)
+			for _, n := range f.Names {
+				if !n.IsExported() {
+					continue
+				}
+				jsonName := name
+				if jsonName == "" {
+					jsonName = n.Name
+				}
+				out = append(out, goField{name: jsonName, omitempty: strings.Contains(opts, "omitempty"), pointer: pointer})
+			}
+		}
+		return out
+	}
+	structs := map[string][]goField{}
+	for name, st := range decls {
+		structs[name] = fieldsOf(st, 0)
+	}
+	return structs, nil
+}
+
+// resolveFields sets the fields of every definition. Definitions sent in request bodies only
+// require the fields their validation requires; in responses every field is there unless it is
+// omitempty, and pointers may be null.
+func resolveFields(sp *spec, structs map[string][]goField, requests map[string]bool) {
+	for ref, def := range sp.Definitions {
+		if len(def.Properties) == 0 {
+			continue
+		}
+		required := map[string]bool{}
+		for _, name := range def.Required {
+			required[name] = true
+		}
+		seen := map[string]bool{}
+		add := func(name string, g *goField) {
+			prop, ok := def.Properties[name]
+			if !ok || seen[name] {
+				return
+			}
+			seen[name] = true
+			f := field{name: name, schema: prop, optional: !required[name]}
+			if g != nil && !requests[ref] {
+				f.optional, f.nullable = g.omitempty, g.pointer && !g.omitempty
+			}
+			def.fields = append(def.fields, f)
+		}
+		for _, g := range structs[ref[strings.LastIndex(ref, ".")+1:]] {
+			add(g.name, &g)
+		}
+		rest := make([]string, 0, len(def.Properties))
+		for name := range def.Properties {
+			rest = append(rest, name)
+		}
+		sort.Strings(rest)
+		for _, name := range rest {
+			add(name, nil)
+		}
+	}
+}
+
+// requestDefs returns the definitions that request bodies refer to, directly or through others.
+func requestDefs(sp *spec) map[string]bool {
+	out := map[string]bool{}
+	var walk func(s *schema)
+	walk = func(s *schema) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			ref := strings.TrimPrefix(s.Ref, "#/definitions/")
+			if out[ref] {
+				return
+			}
+			out[ref] = true
+			walk(sp.Definitions[ref])
+			return
+		}
+		walk(s.Items)
+		for _, p := range s.Properties {
+			walk(p)
+		}
+		for _, a := range s.AllOf {
+			walk(a)
+		}
+		if v, _ := s.valueSchema(); v != nil {
+			walk(v)
+		}
+	}
+	for _, methods := range sp.Paths {
+		for method, op := range methods {
+			if method == "get" {
+				continue
+			}
+			for _, p := range op.Parameters {
+				if p.In == "body" {
+					walk(p.Schema)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// operation is one client method.
+type operation struct {
+	words      []string // of the name, e.g. ["Create", "Order"], spelled per language
+	handler    string
+	method     string
+	path       string // with {param} placeholders
+	pathParams []string
+	query      []parameter
+	body       *schema
+	bodyOpt    bool
+	keyField   string      // body field taking the idempotency key, filled in when left empty
+	form       []parameter // form-encoded body fields
+	result     *schema     // nil if the response has no body
+	summary    string
+	doc        string
+}
+
+// buildOperations joins the route table with the OpenAPI operations; documented paths outside
+// the table, such as /metrics, are not part of the clients. Operations are named after
+// their handler, prefixed with Admin on the admin routes; where a handler serves several routes
+// its methods are told apart by a verb (List, Get, Create, Update).
+func buildOperations(sp *spec, routes []route) ([]operation, []string) {
+	var (
+		ops      []operation
+		warnings []string
+	)
+	for _, rt := range routes {
+		method := strings.ToLower(rt.method)
+		var sop *specOp
+		for _, p := range []string{rt.legacy, rt.path, strings.TrimPrefix(rt.path, "/v1")} {
+			if sop = sp.Paths[p][method]; p != "" && sop != nil {
+				break
+			}
+		}
+		if sop == nil || rt.handler == "" {
+			warnings = append(warnings, fmt.Sprintf("%s %s is not documented; skipped", rt.method, rt.path))
+			continue
+		}
+		if len(sop.Produces) > 0 && !contains(sop.Produces, "application/json") {
+			// Streams such as the NDJSON ledger export are read with plain HTTP
+			continue
+		}
+		op := operation{
+			handler: rt.handler,
+			method:  rt.method,
+			path:    rt.path,
+			summary: sop.Summary,
+			doc:     firstParagraph(sop.Description),
+		}
+		for _, seg := range strings.Split(rt.path, "/") {
+			if strings.HasPrefix(seg, "{") {
+				op.pathParams = append(op.pathParams, strings.Trim(seg, "{}"))
+			}
+		}
+		seen := map[string]bool{}
+		for _, p := range sop.Parameters {
+			if seen[p.In+" "+p.Name] {
+				continue
+			}
+			seen[p.In+" "+p.Name] = true
+			switch {
+			case p.In == "query" && !contains(op.pathParams, p.Name):
+				op.query = append(op.query, p)
+			case p.In == "body" && rt.method != "GET":
+				op.body, op.bodyOpt = p.Schema, !p.Required
+			case p.In == "formData":
+				op.form = append(op.form, p)
+			}
+		}
+		if op.body != nil && op.body.Ref != "" {
+			for name := range sp.Definitions[strings.TrimPrefix(op.body.Ref, "#/definitions/")].Properties {
+				if name == "idempotency_key" || strings.HasSuffix(name, "_idempotency_key") {
+					op.keyField = name
+				}
+			}
+		}
+		codes := []string{"200"}
+		if rt.method != "GET" {
+			codes = []string{"201", "202", "200"}
+		}
+		for _, code := range codes {
+			if r, ok := sop.Responses[code]; ok {
+				op.result = r.Schema
+				break
+			}
+		}
+		base := splitWords(strings.TrimSuffix(rt.handler, "Handler"))
+		if strings.HasPrefix(rt.path, "/v1/admin/") && base[0] != "Admin" {
+			base = append([]string{"Admin"}, base...)
+		}
+		op.words = base
+		ops = append(ops, op)
+	}
+
+	// Handlers serving more than one method, or the same method on more than one path, need a verb.
+	byHandler := map[string][]int{}
+	for i, op := range ops {
+		byHandler[op.handler] = append(byHandler[op.handler], i)
+	}
+	for _, idx := range byHandler {
+		methods, names := map[string]bool{}, map[string]int{}
+		for _, i := range idx {
+			methods[ops[i].method] = true
+			names[strings.Join(ops[i].words, "")]++
+		}
+		needVerb := len(methods) > 1
+		for _, n := range names {
+			needVerb = needVerb || n > 1
+		}
+		if !needVerb {
+			continue
+		}
+		listing := false
+		for _, i := range idx {
+			listing = listing || (ops[i].method == "GET" && isArray(ops[i].result))
+		}
+		for _, i := range idx {
+			op := &ops[i]
+			verb := "Create"
+			switch {
+			case op.method == "GET" && isArray(op.result):
+				verb = "List"
+			case op.method == "GET":
+				verb = "Get"
+			case !listing:
+				verb = "Update"
+			}
+			if op.words[0] == "Admin" {
+				op.words = append([]string{"Admin", verb}, op.words[1:]...)
+			} else {
+				op.words = append([]string{verb}, op.words...)
+			}
+		}
+	}
+	names := map[string]string{}
+	for _, op := range ops {
+		name := strings.Join(op.words, "")
+		if prev, ok := names[name]; ok {
+			warnings = append(warnings, fmt.Sprintf("%s %s and %s are both named %s", op.method, op.path, prev, name))
+		}
+		names[name] = op.method + " " + op.path
+	}
+
+	sort.Strings(warnings)
+	return ops, warnings
+}
+
+func isArray(s *schema) bool { return s != nil && s.Type == "array" }
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// firstParagraph returns the first of the paragraphs swag joins when a description is repeated.
+func firstParagraph(s string) string {
+	first, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(first)
+}
+
+// splitWords splits a Go identifier into words, keeping initialisms whole: "APIKeysHandler" is
+// API, Keys, Handler, and OAuth stays one word.
+func splitWords(s string) []string {
+	var words []string
+	rs := []rune(s)
+	start := 0
+	for i := 1; i <= len(rs); i++ {
+		if i < len(rs) && string(rs[start:i]) == "O" && string(rs[i:min(i+4, len(rs))]) == "Auth" {
+			continue
+		}
+		end := i == len(rs)
+		if !end {
+			prev, cur := rs[i-1], rs[i]
+			next := rune(0)
+			if i+1 < len(rs) {
+				next = rs[i+1]
+			}
+			lowerToUpper := unicode.IsLower(prev) && unicode.IsUpper(cur)
+			initialismEnd := unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(next) && !(next == 's' && (i+2 >= len(rs) || unicode.IsUpper(rs[i+2])))
+			end = lowerToUpper || initialismEnd
+		}
+		if end {
+			words = append(words, string(rs[start:i]))
+			start = i
+		}
+	}
+	return words
+}
+
+// loadSpec reads the OpenAPI document.
+func loadSpec(file string) (*spec, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var sp spec
+	if err := json.Unmarshal(b, &sp); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &sp, nil
+}
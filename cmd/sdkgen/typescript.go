@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const generatedNotice = "Code generated by sdkgen from docs/swagger.json. DO NOT EDIT."
+
+// typescriptFiles renders the generated files of the TypeScript package, by path within it.
+func typescriptFiles(sp *spec, ops []operation) map[string][]byte {
+	return map[string][]byte{
+		"src/types.ts":  tsTypes(sp),
+		"src/client.ts": tsClient(ops),
+	}
+}
+
+func tsTypes(sp *spec) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s\n", generatedNotice)
+	for _, ref := range sortedDefs(sp) {
+		def := sp.Definitions[ref]
+		b.WriteString("\n")
+		tsDoc(&b, "", def.Description)
+		name := defName(ref)
+		switch {
+		case len(def.fields) > 0:
+			fmt.Fprintf(&b, "export interface %s {\n", name)
+			for _, f := range def.fields {
+				tsDoc(&b, "  ", describe(f.schema))
+				opt, typ := "", tsType(f.schema, "")
+				if f.optional {
+					opt = "?"
+				}
+				if f.nullable {
+					typ += " | null"
+				}
+				fmt.Fprintf(&b, "  %s%s: %s;\n", tsKey(f.name), opt, typ)
+			}
+			b.WriteString("}\n")
+		case len(def.Enum) > 8:
+			fmt.Fprintf(&b, "export type %s =\n", name)
+			for i, v := range def.Enum {
+				lit, _ := json.Marshal(v)
+				end := ""
+				if i == len(def.Enum)-1 {
+					end = ";"
+				}
+				fmt.Fprintf(&b, "  | %s%s\n", lit, end)
+			}
+		default:
+			fmt.Fprintf(&b, "export type %s = %s;\n", name, tsType(def, ""))
+		}
+	}
+	return b.Bytes()
+}
+
+// tsType is the TypeScript type of s; ns qualifies the names of definitions.
+func tsType(s *schema, ns string) string {
+	switch {
+	case s == nil:
+		return "unknown"
+	case s.Ref != "":
+		return ns + refName(s.Ref)
+	case len(s.AllOf) > 0:
+		return tsType(s.AllOf[0], ns)
+	case len(s.Enum) > 0:
+		lits := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			b, _ := json.Marshal(v)
+			lits[i] = string(b)
+		}
+		return strings.Join(lits, " | ")
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		elem := tsType(s.Items, ns)
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case "object":
+		if v, ok := s.valueSchema(); ok {
+			return "Record<string, " + tsType(v, ns) + ">"
+		}
+		if len(s.Properties) > 0 {
+			names := make([]string, 0, len(s.Properties))
+			for name := range s.Properties {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			props := make([]string, len(names))
+			for i, name := range names {
+				props[i] = tsKey(name) + "?: " + tsType(s.Properties[name], ns)
+			}
+			return "{ " + strings.Join(props, "; ") + " }"
+		}
+		return "Record<string, unknown>"
+	}
+	return "unknown"
+}
+
+var jsIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func tsKey(name string) string {
+	if jsIdent.MatchString(name) {
+		return name
+	}
+	b, _ := json.Marshal(name)
+	return string(b)
+}
+
+func tsClient(ops []operation) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s\n\n", generatedNotice)
+	b.WriteString(`import { Transport, type ClientOptions, type RequestOptions } from "./http.js";
+import type * as t from "./types.js";
+
+/**
+ * A typed client for the OSPay API. Every method maps to one /v1 endpoint; see ClientOptions for
+ * credentials, retries and timeouts.
+ */
+export class OSPayClient {
+  private readonly http: Transport;
+
+  constructor(options: ClientOptions) {
+    this.http = new Transport(options);
+  }
+`)
+	for _, op := range ops {
+		b.WriteString("\n")
+		tsDoc(&b, "  ", op.summary+"\n\n"+op.doc)
+		var params, call []string
+		path := op.path
+		for _, p := range op.pathParams {
+			name := lowerCamel(splitWords(snakeToPascal(p)))
+			params = append(params, name+": string")
+			path = strings.ReplaceAll(path, "{"+p+"}", "${encodeURIComponent("+name+")}")
+		}
+		switch {
+		case len(op.form) > 0:
+			params = append(params, "form: "+tsParamsType(op.form))
+			call = append(call, "form")
+		case op.body != nil && op.bodyOpt:
+			params = append(params, "body?: "+tsType(op.body, "t."))
+			call = append(call, "body")
+		case op.body != nil:
+			params = append(params, "body: "+tsType(op.body, "t."))
+			call = append(call, "body")
+		}
+		if op.keyField != "" {
+			call = append(call, fmt.Sprintf("idempotencyField: %q", op.keyField))
+		}
+		if len(op.query) > 0 {
+			q := "query: " + tsParamsType(op.query)
+			if !anyRequired(op.query) {
+				q += " = {}"
+			}
+			params = append(params, q)
+			call = append(call, "query")
+		}
+		params = append(params, "options?: RequestOptions")
+		call = append(call, "...options")
+		result := "void"
+		if op.result != nil {
+			result = tsType(op.result, "t.")
+		}
+		pathExpr := `"` + path + `"`
+		if len(op.pathParams) > 0 {
+			pathExpr = "`" + path + "`"
+		}
+		sig := fmt.Sprintf("  %s(%s): Promise<%s> {\n", lowerCamel(op.words), strings.Join(params, ", "), result)
+		if len(sig) > 101 || strings.Contains(sig[:len(sig)-1], "\n") {
+			sig = fmt.Sprintf("  %s(\n    %s,\n  ): Promise<%s> {\n", lowerCamel(op.words), strings.Join(params, ",\n    "), result)
+		}
+		b.WriteString(sig)
+		ret := fmt.Sprintf("    return this.http.request(%q, %s, { %s });\n", op.method, pathExpr, strings.Join(call, ", "))
+		if len(ret) > 101 {
+			ret = fmt.Sprintf("    return this.http.request(%q, %s, {\n      %s,\n    });\n", op.method, pathExpr, strings.Join(call, ",\n      "))
+		}
+		b.WriteString(ret + "  }\n")
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// tsParamsType is an object type with a property per parameter. A parameter named "prefix.key"
+// stands for any number of them (metadata.plan=pro, ...) and becomes a record.
+func tsParamsType(params []parameter) string {
+	props := make([]string, len(params))
+	for i, p := range params {
+		opt := "?"
+		if p.Required {
+			opt = ""
+		}
+		if prefix, ok := strings.CutSuffix(p.Name, ".key"); ok {
+			props[i] = tsKey(prefix) + "?: Record<string, string>"
+			continue
+		}
+		props[i] = tsKey(p.Name) + opt + ": " + tsType(p.schema(), "t.")
+	}
+	if oneLine := "{ " + strings.Join(props, "; ") + " }"; len(oneLine) <= 80 {
+		return oneLine
+	}
+	return "{\n      " + strings.Join(props, ";\n      ") + ";\n    }"
+}
+
+func anyRequired(params []parameter) bool {
+	for _, p := range params {
+		if p.Required {
+			return true
+		}
+	}
+	return false
+}
+
+// tsDoc writes text as a JSDoc comment, wrapped, paragraphs kept.
+func tsDoc(b *bytes.Buffer, indent, text string) {
+	paras := paragraphs(text)
+	if len(paras) == 0 {
+		return
+	}
+	if len(paras) == 1 && len(indent)+len(paras[0])+7 <= 100 {
+		fmt.Fprintf(b, "%s/** %s */\n", indent, strings.ReplaceAll(paras[0], "*/", "*\\/"))
+		return
+	}
+	fmt.Fprintf(b, "%s/**\n", indent)
+	for i, p := range paras {
+		if i > 0 {
+			fmt.Fprintf(b, "%s *\n", indent)
+		}
+		for _, line := range wrap(strings.ReplaceAll(p, "*/", "*\\/"), 100-len(indent)-3) {
+			fmt.Fprintf(b, "%s * %s\n", indent, line)
+		}
+	}
+	fmt.Fprintf(b, "%s */\n", indent)
+}
+
+// describe returns the description of a property, which swag puts beside the allOf of a reference.
+func describe(s *schema) string {
+	if s == nil {
+		return ""
+	}
+	return s.Description
+}
+
+func paragraphs(text string) []string {
+	var out []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.Join(strings.Fields(p), " "); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// wrap breaks text into lines of at most width columns, between words.
+func wrap(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func sortedDefs(sp *spec) []string {
+	refs := make([]string, 0, len(sp.Definitions))
+	for ref := range sp.Definitions {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return defName(refs[i]) < defName(refs[j]) })
+	return refs
+}
+
+// lowerCamel joins words into a method name: createOrder, listAPIKeys, oauthToken.
+func lowerCamel(words []string) string {
+	return strings.ToLower(words[0]) + strings.Join(words[1:], "")
+}
+
+func snakeToPascal(s string) string {
+	parts := strings.Split(s, "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
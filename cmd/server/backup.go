@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/backup"
+	"github.com/oxzoid/OSPay/pkg/datamigrate"
+	"github.com/oxzoid/OSPay/pkg/db"
+)
+
+const usage = `usage:
+  server backup <dest>     snapshot the live database (safe while the server runs)
+  server verify <file>     integrity-check a snapshot
+  server restore <file>    replace the database with a verified snapshot (server stopped)
+  server migrate-data [-dry-run] [-from ospay.db] -to-driver <driver> -to <dsn>
+                           copy the SQLite data into another backend, e.g. Postgres`
+
+// runCommand handles the operator subcommands. It reports false when args name no subcommand, so
+// main starts the server.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	var err error
+	switch args[0] {
+	case "backup", "verify", "restore":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		err = runBackupCommand(ctx, args[0], args[1])
+	case "migrate-data":
+		err = runMigrateData(ctx, args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	return true
+}
+
+func runBackupCommand(ctx context.Context, cmd, path string) error {
+	var err error
+	switch cmd {
+	case "backup":
+		var database *db.DB
+		if database, err = db.Open(dsn); err == nil {
+			err = backup.Snapshot(ctx, database, path)
+			database.Close()
+		}
+	case "verify":
+		err = backup.Verify(ctx, path)
+	case "restore":
+		err = backup.Restore(ctx, path, dbFile)
+	}
+	if err == nil {
+		fmt.Printf("%s ok: %s\n", cmd, path)
+	}
+	return err
+}
+
+// runMigrateData copies the SQLite database into the target backend. The target driver has to be
+// linked into the binary (e.g. a blank import of github.com/jackc/pgx/v5/stdlib for "pgx") and its
+// schema created beforehand.
+func runMigrateData(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate-data", flag.ExitOnError)
+	from := fs.String("from", dbFile, "source SQLite file")
+	toDriver := fs.String("to-driver", "pgx", "database/sql driver for the target")
+	to := fs.String("to", "", "target DSN")
+	dryRun := fs.Bool("dry-run", false, "check source and target and report without writing")
+	_ = fs.Parse(args)
+	if *to == "" {
+		return fmt.Errorf("-to is required")
+	}
+	if !slices.Contains(sql.Drivers(), *toDriver) {
+		return fmt.Errorf("driver %q is not built into this binary (available: %v)", *toDriver, sql.Drivers())
+	}
+	if err := backup.Verify(ctx, *from); err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	src, err := db.Open("file:" + *from + "?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := sql.Open(*toDriver, *to)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	placeholder := "?"
+	if *toDriver == "pgx" || *toDriver == "postgres" {
+		placeholder = "$"
+	}
+	rep, err := datamigrate.Copy(ctx, src, dst, datamigrate.Options{DryRun: *dryRun, Placeholder: placeholder})
+	out, _ := json.MarshalIndent(rep, "", "  ")
+	fmt.Println(string(out))
+	return err
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipMinSize is the smallest response worth compressing; below it the gzip framing outweighs the
+// savings.
+const gzipMinSize = 1024
+
+// compressibleTypes are the media types gzipMiddleware compresses: the API's JSON and exports.
+var compressibleTypes = map[string]bool{
+	"application/json":         true,
+	"application/problem+json": true,
+	"application/x-ndjson":     true,
+	"text/csv":                 true,
+}
+
+var gzipWriters = sync.Pool{New: func() any { w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression); return w }}
+
+// gzipMiddleware compresses JSON and CSV responses of at least gzipMinSize bytes for clients that
+// accept gzip. Streaming responses stay streaming: Flush flushes the compressed stream.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip (a q of 0 refuses it).
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") && strings.TrimSpace(coding) != "*" {
+			continue
+		}
+		name, value, _ := strings.Cut(strings.TrimSpace(params), "=")
+		if strings.TrimSpace(name) != "q" {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err != nil || q > 0
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the start of a response until it knows whether to compress it:
+// once gzipMinSize bytes are written, on Flush, or when the handler returns.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	// Informational responses pass straight through.
+	if status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		w.status = 0
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < gzipMinSize {
+			return len(p), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide sends the headers, compressing when the response is large enough, has a compressible
+// type and is not already encoded, then writes what was held back.
+func (w *gzipResponseWriter) decide() error {
+	w.decided = true
+	h := w.Header()
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	if len(w.buf) >= gzipMinSize && compressibleTypes[mediaType] && h.Get("Content-Encoding") == "" &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends whatever is held back, so streamed exports reach the client as they are written.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response after the handler returned.
+func (w *gzipResponseWriter) Close() {
+	if !w.decided && w.status != 0 {
+		_ = w.decide()
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -11,23 +11,349 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata" // merchant timezones must resolve on hosts without zoneinfo
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/oxzoid/OSPay/pkg/api"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/convert"
 	"github.com/oxzoid/OSPay/pkg/db"
+	"github.com/oxzoid/OSPay/pkg/offramp"
+	"github.com/oxzoid/OSPay/pkg/rates"
+	"github.com/oxzoid/OSPay/pkg/risk"
+	"github.com/oxzoid/OSPay/pkg/secrets"
+	"github.com/oxzoid/OSPay/pkg/store"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	_ "github.com/oxzoid/OSPay/docs"
 )
 
+// newRiskScreener builds payer screening from RISK_DENYLIST (comma-separated addresses),
+// RISK_DENYLIST_FILE (one address per line) and CHAINALYSIS_API_KEY. RISK_ACTION=flag credits
+// flagged payments and only records the reason; the default holds them in REVIEW.
+func newRiskScreener() *risk.Screener {
+	var addrs []string
+	if v := os.Getenv("RISK_DENYLIST"); v != "" {
+		addrs = append(addrs, strings.Split(v, ",")...)
+	}
+	if path := os.Getenv("RISK_DENYLIST_FILE"); path != "" {
+		fileAddrs, err := risk.LoadDenylistFile(path)
+		if err != nil {
+			log.Fatalf("risk denylist: %v", err)
+		}
+		addrs = append(addrs, fileAddrs...)
+	}
+	var providers []risk.Provider
+	if len(addrs) > 0 {
+		providers = append(providers, risk.NewDenylist(addrs))
+	}
+	if key := os.Getenv("CHAINALYSIS_API_KEY"); key != "" {
+		providers = append(providers, &risk.Chainalysis{APIKey: key})
+	}
+	if len(providers) == 0 {
+		return nil
+	}
+	return &risk.Screener{Providers: providers, Hold: os.Getenv("RISK_ACTION") != "flag"}
+}
+
+// newRiskScorer applies RISK_REVIEW_SCORE (0 records scores without holding payments),
+// RISK_AMOUNT_PERCENTILE, RISK_VELOCITY_PER_HOUR and RISK_CHAIN_WEIGHTS (e.g. "ETH:20,TRON:10")
+// on top of the default rules.
+func newRiskScorer() *risk.Scorer {
+	s := risk.DefaultScorer()
+	if v := os.Getenv("RISK_REVIEW_SCORE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("RISK_REVIEW_SCORE: %v", err)
+		}
+		s.ReviewThreshold = n
+	}
+	if v := os.Getenv("RISK_AMOUNT_PERCENTILE"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Fatalf("RISK_AMOUNT_PERCENTILE: %v", err)
+		}
+		s.AmountPercentile = f
+	}
+	if v := os.Getenv("RISK_VELOCITY_PER_HOUR"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("RISK_VELOCITY_PER_HOUR: %v", err)
+		}
+		s.VelocityLimit = n
+	}
+	if v := os.Getenv("RISK_CHAIN_WEIGHTS"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			chain, weight, ok := strings.Cut(pair, ":")
+			n, err := strconv.Atoi(weight)
+			if !ok || err != nil {
+				log.Fatalf("RISK_CHAIN_WEIGHTS: invalid entry %q", pair)
+			}
+			s.ChainWeights[strings.ToUpper(strings.TrimSpace(chain))] = n
+		}
+	}
+	return s
+}
+
+// newTxSigner loads the hot wallet key from HOT_WALLET_PRIVATE_KEY (or HOT_WALLET_PRIVATE_KEY_FILE).
+// Without it the server does not send transactions.
+func newTxSigner(ctx context.Context, p secrets.Provider) blockchain.Signer {
+	key, err := p.Get(ctx, "HOT_WALLET_PRIVATE_KEY")
+	if err != nil {
+		log.Fatalf("hot wallet key: %v", err)
+	}
+	if key == "" {
+		return nil
+	}
+	s, err := blockchain.NewKeySigner(key)
+	if err != nil {
+		log.Fatalf("HOT_WALLET_PRIVATE_KEY: %v", err)
+	}
+	return s
+}
+
+// configureFeeProfiles applies TX_FEE_PROFILES, urgency profiles as
+// "name:tip percent:base fee multiplier:bump after" such as "fast:200:3:1m,slow:70:2:30m".
+func configureFeeProfiles() {
+	v := os.Getenv("TX_FEE_PROFILES")
+	if v == "" {
+		return
+	}
+	for _, entry := range strings.Split(v, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 4 {
+			log.Fatalf("TX_FEE_PROFILES: invalid entry %q", entry)
+		}
+		tip, err1 := strconv.ParseInt(parts[1], 10, 64)
+		mult, err2 := strconv.ParseInt(parts[2], 10, 64)
+		after, err3 := time.ParseDuration(parts[3])
+		if err1 != nil || err2 != nil || err3 != nil || tip <= 0 || mult < 1 {
+			log.Fatalf("TX_FEE_PROFILES: invalid entry %q", entry)
+		}
+		blockchain.SetFeeProfile(blockchain.Urgency(strings.ToLower(parts[0])), blockchain.FeeProfile{TipPercent: tip, BaseFeeMultiplier: mult, BumpAfter: after})
+	}
+}
+
+// newRateProvider builds the RATE_PROVIDER rate source. For chainlink, CHAINLINK_FEEDS adds or
+// replaces feeds as "BASE/QUOTE:chain:address:max age" such as
+// "EUR/USD:ETH:0xb49f677943BC038e9857d61E7d053CaA2C1734C1:25h".
+func newRateProvider() rates.Provider {
+	switch os.Getenv("RATE_PROVIDER") {
+	case "":
+		return nil
+	case "chainlink":
+	default:
+		log.Fatalf("RATE_PROVIDER: unknown provider %q", os.Getenv("RATE_PROVIDER"))
+	}
+	feeds := rates.DefaultChainlinkFeeds()
+	if v := os.Getenv("CHAINLINK_FEEDS"); v != "" {
+		for _, entry := range strings.Split(v, ",") {
+			parts := strings.Split(strings.TrimSpace(entry), ":")
+			if len(parts) != 4 || !strings.Contains(parts[0], "/") || !common.IsHexAddress(parts[2]) {
+				log.Fatalf("CHAINLINK_FEEDS: invalid entry %q", entry)
+			}
+			maxAge, err := time.ParseDuration(parts[3])
+			if err != nil {
+				log.Fatalf("CHAINLINK_FEEDS: invalid entry %q", entry)
+			}
+			feeds[strings.ToUpper(parts[0])] = rates.Feed{Chain: strings.ToUpper(parts[1]), Address: common.HexToAddress(parts[2]), MaxAge: maxAge}
+		}
+	}
+	return &rates.Chainlink{Feeds: feeds}
+}
+
+// envGwei reads an optional amount in gwei as wei; unset means nil.
+func envGwei(name string) *big.Int {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		log.Fatalf("%s: invalid amount %q", name, v)
+	}
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1_000_000_000))
+}
+
+// gasTankLowWater parses GAS_TANK_LOW_WATER, low-water marks in the native coin per chain such as
+// "BSC:0.05,ETH:0.02", into wei.
+func gasTankLowWater() map[string]*big.Int {
+	marks := map[string]*big.Int{}
+	v := os.Getenv("GAS_TANK_LOW_WATER")
+	if v == "" {
+		return marks
+	}
+	for _, pair := range strings.Split(v, ",") {
+		chain, amount, ok := strings.Cut(pair, ":")
+		whole, frac, _ := strings.Cut(strings.TrimSpace(amount), ".")
+		wei, okw := new(big.Int).SetString(whole+frac+strings.Repeat("0", 18-min(len(frac), 18)), 10)
+		if !ok || !okw || len(frac) > 18 || wei.Sign() < 0 {
+			log.Fatalf("GAS_TANK_LOW_WATER: invalid entry %q", pair)
+		}
+		marks[strings.ToUpper(strings.TrimSpace(chain))] = wei
+	}
+	return marks
+}
+
+// coldSweepLimits parses COLD_SWEEP_LIMITS, hot wallet limits in the token's smallest unit per
+// chain and token such as "BSC:USDT:50000000000:10000000000": above 50000 USDT the excess over
+// 10000 is swept. Leaving out the balance to keep sweeps down to the limit.
+func coldSweepLimits() []api.SweepLimit {
+	var limits []api.SweepLimit
+	v := os.Getenv("COLD_SWEEP_LIMITS")
+	if v == "" {
+		return limits
+	}
+	for _, entry := range strings.Split(v, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) == 3 {
+			parts = append(parts, parts[2])
+		}
+		if len(parts) != 4 {
+			log.Fatalf("COLD_SWEEP_LIMITS: invalid entry %q", entry)
+		}
+		chain, asset := strings.ToUpper(parts[0]), strings.ToUpper(parts[1])
+		limit, okl := new(big.Int).SetString(parts[2], 10)
+		keep, okk := new(big.Int).SetString(parts[3], 10)
+		if !okl || !okk || keep.Sign() < 0 || keep.Cmp(limit) > 0 {
+			log.Fatalf("COLD_SWEEP_LIMITS: invalid entry %q: want CHAIN:TOKEN:LIMIT[:KEEP] with 0 <= KEEP <= LIMIT", entry)
+		}
+		if !blockchain.KnownToken(chain, asset) {
+			log.Fatalf("COLD_SWEEP_LIMITS: invalid entry %q: no %s contract on %s; native balances pay gas and are not swept", entry, asset, chain)
+		}
+		limits = append(limits, api.SweepLimit{Chain: chain, Asset: asset, Limit: limit, Keep: keep})
+	}
+	return limits
+}
+
+// reconcileWallets reads the custody wallets to reconcile from RECONCILE_WALLETS, e.g.
+// "0xabc...,BSC:0xdef...": an address alone is used on every chain. Unset means the hot wallet.
+func reconcileWallets(hotWallet string) map[string][]string {
+	wallets := map[string][]string{}
+	v := os.Getenv("RECONCILE_WALLETS")
+	if v == "" {
+		if hotWallet != "" {
+			wallets[""] = []string{hotWallet}
+		}
+		return wallets
+	}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		chain, addr, ok := strings.Cut(entry, ":")
+		if !ok {
+			chain, addr = "", entry
+		}
+		norm, err := blockchain.NormalizeAddress("ETH", strings.TrimSpace(addr))
+		if err != nil {
+			log.Fatalf("RECONCILE_WALLETS: invalid entry %q: %v", entry, err)
+		}
+		chain = strings.ToUpper(strings.TrimSpace(chain))
+		wallets[chain] = append(wallets[chain], norm)
+	}
+	return wallets
+}
+
+// newKeyring loads the field-encryption keys from FIELD_ENCRYPTION_KEYS (or FIELD_ENCRYPTION_KEYS_FILE)
+// as "id:base64key,..." with the current key first. Without keys sensitive fields are stored in
+// plaintext.
+func newKeyring(ctx context.Context, p secrets.Provider) *secrets.Keyring {
+	spec, err := p.Get(ctx, "FIELD_ENCRYPTION_KEYS")
+	if err != nil {
+		log.Fatalf("field encryption keys: %v", err)
+	}
+	if spec == "" {
+		log.Printf("FIELD_ENCRYPTION_KEYS not set; customer emails are stored unencrypted")
+		return nil
+	}
+	k, err := secrets.ParseKeyring(spec)
+	if err != nil {
+		log.Fatalf("FIELD_ENCRYPTION_KEYS: %v", err)
+	}
+	return k
+}
+
+// envInt reads an optional integer setting; unset means 0.
+func envInt(name string) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return n
+}
+
+// envDuration reads an optional duration setting such as "24h"; unset means def.
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return d
+}
+
+// configureSchedules reads <NAME>_SCHEDULE (e.g. SETTLEMENT_SCHEDULE="0 2 * * *") for every
+// scheduler, replacing its default interval.
+func configureSchedules() {
+	for _, name := range api.SchedulerNames() {
+		env := strings.ToUpper(name) + "_SCHEDULE"
+		if v := os.Getenv(env); v != "" {
+			if err := api.SetSchedule(name, v); err != nil {
+				log.Fatalf("%s: %v", env, err)
+			}
+		}
+	}
+}
+
+// configureRetryPolicies reads RETRY_POLICY_<TYPE> (e.g. RETRY_POLICY_WEBHOOK="max_attempts=8,
+// dead_letter=discard") for every kind of retried work, replacing its default policy.
+func configureRetryPolicies() {
+	for _, typ := range api.RetryPolicyTypes() {
+		env := "RETRY_POLICY_" + strings.ToUpper(typ)
+		if v := os.Getenv(env); v != "" {
+			if err := api.SetRetryPolicy(typ, v); err != nil {
+				log.Fatalf("%s: %v", env, err)
+			}
+		}
+	}
+}
+
+// configureDBTimeouts reads DB_TIMEOUT_<CLASS> (e.g. DB_TIMEOUT_REPORT=2m) for every operation
+// class, replacing its default query timeout.
+func configureDBTimeouts() {
+	for _, op := range store.Ops() {
+		env := "DB_TIMEOUT_" + strings.ToUpper(string(op))
+		if v := os.Getenv(env); v != "" {
+			if err := store.SetTimeout(op, envDuration(env, 0)); err != nil {
+				log.Fatalf("%s: %v", env, err)
+			}
+		}
+	}
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, Authorization, If-None-Match, Idempotency-Key, X-Merchant-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -38,8 +364,49 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// newHTTPServer configures the listener from HTTP_* settings. Keep-alive connections idle for
+// HTTP_IDLE_TIMEOUT (HTTP_KEEP_ALIVE=off closes each after its response); HTTP_WRITE_TIMEOUT is
+// unset by default so long exports are not cut off. HTTP/2 is negotiated over TLS
+// (TLS_CERT_FILE/TLS_KEY_FILE) and, with HTTP2_CLEARTEXT=on, spoken in cleartext (h2c) to a proxy.
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: envDuration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+		ReadTimeout:       envDuration("HTTP_READ_TIMEOUT", time.Minute),
+		WriteTimeout:      envDuration("HTTP_WRITE_TIMEOUT", 0),
+		IdleTimeout:       envDuration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+		MaxHeaderBytes:    64 << 10,
+	}
+	srv.SetKeepAlivesEnabled(os.Getenv("HTTP_KEEP_ALIVE") != "off")
+	srv.Protocols = new(http.Protocols)
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetHTTP2(true)
+	srv.Protocols.SetUnencryptedHTTP2(os.Getenv("HTTP2_CLEARTEXT") == "on")
+	srv.HTTP2 = &http.HTTP2Config{
+		MaxConcurrentStreams: envInt("HTTP2_MAX_CONCURRENT_STREAMS"),
+		PingTimeout:          envDuration("HTTP2_PING_TIMEOUT", 0),
+		SendPingTimeout:      envDuration("HTTP2_PING_IDLE", 0),
+	}
+	return srv
+}
+
+const (
+	dbFile = "ospay.db"
+	dsn    = "file:" + dbFile + "?_pragma=busy_timeout=5000"
+)
+
 func main() {
-	dsn := "file:ospay.db?_pragma=busy_timeout=5000"
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
+	busyRetries := 3
+	if os.Getenv("DB_BUSY_RETRIES") != "" {
+		busyRetries = envInt("DB_BUSY_RETRIES")
+	}
+	store.SetBusyRetry(busyRetries, envDuration("DB_BUSY_BACKOFF", 50*time.Millisecond))
+	configureDBTimeouts()
 	database, err := db.Open(dsn)
 	if err != nil {
 		log.Fatalf("DB open failed: %v", err)
@@ -56,12 +423,134 @@ func main() {
 		log.Fatalf("migrations failed: %v", err)
 	}
 
+	// Re-encrypt fields still in plaintext or under a retired key, so rotating is: put the new key
+	// first, restart, then drop the old key.
+	if kr := newKeyring(ctx, secrets.Env{}); kr != nil {
+		secrets.SetKeyring(kr)
+		n, err := db.ReencryptFields(context.Background(), database)
+		if err != nil {
+			log.Fatalf("re-encrypting fields: %v", err)
+		}
+		if n > 0 {
+			log.Printf("re-encrypted %d fields with key %s", n, kr.CurrentKeyID())
+		}
+	}
+
+	for chain, env := range map[string]string{"BSC": "BSC_RPC_URL", "ETH": "ETH_RPC_URL", "POLYGON": "POLYGON_RPC_URL"} {
+		if url := os.Getenv(env); url != "" {
+			blockchain.SetRPCURL(chain, url)
+		}
+		if url := os.Getenv("SAFE_TX_SERVICE_URL_" + chain); url != "" {
+			blockchain.SetSafeServiceURL(chain, url)
+		}
+		if addr := os.Getenv("MULTISEND_CONTRACT_" + chain); addr != "" {
+			blockchain.SetDisperseContract(chain, common.HexToAddress(addr))
+		}
+		if v := os.Getenv("FINALITY_DEPTH_" + chain); v != "" {
+			depth, err := strconv.Atoi(v)
+			if err != nil || depth < 0 {
+				log.Fatalf("invalid FINALITY_DEPTH_%s %q", chain, v)
+			}
+			blockchain.SetFinalityDepth(chain, depth)
+		}
+		if d := envDuration("BLOCK_TIME_"+chain, 0); d > 0 {
+			blockchain.SetBlockTime(chain, d)
+		}
+	}
+	blockchain.SetSafeAPIKey(os.Getenv("SAFE_API_KEY"))
+
 	api.Init(database)
+	if replicaDSN := os.Getenv("READ_REPLICA_DSN"); replicaDSN != "" {
+		replica, err := db.OpenReplica(replicaDSN)
+		if err != nil {
+			log.Fatalf("read replica open failed: %v", err)
+		}
+		defer replica.Close()
+		api.SetReadReplica(replica)
+	}
+	api.SetAdminKey(os.Getenv("ADMIN_API_KEY"))
+	api.SetRiskScreener(newRiskScreener())
+	api.SetRiskScorer(newRiskScorer())
 
+	configureSchedules()
+	configureRetryPolicies()
 	api.StartSettlementScheduler(database, 5*time.Minute, 10*time.Minute)
 
-	api.StartOrderTimeoutScheduler(database, 30*time.Minute, 5*time.Minute)
+	api.StartOrderTimeoutScheduler(database, api.OrderTTL, time.Minute)
+	api.SetLatePaymentGrace(envDuration("LATE_PAYMENT_GRACE", 24*time.Hour))
+
+	hotWallet := os.Getenv("HOT_WALLET_ADDRESS")
+	if signer := newTxSigner(ctx, secrets.Env{}); signer != nil {
+		api.SetTxSigner(signer)
+		if hotWallet == "" {
+			hotWallet = signer.Address().Hex()
+		}
+	}
+	configureFeeProfiles()
+	api.SetTxFeePolicy(blockchain.Urgency(os.Getenv("TX_URGENCY")), envGwei("TX_MAX_FEE_GWEI"))
+	api.StartTxMonitor(envDuration("TX_MONITOR_INTERVAL", 30*time.Second))
+	api.StartConfirmationTracker(database, envDuration("CONFIRMATION_CHECK_INTERVAL", 15*time.Second))
+	if os.Getenv("CONVERSION_PROVIDER") == "lifi" {
+		api.SetConverter(&convert.LiFi{APIKey: os.Getenv("LIFI_API_KEY"), BaseURL: os.Getenv("LIFI_API_URL")})
+	}
+	if u := os.Getenv("OFFRAMP_API_URL"); u != "" {
+		api.SetOfframpProvider(&offramp.Partner{BaseURL: u, APIKey: os.Getenv("OFFRAMP_API_KEY")})
+	}
+	api.SetRateProvider(newRateProvider())
+	api.SetPayoutMultiSend(os.Getenv("PAYOUT_MULTISEND") != "off")
+	api.SetWalletProofRequired(os.Getenv("WALLET_PROOF_REQUIRED") != "off")
+	api.StartPayoutDispatcher(envDuration("PAYOUT_DISPATCH_INTERVAL", time.Minute))
+	api.StartRefundJobRunner(envDuration("REFUND_JOB_INTERVAL", 5*time.Second))
 
+	api.SetGasTank(hotWallet, gasTankLowWater(), os.Getenv("GAS_TANK_ALERT_URL"))
+	api.StartGasTankMonitor(envDuration("GAS_TANK_CHECK_INTERVAL", 10*time.Minute))
+	reconTolerance := 10
+	if os.Getenv("RECONCILE_TOLERANCE_BPS") != "" {
+		reconTolerance = envInt("RECONCILE_TOLERANCE_BPS")
+	}
+	api.SetOnchainReconciliation(reconcileWallets(hotWallet), int64(reconTolerance), os.Getenv("RECONCILE_ALERT_URL"))
+	api.StartOnchainReconciler(envDuration("RECONCILE_INTERVAL", time.Hour))
+	api.StartReserveAttestations(envDuration("ATTESTATION_INTERVAL", 24*time.Hour))
+	if cold := os.Getenv("COLD_WALLET_ADDRESS"); cold != "" {
+		norm, err := blockchain.NormalizeAddress("ETH", cold)
+		if err != nil {
+			log.Fatalf("COLD_WALLET_ADDRESS: %v", err)
+		}
+		api.SetColdSweep(norm, coldSweepLimits(), os.Getenv("COLD_SWEEP_APPROVAL") == "on", os.Getenv("COLD_SWEEP_ALERT_URL"))
+	}
+	api.StartColdSweeper(envDuration("COLD_SWEEP_INTERVAL", 15*time.Minute))
+	api.StartAddressWatcher(envDuration("ADDRESS_WATCH_INTERVAL", 30*time.Second))
+	api.SetENSAlert(os.Getenv("ENS_ALERT_URL"))
+	api.SetMerchantApproval(os.Getenv("MERCHANT_APPROVAL_REQUIRED") == "on", os.Getenv("MERCHANT_APPROVAL_ALERT_URL"))
+	api.StartENSRefresher(envDuration("ENS_REFRESH_INTERVAL", time.Hour))
+	api.SetNegativeBalanceAlert(os.Getenv("NEGATIVE_BALANCE_ALERT_URL"))
+	api.StartNegativeBalanceTracker(envDuration("NEGATIVE_BALANCE_CHECK_INTERVAL", time.Minute))
+	api.StartBalanceHoldExpirer(envDuration("BALANCE_HOLD_EXPIRY_INTERVAL", time.Minute))
+
+	api.StartIdempotencyPruner(database, time.Hour)
+	api.StartJobsPruner(time.Hour)
+	api.SetWebhookPrivateNetworks(os.Getenv("WEBHOOK_PRIVATE_NETWORKS") == "on")
+	api.SetDeadLetterAlert(envDuration("WEBHOOK_DEAD_LETTER_ALERT_AFTER", time.Hour), os.Getenv("WEBHOOK_DEAD_LETTER_ALERT_URL"))
+	api.StartWebhookDispatcher(database, 5*time.Second)
+	api.SetOrderCacheTTL(envDuration("ORDER_CACHE_TTL", 0))
+	if n := envInt("MAX_BODY_BYTES"); n > 0 {
+		api.SetMaxBodyBytes(int64(n))
+	}
+	api.SetTrustForwardedFor(os.Getenv("TRUST_FORWARDED_FOR") == "on")
+	authBanAfter := 20
+	if os.Getenv("AUTH_BAN_AFTER") != "" {
+		authBanAfter = envInt("AUTH_BAN_AFTER")
+	}
+	api.SetAuthGuard(authBanAfter, envDuration("AUTH_BAN_DURATION", 15*time.Minute), os.Getenv("AUTH_ALERT_URL"))
+	api.StartCounterFlusher(10 * time.Second)
+
+	api.SetBackupDir(os.Getenv("BACKUP_DIR"))
+	api.SetRetentionPolicy(envInt("RETENTION_MONTHS"), envInt("OUTBOX_RETENTION_DAYS"), os.Getenv("OUTBOX_ARCHIVE") == "on")
+	api.StartRetentionScheduler(database, 6*time.Hour)
+
+	if os.Getenv("VERIFY_QUEUE_MAX") != "" {
+		api.SetVerifyQueueLimit(int64(envInt("VERIFY_QUEUE_MAX")))
+	}
 	api.StartVerificationWorkers(4)
 	addr := ":8080"
 	fmt.Println("Server running on", addr)
@@ -85,17 +574,47 @@ func main() {
 		w.Write([]byte(`{"ok":true}`))
 	})
 
+	mux.HandleFunc("/health/chains", api.ChainHealthHandler)
+
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
 
-	mux.HandleFunc("/orders", api.APIKeyAuthMiddleware(api.CreateOrderHandler))
-	mux.HandleFunc("/orders/get", api.APIKeyAuthMiddleware(api.GetOrderHandler))
-	mux.HandleFunc("/orders/refund", api.APIKeyAuthMiddleware(api.RefundHandler))
-	mux.HandleFunc("/reconciliation", api.APIKeyAuthMiddleware(api.ReconciliationHandler))
-	mux.HandleFunc("/events/payment-detected", api.APIKeyAuthMiddleware(api.PaymentDetectedHandler))
 	mux.HandleFunc("/debug/metrics", api.DebugMetricsHandler)
-	mux.HandleFunc("/merchants", api.CreateMerchantHandler)
+	mux.HandleFunc("/metrics", api.PrometheusMetricsHandler)
+	registerRoutes(mux)
+
+	var handler http.Handler = mux
+	if os.Getenv("HTTP_COMPRESSION") != "off" {
+		handler = gzipMiddleware(handler)
+	}
+	handler = corsMiddleware(handler)
 
-	handler := corsMiddleware(mux)
+	srv := newHTTPServer(addr, handler)
+	go func() {
+		var err error
+		if cert, key := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"); cert != "" || key != "" {
+			err = srv.ListenAndServeTLS(cert, key)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
 
-	log.Fatal(http.ListenAndServe(addr, handler))
+	// On SIGINT or SIGTERM, finish the requests and background jobs in flight and persist the counters
+	// before exiting.
+	stop, release := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer release()
+	<-stop.Done()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	if err := api.StopJobs(shutdownCtx); err != nil {
+		log.Printf("stop jobs: %v", err)
+	}
+	if err := api.FlushCounters(); err != nil {
+		log.Printf("flush counters: %v", err)
+	}
 }
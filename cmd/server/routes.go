@@ -0,0 +1,239 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/oxzoid/OSPay/pkg/api"
+)
+
+// route is one API endpoint: its /v1 pattern (method and path parameters, see http.ServeMux) and
+// the unversioned path it was served on before /v1, kept as a deprecated alias. Legacy paths take
+// path parameters as ?id= instead.
+type route struct {
+	pattern string
+	legacy  string
+	handler http.HandlerFunc
+}
+
+// merchant authenticates a merchant credential and requires scope.
+func merchant(scope string, h http.HandlerFunc) http.HandlerFunc {
+	return api.APIKeyAuthMiddleware(api.RequireScope(scope, h))
+}
+
+var routes = []route{
+	{"POST /v1/orders", "/orders", merchant(api.ScopeOrdersWrite, api.CreateOrderHandler)},
+	{"GET /v1/orders", "/orders/list", merchant(api.ScopeOrdersRead, api.ListOrdersHandler)},
+	{"GET /v1/orders/search", "/orders/search", merchant(api.ScopeOrdersRead, api.SearchOrdersHandler)},
+	{"POST /v1/orders/batch-get", "/orders/batch-get", merchant(api.ScopeOrdersRead, api.BatchGetOrdersHandler)},
+	{"GET /v1/orders/{id}", "/orders/get", merchant(api.ScopeOrdersRead, api.GetOrderHandler)},
+	{"POST /v1/orders/{id}/extend", "/orders/extend", merchant(api.ScopeOrdersWrite, api.ExtendOrderHandler)},
+	{"POST /v1/orders/{id}/refunds", "/orders/refund", merchant(api.ScopeRefundsWrite, api.RefundHandler)},
+	{"GET /v1/orders/{id}/refunds", "/orders/refunds", merchant(api.ScopeOrdersRead, api.ListRefundsHandler)},
+	{"POST /v1/orders/{id}/notes", "/orders/notes", merchant(api.ScopeOrdersWrite, api.OrderNotesHandler)},
+	{"GET /v1/orders/{id}/notes", "/orders/notes", merchant(api.ScopeOrdersRead, api.OrderNotesHandler)},
+	{"POST /v1/orders/{id}/tags", "/orders/tags", merchant(api.ScopeOrdersWrite, api.OrderTagsHandler)},
+	{"GET /v1/orders/{id}/tags", "/orders/tags", merchant(api.ScopeOrdersRead, api.OrderTagsHandler)},
+	{"POST /v1/orders/{id}/tags/remove", "/orders/tags/remove", merchant(api.ScopeOrdersWrite, api.RemoveOrderTagsHandler)},
+	{"GET /v1/orders/{id}/timeline", "/orders/timeline", merchant(api.ScopeOrdersRead, api.OrderTimelineHandler)},
+	{"POST /v1/orders/{id}/delete", "/orders/delete", merchant(api.ScopeOrdersWrite, api.DeleteOrderHandler)},
+	{"POST /v1/refunds/{id}/approve", "/refunds/approve", merchant(api.ScopeRefundsApprove, api.ApproveRefundHandler)},
+	{"POST /v1/refunds/{id}/reject", "/refunds/reject", merchant(api.ScopeRefundsApprove, api.RejectRefundHandler)},
+	{"POST /v1/refunds/bulk", "/refunds/bulk", merchant(api.ScopeRefundsWrite, api.BulkRefundHandler)},
+	{"GET /v1/refunds/bulk/{id}", "/refunds/bulk/get", merchant(api.ScopeOrdersRead, api.GetBulkRefundHandler)},
+	{"GET /v1/overpayments", "/overpayments", merchant(api.ScopeOrdersRead, api.ListOverpaymentsHandler)},
+	{"POST /v1/overpayments/{id}/refund", "/overpayments/refund", merchant(api.ScopeRefundsWrite, api.RefundOverpaymentHandler)},
+	{"GET /v1/reconciliation", "/reconciliation", merchant(api.ScopeBalancesRead, api.ReconciliationHandler)},
+	{"GET /v1/ledger/export.ndjson", "/ledger/export.ndjson", merchant(api.ScopeBalancesRead, api.LedgerExportHandler)},
+	{"GET /v1/ledger/transactions", "/ledger/transactions", merchant(api.ScopeBalancesRead, api.ListLedgerTransactionsHandler)},
+	{"GET /v1/ledger/transactions/{id}", "/ledger/transactions/get", merchant(api.ScopeBalancesRead, api.GetLedgerTransactionHandler)},
+	{"GET /v1/settlements", "/settlements", merchant(api.ScopeBalancesRead, api.ListSettlementsHandler)},
+	{"GET /v1/negative-balances", "/negative-balances", merchant(api.ScopeBalancesRead, api.ListNegativeBalancesHandler)},
+	{"GET /v1/holds", "/holds", merchant(api.ScopeBalancesRead, api.BalanceHoldsHandler)},
+	{"GET /v1/attestations", "/attestations", merchant(api.ScopeBalancesRead, api.ListAttestationsHandler)},
+	{"GET /v1/attestations/{id}", "/attestations/get", merchant(api.ScopeBalancesRead, api.GetAttestationHandler)},
+	{"GET /v1/payouts", "/payouts", merchant(api.ScopeBalancesRead, api.ListPayoutsHandler)},
+	{"GET /v1/conversions", "/conversions", merchant(api.ScopeBalancesRead, api.ListConversionsHandler)},
+	{"GET /v1/offramp/kyc", "/offramp/kyc", merchant(api.ScopeBalancesRead, api.OfframpKYCHandler)},
+	{"GET /v1/offramp/payouts", "/offramp/payouts", merchant(api.ScopeBalancesRead, api.FiatPayoutsHandler)},
+	{"POST /v1/offramp/payouts", "/offramp/payouts", api.APIKeyAuthMiddleware(api.FiatPayoutsHandler)},
+	{"GET /v1/stats/timeseries", "/stats/timeseries", merchant(api.ScopeOrdersRead, api.TimeseriesHandler)},
+	{"GET /v1/rates", "/rates", merchant(api.ScopeOrdersRead, api.RateHandler)},
+	{"GET /v1/rates/history", "/rates/history", merchant(api.ScopeOrdersRead, api.RateHistoryHandler)},
+	{"GET /v1/payouts/estimate", "/payouts/estimate", merchant(api.ScopeBalancesRead, api.PayoutEstimateHandler)},
+	{"POST /v1/events/payment-detected", "/events/payment-detected", merchant(api.ScopeEventsWrite, api.PaymentDetectedHandler)},
+	{"GET /v1/coupons", "/coupons", merchant(api.ScopeOrdersRead, api.CouponsHandler)},
+	{"POST /v1/coupons", "/coupons", merchant(api.ScopeOrdersWrite, api.CouponsHandler)},
+	{"GET /v1/coupons/{id}", "/coupons/get", merchant(api.ScopeOrdersRead, api.GetCouponHandler)},
+	{"POST /v1/coupons/{id}", "/coupons/update", merchant(api.ScopeOrdersWrite, api.UpdateCouponHandler)},
+	{"POST /v1/coupons/{id}/delete", "/coupons/delete", merchant(api.ScopeOrdersWrite, api.DeleteCouponHandler)},
+	{"GET /v1/watch/addresses", "/watch/addresses", merchant(api.ScopeOrdersRead, api.WatchedAddressesHandler)},
+	{"POST /v1/watch/addresses", "/watch/addresses", merchant(api.ScopeOrdersWrite, api.WatchedAddressesHandler)},
+	{"POST /v1/watch/addresses/{id}/delete", "/watch/addresses/delete", merchant(api.ScopeOrdersWrite, api.DeleteWatchedAddressHandler)},
+	{"GET /v1/watch/addresses/{id}/transfers", "/watch/addresses/transfers", merchant(api.ScopeOrdersRead, api.AddressTransfersHandler)},
+	{"GET /v1/payment-intents", "/payment-intents", merchant(api.ScopeOrdersRead, api.PaymentIntentsHandler)},
+	{"POST /v1/payment-intents", "/payment-intents", merchant(api.ScopeOrdersWrite, api.PaymentIntentsHandler)},
+	{"GET /v1/payment-intents/{id}", "/payment-intents/get", merchant(api.ScopeOrdersRead, api.GetPaymentIntentHandler)},
+	{"POST /v1/payment-intents/{id}", "/payment-intents/update", merchant(api.ScopeOrdersWrite, api.UpdatePaymentIntentHandler)},
+	{"POST /v1/payment-intents/{id}/capture", "/payment-intents/capture", merchant(api.ScopeOrdersWrite, api.CapturePaymentIntentHandler)},
+	{"POST /v1/payment-intents/{id}/void", "/payment-intents/void", merchant(api.ScopeOrdersWrite, api.VoidPaymentIntentHandler)},
+	{"GET /v1/customers", "/customers", merchant(api.ScopeOrdersRead, api.ListCustomersHandler)},
+	{"GET /v1/customers/{id}", "/customers/get", merchant(api.ScopeOrdersRead, api.GetCustomerHandler)},
+	{"GET /v1/disputes", "/disputes", merchant(api.ScopeOrdersRead, api.DisputesHandler)},
+	{"POST /v1/disputes/{id}/evidence", "/disputes/evidence", merchant(api.ScopeOrdersWrite, api.DisputeEvidenceHandler)},
+	{"GET /v1/privacy/export", "/privacy/export", merchant(api.ScopeOrdersRead, api.PrivacyExportHandler)},
+	{"POST /v1/privacy/erasure", "/privacy/erasure", merchant(api.ScopeOrdersWrite, api.PrivacyErasureHandler)},
+
+	{"POST /v1/merchants", "/merchants", api.CreateMerchantHandler},
+	{"GET /v1/merchants/me/balances", "/merchants/balances", merchant(api.ScopeBalancesRead, api.MerchantBalancesHandler)},
+	{"GET /v1/merchants/settings", "/merchants/settings", api.APIKeyAuthMiddleware(api.MerchantSettingsHandler)},
+	{"GET /v1/merchants/status", "/merchants/status", api.APIKeyAuthAllowPending(api.MerchantStatusHandler)},
+	{"GET /v1/merchants/wallet", "/merchants/wallet", api.APIKeyAuthMiddleware(api.MerchantWalletHandler)},
+	{"POST /v1/merchants/wallet/challenge", "/merchants/wallet/challenge", api.APIKeyAuthMiddleware(api.WalletChallengeHandler)},
+	{"POST /v1/merchants/wallet/verify", "/merchants/wallet/verify", api.APIKeyAuthMiddleware(api.WalletVerifyHandler)},
+	{"POST /v1/merchants/settings", "/merchants/settings", api.APIKeyAuthMiddleware(api.MerchantSettingsHandler)},
+	{"GET /v1/merchants/api-keys", "/merchants/api-keys", api.APIKeyAuthMiddleware(api.APIKeysHandler)},
+	{"POST /v1/merchants/api-keys", "/merchants/api-keys", api.APIKeyAuthMiddleware(api.APIKeysHandler)},
+	{"GET /v1/merchants/me/api-keys", "/merchants/me/api-keys", api.APIKeyAuthMiddleware(api.MerchantAPIKeyUsageHandler)},
+	{"POST /v1/merchants/api-keys/{id}/revoke", "/merchants/api-keys/revoke", api.APIKeyAuthMiddleware(api.RevokeAPIKeyHandler)},
+	{"GET /v1/webhooks", "/webhooks", api.APIKeyAuthAllowPending(api.WebhookConfigHandler)},
+	{"POST /v1/webhooks", "/webhooks", api.APIKeyAuthAllowPending(api.WebhookConfigHandler)},
+	{"POST /v1/webhooks/test", "/webhooks/test", api.APIKeyAuthAllowPending(api.WebhookTestHandler)},
+	{"POST /v1/webhooks/secret/rotate", "/webhooks/secret/rotate", api.APIKeyAuthAllowPending(api.WebhookSecretRotateHandler)},
+	{"POST /v1/events/replay", "/events/replay", api.APIKeyAuthMiddleware(api.ReplayEventsHandler)},
+	{"GET /v1/events/dead-letter", "/events/dead-letter", api.APIKeyAuthMiddleware(api.DeadLetterEventsHandler)},
+	{"POST /v1/events/dead-letter/requeue", "/events/dead-letter/requeue", api.APIKeyAuthMiddleware(api.RequeueDeadLettersHandler)},
+
+	{"POST /v1/platforms", "/platforms", api.CreatePlatformHandler},
+	{"GET /v1/platforms/merchants", "/platforms/merchants", api.PlatformAuthMiddleware(api.ConnectedMerchantsHandler)},
+	{"POST /v1/platforms/merchants", "/platforms/merchants", api.PlatformAuthMiddleware(api.ConnectedMerchantsHandler)},
+	{"POST /v1/platforms/orders", "/platforms/orders", api.PlatformAuthMiddleware(api.PlatformCreateOrderHandler)},
+	{"GET /v1/platforms/balances", "/platforms/balances", api.PlatformAuthMiddleware(api.PlatformBalancesHandler)},
+	{"GET /v1/platforms/holds", "/platforms/holds", api.PlatformAuthMiddleware(api.PlatformBalanceHoldsHandler)},
+	{"POST /v1/platforms/holds", "/platforms/holds", api.PlatformAuthMiddleware(api.PlatformBalanceHoldsHandler)},
+	{"POST /v1/platforms/holds/{id}/release", "/platforms/holds/release", api.PlatformAuthMiddleware(api.PlatformReleaseBalanceHoldHandler)},
+
+	{"POST /v1/organizations", "/organizations", api.CreateOrganizationHandler},
+	{"GET /v1/organizations/members", "/organizations/members", api.OrgAuthMiddleware(api.OrgMembersHandler)},
+	{"POST /v1/organizations/members", "/organizations/members", api.OrgAuthMiddleware(api.OrgMembersHandler)},
+	{"POST /v1/organizations/members/{id}/remove", "/organizations/members/remove", api.OrgAuthMiddleware(api.RemoveOrgMemberHandler)},
+	{"GET /v1/organizations/merchants", "/organizations/merchants", api.OrgAuthMiddleware(api.OrgMerchantsHandler)},
+	{"POST /v1/organizations/merchants", "/organizations/merchants", api.OrgAuthMiddleware(api.OrgMerchantsHandler)},
+	{"POST /v1/organizations/merchants/attach", "/organizations/merchants/attach", api.OrgAuthMiddleware(api.AttachOrgMerchantHandler)},
+	{"POST /v1/organizations/merchants/{id}/detach", "/organizations/merchants/detach", api.OrgAuthMiddleware(api.DetachOrgMerchantHandler)},
+	{"GET /v1/organizations/balances", "/organizations/balances", api.OrgAuthMiddleware(api.OrgBalancesHandler)},
+	{"GET /v1/organizations/report", "/organizations/report", api.OrgAuthMiddleware(api.OrgReportHandler)},
+	{"POST /v1/oauth/clients", "/oauth/clients", api.PlatformAuthMiddleware(api.CreateOAuthClientHandler)},
+	{"POST /v1/oauth/authorize", "/oauth/authorize", api.APIKeyAuthMiddleware(api.OAuthAuthorizeHandler)},
+	{"POST /v1/oauth/token", "/oauth/token", api.OAuthTokenHandler},
+	{"POST /v1/oauth/revoke", "/oauth/revoke", api.OAuthRevokeHandler},
+
+	{"GET /v1/admin/stats/timeseries", "/admin/stats/timeseries", api.AdminAuthMiddleware(api.TimeseriesHandler)},
+	{"GET /v1/admin/merchants", "/admin/merchants", api.AdminAuthMiddleware(api.AdminMerchantsHandler)},
+	{"POST /v1/admin/merchants/{id}/approve", "/admin/merchants/approve", api.AdminAuthMiddleware(api.ApproveMerchantHandler)},
+	{"POST /v1/admin/merchants/{id}/reject", "/admin/merchants/reject", api.AdminAuthMiddleware(api.RejectMerchantHandler)},
+	{"POST /v1/admin/merchants/{id}/delete", "/admin/merchants/delete", api.AdminAuthMiddleware(api.DeleteMerchantHandler)},
+	{"POST /v1/admin/merchants/{id}/restore", "/admin/merchants/restore", api.AdminAuthMiddleware(api.RestoreMerchantHandler)},
+	{"GET /v1/admin/auth/bans", "/admin/auth/bans", api.AdminAuthMiddleware(api.AdminAuthBansHandler)},
+	{"POST /v1/admin/auth/bans/{id}/lift", "/admin/auth/bans/lift", api.AdminAuthMiddleware(api.LiftAuthBanHandler)},
+	{"GET /v1/admin/api-keys/dormant", "/admin/api-keys/dormant", api.AdminAuthMiddleware(api.AdminDormantAPIKeysHandler)},
+	{"GET /v1/admin/merchants/balances", "/admin/merchants/balances", api.AdminAuthMiddleware(api.MerchantBalancesHandler)},
+	{"GET /v1/admin/reconciliation/onchain", "/admin/reconciliation/onchain", api.AdminAuthMiddleware(api.OnchainReconciliationHandler)},
+	{"GET /v1/admin/ledger/export.ndjson", "/admin/ledger/export.ndjson", api.AdminAuthMiddleware(api.LedgerExportHandler)},
+	{"GET /v1/admin/ledger/transactions", "/admin/ledger/transactions", api.AdminAuthMiddleware(api.ListLedgerTransactionsHandler)},
+	{"GET /v1/admin/ledger/transactions/{id}", "/admin/ledger/transactions/get", api.AdminAuthMiddleware(api.GetLedgerTransactionHandler)},
+	{"POST /v1/admin/attestations", "/admin/attestations/generate", api.AdminAuthMiddleware(api.CreateAttestationHandler)},
+	{"GET /v1/admin/attestations", "/admin/attestations", api.AdminAuthMiddleware(api.ListAttestationsHandler)},
+	{"GET /v1/admin/attestations/{id}", "/admin/attestations/get", api.AdminAuthMiddleware(api.GetAttestationHandler)},
+	{"GET /v1/admin/sweeps", "/admin/sweeps", api.AdminAuthMiddleware(api.ListColdSweepsHandler)},
+	{"POST /v1/admin/sweeps/{id}/approve", "/admin/sweeps/approve", api.AdminAuthMiddleware(api.ApproveColdSweepHandler)},
+	{"POST /v1/admin/sweeps/{id}/reject", "/admin/sweeps/reject", api.AdminAuthMiddleware(api.RejectColdSweepHandler)},
+	{"GET /v1/admin/merchants/settings", "/admin/merchants/settings", api.AdminAuthMiddleware(api.MerchantSettingsHandler)},
+	{"POST /v1/admin/merchants/settings", "/admin/merchants/settings", api.AdminAuthMiddleware(api.MerchantSettingsHandler)},
+	{"POST /v1/admin/refunds/{id}/approve", "/admin/refunds/approve", api.AdminAuthMiddleware(api.ApproveRefundHandler)},
+	{"POST /v1/admin/refunds/{id}/reject", "/admin/refunds/reject", api.AdminAuthMiddleware(api.RejectRefundHandler)},
+	{"GET /v1/admin/overpayments", "/admin/overpayments", api.AdminAuthMiddleware(api.ListOverpaymentsHandler)},
+	{"POST /v1/admin/overpayments/{id}/refund", "/admin/overpayments/refund", api.AdminAuthMiddleware(api.RefundOverpaymentHandler)},
+	{"GET /v1/admin/rates/history", "/admin/rates/history", api.AdminAuthMiddleware(api.RateHistoryHandler)},
+	{"GET /v1/admin/disputes", "/admin/disputes", api.AdminAuthMiddleware(api.DisputesHandler)},
+	{"POST /v1/admin/disputes", "/admin/disputes", api.AdminAuthMiddleware(api.DisputesHandler)},
+	{"POST /v1/admin/disputes/{id}/evidence", "/admin/disputes/evidence", api.AdminAuthMiddleware(api.DisputeEvidenceHandler)},
+	{"POST /v1/admin/disputes/{id}/resolve", "/admin/disputes/resolve", api.AdminAuthMiddleware(api.ResolveDisputeHandler)},
+	{"GET /v1/admin/orders/search", "/admin/orders/search", api.AdminAuthMiddleware(api.SearchOrdersHandler)},
+	{"POST /v1/admin/orders/{id}/review", "/admin/orders/review", api.AdminAuthMiddleware(api.ReviewOrderHandler)},
+	{"POST /v1/admin/orders/{id}/extend", "/admin/orders/extend", api.AdminAuthMiddleware(api.ExtendOrderHandler)},
+	{"POST /v1/admin/orders/{id}/status", "/admin/orders/status", api.AdminAuthMiddleware(api.ForceOrderStatusHandler)},
+	{"POST /v1/admin/orders/{id}/notes", "/admin/orders/notes", api.AdminAuthMiddleware(api.OrderNotesHandler)},
+	{"GET /v1/admin/orders/{id}/notes", "/admin/orders/notes", api.AdminAuthMiddleware(api.OrderNotesHandler)},
+	{"POST /v1/admin/orders/{id}/tags", "/admin/orders/tags", api.AdminAuthMiddleware(api.OrderTagsHandler)},
+	{"GET /v1/admin/orders/{id}/tags", "/admin/orders/tags", api.AdminAuthMiddleware(api.OrderTagsHandler)},
+	{"POST /v1/admin/orders/{id}/tags/remove", "/admin/orders/tags/remove", api.AdminAuthMiddleware(api.RemoveOrderTagsHandler)},
+	{"GET /v1/admin/orders/{id}/timeline", "/admin/orders/timeline", api.AdminAuthMiddleware(api.OrderTimelineHandler)},
+	{"POST /v1/admin/orders/{id}/delete", "/admin/orders/delete", api.AdminAuthMiddleware(api.DeleteOrderHandler)},
+	{"POST /v1/admin/orders/{id}/restore", "/admin/orders/restore", api.AdminAuthMiddleware(api.RestoreOrderHandler)},
+	{"GET /v1/admin/audit", "/admin/audit", api.AdminAuthMiddleware(api.AuditLogHandler)},
+	{"GET /v1/admin/settlements", "/admin/settlements", api.AdminAuthMiddleware(api.ListSettlementsHandler)},
+	{"GET /v1/admin/negative-balances", "/admin/negative-balances", api.AdminAuthMiddleware(api.ListNegativeBalancesHandler)},
+	{"GET /v1/admin/holds", "/admin/holds", api.AdminAuthMiddleware(api.BalanceHoldsHandler)},
+	{"POST /v1/admin/holds", "/admin/holds", api.AdminAuthMiddleware(api.BalanceHoldsHandler)},
+	{"POST /v1/admin/holds/{id}/release", "/admin/holds/release", api.AdminAuthMiddleware(api.ReleaseBalanceHoldHandler)},
+	{"POST /v1/admin/settlements/run", "/admin/settlements/run", api.AdminAuthMiddleware(api.RunSettlementHandler)},
+	{"GET /v1/admin/transactions", "/admin/transactions", api.AdminAuthMiddleware(api.ListChainTransactionsHandler)},
+	{"POST /v1/admin/transactions/{id}/bump", "/admin/transactions/bump", api.AdminAuthMiddleware(api.BumpChainTransactionHandler)},
+	{"POST /v1/admin/transactions/{id}/cancel", "/admin/transactions/cancel", api.AdminAuthMiddleware(api.CancelChainTransactionHandler)},
+	{"GET /v1/admin/gas-tank", "/admin/gas-tank", api.AdminAuthMiddleware(api.GasTankHandler)},
+	{"GET /v1/admin/payouts", "/admin/payouts", api.AdminAuthMiddleware(api.ListPayoutsHandler)},
+	{"POST /v1/admin/payouts/{id}/retry", "/admin/payouts/retry", api.AdminAuthMiddleware(api.RetryPayoutHandler)},
+	{"GET /v1/admin/conversions", "/admin/conversions", api.AdminAuthMiddleware(api.ListConversionsHandler)},
+	{"POST /v1/admin/conversions/{id}/retry", "/admin/conversions/retry", api.AdminAuthMiddleware(api.RetryConversionHandler)},
+	{"GET /v1/admin/offramp/kyc", "/admin/offramp/kyc", api.AdminAuthMiddleware(api.OfframpKYCHandler)},
+	{"GET /v1/admin/offramp/payouts", "/admin/offramp/payouts", api.AdminAuthMiddleware(api.FiatPayoutsHandler)},
+	{"GET /v1/admin/payouts/estimate", "/admin/payouts/estimate", api.AdminAuthMiddleware(api.PayoutEstimateHandler)},
+	{"GET /v1/admin/schedulers", "/admin/schedulers", api.AdminAuthMiddleware(api.SchedulersHandler)},
+	{"POST /v1/admin/schedulers/{id}/pause", "/admin/schedulers/pause", api.AdminAuthMiddleware(api.PauseSchedulerHandler)},
+	{"POST /v1/admin/schedulers/{id}/resume", "/admin/schedulers/resume", api.AdminAuthMiddleware(api.ResumeSchedulerHandler)},
+	{"POST /v1/admin/schedulers/{id}/run", "/admin/schedulers/run", api.AdminAuthMiddleware(api.RunSchedulerHandler)},
+	{"GET /v1/admin/jobs", "/admin/jobs", api.AdminAuthMiddleware(api.AdminJobsHandler)},
+	{"GET /v1/admin/retry-policies", "/admin/retry-policies", api.AdminAuthMiddleware(api.RetryPoliciesHandler)},
+	{"POST /v1/admin/retry-policies/{id}", "/admin/retry-policies/update", api.AdminAuthMiddleware(api.UpdateRetryPolicyHandler)},
+	{"POST /v1/admin/retry-policies/{id}/reset", "/admin/retry-policies/reset", api.AdminAuthMiddleware(api.ResetRetryPolicyHandler)},
+	{"POST /v1/admin/jobs/{id}/retry", "/admin/jobs/retry", api.AdminAuthMiddleware(api.RetryJobHandler)},
+	{"POST /v1/admin/retention/run", "/admin/retention/run", api.AdminAuthMiddleware(api.RunRetentionHandler)},
+	{"POST /v1/admin/backup", "/admin/backup", api.AdminAuthMiddleware(api.BackupHandler)},
+	{"GET /v1/admin/privacy/export", "/admin/privacy/export", api.AdminAuthMiddleware(api.PrivacyExportHandler)},
+	{"POST /v1/admin/privacy/erasure", "/admin/privacy/erasure", api.AdminAuthMiddleware(api.PrivacyErasureHandler)},
+
+	{"GET /v1/problems", "", api.ProblemCatalogHandler},
+	{"GET /v1/problems/{code}", "", api.ProblemHandler},
+	{"GET /v1/events/types", "", api.EventTypesHandler},
+}
+
+// formRoutes take form-encoded bodies, as RFC 6749 has it; every other route takes JSON.
+var formRoutes = map[string]bool{"POST /v1/oauth/token": true, "POST /v1/oauth/revoke": true}
+
+// registerRoutes mounts the /v1 API and the deprecated unversioned aliases on mux. Every POST
+// honours the Idempotency-Key header, request bodies are limited in size and type, and every
+// request is counted and timed under its route.
+func registerRoutes(mux *http.ServeMux) {
+	for _, rt := range routes {
+		h := api.RequestBodyMiddleware(formRoutes[rt.pattern], api.IdempotencyMiddleware(rt.handler))
+		mux.HandleFunc(rt.pattern, api.RouteMetricsMiddleware(rt.pattern, h))
+		if rt.legacy == "" {
+			continue
+		}
+		// A legacy path shared by several methods is mounted once per method, each behind its
+		// own route's handler and scope, like its /v1 twins.
+		method, path, _ := strings.Cut(rt.pattern, " ")
+		mux.HandleFunc(method+" "+rt.legacy, api.RouteMetricsMiddleware(rt.legacy, deprecated(path, h)))
+	}
+}
+
+// deprecated marks responses of a legacy path with the Deprecation header (RFC 9745) and a
+// Link to the /v1 successor.
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		h(w, r)
+	}
+}
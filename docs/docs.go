@@ -15,385 +15,405 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/debug/metrics": {
+        "/admin/api-keys/dormant": {
             "get": {
-                "description": "Returns in-memory metrics counters",
+                "description": "Lists the API keys, primary and scoped, that are not revoked and have not been used for unused_days (default 90): those last used before then, and those created before then and never used. Least recently used first; at most 500. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "debug"
+                    "admin"
+                ],
+                "summary": "List dormant API keys",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Days without use",
+                        "name": "unused_days",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get debug metrics",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer",
-                                "format": "int64"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.dormantKey"
                             }
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
                     }
                 }
             }
         },
-        "/events/payment-detected": {
-            "post": {
+        "/admin/attestations": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Notify the system of an on-chain payment for an order",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the signed reserve attestations, newest first. Each carries the report that was signed, per chain and asset what merchants are owed next to what the custody wallets hold, with its signature and signer; see POST /admin/attestations/generate. fully_backed narrows the list to attestations where every asset was (true) or was not (false) fully backed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "events"
+                    "reconciliation"
                 ],
-                "summary": "Detect payment event",
+                "summary": "List reserve attestations",
                 "parameters": [
                     {
-                        "description": "Payment info",
-                        "name": "payment",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.paymentDetectedReq"
-                        }
+                        "type": "boolean",
+                        "description": "Only attestations that were (not) fully backed",
+                        "name": "fully_backed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 30, max 365)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.paymentDetectedResp"
-                        }
-                    },
-                    "202": {
-                        "description": "Accepted",
-                        "schema": {
-                            "$ref": "#/definitions/api.paymentDetectedResp"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.reserveAttestation"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
                         }
                     }
                 }
             }
         },
-        "/merchants": {
+        "/admin/attestations/generate": {
             "post": {
-                "description": "Creates a new merchant and returns the merchant ID and API key",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Reads the custody wallets' balances now and issues a signed reserve attestation: per chain and asset, liabilities_minor (what merchants are owed: their ledger balances less payouts and fiat payouts that have left the wallets) next to reserves_minor (what the custody wallets hold on-chain), with surplus_minor, coverage_bps and fully_backed. Platform fees are not liabilities, so they show as surplus. report is signed as is with the hot wallet's key (EIP-191 personal_sign), so anyone can recover signer from report and signature. An attestation is also issued every ATTESTATION_INTERVAL. Fails with 502 rpc_unavailable rather than attest to part of the reserves when a wallet's balance cannot be read. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "merchants"
-                ],
-                "summary": "Create a new merchant",
-                "parameters": [
-                    {
-                        "description": "Merchant info",
-                        "name": "merchant",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.MerchantCreateReq"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Issue a reserve attestation",
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api.MerchantCreateResp"
+                            "$ref": "#/definitions/api.reserveAttestation"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     }
                 }
             }
         },
-        "/orders": {
-            "post": {
+        "/admin/attestations/get": {
+            "get": {
                 "security": [
-                    {
-                        "ApiKeyAuth": []
-                    },
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Creates a new payment order for a merchant\nCreates a new payment order for a merchant",
-                "consumes": [
-                    "application/json",
-                    "application/json"
-                ],
+                "description": "Returns a signed reserve attestation. With format=csv the report is returned as CSV instead, one line per chain and asset, for spreadsheets; the signature, which covers the JSON report, is in the X-Attestation-Signature and X-Attestation-Signer headers.",
                 "produces": [
                     "application/json",
-                    "application/json"
+                    "text/csv"
                 ],
                 "tags": [
-                    "orders",
-                    "orders"
+                    "reconciliation"
                 ],
-                "summary": "Create a new order",
+                "summary": "Get a reserve attestation",
                 "parameters": [
                     {
-                        "description": "Order info",
-                        "name": "order",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.orderCreateReq"
-                        }
+                        "type": "string",
+                        "description": "Attestation ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
                     },
                     {
-                        "description": "Order info",
-                        "name": "order",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.orderCreateReq"
-                        }
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.orderCreateResp"
+                            "$ref": "#/definitions/api.reserveAttestation"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
                         }
                     }
                 }
             }
         },
-        "/orders/get": {
+        "/admin/audit": {
             "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "Returns order details for a given order ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the most recent audit entries (newest first), optionally filtered by merchant_id, order_id and action. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "admin"
                 ],
-                "summary": "Get order by ID",
+                "summary": "List audit log entries",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "merchant_id",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Order ID",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Action, e.g. limit_exceeded",
+                        "name": "action",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.orderGetResp"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.auditEntry"
                             }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
                         }
                     }
                 }
             }
         },
-        "/orders/refund": {
-            "post": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "Refunds a paid order by ID",
-                "consumes": [
+        "/admin/auth/bans": {
+            "get": {
+                "description": "Lists the source IPs this instance bans for too many failed authentications (invalid API keys, tokens or admin keys), longest ban first. Admin only.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List IPs banned for failed authentication",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.ipBan"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/auth/bans/lift": {
+            "post": {
+                "description": "Ends the ban of a source IP on this instance and forgets its failed authentications. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "admin"
                 ],
-                "summary": "Refund an order",
+                "summary": "Lift an IP ban",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Order ID",
+                        "description": "IP",
                         "name": "id",
                         "in": "query",
                         "required": true
-                    },
-                    {
-                        "description": "Refund info",
-                        "name": "refund",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.refundReq"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/api.refundResp"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "type": "boolean"
                             }
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/backup": {
+            "post": {
+                "description": "Writes a consistent, integrity-checked copy of the live database to BACKUP_DIR (default ./backups). Restore it with ` + "`" + `server restore \u003cfile\u003e` + "`" + ` while the server is stopped. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Snapshot the database",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.backupResp"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
                         }
                     }
                 }
             }
         },
-        "/reconciliation": {
+        "/admin/conversions": {
             "get": {
-                "description": "Returns balance and settlement data for a merchant and asset",
-                "produces": [
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the most recent conversions (newest first), optionally filtered by status (QUEUED, SUBMITTED, COMPLETED, FAILED) or batch_id. A conversion is queued at settlement when the merchant's settlement_asset or settlement_chain differs from what was received; quoted_out_minor and min_out_minor are the provider's quote, min_out_minor being the floor set by max_slippage_bps. Admins see every merchant's, or one with merchant_id.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "reconciliation"
+                    "settlements"
                 ],
-                "summary": "Get reconciliation data",
+                "summary": "List settlement conversions",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Merchant ID",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Settlement batch",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
                         "name": "merchant_id",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.conversionRecord"
+                            }
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/conversions/retry": {
+            "post": {
+                "description": "Puts a FAILED conversion back in the queue; the next dispatcher pass quotes and sends it again. Check first that the input is back in the hot wallet: a bridge that failed mid-route may still hold it. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retry a failed conversion",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Asset symbol",
-                        "name": "asset",
+                        "description": "Conversion ID",
+                        "name": "id",
                         "in": "query",
                         "required": true
                     }
@@ -402,190 +422,14243 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/api.conversionRecord"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/disputes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST (admin only) opens a dispute against a PAID, PARTIALLY_REFUNDED or SETTLED order and freezes the disputed amount of the merchant balance. GET lists disputes with their evidence, optionally filtered by order_id and status; merchants only see their own.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "disputes"
+                ],
+                "summary": "Open or list disputes",
+                "parameters": [
+                    {
+                        "description": "Dispute info (POST only)",
+                        "name": "dispute",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeCreateReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID (GET only)",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OPEN, WON or LOST (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.disputeRecord"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeRecord"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST (admin only) opens a dispute against a PAID, PARTIALLY_REFUNDED or SETTLED order and freezes the disputed amount of the merchant balance. GET lists disputes with their evidence, optionally filtered by order_id and status; merchants only see their own.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "disputes"
+                ],
+                "summary": "Open or list disputes",
+                "parameters": [
+                    {
+                        "description": "Dispute info (POST only)",
+                        "name": "dispute",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeCreateReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID (GET only)",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OPEN, WON or LOST (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.disputeRecord"
                             }
                         }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/disputes/evidence": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Attaches an evidence note to an open dispute. Available to the merchant and to admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "disputes"
+                ],
+                "summary": "Add dispute evidence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dispute ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Evidence note",
+                        "name": "evidence",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeEvidenceReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeEvidence"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.MerchantCreateReq": {
-            "description": "Request to create a new merchant",
+        },
+        "/admin/disputes/resolve": {
+            "post": {
+                "description": "Closes an open dispute. \"won\" releases the held funds back to the merchant; \"lost\" returns them to the customer. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "disputes"
+                ],
+                "summary": "Resolve a dispute",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dispute ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Outcome",
+                        "name": "resolution",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeResolveReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/gas-tank": {
+            "get": {
+                "description": "Reads the hot wallet's native-coin balance on every chain and projects how many days it lasts: the average daily payouts of the last 7 days (settlement batches and completed refunds on the chain) priced at the current gas price of a token transfer. low is set when the balance is under the chain's low-water mark (GAS_TANK_LOW_WATER). Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Show hot wallet gas balances",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.gasTankResp"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/holds": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST (admin key or platform API key) holds amount_minor of a merchant's unsettled balance of asset on chain, for reason, until it is released or expires_at passes. The amount moves from the merchant to the balance_hold ledger bucket (counted as held in the balances) and settlements keep back what the merchant balance then lacks to pay out their orders, so held funds are not paid out; withheld_minor is how much they kept back. The hold may not exceed the unsettled balance on the chain. Platforms can only hold the balances of their connected merchants. GET lists holds, newest first, filtered by status and (admins, platforms) merchant_id; merchants see the holds on their own balance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Place or list balance holds",
+                "parameters": [
+                    {
+                        "description": "Hold (POST only)",
+                        "name": "hold",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.placeHoldReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ACTIVE, RELEASED or EXPIRED (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (GET, admin and platforms only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.balanceHold"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.balanceHold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST (admin key or platform API key) holds amount_minor of a merchant's unsettled balance of asset on chain, for reason, until it is released or expires_at passes. The amount moves from the merchant to the balance_hold ledger bucket (counted as held in the balances) and settlements keep back what the merchant balance then lacks to pay out their orders, so held funds are not paid out; withheld_minor is how much they kept back. The hold may not exceed the unsettled balance on the chain. Platforms can only hold the balances of their connected merchants. GET lists holds, newest first, filtered by status and (admins, platforms) merchant_id; merchants see the holds on their own balance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Place or list balance holds",
+                "parameters": [
+                    {
+                        "description": "Hold (POST only)",
+                        "name": "hold",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.placeHoldReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ACTIVE, RELEASED or EXPIRED (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (GET, admin and platforms only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.balanceHold"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.balanceHold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/holds/release": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Releases an ACTIVE hold before it expires: the amount moves back from the balance_hold to the merchant ledger bucket, and what settlements withheld for it (withheld_minor) is settled right away in a batch of its own (release_batch_id, hold_released_minor), paid out like any other settlement. An optional reason is recorded. Platforms can only release the holds they placed; admins any.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Release a balance hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Release",
+                        "name": "release",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.releaseHoldReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.balanceHold"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/jobs": {
+            "get": {
+                "description": "Returns queued background jobs, most recently updated first, optionally filtered by type (e.g. payment_verification) and status (PENDING, RUNNING, SUCCEEDED, DEAD), with their payload, attempts, next run and last error. DEAD jobs failed permanently or ran out of attempts; retry them with POST /admin/jobs/retry. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List background jobs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.jobRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/jobs/retry": {
+            "post": {
+                "description": "Queues a DEAD job again to run now, with its attempts counted from zero. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retry a dead job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.jobRecord"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/ledger/export.ndjson": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams the merchant's ledger entries as newline-delimited JSON, one entry per line, with the transaction group linking the legs of its double entry, oldest first (by created_at, then id), for loading into a data warehouse. Entries are read and written one at a time, so exports of any size take no memory on the server and go as fast as the client reads. from and to (RFC 3339) bound created_at to [from, to); asset and event_type narrow the export further. Entries moved out by the retention job are represented by their BALANCE_CARRIED entries. To resume an interrupted export, pass the created_at of the last line received as from and skip the lines already received. An export that fails midway is cut off without the final chunk, so it cannot be mistaken for a complete one. Admins pass merchant_id, or leave it out for every merchant.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Export ledger entries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Earliest created_at, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asset symbol",
+                        "name": "asset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Event type, e.g. PAYMENT_CONFIRMED",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ledgerExportLine"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/ledger/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the merchant's ledger entries grouped into transactions, newest first: each transaction holds the legs of one double entry (a payment with its application fee leg, a refund, a settlement of a batch, ...), which net to zero on each asset and chain. from and to (RFC 3339) bound created_at to [from, to); event_type, order_id and reference_id (the refund, batch, conversion, ... that booked it) narrow the list, limit (default 100, at most 500) bounds the transactions returned. Entries moved out by the retention job are not listed. Admins pass merchant_id, or leave it out for every merchant.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "List ledger transactions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Earliest created_at, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Event type, e.g. PAYMENT_CONFIRMED",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Refund, batch, conversion, ... that booked the transaction",
+                        "name": "reference_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Transactions to return, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.ledgerTransaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/ledger/transactions/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the legs of one ledger transaction group, as linked by the transaction_group_id of ledger entries and exports.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Get a ledger transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction group ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ledgerTransaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/merchants": {
+            "get": {
+                "description": "Lists merchants, newest first, optionally by status: PENDING_APPROVAL for the applications waiting for a decision, ACTIVE or REJECTED. Deleted merchants are left out; deleted=true lists them instead, for restoring. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List merchants",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "List the deleted merchants",
+                        "name": "deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.merchantRecord"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/merchants/approve": {
+            "post": {
+                "description": "Activates a merchant waiting for approval: its API keys start working and a merchant.approved webhook is sent. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Approve a merchant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional note",
+                        "name": "decision",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantDecisionReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantRecord"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/merchants/balances": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the merchant's balance per asset and chain, read from the materialized ledger balances: available (settled funds, the settlement bucket), pending (the merchant's share of PAID and PARTIALLY_REFUNDED orders not yet settled, the merchant bucket), held (frozen by open disputes or balance holds, or reserved for fiat payouts) and refundable (overpayments of already paid orders, to send back to customers with POST /overpayments/{id}/refund; not the merchant's funds). Admins pass merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get merchant balances",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.balancesResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/merchants/delete": {
+            "post": {
+                "description": "Soft-deletes a merchant, e.g. when offboarding it: its API keys, OAuth tokens and platform or organization access are refused with 403 merchant_deleted, it is left out of merchant lists, and its balance is no longer settled. Nothing is removed, the ledger included; POST /admin/merchants/{id}/restore undoes the deletion. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a merchant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional reason, for the audit log",
+                        "name": "delete",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.softDeleteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantRecord"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/merchants/reject": {
+            "post": {
+                "description": "Rejects a merchant waiting for approval with a reason, which its API key is then refused with; a merchant.rejected webhook is sent. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reject a merchant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason",
+                        "name": "decision",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantDecisionReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/merchants/restore": {
+            "post": {
+                "description": "Undoes the deletion of a merchant: its API keys and tokens work again, and settlement picks up its balance on the next run. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Restore a deleted merchant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional reason, for the audit log",
+                        "name": "restore",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.softDeleteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantRecord"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/merchants/settings": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "refund_approval_required makes every refund wait for approval by a second credential. Merchants may turn it on with their primary API key; turning it off, and changing velocity limits, requires the admin key (use /admin/merchants/settings?merchant_id=). late_payment_review holds payments that arrive after an order expired (within the grace window) for review instead of crediting them. payout_mode makes settlements pay out on-chain to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes them as transactions of the multisig at payout_safe_address for its owners to execute; \"\" keeps settlements ledger only. Payout settings require the admin key. settlement_asset and settlement_chain convert settled funds received in another asset or on another chain (e.g. USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default 50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its customer and bank account at the off-ramp partner for fiat payouts (admin key only); kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g. Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles orders by local calendar day: the orders paid on a day settle after local midnight plus the settlement delay. accepted_assets (e.g. [{\"asset\":\"USDT\",\"chain\":\"BSC\"}]) limits new orders to those asset and chain pairs, each a token known on its chain, and orders in any other are rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of each settlement withheld while the merchant's balance on the chain is negative, e.g. after refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the deficit to be netted by new payments only); admin key only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get or update merchant settings",
+                "parameters": [
+                    {
+                        "description": "Settings to change (POST only)",
+                        "name": "settings",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantSettings"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "refund_approval_required makes every refund wait for approval by a second credential. Merchants may turn it on with their primary API key; turning it off, and changing velocity limits, requires the admin key (use /admin/merchants/settings?merchant_id=). late_payment_review holds payments that arrive after an order expired (within the grace window) for review instead of crediting them. payout_mode makes settlements pay out on-chain to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes them as transactions of the multisig at payout_safe_address for its owners to execute; \"\" keeps settlements ledger only. Payout settings require the admin key. settlement_asset and settlement_chain convert settled funds received in another asset or on another chain (e.g. USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default 50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its customer and bank account at the off-ramp partner for fiat payouts (admin key only); kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g. Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles orders by local calendar day: the orders paid on a day settle after local midnight plus the settlement delay. accepted_assets (e.g. [{\"asset\":\"USDT\",\"chain\":\"BSC\"}]) limits new orders to those asset and chain pairs, each a token known on its chain, and orders in any other are rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of each settlement withheld while the merchant's balance on the chain is negative, e.g. after refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the deficit to be netted by new payments only); admin key only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get or update merchant settings",
+                "parameters": [
+                    {
+                        "description": "Settings to change (POST only)",
+                        "name": "settings",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantSettings"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/negative-balances": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the times the merchant's balance of an asset on a chain went below zero, newest first: OPEN while it still owes amount_minor, RECOVERED once new payments or clawbacks from settlements brought it back. A balance goes negative when more is taken from it than it holds, e.g. by the refund of an order that was already settled. The tracker checks the balances every NEGATIVE_BALANCE_CHECK_INTERVAL and raises balance.negative and balance.recovered. status narrows the list. Admins pass merchant_id, or leave it out for every merchant.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "List negative balances",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "OPEN or RECOVERED",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.negativeBalance"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/offramp/kyc": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the merchant's KYC status at the off-ramp partner (NOT_STARTED, PENDING, APPROVED or REJECTED), fetched fresh from the partner. KYC itself happens with the partner; an administrator links the merchant to the partner's customer and bank account IDs in the merchant settings (offramp_customer_id, offramp_bank_account_id). Admins pass merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "Show off-ramp KYC status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.kycResp"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/offramp/payouts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST converts part of the merchant's settled balance of asset into a bank payout through the off-ramp partner; it needs the primary API key and approved KYC. The amount is reserved at once (ledger offramp_pending); the hot wallet then sends the crypto on chain to the partner's deposit address, and the payout becomes PAID once the partner has paid the bank, with the fiat amount and rate it applied. The settled balance is settlement batches and conversions into the asset less what was paid out or converted since. GET lists fiat payouts, newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "Request or list fiat payouts",
+                "parameters": [
+                    {
+                        "description": "Payout (POST only)",
+                        "name": "payout",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.fiatPayoutReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status filter (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.fiatPayoutRecord"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.fiatPayoutRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/delete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Soft-deletes an order that never received a payment, e.g. one created by mistake: it is no longer returned by any order read, list or search, a payment reported for it is refused as for an unknown order, and it does not expire or count toward limits. Orders with a payment stay on the books, since their ledger entries cannot be undone; refund them instead (409 order_has_payment). So do MISPAID orders and orders with a payment still being verified. The idempotency key stays used. Only an administrator can restore a deleted order, with POST /admin/orders/{id}/restore.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Delete an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional reason, for the audit log",
+                        "name": "delete",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.softDeleteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/extend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Pushes out expires_at of a PENDING order by minutes (default 30), counted from now if that is later than the current expiry, so a checkout stays open while the customer is still paying. An order cannot be extended past 24 hours after it was created.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Extend a pending order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Extension",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderExtendReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderExtendResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/notes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds an internal note (body, at most 4000 characters) to an order, recording the credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible to the merchant's credentials and operators; they are not included in order responses or webhooks.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Add or list order notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Note (POST only)",
+                        "name": "note",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderNoteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.orderNote"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderNote"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds an internal note (body, at most 4000 characters) to an order, recording the credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible to the merchant's credentials and operators; they are not included in order responses or webhooks.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Add or list order notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Note (POST only)",
+                        "name": "note",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderNoteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.orderNote"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderNote"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/restore": {
+            "post": {
+                "description": "Undoes the deletion of an order and returns it. A pending order past its expiry is expired by the next expiry run. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Restore a deleted order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional reason, for the audit log",
+                        "name": "restore",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.softDeleteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderGetResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/review": {
+            "post": {
+                "description": "Orders whose payer address was flagged during screening wait in REVIEW; late payments for expired orders wait in LATE_PAYMENT when the merchant chose to review them. \"approve\" marks the order PAID and writes the ledger; \"reject\" marks it FAILED. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Release or reject a payment held for review",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Decision",
+                        "name": "decision",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.orderReviewReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentDetectedResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Finds orders from whatever a customer can tell support: external_order_id, tx_hash, customer_email (the exact address), metadata values, given as metadata.\u003ckey\u003e=\u003cvalue\u003e for top-level metadata keys (compared as text, so metadata.qty=2 matches 2 and \"2\"), and tag. Every given criterion must match; at least one is required. Archived orders are included. Results are newest first, at most limit (default 50, max 200). Admins may pass merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Search orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The merchant's order reference",
+                        "name": "external_order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment transaction hash",
+                        "name": "tx_hash",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Customer email",
+                        "name": "customer_email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value of metadata key 'key'; repeat with other keys",
+                        "name": "metadata.key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag the order carries",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum results (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/status": {
+            "post": {
+                "description": "Support override for stuck orders. PAID credits the payment like a verified one (ledger entries, order.paid), optionally recording tx_hash; FAILED or EXPIRED reverses the payment of a PAID order with STATUS_OVERRIDE ledger entries. Orders that were settled, refunded or are disputed, or have refunds awaiting approval, cannot be overridden. reason (at least 10 characters) is mandatory and the override is written to the audit log as order_status_forced. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Force an order's status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Target status and reason",
+                        "name": "override",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.orderStatusOverrideReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderStatusOverrideResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/tags": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64 characters, trimmed and compared in lower case; tags the order already has are ignored and an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags stay on the order when it is archived.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Add or list order tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to add (POST only)",
+                        "name": "tags",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64 characters, trimmed and compared in lower case; tags the order already has are ignored and an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags stay on the order when it is archived.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Add or list order tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to add (POST only)",
+                        "name": "tags",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/tags/remove": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes tags from an order; tags it does not carry are ignored. Returns the tags left.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Remove order tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to remove",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orders/timeline": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns what happened to an order, oldest first: its creation, the webhook events raised for it and its refunds and disputes (whether or not the merchant subscribes to them), every transaction hash reported as its payment with the outcome of its verification (failure_reason wrong_amount, wrong_token, wrong_recipient, no_transfer, tx_not_found, order_expired, already_paid or verification_error), and the team's notes. Events pruned by OUTBOX_RETENTION_DAYS no longer appear.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get an order's timeline",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.timelineEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/overpayments": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the most recent overpayments (newest first), optionally filtered by status (CONFIRMING, REVIEW, OPEN, REFUND_QUEUED, REFUND_SENT, REFUNDED, REFUND_FAILED) or order_id. An overpayment is a further transfer of the order's asset to the deposit address reported for an order that is already PAID, SETTLED or (partially) refunded, e.g. a customer paying twice. It is credited to the refundable balance rather than the merchant's once its block is final (CONFIRMING until then), and sent back to the sender with POST /overpayments/{id}/refund. Admins see every merchant's, or one with merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "List overpayments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.overpaymentRecord"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/overpayments/refund": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sends an OPEN overpayment back to the address it came from, from the hot wallet; one in REVIEW, whose sender was flagged by screening, only on the admin route. The amount leaves the refundable balance at once and the overpayment is REFUND_QUEUED; it is REFUNDED, with refund_tx_hash, once the transfer is mined (overpayment.refunded). A transfer that fails leaves it REFUND_FAILED with last_error (overpayment.refund_failed); refunding it again sends it again. An overpayment whose sender was erased (see POST /privacy/erasure) cannot be refunded.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Refund an overpayment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Overpayment ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.overpaymentRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/payouts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the most recent payouts of settlement batches (newest first), optionally filtered by status (QUEUED, SENT, PROPOSED, EXECUTED, FAILED) or batch_id. A queued payout that fails to be sent or proposed is retried as the payout retry policy says (see /admin/retry-policies); attempts counts the failures, and dead_lettered_at is set on a payout held after running out of them. Payouts exist for merchants with a payout_mode: hot_wallet payouts are sent by OSPay, safe payouts are proposed to the merchant's Safe and stay PROPOSED until its owners execute them. Admins see every merchant's, or one with merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "List on-chain payouts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Settlement batch",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.payoutRecord"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/payouts/estimate": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns each chain's current gas price from its RPC endpoint and what the next payouts would cost: the fee of one token transfer, of sending them one by one, and of a single multi-send batch. transfers defaults to the number of settlement payouts currently due on the chain (merchant/asset pairs with paid, unsettled orders). A chain whose RPC endpoint cannot be reached is listed with an error; asking for that chain alone returns 502.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "Estimate payout gas costs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only this chain, e.g. BSC",
+                        "name": "chain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of payouts to price",
+                        "name": "transfers",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.payoutEstimateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/payouts/retry": {
+            "post": {
+                "description": "Sends or proposes again a QUEUED payout held after running out of attempts under the payout retry policy, with its attempts counted from zero. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retry a held payout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payout ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.payoutRecord"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/privacy/erasure": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Pseudonymizes the customer's wallet address and removes email and metadata from every matching order within the authenticated merchant (admins: all merchants), pseudonymizes the sender of the overpayments of those orders or sent from the customer's wallets and of the transfers from those wallets to watched addresses, and deletes the matching customer profiles. Amounts, statuses, tx hashes and ledger entries are kept. An erased overpayment can no longer be refunded, and the erasure fails with 409 while one of them is being refunded. The erasure is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Erase a customer's data",
+                "parameters": [
+                    {
+                        "description": "Customer to erase",
+                        "name": "subject",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.privacySubject"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.privacyErasureResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/privacy/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns every order (with refunds) tied to the given customer wallet and/or email, the overpayments of those orders or sent from the customer's wallets, and the transfers from those wallets to watched addresses, within the authenticated merchant. Admins see all merchants. The export is recorded in the audit log.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Export a customer's data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Customer wallet address",
+                        "name": "customer_wallet_address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Customer email",
+                        "name": "customer_email",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.privacyExportResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rates/history": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the rates /rates served the merchant, newest first, so the conversion behind a fiat-priced order can be checked after the fact: base and quote narrow it to a pair, from and to (RFC 3339) bound when they were served to [from, to), and limit caps the list (default 100, max 1000). A quote is kept as served, with the provider, its source and when the provider last updated it. Admins see every merchant's quotes, or one merchant's with merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "List rates served",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Asset or currency priced, e.g. USDT",
+                        "name": "base",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency the price is in, e.g. USD",
+                        "name": "quote",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Served at or after, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Served before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum quotes (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.rateHistoryResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliation/onchain": {
+            "get": {
+                "description": "Compares, per chain and asset, what the ledger says the custody wallets hold with their balances on-chain, read now through the chain's RPC endpoint. The books are the net of the custody buckets (merchant, settlement, dispute_hold, balance_hold, offramp_pending, conversion and platform_fee, across merchants) less payouts SENT or EXECUTED and fiat payouts FUNDED, which have left the wallets. drift_minor is the on-chain total less the books, negative when the wallets hold less; drifting is set beyond RECONCILE_TOLERANCE_BPS of the books, and a chain and asset that starts drifting is alerted once (reconciliation.drift). The custody wallets are RECONCILE_WALLETS, or the hot wallet. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reconcile the books with the chain",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.onchainReconResp"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/refunds/approve": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Executes a REQUESTED refund. Must be called with the admin key or a merchant credential holding refunds:approve of a different origin than the one that requested it: the merchant's primary key and the scoped API keys it created count as one, while each organization member's key and each OAuth grant is its own.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Approve a requested refund",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Refund ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.refundResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/refunds/reject": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Rejects a REQUESTED refund, releasing its reserved amount. Same credential rules as approval.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Reject a requested refund",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Refund ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.refundResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retention/run": {
+            "post": {
+                "description": "Archives terminal orders, refunds and ledger rows past RETENTION_MONTHS and prunes delivered outbox events past OUTBOX_RETENTION_DAYS, into outbox_events_archive with OUTBOX_ARCHIVE=on. Archived ledger rows are replaced by BALANCE_CARRIED entries, so balances do not change. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Run the retention job now",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.retentionResult"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retry-policies": {
+            "get": {
+                "description": "Returns the retry policy of each kind of background work: verification (payment_verification jobs), webhook (webhook deliveries) and payout (sending or proposing queued payouts). A failed attempt is retried after base_delay, doubling up to max_delay, with up to jitter of the delay added at random, until max_attempts. Work that runs out of attempts is kept with dead_letter keep (DEAD jobs, DEAD_LETTER events, payouts held until retried) or given up with discard (jobs deleted, events DISCARDED, payouts FAILED). source says whether the policy is the default, from RETRY_POLICY_\u003cTYPE\u003e or set through the admin API. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List retry policies",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.retryPolicyResp"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retry-policies/reset": {
+            "post": {
+                "description": "Drops the policy set through the admin API for verification, webhook or payout work, going back to RETRY_POLICY_\u003cTYPE\u003e or the default. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset a retry policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Type: verification, webhook or payout",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.retryPolicyResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retry-policies/update": {
+            "post": {
+                "description": "Sets the retry policy of verification, webhook or payout work. Fields left out keep their current value. The policy is stored in the database, takes precedence over RETRY_POLICY_\u003cTYPE\u003e, applies from the next failed attempt on and reaches every instance within 30 seconds. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Change a retry policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Type: verification, webhook or payout",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Settings to change",
+                        "name": "policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.retryPolicyUpdateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.retryPolicyResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/schedulers": {
+            "get": {
+                "description": "Lists the background schedulers started by this instance with their schedule and next run, whether they are paused or running, and the outcome of their last run: when it started, how long it took, the rows it processed and its error. skipped counts the runs this instance left out because another instance was running the scheduler. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List background schedulers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.schedulerStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/schedulers/pause": {
+            "post": {
+                "description": "Stops a scheduler's runs until it is resumed; a run in progress finishes. The paused state lasts until the process restarts. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Pause a scheduler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduler name",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.schedulerStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/schedulers/resume": {
+            "post": {
+                "description": "Lets a paused scheduler run again on its interval. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Resume a scheduler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduler name",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.schedulerStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/schedulers/run": {
+            "post": {
+                "description": "Starts a run of the scheduler now, even when it is paused, and returns without waiting for it; poll /admin/schedulers for the outcome. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Run a scheduler now",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduler name",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.schedulerStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/settlements": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the merchant's settlement batches, newest first, each itemized into the gross volume of its orders, the application fees withheld, the completed refunds and lost disputes netted, what was clawed back against a negative balance or withheld for balance holds, and the net payout (total_amount_minor, what the payout sends). Releasing a hold settles what it withheld in a batch of its own (hold_released_minor). Batches settled before itemization was stored only carry the net. from and to (RFC 3339) bound settled_at to [from, to), asset narrows the list. With format=csv the list is returned as a statement, one line per batch, for accounting. Admins pass merchant_id, or leave it out for every merchant.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "List settlement batches",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Earliest settled_at, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Settled before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asset symbol",
+                        "name": "asset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.settlementRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/settlements/run": {
+            "post": {
+                "description": "Settles every PAID or PARTIALLY_REFUNDED order immediately instead of waiting for the scheduler's delay, optionally for one merchant. Orders with pending refunds or open disputes are skipped as usual. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Settle paid orders now",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only settle this merchant",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.settlementBatch"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/stats/timeseries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Buckets a metric by hour or day over [from, to): orders_created (count), paid_volume (amount paid, by paid_at; needs asset), discounts (coupon discounts given on those payments; needs asset), refunds (amount refunded by completed refunds; needs asset) and conversion_rate (paid share of the orders created in the bucket). Buckets start at hour or midnight boundaries in the merchant's timezone and every bucket in the range is returned, zero-filled. from defaults to 24 hours (hour) or 30 days (day) before to, which defaults to now; a series has at most 744 hourly or 366 daily points. Admins pass merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get a metric as a time series",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "orders_created, paid_volume, discounts, refunds or conversion_rate",
+                        "name": "metric",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "hour or day (default hour)",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asset symbol; required for paid_volume, discounts and refunds",
+                        "name": "asset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range start, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.timeseriesResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/sweeps": {
+            "get": {
+                "description": "Returns the sweeps of hot wallet balances to the cold wallet, newest first, optionally filtered by status (PENDING_APPROVAL, QUEUED, SENT, EXECUTED, REJECTED, FAILED). A sweep is started when the hot wallet's balance of a token goes over its COLD_SWEEP_LIMITS limit and moves the excess over what the limit keeps; with COLD_SWEEP_APPROVAL on it waits in PENDING_APPROVAL for POST /admin/sweeps/approve. Executed sweeps are booked on the platform account, from hot_wallet to cold_storage. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List cold sweeps",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.coldSweep"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/sweeps/approve": {
+            "post": {
+                "description": "Sends a PENDING_APPROVAL sweep to the cold wallet now. The amount is the one computed when the sweep was started; the hot wallet's balance is not read again. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Approve a cold sweep",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sweep ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.coldSweep"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/sweeps/reject": {
+            "post": {
+                "description": "Rejects a PENDING_APPROVAL sweep, which is not sent. A new sweep is started on the next run while the hot wallet is still over the limit. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reject a cold sweep",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sweep ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.coldSweep"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transactions": {
+            "get": {
+                "description": "Returns the most recent transactions sent by the hot wallet (newest first), optionally filtered by status (PENDING, CANCELLING, CONFIRMED, FAILED, CANCELLED, DROPPED) and chain, with their fees, any replaced predecessors and which hash was mined. stuck marks transactions pending for over three times their profile's wait. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List outgoing transactions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chain",
+                        "name": "chain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.chainTx"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transactions/bump": {
+            "post": {
+                "description": "Re-signs a PENDING (or CANCELLING) outgoing transaction with the same nonce and fees from the given urgency profile (slow, standard, fast), raised at least 12% over the current ones so nodes accept the replacement, and broadcasts it. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replace a stuck transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Urgency",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.chainTxBumpReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.chainTx"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transactions/cancel": {
+            "post": {
+                "description": "Sends a zero-value transfer from the hot wallet to itself with the transaction's nonce and higher fees, so the original is dropped once it is mined. The transaction is CANCELLING until one of the two is mined, then CANCELLED or, if the original won, CONFIRMED. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Cancel a pending transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Urgency",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.chainTxBumpReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.chainTx"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/attestations": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the signed reserve attestations, newest first. Each carries the report that was signed, per chain and asset what merchants are owed next to what the custody wallets hold, with its signature and signer; see POST /admin/attestations/generate. fully_backed narrows the list to attestations where every asset was (true) or was not (false) fully backed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "List reserve attestations",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only attestations that were (not) fully backed",
+                        "name": "fully_backed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 30, max 365)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.reserveAttestation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/attestations/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a signed reserve attestation. With format=csv the report is returned as CSV instead, one line per chain and asset, for spreadsheets; the signature, which covers the JSON report, is in the X-Attestation-Signature and X-Attestation-Signer headers.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Get a reserve attestation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Attestation ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.reserveAttestation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/conversions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the most recent conversions (newest first), optionally filtered by status (QUEUED, SUBMITTED, COMPLETED, FAILED) or batch_id. A conversion is queued at settlement when the merchant's settlement_asset or settlement_chain differs from what was received; quoted_out_minor and min_out_minor are the provider's quote, min_out_minor being the floor set by max_slippage_bps. Admins see every merchant's, or one with merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "List settlement conversions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Settlement batch",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.conversionRecord"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/coupons": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST creates a discount code customers can redeem at order creation (coupon_code): type percent takes percent_off (1-100) percent off the amount, type fixed takes amount_off_minor off orders in asset. max_redemptions limits how many orders may use it and expires_at when it stops being accepted. Codes are unique per merchant, ignoring case. GET lists the merchant's coupons with their redemption counts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "coupons"
+                ],
+                "summary": "Create or list coupons",
+                "parameters": [
+                    {
+                        "description": "Coupon (POST only)",
+                        "name": "coupon",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.couponCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.coupon"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.coupon"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST creates a discount code customers can redeem at order creation (coupon_code): type percent takes percent_off (1-100) percent off the amount, type fixed takes amount_off_minor off orders in asset. max_redemptions limits how many orders may use it and expires_at when it stops being accepted. Codes are unique per merchant, ignoring case. GET lists the merchant's coupons with their redemption counts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "coupons"
+                ],
+                "summary": "Create or list coupons",
+                "parameters": [
+                    {
+                        "description": "Coupon (POST only)",
+                        "name": "coupon",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.couponCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.coupon"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.coupon"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/coupons/delete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a coupon so its code can no longer be redeemed (and may be reused). Orders that redeemed it keep their coupon_code and discount.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "coupons"
+                ],
+                "summary": "Delete a coupon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Coupon ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/coupons/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns one of the merchant's coupons with its redemption count.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "coupons"
+                ],
+                "summary": "Get a coupon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Coupon ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.coupon"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/coupons/update": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Changes a coupon's redemption limit (0 removes it), expiry (\"\" removes it) or whether it is active; inactive coupons are refused at order creation. The code and discount cannot change, as orders keep the discount they were given.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "coupons"
+                ],
+                "summary": "Update a coupon",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Coupon ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "coupon",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.couponUpdateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.coupon"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the merchant's returning-customer profiles, most recently paying first. Profiles are created from verified payments whose payer wallet is known; wallet_address looks one up.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "List customers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only the customer with this wallet",
+                        "name": "wallet_address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.customerListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a customer profile, the total paid per asset and up to 200 of their credited payments, newest first. Archived orders are not included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get a customer with payment history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.customerDetailResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/debug/metrics": {
+            "get": {
+                "description": "Returns operational metrics. orders_created_total, payments_detected_total and refunds_processed_total are persisted: they survive restarts and add up across instances. The other counters are the instance's own since it started. db_busy_retries_total counts statements tried again because the database was locked by another writer, db_busy_errors_total those that failed locked all the same; db_wal_size_bytes is the size of the SQLite write-ahead log and db_pool_waits_total how often a query waited for a free connection.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "debug"
+                ],
+                "summary": "Get debug metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer",
+                                "format": "int64"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/disputes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST (admin only) opens a dispute against a PAID, PARTIALLY_REFUNDED or SETTLED order and freezes the disputed amount of the merchant balance. GET lists disputes with their evidence, optionally filtered by order_id and status; merchants only see their own.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "disputes"
+                ],
+                "summary": "Open or list disputes",
+                "parameters": [
+                    {
+                        "description": "Dispute info (POST only)",
+                        "name": "dispute",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeCreateReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID (GET only)",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OPEN, WON or LOST (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.disputeRecord"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/disputes/evidence": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Attaches an evidence note to an open dispute. Available to the merchant and to admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "disputes"
+                ],
+                "summary": "Add dispute evidence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dispute ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Evidence note",
+                        "name": "evidence",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeEvidenceReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.disputeEvidence"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/dead-letter": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the authenticated merchant's events that were given up on after 10 failed delivery attempts, newest first, with the attempt count and the last error. They stay dead-lettered until requeued with POST /events/dead-letter/requeue.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List dead-lettered webhook events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.deadLetterListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/dead-letter/requeue": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Puts the given dead-lettered events (event_ids), or all of them with \"all\": true, back in the delivery queue with a fresh set of attempts, oldest first, up to 1000 per request. Unlike a replay the events keep their id. IDs that are not dead-lettered events of the merchant are ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Requeue dead-lettered webhook events",
+                "parameters": [
+                    {
+                        "description": "event_ids or all",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.deadLetterRequeueReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.deadLetterRequeueResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/payment-detected": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Notify the system of an on-chain payment for an order. The payment is verified in the background and the report answered with 202; when too many payments are already waiting for verification, the report is refused with 503 and Retry-After, and should be sent again after that many seconds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Detect payment event",
+                "parameters": [
+                    {
+                        "description": "Payment info",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentDetectedReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentDetectedResp"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentDetectedResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/replay": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Queues every event already sent (or skipped or failed) for an order, a time window, or both for delivery again, e.g. after the receiver was down. Each copy gets a new id and carries \"replay_of\" with the original event id; subscriptions apply as for new events. At most 1000 events per request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Replay webhook events",
+                "parameters": [
+                    {
+                        "description": "order_id and/or from, to",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.eventReplayReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.eventReplayResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/types": {
+            "get": {
+                "description": "Returns every event type with its payload version and a JSON Schema (draft 2020-12) of its data, generated from the types the server encodes, plus the schema of the delivery envelope. A version changes only when a payload changes incompatibly.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook event types",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.eventCatalogResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/chains": {
+            "get": {
+                "description": "Checks every chain with an RPC endpoint: whether it answers, the latest block height and time, how far that block trails the clock against the chain's expected block time, and the provider (host) in use. A chain is lagging when its head is older than 20 block times (at least 30 seconds). Responds 503 when any chain is unreachable or lagging.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get chain RPC health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.chainHealthResp"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.chainHealthResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/holds": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST (admin key or platform API key) holds amount_minor of a merchant's unsettled balance of asset on chain, for reason, until it is released or expires_at passes. The amount moves from the merchant to the balance_hold ledger bucket (counted as held in the balances) and settlements keep back what the merchant balance then lacks to pay out their orders, so held funds are not paid out; withheld_minor is how much they kept back. The hold may not exceed the unsettled balance on the chain. Platforms can only hold the balances of their connected merchants. GET lists holds, newest first, filtered by status and (admins, platforms) merchant_id; merchants see the holds on their own balance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Place or list balance holds",
+                "parameters": [
+                    {
+                        "description": "Hold (POST only)",
+                        "name": "hold",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.placeHoldReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ACTIVE, RELEASED or EXPIRED (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (GET, admin and platforms only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.balanceHold"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.balanceHold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/ledger/export.ndjson": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams the merchant's ledger entries as newline-delimited JSON, one entry per line, with the transaction group linking the legs of its double entry, oldest first (by created_at, then id), for loading into a data warehouse. Entries are read and written one at a time, so exports of any size take no memory on the server and go as fast as the client reads. from and to (RFC 3339) bound created_at to [from, to); asset and event_type narrow the export further. Entries moved out by the retention job are represented by their BALANCE_CARRIED entries. To resume an interrupted export, pass the created_at of the last line received as from and skip the lines already received. An export that fails midway is cut off without the final chunk, so it cannot be mistaken for a complete one. Admins pass merchant_id, or leave it out for every merchant.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Export ledger entries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Earliest created_at, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asset symbol",
+                        "name": "asset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Event type, e.g. PAYMENT_CONFIRMED",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ledgerExportLine"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/ledger/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the merchant's ledger entries grouped into transactions, newest first: each transaction holds the legs of one double entry (a payment with its application fee leg, a refund, a settlement of a batch, ...), which net to zero on each asset and chain. from and to (RFC 3339) bound created_at to [from, to); event_type, order_id and reference_id (the refund, batch, conversion, ... that booked it) narrow the list, limit (default 100, at most 500) bounds the transactions returned. Entries moved out by the retention job are not listed. Admins pass merchant_id, or leave it out for every merchant.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "List ledger transactions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Earliest created_at, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Event type, e.g. PAYMENT_CONFIRMED",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Refund, batch, conversion, ... that booked the transaction",
+                        "name": "reference_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Transactions to return, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.ledgerTransaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/ledger/transactions/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the legs of one ledger transaction group, as linked by the transaction_group_id of ledger entries and exports.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Get a ledger transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction group ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ledgerTransaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants": {
+            "post": {
+                "description": "Creates a new merchant and returns the merchant ID and API key. merchant_wallet_address must be an EVM (0x..., EIP-55 checksummed when in mixed case), Tron, Solana or Bitcoin address; EVM addresses are stored and returned checksummed. Orders can only be created on chains the wallet's address format belongs to. An ENS name (e.g. shop.eth) is resolved through ETH_RPC_URL; the response carries the resolved address and wallet_ens_name, and the name is re-resolved periodically, moving the wallet when the name is pointed elsewhere (merchant.wallet_changed). With MERCHANT_APPROVAL_REQUIRED on, the merchant is created PENDING_APPROVAL and its API key is refused with 403 merchant_pending_approval, apart from /merchants/status and the webhook settings, until an administrator approves it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Create a new merchant",
+                "parameters": [
+                    {
+                        "description": "Merchant info",
+                        "name": "merchant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST creates an additional merchant API key limited to the given scopes, e.g. a read-only key for a reporting job; GET lists them. Requires the primary API key. A scoped key counts as the primary key for refund approval: whoever holds the primary key can create any scoped key, so a second approver needs a credential of their own (an organization member's key, an OAuth grant or the admin key).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Create or list scoped API keys",
+                "parameters": [
+                    {
+                        "description": "Key info (POST only)",
+                        "name": "key",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.apiKeyCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.apiKeyInfo"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.apiKeyCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST creates an additional merchant API key limited to the given scopes, e.g. a read-only key for a reporting job; GET lists them. Requires the primary API key. A scoped key counts as the primary key for refund approval: whoever holds the primary key can create any scoped key, so a second approver needs a credential of their own (an organization member's key, an OAuth grant or the admin key).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Create or list scoped API keys",
+                "parameters": [
+                    {
+                        "description": "Key info (POST only)",
+                        "name": "key",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.apiKeyCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.apiKeyInfo"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.apiKeyCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/api-keys/revoke": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revokes one of the merchant's scoped API keys. Requires the primary API key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Revoke a scoped API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/balances": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the merchant's balance per asset and chain, read from the materialized ledger balances: available (settled funds, the settlement bucket), pending (the merchant's share of PAID and PARTIALLY_REFUNDED orders not yet settled, the merchant bucket), held (frozen by open disputes or balance holds, or reserved for fiat payouts) and refundable (overpayments of already paid orders, to send back to customers with POST /overpayments/{id}/refund; not the merchant's funds). Admins pass merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get merchant balances",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.balancesResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/me/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the merchant's API keys, its primary key (id \"primary\") first, with when each was last used, from which IP, and how many requests it made, along with the IPs it was used from most recently (at most 10). A key used from an unexpected IP may have leaked; one not used for long may be forgotten and is better revoked. Counts are kept from when usage tracking was introduced and may trail by some seconds. Requires the primary API key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "List API keys with their usage",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.apiKeyUsage"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/settings": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "refund_approval_required makes every refund wait for approval by a second credential. Merchants may turn it on with their primary API key; turning it off, and changing velocity limits, requires the admin key (use /admin/merchants/settings?merchant_id=). late_payment_review holds payments that arrive after an order expired (within the grace window) for review instead of crediting them. payout_mode makes settlements pay out on-chain to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes them as transactions of the multisig at payout_safe_address for its owners to execute; \"\" keeps settlements ledger only. Payout settings require the admin key. settlement_asset and settlement_chain convert settled funds received in another asset or on another chain (e.g. USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default 50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its customer and bank account at the off-ramp partner for fiat payouts (admin key only); kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g. Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles orders by local calendar day: the orders paid on a day settle after local midnight plus the settlement delay. accepted_assets (e.g. [{\"asset\":\"USDT\",\"chain\":\"BSC\"}]) limits new orders to those asset and chain pairs, each a token known on its chain, and orders in any other are rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of each settlement withheld while the merchant's balance on the chain is negative, e.g. after refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the deficit to be netted by new payments only); admin key only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get or update merchant settings",
+                "parameters": [
+                    {
+                        "description": "Settings to change (POST only)",
+                        "name": "settings",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantSettings"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "refund_approval_required makes every refund wait for approval by a second credential. Merchants may turn it on with their primary API key; turning it off, and changing velocity limits, requires the admin key (use /admin/merchants/settings?merchant_id=). late_payment_review holds payments that arrive after an order expired (within the grace window) for review instead of crediting them. payout_mode makes settlements pay out on-chain to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes them as transactions of the multisig at payout_safe_address for its owners to execute; \"\" keeps settlements ledger only. Payout settings require the admin key. settlement_asset and settlement_chain convert settled funds received in another asset or on another chain (e.g. USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default 50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its customer and bank account at the off-ramp partner for fiat payouts (admin key only); kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g. Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles orders by local calendar day: the orders paid on a day settle after local midnight plus the settlement delay. accepted_assets (e.g. [{\"asset\":\"USDT\",\"chain\":\"BSC\"}]) limits new orders to those asset and chain pairs, each a token known on its chain, and orders in any other are rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of each settlement withheld while the merchant's balance on the chain is negative, e.g. after refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the deficit to be netted by new payments only); admin key only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get or update merchant settings",
+                "parameters": [
+                    {
+                        "description": "Settings to change (POST only)",
+                        "name": "settings",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantSettings"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/status": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the merchant and its status: ACTIVE, PENDING_APPROVAL while compliance mode (MERCHANT_APPROVAL_REQUIRED) holds it for an administrator, or REJECTED with the reason. A pending merchant's API key only works here and for the webhook settings; the decision is also sent as a merchant.approved or merchant.rejected webhook.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get the merchant's approval status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.merchantRecord"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/wallet": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the payout wallet, its ENS name if it was given as one, and whether the merchant has proved control of it (POST /merchants/wallet/challenge, then /merchants/wallet/verify). While proof_required is set, payouts to an unverified wallet wait in QUEUED.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Get the merchant wallet and its verification",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.walletStatus"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/wallet/challenge": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Issues a single-use challenge for the merchant's current payout wallet, valid for 15 minutes. Sign message with personal_sign (EIP-191), or typed_data with eth_signTypedData_v4 (EIP-712), from the wallet and send the signature to /merchants/wallet/verify. Only EVM wallets can be verified. Primary API key only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Issue a wallet ownership challenge",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.walletChallenge"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/merchants/wallet/verify": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Checks a signature of a challenge from /merchants/wallet/challenge, as an EIP-191 personal message or as EIP-712 typed data. When it was made by the wallet's key the wallet is verified and payouts held for it go out. The challenge is used up; it fails with 409 once expired, used, or when the wallet changed since it was issued. Primary API key only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "merchants"
+                ],
+                "summary": "Prove control of the merchant wallet",
+                "parameters": [
+                    {
+                        "description": "Challenge and signature",
+                        "name": "proof",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.walletVerifyReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.walletStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Prometheus text exposition of the request metrics — ospay_http_requests_total by route, merchant and status class (code=\"2xx\" etc.) and ospay_http_request_duration_seconds histograms by route and status class — of the background jobs — ospay_jobs by type and status and ospay_job_attempts_total by type and outcome — of database transactions — ospay_db_transaction_duration_seconds histograms by outcome — and of the counters and gauges of /debug/metrics, prefixed with ospay_.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "debug"
+                ],
+                "summary": "Get Prometheus metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/negative-balances": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the times the merchant's balance of an asset on a chain went below zero, newest first: OPEN while it still owes amount_minor, RECOVERED once new payments or clawbacks from settlements brought it back. A balance goes negative when more is taken from it than it holds, e.g. by the refund of an order that was already settled. The tracker checks the balances every NEGATIVE_BALANCE_CHECK_INTERVAL and raises balance.negative and balance.recovered. status narrows the list. Admins pass merchant_id, or leave it out for every merchant.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "List negative balances",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "OPEN or RECOVERED",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.negativeBalance"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/authorize": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Called by the merchant (with their primary API key) to grant a platform client the requested scopes. Scoped keys, OAuth tokens and organization member keys are refused. Returns a short-lived authorization code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "Authorize an OAuth client",
+                "parameters": [
+                    {
+                        "description": "Authorization request",
+                        "name": "authorization",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.oauthAuthorizeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.oauthAuthorizeResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/clients": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Registers an OAuth2 client for the calling platform. The client secret is only returned once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "Register an OAuth client",
+                "parameters": [
+                    {
+                        "description": "Client info",
+                        "name": "client",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.oauthClientCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.oauthClientCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/revoke": {
+            "post": {
+                "description": "RFC 7009 revocation. Revoking a refresh token also revokes every access token from the same grant. Unknown tokens are accepted silently.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "Revoke an OAuth token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Access or refresh token",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client ID (if not using Basic auth)",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret (if not using Basic auth)",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/token": {
+            "post": {
+                "description": "RFC 6749 token endpoint. Supports grant_type=authorization_code and grant_type=refresh_token; refresh tokens are rotated on use. Client credentials go in HTTP Basic auth or the form body.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "Issue or refresh OAuth tokens",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization_code or refresh_token",
+                        "name": "grant_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Redirect URI sent to /oauth/authorize; required, and identical, if one was sent there",
+                        "name": "redirect_uri",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Refresh token",
+                        "name": "refresh_token",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client ID (if not using Basic auth)",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret (if not using Basic auth)",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.oauthTokenResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/offramp/kyc": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the merchant's KYC status at the off-ramp partner (NOT_STARTED, PENDING, APPROVED or REJECTED), fetched fresh from the partner. KYC itself happens with the partner; an administrator links the merchant to the partner's customer and bank account IDs in the merchant settings (offramp_customer_id, offramp_bank_account_id). Admins pass merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "Show off-ramp KYC status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.kycResp"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/offramp/payouts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST converts part of the merchant's settled balance of asset into a bank payout through the off-ramp partner; it needs the primary API key and approved KYC. The amount is reserved at once (ledger offramp_pending); the hot wallet then sends the crypto on chain to the partner's deposit address, and the payout becomes PAID once the partner has paid the bank, with the fiat amount and rate it applied. The settled balance is settlement batches and conversions into the asset less what was paid out or converted since. GET lists fiat payouts, newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "Request or list fiat payouts",
+                "parameters": [
+                    {
+                        "description": "Payout (POST only)",
+                        "name": "payout",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.fiatPayoutReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status filter (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.fiatPayoutRecord"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.fiatPayoutRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST converts part of the merchant's settled balance of asset into a bank payout through the off-ramp partner; it needs the primary API key and approved KYC. The amount is reserved at once (ledger offramp_pending); the hot wallet then sends the crypto on chain to the partner's deposit address, and the payout becomes PAID once the partner has paid the bank, with the fiat amount and rate it applied. The settled balance is settlement batches and conversions into the asset less what was paid out or converted since. GET lists fiat payouts, newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "Request or list fiat payouts",
+                "parameters": [
+                    {
+                        "description": "Payout (POST only)",
+                        "name": "payout",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.fiatPayoutReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status filter (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.fiatPayoutRecord"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.fiatPayoutRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a new payment order for a merchant\nCreates a new payment order for a merchant",
+                "consumes": [
+                    "application/json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/json"
+                ],
+                "tags": [
+                    "orders",
+                    "orders"
+                ],
+                "summary": "Create a new order",
+                "parameters": [
+                    {
+                        "description": "Order info",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.orderCreateReq"
+                        }
+                    },
+                    {
+                        "description": "Order info",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.orderCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/batch-get": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns up to 100 of the authenticated merchant's orders by ID in one request, for integrations syncing order status. Orders come in the order of order_ids, archived ones included; IDs given twice are returned once, and IDs that match no order of the merchant are listed in not_found.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get orders by ID",
+                "parameters": [
+                    {
+                        "description": "Order IDs",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.batchGetOrdersReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.batchGetOrdersResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/delete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Soft-deletes an order that never received a payment, e.g. one created by mistake: it is no longer returned by any order read, list or search, a payment reported for it is refused as for an unknown order, and it does not expire or count toward limits. Orders with a payment stay on the books, since their ledger entries cannot be undone; refund them instead (409 order_has_payment). So do MISPAID orders and orders with a payment still being verified. The idempotency key stays used. Only an administrator can restore a deleted order, with POST /admin/orders/{id}/restore.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Delete an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional reason, for the audit log",
+                        "name": "delete",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.softDeleteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/extend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Pushes out expires_at of a PENDING order by minutes (default 30), counted from now if that is later than the current expiry, so a checkout stays open while the customer is still paying. An order cannot be extended past 24 hours after it was created.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Extend a pending order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Extension",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderExtendReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderExtendResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns order details for a given order ID. The response carries an ETag computed from the response body, so it changes whenever any field of the order does; pollers sending it back in If-None-Match get 304 Not Modified until then.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderGetResp"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/list": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the authenticated merchant's orders, newest first, optionally filtered by status and tag. Archived orders are not listed but stay readable via /orders/get.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "List orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order status, e.g. PAID",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only orders carrying this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/notes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds an internal note (body, at most 4000 characters) to an order, recording the credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible to the merchant's credentials and operators; they are not included in order responses or webhooks.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Add or list order notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Note (POST only)",
+                        "name": "note",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderNoteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.orderNote"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderNote"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds an internal note (body, at most 4000 characters) to an order, recording the credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible to the merchant's credentials and operators; they are not included in order responses or webhooks.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Add or list order notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Note (POST only)",
+                        "name": "note",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderNoteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.orderNote"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderNote"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/refund": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Records a full or partial refund of a paid order. Multiple partial refunds are allowed up to the remaining refundable amount; each needs its own refund_idempotency_key. If the merchant refunded on-chain, refundtxhash and amount_minor must be provided and the transfer to the customer wallet is verified first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Refund an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Refund info",
+                        "name": "refund",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.refundReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.refundResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/refunds": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns every refund recorded against an order, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "List refunds for an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.refundRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Finds orders from whatever a customer can tell support: external_order_id, tx_hash, customer_email (the exact address), metadata values, given as metadata.\u003ckey\u003e=\u003cvalue\u003e for top-level metadata keys (compared as text, so metadata.qty=2 matches 2 and \"2\"), and tag. Every given criterion must match; at least one is required. Archived orders are included. Results are newest first, at most limit (default 50, max 200). Admins may pass merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Search orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The merchant's order reference",
+                        "name": "external_order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment transaction hash",
+                        "name": "tx_hash",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Customer email",
+                        "name": "customer_email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value of metadata key 'key'; repeat with other keys",
+                        "name": "metadata.key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag the order carries",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum results (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/tags": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64 characters, trimmed and compared in lower case; tags the order already has are ignored and an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags stay on the order when it is archived.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Add or list order tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to add (POST only)",
+                        "name": "tags",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64 characters, trimmed and compared in lower case; tags the order already has are ignored and an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags stay on the order when it is archived.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Add or list order tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to add (POST only)",
+                        "name": "tags",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/tags/remove": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes tags from an order; tags it does not carry are ignored. Returns the tags left.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Remove order tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to remove",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderTagsResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/timeline": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns what happened to an order, oldest first: its creation, the webhook events raised for it and its refunds and disputes (whether or not the merchant subscribes to them), every transaction hash reported as its payment with the outcome of its verification (failure_reason wrong_amount, wrong_token, wrong_recipient, no_transfer, tx_not_found, order_expired, already_paid or verification_error), and the team's notes. Events pruned by OUTBOX_RETENTION_DAYS no longer appear.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get an order's timeline",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.timelineEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizations": {
+            "post": {
+                "description": "Creates an organization to own several merchants, with owner_email as its owner. The response carries the owner's API key, which is not shown again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Create an organization",
+                "parameters": [
+                    {
+                        "description": "Organization info",
+                        "name": "organization",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.orgCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.orgCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizations/balances": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns each merchant's balances, as GET /merchants/balances does, and their totals per asset and chain.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Get the organization's balances",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orgBalancesResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizations/members": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds a member with its own API key, returned once: admins manage members and merchants and have full access to the merchants, viewers read orders and balances. GET lists the members. Adding members needs an owner or admin key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Add or list organization members",
+                "parameters": [
+                    {
+                        "description": "Member (POST only)",
+                        "name": "member",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orgMemberReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.orgMember"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.orgMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST adds a member with its own API key, returned once: admins manage members and merchants and have full access to the merchants, viewers read orders and balances. GET lists the members. Adding members needs an owner or admin key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Add or list organization members",
+                "parameters": [
+                    {
+                        "description": "Member (POST only)",
+                        "name": "member",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.orgMemberReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.orgMember"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.orgMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizations/members/remove": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes a member; its API key stops working at once. The owner cannot be removed. Needs an owner or admin key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Remove an organization member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Member ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizations/merchants": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST creates a merchant owned by the organization, like POST /merchants (the merchant's own API key is returned once, and the merchant may wait for approval); it needs an owner or admin key. GET lists the organization's merchants.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Create or list an organization's merchants",
+                "parameters": [
+                    {
+                        "description": "Merchant info (POST only)",
+                        "name": "merchant",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.orgMerchant"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST creates a merchant owned by the organization, like POST /merchants (the merchant's own API key is returned once, and the merchant may wait for approval); it needs an owner or admin key. GET lists the organization's merchants.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Create or list an organization's merchants",
+                "parameters": [
+                    {
+                        "description": "Merchant info (POST only)",
+                        "name": "merchant",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.orgMerchant"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizations/merchants/attach": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Moves a merchant that is not in an organization yet into this one; the merchant's primary API key proves it may. The merchant's own keys keep working. Needs an owner or admin key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Add an existing merchant to the organization",
+                "parameters": [
+                    {
+                        "description": "The merchant's primary API key",
+                        "name": "merchant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.orgAttachReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orgMerchant"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizations/merchants/detach": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Takes a merchant out of the organization; the merchant keeps its account and its own keys, but members no longer act for it. Needs an owner or admin key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Remove a merchant from the organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/organizations/report": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sums, per merchant and asset and over all merchants per asset, the orders created in [from, to), the orders paid and the amount paid in it (by paid_at), and the completed refunds (by created_at). from defaults to 30 days before to, which defaults to now.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organizations"
+                ],
+                "summary": "Get the organization's sales report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Range start, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orgReportResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/overpayments": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the most recent overpayments (newest first), optionally filtered by status (CONFIRMING, REVIEW, OPEN, REFUND_QUEUED, REFUND_SENT, REFUNDED, REFUND_FAILED) or order_id. An overpayment is a further transfer of the order's asset to the deposit address reported for an order that is already PAID, SETTLED or (partially) refunded, e.g. a customer paying twice. It is credited to the refundable balance rather than the merchant's once its block is final (CONFIRMING until then), and sent back to the sender with POST /overpayments/{id}/refund. Admins see every merchant's, or one with merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "List overpayments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.overpaymentRecord"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/overpayments/refund": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sends an OPEN overpayment back to the address it came from, from the hot wallet; one in REVIEW, whose sender was flagged by screening, only on the admin route. The amount leaves the refundable balance at once and the overpayment is REFUND_QUEUED; it is REFUNDED, with refund_tx_hash, once the transfer is mined (overpayment.refunded). A transfer that fails leaves it REFUND_FAILED with last_error (overpayment.refund_failed); refunding it again sends it again. An overpayment whose sender was erased (see POST /privacy/erasure) cannot be refunded.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Refund an overpayment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Overpayment ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.overpaymentRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-intents": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST records that a customer committed to pay amount_minor of asset on chain, before any order exists: the amount can still be adjusted (POST /payment-intents/update) until the intent is captured, which creates the order the customer pays, or voided. The merchant wallet must be an address on chain, and coupon_code must be redeemable now; it is redeemed on capture. GET lists the merchant's intents, newest first, optionally filtered by status (REQUIRES_CAPTURE, CAPTURED, VOIDED), at most limit (default 50, max 200).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-intents"
+                ],
+                "summary": "Create or list payment intents",
+                "parameters": [
+                    {
+                        "description": "Payment intent (POST only)",
+                        "name": "intent",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntentCreateReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Intent status (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum results (GET only)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.paymentIntent"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST records that a customer committed to pay amount_minor of asset on chain, before any order exists: the amount can still be adjusted (POST /payment-intents/update) until the intent is captured, which creates the order the customer pays, or voided. The merchant wallet must be an address on chain, and coupon_code must be redeemable now; it is redeemed on capture. GET lists the merchant's intents, newest first, optionally filtered by status (REQUIRES_CAPTURE, CAPTURED, VOIDED), at most limit (default 50, max 200).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-intents"
+                ],
+                "summary": "Create or list payment intents",
+                "parameters": [
+                    {
+                        "description": "Payment intent (POST only)",
+                        "name": "intent",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntentCreateReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Intent status (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum results (GET only)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.paymentIntent"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-intents/capture": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Turns an intent into a PENDING order for amount_minor (default: the intent's amount; never more), carrying the intent's metadata, external_order_id and coupon, and returns both. From there the order is paid, credited to the ledger and settled like any other. Order limits, the coupon and the wallets are checked as at order creation. Capturing again after an interrupted capture finishes it with the same order.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-intents"
+                ],
+                "summary": "Capture a payment intent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment intent ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Amount and customer details",
+                        "name": "capture",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntentCaptureReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntentCaptureResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-intents/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns one of the merchant's payment intents; once captured, order_id is the order the customer pays.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-intents"
+                ],
+                "summary": "Get a payment intent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment intent ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntent"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-intents/update": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Changes the amount, metadata or external_order_id of an intent that is not yet captured or voided, e.g. after the customer changed the cart or shipping was added. The new amount_minor is what capture creates the order for.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-intents"
+                ],
+                "summary": "Adjust a payment intent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment intent ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "intent",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntentUpdateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-intents/void": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Cancels an intent that is not yet captured, e.g. when the customer abandons the checkout. An order left by an interrupted capture is expired, so it can no longer be paid.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-intents"
+                ],
+                "summary": "Void a payment intent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment intent ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason",
+                        "name": "void",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntentVoidReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.paymentIntent"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/payouts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the most recent payouts of settlement batches (newest first), optionally filtered by status (QUEUED, SENT, PROPOSED, EXECUTED, FAILED) or batch_id. A queued payout that fails to be sent or proposed is retried as the payout retry policy says (see /admin/retry-policies); attempts counts the failures, and dead_lettered_at is set on a payout held after running out of them. Payouts exist for merchants with a payout_mode: hot_wallet payouts are sent by OSPay, safe payouts are proposed to the merchant's Safe and stay PROPOSED until its owners execute them. Admins see every merchant's, or one with merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "List on-chain payouts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Settlement batch",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.payoutRecord"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/payouts/estimate": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns each chain's current gas price from its RPC endpoint and what the next payouts would cost: the fee of one token transfer, of sending them one by one, and of a single multi-send batch. transfers defaults to the number of settlement payouts currently due on the chain (merchant/asset pairs with paid, unsettled orders). A chain whose RPC endpoint cannot be reached is listed with an error; asking for that chain alone returns 502.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settlements"
+                ],
+                "summary": "Estimate payout gas costs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only this chain, e.g. BSC",
+                        "name": "chain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of payouts to price",
+                        "name": "transfers",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.payoutEstimateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/platforms": {
+            "post": {
+                "description": "Creates a marketplace platform that can onboard connected merchant accounts",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "platforms"
+                ],
+                "summary": "Create a new platform",
+                "parameters": [
+                    {
+                        "description": "Platform info",
+                        "name": "platform",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.platformCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.platformCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/platforms/balances": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns each connected merchant's ledger balance and the platform fees collected for an asset",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "platforms"
+                ],
+                "summary": "Get connected merchant balances",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Asset symbol",
+                        "name": "asset",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.platformBalancesResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/platforms/holds": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST holds part of a connected merchant's unsettled balance, like POST /admin/holds. GET lists the holds on the balances of the platform's connected merchants, newest first, filtered by status and merchant_id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "platforms"
+                ],
+                "summary": "Place or list connected merchant balance holds",
+                "parameters": [
+                    {
+                        "description": "Hold (POST only)",
+                        "name": "hold",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.placeHoldReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ACTIVE, RELEASED or EXPIRED (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (GET only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.balanceHold"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.balanceHold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST holds part of a connected merchant's unsettled balance, like POST /admin/holds. GET lists the holds on the balances of the platform's connected merchants, newest first, filtered by status and merchant_id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "platforms"
+                ],
+                "summary": "Place or list connected merchant balance holds",
+                "parameters": [
+                    {
+                        "description": "Hold (POST only)",
+                        "name": "hold",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.placeHoldReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ACTIVE, RELEASED or EXPIRED (GET only)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (GET only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.balanceHold"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.balanceHold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/platforms/holds/release": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Releases an ACTIVE hold the platform placed, like POST /admin/holds/release.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "platforms"
+                ],
+                "summary": "Release a connected merchant balance hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Release",
+                        "name": "release",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.releaseHoldReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.balanceHold"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/platforms/merchants": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST creates a merchant account connected to the calling platform; GET lists them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "platforms"
+                ],
+                "summary": "Create or list connected merchants",
+                "parameters": [
+                    {
+                        "description": "Merchant info (POST only)",
+                        "name": "merchant",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.connectedMerchant"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST creates a merchant account connected to the calling platform; GET lists them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "platforms"
+                ],
+                "summary": "Create or list connected merchants",
+                "parameters": [
+                    {
+                        "description": "Merchant info (POST only)",
+                        "name": "merchant",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.connectedMerchant"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.MerchantCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/platforms/orders": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a payment order on behalf of a connected merchant, optionally withholding an application fee for the platform",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "platforms"
+                ],
+                "summary": "Create an order for a connected merchant",
+                "parameters": [
+                    {
+                        "description": "Order info",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.platformOrderCreateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.orderCreateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/privacy/erasure": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Pseudonymizes the customer's wallet address and removes email and metadata from every matching order within the authenticated merchant (admins: all merchants), pseudonymizes the sender of the overpayments of those orders or sent from the customer's wallets and of the transfers from those wallets to watched addresses, and deletes the matching customer profiles. Amounts, statuses, tx hashes and ledger entries are kept. An erased overpayment can no longer be refunded, and the erasure fails with 409 while one of them is being refunded. The erasure is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Erase a customer's data",
+                "parameters": [
+                    {
+                        "description": "Customer to erase",
+                        "name": "subject",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.privacySubject"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.privacyErasureResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/privacy/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns every order (with refunds) tied to the given customer wallet and/or email, the overpayments of those orders or sent from the customer's wallets, and the transfers from those wallets to watched addresses, within the authenticated merchant. Admins see all merchants. The export is recorded in the audit log.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Export a customer's data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Customer wallet address",
+                        "name": "customer_wallet_address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Customer email",
+                        "name": "customer_email",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.privacyExportResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/rates": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the current rate of base in quote (e.g. base=USDT\u0026quote=USD) from the configured rate provider. Every rate served is recorded under its quote_id, which prices an order in fiat (rate_quote_id) and stays listed in /rates/history. With RATE_PROVIDER=chainlink rates are read from Chainlink price feed contracts over the chains' RPC endpoints; a feed whose latest answer is older than its staleness limit is refused with 503 stale_rate rather than served.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "Get an exchange rate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Asset or currency priced, e.g. USDT",
+                        "name": "base",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency the price is in, e.g. USD",
+                        "name": "quote",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.rateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/rates/history": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the rates /rates served the merchant, newest first, so the conversion behind a fiat-priced order can be checked after the fact: base and quote narrow it to a pair, from and to (RFC 3339) bound when they were served to [from, to), and limit caps the list (default 100, max 1000). A quote is kept as served, with the provider, its source and when the provider last updated it. Admins see every merchant's quotes, or one merchant's with merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "List rates served",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Asset or currency priced, e.g. USDT",
+                        "name": "base",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency the price is in, e.g. USD",
+                        "name": "quote",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Served at or after, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Served before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum quotes (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.rateHistoryResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reconciliation": {
+            "get": {
+                "description": "Returns balance and settlement data for a merchant and asset",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "Get reconciliation data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant ID",
+                        "name": "merchant_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asset symbol",
+                        "name": "asset",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/refunds/approve": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Executes a REQUESTED refund. Must be called with the admin key or a merchant credential holding refunds:approve of a different origin than the one that requested it: the merchant's primary key and the scoped API keys it created count as one, while each organization member's key and each OAuth grant is its own.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Approve a requested refund",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Refund ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.refundResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/refunds/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Queues a job refunding up to 1000 orders, each by amount_minor or, when omitted, its full remaining balance, and returns it with 202. The job runs in the background: each item is checked like a single refund, recorded (REQUESTED when the merchant requires approval) and its transfer to the customer wallet queued for the hot wallet. Items that cannot be refunded fail on their own with an error; follow the job with GET /refunds/bulk/get. A refund_idempotency_key already used on the order refunds it only once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Refund many orders",
+                "parameters": [
+                    {
+                        "description": "Orders to refund",
+                        "name": "job",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.bulkRefundReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.refundJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/refunds/bulk/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a bulk refund job with per-item outcomes: the refund recorded for each order, how far its on-chain transfer got (execution_status, refund_tx_hash), or why the item failed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get a bulk refund job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.refundJob"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/refunds/reject": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Rejects a REQUESTED refund, releasing its reserved amount. Same credential rules as approval.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Reject a requested refund",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Refund ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.refundResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/settlements": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the merchant's settlement batches, newest first, each itemized into the gross volume of its orders, the application fees withheld, the completed refunds and lost disputes netted, what was clawed back against a negative balance or withheld for balance holds, and the net payout (total_amount_minor, what the payout sends). Releasing a hold settles what it withheld in a batch of its own (hold_released_minor). Batches settled before itemization was stored only carry the net. from and to (RFC 3339) bound settled_at to [from, to), asset narrows the list. With format=csv the list is returned as a statement, one line per batch, for accounting. Admins pass merchant_id, or leave it out for every merchant.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "reconciliation"
+                ],
+                "summary": "List settlement batches",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Earliest settled_at, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Settled before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asset symbol",
+                        "name": "asset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.settlementRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/timeseries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Buckets a metric by hour or day over [from, to): orders_created (count), paid_volume (amount paid, by paid_at; needs asset), discounts (coupon discounts given on those payments; needs asset), refunds (amount refunded by completed refunds; needs asset) and conversion_rate (paid share of the orders created in the bucket). Buckets start at hour or midnight boundaries in the merchant's timezone and every bucket in the range is returned, zero-filled. from defaults to 24 hours (hour) or 30 days (day) before to, which defaults to now; a series has at most 744 hourly or 366 daily points. Admins pass merchant_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get a metric as a time series",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "orders_created, paid_volume, discounts, refunds or conversion_rate",
+                        "name": "metric",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "hour or day (default hour)",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asset symbol; required for paid_volume, discounts and refunds",
+                        "name": "asset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range start, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant ID (admin route only)",
+                        "name": "merchant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.timeseriesResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/problems": {
+            "get": {
+                "description": "Returns the catalog of error codes used in problem+json responses.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "meta"
+                ],
+                "summary": "List error codes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.problemCatalogEntry"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/problems/{code}": {
+            "get": {
+                "description": "Returns the catalog entry of one error code; the type URI of a problem+json response points here.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "meta"
+                ],
+                "summary": "Get an error code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Error code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.problemCatalogEntry"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/watch/addresses": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST registers an address for the watcher to report incoming token transfers to, independently of orders, e.g. one handed out on a legacy invoice. Transfers of the chain's known tokens (USDT, USDC) are reported once their block has the chain's finality depth, from the next scan on, with an address.transfer_received webhook each; GET /watch/addresses/transfers lists them. An address is watched once per merchant and chain; a merchant can watch up to 1000. GET lists the merchant's watched addresses, oldest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "watch"
+                ],
+                "summary": "Watch an address, or list watched addresses",
+                "parameters": [
+                    {
+                        "description": "Address (POST only)",
+                        "name": "address",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.watchAddressReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.watchedAddress"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.watchedAddress"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST registers an address for the watcher to report incoming token transfers to, independently of orders, e.g. one handed out on a legacy invoice. Transfers of the chain's known tokens (USDT, USDC) are reported once their block has the chain's finality depth, from the next scan on, with an address.transfer_received webhook each; GET /watch/addresses/transfers lists them. An address is watched once per merchant and chain; a merchant can watch up to 1000. GET lists the merchant's watched addresses, oldest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "watch"
+                ],
+                "summary": "Watch an address, or list watched addresses",
+                "parameters": [
+                    {
+                        "description": "Address (POST only)",
+                        "name": "address",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.watchAddressReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.watchedAddress"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.watchedAddress"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/watch/addresses/delete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops reporting transfers to a watched address. The transfers already reported stay listed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "watch"
+                ],
+                "summary": "Stop watching an address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Watched address ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/watch/addresses/transfers": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the token transfers the watcher found to a watched address, newest first (by block, then log index), including those found before the address was removed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "watch"
+                ],
+                "summary": "List transfers to a watched address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Watched address ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 100, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.addressTransfer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST sets the URL events are delivered to (an empty url disables delivery) and the event types to receive: order.paid, order.in_review, order.mispaid, order.failed, order.expired, order.settled, refund.requested, refund.completed, refund.rejected, dispute.opened, dispute.resolved, verification.failed, or [\"*\"] for all (the default). A signing secret is generated the first time a URL is set and only returned in that response. Requires the primary API key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get or set the webhook endpoint",
+                "parameters": [
+                    {
+                        "description": "Fields to change (POST only)",
+                        "name": "config",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookConfigReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "POST sets the URL events are delivered to (an empty url disables delivery) and the event types to receive: order.paid, order.in_review, order.mispaid, order.failed, order.expired, order.settled, refund.requested, refund.completed, refund.rejected, dispute.opened, dispute.resolved, verification.failed, or [\"*\"] for all (the default). A signing secret is generated the first time a URL is set and only returned in that response. Requires the primary API key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get or set the webhook endpoint",
+                "parameters": [
+                    {
+                        "description": "Fields to change (POST only)",
+                        "name": "config",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookConfigReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/secret/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generates a new signing secret and returns it; it is not shown again. Until previous_secret_expires_at (grace_period_hours, default 24, at most 168) deliveries are signed with both the new and the previous secret, so a receiver verifying either keeps accepting them while it switches. X-OSPay-Key-Version names the versions that signed a delivery, newest first, in the order of the v1 signatures. Rotating again within a grace period retires the oldest secret immediately. Requires the primary API key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Rotate the webhook signing secret",
+                "parameters": [
+                    {
+                        "description": "Grace period",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookSecretRotateReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookSecretRotateResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/test": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sends a signed sample event (marked \"test\": true) to the configured webhook URL and reports the receiver's status code and latency, not its body. Redirects are not followed, and URLs resolving to loopback, private or link-local addresses are refused. Nothing is stored or retried.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Send a test webhook",
+                "parameters": [
+                    {
+                        "description": "Event type to sample",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookTestReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.webhookTestResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "bad_request",
+                "internal_error",
+                "db_error",
+                "db_not_initialized",
+                "method_not_allowed",
+                "invalid_json",
+                "missing_fields",
+                "missing_query_param",
+                "missing_idempotency_key",
+                "invalid_metadata",
+                "invalid_amount",
+                "invalid_limit",
+                "limit_exceeded",
+                "api_key_required",
+                "invalid_api_key",
+                "invalid_token",
+                "invalid_platform_key",
+                "api_key_not_found",
+                "primary_key_required",
+                "insufficient_scope",
+                "invalid_scope",
+                "admin_disabled",
+                "invalid_admin_key",
+                "admin_required",
+                "invalid_client",
+                "invalid_redirect_uri",
+                "merchant_not_found",
+                "merchant_mismatch",
+                "merchant_not_connected",
+                "missing_wallet_address",
+                "invalid_application_fee",
+                "order_not_found",
+                "order_not_paid",
+                "order_not_refundable",
+                "order_not_in_review",
+                "order_not_disputable",
+                "onchain_verification_failed",
+                "customer_wallet_unknown",
+                "already_refunded",
+                "cannot_refund_settled",
+                "missing_refund_amount",
+                "invalid_refund_amount",
+                "refund_exceeds_order",
+                "invalid_refund_tx",
+                "refund_tx_already_used",
+                "refund_verification_failed",
+                "refund_not_found",
+                "refund_not_pending",
+                "approver_must_differ",
+                "invalid_decision",
+                "dispute_not_found",
+                "dispute_open",
+                "dispute_closed",
+                "dispute_already_open",
+                "invalid_dispute_amount",
+                "invalid_outcome",
+                "retention_disabled",
+                "invalid_cursor",
+                "invalid_idempotency_key",
+                "idempotency_key_reused",
+                "idempotency_in_progress",
+                "invalid_webhook_url",
+                "webhook_not_configured",
+                "invalid_event_type",
+                "invalid_time_range",
+                "order_expired",
+                "order_not_pending",
+                "extension_limit_exceeded",
+                "customer_not_found",
+                "unsupported_chain",
+                "rpc_unavailable",
+                "gas_tank_not_configured",
+                "transaction_not_found",
+                "transaction_not_pending",
+                "fee_cap_reached",
+                "invalid_payout_mode",
+                "conversion_not_found",
+                "conversion_not_failed",
+                "invalid_settlement_target",
+                "offramp_not_configured",
+                "kyc_not_approved",
+                "insufficient_balance",
+                "invalid_currency",
+                "offramp_unavailable",
+                "rate_unavailable",
+                "stale_rate",
+                "unsupported_rate_pair",
+                "invalid_timezone",
+                "invalid_metric",
+                "scheduler_not_found",
+                "coupon_not_found",
+                "coupon_exists",
+                "coupon_invalid",
+                "invalid_line_items",
+                "hot_wallet_unavailable",
+                "refund_job_not_found",
+                "status_override_not_allowed",
+                "tx_already_used",
+                "invalid_wallet_address",
+                "wallet_challenge_not_found",
+                "wallet_challenge_expired",
+                "wallet_proof_invalid",
+                "wallet_proof_unsupported",
+                "merchant_pending_approval",
+                "merchant_rejected",
+                "merchant_already_decided",
+                "auth_blocked",
+                "auth_ban_not_found",
+                "validation_failed",
+                "request_too_large",
+                "unsupported_media_type",
+                "payment_intent_not_found",
+                "payment_intent_finalized",
+                "job_not_found",
+                "job_not_dead",
+                "retry_policy_not_found",
+                "invalid_retry_policy",
+                "payout_not_found",
+                "payout_not_dead_lettered",
+                "verification_queue_full",
+                "reconciliation_not_configured",
+                "attestation_not_found",
+                "sweep_not_found",
+                "sweep_not_pending",
+                "address_already_watched",
+                "watch_limit_reached",
+                "watched_address_not_found",
+                "invalid_organization_key",
+                "organization_role_forbidden",
+                "member_not_found",
+                "member_already_exists",
+                "owner_not_removable",
+                "merchant_not_in_organization",
+                "merchant_in_organization",
+                "merchant_header_required",
+                "invalid_webhook_secret",
+                "invalid_tag",
+                "too_many_tags",
+                "overpayment_not_found",
+                "overpayment_not_open",
+                "asset_not_accepted",
+                "invalid_accepted_assets",
+                "invalid_rate_quote",
+                "hold_not_found",
+                "hold_not_active",
+                "hold_exceeds_balance",
+                "ledger_transaction_not_found",
+                "merchant_deleted",
+                "merchant_not_deleted",
+                "order_not_deleted",
+                "order_has_payment",
+                "idempotency_response_withheld",
+                "overpayment_refund_in_flight",
+                "overpayment_sender_erased",
+                "not_found"
+            ],
+            "x-enum-varnames": [
+                "CodeBadRequest",
+                "CodeInternalError",
+                "CodeDBError",
+                "CodeDBNotInitialized",
+                "CodeMethodNotAllowed",
+                "CodeInvalidJSON",
+                "CodeMissingFields",
+                "CodeMissingQueryParam",
+                "CodeMissingIdempotencyKey",
+                "CodeInvalidMetadata",
+                "CodeInvalidAmount",
+                "CodeInvalidLimit",
+                "CodeLimitExceeded",
+                "CodeAPIKeyRequired",
+                "CodeInvalidAPIKey",
+                "CodeInvalidToken",
+                "CodeInvalidPlatformKey",
+                "CodeAPIKeyNotFound",
+                "CodePrimaryKeyRequired",
+                "CodeInsufficientScope",
+                "CodeInvalidScope",
+                "CodeAdminDisabled",
+                "CodeInvalidAdminKey",
+                "CodeAdminRequired",
+                "CodeInvalidClient",
+                "CodeInvalidRedirectURI",
+                "CodeMerchantNotFound",
+                "CodeMerchantMismatch",
+                "CodeMerchantNotConnected",
+                "CodeMissingWalletAddress",
+                "CodeInvalidApplicationFee",
+                "CodeOrderNotFound",
+                "CodeOrderNotPaid",
+                "CodeOrderNotRefundable",
+                "CodeOrderNotInReview",
+                "CodeOrderNotDisputable",
+                "CodeOnchainVerificationFailed",
+                "CodeCustomerWalletUnknown",
+                "CodeAlreadyRefunded",
+                "CodeCannotRefundSettled",
+                "CodeMissingRefundAmount",
+                "CodeInvalidRefundAmount",
+                "CodeRefundExceedsOrder",
+                "CodeInvalidRefundTx",
+                "CodeRefundTxAlreadyUsed",
+                "CodeRefundVerificationFailed",
+                "CodeRefundNotFound",
+                "CodeRefundNotPending",
+                "CodeApproverMustDiffer",
+                "CodeInvalidDecision",
+                "CodeDisputeNotFound",
+                "CodeDisputeOpen",
+                "CodeDisputeClosed",
+                "CodeDisputeAlreadyOpen",
+                "CodeInvalidDisputeAmount",
+                "CodeInvalidOutcome",
+                "CodeRetentionDisabled",
+                "CodeInvalidCursor",
+                "CodeInvalidIdempotencyKey",
+                "CodeIdempotencyKeyReused",
+                "CodeIdempotencyInProgress",
+                "CodeInvalidWebhookURL",
+                "CodeWebhookNotConfigured",
+                "CodeInvalidEventType",
+                "CodeInvalidTimeRange",
+                "CodeOrderExpired",
+                "CodeOrderNotPending",
+                "CodeExtensionLimitExceeded",
+                "CodeCustomerNotFound",
+                "CodeUnsupportedChain",
+                "CodeRPCUnavailable",
+                "CodeGasTankNotConfigured",
+                "CodeTransactionNotFound",
+                "CodeTransactionNotPending",
+                "CodeFeeCapReached",
+                "CodeInvalidPayoutMode",
+                "CodeConversionNotFound",
+                "CodeConversionNotFailed",
+                "CodeInvalidSettlementTarget",
+                "CodeOfframpNotConfigured",
+                "CodeKYCNotApproved",
+                "CodeInsufficientBalance",
+                "CodeInvalidCurrency",
+                "CodeOfframpUnavailable",
+                "CodeRateUnavailable",
+                "CodeStaleRate",
+                "CodeUnsupportedRatePair",
+                "CodeInvalidTimezone",
+                "CodeInvalidMetric",
+                "CodeSchedulerNotFound",
+                "CodeCouponNotFound",
+                "CodeCouponExists",
+                "CodeCouponInvalid",
+                "CodeInvalidLineItems",
+                "CodeHotWalletUnavailable",
+                "CodeRefundJobNotFound",
+                "CodeStatusOverrideNotAllowed",
+                "CodeTxAlreadyUsed",
+                "CodeInvalidWalletAddress",
+                "CodeWalletChallengeNotFound",
+                "CodeWalletChallengeExpired",
+                "CodeWalletProofInvalid",
+                "CodeWalletProofUnsupported",
+                "CodeMerchantPendingApproval",
+                "CodeMerchantRejected",
+                "CodeMerchantAlreadyDecided",
+                "CodeAuthBlocked",
+                "CodeAuthBanNotFound",
+                "CodeValidationFailed",
+                "CodeRequestTooLarge",
+                "CodeUnsupportedMediaType",
+                "CodePaymentIntentNotFound",
+                "CodePaymentIntentFinalized",
+                "CodeJobNotFound",
+                "CodeJobNotDead",
+                "CodeRetryPolicyNotFound",
+                "CodeInvalidRetryPolicy",
+                "CodePayoutNotFound",
+                "CodePayoutNotDeadLettered",
+                "CodeVerificationQueueFull",
+                "CodeReconciliationNotConfigured",
+                "CodeAttestationNotFound",
+                "CodeSweepNotFound",
+                "CodeSweepNotPending",
+                "CodeAddressAlreadyWatched",
+                "CodeWatchLimitReached",
+                "CodeWatchedAddressNotFound",
+                "CodeInvalidOrganizationKey",
+                "CodeOrganizationRoleForbidden",
+                "CodeMemberNotFound",
+                "CodeMemberAlreadyExists",
+                "CodeOwnerNotRemovable",
+                "CodeMerchantNotInOrganization",
+                "CodeMerchantInOrganization",
+                "CodeMerchantHeaderRequired",
+                "CodeInvalidWebhookSecret",
+                "CodeInvalidTag",
+                "CodeTooManyTags",
+                "CodeOverpaymentNotFound",
+                "CodeOverpaymentNotOpen",
+                "CodeAssetNotAccepted",
+                "CodeInvalidAcceptedAssets",
+                "CodeInvalidRateQuote",
+                "CodeHoldNotFound",
+                "CodeHoldNotActive",
+                "CodeHoldExceedsBalance",
+                "CodeLedgerTransactionNotFound",
+                "CodeMerchantDeleted",
+                "CodeMerchantNotDeleted",
+                "CodeOrderNotDeleted",
+                "CodeOrderHasPayment",
+                "CodeIdempotencyResponseWithheld",
+                "CodeOverpaymentRefundInFlight",
+                "CodeOverpaymentSenderErased",
+                "CodeNotFound"
+            ]
+        },
+        "api.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "JSON path, e.g. \"line_items[0].name\"",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "description": "required | type | max | min | oneof | asset | chain | amount | rfc3339",
+                    "type": "string"
+                }
+            }
+        },
+        "api.MerchantCreateReq": {
+            "description": "Request to create a new merchant",
+            "type": "object",
+            "required": [
+                "merchant_wallet_address",
+                "name"
+            ],
+            "properties": {
+                "merchant_wallet_address": {
+                    "description": "an address, or an ENS name",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "api.MerchantCreateResp": {
+            "description": "Response after creating a merchant",
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_wallet_address": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is PENDING_APPROVAL when new merchants wait for an administrator; the API key works\nonce it is ACTIVE.",
+                    "type": "string"
+                },
+                "wallet_ens_name": {
+                    "description": "WalletENSName is the ENS name merchant_wallet_address was resolved from, if one was given.",
+                    "type": "string"
+                }
+            }
+        },
+        "api.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/api.ErrorCode"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.acceptedAsset": {
+            "type": "object",
+            "required": [
+                "asset",
+                "chain"
+            ],
+            "properties": {
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.addressTransfer": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "description": "the watched address, which received the transfer",
+                    "type": "string"
+                },
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "block_number": {
+                    "type": "integer"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "when the watcher found it",
+                    "type": "string"
+                },
+                "from_address": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "log_index": {
+                    "type": "integer"
+                },
+                "tx_hash": {
+                    "type": "string"
+                },
+                "watch_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.apiKeyCreateReq": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "scope": {
+                    "description": "space-separated, same vocabulary as OAuth scopes",
+                    "type": "string"
+                }
+            }
+        },
+        "api.apiKeyCreateResp": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.apiKeyInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.apiKeyUsage": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "\"primary\" for the merchant's own key",
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "description": "null if never used",
+                    "type": "string"
+                },
+                "last_used_ip": {
+                    "type": "string"
+                },
+                "request_count": {
+                    "type": "integer"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "sources": {
+                    "description": "the most recently seen IPs, at most maxKeySources",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.keySource"
+                    }
+                }
+            }
+        },
+        "api.assetBalance": {
+            "type": "object",
+            "properties": {
+                "asset": {
+                    "type": "string"
+                },
+                "available_minor": {
+                    "description": "settled funds (settlement bucket)",
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "held_minor": {
+                    "description": "frozen by open disputes or balance holds, or reserved for fiat payouts",
+                    "type": "string"
+                },
+                "pending_minor": {
+                    "description": "paid orders not yet settled (merchant bucket)",
+                    "type": "string"
+                },
+                "refundable_minor": {
+                    "description": "overpayments to return to customers (overpayment bucket)",
+                    "type": "string"
+                }
+            }
+        },
+        "api.assetReconciliation": {
+            "type": "object",
+            "properties": {
+                "asset": {
+                    "type": "string"
+                },
+                "books_minor": {
+                    "description": "BooksMinor is what the custody wallets should hold: the custody buckets less what was sent\nout and is not booked yet (payouts SENT or EXECUTED, fiat payouts FUNDED).",
+                    "type": "string"
+                },
+                "buckets": {
+                    "description": "net of each custody bucket",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "chain_minor": {
+                    "description": "sum of the wallets' balances",
+                    "type": "string"
+                },
+                "drift_bps": {
+                    "description": "of books_minor; omitted when the books are zero or the drift is vast",
+                    "type": "integer"
+                },
+                "drift_minor": {
+                    "description": "DriftMinor is chain_minor - books_minor: negative when the wallets hold less than the books.",
+                    "type": "string"
+                },
+                "drifting": {
+                    "description": "beyond the tolerance",
+                    "type": "boolean"
+                },
+                "error": {
+                    "description": "a wallet balance could not be read",
+                    "type": "string"
+                },
+                "offramp_funded_minor": {
+                    "type": "string"
+                },
+                "paid_out_minor": {
+                    "type": "string"
+                },
+                "wallets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.walletBalance"
+                    }
+                }
+            }
+        },
+        "api.auditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "object"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.backupResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "size_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.balanceHold": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "placed_by": {
+                    "type": "string"
+                },
+                "platform_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "release_batch_id": {
+                    "description": "settled the withheld part",
+                    "type": "string"
+                },
+                "release_reason": {
+                    "type": "string"
+                },
+                "released_at": {
+                    "type": "string"
+                },
+                "released_by": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "ACTIVE, RELEASED or EXPIRED",
+                    "type": "string"
+                },
+                "withheld_minor": {
+                    "description": "kept back from settlements while active",
+                    "type": "string"
+                }
+            }
+        },
+        "api.balancesResp": {
+            "type": "object",
+            "properties": {
+                "balances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.assetBalance"
+                    }
+                },
+                "merchant_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.batchGetOrdersReq": {
+            "type": "object",
+            "required": [
+                "order_ids"
+            ],
+            "properties": {
+                "order_ids": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.batchGetOrdersResp": {
+            "type": "object",
+            "properties": {
+                "not_found": {
+                    "description": "IDs of no order of the merchant",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "orders": {
+                    "description": "in the order of order_ids, each once",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.orderGetResp"
+                    }
+                }
+            }
+        },
+        "api.bulkRefundReq": {
+            "type": "object"
+        },
+        "api.chainHealth": {
+            "type": "object",
+            "properties": {
+                "block_height": {
+                    "type": "integer"
+                },
+                "block_time": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "expected_block_time_seconds": {
+                    "description": "seconds between blocks",
+                    "type": "number"
+                },
+                "lag_seconds": {
+                    "description": "how far the latest block trails the clock",
+                    "type": "number"
+                },
+                "latency_ms": {
+                    "description": "of fetching the latest block",
+                    "type": "integer"
+                },
+                "max_lag_seconds": {
+                    "description": "lag beyond which the chain is lagging",
+                    "type": "number"
+                },
+                "provider": {
+                    "description": "host of the RPC endpoint in use",
+                    "type": "string"
+                },
+                "reachable": {
+                    "type": "boolean"
+                },
+                "status": {
+                    "description": "ok | lagging | unreachable",
+                    "type": "string"
+                }
+            }
+        },
+        "api.chainHealthResp": {
+            "type": "object",
+            "properties": {
+                "chains": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.chainHealth"
+                    }
+                },
+                "ok": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.chainTx": {
+            "type": "object",
+            "properties": {
+                "bumps": {
+                    "type": "integer"
+                },
+                "cancel_tx_hash": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "confirmed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from_address": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "max_fee_wei": {
+                    "type": "string"
+                },
+                "mined_tx_hash": {
+                    "description": "which of the hashes made it into a block",
+                    "type": "string"
+                },
+                "nonce": {
+                    "type": "integer"
+                },
+                "priority_fee_wei": {
+                    "type": "string"
+                },
+                "purpose": {
+                    "type": "string"
+                },
+                "reference_id": {
+                    "type": "string"
+                },
+                "replaced_hashes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "stuck": {
+                    "description": "still pending after three times its profile's wait",
+                    "type": "boolean"
+                },
+                "submitted_at": {
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                },
+                "urgency": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.chainTxBumpReq": {
+            "type": "object",
+            "properties": {
+                "urgency": {
+                    "description": "fee profile for the replacement; defaults to the transaction's own",
+                    "type": "string"
+                }
+            }
+        },
+        "api.coldSweep": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "balance_minor": {
+                    "description": "hot wallet balance that went over the limit",
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "decided_by": {
+                    "description": "who approved or rejected it",
+                    "type": "string"
+                },
+                "from_address": {
+                    "description": "the hot wallet",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "keep_minor": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "limit_minor": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to_address": {
+                    "description": "the cold wallet",
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.connectedBalance": {
+            "type": "object",
+            "properties": {
+                "merchant_balance_minor": {
+                    "type": "integer"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "platform_fee_minor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.connectedMerchant": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_wallet_address": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.conversionRecord": {
+            "type": "object",
+            "properties": {
+                "amount_in_minor": {
+                    "type": "string"
+                },
+                "amount_out_minor": {
+                    "type": "string"
+                },
+                "batch_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from_asset": {
+                    "type": "string"
+                },
+                "from_chain": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "max_slippage_bps": {
+                    "type": "integer"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "min_out_minor": {
+                    "description": "the swap reverts below it",
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "quoted_out_minor": {
+                    "type": "string"
+                },
+                "receive_tx_hash": {
+                    "description": "the delivery on to_chain",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to_asset": {
+                    "type": "string"
+                },
+                "to_chain": {
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "description": "the swap on from_chain",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.coupon": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "amount_off_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "max_redemptions": {
+                    "type": "integer"
+                },
+                "percent_off": {
+                    "type": "integer"
+                },
+                "redemptions": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.couponCreateReq": {
+            "type": "object",
+            "required": [
+                "code",
+                "type"
+            ],
+            "properties": {
+                "amount_off_minor": {
+                    "description": "fixed coupons",
+                    "type": "string"
+                },
+                "asset": {
+                    "description": "fixed coupons",
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "expires_at": {
+                    "description": "omitted: never",
+                    "type": "string"
+                },
+                "max_redemptions": {
+                    "description": "omitted: unlimited",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "percent_off": {
+                    "description": "percent coupons",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "percent",
+                        "fixed"
+                    ]
+                }
+            }
+        },
+        "api.couponUpdateReq": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_redemptions": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "api.customerDetailResp": {
+            "type": "object",
+            "properties": {
+                "first_paid_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_paid_at": {
+                    "type": "string"
+                },
+                "payment_count": {
+                    "type": "integer"
+                },
+                "payments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.orderGetResp"
+                    }
+                },
+                "totals_minor": {
+                    "description": "paid amount per asset",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "wallet_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.customerListResp": {
+            "type": "object",
+            "properties": {
+                "customers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.customerRecord"
+                    }
+                },
+                "next_cursor": {
+                    "description": "pass as cursor to fetch the next page",
+                    "type": "string"
+                }
+            }
+        },
+        "api.customerRecord": {
+            "type": "object",
+            "properties": {
+                "first_paid_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_paid_at": {
+                    "type": "string"
+                },
+                "payment_count": {
+                    "type": "integer"
+                },
+                "wallet_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.deadLetterEvent": {
+            "type": "object",
+            "properties": {
+                "aggregate_id": {
+                    "type": "string"
+                },
+                "aggregate_type": {
+                    "type": "string"
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object"
+                },
+                "dead_lettered_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "replay_of": {
+                    "type": "string"
+                },
+                "sequence": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.deadLetterListResp": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.deadLetterEvent"
+                    }
+                },
+                "next_cursor": {
+                    "description": "pass as cursor to fetch the next page",
+                    "type": "string"
+                }
+            }
+        },
+        "api.deadLetterRequeueReq": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "description": "every dead-lettered event, up to 1000 per request",
+                    "type": "boolean"
+                },
+                "event_ids": {
+                    "description": "at most 1000",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.deadLetterRequeueResp": {
+            "type": "object",
+            "properties": {
+                "requeued": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.disputeCreateReq": {
+            "type": "object"
+        },
+        "api.disputeEvidence": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "description": "\"merchant\" or \"admin\"",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.disputeEvidenceReq": {
+            "type": "object",
+            "required": [
+                "note"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 4000
+                }
+            }
+        },
+        "api.disputeRecord": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "evidence": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.disputeEvidence"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "resolved_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "OPEN | WON | LOST",
+                    "type": "string"
+                }
+            }
+        },
+        "api.disputeResolveReq": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 4000
+                },
+                "outcome": {
+                    "description": "\"won\" (merchant keeps the funds) or \"lost\" (funds go back to the customer)",
+                    "type": "string"
+                }
+            }
+        },
+        "api.dormantKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "key_id": {
+                    "description": "\"primary\" for the merchant's own key",
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "description": "null if never used",
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "request_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.eventCatalogResp": {
+            "type": "object",
+            "properties": {
+                "envelope": {
+                    "description": "schema of the delivered body",
+                    "type": "object"
+                },
+                "types": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.eventTypeInfo"
+                    }
+                }
+            }
+        },
+        "api.eventReplayReq": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "RFC 3339, inclusive",
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "description": "RFC 3339, exclusive; defaults to now",
+                    "type": "string"
+                }
+            }
+        },
+        "api.eventReplayResp": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "replayed": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.eventTypeInfo": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "schema": {
+                    "type": "object"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.fiatPayoutRecord": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deposit_address": {
+                    "type": "string"
+                },
+                "fiat_amount_minor": {
+                    "description": "in cents (the currency's minor unit)",
+                    "type": "string"
+                },
+                "fiat_currency": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "provider_payout_id": {
+                    "description": "the partner's reference",
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.fiatPayoutReq": {
+            "type": "object",
+            "required": [
+                "amount_minor",
+                "asset",
+                "chain"
+            ],
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "fiat_currency": {
+                    "description": "ISO 4217; default USD",
+                    "type": "string"
+                }
+            }
+        },
+        "api.fiatPricing": {
+            "type": "object",
+            "properties": {
+                "fiat_amount": {
+                    "description": "price in fiat_currency, as a decimal",
+                    "type": "string"
+                },
+                "fiat_currency": {
+                    "type": "string"
+                },
+                "rate_quote": {
+                    "$ref": "#/definitions/api.rateResp"
+                }
+            }
+        },
+        "api.gasEstimate": {
+            "type": "object",
+            "properties": {
+                "base_fee_wei": {
+                    "description": "EIP-1559 chains only",
+                    "type": "string"
+                },
+                "batch_fee_wei": {
+                    "type": "string"
+                },
+                "batch_gas": {
+                    "type": "integer"
+                },
+                "batch_savings_wei": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "cheaper": {
+                    "description": "\"batch\" or \"individual\"",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "fee_per_transfer": {
+                    "description": "in the native asset, e.g. \"0.000195\"",
+                    "type": "string"
+                },
+                "fee_per_transfer_wei": {
+                    "type": "string"
+                },
+                "fetched_at": {
+                    "type": "string"
+                },
+                "gas_price_wei": {
+                    "type": "string"
+                },
+                "individual_fee_wei": {
+                    "type": "string"
+                },
+                "native_asset": {
+                    "type": "string"
+                },
+                "priority_fee_wei": {
+                    "description": "EIP-1559 chains only",
+                    "type": "string"
+                },
+                "transfer_gas": {
+                    "type": "integer"
+                },
+                "transfers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.gasTankResp": {
+            "type": "object",
+            "properties": {
+                "chains": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.gasTankStatus"
+                    }
+                }
+            }
+        },
+        "api.gasTankStatus": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "description": "in the native asset",
+                    "type": "string"
+                },
+                "balance_wei": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "checked_at": {
+                    "type": "string"
+                },
+                "daily_burn_wei": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "fee_per_transfer_wei": {
+                    "type": "string"
+                },
+                "low": {
+                    "type": "boolean"
+                },
+                "low_water_wei": {
+                    "type": "string"
+                },
+                "native_asset": {
+                    "type": "string"
+                },
+                "payouts_7d": {
+                    "description": "settlement batches and completed refunds on the chain",
+                    "type": "integer"
+                },
+                "runway_days": {
+                    "description": "omitted without recent payouts",
+                    "type": "number"
+                },
+                "wallet_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ipBan": {
+            "type": "object",
+            "properties": {
+                "banned_until": {
+                    "type": "string"
+                },
+                "bans": {
+                    "description": "how many times the IP was banned, this one included",
+                    "type": "integer"
+                },
+                "failures": {
+                    "description": "in the window that led to the ban",
+                    "type": "integer"
+                },
+                "first_failed_at": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_failed_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.jobRecord": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "locked_by": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "run_at": {
+                    "description": "not before; the next retry of a failed job",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "PENDING, RUNNING, SUCCEEDED or DEAD",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.keySource": {
+            "type": "object",
+            "properties": {
+                "first_used_at": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "request_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.kycResp": {
+            "type": "object",
+            "properties": {
+                "bank_account_id": {
+                    "type": "string"
+                },
+                "checked_at": {
+                    "type": "string"
+                },
+                "customer_id": {
+                    "type": "string"
+                },
+                "kyc_status": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ledgerExportLine": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "bucket": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "direction": {
+                    "description": "credit or debit",
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "rate_quote_id": {
+                    "description": "rate quote of a fiat-priced order, see /rates/history",
+                    "type": "string"
+                },
+                "reference_id": {
+                    "description": "refund, batch, conversion, ... that produced the entry",
+                    "type": "string"
+                },
+                "transaction_group_id": {
+                    "description": "TransactionGroupID is shared by the legs of one double entry, see /ledger/transactions",
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ledgerTransaction": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ledgerExportLine"
+                    }
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "transaction_group_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.lineItem": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "unit_amount_minor": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.merchantDecisionReq": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "description": "shown to the merchant; required to reject",
+                    "type": "string",
+                    "maxLength": 1000
+                }
+            }
+        },
+        "api.merchantRecord": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "decided_at": {
+                    "type": "string"
+                },
+                "decided_by": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "deleted_by": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_wallet_address": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "platform_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "ACTIVE, PENDING_APPROVAL or REJECTED",
+                    "type": "string"
+                }
+            }
+        },
+        "api.merchantSettings": {
+            "type": "object",
+            "properties": {
+                "accepted_assets": {
+                    "description": "AcceptedAssets restricts the asset and chain pairs of new orders; empty accepts any.",
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "$ref": "#/definitions/api.acceptedAsset"
+                    }
+                },
+                "clawback_max_bps": {
+                    "description": "ClawbackMaxBps is the most of each settlement withheld while the balance is negative; default\n10000 (all of it), 0 withholds nothing. Only an administrator can change it.",
+                    "type": "integer"
+                },
+                "kyc_status": {
+                    "type": "string"
+                },
+                "late_payment_review": {
+                    "description": "LatePaymentReview holds payments for expired orders in LATE_PAYMENT instead of crediting them.",
+                    "type": "boolean"
+                },
+                "max_daily_volume_minor": {
+                    "description": "per asset, per day in Timezone",
+                    "type": "string"
+                },
+                "max_order_amount_minor": {
+                    "description": "Velocity limits; \"0\" / 0 removes the limit. Only an administrator can change them.",
+                    "type": "string"
+                },
+                "max_slippage_bps": {
+                    "type": "integer"
+                },
+                "max_wallet_orders_per_hour": {
+                    "type": "integer"
+                },
+                "offramp_bank_account_id": {
+                    "type": "string"
+                },
+                "offramp_customer_id": {
+                    "description": "The merchant's customer and bank account at the off-ramp partner, for fiat payouts. Only an\nadministrator can link them; KYCStatus is read only.",
+                    "type": "string"
+                },
+                "payout_mode": {
+                    "description": "PayoutMode sends settlements on-chain: \"hot_wallet\" or \"safe\" (\"\" back to ledger only).\nOnly an administrator can change it or the Safe address.",
+                    "type": "string"
+                },
+                "payout_safe_address": {
+                    "type": "string"
+                },
+                "refund_approval_required": {
+                    "type": "boolean"
+                },
+                "settlement_asset": {
+                    "description": "Settlements received in another asset or on another chain are converted before payout\n(\"\" keeps what was received). MaxSlippageBps limits conversion slippage; default 50.",
+                    "type": "string"
+                },
+                "settlement_chain": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone (IANA, e.g. \"Europe/Berlin\") sets where the merchant's days start for daily limits,\nreports and settlement; with one set, orders settle by local calendar day. \"\" is UTC.",
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "api.mispayment": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "in the smallest unit of the token sent",
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "from_address": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "wrong_token | wrong_chain",
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.negativeBalance": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "what the merchant owes, positive; 0 once recovered",
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "opened_at": {
+                    "type": "string"
+                },
+                "peak_minor": {
+                    "description": "the most it owed",
+                    "type": "string"
+                },
+                "recovered_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "OPEN or RECOVERED",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.oauthAuthorizeReq": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "string"
+                },
+                "redirect_uri": {
+                    "type": "string"
+                },
+                "scope": {
+                    "description": "space-separated, e.g. \"orders:write balances:read\"",
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "api.oauthAuthorizeResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "redirect_uri": {
+                    "description": "redirect_uri with code and state appended",
+                    "type": "string"
+                }
+            }
+        },
+        "api.oauthClientCreateReq": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "redirect_uri": {
+                    "type": "string",
+                    "maxLength": 2000
+                }
+            }
+        },
+        "api.oauthClientCreateResp": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "string"
+                },
+                "client_secret": {
+                    "type": "string"
+                },
+                "redirect_uri": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.oauthTokenResp": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.onchainReconResp": {
+            "type": "object",
+            "properties": {
+                "assets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.assetReconciliation"
+                    }
+                },
+                "checked_at": {
+                    "type": "string"
+                },
+                "tolerance_bps": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.orderCreateReq": {
+            "type": "object",
+            "required": [
+                "asset",
+                "chain"
+            ],
+            "properties": {
+                "amount_minor": {
+                    "description": "String to handle large 18-decimal numbers",
+                    "type": "string"
+                },
+                "asset": {
+                    "description": "e.g., \"USDC\"",
+                    "type": "string"
+                },
+                "chain": {
+                    "description": "e.g., \"POLYGON\"",
+                    "type": "string"
+                },
+                "coupon_code": {
+                    "description": "CouponCode is one of the merchant's coupons; its discount is taken off amount_minor.",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "customer_email": {
+                    "type": "string",
+                    "maxLength": 254
+                },
+                "customer_wallet_address": {
+                    "description": "CustomerWalletAddress is optional; when given, per-wallet velocity limits apply at creation.",
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "external_order_id": {
+                    "description": "the merchant's own order reference",
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "fiat_amount": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "idempotency_key": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "line_items": {
+                    "description": "LineItems are what is being bought; amount_minor may then be omitted and defaults to their sum.",
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "$ref": "#/definitions/api.lineItem"
+                    }
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "description": "free-form JSON object",
+                    "type": "object"
+                },
+                "rate_quote_id": {
+                    "description": "RateQuoteID and FiatAmount record a fiat price: the quote_id of the /rates quote between\nasset and the fiat currency that amount_minor was converted with, and the price in that\ncurrency. The quote is kept on the order and its ledger entries.",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "webhook_secret": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "webhook_url": {
+                    "description": "WebhookURL receives the order's events instead of the merchant's webhook URL, signed with\nWebhookSecret; one is generated when it is omitted.",
+                    "type": "string",
+                    "maxLength": 2000
+                }
+            }
+        },
+        "api.orderCreateResp": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "amount due, after any discount",
+                    "type": "string"
+                },
+                "deposit_address": {
+                    "type": "string"
+                },
+                "discount_minor": {
+                    "description": "taken off by coupon_code",
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhook_secret": {
+                    "description": "generated for webhook_url; only in the response that created the order",
+                    "type": "string"
+                }
+            }
+        },
+        "api.orderExtendReq": {
+            "type": "object",
+            "properties": {
+                "minutes": {
+                    "description": "added to the later of now and the current expiry; defaults to 30",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.orderExtendResp": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.orderGetResp": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "String to handle large 18-decimal numbers",
+                    "type": "string"
+                },
+                "application_fee_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "block_timestamp": {
+                    "description": "and its time",
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "confirmed_block": {
+                    "description": "block that mined the verified payment",
+                    "type": "integer"
+                },
+                "coupon_code": {
+                    "description": "CouponCode and DiscountMinor are set when a coupon was redeemed; amount_minor is net of the discount.",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_email": {
+                    "type": "string"
+                },
+                "customer_wallet_address": {
+                    "description": "CustomerWalletAddress is the sender of the verified payment transfer; refunds go back to it.",
+                    "type": "string"
+                },
+                "deposit_address": {
+                    "type": "string"
+                },
+                "discount_minor": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "PENDING orders become EXPIRED after this",
+                    "type": "string"
+                },
+                "external_order_id": {
+                    "type": "string"
+                },
+                "fiat_pricing": {
+                    "description": "FiatPricing is the fiat price and rate quote of an order created with rate_quote_id.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.fiatPricing"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "line_items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.lineItem"
+                    }
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "mispayment": {
+                    "description": "Mispayment is the transfer in the wrong token or on the wrong chain of a MISPAID order.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.mispayment"
+                        }
+                    ]
+                },
+                "paid_at": {
+                    "type": "string"
+                },
+                "risk_factors": {
+                    "type": "string"
+                },
+                "risk_reason": {
+                    "description": "Risk fields are set when the payment is confirmed; REVIEW orders wait for an admin decision.",
+                    "type": "string"
+                },
+                "risk_score": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "in list, search and batch-get results",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tx_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.orderListResp": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "description": "pass as cursor to fetch the next page",
+                    "type": "string"
+                },
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.orderGetResp"
+                    }
+                }
+            }
+        },
+        "api.orderNote": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "description": "credential that wrote it, e.g. \"key:primary\" or \"admin\"",
+                    "type": "string"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.orderNoteReq": {
+            "type": "object",
+            "required": [
+                "body"
+            ],
+            "properties": {
+                "body": {
+                    "description": "at most maxNoteLength",
+                    "type": "string",
+                    "maxLength": 4000
+                }
+            }
+        },
+        "api.orderReviewReq": {
+            "type": "object",
+            "properties": {
+                "decision": {
+                    "description": "\"approve\" credits the payment, \"reject\" fails the order",
+                    "type": "string"
+                }
+            }
+        },
+        "api.orderStatusOverrideReq": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "reason": {
+                    "description": "why, e.g. the block explorer link of a payment verified by hand",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "PAID",
+                        "FAILED",
+                        "EXPIRED"
+                    ]
+                },
+                "tx_hash": {
+                    "description": "the payment, recorded when forcing PAID",
+                    "type": "string"
+                }
+            }
+        },
+        "api.orderStatusOverrideResp": {
+            "type": "object",
+            "properties": {
+                "ledger_entries": {
+                    "description": "written to credit or reverse the payment",
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "override_id": {
+                    "type": "string"
+                },
+                "previous_status": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.orderTagsReq": {
+            "type": "object",
+            "required": [
+                "tags"
+            ],
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.orderTagsResp": {
+            "type": "object",
+            "properties": {
+                "order_id": {
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "sorted",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.orgAttachReq": {
+            "type": "object",
+            "required": [
+                "api_key"
+            ],
+            "properties": {
+                "api_key": {
+                    "description": "the merchant's primary API key",
+                    "type": "string"
+                }
+            }
+        },
+        "api.orgBalancesResp": {
+            "type": "object",
+            "properties": {
+                "merchants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.orgMerchantBalances"
+                    }
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "totals": {
+                    "description": "all merchants together, per asset and chain",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.assetBalance"
+                    }
+                }
+            }
+        },
+        "api.orgCreateReq": {
+            "type": "object",
+            "required": [
+                "name",
+                "owner_email"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "owner_email": {
+                    "type": "string",
+                    "maxLength": 254
+                }
+            }
+        },
+        "api.orgCreateResp": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "description": "the owner's; shown once",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "member_id": {
+                    "description": "the owner",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.orgMember": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "description": "only when the member is added",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.orgMemberReq": {
+            "type": "object",
+            "required": [
+                "email",
+                "role"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 254
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "viewer"
+                    ]
+                }
+            }
+        },
+        "api.orgMerchant": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_wallet_address": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.orgMerchantBalances": {
+            "type": "object",
+            "properties": {
+                "balances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.assetBalance"
+                    }
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.orgReportResp": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "merchants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.orgReportRow"
+                    }
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "totals": {
+                    "description": "per asset",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.orgReportRow"
+                    }
+                }
+            }
+        },
+        "api.orgReportRow": {
+            "type": "object",
+            "properties": {
+                "asset": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "description": "empty in totals",
+                    "type": "string"
+                },
+                "orders_created": {
+                    "type": "integer"
+                },
+                "orders_paid": {
+                    "type": "integer"
+                },
+                "paid_volume_minor": {
+                    "type": "string"
+                },
+                "refunded_minor": {
+                    "description": "completed refunds",
+                    "type": "string"
+                }
+            }
+        },
+        "api.overpaymentRecord": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "confirmed_block": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from_address": {
+                    "description": "the refund goes back here",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "refund_tx_hash": {
+                    "type": "string"
+                },
+                "refunded_at": {
+                    "type": "string"
+                },
+                "risk_reason": {
+                    "description": "why screening flagged the sender",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "CONFIRMING | REVIEW | OPEN | REFUND_QUEUED | REFUND_SENT | REFUNDED | REFUND_FAILED",
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.paymentAttempt": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "failure_reason": {
+                    "description": "wrong_amount | wrong_token | wrong_chain | wrong_recipient | no_transfer | tx_not_found | order_expired | already_paid | tx_already_used | multiple_senders | verification_error",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending | succeeded | failed",
+                    "type": "string"
+                },
+                "submissions": {
+                    "description": "times the hash was reported",
+                    "type": "integer"
+                },
+                "tx_hash": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.paymentDetectedReq": {
+            "type": "object",
+            "required": [
+                "order_id",
+                "tx_hash"
+            ],
+            "properties": {
+                "amount_minor": {
+                    "description": "optional override; if nil, use order.amount_minor (string for large numbers)",
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.paymentDetectedResp": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.paymentIntent": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "captured_amount_minor": {
+                    "description": "before any coupon discount",
+                    "type": "string"
+                },
+                "captured_at": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "coupon_code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "external_order_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "order_id": {
+                    "description": "set by capture",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "REQUIRES_CAPTURE, CAPTURED or VOIDED",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "void_reason": {
+                    "type": "string"
+                },
+                "voided_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.paymentIntentCaptureReq": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "AmountMinor captures less than the intent's amount; omitted, all of it is captured.",
+                    "type": "string"
+                },
+                "customer_email": {
+                    "type": "string",
+                    "maxLength": 254
+                },
+                "customer_wallet_address": {
+                    "type": "string",
+                    "maxLength": 128
+                }
+            }
+        },
+        "api.paymentIntentCaptureResp": {
+            "type": "object",
+            "properties": {
+                "order": {
+                    "$ref": "#/definitions/api.orderCreateResp"
+                },
+                "payment_intent": {
+                    "$ref": "#/definitions/api.paymentIntent"
+                }
+            }
+        },
+        "api.paymentIntentCreateReq": {
+            "type": "object",
+            "required": [
+                "amount_minor",
+                "asset",
+                "chain"
+            ],
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "coupon_code": {
+                    "description": "CouponCode is checked now and redeemed when the intent is captured.",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "external_order_id": {
+                    "description": "copied to the order",
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "metadata": {
+                    "description": "copied to the order",
+                    "type": "object"
+                }
+            }
+        },
+        "api.paymentIntentUpdateReq": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "external_order_id": {
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "metadata": {
+                    "type": "object"
+                }
+            }
+        },
+        "api.paymentIntentVoidReq": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "api.payoutEstimateResp": {
+            "type": "object",
+            "properties": {
+                "estimates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.gasEstimate"
+                    }
+                }
+            }
+        },
+        "api.payoutRecord": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "attempts": {
+                    "description": "Failed tries to send or propose the payout, retried as the payout retry policy says",
+                    "type": "integer"
+                },
+                "batch_id": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "dead_lettered_at": {
+                    "description": "out of attempts, held until retried",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "safe_address": {
+                    "type": "string"
+                },
+                "safe_nonce": {
+                    "type": "integer"
+                },
+                "safe_tx_hash": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to_address": {
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.placeHoldReq": {
+            "type": "object",
+            "required": [
+                "amount_minor",
+                "asset",
+                "chain",
+                "merchant_id",
+                "reason"
+            ],
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "omitted: held until released",
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "e.g. \"risk review\"",
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "api.platformBalancesResp": {
+            "type": "object",
+            "properties": {
+                "asset": {
+                    "type": "string"
+                },
+                "merchants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.connectedBalance"
+                    }
+                },
+                "platform_fee_total_minor": {
+                    "type": "integer"
+                },
+                "platform_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.platformCreateReq": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "api.platformCreateResp": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.platformOrderCreateReq": {
+            "type": "object",
+            "required": [
+                "asset",
+                "chain",
+                "merchant_id"
+            ],
+            "properties": {
+                "amount_minor": {
+                    "description": "String to handle large 18-decimal numbers",
+                    "type": "string"
+                },
+                "application_fee_minor": {
+                    "description": "withheld for the platform on payment",
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "customer_email": {
+                    "type": "string",
+                    "maxLength": 254
+                },
+                "customer_wallet_address": {
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "external_order_id": {
+                    "type": "string",
+                    "maxLength": 128
+                },
+                "idempotency_key": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "line_items": {
+                    "description": "amount_minor defaults to their sum",
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "$ref": "#/definitions/api.lineItem"
+                    }
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object"
+                }
+            }
+        },
+        "api.privacyErasureResp": {
+            "type": "object",
+            "properties": {
+                "address_transfers_erased": {
+                    "type": "integer"
+                },
+                "erased_at": {
+                    "type": "string"
+                },
+                "orders_erased": {
+                    "type": "integer"
+                },
+                "overpayments_erased": {
+                    "type": "integer"
+                },
+                "pseudonym": {
+                    "description": "replaces the wallet address on erased orders, overpayments and transfers",
+                    "type": "string"
+                }
+            }
+        },
+        "api.privacyExportResp": {
+            "type": "object",
+            "properties": {
+                "address_transfers": {
+                    "description": "Transfers from the subject's wallets to the merchant's watched addresses",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.addressTransfer"
+                    }
+                },
+                "exported_at": {
+                    "type": "string"
+                },
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.privacyOrder"
+                    }
+                },
+                "overpayments": {
+                    "description": "Overpayments of those orders, and any other sent from the subject's wallets",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.overpaymentRecord"
+                    }
+                },
+                "subject": {
+                    "$ref": "#/definitions/api.privacySubject"
+                },
+                "timezone": {
+                    "description": "timestamps are in the merchant's timezone",
+                    "type": "string"
+                }
+            }
+        },
+        "api.privacyOrder": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "customer_email": {
+                    "type": "string"
+                },
+                "customer_wallet_address": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "line_items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.lineItem"
+                    }
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "paid_at": {
+                    "type": "string"
+                },
+                "refunds": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.refundRecord"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tx_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.privacySubject": {
+            "type": "object",
+            "properties": {
+                "customer_email": {
+                    "type": "string"
+                },
+                "customer_wallet_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.problemCatalogEntry": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/api.ErrorCode"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.rateHistoryResp": {
+            "type": "object",
+            "properties": {
+                "quotes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.rateResp"
+                    }
+                }
+            }
+        },
+        "api.rateResp": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "quote": {
+                    "type": "string"
+                },
+                "quote_id": {
+                    "description": "prices an order in fiat (rate_quote_id) and finds the rate later",
+                    "type": "string"
+                },
+                "quoted_at": {
+                    "description": "when it was served",
+                    "type": "string"
+                },
+                "rate": {
+                    "description": "quote per unit of base, as a decimal",
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.refundJob": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.refundJobItem"
+                    }
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "refunded": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.refundJobItem": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "as requested",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "execution_status": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "refund_id": {
+                    "type": "string"
+                },
+                "refund_status": {
+                    "type": "string"
+                },
+                "refund_tx_hash": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "PENDING | REFUNDED | FAILED",
+                    "type": "string"
+                }
+            }
+        },
+        "api.refundRecord": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "decided_at": {
+                    "type": "string"
+                },
+                "decided_by": {
+                    "type": "string"
+                },
+                "execution_error": {
+                    "type": "string"
+                },
+                "execution_status": {
+                    "description": "Set for refunds the hot wallet sends to the customer: QUEUED | SENT | EXECUTED | FAILED",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "refund_tx_hash": {
+                    "type": "string"
+                },
+                "requested_by": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.refundReq": {
+            "type": "object"
+        },
+        "api.refundResp": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "this refund",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "refund_id": {
+                    "type": "string"
+                },
+                "refund_status": {
+                    "description": "REQUESTED | COMPLETED | REJECTED",
+                    "type": "string"
+                },
+                "refundable_minor": {
+                    "description": "what is left to refund",
+                    "type": "string"
+                },
+                "refunded_total_minor": {
+                    "description": "all completed refunds on the order",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "order status",
+                    "type": "string"
+                }
+            }
+        },
+        "api.releaseHoldReq": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "api.reserveAttestation": {
+            "type": "object",
+            "properties": {
+                "generated_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "report": {
+                    "description": "Report is the reserveReport exactly as signed: verify signature against these bytes.",
+                    "type": "object"
+                },
+                "signature": {
+                    "description": "EIP-191 personal_sign of report",
+                    "type": "string"
+                },
+                "signer": {
+                    "description": "address that signed, the hot wallet",
+                    "type": "string"
+                }
+            }
+        },
+        "api.retentionResult": {
+            "type": "object",
+            "properties": {
+                "ledger_entries_archived": {
+                    "type": "integer"
+                },
+                "orders_archived": {
+                    "type": "integer"
+                },
+                "outbox_events_archived": {
+                    "description": "of those, copied to outbox_events_archive",
+                    "type": "integer"
+                },
+                "outbox_events_pruned": {
+                    "description": "removed from outbox_events",
+                    "type": "integer"
+                },
+                "refunds_archived": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.retryPolicyResp": {
             "type": "object",
             "properties": {
-                "merchant_wallet_address": {
+                "base_delay": {
+                    "description": "before the first retry, doubling after each, e.g. \"30s\"",
                     "type": "string"
                 },
-                "name": {
+                "dead_letter": {
+                    "description": "keep or discard",
+                    "type": "string"
+                },
+                "jitter": {
+                    "description": "up to this fraction of the delay is added at random",
+                    "type": "number"
+                },
+                "max_attempts": {
+                    "description": "including the first",
+                    "type": "integer"
+                },
+                "max_delay": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "default, config or admin",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "verification, webhook or payout",
                     "type": "string"
                 }
             }
         },
-        "api.MerchantCreateResp": {
-            "description": "Response after creating a merchant",
+        "api.retryPolicyUpdateReq": {
             "type": "object",
             "properties": {
-                "api_key": {
+                "base_delay": {
+                    "description": "Go duration, e.g. \"30s\"",
                     "type": "string"
                 },
-                "id": {
-                    "type": "string"
+                "dead_letter": {
+                    "type": "string",
+                    "enum": [
+                        "keep",
+                        "discard"
+                    ]
                 },
-                "merchant_wallet_address": {
+                "jitter": {
+                    "type": "number"
+                },
+                "max_attempts": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                },
+                "max_delay": {
                     "type": "string"
                 }
             }
         },
-        "api.orderCreateReq": {
+        "api.schedulerStatus": {
             "type": "object",
             "properties": {
-                "amount_minor": {
+                "failures": {
+                    "type": "integer"
+                },
+                "last_duration_ms": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_rows": {
+                    "type": "integer"
+                },
+                "last_run_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "next_run_at": {
+                    "type": "string"
+                },
+                "paused": {
+                    "type": "boolean"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "runs": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "skipped": {
                     "type": "integer"
+                }
+            }
+        },
+        "api.seriesPoint": {
+            "type": "object",
+            "properties": {
+                "start": {
+                    "description": "bucket start in the merchant's timezone",
+                    "type": "string"
                 },
+                "value": {
+                    "description": "a count, an amount in minor units or a ratio, as a decimal",
+                    "type": "string"
+                }
+            }
+        },
+        "api.settlementBatch": {
+            "type": "object",
+            "properties": {
                 "asset": {
-                    "description": "e.g., \"USDC\"",
                     "type": "string"
                 },
-                "chain": {
-                    "description": "e.g., \"polygon-amoy\"",
+                "batch_id": {
                     "type": "string"
                 },
-                "idempotency_key": {
+                "clawback_minor": {
+                    "description": "withheld against a negative balance",
+                    "type": "string"
+                },
+                "disputes_lost_minor": {
+                    "description": "lost disputes netted",
+                    "type": "string"
+                },
+                "fees_minor": {
+                    "description": "application fees withheld",
+                    "type": "string"
+                },
+                "gross_amount_minor": {
+                    "description": "amounts of the batch's orders",
+                    "type": "string"
+                },
+                "held_minor": {
+                    "description": "withheld for balance holds",
+                    "type": "string"
+                },
+                "hold_released_minor": {
+                    "description": "withheld funds of a released hold",
                     "type": "string"
                 },
                 "merchant_id": {
                     "type": "string"
+                },
+                "orders": {
+                    "type": "integer"
+                },
+                "refunds_minor": {
+                    "description": "completed refunds netted",
+                    "type": "string"
+                },
+                "total_amount_minor": {
+                    "description": "TotalAmountMinor is the net payout: the gross volume of the orders less the application fees\nwithheld, the refunds and the lost disputes netted.",
+                    "type": "string"
                 }
             }
         },
-        "api.orderCreateResp": {
+        "api.settlementRecord": {
             "type": "object",
             "properties": {
-                "deposit_address": {
+                "asset": {
                     "type": "string"
                 },
-                "order_id": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "clawback_minor": {
+                    "description": "withheld against a negative balance",
+                    "type": "string"
+                },
+                "disputes_lost_minor": {
+                    "description": "lost disputes netted",
+                    "type": "string"
+                },
+                "fees_minor": {
+                    "description": "application fees withheld",
+                    "type": "string"
+                },
+                "gross_amount_minor": {
+                    "description": "amounts of the batch's orders",
+                    "type": "string"
+                },
+                "held_minor": {
+                    "description": "withheld for balance holds",
+                    "type": "string"
+                },
+                "hold_released_minor": {
+                    "description": "withheld funds of a released hold",
+                    "type": "string"
+                },
+                "merchant_id": {
+                    "type": "string"
+                },
+                "payout_tx_hash": {
+                    "type": "string"
+                },
+                "refunds_minor": {
+                    "description": "completed refunds netted",
+                    "type": "string"
+                },
+                "settled_at": {
                     "type": "string"
                 },
                 "status": {
                     "type": "string"
+                },
+                "total_amount_minor": {
+                    "description": "net payout",
+                    "type": "string"
                 }
             }
         },
-        "api.orderGetResp": {
+        "api.softDeleteReq": {
             "type": "object",
             "properties": {
-                "amount_minor": {
-                    "type": "integer"
+                "reason": {
+                    "description": "kept in the audit log",
+                    "type": "string",
+                    "maxLength": 1000
+                }
+            }
+        },
+        "api.timelineEntry": {
+            "type": "object",
+            "properties": {
+                "aggregate_id": {
+                    "type": "string"
                 },
-                "asset": {
+                "aggregate_type": {
+                    "description": "order | refund | dispute",
                     "type": "string"
                 },
-                "chain": {
+                "at": {
                     "type": "string"
                 },
-                "confirmed_block": {
-                    "type": "integer"
+                "event": {
+                    "description": "webhook event type, for kind event",
+                    "type": "string"
                 },
-                "created_at": {
+                "kind": {
+                    "description": "created | event | note | payment_attempt",
                     "type": "string"
                 },
-                "deposit_address": {
+                "note": {
+                    "$ref": "#/definitions/api.orderNote"
+                },
+                "payment_attempt": {
+                    "description": "PaymentAttempt is a reported transaction hash and why it was not taken, for kind payment_attempt.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.paymentAttempt"
+                        }
+                    ]
+                }
+            }
+        },
+        "api.timeseriesResp": {
+            "type": "object",
+            "properties": {
+                "asset": {
                     "type": "string"
                 },
-                "id": {
+                "from": {
                     "type": "string"
                 },
-                "merchant_id": {
+                "interval": {
                     "type": "string"
                 },
-                "paid_at": {
+                "metric": {
                     "type": "string"
                 },
-                "status": {
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.seriesPoint"
+                    }
+                },
+                "timezone": {
                     "type": "string"
                 },
-                "tx_hash": {
+                "to": {
                     "type": "string"
                 }
             }
         },
-        "api.paymentDetectedReq": {
+        "api.walletBalance": {
             "type": "object",
             "properties": {
-                "amount_minor": {
-                    "description": "optional override; if nil, use order.amount_minor",
-                    "type": "integer"
+                "address": {
+                    "type": "string"
                 },
-                "order_id": {
+                "balance_minor": {
                     "type": "string"
                 },
-                "tx_hash": {
+                "error": {
                     "type": "string"
                 }
             }
         },
-        "api.paymentDetectedResp": {
+        "api.walletChallenge": {
             "type": "object",
             "properties": {
+                "challenge_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "issued_at": {
+                    "type": "string"
+                },
                 "message": {
+                    "description": "sign with personal_sign (EIP-191)",
                     "type": "string"
                 },
-                "order_id": {
+                "nonce": {
                     "type": "string"
                 },
-                "status": {
+                "typed_data": {
+                    "description": "or with eth_signTypedData_v4 (EIP-712)",
+                    "type": "object"
+                },
+                "wallet_address": {
                     "type": "string"
                 }
             }
         },
-        "api.refundReq": {
+        "api.walletStatus": {
             "type": "object",
             "properties": {
-                "amount_minor": {
-                    "type": "integer"
+                "method": {
+                    "description": "eip191 or eip712, in a verify response",
+                    "type": "string"
                 },
-                "order_id": {
+                "proof_required": {
+                    "description": "ProofRequired is whether payouts wait until the wallet is verified.",
+                    "type": "boolean"
+                },
+                "verified": {
+                    "description": "the merchant proved control of wallet_address",
+                    "type": "boolean"
+                },
+                "verified_at": {
                     "type": "string"
                 },
-                "refund_idempotency_key": {
+                "wallet_address": {
                     "type": "string"
                 },
-                "refundtxhash": {
+                "wallet_ens_name": {
                     "type": "string"
                 }
             }
         },
-        "api.refundResp": {
+        "api.walletVerifyReq": {
             "type": "object",
             "properties": {
-                "message": {
+                "challenge_id": {
                     "type": "string"
                 },
-                "order_id": {
+                "signature": {
+                    "description": "65 bytes, 0x-prefixed hex",
+                    "type": "string"
+                }
+            }
+        },
+        "api.watchAddressReq": {
+            "type": "object",
+            "required": [
+                "address",
+                "chain"
+            ],
+            "properties": {
+                "address": {
                     "type": "string"
                 },
-                "status": {
+                "chain": {
+                    "type": "string"
+                },
+                "label": {
+                    "description": "e.g. the invoice the address was handed out for",
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "api.watchedAddress": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookConfig": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookConfigReq": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "description": "event types, or [\"*\"] for all",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "description": "empty string disables delivery",
+                    "type": "string",
+                    "maxLength": 2000
+                }
+            }
+        },
+        "api.webhookSecretRotateReq": {
+            "type": "object",
+            "properties": {
+                "grace_period_hours": {
+                    "description": "how long the previous secret keeps signing; defaults to 24, at most 168, 0 retires it at once",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.webhookSecretRotateResp": {
+            "type": "object",
+            "properties": {
+                "previous_secret_expires_at": {
+                    "type": "string"
+                },
+                "previous_version": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.webhookTestReq": {
+            "type": "object",
+            "properties": {
+                "event_type": {
+                    "description": "defaults to order.paid",
+                    "type": "string"
+                }
+            }
+        },
+        "api.webhookTestResp": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "url": {
                     "type": "string"
                 }
             }
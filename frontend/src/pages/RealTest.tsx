@@ -293,10 +293,10 @@ export default function RealTest() {
       case 'BSC':
         setTokenContract('0x55d398326f99059ff775485246999027b3197955') // BSC-USD BEP-20
         break
-      case 'TRC-20':
+      case 'TRON':
         setTokenContract('TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t') // USDT TRC-20
         break
-      case 'ERC-20':
+      case 'ETH':
         setTokenContract('0xdAC17F958D2ee523a2206206994597C13D831ec7') // USDT ERC-20
         break
       default:
@@ -438,8 +438,8 @@ export default function RealTest() {
                     }}
                   >
                     <option value="BSC">BSC (BEP-20)</option>
-                    <option value="TRC-20">TRC-20 (Tron)</option>
-                    <option value="ERC-20">ERC-20 (Ethereum)</option>
+                    <option value="TRON">TRC-20 (Tron)</option>
+                    <option value="ETH">ERC-20 (Ethereum)</option>
                   </select>
                 </div>
               </div>
@@ -463,8 +463,8 @@ export default function RealTest() {
                 />
                 <small style={{ color: '#6c757d' }}>
                   {chain === 'BSC' && 'BSC-USD: 0x55d398326f99059ff775485246999027b3197955'}
-                  {chain === 'TRC-20' && 'TRC-20 USDT: TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t'}
-                  {chain === 'ERC-20' && 'ERC-20 USDT: 0xdAC17F958D2ee523a2206206994597C13D831ec7'}
+                  {chain === 'TRON' && 'TRC-20 USDT: TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t'}
+                  {chain === 'ETH' && 'ERC-20 USDT: 0xdAC17F958D2ee523a2206206994597C13D831ec7'}
                 </small>
               </div>
               
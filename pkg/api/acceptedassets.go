@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// acceptedAsset is an asset and chain pair a merchant's orders may use. A merchant without any
+// accepts every pair.
+type acceptedAsset struct {
+	Asset string `json:"asset" validate:"required,asset"`
+	Chain string `json:"chain" validate:"required,chain"`
+}
+
+func (a acceptedAsset) String() string { return a.Asset + " on " + a.Chain }
+
+// parseAcceptedAssets reads merchants.accepted_assets, comma-separated ASSET:CHAIN pairs.
+func parseAcceptedAssets(s string) []acceptedAsset {
+	var out []acceptedAsset
+	for _, pair := range strings.Split(s, ",") {
+		if asset, chain, ok := strings.Cut(pair, ":"); ok {
+			out = append(out, acceptedAsset{Asset: asset, Chain: chain})
+		}
+	}
+	return out
+}
+
+// formatAcceptedAssets upper-cases and checks pairs for merchants.accepted_assets, dropping
+// repeats; an empty list is NULL. Each pair must be a token known on its chain.
+func formatAcceptedAssets(pairs []acceptedAsset) (sql.NullString, error) {
+	var out []string
+	for _, a := range pairs {
+		a = acceptedAsset{Asset: strings.ToUpper(a.Asset), Chain: strings.ToUpper(a.Chain)}
+		if !blockchain.KnownToken(a.Chain, a.Asset) {
+			return sql.NullString{}, errors.New("no " + a.String() + " token is known")
+		}
+		if pair := a.Asset + ":" + a.Chain; !slices.Contains(out, pair) {
+			out = append(out, pair)
+		}
+	}
+	return sql.NullString{String: strings.Join(out, ","), Valid: len(out) > 0}, nil
+}
+
+// assetNotAcceptedError rejects an order in an asset and chain the merchant does not accept.
+type assetNotAcceptedError struct{ Msg string }
+
+func (e *assetNotAcceptedError) Error() string { return e.Msg }
+
+// checkAcceptedAsset returns an *assetNotAcceptedError unless the merchant accepts asset on chain.
+func checkAcceptedAsset(ctx context.Context, q queryer, merchantID, asset, chain string) error {
+	var accepted sql.NullString
+	if err := q.QueryRowContext(ctx, `SELECT accepted_assets FROM merchants WHERE id = ?`, merchantID).Scan(&accepted); err != nil {
+		return err
+	}
+	if !accepted.Valid {
+		return nil
+	}
+	pairs := parseAcceptedAssets(accepted.String)
+	names := make([]string, len(pairs))
+	for i, a := range pairs {
+		if strings.EqualFold(a.Asset, asset) && strings.EqualFold(a.Chain, chain) {
+			return nil
+		}
+		names[i] = a.String()
+	}
+	return &assetNotAcceptedError{"the merchant does not accept " + strings.ToUpper(asset) + " on " + strings.ToUpper(chain) + "; accepted: " + strings.Join(names, ", ")}
+}
@@ -0,0 +1,404 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Merchants can have the watcher report token transfers to addresses of their own, apart from
+// orders: each chain with watched addresses is scanned for Transfer events of its known tokens, a
+// block range at a time, once the blocks have the chain's finality depth. Every transfer found is
+// recorded once and raises address.transfer_received.
+const (
+	maxWatchedAddresses = 1000 // per merchant
+	addressWatchBlocks  = 2000 // blocks per log query
+	addressWatchRanges  = 10   // log queries per chain and run
+)
+
+type watchAddressReq struct {
+	Chain   string `json:"chain" validate:"required,chain"`
+	Address string `json:"address" validate:"required"`
+	Label   string `json:"label,omitempty" validate:"max=200"` // e.g. the invoice the address was handed out for
+}
+
+type watchedAddress struct {
+	ID        string  `json:"id"`
+	Chain     string  `json:"chain"`
+	Address   string  `json:"address"`
+	Label     *string `json:"label,omitempty"`
+	CreatedAt string  `json:"created_at"`
+}
+
+type addressTransfer struct {
+	ID          string `json:"id"`
+	WatchID     string `json:"watch_id"`
+	Chain       string `json:"chain"`
+	Asset       string `json:"asset"`
+	Address     string `json:"address"` // the watched address, which received the transfer
+	FromAddress string `json:"from_address"`
+	AmountMinor string `json:"amount_minor"`
+	TxHash      string `json:"tx_hash"`
+	LogIndex    int64  `json:"log_index"`
+	BlockNumber int64  `json:"block_number"`
+	CreatedAt   string `json:"created_at"` // when the watcher found it
+}
+
+const watchedAddressCols = `id, chain, address, label, created_at`
+
+func scanWatchedAddress(row scanner) (watchedAddress, error) {
+	var a watchedAddress
+	var label sql.NullString
+	err := row.Scan(&a.ID, &a.Chain, &a.Address, &label, &a.CreatedAt)
+	a.Label = nullStringPtr(label)
+	return a, err
+}
+
+// StartAddressWatcher scans for transfers to watched addresses every interval.
+func StartAddressWatcher(interval time.Duration) {
+	startScheduler(schedulerAddressWatch, interval, false, func(ctx context.Context) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return scanWatchedAddresses(ctx)
+	})
+}
+
+// scanWatchedAddresses scans every chain with watched addresses and reports how many transfers
+// it found. A failing chain is logged and skipped; the error of the last failure is returned.
+func scanWatchedAddresses(ctx context.Context) (int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT chain FROM watched_addresses WHERE deleted_at IS NULL ORDER BY chain`)
+	if err != nil {
+		return 0, err
+	}
+	var chains []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err == nil {
+			chains = append(chains, c)
+		}
+	}
+	rows.Close()
+	n := 0
+	var lastErr error
+	for _, chain := range chains {
+		found, err := scanWatchChain(ctx, chain)
+		n += found
+		if err != nil {
+			log.Printf("event=address_watch_error chain=%s err=%v", chain, err)
+			lastErr = err
+		}
+	}
+	return n, lastErr
+}
+
+// scanWatchChain scans chain from its cursor up to the last final block. The first scan of a
+// chain starts at the last final block, so only transfers from then on are reported.
+func scanWatchChain(ctx context.Context, chain string) (int, error) {
+	head, err := blockchain.HeadBlock(ctx, chain)
+	if err != nil {
+		return 0, err
+	}
+	final := head
+	if depth := uint64(max(blockchain.FinalityDepth(chain), 1)); head >= depth-1 {
+		final = head - (depth - 1)
+	}
+	var cursor int64
+	err = db.QueryRowContext(ctx, `SELECT last_block FROM address_watch_cursors WHERE chain = ?`, chain).Scan(&cursor)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = db.ExecContext(ctx, `INSERT INTO address_watch_cursors (chain, last_block, updated_at) VALUES (?, ?, ?)`,
+			chain, final, time.Now().UTC().Format(time.RFC3339))
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	found := 0
+	for i := 0; i < addressWatchRanges && uint64(cursor) < final; i++ {
+		from := uint64(cursor) + 1
+		to := min(final, from+addressWatchBlocks-1)
+		n, err := scanWatchRange(ctx, chain, from, to)
+		found += n
+		if err != nil {
+			return found, err
+		}
+		cursor = int64(to)
+	}
+	return found, nil
+}
+
+// scanWatchRange records the transfers to chain's watched addresses in blocks from through to,
+// with their events, and moves the cursor past to, in one transaction.
+func scanWatchRange(ctx context.Context, chain string, from, to uint64) (int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, merchant_id, address FROM watched_addresses WHERE chain = ? AND deleted_at IS NULL
+	`, chain)
+	if err != nil {
+		return 0, err
+	}
+	type watch struct{ id, merchantID, address string }
+	byAddress := map[common.Address][]watch{}
+	for rows.Next() {
+		var w watch
+		if err := rows.Scan(&w.id, &w.merchantID, &w.address); err == nil {
+			a := common.HexToAddress(w.address)
+			byAddress[a] = append(byAddress[a], w)
+		}
+	}
+	rows.Close()
+	recipients := make([]common.Address, 0, len(byAddress))
+	for a := range byAddress {
+		recipients = append(recipients, a)
+	}
+	transfers, err := blockchain.TokenTransfersTo(ctx, chain, from, to, recipients)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	found := 0
+	for _, t := range transfers {
+		for _, w := range byAddress[t.To] {
+			rec := addressTransfer{
+				ID: "atr_" + uuid.New().String(), WatchID: w.id, Chain: chain, Asset: t.Asset, Address: w.address,
+				FromAddress: t.From.Hex(), AmountMinor: t.Amount.String(), TxHash: t.TxHash.Hex(), LogIndex: int64(t.LogIndex),
+				BlockNumber: int64(t.BlockNumber), CreatedAt: now,
+			}
+			res, err := tx.ExecContext(ctx, `
+				INSERT INTO address_transfers (id, watch_id, merchant_id, chain, asset, address, from_address, amount_minor, tx_hash, log_index, block_number, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (watch_id, tx_hash, log_index) DO NOTHING
+			`, rec.ID, rec.WatchID, w.merchantID, rec.Chain, rec.Asset, rec.Address, rec.FromAddress, rec.AmountMinor, rec.TxHash,
+				rec.LogIndex, rec.BlockNumber, now)
+			if err != nil {
+				return 0, err
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				continue
+			}
+			if err := enqueueEvent(ctx, tx, w.merchantID, "watched_address", w.id, webhookAddressTransferReceived, rec); err != nil {
+				return 0, err
+			}
+			found++
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE address_watch_cursors SET last_block = ?, updated_at = ? WHERE chain = ? AND last_block < ?
+	`, to, now, chain, to); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if found > 0 {
+		log.Printf("event=address_transfers_found chain=%s from_block=%d to_block=%d transfers=%d", chain, from, to, found)
+	}
+	return found, nil
+}
+
+// WatchedAddressesHandler godoc
+// @Summary      Watch an address, or list watched addresses
+// @Description  POST registers an address for the watcher to report incoming token transfers to, independently of orders, e.g. one handed out on a legacy invoice. Transfers of the chain's known tokens (USDT, USDC) are reported once their block has the chain's finality depth, from the next scan on, with an address.transfer_received webhook each; GET /watch/addresses/transfers lists them. An address is watched once per merchant and chain; a merchant can watch up to 1000. GET lists the merchant's watched addresses, oldest first.
+// @Tags         watch
+// @Accept       json
+// @Produce      json
+// @Param        address  body  watchAddressReq  false  "Address (POST only)"
+// @Success      200  {array}   watchedAddress
+// @Success      201  {object}  watchedAddress
+// @Failure      400  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /watch/addresses [get]
+// @Router       /watch/addresses [post]
+func WatchedAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	merchantID := merchantIDFromContext(r.Context())
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	switch r.Method {
+	case http.MethodPost:
+		var req watchAddressReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		chain := strings.ToUpper(req.Chain)
+		if !slices.Contains(blockchain.TokenChains(), chain) {
+			badReq(w, "token transfers can be watched on "+strings.Join(blockchain.TokenChains(), ", "))
+			return
+		}
+		addr, err := blockchain.NormalizeAddress(chain, strings.TrimSpace(req.Address))
+		if err != nil {
+			badReq(w, "address: "+err.Error())
+			return
+		}
+		var n int
+		if err := db.QueryRowContext(ctx, `
+			SELECT COUNT(1) FROM watched_addresses WHERE merchant_id = ? AND deleted_at IS NULL
+		`, merchantID).Scan(&n); err != nil {
+			serverErr(w, err)
+			return
+		}
+		if n >= maxWatchedAddresses {
+			writeProblem(w, http.StatusConflict, CodeWatchLimitReached, "remove a watched address first")
+			return
+		}
+		a := watchedAddress{ID: "wad_" + uuid.New().String(), Chain: chain, Address: addr, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+		if label := strings.TrimSpace(req.Label); label != "" {
+			a.Label = &label
+		}
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO watched_addresses (id, merchant_id, chain, address, label, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		`, a.ID, merchantID, a.Chain, a.Address, a.Label, a.CreatedAt); err != nil {
+			if sqliteIsUniqueConstraintError(err) {
+				writeProblem(w, http.StatusConflict, CodeAddressAlreadyWatched, "")
+				return
+			}
+			serverErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, a)
+	case http.MethodGet:
+		rows, err := db.QueryContext(ctx, `
+			SELECT `+watchedAddressCols+` FROM watched_addresses WHERE merchant_id = ? AND deleted_at IS NULL ORDER BY created_at, id
+		`, merchantID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		defer rows.Close()
+		out := []watchedAddress{}
+		for rows.Next() {
+			a, err := scanWatchedAddress(rows)
+			if err != nil {
+				serverErr(w, err)
+				return
+			}
+			out = append(out, a)
+		}
+		writeJSON(w, http.StatusOK, out)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+	}
+}
+
+// DeleteWatchedAddressHandler godoc
+// @Summary      Stop watching an address
+// @Description  Stops reporting transfers to a watched address. The transfers already reported stay listed.
+// @Tags         watch
+// @Produce      json
+// @Param        id  query  string  true  "Watched address ID"
+// @Success      200  {object}  map[string]bool
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /watch/addresses/delete [post]
+func DeleteWatchedAddressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing watched address id")
+		return
+	}
+	res, err := db.ExecContext(r.Context(), `
+		UPDATE watched_addresses SET deleted_at = ? WHERE id = ? AND merchant_id = ? AND deleted_at IS NULL
+	`, time.Now().UTC().Format(time.RFC3339), id, merchantIDFromContext(r.Context()))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusNotFound, CodeWatchedAddressNotFound, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// AddressTransfersHandler godoc
+// @Summary      List transfers to a watched address
+// @Description  Returns the token transfers the watcher found to a watched address, newest first (by block, then log index), including those found before the address was removed.
+// @Tags         watch
+// @Produce      json
+// @Param        id     query  string  true   "Watched address ID"
+// @Param        limit  query  int     false  "Page size (default 100, max 500)"
+// @Success      200  {array}   addressTransfer
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /watch/addresses/transfers [get]
+func AddressTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing watched address id")
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			badReq(w, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+	ctx := r.Context()
+	merchantID := merchantIDFromContext(ctx)
+	var exists bool
+	if err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM watched_addresses WHERE id = ? AND merchant_id = ?)
+	`, id, merchantID).Scan(&exists); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if !exists {
+		writeProblem(w, http.StatusNotFound, CodeWatchedAddressNotFound, "")
+		return
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, watch_id, chain, asset, address, from_address, amount_minor, tx_hash, log_index, block_number, created_at
+		FROM address_transfers WHERE watch_id = ? AND merchant_id = ?
+		ORDER BY block_number DESC, log_index DESC LIMIT ?
+	`, id, merchantID, limit)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	out := []addressTransfer{}
+	for rows.Next() {
+		var t addressTransfer
+		if err := rows.Scan(&t.ID, &t.WatchID, &t.Chain, &t.Asset, &t.Address, &t.FromAddress, &t.AmountMinor, &t.TxHash,
+			&t.LogIndex, &t.BlockNumber, &t.CreatedAt); err != nil {
+			serverErr(w, err)
+			return
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+// adminKey is the shared operator secret for /admin endpoints; empty disables them.
+var adminKey string
+
+// SetAdminKey is called from main.go with the configured operator key.
+func SetAdminKey(key string) { adminKey = key }
+
+const adminCtxKey ctxKey = "admin"
+
+// isAdmin reports whether the request was authenticated by AdminAuthMiddleware.
+func isAdmin(ctx context.Context) bool {
+	ok, _ := ctx.Value(adminCtxKey).(bool)
+	return ok
+}
+
+// AdminAuthMiddleware authenticates operators by the X-Admin-Key header.
+func AdminAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminKey == "" {
+			writeProblem(w, http.StatusForbidden, CodeAdminDisabled, "admin endpoints are disabled; set ADMIN_API_KEY")
+			return
+		}
+		if authBlocked(w, r) {
+			return
+		}
+		key := r.Header.Get("X-Admin-Key")
+		if subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) != 1 {
+			authFailed(r)
+			writeProblem(w, http.StatusUnauthorized, CodeInvalidAdminKey, "Unauthorized")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), adminCtxKey, true)))
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// credentialKey identifies which credential authenticated a request: "key:primary", "key:<id>", "oauth:<grant_id>"
+// or "org:<member_id>".
+const credentialKey ctxKey = "credential"
+
+const primaryCredential = "key:primary"
+
+func credentialFromContext(ctx context.Context) string {
+	c, _ := ctx.Value(credentialKey).(string)
+	return c
+}
+
+type apiKeyCreateReq struct {
+	Label string `json:"label" validate:"max=100"`
+	Scope string `json:"scope"` // space-separated, same vocabulary as OAuth scopes
+}
+
+type apiKeyCreateResp struct {
+	ID     string `json:"id"`
+	APIKey string `json:"api_key"`
+	Label  string `json:"label"`
+	Scope  string `json:"scope"`
+}
+
+type apiKeyInfo struct {
+	ID        string  `json:"id"`
+	Label     string  `json:"label"`
+	Scope     string  `json:"scope"`
+	CreatedAt string  `json:"created_at"`
+	RevokedAt *string `json:"revoked_at,omitempty"`
+}
+
+// credentialOrigin returns who is behind credential: a scoped API key stands for the credential that
+// created it, and so, since only the primary key creates keys, for the primary key. Two credentials
+// of the same origin are held by one person as far as OSPay can tell.
+func credentialOrigin(ctx context.Context, q queryer, credential string) (string, error) {
+	id, ok := strings.CutPrefix(credential, "key:")
+	if !ok || credential == primaryCredential {
+		return credential, nil
+	}
+	var createdBy string
+	err := q.QueryRowContext(ctx, `SELECT COALESCE(created_by, ?) FROM api_keys WHERE id = ?`, primaryCredential, id).Scan(&createdBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return credential, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return credentialOrigin(ctx, q, createdBy)
+}
+
+// lookupScopedAPIKey resolves a secondary merchant API key.
+func lookupScopedAPIKey(ctx context.Context, key string) (id, merchantID, scope string, err error) {
+	err = db.QueryRowContext(ctx, `
+		SELECT id, merchant_id, scope FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL
+	`, hashToken(key)).Scan(&id, &merchantID, &scope)
+	return id, merchantID, scope, err
+}
+
+// APIKeysHandler godoc
+// @Summary      Create or list scoped API keys
+// @Description  POST creates an additional merchant API key limited to the given scopes, e.g. a read-only key for a reporting job; GET lists them. Requires the primary API key. A scoped key counts as the primary key for refund approval: whoever holds the primary key can create any scoped key, so a second approver needs a credential of their own (an organization member's key, an OAuth grant or the admin key).
+// @Tags         merchants
+// @Accept       json
+// @Produce      json
+// @Param        key  body  apiKeyCreateReq  false  "Key info (POST only)"
+// @Success      200  {array}   apiKeyInfo
+// @Success      201  {object}  apiKeyCreateResp
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/api-keys [get]
+// @Router       /merchants/api-keys [post]
+func APIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "API keys can only be managed with the primary merchant API key")
+		return
+	}
+	merchantID := merchantIDFromContext(r.Context())
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	switch r.Method {
+	case http.MethodPost:
+		var req apiKeyCreateReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		scope, ok := parseScopes(req.Scope)
+		if !ok {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidScope, "scope must be a space-separated list of supported scopes")
+			return
+		}
+		key, err := newOpaqueToken("ospay_sk_")
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		id := "key_" + uuid.New().String()
+		now := time.Now().UTC().Format(time.RFC3339)
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO api_keys (id, merchant_id, key_hash, label, scope, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, merchantID, hashToken(key), req.Label, scope, credentialFromContext(ctx), now); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		noStore(w)
+		writeJSON(w, http.StatusCreated, apiKeyCreateResp{ID: id, APIKey: key, Label: req.Label, Scope: scope})
+	case http.MethodGet:
+		rows, err := db.QueryContext(ctx, `
+			SELECT id, label, scope, created_at, revoked_at FROM api_keys WHERE merchant_id = ? ORDER BY created_at, id
+		`, merchantID)
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		defer rows.Close()
+		keys := []apiKeyInfo{}
+		for rows.Next() {
+			var (
+				k         apiKeyInfo
+				revokedAt sql.NullString
+			)
+			if err := rows.Scan(&k.ID, &k.Label, &k.Scope, &k.CreatedAt, &revokedAt); err != nil {
+				writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+				return
+			}
+			if revokedAt.Valid {
+				k.RevokedAt = &revokedAt.String
+			}
+			keys = append(keys, k)
+		}
+		writeJSON(w, http.StatusOK, keys)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+// RevokeAPIKeyHandler godoc
+// @Summary      Revoke a scoped API key
+// @Description  Revokes one of the merchant's scoped API keys. Requires the primary API key.
+// @Tags         merchants
+// @Produce      json
+// @Param        id  query  string  true  "API key ID"
+// @Success      200  {object}  map[string]bool
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/api-keys/revoke [post]
+func RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "API keys can only be managed with the primary merchant API key")
+		return
+	}
+	id := pathID(r)
+	res, err := db.ExecContext(r.Context(), `
+		UPDATE api_keys SET revoked_at = ? WHERE id = ? AND merchant_id = ? AND revoked_at IS NULL
+	`, time.Now().UTC().Format(time.RFC3339), id, merchantIDFromContext(r.Context()))
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusNotFound, CodeAPIKeyNotFound, "API key not found or already revoked")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"revoked": true})
+}
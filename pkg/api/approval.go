@@ -0,0 +1,296 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Merchant states. In compliance mode new merchants start PENDING_APPROVAL and their API key is
+// refused, apart from their status and webhook settings, until an administrator approves them.
+const (
+	merchantActive          = "ACTIVE"
+	merchantPendingApproval = "PENDING_APPROVAL"
+	merchantRejected        = "REJECTED"
+)
+
+var (
+	approvalMu       sync.Mutex
+	approvalRequired bool
+	approvalAlertURL string
+)
+
+// SetMerchantApproval turns compliance mode on or off and sets the URL new applications are
+// announced to (merchant.pending_approval); "" only logs them.
+func SetMerchantApproval(required bool, alertURL string) {
+	approvalMu.Lock()
+	defer approvalMu.Unlock()
+	approvalRequired, approvalAlertURL = required, alertURL
+}
+
+// newMerchantStatus is the status a merchant is created with.
+func newMerchantStatus() string {
+	approvalMu.Lock()
+	defer approvalMu.Unlock()
+	if approvalRequired {
+		return merchantPendingApproval
+	}
+	return merchantActive
+}
+
+// announceApplication alerts operators to a merchant waiting for approval.
+func announceApplication(m store.Merchant) {
+	if m.Status != merchantPendingApproval {
+		return
+	}
+	log.Printf("event=merchant_pending_approval merchant_id=%s name=%q", m.ID, m.Name)
+	approvalMu.Lock()
+	url := approvalAlertURL
+	approvalMu.Unlock()
+	go sendOperatorAlert(url, "merchant.pending_approval", merchantRecordOf(m))
+}
+
+// checkMerchantActive refuses a credential of a merchant that is not ACTIVE, or was deleted, and
+// reports whether the request may go on. allowPending lets a merchant waiting for approval through.
+func checkMerchantActive(ctx context.Context, w http.ResponseWriter, merchantID string, allowPending bool) bool {
+	var status string
+	var reason, deletedAt sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT status, status_reason, deleted_at FROM merchants WHERE id = ?`, merchantID).Scan(&status, &reason, &deletedAt)
+	if err != nil {
+		serverErr(w, err)
+		return false
+	}
+	switch {
+	case deletedAt.Valid:
+		writeProblem(w, http.StatusForbidden, CodeMerchantDeleted, "")
+	case status == merchantActive:
+		return true
+	case status == merchantPendingApproval && allowPending:
+		return true
+	case status == merchantPendingApproval:
+		writeProblem(w, http.StatusForbidden, CodeMerchantPendingApproval, "the API key is activated once an administrator approves the merchant; GET /v1/merchants/status shows the decision")
+	default:
+		detail := "the merchant application was rejected"
+		if reason.String != "" {
+			detail += ": " + reason.String
+		}
+		writeProblem(w, http.StatusForbidden, CodeMerchantRejected, detail)
+	}
+	return false
+}
+
+// merchantRecord is a merchant as administrators review it, and the payload of the
+// merchant.approved and merchant.rejected events.
+type merchantRecord struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	WalletAddress  string  `json:"merchant_wallet_address"`
+	PlatformID     *string `json:"platform_id,omitempty"`
+	OrganizationID *string `json:"organization_id,omitempty"`
+	Status         string  `json:"status"` // ACTIVE, PENDING_APPROVAL or REJECTED
+	Reason         *string `json:"reason,omitempty"`
+	DecidedBy      *string `json:"decided_by,omitempty"`
+	DecidedAt      *string `json:"decided_at,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	DeletedAt      *string `json:"deleted_at,omitempty"`
+	DeletedBy      *string `json:"deleted_by,omitempty"`
+}
+
+func merchantRecordOf(m store.Merchant) merchantRecord {
+	rec := merchantRecord{ID: m.ID, Name: m.Name, WalletAddress: m.WalletAddress, Status: m.Status, CreatedAt: m.CreatedAt}
+	if m.PlatformID != "" {
+		rec.PlatformID = &m.PlatformID
+	}
+	if m.OrganizationID != "" {
+		rec.OrganizationID = &m.OrganizationID
+	}
+	return rec
+}
+
+const merchantRecordCols = `id, COALESCE(name, ''), COALESCE(merchant_wallet_address, ''), platform_id, organization_id, status,
+	status_reason, status_decided_by, status_decided_at, created_at, deleted_at, deleted_by`
+
+func scanMerchantRecord(row interface{ Scan(...any) error }) (merchantRecord, error) {
+	var m merchantRecord
+	var platform, org, reason, by, at, deletedAt, deletedBy sql.NullString
+	if err := row.Scan(&m.ID, &m.Name, &m.WalletAddress, &platform, &org, &m.Status, &reason, &by, &at, &m.CreatedAt, &deletedAt, &deletedBy); err != nil {
+		return m, err
+	}
+	m.PlatformID, m.OrganizationID = nullStringPtr(platform), nullStringPtr(org)
+	m.Reason, m.DecidedBy, m.DecidedAt = nullStringPtr(reason), nullStringPtr(by), nullStringPtr(at)
+	m.DeletedAt, m.DeletedBy = nullStringPtr(deletedAt), nullStringPtr(deletedBy)
+	return m, nil
+}
+
+// MerchantStatusHandler godoc
+// @Summary      Get the merchant's approval status
+// @Description  Returns the merchant and its status: ACTIVE, PENDING_APPROVAL while compliance mode (MERCHANT_APPROVAL_REQUIRED) holds it for an administrator, or REJECTED with the reason. A pending merchant's API key only works here and for the webhook settings; the decision is also sent as a merchant.approved or merchant.rejected webhook.
+// @Tags         merchants
+// @Produce      json
+// @Success      200  {object}  merchantRecord
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/status [get]
+func MerchantStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	m, err := scanMerchantRecord(db.QueryRowContext(r.Context(), `SELECT `+merchantRecordCols+` FROM merchants WHERE id = ?`, merchantIDFromContext(r.Context())))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// AdminMerchantsHandler godoc
+// @Summary      List merchants
+// @Description  Lists merchants, newest first, optionally by status: PENDING_APPROVAL for the applications waiting for a decision, ACTIVE or REJECTED. Deleted merchants are left out; deleted=true lists them instead, for restoring. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        status   query  string  false  "Status"
+// @Param        deleted  query  bool    false  "List the deleted merchants"
+// @Success      200  {array}   merchantRecord
+// @Failure      500  {object}  Problem
+// @Router       /admin/merchants [get]
+func AdminMerchantsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	status := strings.ToUpper(r.URL.Query().Get("status"))
+	deleted := r.URL.Query().Get("deleted") == "true"
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+merchantRecordCols+` FROM merchants
+		WHERE (? = '' OR status = ?) AND (deleted_at IS NOT NULL) = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT 500
+	`, status, status, deleted)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	merchants := []merchantRecord{}
+	for rows.Next() {
+		m, err := scanMerchantRecord(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		merchants = append(merchants, m)
+	}
+	writeJSONOrders(w, http.StatusOK, merchants)
+}
+
+type merchantDecisionReq struct {
+	Reason string `json:"reason" validate:"max=1000"` // shown to the merchant; required to reject
+}
+
+// ApproveMerchantHandler godoc
+// @Summary      Approve a merchant
+// @Description  Activates a merchant waiting for approval: its API keys start working and a merchant.approved webhook is sent. Admin only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id        query  string               true   "Merchant ID"
+// @Param        decision  body   merchantDecisionReq  false  "Optional note"
+// @Success      200  {object}  merchantRecord
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/merchants/approve [post]
+func ApproveMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	decideMerchant(w, r, merchantActive)
+}
+
+// RejectMerchantHandler godoc
+// @Summary      Reject a merchant
+// @Description  Rejects a merchant waiting for approval with a reason, which its API key is then refused with; a merchant.rejected webhook is sent. Admin only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id        query  string               true  "Merchant ID"
+// @Param        decision  body   merchantDecisionReq  true  "Reason"
+// @Success      200  {object}  merchantRecord
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/merchants/reject [post]
+func RejectMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	decideMerchant(w, r, merchantRejected)
+}
+
+func decideMerchant(w http.ResponseWriter, r *http.Request, status string) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	var req merchantDecisionReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if status == merchantRejected && req.Reason == "" {
+		badReq(w, "reason is required")
+		return
+	}
+	ctx := r.Context()
+	id := pathID(r)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer tx.Rollback()
+	var current string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM merchants WHERE id = ? AND deleted_at IS NULL`, id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeMerchantNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if current != merchantPendingApproval {
+		writeProblem(w, http.StatusConflict, CodeMerchantAlreadyDecided, "the merchant is "+current)
+		return
+	}
+	actor, now := actorFromContext(ctx), time.Now().UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE merchants SET status = ?, status_reason = ?, status_decided_by = ?, status_decided_at = ? WHERE id = ?
+	`, status, sql.NullString{String: req.Reason, Valid: req.Reason != ""}, actor, now, id); err != nil {
+		serverErr(w, err)
+		return
+	}
+	m, err := scanMerchantRecord(tx.QueryRowContext(ctx, `SELECT `+merchantRecordCols+` FROM merchants WHERE id = ?`, id))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	event := webhookMerchantApproved
+	if status == merchantRejected {
+		event = webhookMerchantRejected
+	}
+	if err := enqueueEvent(ctx, tx, id, "merchant", id, event, m); err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(ctx, tx, actor, id, "", event, map[string]string{"reason": req.Reason})
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=%s merchant_id=%s by=%s", strings.ReplaceAll(event, ".", "_"), id, actor)
+	writeJSON(w, http.StatusOK, m)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// A payment attempt is a transaction hash reported for an order and how its verification went, so
+// support can tell a customer why a payment was not taken. Reporting a hash again updates its
+// attempt rather than adding one.
+const (
+	attemptPending   = "pending"   // queued for verification
+	attemptSucceeded = "succeeded" // the payment was taken (possibly held for review or confirmations)
+	attemptFailed    = "failed"
+)
+
+// Failure reasons of a failed attempt.
+const (
+	attemptWrongAmount       = "wrong_amount"
+	attemptWrongToken        = "wrong_token"
+	attemptWrongChain        = "wrong_chain" // a known token reached the deposit address on another chain
+	attemptWrongRecipient    = "wrong_recipient"
+	attemptNoTransfer        = "no_transfer"
+	attemptTxNotFound        = "tx_not_found"
+	attemptOrderExpired      = "order_expired"    // paid after the late payment grace window
+	attemptAlreadyPaid       = "already_paid"     // the order was paid by another transaction
+	attemptTxAlreadyUsed     = "tx_already_used"  // the transaction was credited as another order's overpayment
+	attemptMultipleSenders   = "multiple_senders" // an overpayment sent from several addresses, with no single one to refund
+	attemptVerificationError = "verification_error"
+)
+
+// paymentAttempt is a reported transaction hash of an order.
+type paymentAttempt struct {
+	ID            string  `json:"id"`
+	TxHash        string  `json:"tx_hash"`
+	Status        string  `json:"status"`                   // pending | succeeded | failed
+	FailureReason *string `json:"failure_reason,omitempty"` // wrong_amount | wrong_token | wrong_chain | wrong_recipient | no_transfer | tx_not_found | order_expired | already_paid | tx_already_used | multiple_senders | verification_error
+	Detail        *string `json:"detail,omitempty"`
+	Submissions   int64   `json:"submissions"` // times the hash was reported
+	CreatedAt     string  `json:"created_at"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+// verificationFailureReason classifies an error of blockchain.VerifyBSCUSDTransfer.
+func verificationFailureReason(err error) string {
+	switch {
+	case errors.Is(err, blockchain.ErrWrongAmount):
+		return attemptWrongAmount
+	case errors.Is(err, blockchain.ErrWrongToken):
+		return attemptWrongToken
+	case errors.Is(err, blockchain.ErrWrongRecipient):
+		return attemptWrongRecipient
+	case errors.Is(err, blockchain.ErrNoTransfer):
+		return attemptNoTransfer
+	case errors.Is(err, ethereum.NotFound):
+		return attemptTxNotFound
+	case errors.Is(err, errOverpaymentSenders):
+		return attemptMultipleSenders
+	}
+	return attemptVerificationError
+}
+
+// mismatchedTransfer reports whether err says a mined transaction does not pay the order, which
+// verifying again cannot change.
+func mismatchedTransfer(err error) bool {
+	switch verificationFailureReason(err) {
+	case attemptTxNotFound, attemptVerificationError:
+		return false
+	}
+	return true
+}
+
+// recordPaymentAttempt records the outcome of a reported transaction hash; submitted counts a
+// report. A succeeded attempt stays succeeded when its hash is reported again.
+func recordPaymentAttempt(ctx context.Context, q execer, orderID, merchantID, txHash string, submitted bool, status, reason, detail string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	n := 0
+	if submitted {
+		n = 1
+	}
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO payment_attempts (id, order_id, merchant_id, tx_hash, status, failure_reason, detail, submissions, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)
+		ON CONFLICT (order_id, tx_hash) DO UPDATE SET
+		  status = CASE WHEN payment_attempts.status = 'succeeded' THEN payment_attempts.status ELSE excluded.status END,
+		  failure_reason = CASE WHEN payment_attempts.status = 'succeeded' THEN payment_attempts.failure_reason ELSE excluded.failure_reason END,
+		  detail = CASE WHEN payment_attempts.status = 'succeeded' THEN payment_attempts.detail ELSE excluded.detail END,
+		  submissions = payment_attempts.submissions + excluded.submissions,
+		  updated_at = excluded.updated_at
+	`, "pat_"+uuid.New().String(), orderID, merchantID, strings.ToLower(txHash), status, reason, detail, n, now, now)
+	return err
+}
+
+// logPaymentAttempt records an attempt outside the payment's transaction; a failure to record it
+// does not fail the payment.
+func logPaymentAttempt(orderID, merchantID, txHash string, submitted bool, status, reason, detail string) {
+	ctx, cancel := store.WithTimeout(context.Background(), store.OpWrite)
+	defer cancel()
+	if err := recordPaymentAttempt(ctx, db, orderID, merchantID, txHash, submitted, status, reason, detail); err != nil {
+		log.Printf("record payment attempt order=%s tx=%s: %v", orderID, txHash, err)
+	}
+}
+
+// logSettledPaymentAttempt records a report for an order that was already paid: it succeeded if
+// the order was paid by txHash.
+func logSettledPaymentAttempt(orderID, merchantID, txHash string, submitted bool) {
+	ctx, cancel := store.WithTimeout(context.Background(), store.OpWrite)
+	defer cancel()
+	var paidBy sql.NullString
+	if err := db.QueryRowContext(ctx, `SELECT tx_hash FROM orders WHERE id = ?`, orderID).Scan(&paidBy); err != nil {
+		log.Printf("record payment attempt order=%s tx=%s: %v", orderID, txHash, err)
+		return
+	}
+	if strings.EqualFold(paidBy.String, txHash) {
+		logPaymentAttempt(orderID, merchantID, txHash, submitted, attemptSucceeded, "", "")
+		return
+	}
+	logPaymentAttempt(orderID, merchantID, txHash, submitted, attemptFailed, attemptAlreadyPaid, "")
+}
+
+func loadPaymentAttempts(ctx context.Context, q queryer, orderID string) ([]paymentAttempt, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, tx_hash, status, failure_reason, detail, submissions, created_at, updated_at
+		FROM payment_attempts WHERE order_id = ? ORDER BY created_at, rowid
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	attempts := []paymentAttempt{}
+	for rows.Next() {
+		var (
+			a              paymentAttempt
+			reason, detail sql.NullString
+		)
+		if err := rows.Scan(&a.ID, &a.TxHash, &a.Status, &reason, &detail, &a.Submissions, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		a.FailureReason, a.Detail = nullStringPtr(reason), nullStringPtr(detail)
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// A reserve attestation is a proof-of-reserves style report: per chain and asset, what the
+// merchants are owed next to what the custody wallets hold on-chain, signed with the hot wallet's
+// key so that merchants and auditors can check it was issued by the platform and not altered.
+
+type reserveAsset struct {
+	Chain string `json:"chain"`
+	Asset string `json:"asset"`
+	// LiabilitiesMinor is what is owed to merchants: their custody buckets (all but platform_fee)
+	// less payouts and fiat payouts that have left the wallets but are not booked yet.
+	LiabilitiesMinor string          `json:"liabilities_minor"`
+	ReservesMinor    string          `json:"reserves_minor"` // held by the custody wallets
+	SurplusMinor     string          `json:"surplus_minor"`  // reserves_minor - liabilities_minor
+	CoverageBps      *int64          `json:"coverage_bps,omitempty"`
+	FullyBacked      bool            `json:"fully_backed"` // reserves cover the liabilities
+	Wallets          []walletBalance `json:"wallets"`
+}
+
+// reserveReport is the signed content of an attestation.
+type reserveReport struct {
+	ID          string         `json:"id"`
+	GeneratedAt string         `json:"generated_at"`
+	Signer      string         `json:"signer"`
+	FullyBacked bool           `json:"fully_backed"` // every asset is
+	Assets      []reserveAsset `json:"assets"`
+}
+
+type reserveAttestation struct {
+	ID          string `json:"id"`
+	GeneratedAt string `json:"generated_at"`
+	// Report is the reserveReport exactly as signed: verify signature against these bytes.
+	Report    json.RawMessage `json:"report" swaggertype:"object"`
+	Signature string          `json:"signature"` // EIP-191 personal_sign of report
+	Signer    string          `json:"signer"`    // address that signed, the hot wallet
+}
+
+var errNoAttestationSigner = errors.New("no hot wallet signer configured to sign attestations")
+
+// StartReserveAttestations issues a reserve attestation every interval. It does nothing unless
+// on-chain reconciliation and the hot wallet signer are configured.
+func StartReserveAttestations(interval time.Duration) {
+	if !reconConfigured() || txSigner == nil {
+		return
+	}
+	startScheduler(schedulerAttestations, interval, false, func(ctx context.Context) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		a, err := attestReserves(ctx)
+		if err != nil {
+			return 0, err
+		}
+		var report reserveReport
+		_ = json.Unmarshal(a.Report, &report)
+		return len(report.Assets), nil
+	})
+}
+
+// attestReserves builds, signs and stores a reserve attestation. It fails when a custody wallet's
+// balance cannot be read rather than attest to part of the reserves.
+func attestReserves(ctx context.Context) (reserveAttestation, error) {
+	if txSigner == nil {
+		return reserveAttestation{}, errNoAttestationSigner
+	}
+	recon, err := onchainReconciliation(ctx)
+	if err != nil {
+		return reserveAttestation{}, err
+	}
+	report := reserveReport{
+		ID: uuid.New().String(), GeneratedAt: recon.CheckedAt, Signer: txSigner.Address().Hex(),
+		FullyBacked: true, Assets: []reserveAsset{},
+	}
+	for _, l := range recon.Assets {
+		if l.Error != "" {
+			return reserveAttestation{}, &rpcError{fmt.Errorf("%s %s: %s", l.Chain, l.Asset, l.Error)}
+		}
+		liabilities, _ := new(big.Int).SetString(l.BooksMinor, 10)
+		if fee, ok := new(big.Int).SetString(l.Buckets[bucketPlatformFee], 10); ok {
+			liabilities.Sub(liabilities, fee)
+		}
+		reserves, _ := new(big.Int).SetString(l.ChainMinor, 10)
+		surplus := new(big.Int).Sub(reserves, liabilities)
+		line := reserveAsset{
+			Chain: l.Chain, Asset: l.Asset, LiabilitiesMinor: liabilities.String(), ReservesMinor: reserves.String(),
+			SurplusMinor: surplus.String(), FullyBacked: surplus.Sign() >= 0, Wallets: l.Wallets,
+		}
+		if liabilities.Sign() > 0 {
+			if bps := new(big.Int).Quo(new(big.Int).Mul(reserves, big.NewInt(10000)), liabilities); bps.IsInt64() {
+				v := bps.Int64()
+				line.CoverageBps = &v
+			}
+		}
+		report.FullyBacked = report.FullyBacked && line.FullyBacked
+		report.Assets = append(report.Assets, line)
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return reserveAttestation{}, err
+	}
+	sig, err := txSigner.SignHash(blockchain.PersonalMessageHash(body))
+	if err != nil {
+		return reserveAttestation{}, err
+	}
+	a := reserveAttestation{
+		ID: report.ID, GeneratedAt: report.GeneratedAt, Report: body, Signature: hexutil.Encode(sig), Signer: report.Signer,
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO reserve_attestations (id, report, signature, signer, fully_backed, created_at) VALUES (?, ?, ?, ?, ?, ?)
+	`, a.ID, string(body), a.Signature, a.Signer, report.FullyBacked, a.GeneratedAt); err != nil {
+		return reserveAttestation{}, err
+	}
+	return a, nil
+}
+
+// rpcError marks a failure to read the chain, as opposed to one of the database.
+type rpcError struct{ err error }
+
+func (e *rpcError) Error() string { return e.err.Error() }
+func (e *rpcError) Unwrap() error { return e.err }
+
+// CreateAttestationHandler godoc
+// @Summary      Issue a reserve attestation
+// @Description  Reads the custody wallets' balances now and issues a signed reserve attestation: per chain and asset, liabilities_minor (what merchants are owed: their ledger balances less payouts and fiat payouts that have left the wallets) next to reserves_minor (what the custody wallets hold on-chain), with surplus_minor, coverage_bps and fully_backed. Platform fees are not liabilities, so they show as surplus. report is signed as is with the hot wallet's key (EIP-191 personal_sign), so anyone can recover signer from report and signature. An attestation is also issued every ATTESTATION_INTERVAL. Fails with 502 rpc_unavailable rather than attest to part of the reserves when a wallet's balance cannot be read. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Success      201  {object}  reserveAttestation
+// @Failure      409  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/attestations/generate [post]
+func CreateAttestationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	if !reconConfigured() {
+		writeProblem(w, http.StatusConflict, CodeReconciliationNotConfigured, "set RECONCILE_WALLETS or HOT_WALLET_ADDRESS")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	a, err := attestReserves(ctx)
+	var rpcErr *rpcError
+	switch {
+	case errors.Is(err, errNoAttestationSigner):
+		writeProblem(w, http.StatusConflict, CodeHotWalletUnavailable, err.Error())
+		return
+	case errors.As(err, &rpcErr):
+		writeProblem(w, http.StatusBadGateway, CodeRPCUnavailable, err.Error())
+		return
+	case err != nil:
+		serverErr(w, err)
+		return
+	}
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), "", "", "reserve_attestation", map[string]any{"attestation_id": a.ID})
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// ListAttestationsHandler godoc
+// @Summary      List reserve attestations
+// @Description  Returns the signed reserve attestations, newest first. Each carries the report that was signed, per chain and asset what merchants are owed next to what the custody wallets hold, with its signature and signer; see POST /admin/attestations/generate. fully_backed narrows the list to attestations where every asset was (true) or was not (false) fully backed.
+// @Tags         reconciliation
+// @Produce      json
+// @Param        fully_backed  query  bool  false  "Only attestations that were (not) fully backed"
+// @Param        limit         query  int   false  "Page size (default 30, max 365)"
+// @Success      200  {array}   reserveAttestation
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /attestations [get]
+// @Router       /admin/attestations [get]
+func ListAttestationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	limit := 30
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 365 {
+			badReq(w, "limit must be between 1 and 365")
+			return
+		}
+		limit = n
+	}
+	backed := -1
+	if v := q.Get("fully_backed"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			badReq(w, "fully_backed must be true or false")
+			return
+		}
+		backed = 0
+		if b {
+			backed = 1
+		}
+	}
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT id, report, signature, signer, created_at FROM reserve_attestations
+		WHERE (? = -1 OR fully_backed = ?)
+		ORDER BY created_at DESC, id DESC LIMIT ?
+	`, backed, backed, limit)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	out := []reserveAttestation{}
+	for rows.Next() {
+		var a reserveAttestation
+		var report string
+		if err := rows.Scan(&a.ID, &report, &a.Signature, &a.Signer, &a.GeneratedAt); err != nil {
+			serverErr(w, err)
+			return
+		}
+		a.Report = json.RawMessage(report)
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// GetAttestationHandler godoc
+// @Summary      Get a reserve attestation
+// @Description  Returns a signed reserve attestation. With format=csv the report is returned as CSV instead, one line per chain and asset, for spreadsheets; the signature, which covers the JSON report, is in the X-Attestation-Signature and X-Attestation-Signer headers.
+// @Tags         reconciliation
+// @Produce      json,text/csv
+// @Param        id      query  string  true   "Attestation ID"
+// @Param        format  query  string  false  "json (default) or csv"
+// @Success      200  {object}  reserveAttestation
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /attestations/get [get]
+// @Router       /admin/attestations/get [get]
+func GetAttestationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing attestation id")
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "csv" {
+		badReq(w, "format must be json or csv")
+		return
+	}
+	var a reserveAttestation
+	var report string
+	err := db.QueryRowContext(r.Context(), `
+		SELECT id, report, signature, signer, created_at FROM reserve_attestations WHERE id = ?
+	`, id).Scan(&a.ID, &report, &a.Signature, &a.Signer, &a.GeneratedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeAttestationNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	a.Report = json.RawMessage(report)
+	if format != "csv" {
+		writeJSON(w, http.StatusOK, a)
+		return
+	}
+	var rr reserveReport
+	if err := json.Unmarshal(a.Report, &rr); err != nil {
+		serverErr(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="attestation-`+a.ID+`.csv"`)
+	w.Header().Set("X-Attestation-Signature", a.Signature)
+	w.Header().Set("X-Attestation-Signer", a.Signer)
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"attestation_id", "generated_at", "chain", "asset", "liabilities_minor", "reserves_minor", "surplus_minor", "coverage_bps", "fully_backed"})
+	for _, l := range rr.Assets {
+		coverage := ""
+		if l.CoverageBps != nil {
+			coverage = strconv.FormatInt(*l.CoverageBps, 10)
+		}
+		_ = cw.Write([]string{rr.ID, rr.GeneratedAt, l.Chain, l.Asset, l.LiabilitiesMinor, l.ReservesMinor, l.SurplusMinor,
+			coverage, strconv.FormatBool(l.FullyBacked)})
+	}
+	cw.Flush()
+}
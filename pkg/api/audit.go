@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// actorFromContext names who performed a request for the audit log: "admin", a merchant
+// credential ("key:primary", "oauth:<grant>") or "system" for background jobs.
+func actorFromContext(ctx context.Context) string {
+	if isAdmin(ctx) {
+		return "admin"
+	}
+	if c := credentialFromContext(ctx); c != "" {
+		return c
+	}
+	return "system"
+}
+
+// recordAudit appends an entry to audit_log. detail is stored as JSON. Failures are logged rather
+// than returned so auditing never blocks the operation it describes; pass the surrounding tx when
+// the entry must commit together with it.
+func recordAudit(ctx context.Context, ex execer, actor, merchantID, orderID, action string, detail any) {
+	payload, err := json.Marshal(detail)
+	if err != nil {
+		payload = []byte(`{}`)
+	}
+	var oid sql.NullString
+	if orderID != "" {
+		oid = sql.NullString{String: orderID, Valid: true}
+	}
+	if _, err := ex.ExecContext(ctx, `
+		INSERT INTO audit_log (id, actor, merchant_id, order_id, action, detail_json, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, "aud_"+uuid.New().String(), actor, merchantID, oid, action, string(payload), time.Now().UTC().Format(time.RFC3339)); err != nil {
+		log.Printf("audit: failed to record %s for merchant %s: %v", action, merchantID, err)
+	}
+}
+
+type auditEntry struct {
+	ID         string          `json:"id"`
+	Actor      string          `json:"actor"`
+	MerchantID *string         `json:"merchant_id,omitempty"`
+	OrderID    *string         `json:"order_id,omitempty"`
+	Action     string          `json:"action"`
+	Detail     json.RawMessage `json:"detail" swaggertype:"object"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// AuditLogHandler godoc
+// @Summary      List audit log entries
+// @Description  Returns the most recent audit entries (newest first), optionally filtered by merchant_id, order_id and action. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        merchant_id  query  string  false  "Merchant ID"
+// @Param        order_id     query  string  false  "Order ID"
+// @Param        action       query  string  false  "Action, e.g. limit_exceeded"
+// @Success      200  {array}   auditEntry
+// @Failure      500  {object}  Problem
+// @Router       /admin/audit [get]
+func AuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	merchantID, orderID, action := q.Get("merchant_id"), q.Get("order_id"), q.Get("action")
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT id, actor, merchant_id, order_id, action, detail_json, created_at
+		FROM audit_log
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR order_id = ?) AND (? = '' OR action = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 500
+	`, merchantID, merchantID, orderID, orderID, action, action)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	entries := []auditEntry{}
+	for rows.Next() {
+		var (
+			e        auditEntry
+			mid, oid sql.NullString
+			detail   string
+		)
+		if err := rows.Scan(&e.ID, &e.Actor, &mid, &oid, &e.Action, &detail, &e.CreatedAt); err != nil {
+			serverErr(w, err)
+			return
+		}
+		e.MerchantID = nullStringPtr(mid)
+		e.OrderID = nullStringPtr(oid)
+		e.Detail = json.RawMessage(detail)
+		entries = append(entries, e)
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
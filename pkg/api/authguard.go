@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Failed authentications (an invalid API key, bearer token or admin key) are counted per source IP.
+// Past authFreeFailures in authFailureWindow, each further failure is answered more slowly, and at
+// banAfter failures the IP is banned: every request from it that authenticates is refused with 429
+// until the ban ends, valid keys included. A failure soon after a ban ends bans the IP again, for
+// twice as long as the last time, up to maxAuthBan. The counts are kept in memory, per instance.
+const (
+	authFailureWindow = 15 * time.Minute
+	authFreeFailures  = 5
+	maxAuthDelay      = 5 * time.Second
+	maxAuthBan        = 24 * time.Hour
+)
+
+type authFailures struct {
+	count       int // within the window
+	first, last time.Time
+	bans        int // bans so far, for escalation
+	bannedUntil time.Time
+}
+
+var (
+	authGuardMu   sync.Mutex
+	authByIP      = map[string]*authFailures{}
+	authBanAfter  = 20
+	authBanFor    = 15 * time.Minute
+	authAlertURL  string
+	authFailTotal int64
+	authBansTotal int64
+)
+
+// SetAuthGuard sets after how many failed authentications in 15 minutes an IP is banned, for how
+// long the first ban lasts, and the URL auth.ip_banned alerts are POSTed to; "" only logs them.
+// banAfter 0 turns bans off; failures are still slowed down.
+func SetAuthGuard(banAfter int, banFor time.Duration, alertURL string) {
+	authGuardMu.Lock()
+	defer authGuardMu.Unlock()
+	authBanAfter, authBanFor, authAlertURL = banAfter, banFor, alertURL
+}
+
+// authBlocked refuses a request from a banned IP and reports whether it did.
+func authBlocked(w http.ResponseWriter, r *http.Request) bool {
+	ip := clientIP(r)
+	authGuardMu.Lock()
+	f := authByIP[ip]
+	var left time.Duration
+	if f != nil {
+		left = time.Until(f.bannedUntil)
+	}
+	authGuardMu.Unlock()
+	if left <= 0 {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(left.Seconds())+1))
+	writeProblem(w, http.StatusTooManyRequests, CodeAuthBlocked, "too many failed authentication attempts from this IP; try again later")
+	return true
+}
+
+// ipBan is the payload of auth.ip_banned, and an entry of the admin ban list.
+type ipBan struct {
+	IP          string `json:"ip"`
+	Failures    int    `json:"failures"` // in the window that led to the ban
+	FirstFailed string `json:"first_failed_at"`
+	LastFailed  string `json:"last_failed_at"`
+	Bans        int    `json:"bans"` // how many times the IP was banned, this one included
+	BannedUntil string `json:"banned_until"`
+}
+
+func ipBanOf(ip string, f *authFailures) ipBan {
+	return ipBan{
+		IP: ip, Failures: f.count, Bans: f.bans,
+		FirstFailed: f.first.Format(time.RFC3339), LastFailed: f.last.Format(time.RFC3339),
+		BannedUntil: f.bannedUntil.Format(time.RFC3339),
+	}
+}
+
+// authFailed counts a failed authentication of r and holds the response back as long as the IP's
+// failures call for. Call it before answering 401.
+func authFailed(r *http.Request) {
+	ip, now := clientIP(r), time.Now().UTC()
+	atomic.AddInt64(&authFailTotal, 1)
+
+	authGuardMu.Lock()
+	if len(authByIP) > 10000 {
+		pruneAuthFailures(now)
+	}
+	f := authByIP[ip]
+	if f == nil {
+		f = &authFailures{}
+		authByIP[ip] = f
+	}
+	if now.Sub(f.last) > authFailureWindow {
+		f.count, f.first = 0, now
+	}
+	f.count++
+	f.last = now
+	var ban *ipBan
+	if authBanAfter > 0 && f.count >= authBanAfter && !now.Before(f.bannedUntil) {
+		d := authBanFor << min(f.bans, 16)
+		f.bans++
+		f.bannedUntil = now.Add(min(d, maxAuthBan))
+		b := ipBanOf(ip, f)
+		ban = &b
+	}
+	excess, alertURL := f.count-authFreeFailures, authAlertURL
+	authGuardMu.Unlock()
+
+	if ban != nil {
+		atomic.AddInt64(&authBansTotal, 1)
+		log.Printf("event=auth_ip_banned ip=%s failures=%d until=%s", ip, ban.Failures, ban.BannedUntil)
+		ctx, cancel := store.WithTimeout(context.Background(), store.OpAuth)
+		recordAudit(ctx, db, "system", "", "", "auth.ip_banned", ban)
+		cancel()
+		go sendOperatorAlert(alertURL, "auth.ip_banned", ban)
+		return
+	}
+	if excess <= 0 {
+		return
+	}
+	// 250ms, doubling with each further failure
+	delay := min(250*time.Millisecond<<min(excess-1, 8), maxAuthDelay)
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-r.Context().Done():
+	}
+}
+
+// pruneAuthFailures forgets the IPs that are not banned and have not failed within the window.
+// The caller holds authGuardMu.
+func pruneAuthFailures(now time.Time) {
+	for ip, f := range authByIP {
+		if now.Sub(f.last) > authFailureWindow && now.After(f.bannedUntil) {
+			delete(authByIP, ip)
+		}
+	}
+}
+
+// authBannedIPs counts the IPs banned now, for /debug/metrics.
+func authBannedIPs() int64 {
+	authGuardMu.Lock()
+	defer authGuardMu.Unlock()
+	var n int64
+	now := time.Now()
+	for _, f := range authByIP {
+		if now.Before(f.bannedUntil) {
+			n++
+		}
+	}
+	return n
+}
+
+// AdminAuthBansHandler godoc
+// @Summary      List IPs banned for failed authentication
+// @Description  Lists the source IPs this instance bans for too many failed authentications (invalid API keys, tokens or admin keys), longest ban first. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}  ipBan
+// @Router       /admin/auth/bans [get]
+func AdminAuthBansHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	bans := []ipBan{}
+	now := time.Now()
+	authGuardMu.Lock()
+	for ip, f := range authByIP {
+		if now.Before(f.bannedUntil) {
+			bans = append(bans, ipBanOf(ip, f))
+		}
+	}
+	authGuardMu.Unlock()
+	sort.Slice(bans, func(i, j int) bool { return bans[i].BannedUntil > bans[j].BannedUntil })
+	writeJSONOrders(w, http.StatusOK, bans)
+}
+
+// LiftAuthBanHandler godoc
+// @Summary      Lift an IP ban
+// @Description  Ends the ban of a source IP on this instance and forgets its failed authentications. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "IP"
+// @Success      200  {object}  map[string]bool
+// @Failure      404  {object}  Problem
+// @Router       /admin/auth/bans/lift [post]
+func LiftAuthBanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	ip := pathID(r)
+	authGuardMu.Lock()
+	f := authByIP[ip]
+	banned := f != nil && time.Now().Before(f.bannedUntil)
+	if banned {
+		delete(authByIP, ip)
+	}
+	authGuardMu.Unlock()
+	if !banned {
+		writeProblem(w, http.StatusNotFound, CodeAuthBanNotFound, "")
+		return
+	}
+	log.Printf("event=auth_ban_lifted ip=%s by=admin", ip)
+	recordAudit(r.Context(), db, "admin", "", "", "auth.ban_lifted", map[string]string{"ip": ip})
+	writeJSON(w, http.StatusOK, map[string]bool{"lifted": true})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/backup"
+)
+
+// backupDir is where POST /admin/backup writes snapshots; callers cannot choose the path.
+var backupDir = "backups"
+
+// SetBackupDir overrides the snapshot directory; empty keeps the default.
+func SetBackupDir(dir string) {
+	if dir != "" {
+		backupDir = dir
+	}
+}
+
+type backupResp struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	CreatedAt string `json:"created_at"`
+}
+
+// BackupHandler godoc
+// @Summary      Snapshot the database
+// @Description  Writes a consistent, integrity-checked copy of the live database to BACKUP_DIR (default ./backups). Restore it with `server restore <file>` while the server is stopped. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Success      201  {object}  backupResp
+// @Failure      500  {object}  Problem
+// @Router       /admin/backup [post]
+func BackupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	now := time.Now().UTC()
+	dest := filepath.Join(backupDir, "ospay-"+now.Format("20060102T150405Z")+".db")
+	if err := backup.Snapshot(r.Context(), db, dest); err != nil {
+		serverErr(w, err)
+		return
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), "", "", "backup_created", map[string]any{"path": dest, "size_bytes": info.Size()})
+	writeJSON(w, http.StatusCreated, backupResp{Path: dest, SizeBytes: info.Size(), CreatedAt: now.Format(time.RFC3339)})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"math/big"
+	"net/http"
+	"sort"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+type assetBalance struct {
+	Asset           string `json:"asset"`
+	Chain           string `json:"chain,omitempty"`
+	AvailableMinor  string `json:"available_minor"`  // settled funds (settlement bucket)
+	PendingMinor    string `json:"pending_minor"`    // paid orders not yet settled (merchant bucket)
+	HeldMinor       string `json:"held_minor"`       // frozen by open disputes or balance holds, or reserved for fiat payouts
+	RefundableMinor string `json:"refundable_minor"` // overpayments to return to customers (overpayment bucket)
+}
+
+type balancesResp struct {
+	MerchantID string         `json:"merchant_id"`
+	Balances   []assetBalance `json:"balances"`
+}
+
+// heldBuckets are the buckets whose funds belong to the merchant but cannot be spent.
+var heldBuckets = map[string]bool{bucketDisputeHold: true, bucketOfframpPending: true, bucketBalanceHold: true}
+
+// MerchantBalancesHandler godoc
+// @Summary      Get merchant balances
+// @Description  Returns the merchant's balance per asset and chain, read from the materialized ledger balances: available (settled funds, the settlement bucket), pending (the merchant's share of PAID and PARTIALLY_REFUNDED orders not yet settled, the merchant bucket), held (frozen by open disputes or balance holds, or reserved for fiat payouts) and refundable (overpayments of already paid orders, to send back to customers with POST /overpayments/{id}/refund; not the merchant's funds). Admins pass merchant_id.
+// @Tags         merchants
+// @Produce      json
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {object}  balancesResp
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/balances [get]
+// @Router       /admin/merchants/balances [get]
+func MerchantBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	ctx := r.Context()
+	merchantID := merchantIDFromContext(ctx)
+	if isAdmin(ctx) {
+		merchantID = r.URL.Query().Get("merchant_id")
+	}
+	buckets, err := stores.Ledger.Balances(ctx, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, balancesResp{MerchantID: merchantID, Balances: sumBalances(buckets)})
+}
+
+// sumBalances totals bucket balances into available, pending, held and refundable per asset and chain,
+// leaving out the ones that are all zero, ordered by asset and chain.
+func sumBalances(buckets []store.BucketBalance) []assetBalance {
+	type key struct{ asset, chain string }
+	type sums struct{ available, pending, held, refundable *big.Int }
+	byKey := map[key]*sums{}
+	get := func(k key) *sums {
+		if byKey[k] == nil {
+			byKey[k] = &sums{new(big.Int), new(big.Int), new(big.Int), new(big.Int)}
+		}
+		return byKey[k]
+	}
+	for _, b := range buckets {
+		v, ok := new(big.Int).SetString(b.AmountMinor, 10)
+		if !ok {
+			continue
+		}
+		switch {
+		case b.Bucket == bucketSettlement:
+			get(key{b.Asset, b.Chain}).available.Add(get(key{b.Asset, b.Chain}).available, v)
+		case b.Bucket == bucketMerchant:
+			get(key{b.Asset, b.Chain}).pending.Add(get(key{b.Asset, b.Chain}).pending, v)
+		case heldBuckets[b.Bucket]:
+			get(key{b.Asset, b.Chain}).held.Add(get(key{b.Asset, b.Chain}).held, v)
+		case b.Bucket == bucketOverpayment:
+			get(key{b.Asset, b.Chain}).refundable.Add(get(key{b.Asset, b.Chain}).refundable, v)
+		}
+	}
+
+	out := []assetBalance{}
+	for k, s := range byKey {
+		if s.available.Sign() == 0 && s.pending.Sign() == 0 && s.held.Sign() == 0 && s.refundable.Sign() == 0 {
+			continue
+		}
+		out = append(out, assetBalance{
+			Asset: k.asset, Chain: k.chain, AvailableMinor: s.available.String(),
+			PendingMinor: s.pending.String(), HeldMinor: s.held.String(), RefundableMinor: s.refundable.String(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		return a.Asset < b.Asset || a.Asset == b.Asset && a.Chain < b.Chain
+	})
+	return out
+}
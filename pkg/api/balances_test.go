@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// memLedger is an in-memory LedgerStore holding materialized balances per merchant.
+type memLedger struct {
+	balances map[string][]store.BucketBalance
+	err      error
+}
+
+func (l *memLedger) Append(context.Context, ...store.LedgerEntry) error { return errors.ErrUnsupported }
+
+func (l *memLedger) Balance(context.Context, string, string, string) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func (l *memLedger) Balances(_ context.Context, merchantID string) ([]store.BucketBalance, error) {
+	return l.balances[merchantID], l.err
+}
+
+func TestMerchantBalancesHandlerUsesInjectedStores(t *testing.T) {
+	ledger := &memLedger{balances: map[string][]store.BucketBalance{
+		"m1": {
+			{Asset: "USDT", Chain: "BSC", Bucket: bucketSettlement, AmountMinor: "700"},
+			{Asset: "USDT", Chain: "BSC", Bucket: bucketMerchant, AmountMinor: "250"},
+			{Asset: "USDT", Chain: "BSC", Bucket: bucketDisputeHold, AmountMinor: "50"},
+			{Asset: "USDC", Chain: "ETH", Bucket: bucketOverpayment, AmountMinor: "9"},
+			{Asset: "USDC", Chain: "POLYGON", Bucket: bucketSettlement, AmountMinor: "0"},
+		},
+		"m2": {{Asset: "USDT", Chain: "BSC", Bucket: bucketSettlement, AmountMinor: "1"}},
+	}}
+	prevDB, prevStores := db, stores
+	InitStores(nil, store.Stores{Ledger: ledger})
+	t.Cleanup(func() { InitStores(prevDB, prevStores) })
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/merchants/me/balances", nil)
+	req = req.WithContext(context.WithValue(req.Context(), merchantIDKey, "m1"))
+	rec := httptest.NewRecorder()
+	MerchantBalancesHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got balancesResp
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []assetBalance{
+		{Asset: "USDC", Chain: "ETH", AvailableMinor: "0", PendingMinor: "0", HeldMinor: "0", RefundableMinor: "9"},
+		{Asset: "USDT", Chain: "BSC", AvailableMinor: "700", PendingMinor: "250", HeldMinor: "50", RefundableMinor: "0"},
+	}
+	if got.MerchantID != "m1" || len(got.Balances) != len(want) {
+		t.Fatalf("got %+v, want merchant m1 with %+v", got, want)
+	}
+	for i := range want {
+		if got.Balances[i] != want[i] {
+			t.Errorf("balance %d = %+v, want %+v", i, got.Balances[i], want[i])
+		}
+	}
+
+	ledger.err = errors.New("ledger unavailable")
+	rec = httptest.NewRecorder()
+	MerchantBalancesHandler(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status with a failing store = %d, want 500", rec.Code)
+	}
+}
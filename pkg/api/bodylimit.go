@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// maxBodyBytes caps request bodies; 1 MiB unless SetMaxBodyBytes changes it.
+var maxBodyBytes atomic.Int64
+
+func init() { maxBodyBytes.Store(1 << 20) }
+
+// SetMaxBodyBytes sets the largest request body accepted, in bytes.
+func SetMaxBodyBytes(n int64) { maxBodyBytes.Store(n) }
+
+// RequestBodyMiddleware refuses a body larger than the limit with 413, whether its Content-Length
+// says so or it turns out larger while being read, and a body that is not JSON (application/json
+// or a +json type) with 415. form also accepts application/x-www-form-urlencoded, for the OAuth
+// endpoints of RFC 6749. Requests without a body pass whatever their Content-Type.
+func RequestBodyMiddleware(form bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength == 0 || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+		limit := maxBodyBytes.Load()
+		if r.ContentLength > limit {
+			writeBodyTooLarge(w, limit)
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+		if !isJSON && !(form && mediaType == "application/x-www-form-urlencoded") {
+			accepted := []string{"application/json"}
+			if form {
+				accepted = append(accepted, "application/x-www-form-urlencoded")
+			}
+			w.Header().Set("Accept-Post", strings.Join(accepted, ", "))
+			writeProblem(w, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be "+strings.Join(accepted, " or "))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	}
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	writeProblem(w, http.StatusRequestEntityTooLarge, CodeRequestTooLarge, "the request body must be at most "+strconv.FormatInt(limit, 10)+" bytes")
+}
+
+// bodyTooLarge answers 413 if err is a read of a body beyond the limit, and reports whether it did.
+func bodyTooLarge(w http.ResponseWriter, err error) bool {
+	var mbe *http.MaxBytesError
+	if !errors.As(err, &mbe) {
+		return false
+	}
+	writeBodyTooLarge(w, mbe.Limit)
+	return true
+}
@@ -0,0 +1,533 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// maxBulkRefundItems caps the refunds of one bulk job.
+const maxBulkRefundItems = 1000
+
+// refundJobBatch is how many items of a job one runner pass works through, so one large job
+// does not hold the runner for long.
+const refundJobBatch = 200
+
+// Bulk refund jobs are QUEUED until the runner picks them up, RUNNING while items are pending
+// and COMPLETED once every item was refunded or failed.
+const (
+	refundJobQueued    = "QUEUED"
+	refundJobRunning   = "RUNNING"
+	refundJobCompleted = "COMPLETED"
+
+	refundItemPending  = "PENDING"
+	refundItemRefunded = "REFUNDED" // the refund was recorded, COMPLETED or REQUESTED
+	refundItemFailed   = "FAILED"
+)
+
+type bulkRefundItemReq struct {
+	OrderID              string       `json:"order_id" validate:"required"`
+	AmountMinor          *json.Number `json:"amount_minor,omitempty"`                              // omitted means the full remaining balance
+	RefundIdempotencyKey string       `json:"refund_idempotency_key,omitempty" validate:"max=255"` // defaults to one per job and item
+}
+
+type bulkRefundReq struct {
+	Items []bulkRefundItemReq `json:"items" validate:"required,max=1000"` // at most maxBulkRefundItems
+}
+
+type refundJobItem struct {
+	Position        int     `json:"position"`
+	OrderID         string  `json:"order_id"`
+	AmountMinor     *string `json:"amount_minor,omitempty"` // as requested
+	Status          string  `json:"status"`                 // PENDING | REFUNDED | FAILED
+	Error           *string `json:"error,omitempty"`
+	RefundID        *string `json:"refund_id,omitempty"`
+	RefundStatus    *string `json:"refund_status,omitempty"`
+	ExecutionStatus *string `json:"execution_status,omitempty"`
+	RefundTxHash    *string `json:"refund_tx_hash,omitempty"`
+}
+
+type refundJob struct {
+	ID          string          `json:"id"`
+	MerchantID  string          `json:"merchant_id"`
+	Status      string          `json:"status"`
+	Total       int             `json:"total"`
+	Pending     int             `json:"pending"`
+	Refunded    int             `json:"refunded"`
+	Failed      int             `json:"failed"`
+	Items       []refundJobItem `json:"items,omitempty"`
+	CreatedAt   string          `json:"created_at"`
+	UpdatedAt   string          `json:"updated_at"`
+	CompletedAt *string         `json:"completed_at,omitempty"`
+}
+
+// BulkRefundHandler godoc
+// @Summary      Refund many orders
+// @Description  Queues a job refunding up to 1000 orders, each by amount_minor or, when omitted, its full remaining balance, and returns it with 202. The job runs in the background: each item is checked like a single refund, recorded (REQUESTED when the merchant requires approval) and its transfer to the customer wallet queued for the hot wallet. Items that cannot be refunded fail on their own with an error; follow the job with GET /refunds/bulk/get. A refund_idempotency_key already used on the order refunds it only once.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        job  body  bulkRefundReq  true  "Orders to refund"
+// @Success      202  {object}  refundJob
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Failure      503  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /refunds/bulk [post]
+func BulkRefundHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req bulkRefundReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if len(req.Items) == 0 || len(req.Items) > maxBulkRefundItems {
+		badReq(w, "items must hold between 1 and "+strconv.Itoa(maxBulkRefundItems)+" refunds")
+		return
+	}
+	if txSigner == nil {
+		writeProblem(w, http.StatusServiceUnavailable, CodeHotWalletUnavailable, "bulk refunds are sent by the hot wallet, which is not configured")
+		return
+	}
+	jobID := "rfj_" + uuid.New().String()
+	keys := map[[2]string]bool{}
+	for i := range req.Items {
+		it := &req.Items[i]
+		it.OrderID = strings.TrimSpace(it.OrderID)
+		if it.OrderID == "" {
+			badReq(w, "items["+strconv.Itoa(i)+"]: order_id is required")
+			return
+		}
+		if it.AmountMinor != nil && !isValidAmountString(it.AmountMinor.String()) {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidRefundAmount, "items["+strconv.Itoa(i)+"]: amount_minor must be a positive integer in minor units")
+			return
+		}
+		if it.RefundIdempotencyKey == "" {
+			it.RefundIdempotencyKey = jobID + ":" + strconv.Itoa(i)
+		}
+		k := [2]string{it.OrderID, it.RefundIdempotencyKey}
+		if keys[k] {
+			badReq(w, "items["+strconv.Itoa(i)+"]: refund_idempotency_key repeats an earlier item of the same order")
+			return
+		}
+		keys[k] = true
+	}
+
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	merchantID := merchantIDFromContext(r.Context())
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refund_jobs (id, merchant_id, status, requested_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+	`, jobID, merchantID, refundJobQueued, credentialFromContext(r.Context()), now, now); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	for i, it := range req.Items {
+		var amount sql.NullString
+		if it.AmountMinor != nil {
+			amount = sql.NullString{String: it.AmountMinor.String(), Valid: true}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO refund_job_items (job_id, position, order_id, amount_minor, idempotency_key, status) VALUES (?, ?, ?, ?, ?, ?)
+		`, jobID, i, it.OrderID, amount, it.RefundIdempotencyKey, refundItemPending); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+	}
+	recordAudit(ctx, tx, actorFromContext(r.Context()), merchantID, "", "refund_job_created", map[string]any{"job_id": jobID, "items": len(req.Items)})
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=refund_job_queued job_id=%s merchant_id=%s items=%d", jobID, merchantID, len(req.Items))
+	job, err := loadRefundJob(ctx, db, jobID, false)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// GetBulkRefundHandler godoc
+// @Summary      Get a bulk refund job
+// @Description  Returns a bulk refund job with per-item outcomes: the refund recorded for each order, how far its on-chain transfer got (execution_status, refund_tx_hash), or why the item failed.
+// @Tags         orders
+// @Produce      json
+// @Param        id  query  string  true  "Job ID"
+// @Success      200  {object}  refundJob
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /refunds/bulk/get [get]
+func GetBulkRefundHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing query param: id")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	job, err := loadRefundJob(ctx, db, id, true)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !authorizedFor(r.Context(), job.MerchantID)) {
+		writeProblem(w, http.StatusNotFound, CodeRefundJobNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// loadRefundJob reads a job with its item counts and, if withItems, its items.
+func loadRefundJob(ctx context.Context, q queryer, id string, withItems bool) (refundJob, error) {
+	var (
+		j         refundJob
+		completed sql.NullString
+	)
+	if err := q.QueryRowContext(ctx, `
+		SELECT j.id, j.merchant_id, j.status, j.created_at, j.updated_at, j.completed_at,
+		  COUNT(i.position), COUNT(CASE WHEN i.status = ? THEN 1 END), COUNT(CASE WHEN i.status = ? THEN 1 END), COUNT(CASE WHEN i.status = ? THEN 1 END)
+		FROM refund_jobs j LEFT JOIN refund_job_items i ON i.job_id = j.id
+		WHERE j.id = ?
+		GROUP BY j.id
+	`, refundItemPending, refundItemRefunded, refundItemFailed, id).Scan(&j.ID, &j.MerchantID, &j.Status, &j.CreatedAt, &j.UpdatedAt, &completed,
+		&j.Total, &j.Pending, &j.Refunded, &j.Failed); err != nil {
+		return j, err
+	}
+	j.CompletedAt = nullStringPtr(completed)
+	if !withItems {
+		return j, nil
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT i.position, i.order_id, i.amount_minor, i.status, i.error, i.refund_id, f.status, f.execution_status, f.refund_tx_hash
+		FROM refund_job_items i LEFT JOIN refunds f ON f.id = i.refund_id
+		WHERE i.job_id = ?
+		ORDER BY i.position
+	`, id)
+	if err != nil {
+		return j, err
+	}
+	defer rows.Close()
+	j.Items = []refundJobItem{}
+	for rows.Next() {
+		var (
+			it                                                         refundJobItem
+			amount, itemErr, refundID, refundStatus, execution, txHash sql.NullString
+		)
+		if err := rows.Scan(&it.Position, &it.OrderID, &amount, &it.Status, &itemErr, &refundID, &refundStatus, &execution, &txHash); err != nil {
+			return j, err
+		}
+		it.AmountMinor, it.Error, it.RefundID = nullStringPtr(amount), nullStringPtr(itemErr), nullStringPtr(refundID)
+		it.RefundStatus, it.ExecutionStatus, it.RefundTxHash = nullStringPtr(refundStatus), nullStringPtr(execution), nullStringPtr(txHash)
+		j.Items = append(j.Items, it)
+	}
+	return j, rows.Err()
+}
+
+// StartRefundJobRunner works through queued bulk refund jobs every interval.
+func StartRefundJobRunner(interval time.Duration) {
+	startScheduler(schedulerRefundJobs, interval, false, func(ctx context.Context) (int, error) {
+		return runRefundJobs(ctx)
+	})
+}
+
+type refundJobItemRow struct {
+	JobID, MerchantID, RequestedBy string
+	Position                       int
+	OrderID, IdempotencyKey        string
+	AmountMinor                    sql.NullString
+}
+
+// runRefundJobs records the refunds of up to refundJobBatch pending items, oldest job first, and
+// completes the jobs left with none. It reports how many items it processed.
+func runRefundJobs(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `
+		SELECT j.id, j.merchant_id, COALESCE(j.requested_by, ''), i.position, i.order_id, i.idempotency_key, i.amount_minor
+		FROM refund_jobs j JOIN refund_job_items i ON i.job_id = j.id
+		WHERE j.status IN (?, ?) AND i.status = ?
+		ORDER BY j.created_at, j.id, i.position
+		LIMIT ?
+	`, refundJobQueued, refundJobRunning, refundItemPending, refundJobBatch)
+	if err != nil {
+		return 0, err
+	}
+	var items []refundJobItemRow
+	for rows.Next() {
+		var it refundJobItemRow
+		if err := rows.Scan(&it.JobID, &it.MerchantID, &it.RequestedBy, &it.Position, &it.OrderID, &it.IdempotencyKey, &it.AmountMinor); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := db.ExecContext(ctx, `UPDATE refund_jobs SET status = ?, updated_at = ? WHERE status = ?`, refundJobRunning, now, refundJobQueued); err != nil {
+		return 0, err
+	}
+	var lastErr error
+	for _, it := range items {
+		if err := processRefundJobItem(ctx, it); err != nil {
+			log.Printf("event=refund_job_error job_id=%s position=%d order_id=%s err=%v", it.JobID, it.Position, it.OrderID, err)
+			lastErr = err
+		}
+	}
+	if err := completeRefundJobs(ctx); err != nil {
+		lastErr = err
+	}
+	return len(items), lastErr
+}
+
+// processRefundJobItem records one item's refund and its outcome in one transaction. Refusals fail
+// the item; other errors leave it pending for the next pass.
+func processRefundJobItem(ctx context.Context, it refundJobItemRow) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	status, refundID, reason := refundItemRefunded, "", ""
+	err = tx.QueryRowContext(ctx, `SELECT id FROM refunds WHERE idempotency_key = ? AND order_id = ?`, it.IdempotencyKey, it.OrderID).Scan(&refundID)
+	switch {
+	case err == nil:
+		// already refunded under this key
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	default:
+		var amount *json.Number
+		if it.AmountMinor.Valid {
+			n := json.Number(it.AmountMinor.String)
+			amount = &n
+		}
+		rec, err := recordRefund(ctx, tx, refundRequest{
+			OrderID:        it.OrderID,
+			MerchantID:     it.MerchantID,
+			AmountMinor:    amount,
+			IdempotencyKey: it.IdempotencyKey,
+			RequestedBy:    it.RequestedBy,
+			Execute:        true,
+		})
+		// Refusals are decided before the refund writes anything.
+		var re *refundError
+		if errors.As(err, &re) {
+			status, reason = refundItemFailed, string(re.Code)
+			if re.Msg != "" {
+				reason += ": " + re.Msg
+			}
+		} else if err != nil {
+			return err
+		} else {
+			refundID = rec.RefundID
+			if rec.RefundStatus == refundStatusCompleted {
+				refundsProcessedTotal.inc()
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refund_job_items SET status = ?, refund_id = NULLIF(?, ''), error = NULLIF(?, '') WHERE job_id = ? AND position = ? AND status = ?
+	`, status, refundID, reason, it.JobID, it.Position, refundItemPending); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// completeRefundJobs marks running jobs without pending items COMPLETED and enqueues
+// refund_job.completed for each.
+func completeRefundJobs(ctx context.Context) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id FROM refund_jobs j
+		WHERE status = ? AND NOT EXISTS (SELECT 1 FROM refund_job_items i WHERE i.job_id = j.id AND i.status = ?)
+	`, refundJobRunning, refundItemPending)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC().Format(time.RFC3339)
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE refund_jobs SET status = ?, updated_at = ?, completed_at = ? WHERE id = ? AND status = ?
+		`, refundJobCompleted, now, now, id, refundJobRunning); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		job, err := loadRefundJob(ctx, tx, id, false)
+		if err == nil {
+			err = enqueueEvent(ctx, tx, job.MerchantID, "refund_job", id, webhookRefundJobCompleted, job)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		log.Printf("event=refund_job_completed job_id=%s merchant_id=%s refunded=%d failed=%d", id, job.MerchantID, job.Refunded, job.Failed)
+	}
+	return nil
+}
+
+type refundTransfer struct {
+	ID, OrderID, MerchantID, AmountMinor, Asset, Chain, ToAddress, ExecutionStatus string
+}
+
+// dispatchRefundTransfers sends the queued transfers of completed hot wallet refunds to the
+// customer wallet and finishes sent ones once their transaction is final. It runs with the payout
+// dispatcher; errors are kept in execution_error.
+func dispatchRefundTransfers(ctx context.Context) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT f.id, f.order_id, f.merchant_id, f.amount_minor, o.asset, UPPER(o.chain), COALESCE(o.customer_wallet_address, ''), f.execution_status
+		FROM refunds f JOIN orders o ON o.id = f.order_id
+		WHERE f.status = ? AND f.execution_status IN (?, ?)
+		ORDER BY f.created_at
+	`, refundStatusCompleted, refundExecQueued, refundExecSent)
+	if err != nil {
+		log.Printf("event=refund_transfer_error err=%v", err)
+		return
+	}
+	var open []refundTransfer
+	for rows.Next() {
+		var t refundTransfer
+		if err := rows.Scan(&t.ID, &t.OrderID, &t.MerchantID, &t.AmountMinor, &t.Asset, &t.Chain, &t.ToAddress, &t.ExecutionStatus); err == nil {
+			open = append(open, t)
+		}
+	}
+	rows.Close()
+	for _, t := range open {
+		dispatchRefundTransfer(t)
+	}
+}
+
+// dispatchRefundTransfer sends or follows t, with a timeout of its own like a payout's.
+func dispatchRefundTransfer(t refundTransfer) {
+	ctx, cancel := context.WithTimeout(context.Background(), payoutTimeout)
+	defer cancel()
+	var err error
+	if t.ExecutionStatus == refundExecQueued {
+		err = sendRefundTransfer(ctx, t)
+	} else {
+		err = syncRefundTransfer(ctx, t)
+	}
+	if err != nil {
+		log.Printf("event=refund_transfer_error refund_id=%s status=%s err=%v", t.ID, t.ExecutionStatus, err)
+		_, _ = db.ExecContext(ctx, `UPDATE refunds SET execution_error = ? WHERE id = ?`, err.Error(), t.ID)
+	}
+}
+
+// sendRefundTransfer sends t's transfer, referenced by the refund, and marks it sent. A refund
+// still queued because marking it failed after the transfer went out is marked sent by that
+// transfer: submitTokenTransfer returns it rather than refunding the customer again.
+func sendRefundTransfer(ctx context.Context, t refundTransfer) error {
+	token, ok := blockchain.TokenAddress(t.Chain, t.Asset)
+	amount, ok2 := new(big.Int).SetString(t.AmountMinor, 10)
+	if !ok || !ok2 || !common.IsHexAddress(t.ToAddress) {
+		return finishRefundTransfer(ctx, t, refundExecFailed, "", "no token contract, amount or customer wallet to send the refund with")
+	}
+	c, err := submitTokenTransfer(ctx, t.Chain, "refund", t.ID, token, common.HexToAddress(t.ToAddress), amount)
+	if err != nil {
+		return err
+	}
+	log.Printf("event=refund_transfer_sent refund_id=%s order_id=%s chain=%s tx_hash=%s", t.ID, t.OrderID, t.Chain, c.TxHash)
+	_, err = db.ExecContext(ctx, `
+		UPDATE refunds SET execution_status = ?, chain_tx_id = ?, execution_error = NULL WHERE id = ? AND execution_status = ?
+	`, refundExecSent, c.ID, t.ID, refundExecQueued)
+	return err
+}
+
+// syncRefundTransfer finishes a sent refund once its transaction is mined, replaced for good or
+// dropped.
+func syncRefundTransfer(ctx context.Context, t refundTransfer) error {
+	var status string
+	var mined sql.NullString
+	if err := db.QueryRowContext(ctx, `
+		SELECT c.status, c.mined_tx_hash FROM refunds f JOIN chain_transactions c ON c.id = f.chain_tx_id WHERE f.id = ?
+	`, t.ID).Scan(&status, &mined); err != nil {
+		return err
+	}
+	switch status {
+	case chainTxConfirmed:
+		return finishRefundTransfer(ctx, t, refundExecExecuted, mined.String, "")
+	case chainTxFailed, chainTxCancelled, chainTxDropped:
+		return finishRefundTransfer(ctx, t, refundExecFailed, "", "transaction "+strings.ToLower(status))
+	}
+	return nil
+}
+
+// finishRefundTransfer moves a refund's execution to EXECUTED, recording the transaction as its
+// refund_tx_hash, or FAILED, and enqueues refund.executed or refund.execution_failed.
+func finishRefundTransfer(ctx context.Context, t refundTransfer, status, txHash, reason string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.ExecContext(ctx, `
+		UPDATE refunds SET execution_status = ?, refund_tx_hash = COALESCE(NULLIF(?, ''), refund_tx_hash), execution_error = NULLIF(?, '')
+		WHERE id = ? AND execution_status = ?
+	`, status, txHash, reason, t.ID, t.ExecutionStatus)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	event := webhookRefundExecuted
+	if status == refundExecFailed {
+		event = webhookRefundExecutionFailed
+	}
+	if err := enqueueRefundEvent(ctx, tx, event, t.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=refund_transfer_%s refund_id=%s order_id=%s chain=%s tx_hash=%s reason=%q", strings.ToLower(status), t.ID, t.OrderID, t.Chain, txHash, reason)
+	return nil
+}
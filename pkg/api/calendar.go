@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// merchantLocation is the merchant's reporting timezone: its daily windows (volume limits,
+// settlement days, report buckets) start at local midnight. Merchants without one use UTC.
+func merchantLocation(ctx context.Context, q queryer, merchantID string) (*time.Location, error) {
+	var tz sql.NullString
+	if err := q.QueryRowContext(ctx, `SELECT timezone FROM merchants WHERE id = ?`, merchantID).Scan(&tz); err != nil {
+		return nil, err
+	}
+	return parseLocation(tz.String), nil
+}
+
+// parseLocation loads an IANA timezone name, falling back to UTC for "" or unknown names.
+func parseLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// localDayStart is the start of t's calendar day in loc, as a UTC timestamp comparable with the
+// stored ones.
+func localDayStart(t time.Time, loc *time.Location) string {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc).UTC().Format(time.RFC3339)
+}
+
+// settlementCutoff is the paid_at cutoff of a merchant's scheduled settlement, given the rolling
+// one (now less the settlement delay). Merchants with a timezone settle by calendar day: the
+// orders of a local day settle together once the day is over and the delay has passed, T+1 in
+// merchant-local time. Others settle everything paid before the rolling cutoff.
+func settlementCutoff(ctx context.Context, q queryer, merchantID, rolling string) (string, error) {
+	var tz sql.NullString
+	if err := q.QueryRowContext(ctx, `SELECT timezone FROM merchants WHERE id = ?`, merchantID).Scan(&tz); err != nil {
+		return "", err
+	}
+	t, err := time.Parse(time.RFC3339, rolling)
+	if !tz.Valid || err != nil {
+		return rolling, nil
+	}
+	// paid_at <= cutoff: the last second of the previous local day.
+	y, m, d := t.In(parseLocation(tz.String)).Date()
+	return time.Date(y, m, d, 0, 0, -1, 0, parseLocation(tz.String)).UTC().Format(time.RFC3339), nil
+}
+
+// localTimestamp renders a stored UTC timestamp in loc, keeping RFC 3339 with the local offset.
+// Values that do not parse are returned as they are.
+func localTimestamp(ts string, loc *time.Location) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// chainLagBlocks is how many expected block times a chain's head may trail the clock before the
+// chain counts as lagging. It is never less than minChainLag, as block timestamps have second
+// precision and providers take a moment to see new blocks.
+const (
+	chainLagBlocks = 20
+	minChainLag    = 30 * time.Second
+)
+
+// chainHealth is the state of one chain's RPC endpoint.
+type chainHealth struct {
+	Chain             string   `json:"chain"`
+	Status            string   `json:"status"`   // ok | lagging | unreachable
+	Provider          string   `json:"provider"` // host of the RPC endpoint in use
+	Reachable         bool     `json:"reachable"`
+	LatencyMS         int64    `json:"latency_ms,omitempty"` // of fetching the latest block
+	BlockHeight       uint64   `json:"block_height,omitempty"`
+	BlockTime         string   `json:"block_time,omitempty"`
+	LagSeconds        *float64 `json:"lag_seconds,omitempty"`       // how far the latest block trails the clock
+	ExpectedBlockTime float64  `json:"expected_block_time_seconds"` // seconds between blocks
+	MaxLagSeconds     float64  `json:"max_lag_seconds"`             // lag beyond which the chain is lagging
+	Error             string   `json:"error,omitempty"`
+}
+
+type chainHealthResp struct {
+	OK     bool          `json:"ok"`
+	Chains []chainHealth `json:"chains"`
+}
+
+// ChainHealthHandler godoc
+// @Summary      Get chain RPC health
+// @Description  Checks every chain with an RPC endpoint: whether it answers, the latest block height and time, how far that block trails the clock against the chain's expected block time, and the provider (host) in use. A chain is lagging when its head is older than 20 block times (at least 30 seconds). Responds 503 when any chain is unreachable or lagging.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  chainHealthResp
+// @Failure      503  {object}  chainHealthResp
+// @Router       /health/chains [get]
+func ChainHealthHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	chains := blockchain.Chains()
+	resp := chainHealthResp{OK: true, Chains: make([]chainHealth, len(chains))}
+	var wg sync.WaitGroup
+	for i, chain := range chains {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp.Chains[i] = checkChain(ctx, chain)
+		}()
+	}
+	wg.Wait()
+	for _, c := range resp.Chains {
+		if c.Status != "ok" {
+			resp.OK = false
+		}
+	}
+	status := http.StatusOK
+	if !resp.OK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
+
+func checkChain(ctx context.Context, chain string) chainHealth {
+	expected := blockchain.BlockTime(chain)
+	maxLag := max(chainLagBlocks*expected, minChainLag)
+	c := chainHealth{
+		Chain: chain, Provider: blockchain.Provider(chain), Status: "unreachable",
+		ExpectedBlockTime: expected.Seconds(), MaxLagSeconds: maxLag.Seconds(),
+	}
+	head, err := blockchain.LatestHead(ctx, chain)
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	lag := max(time.Since(head.Time), 0)
+	c.Reachable, c.Status = true, "ok"
+	c.LatencyMS = head.Latency.Milliseconds()
+	c.BlockHeight, c.BlockTime = head.Number, head.Time.Format(time.RFC3339)
+	lagSeconds := lag.Round(time.Second).Seconds()
+	c.LagSeconds = &lagSeconds
+	if lag > maxLag {
+		c.Status = "lagging"
+	}
+	return c
+}
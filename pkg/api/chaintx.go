@@ -0,0 +1,526 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// Outgoing transactions are signed by the hot wallet, recorded in chain_transactions and watched
+// until mined. One still pending after its fee profile's BumpAfter is re-signed with the same nonce
+// and higher fees, so it replaces the stuck one.
+const (
+	chainTxPending    = "PENDING"
+	chainTxCancelling = "CANCELLING" // a zero-value self-transfer was sent to take the nonce
+	chainTxConfirmed  = "CONFIRMED"
+	chainTxFailed     = "FAILED"
+	chainTxCancelled  = "CANCELLED"
+	chainTxDropped    = "DROPPED" // the nonce was used by a transaction OSPay did not send
+)
+
+// maxFeeBumps bounds automatic replacements of one transaction; after that it is left for an
+// operator (POST /admin/transactions/{id}/bump or /cancel).
+const maxFeeBumps = 10
+
+var (
+	txSigner    blockchain.Signer
+	txUrgency   = blockchain.UrgencyStandard
+	txMaxFeeWei *big.Int // nil means no ceiling
+)
+
+// SetTxSigner sets the hot wallet signer for outgoing transactions; nil disables sending.
+func SetTxSigner(s blockchain.Signer) { txSigner = s }
+
+// SetTxFeePolicy sets the default urgency of outgoing transactions and the maxFeePerGas (or gas
+// price) that fee bumping never goes above; a nil ceiling means none.
+func SetTxFeePolicy(u blockchain.Urgency, maxFeeWei *big.Int) {
+	if u != "" {
+		txUrgency = u
+	}
+	txMaxFeeWei = maxFeeWei
+}
+
+type chainTx struct {
+	ID             string   `json:"id"`
+	Chain          string   `json:"chain"`
+	Purpose        string   `json:"purpose"`
+	ReferenceID    *string  `json:"reference_id,omitempty"`
+	FromAddress    string   `json:"from_address"`
+	Nonce          uint64   `json:"nonce"`
+	TxHash         string   `json:"tx_hash"`
+	ReplacedHashes []string `json:"replaced_hashes,omitempty"`
+	CancelTxHash   *string  `json:"cancel_tx_hash,omitempty"`
+	MinedTxHash    *string  `json:"mined_tx_hash,omitempty"` // which of the hashes made it into a block
+	Urgency        string   `json:"urgency"`
+	MaxFeeWei      string   `json:"max_fee_wei"`
+	PriorityFeeWei *string  `json:"priority_fee_wei,omitempty"`
+	Bumps          int      `json:"bumps"`
+	Status         string   `json:"status"`
+	LastError      *string  `json:"last_error,omitempty"`
+	SubmittedAt    string   `json:"submitted_at"`
+	CreatedAt      string   `json:"created_at"`
+	ConfirmedAt    *string  `json:"confirmed_at,omitempty"`
+	Stuck          bool     `json:"stuck,omitempty"` // still pending after three times its profile's wait
+	rawTx          string
+}
+
+const chainTxCols = `id, chain, purpose, reference_id, from_address, nonce, tx_hash, replaced_hashes, raw_tx,
+	urgency, max_fee_wei, priority_fee_wei, bumps, status, last_error, submitted_at, created_at, confirmed_at, cancel_tx_hash, mined_tx_hash`
+
+func scanChainTx(row interface{ Scan(...any) error }) (chainTx, error) {
+	var (
+		t                                       chainTx
+		ref, tip, lastErr, confd, cancel, mined sql.NullString
+		replaced                                string
+	)
+	err := row.Scan(&t.ID, &t.Chain, &t.Purpose, &ref, &t.FromAddress, &t.Nonce, &t.TxHash, &replaced, &t.rawTx,
+		&t.Urgency, &t.MaxFeeWei, &tip, &t.Bumps, &t.Status, &lastErr, &t.SubmittedAt, &t.CreatedAt, &confd, &cancel, &mined)
+	if err != nil {
+		return t, err
+	}
+	t.ReferenceID, t.PriorityFeeWei, t.LastError, t.ConfirmedAt = nullStringPtr(ref), nullStringPtr(tip), nullStringPtr(lastErr), nullStringPtr(confd)
+	t.CancelTxHash, t.MinedTxHash = nullStringPtr(cancel), nullStringPtr(mined)
+	if replaced != "" {
+		t.ReplacedHashes = strings.Split(replaced, ",")
+	}
+	if t.inFlight() {
+		profile, _ := blockchain.Profile(blockchain.Urgency(t.Urgency))
+		submitted, _ := time.Parse(time.RFC3339, t.SubmittedAt)
+		t.Stuck = time.Since(submitted) > 3*profile.BumpAfter
+	}
+	return t, nil
+}
+
+// inFlight reports whether t is waiting to be mined.
+func (t chainTx) inFlight() bool {
+	return t.Status == chainTxPending || t.Status == chainTxCancelling
+}
+
+// hashes returns every hash broadcast for t, oldest first.
+func (t chainTx) hashes() []string {
+	return append(append([]string(nil), t.ReplacedHashes...), t.TxHash)
+}
+
+// submitTokenTransfer signs an ERC-20 transfer from the hot wallet, records it and broadcasts it.
+// A failed broadcast is kept as PENDING with last_error set; the monitor sends it again.
+//
+// referenceID names what the transaction pays for (a payout, refund, sweep, ...). When a
+// transaction of the same purpose and reference is already in flight or mined, it is returned
+// instead of sending another: a caller that sent one but failed to record that it did must not pay
+// twice on its next try. Only failed, cancelled and dropped transactions, which moved nothing, are
+// sent again. Approvals are exempt: their reference is the token and spender, approved anew whenever
+// the allowance runs out, and ensureAllowance keeps to one in flight.
+func submitTokenTransfer(ctx context.Context, chain, purpose, referenceID string, token, to common.Address, amount *big.Int) (chainTx, error) {
+	return submitContractCall(ctx, chain, purpose, referenceID, token, blockchain.TokenTransferData(to, amount), blockchain.GasTokenTransfer)
+}
+
+// submitContractCall is submitTokenTransfer for any call of contract from the hot wallet.
+func submitContractCall(ctx context.Context, chain, purpose, referenceID string, contract common.Address, data []byte, gas uint64) (chainTx, error) {
+	if txSigner == nil {
+		return chainTx{}, errors.New("no hot wallet signer configured")
+	}
+	chainID, err := blockchain.ChainID(ctx, chain)
+	if err != nil {
+		return chainTx{}, err
+	}
+	unlock := lockChain(chain)
+	defer unlock()
+	if referenceID != "" && purpose != "approve" {
+		t, err := scanChainTx(db.QueryRowContext(ctx, `
+			SELECT `+chainTxCols+` FROM chain_transactions
+			WHERE chain = ? AND purpose = ? AND reference_id = ? AND status IN (?, ?, ?)
+			ORDER BY created_at DESC LIMIT 1
+		`, chain, purpose, referenceID, chainTxPending, chainTxCancelling, chainTxConfirmed))
+		if err == nil {
+			log.Printf("event=tx_reused id=%s chain=%s purpose=%s reference_id=%s tx_hash=%s", t.ID, chain, purpose, referenceID, t.TxHash)
+			return t, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return chainTx{}, err
+		}
+	}
+	nonce, err := nextNonce(ctx, chain, txSigner.Address())
+	if err != nil {
+		return chainTx{}, err
+	}
+	fees, err := blockchain.SuggestFees(ctx, chain, txUrgency)
+	if err != nil {
+		return chainTx{}, err
+	}
+	fees = capFees(fees)
+	tx, err := txSigner.SignTx(blockchain.NewTx(chainID, nonce, contract, data, gas, fees), chainID)
+	if err != nil {
+		return chainTx{}, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return chainTx{}, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	t := chainTx{
+		ID: "ctx_" + uuid.New().String(), Chain: chain, Purpose: purpose, FromAddress: txSigner.Address().Hex(),
+		Nonce: nonce, TxHash: tx.Hash().Hex(), Urgency: string(txUrgency), MaxFeeWei: fees.MaxFee.String(),
+		Bumps: 0, Status: chainTxPending, SubmittedAt: now, CreatedAt: now, rawTx: hex.EncodeToString(raw),
+	}
+	if referenceID != "" {
+		t.ReferenceID = &referenceID
+	}
+	if !fees.Legacy() {
+		tip := fees.TipCap.String()
+		t.PriorityFeeWei = &tip
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO chain_transactions (id, chain, purpose, reference_id, from_address, nonce, tx_hash, raw_tx,
+		  urgency, max_fee_wei, priority_fee_wei, status, submitted_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, t.Chain, t.Purpose, t.ReferenceID, t.FromAddress, t.Nonce, t.TxHash, t.rawTx,
+		t.Urgency, t.MaxFeeWei, t.PriorityFeeWei, t.Status, now, now); err != nil {
+		return chainTx{}, err
+	}
+	if err := blockchain.SendTx(ctx, chain, tx); err != nil {
+		log.Printf("event=tx_send_failed id=%s chain=%s tx_hash=%s err=%v", t.ID, chain, t.TxHash, err)
+		_, _ = db.ExecContext(ctx, `UPDATE chain_transactions SET last_error = ? WHERE id = ?`, err.Error(), t.ID)
+		msg := err.Error()
+		t.LastError = &msg
+		return t, nil
+	}
+	log.Printf("event=tx_sent id=%s chain=%s purpose=%s nonce=%d tx_hash=%s max_fee_wei=%s", t.ID, chain, purpose, nonce, t.TxHash, t.MaxFeeWei)
+	return t, nil
+}
+
+// capFees lowers fees to the configured ceiling.
+func capFees(f blockchain.Fees) blockchain.Fees {
+	if txMaxFeeWei == nil || f.MaxFee.Cmp(txMaxFeeWei) <= 0 {
+		return f
+	}
+	f.MaxFee = new(big.Int).Set(txMaxFeeWei)
+	if f.TipCap != nil && f.TipCap.Cmp(f.MaxFee) > 0 {
+		f.TipCap = new(big.Int).Set(f.MaxFee)
+	}
+	return f
+}
+
+// StartTxMonitor checks in-flight outgoing transactions every interval (see checkWalletTxs) and
+// recently mined ones for reorgs.
+func StartTxMonitor(interval time.Duration) {
+	startScheduler(schedulerTxMonitor, interval, false, func(context.Context) (int, error) {
+		return checkPendingTxs()
+	})
+}
+
+// checkPendingTxs reports how many in-flight transactions it checked.
+func checkPendingTxs() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+chainTxCols+` FROM chain_transactions WHERE status IN (?, ?) ORDER BY chain, from_address, nonce
+	`, chainTxPending, chainTxCancelling)
+	if err != nil {
+		return 0, err
+	}
+	type wallet struct{ chain, from string }
+	var order []wallet
+	byWallet := map[wallet][]chainTx{}
+	for rows.Next() {
+		if t, err := scanChainTx(rows); err == nil {
+			k := wallet{t.Chain, t.FromAddress}
+			if _, ok := byWallet[k]; !ok {
+				order = append(order, k)
+			}
+			byWallet[k] = append(byWallet[k], t)
+		}
+	}
+	rows.Close()
+	n := 0
+	for _, k := range order {
+		checkWalletTxs(ctx, k.chain, common.HexToAddress(k.from), byWallet[k])
+		n += len(byWallet[k])
+	}
+	checkReorgs(ctx)
+	return n, nil
+}
+
+// settleChainTx looks for a receipt of t or of any transaction it replaced, and records the
+// outcome. It reports whether one was mined.
+func settleChainTx(ctx context.Context, t chainTx) (bool, error) {
+	hashes := t.hashes()
+	for i := len(hashes) - 1; i >= 0; i-- {
+		h := hashes[i]
+		receipt, err := blockchain.Receipt(ctx, t.Chain, common.HexToHash(h))
+		if err != nil {
+			return false, err
+		}
+		if receipt == nil {
+			continue
+		}
+		status := chainTxConfirmed
+		if t.CancelTxHash != nil && i >= slices.Index(hashes, *t.CancelTxHash) {
+			status = chainTxCancelled
+		} else if receipt.Status != types.ReceiptStatusSuccessful {
+			status = chainTxFailed
+		}
+		if _, err := db.ExecContext(ctx, `
+			UPDATE chain_transactions SET status = ?, mined_tx_hash = ?, confirmed_at = ?, last_error = NULL WHERE id = ? AND status IN (?, ?)
+		`, status, h, time.Now().UTC().Format(time.RFC3339), t.ID, chainTxPending, chainTxCancelling); err != nil {
+			return true, err
+		}
+		log.Printf("event=tx_mined id=%s chain=%s tx_hash=%s status=%s block=%s", t.ID, t.Chain, h, status, receipt.BlockNumber)
+		return true, nil
+	}
+	return false, nil
+}
+
+// errFeeCapReached is returned when a replacement would have to exceed the fee ceiling.
+var errFeeCapReached = errors.New("fee ceiling reached")
+
+// replaceChainTx re-signs t's nonce with u's current fees, raised enough to replace what is
+// pending, and broadcasts it. With cancel the replacement is a zero-value self-transfer and t
+// becomes CANCELLING. A transaction whose last broadcast failed is sent again instead when its fees
+// are still current. Callers hold the chain lock.
+func replaceChainTx(ctx context.Context, t chainTx, u blockchain.Urgency, cancel bool) (chainTx, error) {
+	if txSigner == nil {
+		return t, errors.New("no hot wallet signer configured")
+	}
+	prev, err := t.signedTx()
+	if err != nil {
+		return t, err
+	}
+	if !cancel && t.LastError != nil && t.Bumps == 0 && u == blockchain.Urgency(t.Urgency) {
+		if err := blockchain.SendTx(ctx, t.Chain, prev); err == nil {
+			_, err = db.ExecContext(ctx, `UPDATE chain_transactions SET last_error = NULL, submitted_at = ? WHERE id = ?`, time.Now().UTC().Format(time.RFC3339), t.ID)
+			t.LastError = nil
+			return t, err
+		}
+	}
+	next, err := blockchain.SuggestFees(ctx, t.Chain, u)
+	if err != nil {
+		return t, err
+	}
+	fees := blockchain.BumpFees(blockchain.TxFees(prev), next)
+	if txMaxFeeWei != nil && fees.MaxFee.Cmp(txMaxFeeWei) > 0 {
+		return t, fmt.Errorf("%w: replacement needs max_fee_wei %s", errFeeCapReached, fees.MaxFee)
+	}
+	chainID, err := blockchain.ChainID(ctx, t.Chain)
+	if err != nil {
+		return t, err
+	}
+	unsigned := blockchain.WithFees(chainID, prev, fees)
+	if cancel {
+		unsigned = blockchain.CancelTx(chainID, t.Nonce, common.HexToAddress(t.FromAddress), fees)
+	}
+	tx, err := txSigner.SignTx(unsigned, chainID)
+	if err != nil {
+		return t, err
+	}
+	if err := blockchain.SendTx(ctx, t.Chain, tx); err != nil {
+		return t, err
+	}
+	rawNext, err := tx.MarshalBinary()
+	if err != nil {
+		return t, err
+	}
+	replaced := append(t.ReplacedHashes, t.TxHash)
+	var tip *string
+	if !fees.Legacy() {
+		s := fees.TipCap.String()
+		tip = &s
+	}
+	status, cancelHash := t.Status, t.CancelTxHash
+	if cancel && cancelHash == nil {
+		h := tx.Hash().Hex()
+		status, cancelHash = chainTxCancelling, &h
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := db.ExecContext(ctx, `
+		UPDATE chain_transactions
+		SET tx_hash = ?, replaced_hashes = ?, raw_tx = ?, urgency = ?, max_fee_wei = ?, priority_fee_wei = ?,
+		    bumps = bumps + 1, last_error = NULL, submitted_at = ?, status = ?, cancel_tx_hash = ?
+		WHERE id = ? AND tx_hash = ? AND status IN (?, ?)
+	`, tx.Hash().Hex(), strings.Join(replaced, ","), hex.EncodeToString(rawNext), string(u), fees.MaxFee.String(), tip,
+		now, status, cancelHash, t.ID, t.TxHash, chainTxPending, chainTxCancelling)
+	if err != nil {
+		return t, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return t, errors.New("transaction changed while replacing it")
+	}
+	event := "tx_bumped"
+	if cancel {
+		event = "tx_cancel_sent"
+	}
+	log.Printf("event=%s id=%s chain=%s nonce=%d old_tx_hash=%s tx_hash=%s max_fee_wei=%s", event, t.ID, t.Chain, t.Nonce, t.TxHash, tx.Hash().Hex(), fees.MaxFee)
+	t.ReplacedHashes, t.TxHash, t.Urgency, t.MaxFeeWei, t.PriorityFeeWei = replaced, tx.Hash().Hex(), string(u), fees.MaxFee.String(), tip
+	t.Bumps++
+	t.LastError, t.SubmittedAt, t.rawTx = nil, now, hex.EncodeToString(rawNext)
+	t.Status, t.CancelTxHash = status, cancelHash
+	return t, nil
+}
+
+// signedTx decodes the latest signed transaction of t.
+func (t chainTx) signedTx() (*types.Transaction, error) {
+	raw, err := hex.DecodeString(t.rawTx)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	return tx, tx.UnmarshalBinary(raw)
+}
+
+// ListChainTransactionsHandler godoc
+// @Summary      List outgoing transactions
+// @Description  Returns the most recent transactions sent by the hot wallet (newest first), optionally filtered by status (PENDING, CANCELLING, CONFIRMED, FAILED, CANCELLED, DROPPED) and chain, with their fees, any replaced predecessors and which hash was mined. stuck marks transactions pending for over three times their profile's wait. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        status  query  string  false  "Status"
+// @Param        chain   query  string  false  "Chain"
+// @Success      200  {array}   chainTx
+// @Failure      500  {object}  Problem
+// @Router       /admin/transactions [get]
+func ListChainTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	status, chain := strings.ToUpper(q.Get("status")), strings.ToUpper(q.Get("chain"))
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+chainTxCols+` FROM chain_transactions
+		WHERE (? = '' OR status = ?) AND (? = '' OR chain = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 500
+	`, status, status, chain, chain)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	txs := []chainTx{}
+	for rows.Next() {
+		t, err := scanChainTx(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		txs = append(txs, t)
+	}
+	writeJSONOrders(w, http.StatusOK, txs)
+}
+
+type chainTxBumpReq struct {
+	Urgency string `json:"urgency,omitempty"` // fee profile for the replacement; defaults to the transaction's own
+}
+
+// BumpChainTransactionHandler godoc
+// @Summary      Replace a stuck transaction
+// @Description  Re-signs a PENDING (or CANCELLING) outgoing transaction with the same nonce and fees from the given urgency profile (slow, standard, fast), raised at least 12% over the current ones so nodes accept the replacement, and broadcasts it. Admin only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       query  string          true   "Transaction ID"
+// @Param        request  body   chainTxBumpReq  false  "Urgency"
+// @Success      200  {object}  chainTx
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Router       /admin/transactions/bump [post]
+func BumpChainTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	replaceChainTxHandler(w, r, false)
+}
+
+// CancelChainTransactionHandler godoc
+// @Summary      Cancel a pending transaction
+// @Description  Sends a zero-value transfer from the hot wallet to itself with the transaction's nonce and higher fees, so the original is dropped once it is mined. The transaction is CANCELLING until one of the two is mined, then CANCELLED or, if the original won, CONFIRMED. Admin only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       query  string          true   "Transaction ID"
+// @Param        request  body   chainTxBumpReq  false  "Urgency"
+// @Success      200  {object}  chainTx
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Router       /admin/transactions/cancel [post]
+func CancelChainTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	replaceChainTxHandler(w, r, true)
+}
+
+func replaceChainTxHandler(w http.ResponseWriter, r *http.Request, cancel bool) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing transaction id")
+		return
+	}
+	var req chainTxBumpReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Urgency != "" {
+		if _, ok := blockchain.Profile(blockchain.Urgency(strings.ToLower(req.Urgency))); !ok {
+			badReq(w, "unknown urgency: "+req.Urgency)
+			return
+		}
+	}
+	ctx := r.Context()
+	var chain string
+	if err := db.QueryRowContext(ctx, `SELECT chain FROM chain_transactions WHERE id = ?`, id).Scan(&chain); errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeTransactionNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	unlock := lockChain(chain)
+	defer unlock()
+	t, err := scanChainTx(db.QueryRowContext(ctx, `SELECT `+chainTxCols+` FROM chain_transactions WHERE id = ?`, id))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if !t.inFlight() {
+		writeProblem(w, http.StatusConflict, CodeTransactionNotPending, "transaction is "+t.Status)
+		return
+	}
+	if mined, err := settleChainTx(ctx, t); err == nil && mined {
+		writeProblem(w, http.StatusConflict, CodeTransactionNotPending, "transaction was mined")
+		return
+	}
+	u := blockchain.Urgency(t.Urgency)
+	if req.Urgency != "" {
+		u = blockchain.Urgency(strings.ToLower(req.Urgency))
+	}
+	t, err = replaceChainTx(ctx, t, u, cancel || t.Status == chainTxCancelling)
+	if errors.Is(err, errFeeCapReached) {
+		writeProblem(w, http.StatusUnprocessableEntity, CodeFeeCapReached, err.Error())
+		return
+	} else if err != nil {
+		writeProblem(w, http.StatusBadGateway, CodeRPCUnavailable, err.Error())
+		return
+	}
+	action := "transaction_bumped"
+	if cancel {
+		action = "transaction_cancelled"
+	}
+	recordAudit(ctx, db, actorFromContext(ctx), "", "", action, map[string]any{"transaction_id": t.ID, "tx_hash": t.TxHash, "urgency": t.Urgency})
+	writeJSONOrders(w, http.StatusOK, t)
+}
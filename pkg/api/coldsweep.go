@@ -0,0 +1,447 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// The cold sweeper keeps what a compromised hot key could take small: a hot wallet token balance
+// above its limit is moved to the cold wallet, down to what the limit keeps for payouts and
+// refunds. Sweeps go PENDING_APPROVAL (when they need approval) -> QUEUED -> SENT -> EXECUTED, or
+// end REJECTED or FAILED.
+const (
+	sweepPendingApproval = "PENDING_APPROVAL"
+	sweepQueued          = "QUEUED" // approved, or due to be sent again after a failed attempt
+	sweepSent            = "SENT"
+	sweepExecuted        = "EXECUTED"
+	sweepRejected        = "REJECTED"
+	sweepFailed          = "FAILED"
+)
+
+// Sweeps move funds between the platform's own wallets, so they are booked on the platform's
+// account rather than a merchant's: out of hot_wallet and into cold_storage.
+const (
+	platformAccount   = "platform" // merchant_id of the platform's ledger entries
+	bucketHotWallet   = "hot_wallet"
+	bucketColdStorage = "cold_storage"
+	eventColdSweep    = "COLD_SWEEP"
+)
+
+// SweepLimit is the most of Asset the hot wallet holds on Chain before the excess is swept to the
+// cold wallet, and the balance a sweep leaves behind (at most Limit).
+type SweepLimit struct {
+	Chain, Asset string
+	Limit, Keep  *big.Int
+}
+
+var (
+	sweepMu       sync.Mutex
+	coldWallet    string
+	sweepLimits   []SweepLimit
+	sweepApproval bool
+	sweepAlertURL string
+)
+
+// SetColdSweep configures the cold wallet sweeps go to, the limits per chain and token, whether
+// each sweep waits for an administrator's approval and the URL sweep alerts are POSTed to.
+// Without a cold wallet or limits nothing is swept.
+func SetColdSweep(wallet string, limits []SweepLimit, approval bool, alertURL string) {
+	sweepMu.Lock()
+	defer sweepMu.Unlock()
+	coldWallet, sweepLimits, sweepApproval, sweepAlertURL = wallet, limits, approval, alertURL
+}
+
+// coldWalletOn returns the cold wallet when chain has a sweep limit, for reconciliation.
+func coldWalletOn(chain string) string {
+	sweepMu.Lock()
+	defer sweepMu.Unlock()
+	for _, l := range sweepLimits {
+		if strings.EqualFold(l.Chain, chain) {
+			return coldWallet
+		}
+	}
+	return ""
+}
+
+// StartColdSweeper checks the hot wallet's balances against the limits every interval, and
+// follows sweeps until they are mined. It does nothing without a cold wallet, limits or the hot
+// wallet signer.
+func StartColdSweeper(interval time.Duration) {
+	sweepMu.Lock()
+	configured := coldWallet != "" && len(sweepLimits) > 0
+	sweepMu.Unlock()
+	if !configured || txSigner == nil {
+		return
+	}
+	startScheduler(schedulerColdSweep, interval, false, func(ctx context.Context) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return runColdSweeps(ctx)
+	})
+}
+
+type coldSweep struct {
+	ID           string  `json:"id"`
+	Chain        string  `json:"chain"`
+	Asset        string  `json:"asset"`
+	AmountMinor  string  `json:"amount_minor"`
+	FromAddress  string  `json:"from_address"` // the hot wallet
+	ToAddress    string  `json:"to_address"`   // the cold wallet
+	Status       string  `json:"status"`
+	BalanceMinor string  `json:"balance_minor"` // hot wallet balance that went over the limit
+	LimitMinor   string  `json:"limit_minor"`
+	KeepMinor    string  `json:"keep_minor"`
+	TxHash       *string `json:"tx_hash,omitempty"`
+	LastError    *string `json:"last_error,omitempty"`
+	DecidedBy    *string `json:"decided_by,omitempty"` // who approved or rejected it
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+}
+
+const coldSweepCols = `id, chain, asset, amount_minor, from_address, to_address, status, balance_minor, limit_minor, keep_minor,
+	tx_hash, last_error, decided_by, created_at, updated_at`
+
+func scanColdSweep(row interface{ Scan(...any) error }) (coldSweep, error) {
+	var (
+		s                          coldSweep
+		txHash, lastErr, decidedBy sql.NullString
+	)
+	err := row.Scan(&s.ID, &s.Chain, &s.Asset, &s.AmountMinor, &s.FromAddress, &s.ToAddress, &s.Status, &s.BalanceMinor,
+		&s.LimitMinor, &s.KeepMinor, &txHash, &lastErr, &decidedBy, &s.CreatedAt, &s.UpdatedAt)
+	s.TxHash, s.LastError, s.DecidedBy = nullStringPtr(txHash), nullStringPtr(lastErr), nullStringPtr(decidedBy)
+	return s, err
+}
+
+// runColdSweeps sends queued sweeps, finishes sent ones and starts new ones where the hot wallet
+// is over a limit. It reports how many sweeps it worked on; the error of the last failure is
+// returned.
+func runColdSweeps(ctx context.Context) (int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+coldSweepCols+` FROM cold_sweeps WHERE status IN (?, ?) ORDER BY created_at`, sweepQueued, sweepSent)
+	if err != nil {
+		return 0, err
+	}
+	var open []coldSweep
+	for rows.Next() {
+		if s, err := scanColdSweep(rows); err == nil {
+			open = append(open, s)
+		}
+	}
+	rows.Close()
+	var lastErr error
+	for _, s := range open {
+		if s.Status == sweepQueued {
+			err = sendColdSweep(ctx, s)
+		} else {
+			err = syncColdSweep(ctx, s)
+		}
+		if err != nil {
+			coldSweepAttemptFailed(ctx, s, err)
+			lastErr = err
+		}
+	}
+	n := len(open)
+	sweepMu.Lock()
+	limits, wallet := append([]SweepLimit(nil), sweepLimits...), coldWallet
+	sweepMu.Unlock()
+	for _, l := range limits {
+		started, err := startColdSweep(ctx, l, wallet)
+		if err != nil {
+			if !started {
+				log.Printf("event=cold_sweep_error chain=%s asset=%s err=%v", l.Chain, l.Asset, err)
+			}
+			lastErr = err
+		}
+		if started {
+			n++
+		}
+	}
+	return n, lastErr
+}
+
+// coldSweepAttemptFailed records err on s, which is tried again on the next run.
+func coldSweepAttemptFailed(ctx context.Context, s coldSweep, err error) {
+	log.Printf("event=cold_sweep_error sweep_id=%s status=%s err=%v", s.ID, s.Status, err)
+	_, _ = db.ExecContext(ctx, `UPDATE cold_sweeps SET last_error = ?, updated_at = ? WHERE id = ?`,
+		err.Error(), time.Now().UTC().Format(time.RFC3339), s.ID)
+}
+
+// startColdSweep records a sweep of the hot wallet's balance of l's token above l.Keep when the
+// balance is over l.Limit and no sweep of it is open, and sends it unless it needs approval.
+func startColdSweep(ctx context.Context, l SweepLimit, wallet string) (bool, error) {
+	var open int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(1) FROM cold_sweeps WHERE chain = ? AND asset = ? AND status IN (?, ?, ?)
+	`, l.Chain, l.Asset, sweepPendingApproval, sweepQueued, sweepSent).Scan(&open); err != nil || open > 0 {
+		return false, err
+	}
+	hot := txSigner.Address().Hex()
+	balance, err := blockchain.AssetBalance(ctx, l.Chain, l.Asset, hot)
+	if err != nil {
+		return false, err
+	}
+	if balance.Cmp(l.Limit) <= 0 {
+		return false, nil
+	}
+	sweepMu.Lock()
+	approval, alertURL := sweepApproval, sweepAlertURL
+	sweepMu.Unlock()
+	status := sweepQueued
+	if approval {
+		status = sweepPendingApproval
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	s := coldSweep{
+		ID: "swp_" + uuid.New().String(), Chain: l.Chain, Asset: l.Asset, AmountMinor: new(big.Int).Sub(balance, l.Keep).String(),
+		FromAddress: hot, ToAddress: wallet, Status: status, BalanceMinor: balance.String(), LimitMinor: l.Limit.String(),
+		KeepMinor: l.Keep.String(), CreatedAt: now, UpdatedAt: now,
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO cold_sweeps (id, chain, asset, amount_minor, from_address, to_address, status, balance_minor, limit_minor, keep_minor, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.ID, s.Chain, s.Asset, s.AmountMinor, s.FromAddress, s.ToAddress, s.Status, s.BalanceMinor, s.LimitMinor, s.KeepMinor, now, now); err != nil {
+		return false, err
+	}
+	log.Printf("event=cold_sweep_created sweep_id=%s chain=%s asset=%s amount_minor=%s balance_minor=%s status=%s",
+		s.ID, s.Chain, s.Asset, s.AmountMinor, s.BalanceMinor, s.Status)
+	if approval {
+		go sendOperatorAlert(alertURL, "cold_sweep.approval_required", s)
+		return true, nil
+	}
+	if err := sendColdSweep(ctx, s); err != nil {
+		coldSweepAttemptFailed(ctx, s, err)
+		return true, err
+	}
+	return true, nil
+}
+
+// sendColdSweep transfers a queued sweep to the cold wallet from the hot wallet.
+func sendColdSweep(ctx context.Context, s coldSweep) error {
+	token, ok := blockchain.TokenAddress(s.Chain, s.Asset)
+	if !ok {
+		return finishColdSweep(ctx, s, sweepFailed, "", "no "+s.Asset+" contract on "+s.Chain)
+	}
+	amount, _ := new(big.Int).SetString(s.AmountMinor, 10)
+	t, err := submitTokenTransfer(ctx, s.Chain, "cold_sweep", s.ID, token, common.HexToAddress(s.ToAddress), amount)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		UPDATE cold_sweeps SET status = ?, chain_tx_id = ?, tx_hash = ?, last_error = NULL, updated_at = ? WHERE id = ? AND status = ?
+	`, sweepSent, t.ID, t.TxHash, time.Now().UTC().Format(time.RFC3339), s.ID, sweepQueued)
+	return err
+}
+
+// syncColdSweep finishes a sent sweep once its transaction is mined, replaced for good or dropped.
+func syncColdSweep(ctx context.Context, s coldSweep) error {
+	var status string
+	var mined sql.NullString
+	if err := db.QueryRowContext(ctx, `
+		SELECT c.status, c.mined_tx_hash FROM cold_sweeps s JOIN chain_transactions c ON c.id = s.chain_tx_id WHERE s.id = ?
+	`, s.ID).Scan(&status, &mined); err != nil {
+		return err
+	}
+	switch status {
+	case chainTxConfirmed:
+		return finishColdSweep(ctx, s, sweepExecuted, mined.String, "")
+	case chainTxFailed, chainTxCancelled, chainTxDropped:
+		return finishColdSweep(ctx, s, sweepFailed, mined.String, "transaction "+strings.ToLower(status))
+	}
+	return nil
+}
+
+// finishColdSweep moves s to EXECUTED, booking the move from hot_wallet to cold_storage, or to
+// FAILED, and alerts either way.
+func finishColdSweep(ctx context.Context, s coldSweep, status, txHash, reason string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		UPDATE cold_sweeps SET status = ?, tx_hash = COALESCE(NULLIF(?, ''), tx_hash), last_error = NULLIF(?, ''), updated_at = ?
+		WHERE id = ? AND status = ?
+	`, status, txHash, reason, now, s.ID, s.Status)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if status == sweepExecuted {
+		entry := func(bucket, direction string) store.LedgerEntry {
+			return store.LedgerEntry{
+				MerchantID: platformAccount, Asset: s.Asset, Chain: s.Chain,
+				AmountMinor: s.AmountMinor, Bucket: bucket, Direction: direction, EventType: eventColdSweep, TxHash: txHash,
+				ReferenceID: s.ID, CreatedAt: now,
+			}
+		}
+		if err := txStores(tx).Ledger.Append(ctx, entry(bucketHotWallet, dirDebit), entry(bucketColdStorage, dirCredit)); err != nil {
+			return err
+		}
+	}
+	updated, err := scanColdSweep(tx.QueryRowContext(ctx, `SELECT `+coldSweepCols+` FROM cold_sweeps WHERE id = ?`, s.ID))
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=cold_sweep_%s sweep_id=%s chain=%s asset=%s amount_minor=%s tx_hash=%s reason=%q",
+		strings.ToLower(status), s.ID, s.Chain, s.Asset, s.AmountMinor, txHash, reason)
+	sweepMu.Lock()
+	alertURL := sweepAlertURL
+	sweepMu.Unlock()
+	go sendOperatorAlert(alertURL, "cold_sweep."+strings.ToLower(status), updated)
+	return nil
+}
+
+// ListColdSweepsHandler godoc
+// @Summary      List cold sweeps
+// @Description  Returns the sweeps of hot wallet balances to the cold wallet, newest first, optionally filtered by status (PENDING_APPROVAL, QUEUED, SENT, EXECUTED, REJECTED, FAILED). A sweep is started when the hot wallet's balance of a token goes over its COLD_SWEEP_LIMITS limit and moves the excess over what the limit keeps; with COLD_SWEEP_APPROVAL on it waits in PENDING_APPROVAL for POST /admin/sweeps/approve. Executed sweeps are booked on the platform account, from hot_wallet to cold_storage. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        status  query  string  false  "Status"
+// @Param        limit   query  int     false  "Page size (default 100, max 500)"
+// @Success      200  {array}   coldSweep
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/sweeps [get]
+func ListColdSweepsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			badReq(w, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+	status := strings.ToUpper(q.Get("status"))
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+coldSweepCols+` FROM cold_sweeps WHERE (? = '' OR status = ?) ORDER BY created_at DESC, id DESC LIMIT ?
+	`, status, status, limit)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	out := []coldSweep{}
+	for rows.Next() {
+		s, err := scanColdSweep(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// ApproveColdSweepHandler godoc
+// @Summary      Approve a cold sweep
+// @Description  Sends a PENDING_APPROVAL sweep to the cold wallet now. The amount is the one computed when the sweep was started; the hot wallet's balance is not read again. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Sweep ID"
+// @Success      200  {object}  coldSweep
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/sweeps/approve [post]
+func ApproveColdSweepHandler(w http.ResponseWriter, r *http.Request) {
+	decideColdSweep(w, r, true)
+}
+
+// RejectColdSweepHandler godoc
+// @Summary      Reject a cold sweep
+// @Description  Rejects a PENDING_APPROVAL sweep, which is not sent. A new sweep is started on the next run while the hot wallet is still over the limit. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Sweep ID"
+// @Success      200  {object}  coldSweep
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/sweeps/reject [post]
+func RejectColdSweepHandler(w http.ResponseWriter, r *http.Request) {
+	decideColdSweep(w, r, false)
+}
+
+func decideColdSweep(w http.ResponseWriter, r *http.Request, approve bool) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing sweep id")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	status, action := sweepQueued, "cold_sweep_approved"
+	if !approve {
+		status, action = sweepRejected, "cold_sweep_rejected"
+	}
+	actor := actorFromContext(ctx)
+	res, err := db.ExecContext(ctx, `
+		UPDATE cold_sweeps SET status = ?, decided_by = ?, updated_at = ? WHERE id = ? AND status = ?
+	`, status, actor, time.Now().UTC().Format(time.RFC3339), id, sweepPendingApproval)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var current string
+		err := db.QueryRowContext(ctx, `SELECT status FROM cold_sweeps WHERE id = ?`, id).Scan(&current)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			writeProblem(w, http.StatusNotFound, CodeSweepNotFound, "")
+		case err != nil:
+			serverErr(w, err)
+		default:
+			writeProblem(w, http.StatusConflict, CodeSweepNotPending, "the sweep is "+current)
+		}
+		return
+	}
+	recordAudit(ctx, db, actor, "", "", action, map[string]any{"sweep_id": id})
+	s, err := scanColdSweep(db.QueryRowContext(ctx, `SELECT `+coldSweepCols+` FROM cold_sweeps WHERE id = ?`, id))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if approve {
+		// A failed send is left QUEUED for the sweeper to try again
+		if err := sendColdSweep(ctx, s); err != nil {
+			coldSweepAttemptFailed(ctx, s, err)
+		}
+		if s, err = scanColdSweep(db.QueryRowContext(ctx, `SELECT `+coldSweepCols+` FROM cold_sweeps WHERE id = ?`, id)); err != nil {
+			serverErr(w, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, s)
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// statusConfirming marks an order whose payment was found on-chain but is not yet final: it is
+// credited once its block has the chain's finality depth (see StartConfirmationTracker).
+const statusConfirming = "CONFIRMING"
+
+// awaitFinality reports whether a payment mined in block must wait for more confirmations before
+// it is credited. Payments not verified on-chain have no block and are credited at once.
+func awaitFinality(chain string, block paymentBlock) bool {
+	return block.number.Valid && blockchain.FinalityDepth(chain) > 1
+}
+
+// markConfirming records a verified payment on an order that is still waiting for it (or EXPIRED,
+// when late) without crediting it. It reports false if the order was updated by someone else.
+func markConfirming(ctx context.Context, tx *sql.Tx, orderID, txHash string, payer sql.NullString, block paymentBlock, late bool) (bool, error) {
+	invalidateOrder(orderID)
+	res, err := tx.ExecContext(ctx, `
+		UPDATE orders
+		SET status = ?, tx_hash = ?, customer_wallet_address = COALESCE(?, customer_wallet_address), confirmed_block = ?, block_timestamp = ?
+		WHERE id = ? AND (status IN ('PENDING', 'MISPAID') OR (status = 'EXPIRED' AND ?))
+	`, statusConfirming, txHash, payer, block.number, block.time, orderID, late)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// StartConfirmationTracker checks CONFIRMING orders every interval and credits those whose
+// payment block is deep enough.
+func StartConfirmationTracker(db *sql.DB, interval time.Duration) {
+	startScheduler(schedulerConfirmations, interval, false, func(ctx context.Context) (int, error) {
+		credited, err := promoteConfirmedPayments(ctx, db)
+		overpaid, oerr := promoteConfirmedOverpayments(ctx, db)
+		if err == nil {
+			err = oerr
+		}
+		return credited + overpaid, err
+	})
+}
+
+type confirmingOrder struct {
+	ID, MerchantID, AmountMinor, Asset, Chain, TxHash string
+	Block                                             int64
+	Payer                                             sql.NullString
+	Late                                              bool
+}
+
+// promoteConfirmedPayments credits the CONFIRMING orders that reached their chain's finality depth
+// and reports how many it credited. A payment whose transaction is no longer in the chain puts
+// its order back to where it was. A failing order is logged and skipped; the error of the last
+// failure is returned.
+func promoteConfirmedPayments(ctx context.Context, db *sql.DB) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, merchant_id, amount_minor, asset, UPPER(chain), tx_hash, confirmed_block, customer_wallet_address, expired_at IS NOT NULL
+		FROM orders WHERE status = ? AND confirmed_block IS NOT NULL
+		ORDER BY confirmed_block
+	`, statusConfirming)
+	if err != nil {
+		return 0, err
+	}
+	var orders []confirmingOrder
+	for rows.Next() {
+		var o confirmingOrder
+		if err := rows.Scan(&o.ID, &o.MerchantID, &o.AmountMinor, &o.Asset, &o.Chain, &o.TxHash, &o.Block, &o.Payer, &o.Late); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	heads := map[string]uint64{}
+	credited := 0
+	var lastErr error
+	for _, o := range orders {
+		head, ok := heads[o.Chain]
+		if !ok {
+			if head, err = blockchain.HeadBlock(ctx, o.Chain); err != nil {
+				lastErr = fmt.Errorf("%s head: %w", o.Chain, err)
+				continue
+			}
+			heads[o.Chain] = head
+		}
+		if int64(head)-o.Block+1 < int64(blockchain.FinalityDepth(o.Chain)) {
+			continue
+		}
+		// The receipt is read again: a reorg may have dropped the payment or moved it to another block.
+		receipt, err := blockchain.Receipt(ctx, o.Chain, common.HexToHash(o.TxHash))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		switch {
+		case receipt == nil || receipt.Status != 1:
+			err = revertConfirming(ctx, db, o)
+		case receipt.BlockNumber.Int64() != o.Block:
+			invalidateOrder(o.ID)
+			_, err = db.ExecContext(ctx, `UPDATE orders SET confirmed_block = ?, block_timestamp = NULL WHERE id = ? AND status = ?`,
+				receipt.BlockNumber.Int64(), o.ID, statusConfirming)
+		default:
+			if err = creditConfirmedPayment(ctx, db, o); err == nil {
+				credited++
+			}
+		}
+		if err != nil {
+			log.Printf("event=confirmation_error order_id=%s err=%v", o.ID, err)
+			lastErr = err
+		}
+	}
+	return credited, lastErr
+}
+
+// creditConfirmedPayment does for a final payment what the verification path does for one that
+// needs no confirmations: risk checks, then PAID with ledger entries, or REVIEW / LATE_PAYMENT.
+func creditConfirmedPayment(ctx context.Context, db *sql.DB, o confirmingOrder) error {
+	screening := screenPayer(ctx, o.ID, o.Chain, o.Payer.String)
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	assessment := assessPayment(ctx, tx, screening, o.ID, o.MerchantID, o.Asset, o.Chain, o.AmountMinor, o.Payer.String)
+	if o.Late {
+		// The grace window was checked when the payment was found.
+		if _, review, err := checkLatePayment(ctx, tx, o.ID); err != nil {
+			return err
+		} else if review {
+			holdLatePayment(&assessment)
+		}
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		UPDATE orders SET status = ?, paid_at = ?, risk_reason = ?, risk_score = ?, risk_factors = ?
+		WHERE id = ? AND status = ?
+	`, assessment.Status, now, assessment.Reason, assessment.Score, assessment.Factors, o.ID, statusConfirming)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if assessment.Status != "PAID" {
+		if err := enqueueOrderEvent(ctx, tx, webhookOrderInReview, o.ID); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("event=payment_held order_id=%s merchant_id=%s tx_hash=%s reason=%q", o.ID, o.MerchantID, o.TxHash, assessment.Reason.String)
+		return nil
+	}
+	if err := writePaymentLedger(ctx, tx, o.ID, o.MerchantID, o.Asset, o.AmountMinor, o.TxHash, now); err != nil {
+		return err
+	}
+	if err := recordCustomerPayment(ctx, tx, o.ID, now); err != nil {
+		return err
+	}
+	if err := enqueueOrderEvent(ctx, tx, webhookOrderPaid, o.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	paymentsDetectedTotal.inc()
+	log.Printf("event=payment_final order_id=%s merchant_id=%s asset=%s amount_minor=%s tx_hash=%s block=%d status=PAID", o.ID, o.MerchantID, o.Asset, o.AmountMinor, o.TxHash, o.Block)
+	return nil
+}
+
+// revertConfirming puts an order whose payment was reorged out (or reverted) back to PENDING, or
+// EXPIRED for a late one, and reports it with verification.failed.
+func revertConfirming(ctx context.Context, db *sql.DB, o confirmingOrder) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	status := "PENDING"
+	if o.Late {
+		status = statusExpired
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE orders SET status = ?, tx_hash = NULL, confirmed_block = NULL, block_timestamp = NULL WHERE id = ? AND status = ?
+	`, status, o.ID, statusConfirming)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := enqueueEvent(ctx, tx, o.MerchantID, "order", o.ID, webhookVerificationFailed,
+		verificationFailedData{OrderID: o.ID, TxHash: o.TxHash, Reason: "payment transaction is no longer in the chain"}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=payment_reorged order_id=%s tx_hash=%s block=%d status=%s", o.ID, o.TxHash, o.Block, status)
+	return nil
+}
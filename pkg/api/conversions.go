@@ -0,0 +1,423 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/convert"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Conversion states: QUEUED until the swap transaction is sent, SUBMITTED until the provider
+// delivers the output, then COMPLETED or FAILED.
+const (
+	conversionQueued    = "QUEUED"
+	conversionSubmitted = "SUBMITTED"
+	conversionCompleted = "COMPLETED"
+	conversionFailed    = "FAILED"
+)
+
+const (
+	bucketConversion = "conversion" // funds in transit between assets during a conversion
+	eventConversion  = "CONVERSION"
+
+	defaultSlippageBps = 50
+	maxSlippageBps     = 1000
+)
+
+var converter convert.Provider
+
+// SetConverter sets the provider settlement conversions go through; nil leaves them QUEUED.
+func SetConverter(p convert.Provider) { converter = p }
+
+// settlementTarget is the chain and asset a merchant is paid out in.
+type settlementTarget struct{ chain, asset string }
+
+type conversionRecord struct {
+	ID             string  `json:"id"`
+	BatchID        string  `json:"batch_id"`
+	MerchantID     string  `json:"merchant_id"`
+	FromChain      string  `json:"from_chain"`
+	FromAsset      string  `json:"from_asset"`
+	ToChain        string  `json:"to_chain"`
+	ToAsset        string  `json:"to_asset"`
+	AmountInMinor  string  `json:"amount_in_minor"`
+	QuotedOutMinor *string `json:"quoted_out_minor,omitempty"`
+	MinOutMinor    *string `json:"min_out_minor,omitempty"` // the swap reverts below it
+	AmountOutMinor *string `json:"amount_out_minor,omitempty"`
+	MaxSlippageBps int64   `json:"max_slippage_bps"`
+	Provider       *string `json:"provider,omitempty"`
+	Status         string  `json:"status"`
+	TxHash         *string `json:"tx_hash,omitempty"`         // the swap on from_chain
+	ReceiveTxHash  *string `json:"receive_tx_hash,omitempty"` // the delivery on to_chain
+	LastError      *string `json:"last_error,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	UpdatedAt      string  `json:"updated_at"`
+	chainTxID      sql.NullString
+}
+
+const conversionCols = `id, batch_id, merchant_id, from_chain, from_asset, to_chain, to_asset, amount_in_minor,
+	quoted_out_minor, min_out_minor, amount_out_minor, max_slippage_bps, provider, status, chain_tx_id, tx_hash,
+	receive_tx_hash, last_error, created_at, updated_at`
+
+func scanConversion(row interface{ Scan(...any) error }) (conversionRecord, error) {
+	var (
+		c                             conversionRecord
+		quoted, minOut, out, provider sql.NullString
+		txHash, receiveHash, lastErr  sql.NullString
+	)
+	err := row.Scan(&c.ID, &c.BatchID, &c.MerchantID, &c.FromChain, &c.FromAsset, &c.ToChain, &c.ToAsset, &c.AmountInMinor,
+		&quoted, &minOut, &out, &c.MaxSlippageBps, &provider, &c.Status, &c.chainTxID, &txHash,
+		&receiveHash, &lastErr, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return c, err
+	}
+	c.QuotedOutMinor, c.MinOutMinor, c.AmountOutMinor, c.Provider = nullStringPtr(quoted), nullStringPtr(minOut), nullStringPtr(out), nullStringPtr(provider)
+	c.TxHash, c.ReceiveTxHash, c.LastError = nullStringPtr(txHash), nullStringPtr(receiveHash), nullStringPtr(lastErr)
+	return c, nil
+}
+
+// queueConversion records that amount of asset settled on chain is to be converted into target
+// before it is paid out.
+func queueConversion(ctx context.Context, tx *sql.Tx, batchID, merchantID, chain, asset string, target settlementTarget, amount *big.Int, slippage sql.NullInt64) error {
+	bps := int64(defaultSlippageBps)
+	if slippage.Valid {
+		bps = slippage.Int64
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO conversions (id, batch_id, merchant_id, from_chain, from_asset, to_chain, to_asset, amount_in_minor,
+		  max_slippage_bps, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "conv_"+uuid.New().String(), batchID, merchantID, chain, asset, target.chain, target.asset, amount.String(),
+		bps, conversionQueued, now, now)
+	return err
+}
+
+// dispatchConversions starts queued conversions and follows submitted ones. It runs on the payout
+// dispatcher's tick, before payouts, so completed conversions are paid out in the same pass.
+func dispatchConversions(ctx context.Context) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+conversionCols+` FROM conversions WHERE status IN (?, ?) ORDER BY created_at
+	`, conversionQueued, conversionSubmitted)
+	if err != nil {
+		log.Printf("failed to query conversions: %v", err)
+		return
+	}
+	var open []conversionRecord
+	for rows.Next() {
+		if c, err := scanConversion(rows); err == nil {
+			open = append(open, c)
+		}
+	}
+	rows.Close()
+	for _, c := range open {
+		var err error
+		if c.Status == conversionQueued {
+			err = startConversion(ctx, c)
+		} else {
+			err = syncConversion(ctx, c)
+		}
+		if err != nil {
+			log.Printf("event=conversion_error conversion_id=%s status=%s err=%v", c.ID, c.Status, err)
+			_, _ = db.ExecContext(ctx, `UPDATE conversions SET last_error = ?, updated_at = ? WHERE id = ?`, err.Error(), time.Now().UTC().Format(time.RFC3339), c.ID)
+		}
+	}
+}
+
+// conversionRequest builds the provider request for c. The hot wallet holds the settled funds and
+// receives the output, which it then pays out like any other settlement.
+func conversionRequest(c conversionRecord) (convert.Request, error) {
+	if txSigner == nil {
+		return convert.Request{}, errors.New("no hot wallet signer configured")
+	}
+	from, ok1 := blockchain.TokenAddress(c.FromChain, c.FromAsset)
+	to, ok2 := blockchain.TokenAddress(c.ToChain, c.ToAsset)
+	if !ok1 || !ok2 {
+		return convert.Request{}, fmt.Errorf("no token contract for %s on %s or %s on %s", c.FromAsset, c.FromChain, c.ToAsset, c.ToChain)
+	}
+	amount, ok := new(big.Int).SetString(c.AmountInMinor, 10)
+	if !ok {
+		return convert.Request{}, fmt.Errorf("invalid amount %q", c.AmountInMinor)
+	}
+	wallet := txSigner.Address().Hex()
+	return convert.Request{
+		FromChain: c.FromChain, ToChain: c.ToChain, FromToken: from.Hex(), ToToken: to.Hex(),
+		AmountIn: amount, From: wallet, To: wallet, SlippageBps: c.MaxSlippageBps,
+	}, nil
+}
+
+// startConversion quotes c and sends the swap. A quote that tolerates more slippage than the
+// merchant's limit is refused and requested again on the next pass.
+func startConversion(ctx context.Context, c conversionRecord) error {
+	if converter == nil {
+		return errors.New("no conversion provider configured")
+	}
+	if txSigner == nil {
+		return errors.New("no hot wallet signer configured")
+	}
+	req, err := conversionRequest(c)
+	if err != nil {
+		// Only an unknown token or a corrupt amount gets here; neither improves with time.
+		return failConversion(ctx, c, err.Error())
+	}
+	q, err := converter.Quote(ctx, req)
+	if err != nil {
+		return err
+	}
+	// AmountOutMin >= AmountOut * (1 - slippage)
+	floor := new(big.Int).Mul(q.AmountOut, big.NewInt(10000-c.MaxSlippageBps))
+	if q.AmountOut.Sign() <= 0 || new(big.Int).Mul(q.AmountOutMin, big.NewInt(10000)).Cmp(floor) < 0 {
+		return fmt.Errorf("quote %s allows more than %d bps slippage: out %s, min %s", q.ID, c.MaxSlippageBps, q.AmountOut, q.AmountOutMin)
+	}
+	if !common.IsHexAddress(q.Spender) || !common.IsHexAddress(q.TxTo) {
+		return fmt.Errorf("quote %s has no valid spender or target contract", q.ID)
+	}
+	ready, err := ensureAllowance(ctx, c.FromChain, common.HexToAddress(req.FromToken), common.HexToAddress(q.Spender), req.AmountIn)
+	if !ready || err != nil {
+		return err
+	}
+	t, err := submitContractCall(ctx, c.FromChain, "conversion", c.ID, common.HexToAddress(q.TxTo), q.TxData, q.TxGas)
+	if err != nil {
+		return err
+	}
+	log.Printf("event=conversion_submitted conversion_id=%s provider=%s %s/%s->%s/%s amount_in=%s quoted_out=%s min_out=%s tx_hash=%s",
+		c.ID, converter.Name(), c.FromChain, c.FromAsset, c.ToChain, c.ToAsset, c.AmountInMinor, q.AmountOut, q.AmountOutMin, t.TxHash)
+	_, err = db.ExecContext(ctx, `
+		UPDATE conversions
+		SET status = ?, provider = ?, quoted_out_minor = ?, min_out_minor = ?, chain_tx_id = ?, tx_hash = ?, last_error = NULL, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, conversionSubmitted, converter.Name(), q.AmountOut.String(), q.AmountOutMin.String(), t.ID, t.TxHash,
+		time.Now().UTC().Format(time.RFC3339), c.ID, conversionQueued)
+	return err
+}
+
+// syncConversion completes c once its swap is mined and the provider has delivered the output.
+func syncConversion(ctx context.Context, c conversionRecord) error {
+	var status string
+	var mined sql.NullString
+	if err := db.QueryRowContext(ctx, `SELECT status, mined_tx_hash FROM chain_transactions WHERE id = ?`, c.chainTxID).Scan(&status, &mined); err != nil {
+		return err
+	}
+	switch status {
+	case chainTxFailed, chainTxCancelled, chainTxDropped:
+		return failConversion(ctx, c, "swap transaction "+strings.ToLower(status))
+	case chainTxConfirmed:
+	default:
+		return nil
+	}
+	if converter == nil {
+		return errors.New("no conversion provider configured")
+	}
+	req, err := conversionRequest(c)
+	if err != nil {
+		return err
+	}
+	st, err := converter.Status(ctx, req, mined.String)
+	if err != nil {
+		return err
+	}
+	switch {
+	case st.Failed:
+		return failConversion(ctx, c, st.Reason)
+	case st.Done:
+		return completeConversion(ctx, c, mined.String, st)
+	}
+	return nil
+}
+
+// completeConversion records the delivered output: the settled balance moves from the input to
+// the output asset through the conversion bucket, as two balanced pairs of entries, and the output
+// is queued for payout.
+func completeConversion(ctx context.Context, c conversionRecord, swapHash string, st convert.Status) error {
+	if c.MinOutMinor != nil {
+		if min, ok := new(big.Int).SetString(*c.MinOutMinor, 10); ok && st.AmountOut.Cmp(min) < 0 {
+			log.Printf("event=conversion_below_min conversion_id=%s amount_out=%s min_out=%s", c.ID, st.AmountOut, min)
+		}
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		UPDATE conversions SET status = ?, amount_out_minor = ?, receive_tx_hash = ?, last_error = NULL, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, conversionCompleted, st.AmountOut.String(), st.TxHash, now, c.ID, conversionSubmitted)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	entry := func(chain, asset, amount, bucket, direction, txHash string) store.LedgerEntry {
+		return store.LedgerEntry{
+			MerchantID: c.MerchantID, Asset: asset, Chain: chain, AmountMinor: amount,
+			Bucket: bucket, Direction: direction, EventType: eventConversion, TxHash: txHash, ReferenceID: c.ID, CreatedAt: now,
+		}
+	}
+	out := st.AmountOut.String()
+	if err := txStores(tx).Ledger.Append(ctx,
+		entry(c.FromChain, c.FromAsset, c.AmountInMinor, bucketSettlement, dirDebit, swapHash),
+		entry(c.FromChain, c.FromAsset, c.AmountInMinor, bucketConversion, dirCredit, swapHash),
+		entry(c.ToChain, c.ToAsset, out, bucketConversion, dirDebit, st.TxHash),
+		entry(c.ToChain, c.ToAsset, out, bucketSettlement, dirCredit, st.TxHash),
+	); err != nil {
+		return err
+	}
+	if err := queuePayout(ctx, tx, c.BatchID, c.MerchantID, c.ToChain, c.ToAsset, st.AmountOut); err != nil {
+		return err
+	}
+	updated, err := scanConversion(tx.QueryRowContext(ctx, `SELECT `+conversionCols+` FROM conversions WHERE id = ?`, c.ID))
+	if err != nil {
+		return err
+	}
+	if err := enqueueEvent(ctx, tx, c.MerchantID, "conversion", c.ID, webhookConversionCompleted, updated); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=conversion_completed conversion_id=%s batch_id=%s amount_in=%s %s amount_out=%s %s", c.ID, c.BatchID, c.AmountInMinor, c.FromAsset, out, c.ToAsset)
+	return nil
+}
+
+// failConversion marks c FAILED. Its input stays in the hot wallet (or, for a failed bridge, with
+// the provider) and in the merchant's balance until an operator retries it.
+func failConversion(ctx context.Context, c conversionRecord, reason string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.ExecContext(ctx, `
+		UPDATE conversions SET status = ?, last_error = ?, updated_at = ? WHERE id = ? AND status = ?
+	`, conversionFailed, reason, time.Now().UTC().Format(time.RFC3339), c.ID, c.Status)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	updated, err := scanConversion(tx.QueryRowContext(ctx, `SELECT `+conversionCols+` FROM conversions WHERE id = ?`, c.ID))
+	if err != nil {
+		return err
+	}
+	if err := enqueueEvent(ctx, tx, c.MerchantID, "conversion", c.ID, webhookConversionFailed, updated); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=conversion_failed conversion_id=%s batch_id=%s reason=%q", c.ID, c.BatchID, reason)
+	return nil
+}
+
+// ListConversionsHandler godoc
+// @Summary      List settlement conversions
+// @Description  Returns the most recent conversions (newest first), optionally filtered by status (QUEUED, SUBMITTED, COMPLETED, FAILED) or batch_id. A conversion is queued at settlement when the merchant's settlement_asset or settlement_chain differs from what was received; quoted_out_minor and min_out_minor are the provider's quote, min_out_minor being the floor set by max_slippage_bps. Admins see every merchant's, or one with merchant_id.
+// @Tags         settlements
+// @Produce      json
+// @Param        status       query  string  false  "Status"
+// @Param        batch_id     query  string  false  "Settlement batch"
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {array}   conversionRecord
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /conversions [get]
+// @Router       /admin/conversions [get]
+func ListConversionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = q.Get("merchant_id")
+	}
+	status, batchID := strings.ToUpper(q.Get("status")), q.Get("batch_id")
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+conversionCols+` FROM conversions
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR status = ?) AND (? = '' OR batch_id = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 500
+	`, merchantID, merchantID, status, status, batchID, batchID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	conversions := []conversionRecord{}
+	for rows.Next() {
+		c, err := scanConversion(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		conversions = append(conversions, c)
+	}
+	writeJSONOrders(w, http.StatusOK, conversions)
+}
+
+// RetryConversionHandler godoc
+// @Summary      Retry a failed conversion
+// @Description  Puts a FAILED conversion back in the queue; the next dispatcher pass quotes and sends it again. Check first that the input is back in the hot wallet: a bridge that failed mid-route may still hold it. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Conversion ID"
+// @Success      200  {object}  conversionRecord
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/conversions/retry [post]
+func RetryConversionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing conversion id")
+		return
+	}
+	ctx := r.Context()
+	c, err := scanConversion(db.QueryRowContext(ctx, `SELECT `+conversionCols+` FROM conversions WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeConversionNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if c.Status != conversionFailed {
+		writeProblem(w, http.StatusConflict, CodeConversionNotFailed, "conversion is "+c.Status)
+		return
+	}
+	if _, err := db.ExecContext(ctx, `
+		UPDATE conversions
+		SET status = ?, chain_tx_id = NULL, tx_hash = NULL, quoted_out_minor = NULL, min_out_minor = NULL, last_error = NULL, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, conversionQueued, time.Now().UTC().Format(time.RFC3339), id, conversionFailed); err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(ctx, db, actorFromContext(ctx), c.MerchantID, "", "conversion_retried", map[string]any{"conversion_id": id, "last_error": c.LastError})
+	c, err = scanConversion(db.QueryRowContext(ctx, `SELECT `+conversionCols+` FROM conversions WHERE id = ?`, id))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, c)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/jobs"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// counter is a running total kept in metric_counters, so it survives restarts and adds up across
+// instances. Increments collect in memory and are added to the table by flushCounters; a crash
+// loses at most the increments of one flush interval.
+type counter struct {
+	name    string
+	pending int64 // increments not yet added to metric_counters
+}
+
+func (c *counter) inc() { atomic.AddInt64(&c.pending, 1) }
+
+var (
+	ordersCreatedTotal    = &counter{name: "orders_created_total"}
+	refundsProcessedTotal = &counter{name: "refunds_processed_total"}
+	paymentsDetectedTotal = &counter{name: "payments_detected_total"}
+
+	persistentCounters = []*counter{ordersCreatedTotal, refundsProcessedTotal, paymentsDetectedTotal}
+)
+
+// StartCounterFlusher adds the counters' increments to the database every interval. Every
+// instance flushes its own.
+func StartCounterFlusher(interval time.Duration) {
+	startPeriodic(schedulerCounters, interval, jobs.PeriodicOptions{Local: true}, func(context.Context) (int, error) {
+		if err := FlushCounters(); err != nil {
+			log.Printf("event=counter_flush_failed error=%q", err.Error())
+			return 0, err
+		}
+		return 0, nil
+	})
+}
+
+// FlushCounters adds the increments collected since the last flush to metric_counters, and the API
+// key uses to api_key_usage. Call it on shutdown so that a restart loses none.
+func FlushCounters() error {
+	ctx, cancel := store.WithTimeout(context.Background(), store.OpWrite)
+	defer cancel()
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, c := range persistentCounters {
+		n := atomic.SwapInt64(&c.pending, 0)
+		if n == 0 {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO metric_counters (name, value, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT (name) DO UPDATE SET value = value + excluded.value, updated_at = excluded.updated_at
+		`, c.name, n, now); err != nil {
+			atomic.AddInt64(&c.pending, n)
+			return err
+		}
+	}
+	return flushKeyUsage(ctx)
+}
+
+// counterValues returns every counter's total: what metric_counters holds plus this instance's
+// increments not flushed yet. Should the table be unreadable, the unflushed increments are reported.
+func counterValues(ctx context.Context) map[string]int64 {
+	values := map[string]int64{}
+	for _, c := range persistentCounters {
+		values[c.name] = atomic.LoadInt64(&c.pending)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT name, value FROM metric_counters`)
+	if err != nil {
+		log.Printf("event=counter_read_failed error=%q", err.Error())
+		return values
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var value int64
+		if err := rows.Scan(&name, &value); err != nil {
+			break
+		}
+		if _, ok := values[name]; ok {
+			values[name] += value
+		}
+	}
+	return values
+}
@@ -0,0 +1,402 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Coupon types: percent takes percent_off percent off the price, fixed takes amount_off_minor off
+// orders in the coupon's asset.
+const (
+	couponPercent = "percent"
+	couponFixed   = "fixed"
+)
+
+type couponCreateReq struct {
+	Code           string `json:"code" validate:"required,max=64"`
+	Type           string `json:"type" validate:"required,oneof=percent fixed"`
+	PercentOff     int64  `json:"percent_off,omitempty" validate:"min=1,max=100"` // percent coupons
+	AmountOffMinor string `json:"amount_off_minor,omitempty" validate:"amount"`   // fixed coupons
+	Asset          string `json:"asset,omitempty" validate:"asset"`               // fixed coupons
+	MaxRedemptions *int64 `json:"max_redemptions,omitempty" validate:"min=1"`     // omitted: unlimited
+	ExpiresAt      string `json:"expires_at,omitempty" validate:"rfc3339"`        // omitted: never
+}
+
+// couponUpdateReq changes the given fields only. max_redemptions 0 and expires_at "" remove the
+// limit and the expiry.
+type couponUpdateReq struct {
+	MaxRedemptions *int64  `json:"max_redemptions,omitempty" validate:"min=0"`
+	ExpiresAt      *string `json:"expires_at,omitempty" validate:"rfc3339"`
+	Active         *bool   `json:"active,omitempty"`
+}
+
+type coupon struct {
+	ID             string  `json:"id"`
+	Code           string  `json:"code"`
+	Type           string  `json:"type"`
+	PercentOff     *int64  `json:"percent_off,omitempty"`
+	AmountOffMinor *string `json:"amount_off_minor,omitempty"`
+	Asset          *string `json:"asset,omitempty"`
+	MaxRedemptions *int64  `json:"max_redemptions,omitempty"`
+	Redemptions    int64   `json:"redemptions"`
+	ExpiresAt      *string `json:"expires_at,omitempty"`
+	Active         bool    `json:"active"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+const couponCols = `id, code, percent_off, amount_off_minor, asset, max_redemptions, redemptions, expires_at, active, created_at`
+
+func scanCoupon(row scanner) (coupon, error) {
+	var (
+		c                    coupon
+		percentOff, maxRedem sql.NullInt64
+		amountOff, asset     sql.NullString
+		expiresAt            sql.NullString
+	)
+	if err := row.Scan(&c.ID, &c.Code, &percentOff, &amountOff, &asset, &maxRedem, &c.Redemptions, &expiresAt, &c.Active, &c.CreatedAt); err != nil {
+		return coupon{}, err
+	}
+	c.Type = couponFixed
+	if percentOff.Valid {
+		c.Type = couponPercent
+		c.PercentOff = &percentOff.Int64
+	}
+	if amountOff.Valid {
+		c.AmountOffMinor = &amountOff.String
+	}
+	if asset.Valid {
+		c.Asset = &asset.String
+	}
+	if maxRedem.Valid {
+		c.MaxRedemptions = &maxRedem.Int64
+	}
+	if expiresAt.Valid {
+		c.ExpiresAt = &expiresAt.String
+	}
+	return c, nil
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// couponError is returned by quoteCoupon when a coupon cannot be redeemed on an order.
+type couponError struct{ Msg string }
+
+func (e *couponError) Error() string { return "coupon " + e.Msg }
+
+// couponQuote is the discount a coupon gives on an order.
+type couponQuote struct {
+	ID       string
+	Code     string
+	Discount *big.Int
+}
+
+// quoteCoupon works out the discount of the merchant's coupon code on an order of amountMinor
+// asset, without redeeming it. The discount must leave something to pay.
+func quoteCoupon(ctx context.Context, q queryer, merchantID, code, asset, amountMinor string) (couponQuote, error) {
+	c, err := scanCoupon(q.QueryRowContext(ctx, `SELECT `+couponCols+` FROM coupons WHERE merchant_id = ? AND code = ?`, merchantID, strings.TrimSpace(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return couponQuote{}, &couponError{Msg: "not found"}
+	}
+	if err != nil {
+		return couponQuote{}, err
+	}
+	switch {
+	case !c.Active:
+		return couponQuote{}, &couponError{Msg: "is inactive"}
+	case c.ExpiresAt != nil && *c.ExpiresAt <= time.Now().UTC().Format(time.RFC3339):
+		return couponQuote{}, &couponError{Msg: "has expired"}
+	case c.MaxRedemptions != nil && c.Redemptions >= *c.MaxRedemptions:
+		return couponQuote{}, &couponError{Msg: "has reached its redemption limit"}
+	}
+	amount, _ := new(big.Int).SetString(amountMinor, 10)
+	var discount *big.Int
+	if c.PercentOff != nil {
+		discount = new(big.Int).Mul(amount, big.NewInt(*c.PercentOff))
+		discount.Quo(discount, big.NewInt(100))
+	} else {
+		if !strings.EqualFold(*c.Asset, asset) {
+			return couponQuote{}, &couponError{Msg: "only applies to " + *c.Asset + " orders"}
+		}
+		discount, _ = new(big.Int).SetString(*c.AmountOffMinor, 10)
+	}
+	if discount.Cmp(amount) >= 0 {
+		return couponQuote{}, &couponError{Msg: "discount is not less than the order amount"}
+	}
+	return couponQuote{ID: c.ID, Code: c.Code, Discount: discount}, nil
+}
+
+// redeemCoupon counts a redemption of a quoted coupon, failing if another order took the last one
+// or the coupon was changed since.
+func redeemCoupon(ctx context.Context, id string) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE coupons SET redemptions = redemptions + 1
+		WHERE id = ? AND active = 1 AND (max_redemptions IS NULL OR redemptions < max_redemptions)
+		  AND (expires_at IS NULL OR expires_at > ?)
+	`, id, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return &couponError{Msg: "has reached its redemption limit"}
+	}
+	return nil
+}
+
+// releaseCoupon gives back a redemption whose order was not created.
+func releaseCoupon(ctx context.Context, id string) {
+	_, _ = db.ExecContext(ctx, `UPDATE coupons SET redemptions = redemptions - 1 WHERE id = ? AND redemptions > 0`, id)
+}
+
+// CouponsHandler godoc
+// @Summary      Create or list coupons
+// @Description  POST creates a discount code customers can redeem at order creation (coupon_code): type percent takes percent_off (1-100) percent off the amount, type fixed takes amount_off_minor off orders in asset. max_redemptions limits how many orders may use it and expires_at when it stops being accepted. Codes are unique per merchant, ignoring case. GET lists the merchant's coupons with their redemption counts.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        coupon  body  couponCreateReq  false  "Coupon (POST only)"
+// @Success      200  {array}   coupon
+// @Success      201  {object}  coupon
+// @Failure      400  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /coupons [get]
+// @Router       /coupons [post]
+func CouponsHandler(w http.ResponseWriter, r *http.Request) {
+	merchantID := merchantIDFromContext(r.Context())
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	switch r.Method {
+	case http.MethodPost:
+		var req couponCreateReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		req.Code = strings.TrimSpace(req.Code)
+		if req.Code == "" || len(req.Code) > 64 {
+			badReq(w, "code is required and at most 64 characters")
+			return
+		}
+		var percentOff, amountOff, asset any
+		switch strings.ToLower(req.Type) {
+		case couponPercent:
+			if req.PercentOff < 1 || req.PercentOff > 100 || req.AmountOffMinor != "" {
+				badReq(w, "percent coupons take percent_off between 1 and 100")
+				return
+			}
+			percentOff = req.PercentOff
+		case couponFixed:
+			if !isValidAmountString(req.AmountOffMinor) || req.Asset == "" || req.PercentOff != 0 {
+				badReq(w, "fixed coupons take amount_off_minor (>0) and asset")
+				return
+			}
+			amountOff, asset = req.AmountOffMinor, strings.ToUpper(req.Asset)
+		default:
+			badReq(w, "type must be percent or fixed")
+			return
+		}
+		if req.MaxRedemptions != nil && *req.MaxRedemptions < 1 {
+			badReq(w, "max_redemptions must be at least 1")
+			return
+		}
+		expiresAt, ok := couponExpiry(req.ExpiresAt)
+		if !ok {
+			badReq(w, "expires_at must be an RFC 3339 timestamp")
+			return
+		}
+		id := "cpn_" + uuid.New().String()
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO coupons (id, merchant_id, code, percent_off, amount_off_minor, asset, max_redemptions, expires_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, merchantID, req.Code, percentOff, amountOff, asset, req.MaxRedemptions, expiresAt, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			if sqliteIsUniqueConstraintError(err) {
+				writeProblem(w, http.StatusConflict, CodeCouponExists, "a coupon with this code already exists")
+				return
+			}
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		c, err := getCoupon(ctx, merchantID, id)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, c)
+	case http.MethodGet:
+		rows, err := db.QueryContext(ctx, `SELECT `+couponCols+` FROM coupons WHERE merchant_id = ? ORDER BY created_at, id`, merchantID)
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		defer rows.Close()
+		coupons := []coupon{}
+		for rows.Next() {
+			c, err := scanCoupon(rows)
+			if err != nil {
+				writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+				return
+			}
+			coupons = append(coupons, c)
+		}
+		writeJSON(w, http.StatusOK, coupons)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+// couponExpiry normalizes an optional RFC 3339 expiry to UTC, nil for none.
+func couponExpiry(s string) (any, bool) {
+	if s == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, false
+	}
+	return t.UTC().Format(time.RFC3339), true
+}
+
+func getCoupon(ctx context.Context, merchantID, id string) (coupon, error) {
+	return scanCoupon(db.QueryRowContext(ctx, `SELECT `+couponCols+` FROM coupons WHERE id = ? AND merchant_id = ?`, id, merchantID))
+}
+
+// GetCouponHandler godoc
+// @Summary      Get a coupon
+// @Description  Returns one of the merchant's coupons with its redemption count.
+// @Tags         coupons
+// @Produce      json
+// @Param        id  query  string  true  "Coupon ID"
+// @Success      200  {object}  coupon
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /coupons/get [get]
+func GetCouponHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	c, err := getCoupon(r.Context(), merchantIDFromContext(r.Context()), pathID(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeCouponNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// UpdateCouponHandler godoc
+// @Summary      Update a coupon
+// @Description  Changes a coupon's redemption limit (0 removes it), expiry ("" removes it) or whether it is active; inactive coupons are refused at order creation. The code and discount cannot change, as orders keep the discount they were given.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        id      query  string           true  "Coupon ID"
+// @Param        coupon  body   couponUpdateReq  true  "Fields to change"
+// @Success      200  {object}  coupon
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /coupons/update [post]
+func UpdateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req couponUpdateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	merchantID, id := merchantIDFromContext(r.Context()), pathID(r)
+	c, err := getCoupon(r.Context(), merchantID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeCouponNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	maxRedemptions, expiresAt, active := any(nil), any(nil), c.Active
+	if c.MaxRedemptions != nil {
+		maxRedemptions = *c.MaxRedemptions
+	}
+	if c.ExpiresAt != nil {
+		expiresAt = *c.ExpiresAt
+	}
+	if req.MaxRedemptions != nil {
+		switch {
+		case *req.MaxRedemptions < 0:
+			badReq(w, "max_redemptions must not be negative")
+			return
+		case *req.MaxRedemptions == 0:
+			maxRedemptions = nil
+		default:
+			maxRedemptions = *req.MaxRedemptions
+		}
+	}
+	if req.ExpiresAt != nil {
+		var ok bool
+		if expiresAt, ok = couponExpiry(*req.ExpiresAt); !ok {
+			badReq(w, "expires_at must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	if req.Active != nil {
+		active = *req.Active
+	}
+	if _, err := db.ExecContext(r.Context(), `
+		UPDATE coupons SET max_redemptions = ?, expires_at = ?, active = ? WHERE id = ? AND merchant_id = ?
+	`, maxRedemptions, expiresAt, active, id, merchantID); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if c, err = getCoupon(r.Context(), merchantID, id); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// DeleteCouponHandler godoc
+// @Summary      Delete a coupon
+// @Description  Deletes a coupon so its code can no longer be redeemed (and may be reused). Orders that redeemed it keep their coupon_code and discount.
+// @Tags         coupons
+// @Produce      json
+// @Param        id  query  string  true  "Coupon ID"
+// @Success      200  {object}  map[string]bool
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /coupons/delete [post]
+func DeleteCouponHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	res, err := db.ExecContext(r.Context(), `DELETE FROM coupons WHERE id = ? AND merchant_id = ?`, pathID(r), merchantIDFromContext(r.Context()))
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusNotFound, CodeCouponNotFound, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// customerRecord is a returning-customer profile: one per merchant and payer wallet, built from
+// verified payments.
+type customerRecord struct {
+	ID            string `json:"id"`
+	WalletAddress string `json:"wallet_address"`
+	PaymentCount  int64  `json:"payment_count"`
+	FirstPaidAt   string `json:"first_paid_at"`
+	LastPaidAt    string `json:"last_paid_at"`
+}
+
+type customerListResp struct {
+	Customers  []customerRecord `json:"customers"`
+	NextCursor string           `json:"next_cursor,omitempty"` // pass as cursor to fetch the next page
+}
+
+// customerDetailResp is a customer with their credited payments, newest first.
+type customerDetailResp struct {
+	customerRecord
+	TotalsMinor map[string]string `json:"totals_minor"` // paid amount per asset
+	Payments    []orderGetResp    `json:"payments"`
+}
+
+// maxCustomerPayments bounds the history returned with a customer.
+const maxCustomerPayments = 200
+
+// recordCustomerPayment adds a credited payment to the profile of the order's payer, creating the
+// profile on their first payment. Orders without a known payer wallet are skipped. Call it in the
+// transaction that credits the payment.
+func recordCustomerPayment(ctx context.Context, tx *sql.Tx, orderID, paidAt string) error {
+	var merchantID string
+	var wallet sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT merchant_id, customer_wallet_address FROM orders WHERE id = ?`, orderID).Scan(&merchantID, &wallet); err != nil {
+		return err
+	}
+	if !wallet.Valid || wallet.String == "" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO customers (id, merchant_id, wallet_address, first_paid_at, last_paid_at, payment_count)
+		VALUES (?, ?, ?, ?, ?, 1)
+		ON CONFLICT (merchant_id, wallet_address) DO UPDATE
+		SET last_paid_at = MAX(last_paid_at, excluded.last_paid_at), payment_count = payment_count + 1
+	`, "cus_"+uuid.New().String(), merchantID, wallet.String, paidAt, paidAt)
+	return err
+}
+
+// ListCustomersHandler godoc
+// @Summary      List customers
+// @Description  Returns the merchant's returning-customer profiles, most recently paying first. Profiles are created from verified payments whose payer wallet is known; wallet_address looks one up.
+// @Tags         customers
+// @Produce      json
+// @Param        wallet_address  query  string  false  "Only the customer with this wallet"
+// @Param        limit           query  int     false  "Page size (default 50, max 200)"
+// @Param        cursor          query  string  false  "next_cursor from the previous page"
+// @Success      200  {object}  customerListResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /customers [get]
+func ListCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	limit := 50
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			badReq(w, "limit must be between 1 and 200")
+			return
+		}
+		limit = n
+	}
+	var afterPaidAt, afterID string
+	if c := q.Get("cursor"); c != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(c)
+		var ok bool
+		afterPaidAt, afterID, ok = strings.Cut(string(raw), "|")
+		if err != nil || !ok {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidCursor, "")
+			return
+		}
+	}
+	wallet := q.Get("wallet_address")
+
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpSearch)
+	defer cancel()
+	rows, err := reportDB.QueryContext(ctx, `
+		SELECT id, wallet_address, payment_count, first_paid_at, last_paid_at FROM customers
+		WHERE merchant_id = ? AND (? = '' OR wallet_address = ?)
+		  AND (? = '' OR last_paid_at < ? OR (last_paid_at = ? AND id < ?))
+		ORDER BY last_paid_at DESC, id DESC
+		LIMIT ?
+	`, merchantIDFromContext(ctx), wallet, wallet, afterID, afterPaidAt, afterPaidAt, afterID, limit)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	resp := customerListResp{Customers: []customerRecord{}}
+	for rows.Next() {
+		var c customerRecord
+		if err := rows.Scan(&c.ID, &c.WalletAddress, &c.PaymentCount, &c.FirstPaidAt, &c.LastPaidAt); err != nil {
+			serverErr(w, err)
+			return
+		}
+		resp.Customers = append(resp.Customers, c)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if len(resp.Customers) == limit {
+		last := resp.Customers[len(resp.Customers)-1]
+		resp.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last.LastPaidAt + "|" + last.ID))
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// GetCustomerHandler godoc
+// @Summary      Get a customer with payment history
+// @Description  Returns a customer profile, the total paid per asset and up to 200 of their credited payments, newest first. Archived orders are not included.
+// @Tags         customers
+// @Produce      json
+// @Param        id  query  string  true  "Customer ID"
+// @Success      200  {object}  customerDetailResp
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /customers/get [get]
+func GetCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	merchantID := merchantIDFromContext(ctx)
+	var c customerRecord
+	err := db.QueryRowContext(ctx, `
+		SELECT id, wallet_address, payment_count, first_paid_at, last_paid_at FROM customers WHERE id = ? AND merchant_id = ?
+	`, pathID(r), merchantID).Scan(&c.ID, &c.WalletAddress, &c.PaymentCount, &c.FirstPaidAt, &c.LastPaidAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeProblem(w, http.StatusNotFound, CodeCustomerNotFound, "")
+			return
+		}
+		serverErr(w, err)
+		return
+	}
+
+	orders, err := stores.Orders.List(ctx, store.OrderFilter{
+		MerchantID: merchantID, CustomerWalletAddress: c.WalletAddress, Credited: true, Limit: maxCustomerPayments,
+	})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := customerDetailResp{customerRecord: c, Payments: []orderGetResp{}}
+	for _, o := range orders {
+		resp.Payments = append(resp.Payments, orderResponse(o))
+	}
+	if resp.TotalsMinor, err = customerTotals(ctx, merchantID, c.WalletAddress); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// customerTotals sums a customer's credited payments per asset. Amounts are summed as big
+// integers in Go since SQLite would lose precision on 18-decimal values.
+func customerTotals(ctx context.Context, merchantID, wallet string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT asset, amount_minor FROM orders
+		WHERE merchant_id = ? AND customer_wallet_address = ? COLLATE NOCASE
+		  AND status IN ('PAID', 'SETTLED', 'REFUNDED', 'PARTIALLY_REFUNDED')
+	`, merchantID, wallet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := map[string]*big.Int{}
+	for rows.Next() {
+		var asset, amount string
+		if err := rows.Scan(&asset, &amount); err != nil {
+			return nil, err
+		}
+		n, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return nil, errors.New("invalid amount_minor format")
+		}
+		if totals[asset] == nil {
+			totals[asset] = new(big.Int)
+		}
+		totals[asset].Add(totals[asset], n)
+	}
+	out := map[string]string{}
+	for asset, t := range totals {
+		out[asset] = t.String()
+	}
+	return out, rows.Err()
+}
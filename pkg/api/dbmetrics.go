@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// dbWALSize returns the size of the database's write-ahead log in bytes, for /debug/metrics, or
+// -1 without a database. A log that keeps growing means checkpoints cannot keep up, usually
+// because of long-running readers.
+func dbWALSize(ctx context.Context) int64 {
+	if db == nil {
+		return -1
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	return store.WALSize(ctx, db)
+}
+
+// dbPoolWaits returns how many times a query waited for a free connection of the pool.
+func dbPoolWaits() int64 {
+	if db == nil {
+		return -1
+	}
+	return db.Stats().WaitCount
+}
+
+func writeDBMetrics(b *strings.Builder) {
+	b.WriteString("# HELP ospay_db_transaction_duration_seconds Database transaction durations by outcome (commit, rollback or error).\n# TYPE ospay_db_transaction_duration_seconds histogram\n")
+	for _, h := range store.Contention().Transactions {
+		for i, le := range store.TxDurationBuckets {
+			fmt.Fprintf(b, "ospay_db_transaction_duration_seconds_bucket{outcome=%q,le=%q} %d\n", h.Outcome, strconv.FormatFloat(le, 'g', -1, 64), h.Buckets[i])
+		}
+		fmt.Fprintf(b, "ospay_db_transaction_duration_seconds_bucket{outcome=%q,le=\"+Inf\"} %d\n", h.Outcome, h.Count)
+		fmt.Fprintf(b, "ospay_db_transaction_duration_seconds_sum{outcome=%q} %g\n", h.Outcome, h.Sum)
+		fmt.Fprintf(b, "ospay_db_transaction_duration_seconds_count{outcome=%q} %d\n", h.Outcome, h.Count)
+	}
+}
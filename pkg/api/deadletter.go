@@ -0,0 +1,324 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Events that exhaust the webhook retry policy are dead-lettered: they stay in the outbox as
+// DEAD_LETTER until the merchant requeues them. A merchant whose endpoint keeps dead-lettering,
+// with no successful delivery in between, for longer than deadLetterAlertAfter raises one
+// operator alert per streak.
+var (
+	deadLetterMu         sync.Mutex
+	deadLetterAlertAfter = time.Hour
+	deadLetterAlertURL   string
+
+	deadLetterAlertsTotal int64
+)
+
+// SetDeadLetterAlert configures how long a merchant's endpoint may keep dead-lettering before an
+// alert is logged and POSTed to alertURL (if set). A zero after turns the alerts off.
+func SetDeadLetterAlert(after time.Duration, alertURL string) {
+	deadLetterMu.Lock()
+	defer deadLetterMu.Unlock()
+	deadLetterAlertAfter = after
+	deadLetterAlertURL = alertURL
+}
+
+// maxRequeueEvents bounds one requeue request.
+const maxRequeueEvents = 1000
+
+// startDeadLetterStreak records when the merchant's endpoint started dead-lettering, unless a
+// streak is already running.
+func startDeadLetterStreak(ctx context.Context, db *sql.DB, merchantID string, at time.Time) {
+	if _, err := db.ExecContext(ctx, `
+		UPDATE merchants SET webhook_dead_letter_since = ? WHERE id = ? AND webhook_dead_letter_since IS NULL
+	`, at.UTC().Format(time.RFC3339), merchantID); err != nil {
+		log.Printf("webhook dispatch: record dead letter for merchant %s: %v", merchantID, err)
+	}
+}
+
+// endDeadLetterStreak clears the streak after a successful delivery, re-arming the alert.
+func endDeadLetterStreak(ctx context.Context, db *sql.DB, merchantID string) {
+	if _, err := db.ExecContext(ctx, `
+		UPDATE merchants SET webhook_dead_letter_since = NULL, webhook_dead_letter_alerted_at = NULL
+		WHERE id = ? AND webhook_dead_letter_since IS NOT NULL
+	`, merchantID); err != nil {
+		log.Printf("webhook dispatch: clear dead letters for merchant %s: %v", merchantID, err)
+	}
+}
+
+type deadLetterAlert struct {
+	MerchantID   string `json:"merchant_id"`
+	WebhookURL   string `json:"webhook_url"`
+	Since        string `json:"dead_lettering_since"`
+	DeadLettered int    `json:"dead_lettered"` // events dead-lettered since then and not requeued
+}
+
+// alertDeadLetterStreaks raises the alert for every merchant whose streak has run longer than
+// deadLetterAlertAfter and was not alerted on yet.
+func alertDeadLetterStreaks(ctx context.Context, db *sql.DB) {
+	deadLetterMu.Lock()
+	after, url := deadLetterAlertAfter, deadLetterAlertURL
+	deadLetterMu.Unlock()
+	if after <= 0 {
+		return
+	}
+	now := time.Now().UTC()
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, COALESCE(webhook_url, ''), webhook_dead_letter_since FROM merchants
+		WHERE webhook_dead_letter_since <= ? AND webhook_dead_letter_alerted_at IS NULL AND deleted_at IS NULL
+	`, now.Add(-after).Format(time.RFC3339))
+	if err != nil {
+		log.Printf("webhook dispatch: find dead-lettering merchants: %v", err)
+		return
+	}
+	var alerts []deadLetterAlert
+	for rows.Next() {
+		var a deadLetterAlert
+		if err := rows.Scan(&a.MerchantID, &a.WebhookURL, &a.Since); err != nil {
+			rows.Close()
+			log.Printf("webhook dispatch: find dead-lettering merchants: %v", err)
+			return
+		}
+		alerts = append(alerts, a)
+	}
+	rows.Close()
+	for _, a := range alerts {
+		res, err := db.ExecContext(ctx, `
+			UPDATE merchants SET webhook_dead_letter_alerted_at = ? WHERE id = ? AND webhook_dead_letter_alerted_at IS NULL
+		`, now.Format(time.RFC3339), a.MerchantID)
+		if err != nil {
+			log.Printf("webhook dispatch: mark dead letter alert for merchant %s: %v", a.MerchantID, err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		_ = db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM outbox_events WHERE merchant_id = ? AND status = ? AND dead_lettered_at >= ?
+		`, a.MerchantID, outboxDeadLetter, a.Since).Scan(&a.DeadLettered)
+		atomic.AddInt64(&deadLetterAlertsTotal, 1)
+		log.Printf("event=webhook_dead_lettering merchant_id=%s url=%s since=%s dead_lettered=%d", a.MerchantID, a.WebhookURL, a.Since, a.DeadLettered)
+		go sendOperatorAlert(url, "webhook.dead_lettering", a)
+	}
+}
+
+// alertHTTPClient sends operator alerts. Unlike webhookHTTPClient it may reach private addresses:
+// alert URLs are configured by the operator, and often point at internal services.
+var alertHTTPClient = &http.Client{Timeout: webhookTimeout}
+
+// sendOperatorAlert POSTs an alert event to url, if set. Alerts are best effort and not retried.
+func sendOperatorAlert(url, eventType string, data any) {
+	if url == "" {
+		return
+	}
+	payload, _ := json.Marshal(data)
+	body, _ := json.Marshal(webhookEvent{
+		ID:        "evt_" + uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Data:      payload,
+	})
+	resp, err := alertHTTPClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("%s alert to %s failed: %v", eventType, url, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		log.Printf("%s alert to %s: status %d", eventType, url, resp.StatusCode)
+	}
+}
+
+// outboxDeadLetters counts dead-lettered events, for /debug/metrics; -1 when it cannot be read.
+func outboxDeadLetters(ctx context.Context) int64 {
+	if db == nil {
+		return -1
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	var n int64
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM outbox_events WHERE status = ?`, outboxDeadLetter).Scan(&n); err != nil {
+		return -1
+	}
+	return n
+}
+
+// deadLetterEvent is an event the dispatcher gave up on.
+type deadLetterEvent struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"`
+	CreatedAt      string          `json:"created_at"`
+	AggregateType  string          `json:"aggregate_type"`
+	AggregateID    string          `json:"aggregate_id"`
+	Sequence       int64           `json:"sequence,omitempty"`
+	DeadLetteredAt string          `json:"dead_lettered_at,omitempty"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error,omitempty"`
+	ReplayOf       string          `json:"replay_of,omitempty"`
+	Data           json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+type deadLetterListResp struct {
+	Events     []deadLetterEvent `json:"events"`
+	NextCursor string            `json:"next_cursor,omitempty"` // pass as cursor to fetch the next page
+}
+
+// DeadLetterEventsHandler godoc
+// @Summary      List dead-lettered webhook events
+// @Description  Returns the authenticated merchant's events that were given up on after 10 failed delivery attempts, newest first, with the attempt count and the last error. They stay dead-lettered until requeued with POST /events/dead-letter/requeue.
+// @Tags         webhooks
+// @Produce      json
+// @Param        limit   query  int     false  "Page size (default 50, max 200)"
+// @Param        cursor  query  string  false  "next_cursor from the previous page"
+// @Success      200  {object}  deadLetterListResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /events/dead-letter [get]
+func DeadLetterEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	limit := 50
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			badReq(w, "limit must be between 1 and 200")
+			return
+		}
+		limit = n
+	}
+	where, args := `merchant_id = ? AND status = ?`, []any{merchantIDFromContext(r.Context()), outboxDeadLetter}
+	if c := q.Get("cursor"); c != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(c)
+		createdAt, id, ok := strings.Cut(string(raw), "|")
+		if err != nil || !ok {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidCursor, "")
+			return
+		}
+		where += ` AND (created_at < ? OR (created_at = ? AND id < ?))`
+		args = append(args, createdAt, createdAt, id)
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpSearch)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, event_name, created_at, aggregate_type, aggregate_id, COALESCE(sequence, 0), COALESCE(dead_lettered_at, ''), retry_count, COALESCE(last_error, ''), COALESCE(replay_of, ''), payload_json
+		FROM outbox_events WHERE `+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	resp := deadLetterListResp{Events: []deadLetterEvent{}}
+	for rows.Next() {
+		var ev deadLetterEvent
+		var payload string
+		if err := rows.Scan(&ev.ID, &ev.Type, &ev.CreatedAt, &ev.AggregateType, &ev.AggregateID, &ev.Sequence, &ev.DeadLetteredAt, &ev.Attempts, &ev.LastError, &ev.ReplayOf, &payload); err != nil {
+			serverErr(w, err)
+			return
+		}
+		ev.Data = json.RawMessage(payload)
+		resp.Events = append(resp.Events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if len(resp.Events) == limit {
+		last := resp.Events[len(resp.Events)-1]
+		resp.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last.CreatedAt + "|" + last.ID))
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+type deadLetterRequeueReq struct {
+	EventIDs []string `json:"event_ids,omitempty"` // at most 1000
+	All      bool     `json:"all,omitempty"`       // every dead-lettered event, up to 1000 per request
+}
+
+type deadLetterRequeueResp struct {
+	Requeued int `json:"requeued"`
+}
+
+// RequeueDeadLettersHandler godoc
+// @Summary      Requeue dead-lettered webhook events
+// @Description  Puts the given dead-lettered events (event_ids), or all of them with "all": true, back in the delivery queue with a fresh set of attempts, oldest first, up to 1000 per request. Unlike a replay the events keep their id. IDs that are not dead-lettered events of the merchant are ignored.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        request  body  deadLetterRequeueReq  true  "event_ids or all"
+// @Success      200  {object}  deadLetterRequeueResp
+// @Failure      400  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /events/dead-letter/requeue [post]
+func RequeueDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req deadLetterRequeueReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.All == (len(req.EventIDs) > 0) {
+		writeProblem(w, http.StatusBadRequest, CodeMissingFields, "pass either event_ids or all")
+		return
+	}
+	if len(req.EventIDs) > maxRequeueEvents {
+		writeProblem(w, http.StatusBadRequest, CodeLimitExceeded, "at most 1000 event_ids per request")
+		return
+	}
+	merchantID := merchantIDFromContext(r.Context())
+	cfg, err := loadWebhook(r.Context(), db, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if cfg.URL == "" {
+		writeProblem(w, http.StatusConflict, CodeWebhookNotConfigured, "set a webhook URL first")
+		return
+	}
+
+	where, args := `merchant_id = ? AND status = ?`, []any{merchantID, outboxDeadLetter}
+	if !req.All {
+		where += ` AND id IN (?` + strings.Repeat(`, ?`, len(req.EventIDs)-1) + `)`
+		for _, id := range req.EventIDs {
+			args = append(args, id)
+		}
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := db.ExecContext(r.Context(), `
+		UPDATE outbox_events SET status = ?, retry_count = 0, next_attempt_at = ?, last_error = NULL, dead_lettered_at = NULL
+		WHERE id IN (SELECT id FROM outbox_events WHERE `+where+` ORDER BY created_at, rowid LIMIT ?)
+	`, append([]any{outboxPending, now}, append(args, maxRequeueEvents)...)...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	n, _ := res.RowsAffected()
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), merchantID, "", "dead_letters_requeued", map[string]any{"events": n, "all": req.All})
+	writeJSONOrders(w, http.StatusOK, deadLetterRequeueResp{Requeued: int(n)})
+}
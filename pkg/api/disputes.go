@@ -0,0 +1,454 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Disputes are opened by an operator when a customer contests a payment. While OPEN the disputed
+// amount is moved from the order's funds (merchant bucket, or settlement once SETTLED) into
+// dispute_hold; resolution releases it back (WON) or
+// returns it to the customer through clearing (LOST).
+const (
+	disputeStatusOpen = "OPEN"
+	disputeStatusWon  = "WON"
+	disputeStatusLost = "LOST"
+
+	bucketDisputeHold = "dispute_hold"
+
+	eventDisputeHold    = "DISPUTE_HOLD"
+	eventDisputeRelease = "DISPUTE_RELEASE"
+	eventDisputeLost    = "DISPUTE_LOST"
+)
+
+type disputeCreateReq struct {
+	OrderID     string       `json:"order_id" validate:"required"`
+	AmountMinor *json.Number `json:"amount_minor,omitempty"` // omitted means everything not yet refunded
+	Reason      string       `json:"reason" validate:"required,max=1000"`
+}
+
+type disputeResolveReq struct {
+	Outcome string `json:"outcome"` // "won" (merchant keeps the funds) or "lost" (funds go back to the customer)
+	Note    string `json:"note,omitempty" validate:"max=4000"`
+}
+
+type disputeEvidenceReq struct {
+	Note string `json:"note" validate:"required,max=4000"`
+}
+
+type disputeEvidence struct {
+	ID        string `json:"id"`
+	Author    string `json:"author"` // "merchant" or "admin"
+	Note      string `json:"note"`
+	CreatedAt string `json:"created_at"`
+}
+
+type disputeRecord struct {
+	ID          string            `json:"id"`
+	OrderID     string            `json:"order_id"`
+	MerchantID  string            `json:"merchant_id"`
+	Asset       string            `json:"asset"`
+	AmountMinor string            `json:"amount_minor"`
+	Reason      string            `json:"reason"`
+	Status      string            `json:"status"` // OPEN | WON | LOST
+	CreatedAt   string            `json:"created_at"`
+	ResolvedAt  *string           `json:"resolved_at,omitempty"`
+	Evidence    []disputeEvidence `json:"evidence,omitempty"`
+}
+
+// hasOpenDispute reports whether the order currently has funds frozen by a dispute.
+func hasOpenDispute(ctx context.Context, tx *sql.Tx, orderID string) (bool, error) {
+	var n int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(1) FROM disputes WHERE order_id = ? AND status = ?`, orderID, disputeStatusOpen).Scan(&n)
+	return n > 0, err
+}
+
+// lostDisputesTotal sums the amounts already returned to the customer through lost disputes.
+func lostDisputesTotal(ctx context.Context, q queryer, orderID string) (*big.Int, error) {
+	rows, err := q.QueryContext(ctx, `SELECT amount_minor FROM disputes WHERE order_id = ? AND status = ?`, orderID, disputeStatusLost)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	total := new(big.Int)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, errors.New("invalid dispute amount_minor format")
+		}
+		total.Add(total, v)
+	}
+	return total, rows.Err()
+}
+
+func insertDisputeLedger(ctx context.Context, tx *sql.Tx, disputeID, orderID, merchantID, asset, amount, eventType, debitBucket, creditBucket, now string) error {
+	entry := func(bucket, direction string) store.LedgerEntry {
+		return store.LedgerEntry{
+			OrderID: orderID, MerchantID: merchantID, Asset: asset,
+			AmountMinor: amount, Bucket: bucket, Direction: direction, EventType: eventType, ReferenceID: disputeID, CreatedAt: now,
+		}
+	}
+	return txStores(tx).Ledger.Append(ctx, entry(debitBucket, dirDebit), entry(creditBucket, dirCredit))
+}
+
+// DisputesHandler godoc
+// @Summary      Open or list disputes
+// @Description  POST (admin only) opens a dispute against a PAID, PARTIALLY_REFUNDED or SETTLED order and freezes the disputed amount of the merchant balance. GET lists disputes with their evidence, optionally filtered by order_id and status; merchants only see their own.
+// @Tags         disputes
+// @Accept       json
+// @Produce      json
+// @Param        dispute   body   disputeCreateReq  false  "Dispute info (POST only)"
+// @Param        order_id  query  string            false  "Order ID (GET only)"
+// @Param        status    query  string            false  "OPEN, WON or LOST (GET only)"
+// @Success      200  {array}   disputeRecord
+// @Success      201  {object}  disputeRecord
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /disputes [get]
+// @Router       /admin/disputes [get]
+// @Router       /admin/disputes [post]
+func DisputesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listDisputes(w, r)
+	case http.MethodPost:
+		if !isAdmin(r.Context()) {
+			writeProblem(w, http.StatusForbidden, CodeAdminRequired, "disputes can only be opened by an administrator")
+			return
+		}
+		createDispute(w, r)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func createDispute(w http.ResponseWriter, r *http.Request) {
+	var req disputeCreateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var merchantID, asset, amountMinor, status string
+	err = tx.QueryRowContext(ctx, `SELECT merchant_id, asset, amount_minor, status FROM orders WHERE id = ?`, req.OrderID).Scan(&merchantID, &asset, &amountMinor, &status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "order not found")
+			return
+		}
+		serverErr(w, err)
+		return
+	}
+	if status != "PAID" && status != "PARTIALLY_REFUNDED" && status != "SETTLED" {
+		writeProblem(w, http.StatusConflict, CodeOrderNotDisputable, "only paid or settled orders can be disputed")
+		return
+	}
+	if open, err := hasOpenDispute(ctx, tx, req.OrderID); err != nil {
+		serverErr(w, err)
+		return
+	} else if open {
+		writeProblem(w, http.StatusConflict, CodeDisputeAlreadyOpen, "order already has an open dispute")
+		return
+	}
+
+	orderAmt, ok := new(big.Int).SetString(amountMinor, 10)
+	if !ok {
+		writeProblem(w, http.StatusInternalServerError, CodeInvalidAmount, "invalid order amount_minor format")
+		return
+	}
+	refunded, err := refundsTotal(ctx, tx, req.OrderID, refundStatusCompleted)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	lost, err := lostDisputesTotal(ctx, tx, req.OrderID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	disputable := new(big.Int).Sub(orderAmt, refunded)
+	disputable.Sub(disputable, lost)
+	amt := new(big.Int).Set(disputable)
+	if req.AmountMinor != nil {
+		if _, ok := amt.SetString(req.AmountMinor.String(), 10); !ok {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidDisputeAmount, "dispute amount must be an integer in minor units")
+			return
+		}
+	}
+	if amt.Sign() <= 0 || amt.Cmp(disputable) > 0 {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidDisputeAmount, "dispute amount must be > 0 and at most "+disputable.String())
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	id := "dsp_" + uuid.New().String()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO disputes (id, order_id, merchant_id, asset, amount_minor, reason, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, req.OrderID, merchantID, asset, amt.String(), req.Reason, disputeStatusOpen, now); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if err := insertDisputeLedger(ctx, tx, id, req.OrderID, merchantID, asset, amt.String(), eventDisputeHold, orderFundsBucket(status), bucketDisputeHold, now); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	d := disputeRecord{
+		ID: id, OrderID: req.OrderID, MerchantID: merchantID, Asset: asset, AmountMinor: amt.String(),
+		Reason: req.Reason, Status: disputeStatusOpen, CreatedAt: now,
+	}
+	if err := enqueueEvent(ctx, tx, merchantID, "dispute", id, webhookDisputeOpened, d); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=dispute_opened dispute_id=%s order_id=%s merchant_id=%s asset=%s amount_minor=%s", id, req.OrderID, merchantID, asset, amt.String())
+	writeJSON(w, http.StatusCreated, d)
+}
+
+func listDisputes(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpSearch)
+	defer cancel()
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, order_id, merchant_id, asset, amount_minor, reason, status, created_at, resolved_at
+		FROM disputes
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR order_id = ?) AND (? = '' OR status = ?)
+		ORDER BY created_at, id
+	`, merchantID, merchantID, q.Get("order_id"), q.Get("order_id"), q.Get("status"), q.Get("status"))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	disputes := []disputeRecord{}
+	for rows.Next() {
+		var (
+			d          disputeRecord
+			resolvedAt sql.NullString
+		)
+		if err := rows.Scan(&d.ID, &d.OrderID, &d.MerchantID, &d.Asset, &d.AmountMinor, &d.Reason, &d.Status, &d.CreatedAt, &resolvedAt); err != nil {
+			serverErr(w, err)
+			return
+		}
+		d.ResolvedAt = nullStringPtr(resolvedAt)
+		disputes = append(disputes, d)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	rows.Close()
+	for i := range disputes {
+		ev, err := db.QueryContext(ctx, `SELECT id, author, note, created_at FROM dispute_evidence WHERE dispute_id = ? ORDER BY created_at, id`, disputes[i].ID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		for ev.Next() {
+			var e disputeEvidence
+			if err := ev.Scan(&e.ID, &e.Author, &e.Note, &e.CreatedAt); err != nil {
+				ev.Close()
+				serverErr(w, err)
+				return
+			}
+			disputes[i].Evidence = append(disputes[i].Evidence, e)
+		}
+		ev.Close()
+	}
+	writeJSON(w, http.StatusOK, disputes)
+}
+
+// DisputeEvidenceHandler godoc
+// @Summary      Add dispute evidence
+// @Description  Attaches an evidence note to an open dispute. Available to the merchant and to admins.
+// @Tags         disputes
+// @Accept       json
+// @Produce      json
+// @Param        id        query  string              true  "Dispute ID"
+// @Param        evidence  body   disputeEvidenceReq  true  "Evidence note"
+// @Success      201  {object}  disputeEvidence
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /disputes/evidence [post]
+// @Router       /admin/disputes/evidence [post]
+func DisputeEvidenceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	disputeID := pathID(r)
+	var req disputeEvidenceReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Note) == "" {
+		writeProblem(w, http.StatusBadRequest, CodeMissingFields, "note is required")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	var merchantID, status string
+	err := db.QueryRowContext(ctx, `SELECT merchant_id, status FROM disputes WHERE id = ?`, disputeID).Scan(&merchantID, &status)
+	if err != nil || !authorizedFor(r.Context(), merchantID) {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			serverErr(w, err)
+			return
+		}
+		writeProblem(w, http.StatusNotFound, CodeDisputeNotFound, "dispute not found")
+		return
+	}
+	if status != disputeStatusOpen {
+		writeProblem(w, http.StatusConflict, CodeDisputeClosed, "dispute is already "+status)
+		return
+	}
+	author := "merchant"
+	if isAdmin(r.Context()) {
+		author = "admin"
+	}
+	e := disputeEvidence{
+		ID:        "dev_" + uuid.New().String(),
+		Author:    author,
+		Note:      req.Note,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO dispute_evidence (id, dispute_id, author, note, created_at) VALUES (?, ?, ?, ?, ?)
+	`, e.ID, disputeID, e.Author, e.Note, e.CreatedAt); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+// ResolveDisputeHandler godoc
+// @Summary      Resolve a dispute
+// @Description  Closes an open dispute. "won" releases the held funds back to the merchant; "lost" returns them to the customer. Admin only.
+// @Tags         disputes
+// @Accept       json
+// @Produce      json
+// @Param        id          query  string             true  "Dispute ID"
+// @Param        resolution  body   disputeResolveReq  true  "Outcome"
+// @Success      200  {object}  disputeRecord
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/disputes/resolve [post]
+func ResolveDisputeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	disputeID := pathID(r)
+	var req disputeResolveReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	var newStatus, eventType, creditBucket string
+	switch strings.ToLower(req.Outcome) {
+	case "won":
+		newStatus, eventType = disputeStatusWon, eventDisputeRelease
+	case "lost":
+		newStatus, eventType, creditBucket = disputeStatusLost, eventDisputeLost, bucketClearing
+	default:
+		writeProblem(w, http.StatusBadRequest, CodeInvalidOutcome, `outcome must be "won" or "lost"`)
+		return
+	}
+
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var d disputeRecord
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, order_id, merchant_id, asset, amount_minor, reason, status, created_at FROM disputes WHERE id = ?
+	`, disputeID).Scan(&d.ID, &d.OrderID, &d.MerchantID, &d.Asset, &d.AmountMinor, &d.Reason, &d.Status, &d.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeProblem(w, http.StatusNotFound, CodeDisputeNotFound, "dispute not found")
+			return
+		}
+		serverErr(w, err)
+		return
+	}
+	if d.Status != disputeStatusOpen {
+		writeProblem(w, http.StatusConflict, CodeDisputeClosed, "dispute is already "+d.Status)
+		return
+	}
+	if newStatus == disputeStatusWon {
+		// Orders with an open dispute are not settled, so the hold goes back where it came from.
+		var orderStatus string
+		if err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ?`, d.OrderID).Scan(&orderStatus); err != nil {
+			serverErr(w, err)
+			return
+		}
+		creditBucket = orderFundsBucket(orderStatus)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `UPDATE disputes SET status = ?, resolved_at = ? WHERE id = ?`, newStatus, now, disputeID); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := insertDisputeLedger(ctx, tx, d.ID, d.OrderID, d.MerchantID, d.Asset, d.AmountMinor, eventType, bucketDisputeHold, creditBucket, now); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if req.Note != "" {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO dispute_evidence (id, dispute_id, author, note, created_at) VALUES (?, ?, 'admin', ?, ?)
+		`, "dev_"+uuid.New().String(), disputeID, req.Note, now); err != nil {
+			serverErr(w, err)
+			return
+		}
+	}
+	d.Status = newStatus
+	d.ResolvedAt = &now
+	if err := enqueueEvent(ctx, tx, d.MerchantID, "dispute", d.ID, webhookDisputeResolved, d); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=dispute_resolved dispute_id=%s order_id=%s outcome=%s amount_minor=%s", d.ID, d.OrderID, newStatus, d.AmountMinor)
+	writeJSON(w, http.StatusOK, d)
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// Merchants may give an ENS name as their wallet. It is resolved at creation, and the address is
+// what orders are paid to and settlements go to; the ENS refresher re-resolves the names and
+// follows a name that was pointed elsewhere, raising an alert each time.
+var (
+	ensMu       sync.Mutex
+	ensAlertURL string
+
+	ensWalletChangesTotal int64
+)
+
+// SetENSAlert sets the URL that merchant.wallet_changed alerts are POSTed to; "" only logs them.
+func SetENSAlert(alertURL string) {
+	ensMu.Lock()
+	defer ensMu.Unlock()
+	ensAlertURL = alertURL
+}
+
+// ensResolveTimeout bounds one resolution, at creation and in the refresher.
+const ensResolveTimeout = 10 * time.Second
+
+// ensLookupError is an ENS resolution that failed on the way to the chain, not because of the name.
+type ensLookupError struct{ err error }
+
+func (e *ensLookupError) Error() string { return "ENS resolution failed: " + e.err.Error() }
+
+// merchantWallet validates the wallet given when a merchant is created. An ENS name is resolved,
+// and returned as ensName alongside the address it points to.
+func merchantWallet(ctx context.Context, input string) (wallet, ensName string, err error) {
+	if !blockchain.IsENSName(input) {
+		wallet, _, err = blockchain.NormalizeAnyAddress(input)
+		return wallet, "", err
+	}
+	ensName = blockchain.NormalizeENSName(input)
+	ctx, cancel := context.WithTimeout(ctx, ensResolveTimeout)
+	defer cancel()
+	addr, err := blockchain.ResolveENS(ctx, ensName)
+	switch {
+	case errors.Is(err, blockchain.ErrENSNotFound):
+		return "", "", errors.New("the ENS name " + ensName + " does not resolve to an address; set its ETH address record or give the address itself")
+	case errors.Is(err, blockchain.ErrUnsupportedChain):
+		return "", "", errors.New("ENS names cannot be resolved on this server (no ETH RPC endpoint); give the address itself")
+	case err != nil:
+		return "", "", &ensLookupError{err}
+	}
+	return addr.Hex(), ensName, nil
+}
+
+// writeWalletProblem reports why merchantWallet refused a wallet.
+func writeWalletProblem(w http.ResponseWriter, err error) {
+	var le *ensLookupError
+	if errors.As(err, &le) {
+		writeProblem(w, http.StatusBadGateway, CodeRPCUnavailable, le.Error())
+		return
+	}
+	writeProblem(w, http.StatusBadRequest, CodeInvalidWalletAddress, "merchant_wallet_address: "+err.Error())
+}
+
+// StartENSRefresher re-resolves the ENS names of merchant wallets every interval.
+func StartENSRefresher(interval time.Duration) {
+	startScheduler(schedulerENS, interval, false, refreshENSWallets)
+}
+
+type ensMerchant struct {
+	id, name, wallet string
+}
+
+// refreshENSWallets resolves every merchant's ENS name again and moves the wallet to the address
+// the name now points to. A name that no longer resolves keeps the last address.
+func refreshENSWallets(ctx context.Context) (int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, wallet_ens_name, COALESCE(merchant_wallet_address, '') FROM merchants
+		WHERE wallet_ens_name IS NOT NULL AND wallet_ens_name != '' AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var merchants []ensMerchant
+	for rows.Next() {
+		var m ensMerchant
+		if err := rows.Scan(&m.id, &m.name, &m.wallet); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		merchants = append(merchants, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var lastErr error
+	for _, m := range merchants {
+		if err := refreshENSWallet(ctx, m); err != nil {
+			log.Printf("event=merchant_ens_resolve_failed merchant_id=%s name=%s err=%v", m.id, m.name, err)
+			lastErr = fmt.Errorf("%s: %w", m.name, err)
+		}
+	}
+	return len(merchants), lastErr
+}
+
+// walletChange is the payload of merchant.wallet_changed.
+type walletChange struct {
+	MerchantID      string `json:"merchant_id"`
+	ENSName         string `json:"ens_name"`
+	PreviousAddress string `json:"previous_address"`
+	WalletAddress   string `json:"wallet_address"`
+	ChangedAt       string `json:"changed_at"`
+}
+
+func refreshENSWallet(ctx context.Context, m ensMerchant) error {
+	rctx, cancel := context.WithTimeout(ctx, ensResolveTimeout)
+	addr, err := blockchain.ResolveENS(rctx, m.name)
+	cancel()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	wallet := addr.Hex()
+	if wallet == m.wallet {
+		_, err := db.ExecContext(ctx, `UPDATE merchants SET wallet_ens_checked_at = ? WHERE id = ?`, now, m.id)
+		return err
+	}
+
+	change := walletChange{MerchantID: m.id, ENSName: m.name, PreviousAddress: m.wallet, WalletAddress: wallet, ChangedAt: now}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Only move the wallet if it is still the one resolved before
+	res, err := tx.ExecContext(ctx, `
+		UPDATE merchants SET merchant_wallet_address = ?, wallet_ens_checked_at = ?
+		WHERE id = ? AND wallet_ens_name = ? AND COALESCE(merchant_wallet_address, '') = ?
+	`, wallet, now, m.id, m.name, m.wallet)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := enqueueEvent(ctx, tx, m.id, "merchant", m.id, webhookMerchantWalletChanged, change); err != nil {
+		return err
+	}
+	recordAudit(ctx, tx, "system", m.id, "", "merchant.wallet_changed", change)
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&ensWalletChangesTotal, 1)
+	log.Printf("event=merchant_wallet_changed merchant_id=%s name=%s previous=%s wallet=%s", m.id, m.name, m.wallet, wallet)
+	ensMu.Lock()
+	url := ensAlertURL
+	ensMu.Unlock()
+	go sendOperatorAlert(url, webhookMerchantWalletChanged, change)
+	return nil
+}
@@ -6,9 +6,9 @@
 // @Produce      json
 // @Param        payment  body  paymentDetectedReq  true  "Payment info"
 // @Success      200  {object}  paymentDetectedResp
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Security     ApiKeyAuth
 // @Router       /events/payment-detected [post]
 package api
@@ -18,20 +18,23 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"math/big"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/jobs"
+	"github.com/oxzoid/OSPay/pkg/store"
 )
 
-// var paymentsDetectedTotal int64
-var paymentsDetectedTotal int64
-
 // throttle concurrent on-chain verifications and dedupe tx hashes
 var (
 	verifySem  = make(chan struct{}, 50) // cap concurrent verifications
@@ -44,8 +47,8 @@ var (
 
 // ----- request/response types -----
 type paymentDetectedReq struct {
-	OrderID     string  `json:"order_id"`
-	TxHash      string  `json:"tx_hash"`
+	OrderID     string  `json:"order_id" validate:"required"`
+	TxHash      string  `json:"tx_hash" validate:"required"`
 	AmountMinor *string `json:"amount_minor,omitempty"` // optional override; if nil, use order.amount_minor (string for large numbers)
 }
 
@@ -57,44 +60,187 @@ type paymentDetectedResp struct {
 
 // Optional background verification job (decouples API from RPC latency)
 type verifyJob struct {
-	OrderID    string
-	TxHash     string
-	MerchantID string
+	OrderID    string `json:"order_id"`
+	TxHash     string `json:"tx_hash"`
+	MerchantID string `json:"merchant_id"`
 }
 
+// jobVerifyPayment is the job type of payment verification.
+const jobVerifyPayment = "payment_verification"
+
+// verifyAsync is set once verification workers are started; until then payments are verified inline.
+var verifyAsync bool
+
+// verifyQueueLimit caps the verification jobs waiting or running; reports beyond it are refused
+// with 503 and Retry-After until the workers catch up. Zero means no cap.
+var verifyQueueLimit int64 = 10000
+
+// verifyRetryAfter is the Retry-After of a refused report.
+const verifyRetryAfter = 10 * time.Second
+
 var (
-	verifyJobs chan verifyJob
+	// verifyDepth caches the verification queue depth for a second, so that reports do not each
+	// count the queue; reports accepted since add to it.
+	verifyDepthMu sync.Mutex
+	verifyDepth   int64
+	verifyDepthAt time.Time
+
+	verifyRejectedTotal int64
 )
 
-// StartVerificationWorkers starts n workers processing verification jobs. Call from main during startup if desired.
-func StartVerificationWorkers(n int) {
-	if n <= 0 {
-		n = 1
+// SetVerifyQueueLimit sets how many verification jobs may wait or run at once; 0 removes the cap.
+func SetVerifyQueueLimit(n int64) { verifyQueueLimit = n }
+
+// verifyQueueFull reports whether the verification queue is at its limit.
+func verifyQueueFull(ctx context.Context) (bool, error) {
+	if verifyQueueLimit <= 0 {
+		return false, nil
 	}
-	if verifyJobs == nil {
-		verifyJobs = make(chan verifyJob, 1000)
+	verifyDepthMu.Lock()
+	defer verifyDepthMu.Unlock()
+	if time.Since(verifyDepthAt) > time.Second {
+		n, err := jobQueue.Depth(ctx, jobVerifyPayment)
+		if err != nil {
+			return false, err
+		}
+		verifyDepth, verifyDepthAt = n, time.Now()
 	}
-	for i := 0; i < n; i++ {
-		go func() {
-			for job := range verifyJobs {
-				// Process verification jobs asynchronously
-				processVerificationJob(job)
-			}
-		}()
+	return verifyDepth >= verifyQueueLimit, nil
+}
+
+// verifyQueued adds a report accepted into the queue to the cached depth.
+func verifyQueued() {
+	verifyDepthMu.Lock()
+	verifyDepth++
+	verifyDepthMu.Unlock()
+}
+
+// verifyQueueDepth returns the verification jobs waiting or running, for /debug/metrics, or -1
+// should the queue be unreadable.
+func verifyQueueDepth(ctx context.Context) int64 {
+	if jobQueue == nil {
+		return -1
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	n, err := jobQueue.Depth(ctx, jobVerifyPayment)
+	if err != nil {
+		return -1
 	}
+	return n
+}
+
+// StartVerificationWorkers starts n workers verifying the payments reported to
+// /events/payment-detected. The jobs are queued in the database, so a restart loses none, and a
+// verification that fails on the database or the RPC node is tried again as the verification
+// retry policy says.
+func StartVerificationWorkers(n int) {
+	jobQueue.Register(jobVerifyPayment, func(_ context.Context, job jobs.Job) error {
+		var vj verifyJob
+		if err := json.Unmarshal(job.Payload, &vj); err != nil {
+			return jobs.Permanent(err)
+		}
+		return processVerificationJob(vj, job.Last)
+	}, jobs.Options{Workers: n, Timeout: 30 * time.Second, RetryFunc: func() jobs.RetryPolicy { return retryPolicy(retryVerification) }})
+	verifyAsync = true
 }
 
 // ----- constants for ledger -----
 const (
-	bucketMerchant = "merchant"
-	bucketClearing = "clearing"
+	bucketMerchant    = "merchant"   // paid to the merchant, not settled yet
+	bucketSettlement  = "settlement" // settled, waiting to be paid out, converted or off-ramped
+	bucketClearing    = "clearing"
+	bucketPlatformFee = "platform_fee"
 
 	dirDebit  = "debit"
 	dirCredit = "credit"
 
 	eventPaymentConfirmed = "PAYMENT_CONFIRMED"
+	eventSettlement       = "SETTLEMENT"
 )
 
+// orderFundsBucket is the bucket holding the merchant's funds of an order in status: the
+// settlement bucket once it is SETTLED, the merchant bucket before.
+func orderFundsBucket(status string) string {
+	if status == "SETTLED" {
+		return bucketSettlement
+	}
+	return bucketMerchant
+}
+
+// paymentBlock is the block of a verified payment as stored on the order; both are NULL for
+// payments not verified on-chain.
+type paymentBlock struct {
+	number sql.NullInt64
+	time   sql.NullString
+}
+
+func blockOf(t blockchain.VerifiedTransfer) paymentBlock {
+	b := paymentBlock{number: sql.NullInt64{Int64: int64(t.BlockNumber), Valid: true}}
+	if !t.BlockTime.IsZero() {
+		b.time = sql.NullString{String: t.BlockTime.Format(time.RFC3339), Valid: true}
+	}
+	return b
+}
+
+// writePaymentLedger writes the PAYMENT_CONFIRMED double entry for an order inside tx:
+//
+//	a) merchant    CREDIT  amount - application fee
+//	b) platform_fee CREDIT application fee (only for platform orders with a fee)
+//	c) clearing    DEBIT   amount
+func writePaymentLedger(ctx context.Context, tx *sql.Tx, orderID, merchantID, asset, amountMinor, txHash, now string) error {
+	entries, err := paymentLedgerEntries(ctx, tx, orderID, merchantID, asset, amountMinor, txHash, now)
+	if err != nil {
+		return err
+	}
+	return txStores(tx).Ledger.Append(ctx, entries...)
+}
+
+// paymentLedgerEntries builds the PAYMENT_CONFIRMED entries of an order's payment: the merchant's
+// net and the platform's application fee credited against clearing.
+func paymentLedgerEntries(ctx context.Context, tx *sql.Tx, orderID, merchantID, asset, amountMinor, txHash, now string) ([]store.LedgerEntry, error) {
+	amount, ok := new(big.Int).SetString(amountMinor, 10)
+	if !ok {
+		return nil, errors.New("invalid amount_minor format")
+	}
+	var appFee, rateQuoteID sql.NullString
+	if err := tx.QueryRowContext(ctx, `
+		SELECT application_fee_minor, json_extract(fiat_pricing_json, '$.rate_quote.quote_id') FROM orders WHERE id = ?
+	`, orderID).Scan(&appFee, &rateQuoteID); err != nil {
+		return nil, err
+	}
+	fee := new(big.Int)
+	if appFee.Valid && appFee.String != "" {
+		if _, ok := fee.SetString(appFee.String, 10); !ok {
+			return nil, errors.New("invalid application_fee_minor format")
+		}
+	}
+	if fee.Cmp(amount) > 0 {
+		return nil, errors.New("application fee exceeds payment amount")
+	}
+	merchantNet := new(big.Int).Sub(amount, fee)
+
+	entry := func(amount, bucket, direction string) store.LedgerEntry {
+		return store.LedgerEntry{
+			OrderID: orderID, MerchantID: merchantID, Asset: asset, AmountMinor: amount,
+			Bucket: bucket, Direction: direction, EventType: eventPaymentConfirmed, TxHash: txHash, RateQuoteID: rateQuoteID.String, CreatedAt: now,
+		}
+	}
+	entries := []store.LedgerEntry{entry(merchantNet.String(), bucketMerchant, dirCredit)}
+	if fee.Sign() > 0 {
+		entries = append(entries, entry(fee.String(), bucketPlatformFee, dirCredit))
+	}
+	entries = append(entries, entry(amountMinor, bucketClearing, dirDebit))
+	return entries, nil
+}
+
+// authorizedFor reports whether the authenticated merchant (if any) is merchantID; admins, with no
+// merchant in the context, are authorized for every merchant.
+func authorizedFor(ctx context.Context, merchantID string) bool {
+	authID := merchantIDFromContext(ctx)
+	return authID == "" || authID == merchantID
+}
+
 func writeJSON(w http.ResponseWriter, code int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -103,56 +249,75 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 
 // PaymentDetectedHandler godoc
 // @Summary      Detect payment event
-// @Description  Notify the system of an on-chain payment for an order
+// @Description  Notify the system of an on-chain payment for an order. The payment is verified in the background and the report answered with 202; when too many payments are already waiting for verification, the report is refused with 503 and Retry-After, and should be sent again after that many seconds.
 // @Tags         events
 // @Accept       json
 // @Produce      json
 // @Param        payment  body  paymentDetectedReq  true  "Payment info"
 // @Success      200  {object}  paymentDetectedResp
 // @Success      202  {object}  paymentDetectedResp
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Failure      503  {object}  Problem
 // @Security     ApiKeyAuth
 // @Router       /events/payment-detected [post]
 func PaymentDetectedHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	if db == nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_not_initialized", "db not initialized")
+		writeProblem(w, http.StatusInternalServerError, CodeDBNotInitialized, "db not initialized")
 		return
 	}
 
 	var req paymentDetectedReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, "invalid_json", "invalid JSON")
-		return
-	}
-	if req.OrderID == "" || req.TxHash == "" {
-		writeErrorJSON(w, http.StatusBadRequest, "missing_fields", "order_id and tx_hash required")
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
-	if verifyJobs != nil {
+	if verifyAsync {
 		// Load merchant_id for the job (needed by worker)
 		var merchantID string
-		if err := db.QueryRow(`SELECT merchant_id FROM orders WHERE id = ?`, req.OrderID).Scan(&merchantID); err != nil {
-			writeErrorJSON(w, http.StatusNotFound, "order_not_found", "order not found")
+		if err := db.QueryRow(`SELECT merchant_id FROM orders WHERE id = ? AND deleted_at IS NULL`, req.OrderID).Scan(&merchantID); err != nil ||
+			!authorizedFor(r.Context(), merchantID) {
+			writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "order not found")
 			return
 		}
-
-		select {
-		case verifyJobs <- verifyJob{OrderID: req.OrderID, TxHash: req.TxHash, MerchantID: merchantID}:
-			writeJSON(w, http.StatusAccepted, paymentDetectedResp{OrderID: req.OrderID, Status: "PENDING", Message: "verification enqueued"})
+		full, err := verifyQueueFull(r.Context())
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		if full {
+			atomic.AddInt64(&verifyRejectedTotal, 1)
+			w.Header().Set("Retry-After", strconv.Itoa(int(verifyRetryAfter.Seconds())))
+			writeProblem(w, http.StatusServiceUnavailable, CodeVerificationQueueFull, "too many payments are waiting for verification; report again later")
+			return
+		}
+		// Reports of the same payment while one is being verified are folded into it
+		_, queued, err := jobQueue.Enqueue(r.Context(), db, jobs.NewJob{
+			Type:    jobVerifyPayment,
+			Payload: verifyJob{OrderID: req.OrderID, TxHash: req.TxHash, MerchantID: merchantID},
+			Key:     req.OrderID + ":" + strings.ToLower(req.TxHash),
+		})
+		if err != nil {
+			serverErr(w, err)
 			return
-		default:
-			// queue full, fall back to inline path
 		}
+		logPaymentAttempt(req.OrderID, merchantID, req.TxHash, true, attemptPending, "", "")
+		msg := "verification enqueued"
+		if queued {
+			verifyQueued()
+		} else {
+			msg = "verification already enqueued"
+		}
+		writeJSON(w, http.StatusAccepted, paymentDetectedResp{OrderID: req.OrderID, Status: "PENDING", Message: msg})
+		return
 	}
 
-	// Inline path (fallback): do verification and DB updates synchronously
+	// Inline path (no workers): do verification and DB updates synchronously
 	// dedupe: if we've recently processed this tx_hash, short-circuit
 	recentTxMu.RLock()
 	t, ok := recentTx[strings.ToLower(req.TxHash)]
@@ -162,11 +327,11 @@ func PaymentDetectedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reqCtx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	reqCtx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
 	defer cancel()
 	tx, err := db.BeginTx(reqCtx, &sql.TxOptions{})
 	if err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
 		return
 	}
 	defer func() {
@@ -177,19 +342,24 @@ func PaymentDetectedHandler(w http.ResponseWriter, r *http.Request) {
 		merchantID  string
 		amountMinor string
 		asset       string
+		chain       string
 		status      string
 	)
 	err = tx.QueryRowContext(reqCtx, `
-		   SELECT merchant_id, amount_minor, asset, status
+		   SELECT merchant_id, amount_minor, asset, chain, status
 		   FROM orders
-		   WHERE id = ?
-	   `, req.OrderID).Scan(&merchantID, &amountMinor, &asset, &status)
+		   WHERE id = ? AND deleted_at IS NULL
+	   `, req.OrderID).Scan(&merchantID, &amountMinor, &asset, &chain, &status)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			writeErrorJSON(w, http.StatusNotFound, "order_not_found", "order not found")
+			writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "order not found")
 			return
 		}
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if !authorizedFor(r.Context(), merchantID) {
+		writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "order not found")
 		return
 	}
 
@@ -197,36 +367,109 @@ func PaymentDetectedHandler(w http.ResponseWriter, r *http.Request) {
 	var merchantWalletAddress string
 	err = tx.QueryRowContext(reqCtx, `SELECT merchant_wallet_address FROM merchants WHERE id = ?`, merchantID).Scan(&merchantWalletAddress)
 	if err != nil || merchantWalletAddress == "" {
-		writeErrorJSON(w, http.StatusBadRequest, "missing_wallet_address", "merchant wallet address not set")
+		writeProblem(w, http.StatusBadRequest, CodeMissingWalletAddress, "merchant wallet address not set")
 		return
 	}
 
-	// 1c) on-chain verification for BSC-USD on BSC (throttled)
+	// 1b') another transfer reported for an order already paid is credited as an overpayment, whatever its amount
+	if overpaidStatuses[status] {
+		_ = tx.Rollback()
+		ovp, err := creditDuplicatePayment(reqCtx, req.OrderID, req.TxHash, true)
+		if err != nil {
+			logPaymentAttempt(req.OrderID, merchantID, req.TxHash, true, attemptFailed, verificationFailureReason(err), err.Error())
+			detail := "transfer not found or invalid"
+			if errors.Is(err, errOverpaymentSenders) {
+				detail = err.Error()
+			}
+			writeProblem(w, http.StatusBadRequest, CodeOnchainVerificationFailed, detail)
+			return
+		}
+		msg := "no-op (already processed)"
+		switch {
+		case ovp != nil && ovp.Status == overpaymentConfirming:
+			msg = "order already paid; the transfer was recorded as overpayment " + ovp.ID + " and is credited once its block is final"
+		case ovp != nil:
+			msg = "order already paid; the transfer was credited as overpayment " + ovp.ID + ", refundable to the sender"
+		default:
+			logSettledPaymentAttempt(req.OrderID, merchantID, req.TxHash, true)
+		}
+		writeJSON(w, http.StatusOK, paymentDetectedResp{OrderID: req.OrderID, Status: status, Message: msg})
+		return
+	}
+
+	// 1c) on-chain verification for BSC-USD on BSC (throttled); the verified sender becomes the refund destination
+	var customerWallet sql.NullString
+	var block paymentBlock
 	if strings.ToUpper(asset) == "USDT" && strings.Contains(strings.ToLower(asset+"-bsc"), "bsc") {
 		verifySem <- struct{}{}
 		defer func() { <-verifySem }()
 		// amount_minor is stored as string for 18 decimals (wei-style), parse to big.Int
 		expectedAmount, ok := new(big.Int).SetString(amountMinor, 10)
 		if !ok {
-			writeErrorJSON(w, http.StatusBadRequest, "invalid_amount", "invalid amount_minor format")
+			writeProblem(w, http.StatusBadRequest, CodeInvalidAmount, "invalid amount_minor format")
 			return
 		}
 
 		log.Printf("BSC verification: using amount %s (18-decimal) directly", amountMinor)
 
-		ok, err := blockchain.VerifyBSCUSDTransfer(req.TxHash, merchantWalletAddress, expectedAmount)
+		transfer, ok, err := blockchain.VerifyBSCUSDTransfer(req.TxHash, merchantWalletAddress, expectedAmount)
 		if err != nil || !ok {
-			writeErrorJSON(w, http.StatusBadRequest, "onchain_verification_failed", "BSC-USD transfer not found or invalid")
+			if m, ferr := flagMispayment(reqCtx, req.OrderID, merchantID, chain, asset, merchantWalletAddress, req.TxHash, err, true); ferr != nil {
+				log.Printf("mispayment check order=%s tx=%s: %v", req.OrderID, req.TxHash, ferr)
+			} else if m != nil {
+				writeJSON(w, http.StatusOK, paymentDetectedResp{
+					OrderID: req.OrderID,
+					Status:  statusMispaid,
+					Message: "transfer found in " + m.Asset + " on " + m.Chain + "; the order needs manual resolution",
+				})
+				return
+			}
+			var detail string
+			if err != nil {
+				detail = err.Error()
+			}
+			logPaymentAttempt(req.OrderID, merchantID, req.TxHash, true, attemptFailed, verificationFailureReason(err), detail)
+			writeProblem(w, http.StatusBadRequest, CodeOnchainVerificationFailed, "BSC-USD transfer not found or invalid")
 			return
 		}
 		recentTxMu.Lock()
 		recentTx[strings.ToLower(req.TxHash)] = time.Now()
 		recentTxMu.Unlock()
+		customerWallet = sql.NullString{String: transfer.From, Valid: true}
+		block = blockOf(transfer)
+	}
+
+	// 1c') the sender is screened with the transaction closed, as the provider is called over the
+	// network; the order's status is read again afterwards, and the guarded update below still only
+	// credits an order that is waiting for its payment
+	var screening payerScreening
+	if riskScreener != nil && customerWallet.Valid && !awaitFinality(chain, block) {
+		_ = tx.Rollback()
+		screening = screenPayer(reqCtx, req.OrderID, chain, customerWallet.String)
+		if tx, err = db.BeginTx(reqCtx, &sql.TxOptions{}); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		if err := tx.QueryRowContext(reqCtx, `SELECT status FROM orders WHERE id = ?`, req.OrderID).Scan(&status); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+	}
+
+	// a transfer credited as another order's overpayment cannot pay this one
+	if ovpID, err := overpaymentOf(reqCtx, tx, req.TxHash); err != nil {
+		serverErr(w, err)
+		return
+	} else if ovpID != "" {
+		logPaymentAttempt(req.OrderID, merchantID, req.TxHash, true, attemptFailed, attemptTxAlreadyUsed, "credited as overpayment "+ovpID)
+		writeProblem(w, http.StatusConflict, CodeTxAlreadyUsed, "the transaction was credited as an overpayment of another order")
+		return
 	}
 
 	// idempotency: if already PAID (or beyond), return OK without duplicating ledger
-	if status == "PAID" || status == "SETTLED" || status == "REFUNDED" {
+	if status == "PAID" || status == "SETTLED" || status == "REFUNDED" || status == "PARTIALLY_REFUNDED" || status == statusReview || status == statusLatePayment || status == statusConfirming {
 		_ = tx.Commit()
+		logSettledPaymentAttempt(req.OrderID, merchantID, req.TxHash, true)
 		writeJSON(w, http.StatusOK, paymentDetectedResp{
 			OrderID: req.OrderID,
 			Status:  status,
@@ -235,27 +478,82 @@ func PaymentDetectedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// a payment for an expired order is still taken within the grace window
+	late := status == statusExpired
+	var holdLate bool
+	if late {
+		allowed, review, err := checkLatePayment(reqCtx, tx, req.OrderID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		if !allowed {
+			log.Printf("event=late_payment_rejected order_id=%s merchant_id=%s tx_hash=%s", req.OrderID, merchantID, req.TxHash)
+			logPaymentAttempt(req.OrderID, merchantID, req.TxHash, true, attemptFailed, attemptOrderExpired, "")
+			writeProblem(w, http.StatusConflict, CodeOrderExpired, "order expired and the late payment grace window has passed")
+			return
+		}
+		holdLate = review
+	}
+
+	// 1d) a payment mined in a block that is not yet final is only recorded; the confirmation tracker credits it
+	if awaitFinality(chain, block) {
+		marked, err := markConfirming(reqCtx, tx, req.OrderID, req.TxHash, customerWallet, block, late)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		if !marked {
+			_ = tx.Commit()
+			logSettledPaymentAttempt(req.OrderID, merchantID, req.TxHash, true)
+			writeJSON(w, http.StatusOK, paymentDetectedResp{
+				OrderID: req.OrderID,
+				Status:  status,
+				Message: "no-op (already processed)",
+			})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		log.Printf("event=payment_confirming order_id=%s merchant_id=%s tx_hash=%s block=%d", req.OrderID, merchantID, req.TxHash, block.number.Int64)
+		logPaymentAttempt(req.OrderID, merchantID, req.TxHash, true, attemptSucceeded, "", "")
+		writeJSON(w, http.StatusAccepted, paymentDetectedResp{
+			OrderID: req.OrderID,
+			Status:  statusConfirming,
+			Message: fmt.Sprintf("payment found; credited after %d confirmations", blockchain.FinalityDepth(chain)),
+		})
+		return
+	}
+
 	// optional override amount
 	if req.AmountMinor != nil && isValidAmountString(*req.AmountMinor) {
 		amountMinor = *req.AmountMinor
 	}
 
 	now := time.Now().UTC().Format(time.RFC3339)
+	assessment := assessPayment(reqCtx, tx, screening, req.OrderID, merchantID, asset, chain, amountMinor, customerWallet.String)
+	if holdLate {
+		holdLatePayment(&assessment)
+	}
 
-	// 2) update order -> PAID, set tx_hash, paid_at, but only if status is PENDING or CONFIRMING
+	// 2) update order -> PAID (or REVIEW / LATE_PAYMENT if held), set tx_hash, paid_at, but only if status is PENDING or MISPAID (or EXPIRED within the grace window)
 	res, err := tx.ExecContext(reqCtx, `
 		UPDATE orders
-		SET status = ?, tx_hash = ?, paid_at = ?
-		WHERE id = ? AND (status = 'PENDING' OR status = 'CONFIRMING')
-	`, "PAID", req.TxHash, now, req.OrderID)
+		SET status = ?, tx_hash = ?, paid_at = ?, customer_wallet_address = COALESCE(?, customer_wallet_address),
+		    confirmed_block = ?, block_timestamp = ?, risk_reason = ?, risk_score = ?, risk_factors = ?
+		WHERE id = ? AND (status IN ('PENDING', 'MISPAID') OR (status = 'EXPIRED' AND ?))
+	`, assessment.Status, req.TxHash, now, customerWallet, block.number, block.time, assessment.Reason, assessment.Score, assessment.Factors, req.OrderID, late)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		serverErr(w, err)
 		return
 	}
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
 		// Another process already updated the order, treat as already processed
 		_ = tx.Commit()
+		logSettledPaymentAttempt(req.OrderID, merchantID, req.TxHash, true)
 		writeJSON(w, http.StatusOK, paymentDetectedResp{
 			OrderID: req.OrderID,
 			Status:  status,
@@ -264,41 +562,52 @@ func PaymentDetectedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 3) insert two balanced ledger entries (double-entry)
-	//    a) merchant CREDIT  +amount
-	//    b) clearing DEBIT   -amount
-	// (Use order_id + event_type to make these rows easy to query.)
-	insertLedger := `
-		INSERT INTO ledger_entries
-		  (id, order_id, merchant_id, asset, amount_minor, bucket, direction, event_type, tx_hash, created_at)
-		VALUES
-		  (?,  ?,        ?,           ?,     ?,            ?,      ?,         ?,           ?,       ?)
-	`
-	// generate simple IDs (SQLite) — you can switch to UUIDs if you like
-	lid1 := "led_" + now + "_a"
-	lid2 := "led_" + now + "_b"
-
-	if _, err := tx.ExecContext(reqCtx, insertLedger,
-		lid1, req.OrderID, merchantID, asset, amountMinor, bucketMerchant, dirCredit, eventPaymentConfirmed, req.TxHash, now,
-	); err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+	if assessment.Status != "PAID" {
+		if err := enqueueOrderEvent(reqCtx, tx, webhookOrderInReview, req.OrderID); err != nil {
+			serverErr(w, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		log.Printf("event=payment_held order_id=%s merchant_id=%s tx_hash=%s reason=%q", req.OrderID, merchantID, req.TxHash, assessment.Reason.String)
+		logPaymentAttempt(req.OrderID, merchantID, req.TxHash, true, attemptSucceeded, "", "")
+		msg := "payment held for risk review"
+		if assessment.Status == statusLatePayment {
+			msg = "late payment held for review"
+		}
+		writeJSON(w, http.StatusAccepted, paymentDetectedResp{
+			OrderID: req.OrderID,
+			Status:  assessment.Status,
+			Message: msg,
+		})
+		return
+	}
+
+	// 3) insert balanced ledger entries (double-entry)
+	if err := writePaymentLedger(reqCtx, tx, req.OrderID, merchantID, asset, amountMinor, req.TxHash, now); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if err := recordCustomerPayment(reqCtx, tx, req.OrderID, now); err != nil {
+		serverErr(w, err)
 		return
 	}
-	if _, err := tx.ExecContext(reqCtx, insertLedger,
-		lid2, req.OrderID, merchantID, asset, amountMinor, bucketClearing, dirDebit, eventPaymentConfirmed, req.TxHash, now,
-	); err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+	if err := enqueueOrderEvent(reqCtx, tx, webhookOrderPaid, req.OrderID); err != nil {
+		serverErr(w, err)
 		return
 	}
 
 	// 4) commit
 	if err := tx.Commit(); err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
 		return
 	}
 
-	log.Printf("event=payment_detected order_id=%s merchant_id=%s asset=%s amount_minor=%d tx_hash=%s status=PAID", req.OrderID, merchantID, asset, amountMinor, req.TxHash)
-	atomic.AddInt64(&paymentsDetectedTotal, 1)
+	log.Printf("event=payment_detected order_id=%s merchant_id=%s asset=%s amount_minor=%s tx_hash=%s status=PAID", req.OrderID, merchantID, asset, amountMinor, req.TxHash)
+	logPaymentAttempt(req.OrderID, merchantID, req.TxHash, true, attemptSucceeded, "", "")
+	paymentsDetectedTotal.inc()
 	writeJSON(w, http.StatusOK, paymentDetectedResp{
 		OrderID: req.OrderID,
 		Status:  "PAID",
@@ -308,18 +617,49 @@ func PaymentDetectedHandler(w http.ResponseWriter, r *http.Request) {
 
 // DebugMetricsHandler godoc
 // @Summary      Get debug metrics
-// @Description  Returns in-memory metrics counters
+// @Description  Returns operational metrics. orders_created_total, payments_detected_total and refunds_processed_total are persisted: they survive restarts and add up across instances. The other counters are the instance's own since it started. db_busy_retries_total counts statements tried again because the database was locked by another writer, db_busy_errors_total those that failed locked all the same; db_wal_size_bytes is the size of the SQLite write-ahead log and db_pool_waits_total how often a query waited for a free connection.
 // @Tags         debug
 // @Produce      json
 // @Success      200  {object}  map[string]int64
 // @Router       /debug/metrics [get]
 func DebugMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]int64{
-		"orders_created_total":    ordersCreatedTotal,
-		"refunds_processed_total": refundsProcessedTotal,
-		"payments_detected_total": paymentsDetectedTotal,
-	})
+	_ = json.NewEncoder(w).Encode(debugMetrics(r.Context()))
+}
+
+// debugMetrics collects the counters and gauges of /debug/metrics.
+func debugMetrics(ctx context.Context) map[string]int64 {
+	metrics := counterValues(ctx)
+	jobsPending, jobsDead := jobBacklog(ctx)
+	contention := store.Contention()
+	for name, v := range map[string]int64{
+		"auth_banned_ips":                   authBannedIPs(),
+		"auth_bans_total":                   atomic.LoadInt64(&authBansTotal),
+		"auth_failures_total":               atomic.LoadInt64(&authFailTotal),
+		"db_busy_errors_total":              contention.BusyErrors,
+		"db_busy_retries_total":             contention.BusyRetries,
+		"db_pool_waits_total":               dbPoolWaits(),
+		"db_wal_size_bytes":                 dbWALSize(ctx),
+		"gas_tank_low_chains":               gasTankLowChains(),
+		"gas_tank_alerts_total":             atomic.LoadInt64(&gasTankAlertsTotal),
+		"jobs_dead":                         jobsDead,
+		"jobs_pending":                      jobsPending,
+		"merchant_wallet_changes_total":     atomic.LoadInt64(&ensWalletChangesTotal),
+		"negative_balances_open":            negativeBalancesOpen(ctx),
+		"order_cache_hits_total":            atomic.LoadInt64(&orderCacheHits),
+		"order_cache_misses_total":          atomic.LoadInt64(&orderCacheMisses),
+		"outbox_backlog":                    outboxBacklog(ctx),
+		"outbox_dead_letter":                outboxDeadLetters(ctx),
+		"reconciliation_drift_alerts_total": atomic.LoadInt64(&reconDriftAlertsTotal),
+		"reconciliation_drifting_assets":    reconDriftingAssets(),
+		"verification_queue_depth":          verifyQueueDepth(ctx),
+		"verification_queue_limit":          verifyQueueLimit,
+		"verification_rejected_total":       atomic.LoadInt64(&verifyRejectedTotal),
+		"webhook_dead_letter_alerts_total":  atomic.LoadInt64(&deadLetterAlertsTotal),
+	} {
+		metrics[name] = v
+	}
+	return metrics
 }
 
 // ReconciliationHandler godoc
@@ -330,72 +670,77 @@ func DebugMetricsHandler(w http.ResponseWriter, r *http.Request) {
 // @Param        merchant_id  query  string  true  "Merchant ID"
 // @Param        asset  query  string  true  "Asset symbol"
 // @Success      200  {object}  map[string]any
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /reconciliation [get]
 func ReconciliationHandler(w http.ResponseWriter, r *http.Request) {
 	merchantID := r.URL.Query().Get("merchant_id")
 	asset := r.URL.Query().Get("asset")
 	if merchantID == "" || asset == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "merchant_id and asset are required"})
+		writeProblem(w, http.StatusBadRequest, CodeMissingQueryParam, "merchant_id and asset are required")
 		return
 	}
 	if db == nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db not initialized"})
+		writeProblem(w, http.StatusInternalServerError, CodeDBNotInitialized, "")
+		return
+	}
+	if !authorizedFor(r.Context(), merchantID) {
+		writeProblem(w, http.StatusForbidden, CodeMerchantMismatch, "merchant_id does not match the authenticated merchant")
 		return
 	}
 	// Apply a short timeout for reconciliation queries
-	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpReconcile)
 	defer cancel()
 
-	var merchantBalance, clearingBalance, unsettledPaid int64
-	// Clearing balance
-	if err := db.QueryRowContext(ctx, `
-		SELECT COALESCE(SUM(CASE WHEN direction='credit' THEN amount_minor ELSE -amount_minor END),0)
-		FROM ledger_entries
-		WHERE merchant_id = ? AND asset = ? AND bucket = 'clearing'
-	`, merchantID, asset).Scan(&clearingBalance); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-	// Merchant balance
-	if err := db.QueryRowContext(ctx, `
-		SELECT COALESCE(SUM(CASE WHEN direction='credit' THEN amount_minor ELSE -amount_minor END),0)
-		FROM ledger_entries
-		WHERE merchant_id = ? AND asset = ? AND bucket = 'merchant'
-	`, merchantID, asset).Scan(&merchantBalance); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
+	var merchantBalance, settlementBalance, clearingBalance, heldBalance, unsettledPaid int64
+	for _, b := range []struct {
+		bucket string
+		out    *int64
+	}{
+		{bucketClearing, &clearingBalance},
+		{bucketMerchant, &merchantBalance},
+		{bucketSettlement, &settlementBalance}, // settled, not yet paid out
+		{bucketDisputeHold, &heldBalance},      // funds frozen by open disputes
+	} {
+		balance, err := reportStores.Ledger.Balance(ctx, merchantID, asset, b.bucket)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		*b.out = balance
 	}
 	// Unsettled PAID orders count
-	if err := db.QueryRowContext(ctx, `
+	if err := reportDB.QueryRowContext(ctx, `
 		SELECT COALESCE(COUNT(1),0)
 		FROM orders
-		WHERE merchant_id = ? AND asset = ? AND status = 'PAID'
+		WHERE merchant_id = ? AND asset = ? AND status IN ('PAID','PARTIALLY_REFUNDED')
 	`, merchantID, asset).Scan(&unsettledPaid); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		serverErr(w, err)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"merchant_id":            merchantID,
-		"asset":                  asset,
-		"merchant_balance_minor": merchantBalance,
-		"clearing_balance_minor": clearingBalance,
-		"unsettled_paid_count":   unsettledPaid,
+		"merchant_id":              merchantID,
+		"asset":                    asset,
+		"merchant_balance_minor":   merchantBalance,
+		"settlement_balance_minor": settlementBalance,
+		"clearing_balance_minor":   clearingBalance,
+		"held_balance_minor":       heldBalance,
+		"unsettled_paid_count":     unsettledPaid,
 	})
 }
 
 // processVerificationJob verifies the tx on-chain and updates the DB/ledger similar to the inline path.
-func processVerificationJob(job verifyJob) {
+// It returns an error for failures worth retrying, and a permanent one for jobs that cannot succeed;
+// on the last attempt a transfer that still fails to verify is reported as verification.failed.
+func processVerificationJob(job verifyJob, lastAttempt bool) error {
 	log.Printf("processing verification job: order=%s tx=%s merchant=%s", job.OrderID, job.TxHash, job.MerchantID)
 
 	// Defensive context timeout per job
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if db == nil {
-		log.Printf("db is nil for job %s", job.OrderID)
-		return
+		return errors.New("db not initialized")
 	}
 
 	// Load order basics
@@ -406,147 +751,520 @@ func processVerificationJob(job verifyJob) {
 		chain       string
 		status      string
 	)
-	if err := db.QueryRowContext(ctx, `SELECT merchant_id, amount_minor, asset, chain, status FROM orders WHERE id = ?`, job.OrderID).Scan(&merchantID, &amountMinor, &asset, &chain, &status); err != nil {
-		log.Printf("failed to load order %s: %v", job.OrderID, err)
-		return
+	if err := db.QueryRowContext(ctx, `SELECT merchant_id, amount_minor, asset, chain, status FROM orders WHERE id = ? AND deleted_at IS NULL`, job.OrderID).Scan(&merchantID, &amountMinor, &asset, &chain, &status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return jobs.Permanent(fmt.Errorf("order %s not found", job.OrderID))
+		}
+		return fmt.Errorf("load order %s: %w", job.OrderID, err)
 	}
 	log.Printf("Processing verification for order %s: asset=%s, chain=%s, amount=%s", job.OrderID, asset, chain, amountMinor)
 
+	// Another transfer reported for an order already paid is credited as an overpayment
+	if overpaidStatuses[status] {
+		ovp, err := creditDuplicatePayment(ctx, job.OrderID, job.TxHash, false)
+		if err != nil && !lastAttempt && !mismatchedTransfer(err) {
+			return fmt.Errorf("check duplicate payment: %w", err)
+		}
+		if err != nil {
+			logPaymentAttempt(job.OrderID, merchantID, job.TxHash, false, attemptFailed, verificationFailureReason(err), err.Error())
+		} else if ovp == nil {
+			logSettledPaymentAttempt(job.OrderID, merchantID, job.TxHash, false)
+		}
+		return nil
+	}
 	// Already processed?
-	if status == "PAID" || status == "SETTLED" || status == "REFUNDED" {
+	if status == "PAID" || status == "SETTLED" || status == "REFUNDED" || status == "PARTIALLY_REFUNDED" || status == statusReview || status == statusLatePayment || status == statusConfirming {
 		log.Printf("order %s already processed with status %s", job.OrderID, status)
-		return
+		logSettledPaymentAttempt(job.OrderID, merchantID, job.TxHash, false)
+		return nil
 	}
 	// Merchant wallet
 	var merchantWalletAddress string
-	if err := db.QueryRowContext(ctx, `SELECT merchant_wallet_address FROM merchants WHERE id = ?`, merchantID).Scan(&merchantWalletAddress); err != nil || merchantWalletAddress == "" {
-		return
+	if err := db.QueryRowContext(ctx, `SELECT merchant_wallet_address FROM merchants WHERE id = ?`, merchantID).Scan(&merchantWalletAddress); err != nil {
+		return fmt.Errorf("load merchant wallet: %w", err)
+	}
+	if merchantWalletAddress == "" {
+		return jobs.Permanent(errors.New("merchant has no wallet address"))
 	}
 	// On-chain verify (only for BSC-USD on BSC chain)
+	var customerWallet sql.NullString
+	var block paymentBlock
 	if strings.ToUpper(asset) == "USDT" && strings.ToUpper(chain) == "BSC" {
 		log.Printf("Starting BSC-USD verification for order %s, tx %s", job.OrderID, job.TxHash)
 		verifySem <- struct{}{}
 		// amount_minor is stored as string for 18 decimals (wei-style), parse to big.Int
 		expected, ok := new(big.Int).SetString(amountMinor, 10)
 		if !ok {
-			log.Printf("invalid amount format for order %s: %s", job.OrderID, amountMinor)
 			<-verifySem
-			return
+			return jobs.Permanent(fmt.Errorf("invalid amount format for order %s: %s", job.OrderID, amountMinor))
 		}
 
 		log.Printf("BSC verification: using amount %s (18-decimal) directly", amountMinor)
 
-		ok, err := blockchain.VerifyBSCUSDTransfer(job.TxHash, merchantWalletAddress, expected)
+		transfer, ok, err := blockchain.VerifyBSCUSDTransfer(job.TxHash, merchantWalletAddress, expected)
 		<-verifySem
+		if err != nil {
+			if m, ferr := flagMispayment(ctx, job.OrderID, merchantID, chain, asset, merchantWalletAddress, job.TxHash, err, false); ferr != nil {
+				log.Printf("mispayment check order=%s tx=%s: %v", job.OrderID, job.TxHash, ferr)
+			} else if m != nil {
+				return nil
+			}
+		}
+		if err != nil && !lastAttempt && !mismatchedTransfer(err) {
+			// The RPC node failed or the transaction is not indexed yet: try again
+			return fmt.Errorf("verify transfer: %w", err)
+		}
 		if err != nil || !ok {
 			log.Printf("verification failed for order=%s tx=%s err=%v ok=%v", job.OrderID, job.TxHash, err, ok)
-			return
+			reason := "transfer to the merchant wallet for the expected amount not found"
+			if err != nil {
+				reason = err.Error()
+			}
+			logPaymentAttempt(job.OrderID, merchantID, job.TxHash, false, attemptFailed, verificationFailureReason(err), reason)
+			if err := enqueueEvent(ctx, db, merchantID, "order", job.OrderID, webhookVerificationFailed,
+				verificationFailedData{OrderID: job.OrderID, TxHash: job.TxHash, Reason: reason}); err != nil {
+				return fmt.Errorf("enqueue verification.failed: %w", err)
+			}
+			return nil
 		}
+		customerWallet = sql.NullString{String: transfer.From, Valid: true}
+		block = blockOf(transfer)
 		log.Printf("BSC verification passed for order %s", job.OrderID)
 	} else if strings.ToUpper(asset) == "USDT" {
 		log.Printf("Skipping blockchain verification for USDT on %s chain (order %s) - auto-approving for testing", chain, job.OrderID)
 	} else {
 		log.Printf("Skipping blockchain verification for %s asset (order %s) - auto-approving for testing", asset, job.OrderID)
 	}
+	// A transfer credited as another order's overpayment cannot pay this one
+	if ovpID, err := overpaymentOf(ctx, db, job.TxHash); err != nil {
+		return fmt.Errorf("check overpayments: %w", err)
+	} else if ovpID != "" {
+		logPaymentAttempt(job.OrderID, merchantID, job.TxHash, false, attemptFailed, attemptTxAlreadyUsed, "credited as overpayment "+ovpID)
+		return nil
+	}
 
+	// The sender is screened before the transaction is opened; a payment that still has to reach
+	// finality is screened when the confirmation tracker credits it.
+	var screening payerScreening
+	if !awaitFinality(chain, block) {
+		screening = screenPayer(ctx, job.OrderID, chain, customerWallet.String)
+	}
 	now := time.Now().UTC().Format(time.RFC3339)
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return
+		return err
 	}
 	defer func() { _ = tx.Rollback() }()
+	late := status == statusExpired
+	var holdLate bool
+	if late {
+		allowed, review, err := checkLatePayment(ctx, tx, job.OrderID)
+		if err != nil {
+			return fmt.Errorf("check late payment: %w", err)
+		}
+		if !allowed {
+			log.Printf("event=late_payment_rejected order_id=%s merchant_id=%s tx_hash=%s", job.OrderID, merchantID, job.TxHash)
+			logPaymentAttempt(job.OrderID, merchantID, job.TxHash, false, attemptFailed, attemptOrderExpired, "")
+			return nil
+		}
+		holdLate = review
+	}
+	if awaitFinality(chain, block) {
+		if marked, err := markConfirming(ctx, tx, job.OrderID, job.TxHash, customerWallet, block, late); err != nil || !marked {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("event=payment_confirming order_id=%s merchant_id=%s tx_hash=%s block=%d", job.OrderID, merchantID, job.TxHash, block.number.Int64)
+		logPaymentAttempt(job.OrderID, merchantID, job.TxHash, false, attemptSucceeded, "", "")
+		return nil
+	}
+	assessment := assessPayment(ctx, tx, screening, job.OrderID, merchantID, asset, chain, amountMinor, customerWallet.String)
+	if holdLate {
+		holdLatePayment(&assessment)
+	}
 	// Guarded update
-	res, err := tx.ExecContext(ctx, `UPDATE orders SET status=?, tx_hash=?, paid_at=? WHERE id=? AND (status='PENDING' OR status='CONFIRMING')`, "PAID", job.TxHash, now, job.OrderID)
+	res, err := tx.ExecContext(ctx, `UPDATE orders SET status=?, tx_hash=?, paid_at=?, customer_wallet_address=COALESCE(?, customer_wallet_address), confirmed_block=?, block_timestamp=?, risk_reason=?, risk_score=?, risk_factors=? WHERE id=? AND (status IN ('PENDING', 'MISPAID') OR (status='EXPIRED' AND ?))`,
+		assessment.Status, job.TxHash, now, customerWallet, block.number, block.time, assessment.Reason, assessment.Score, assessment.Factors, job.OrderID, late)
 	if err != nil {
-		return
+		return err
 	}
 	if rows, _ := res.RowsAffected(); rows == 0 {
-		_ = tx.Commit()
-		return
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		logSettledPaymentAttempt(job.OrderID, merchantID, job.TxHash, false)
+		return nil
+	}
+	if assessment.Status != "PAID" {
+		if err := enqueueOrderEvent(ctx, tx, webhookOrderInReview, job.OrderID); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("event=payment_held order_id=%s merchant_id=%s tx_hash=%s reason=%q", job.OrderID, merchantID, job.TxHash, assessment.Reason.String)
+		logPaymentAttempt(job.OrderID, merchantID, job.TxHash, false, attemptSucceeded, "", "")
+		return nil
 	}
 
-	insertLedger := `INSERT INTO ledger_entries (id, order_id, merchant_id, asset, amount_minor, bucket, direction, event_type, tx_hash, created_at) VALUES (?,?,?,?,?,?,?,?,?,?)`
-	lid1 := "led_" + now + "_a"
-	lid2 := "led_" + now + "_b"
-	if _, err := tx.ExecContext(ctx, insertLedger, lid1, job.OrderID, merchantID, asset, amountMinor, bucketMerchant, dirCredit, eventPaymentConfirmed, job.TxHash, now); err != nil {
-		return
+	if err := writePaymentLedger(ctx, tx, job.OrderID, merchantID, asset, amountMinor, job.TxHash, now); err != nil {
+		return err
 	}
-	if _, err := tx.ExecContext(ctx, insertLedger, lid2, job.OrderID, merchantID, asset, amountMinor, bucketClearing, dirDebit, eventPaymentConfirmed, job.TxHash, now); err != nil {
-		return
+	if err := recordCustomerPayment(ctx, tx, job.OrderID, now); err != nil {
+		return err
+	}
+	if err := enqueueOrderEvent(ctx, tx, webhookOrderPaid, job.OrderID); err != nil {
+		return err
 	}
 	if err := tx.Commit(); err != nil {
-		return
+		return err
 	}
 
 	recentTxMu.Lock()
 	recentTx[strings.ToLower(job.TxHash)] = time.Now()
 	recentTxMu.Unlock()
-	atomic.AddInt64(&paymentsDetectedTotal, 1)
+	logPaymentAttempt(job.OrderID, merchantID, job.TxHash, false, attemptSucceeded, "", "")
+	paymentsDetectedTotal.inc()
+	return nil
 }
 
 // StartSettlementScheduler runs a background goroutine to settle PAID orders after a delay.
+// Each merchant/asset pair gets its own settlement batch, so connected accounts are paid out individually.
 func StartSettlementScheduler(db *sql.DB, delay time.Duration, interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			<-ticker.C
-			now := time.Now().UTC()
-			cutoff := now.Add(-delay).Format(time.RFC3339)
-			rows, err := db.Query(`SELECT id FROM orders WHERE status='PAID' AND paid_at <= ?`, cutoff)
-			if err != nil {
-				continue
-			}
-			for rows.Next() {
-				var orderID string
-				if err := rows.Scan(&orderID); err == nil {
-					_, err := db.Exec(`UPDATE orders SET status='SETTLED' WHERE id=?`, orderID)
-					if err != nil {
-						continue
-					}
-				}
-			}
-			rows.Close()
+	startScheduler(schedulerSettlement, interval, false, func(ctx context.Context) (int, error) {
+		cutoff := time.Now().UTC().Add(-delay).Format(time.RFC3339)
+		batches, err := settleDue(db, cutoff, "", true)
+		orders := 0
+		for _, b := range batches {
+			orders += b.Orders
 		}
-	}()
+		return orders, err
+	})
 }
 
-// StartOrderTimeoutScheduler runs a background goroutine to mark PENDING orders as FAILED after timeout.
-func StartOrderTimeoutScheduler(db *sql.DB, timeout time.Duration, interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			<-ticker.C
-			now := time.Now().UTC()
-			cutoff := now.Add(-timeout).Format(time.RFC3339)
-
-			// Find PENDING orders older than the timeout
-			rows, err := db.Query(`SELECT id FROM orders WHERE status='PENDING' AND created_at <= ?`, cutoff)
-			if err != nil {
-				log.Printf("failed to query expired orders: %v", err)
+type settlementBatch struct {
+	BatchID    string `json:"batch_id"`
+	MerchantID string `json:"merchant_id"`
+	Asset      string `json:"asset"`
+	Orders     int    `json:"orders"`
+	// TotalAmountMinor is the net payout: the gross volume of the orders less the application fees
+	// withheld, the refunds and the lost disputes netted.
+	TotalAmountMinor string `json:"total_amount_minor"`
+	settlementItems
+}
+
+// settlementItems itemizes how a batch's net payout was arrived at. Batches settled before
+// itemization was stored have none.
+type settlementItems struct {
+	GrossAmountMinor  string `json:"gross_amount_minor,omitempty"`  // amounts of the batch's orders
+	FeesMinor         string `json:"fees_minor,omitempty"`          // application fees withheld
+	RefundsMinor      string `json:"refunds_minor,omitempty"`       // completed refunds netted
+	DisputesLostMinor string `json:"disputes_lost_minor,omitempty"` // lost disputes netted
+	ClawbackMinor     string `json:"clawback_minor,omitempty"`      // withheld against a negative balance
+	HeldMinor         string `json:"held_minor,omitempty"`          // withheld for balance holds
+	HoldReleasedMinor string `json:"hold_released_minor,omitempty"` // withheld funds of a released hold
+}
+
+// settleDue settles the orders paid at or before cutoff, for every merchant or only merchantID.
+// With calendar, merchants with a timezone settle by local day instead (see settlementCutoff).
+// Deleted merchants are not settled; their funds wait on the ledger until they are restored.
+// A failing merchant/asset pair is logged and skipped; the error of the last failure is returned.
+func settleDue(db *sql.DB, cutoff, merchantID string, calendar bool) ([]settlementBatch, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT merchant_id, asset FROM orders
+		WHERE status IN ('PAID','PARTIALLY_REFUNDED') AND paid_at <= ? AND settlement_batch_id IS NULL AND (? = '' OR merchant_id = ?)
+		  AND merchant_id NOT IN (SELECT id FROM merchants WHERE deleted_at IS NOT NULL)
+	`, cutoff, merchantID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	type merchantAsset struct{ merchantID, asset string }
+	var groups []merchantAsset
+	for rows.Next() {
+		var g merchantAsset
+		if err := rows.Scan(&g.merchantID, &g.asset); err == nil {
+			groups = append(groups, g)
+		}
+	}
+	rows.Close()
+	batches := []settlementBatch{}
+	var lastErr error
+	for _, g := range groups {
+		groupCutoff := cutoff
+		if calendar {
+			if groupCutoff, err = settlementCutoff(context.Background(), db, g.merchantID, cutoff); err != nil {
+				log.Printf("settlement failed merchant_id=%s asset=%s: %v", g.merchantID, g.asset, err)
+				lastErr = err
 				continue
 			}
+		}
+		b, err := settleMerchantOrders(db, g.merchantID, g.asset, groupCutoff)
+		if err != nil {
+			log.Printf("settlement failed merchant_id=%s asset=%s: %v", g.merchantID, g.asset, err)
+			lastErr = err
+			continue
+		}
+		if b != nil {
+			batches = append(batches, *b)
+		}
+	}
+	return batches, lastErr
+}
 
-			var expiredCount int
-			for rows.Next() {
-				var orderID string
-				if err := rows.Scan(&orderID); err == nil {
-					// Mark as FAILED
-					_, err := db.Exec(`UPDATE orders SET status='FAILED' WHERE id=? AND status='PENDING'`, orderID)
-					if err != nil {
-						log.Printf("failed to mark order %s as FAILED: %v", orderID, err)
-						continue
-					}
-					expiredCount++
-					log.Printf("marked order %s as FAILED due to 30-minute timeout", orderID)
-				}
-			}
+// settleMerchantOrders moves one merchant's PAID (or partially refunded) orders for asset into a new settlement batch.
+// The batch total is the merchant's net payout: order amounts minus platform application fees, refunds and lost
+// disputes, each stored on the batch. It is moved from the merchant bucket to the settlement bucket in the ledger.
+func settleMerchantOrders(db *sql.DB, merchantID, asset, cutoff string) (*settlementBatch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, chain, amount_minor, COALESCE(application_fee_minor, '0')
+		FROM orders
+		WHERE merchant_id = ? AND asset = ? AND status IN ('PAID','PARTIALLY_REFUNDED') AND paid_at <= ? AND settlement_batch_id IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM refunds WHERE refunds.order_id = orders.id AND refunds.status = 'REQUESTED')
+		  AND NOT EXISTS (SELECT 1 FROM disputes WHERE disputes.order_id = orders.id AND disputes.status = 'OPEN')
+	`, merchantID, asset, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	var orderIDs []string
+	total := new(big.Int)
+	gross, fees, refunds, disputesLost := new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	orderChain := map[string]string{}
+	byChain := map[string]*big.Int{} // net per chain, for on-chain payouts
+	for rows.Next() {
+		var id, chain, amountMinor, feeMinor string
+		if err := rows.Scan(&id, &chain, &amountMinor, &feeMinor); err != nil {
 			rows.Close()
+			return nil, err
+		}
+		amount, ok1 := new(big.Int).SetString(amountMinor, 10)
+		fee, ok2 := new(big.Int).SetString(feeMinor, 10)
+		if !ok1 || !ok2 {
+			log.Printf("settlement: skipping order %s with invalid amounts", id)
+			continue
+		}
+		gross.Add(gross, amount)
+		fees.Add(fees, fee)
+		net := amount.Sub(amount, fee)
+		total.Add(total, net)
+		chain = strings.ToUpper(chain)
+		if byChain[chain] == nil {
+			byChain[chain] = new(big.Int)
+		}
+		byChain[chain].Add(byChain[chain], net)
+		orderChain[id] = chain
+		orderIDs = append(orderIDs, id)
+	}
+	rows.Close()
+	for _, id := range orderIDs {
+		refunded, err := refundsTotal(ctx, tx, id, refundStatusCompleted)
+		if err != nil {
+			return nil, err
+		}
+		lost, err := lostDisputesTotal(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		refunds.Add(refunds, refunded)
+		disputesLost.Add(disputesLost, lost)
+		total.Sub(total, refunded)
+		total.Sub(total, lost)
+		byChain[orderChain[id]].Sub(byChain[orderChain[id]], refunded)
+		byChain[orderChain[id]].Sub(byChain[orderChain[id]], lost)
+	}
+	if len(orderIDs) == 0 {
+		return nil, nil
+	}
+	held, err := withholdHolds(ctx, tx, merchantID, asset, byChain)
+	if err != nil {
+		return nil, err
+	}
+	total.Sub(total, held)
+	clawback, err := withholdClawback(ctx, tx, merchantID, asset, byChain)
+	if err != nil {
+		return nil, err
+	}
+	total.Sub(total, clawback)
 
-			if expiredCount > 0 {
-				log.Printf("marked %d orders as FAILED due to timeout", expiredCount)
+	now := time.Now().UTC().Format(time.RFC3339)
+	batchID := "batch_" + uuid.New().String()
+	items := settlementItems{
+		GrossAmountMinor: gross.String(), FeesMinor: fees.String(), RefundsMinor: refunds.String(), DisputesLostMinor: disputesLost.String(),
+		ClawbackMinor: clawback.String(), HeldMinor: held.String(),
+	}
+	if err := insertSettlementBatch(ctx, tx, batchID, merchantID, asset, total, items, now); err != nil {
+		return nil, err
+	}
+	for _, id := range orderIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE orders SET status='SETTLED', settlement_batch_id=? WHERE id=? AND status IN ('PAID','PARTIALLY_REFUNDED')`, batchID, id); err != nil {
+			return nil, err
+		}
+		if err := enqueueOrderEvent(ctx, tx, webhookOrderSettled, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeSettlementLedger(ctx, tx, batchID, merchantID, asset, byChain, now); err != nil {
+		return nil, err
+	}
+	if err := queuePayouts(ctx, tx, batchID, merchantID, asset, byChain); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("event=settlement_executed batch_id=%s merchant_id=%s asset=%s orders=%d gross_amount_minor=%s fees_minor=%s refunds_minor=%s disputes_lost_minor=%s held_minor=%s clawback_minor=%s total_amount_minor=%s",
+		batchID, merchantID, asset, len(orderIDs), gross.String(), fees.String(), refunds.String(), disputesLost.String(), held.String(), clawback.String(), total.String())
+	return &settlementBatch{
+		BatchID: batchID, MerchantID: merchantID, Asset: asset, Orders: len(orderIDs), TotalAmountMinor: total.String(), settlementItems: items,
+	}, nil
+}
+
+// insertSettlementBatch records an executed settlement batch paying out total, itemized by items.
+func insertSettlementBatch(ctx context.Context, tx *sql.Tx, batchID, merchantID, asset string, total *big.Int, items settlementItems, now string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO settlement_batches
+		  (id, merchant_id, asset, scheduled_for, status, total_amount_minor, gross_amount_minor, fees_minor, refunds_minor,
+		   disputes_lost_minor, clawback_minor, held_minor, hold_released_minor, created_at, executed_at)
+		VALUES (?, ?, ?, ?, 'EXECUTED', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, batchID, merchantID, asset, now, total.String(), optionalString(items.GrossAmountMinor), optionalString(items.FeesMinor), optionalString(items.RefundsMinor),
+		optionalString(items.DisputesLostMinor), optionalString(items.ClawbackMinor), optionalString(items.HeldMinor), optionalString(items.HoldReleasedMinor), now, now)
+	return err
+}
+
+// writeSettlementLedger books a batch's SETTLEMENT double entry for each chain: merchant DEBIT,
+// settlement CREDIT of the batch's net on the chain, moving it out of the unsettled balance.
+func writeSettlementLedger(ctx context.Context, tx *sql.Tx, batchID, merchantID, asset string, byChain map[string]*big.Int, now string) error {
+	chains := make([]string, 0, len(byChain))
+	for chain := range byChain {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	var entries []store.LedgerEntry
+	for _, chain := range chains {
+		amount := byChain[chain]
+		if amount.Sign() == 0 {
+			continue
+		}
+		from, to := bucketMerchant, bucketSettlement
+		if amount.Sign() < 0 {
+			from, to = to, from
+		}
+		entry := func(bucket, direction string) store.LedgerEntry {
+			return store.LedgerEntry{
+				MerchantID: merchantID, Asset: asset, Chain: chain,
+				AmountMinor: new(big.Int).Abs(amount).String(), Bucket: bucket, Direction: direction, EventType: eventSettlement, ReferenceID: batchID, CreatedAt: now,
 			}
 		}
-	}()
+		entries = append(entries, entry(from, dirDebit), entry(to, dirCredit))
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return txStores(tx).Ledger.Append(ctx, entries...)
+}
+
+// RunSettlementHandler godoc
+// @Summary      Settle paid orders now
+// @Description  Settles every PAID or PARTIALLY_REFUNDED order immediately instead of waiting for the scheduler's delay, optionally for one merchant. Orders with pending refunds or open disputes are skipped as usual. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        merchant_id  query  string  false  "Only settle this merchant"
+// @Success      200  {array}   settlementBatch
+// @Failure      500  {object}  Problem
+// @Router       /admin/settlements/run [post]
+func RunSettlementHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	merchantID := r.URL.Query().Get("merchant_id")
+	batches, err := settleDue(db, time.Now().UTC().Format(time.RFC3339), merchantID, false)
+	if err != nil && len(batches) == 0 {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), merchantID, "", "settlement_run", map[string]any{"batches": len(batches)})
+	writeJSON(w, http.StatusOK, batches)
+}
+
+// StartOrderTimeoutScheduler runs a background goroutine to mark PENDING orders as EXPIRED once
+// their expires_at has passed. Orders without expires_at expire timeout after creation.
+func StartOrderTimeoutScheduler(db *sql.DB, timeout time.Duration, interval time.Duration) {
+	startScheduler(schedulerOrderTimeout, interval, false, func(ctx context.Context) (int, error) {
+		return expireDueOrders(db, timeout)
+	})
+}
+
+// expireDueOrders expires the PENDING orders past their expiry and reports how many it expired.
+// A failing order is logged and skipped; the error of the last failure is returned.
+func expireDueOrders(db *sql.DB, timeout time.Duration) (int, error) {
+	now := time.Now().UTC()
+	cutoff := now.Add(-timeout).Format(time.RFC3339)
+
+	// Find PENDING orders past their expiry
+	rows, err := db.Query(`
+		SELECT id FROM orders
+		WHERE status='PENDING' AND (expires_at <= ? OR (expires_at IS NULL AND created_at <= ?)) AND deleted_at IS NULL
+	`, now.Format(time.RFC3339), cutoff)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err == nil {
+			ids = append(ids, orderID)
+		}
+	}
+	rows.Close()
+
+	var expiredCount int
+	var lastErr error
+	for _, orderID := range ids {
+		if err := expireOrder(db, orderID); err != nil {
+			log.Printf("failed to expire order %s: %v", orderID, err)
+			lastErr = err
+			continue
+		}
+		expiredCount++
+		log.Printf("marked order %s as EXPIRED due to timeout", orderID)
+	}
+	if expiredCount > 0 {
+		log.Printf("marked %d orders as EXPIRED due to timeout", expiredCount)
+	}
+	return expiredCount, lastErr
+}
+
+// expireOrder marks a PENDING order that was never paid EXPIRED and enqueues order.expired. An
+// order extended since it was picked up is left alone.
+func expireOrder(db *sql.DB, orderID string) error {
+	ctx, cancel := store.WithTimeout(context.Background(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `UPDATE orders SET status='EXPIRED', expired_at=? WHERE id=? AND status='PENDING' AND (expires_at IS NULL OR expires_at <= ?)`,
+		now, orderID, now)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := enqueueOrderEvent(ctx, tx, webhookOrderExpired, orderID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
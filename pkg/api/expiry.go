@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// OrderTTL is how long a new order waits for payment before the timeout scheduler expires it.
+const OrderTTL = 30 * time.Minute
+
+// maxOrderLifetime bounds extensions: an order never stays payable longer than this after creation.
+const maxOrderLifetime = 24 * time.Hour
+
+type orderExtendReq struct {
+	Minutes int `json:"minutes,omitempty"` // added to the later of now and the current expiry; defaults to 30
+}
+
+type orderExtendResp struct {
+	OrderID   string `json:"order_id"`
+	Status    string `json:"status"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// ExtendOrderHandler godoc
+// @Summary      Extend a pending order
+// @Description  Pushes out expires_at of a PENDING order by minutes (default 30), counted from now if that is later than the current expiry, so a checkout stays open while the customer is still paying. An order cannot be extended past 24 hours after it was created.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id       query  string          true   "Order ID"
+// @Param        request  body   orderExtendReq  false  "Extension"
+// @Success      200  {object}  orderExtendResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/extend [post]
+// @Router       /admin/orders/extend [post]
+func ExtendOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	orderID := pathID(r)
+	if orderID == "" {
+		badReq(w, "missing query param: id")
+		return
+	}
+	var req orderExtendReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Minutes == 0 {
+		req.Minutes = 30
+	}
+	if req.Minutes < 0 || time.Duration(req.Minutes)*time.Minute > maxOrderLifetime {
+		badReq(w, "minutes must be between 1 and 1440")
+		return
+	}
+
+	ctx := r.Context()
+	o, err := stores.Orders.Get(ctx, orderID, merchantIDFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
+			return
+		}
+		serverErr(w, err)
+		return
+	}
+	if o.Status != "PENDING" {
+		writeProblem(w, http.StatusConflict, CodeOrderNotPending, "only PENDING orders can be extended; order is "+o.Status)
+		return
+	}
+	created, err := time.Parse(time.RFC3339, o.CreatedAt)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	now := time.Now().UTC()
+	expires := created.Add(OrderTTL)
+	if o.ExpiresAt != nil {
+		if t, err := time.Parse(time.RFC3339, *o.ExpiresAt); err == nil {
+			expires = t
+		}
+	}
+	if now.After(expires) {
+		expires = now
+	}
+	expires = expires.Add(time.Duration(req.Minutes) * time.Minute)
+	if limit := created.Add(maxOrderLifetime); expires.After(limit) {
+		writeProblem(w, http.StatusUnprocessableEntity, CodeExtensionLimitExceeded,
+			"orders cannot stay open past "+limit.Format(time.RFC3339)+", 24 hours after creation")
+		return
+	}
+
+	newExpiry := expires.Format(time.RFC3339)
+	invalidateOrder(orderID)
+	res, err := db.ExecContext(ctx, `UPDATE orders SET expires_at = ? WHERE id = ? AND status = 'PENDING'`, newExpiry, orderID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Paid or expired since we read it
+		writeProblem(w, http.StatusConflict, CodeOrderNotPending, "order is no longer PENDING")
+		return
+	}
+	previous := ""
+	if o.ExpiresAt != nil {
+		previous = *o.ExpiresAt
+	}
+	recordAudit(ctx, db, actorFromContext(ctx), o.MerchantID, orderID, "order_extended", map[string]string{"from": previous, "to": newExpiry})
+	writeJSONOrders(w, http.StatusOK, orderExtendResp{OrderID: orderID, Status: o.Status, ExpiresAt: newExpiry})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// rateQuoteTTL is how long after it was served a rate quote may still price an order.
+const rateQuoteTTL = 15 * time.Minute
+
+var fiatAmountRe = regexp.MustCompile(`^[0-9]{1,30}(\.[0-9]{1,18})?$`)
+
+// fiatPricing is how a fiat-priced order was converted: its price in fiat and the rate quote the
+// merchant converted it with, as served.
+type fiatPricing struct {
+	FiatAmount   string   `json:"fiat_amount"` // price in fiat_currency, as a decimal
+	FiatCurrency string   `json:"fiat_currency"`
+	RateQuote    rateResp `json:"rate_quote"`
+}
+
+// rateQuoteError rejects an order whose rate_quote_id cannot price it.
+type rateQuoteError struct{ Msg string }
+
+func (e *rateQuoteError) Error() string { return e.Msg }
+
+// priceOrder looks up the rate quote a merchant priced an order of asset in fiatAmount with. The
+// quote must have been served to the merchant (or to an admin) within rateQuoteTTL, and be between
+// asset and another currency, the fiat one.
+func priceOrder(ctx context.Context, q queryer, merchantID, asset, quoteID, fiatAmount string) (*fiatPricing, error) {
+	if quoteID == "" && fiatAmount == "" {
+		return nil, nil
+	}
+	if quoteID == "" || fiatAmount == "" {
+		return nil, &rateQuoteError{"rate_quote_id and fiat_amount must be given together"}
+	}
+	if !fiatAmountRe.MatchString(fiatAmount) {
+		return nil, &rateQuoteError{"fiat_amount must be a decimal number, e.g. 25.00"}
+	}
+	rq, err := scanRateQuote(q.QueryRowContext(ctx, `
+		SELECT `+rateQuoteCols+` FROM rate_quotes WHERE id = ? AND (merchant_id IS NULL OR merchant_id = ?)
+	`, quoteID, merchantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &rateQuoteError{"rate quote " + quoteID + " not found"}
+	} else if err != nil {
+		return nil, err
+	}
+	if quotedAt, err := time.Parse(time.RFC3339, rq.QuotedAt); err != nil || time.Since(quotedAt) > rateQuoteTTL {
+		return nil, &rateQuoteError{"rate quote " + quoteID + " is older than " + rateQuoteTTL.String() + "; get a new one from /rates"}
+	}
+	p := &fiatPricing{FiatAmount: fiatAmount, RateQuote: rq}
+	switch asset = strings.ToUpper(asset); asset {
+	case rq.Base:
+		p.FiatCurrency = rq.Quote
+	case rq.Quote:
+		p.FiatCurrency = rq.Base
+	default:
+		return nil, &rateQuoteError{"rate quote " + quoteID + " is for " + rq.Base + "/" + rq.Quote + ", not " + asset}
+	}
+	return p, nil
+}
+
+// fiatPricingJSON encodes p for orders.fiat_pricing_json.
+func fiatPricingJSON(p *fiatPricing) (*string, error) {
+	if p == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	return &s, nil
+}
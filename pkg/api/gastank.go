@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// runwayWindow is the payout history the gas tank runway is projected from.
+const runwayWindow = 7 * 24 * time.Hour
+
+// The gas tank is the hot wallet that pays gas for payouts and on-chain refunds. The same EVM
+// address is watched on every chain with an RPC endpoint.
+var (
+	gasTankMu       sync.Mutex
+	gasTankWallet   string
+	gasTankLowWater = map[string]*big.Int{} // chain -> low-water mark in wei
+	gasTankAlertURL string
+	gasTankState    = map[string]*gasTankReading{}
+
+	gasTankAlertsTotal int64
+)
+
+// gasTankReading is the last balance seen for a chain. Low is set while the balance is below the
+// chain's low-water mark, so an alert fires once per drop rather than on every check.
+type gasTankReading struct {
+	balance   *big.Int
+	checkedAt time.Time
+	err       string
+	low       bool
+}
+
+// SetGasTank configures the hot wallet to watch, the per-chain low-water marks in wei and the URL
+// that low-balance alerts are POSTed to. An empty wallet turns monitoring off.
+func SetGasTank(wallet string, lowWater map[string]*big.Int, alertURL string) {
+	gasTankMu.Lock()
+	defer gasTankMu.Unlock()
+	gasTankWallet = wallet
+	gasTankLowWater = lowWater
+	gasTankAlertURL = alertURL
+}
+
+// StartGasTankMonitor checks the hot wallet's native balance on every chain now and then every
+// interval, alerting when a chain drops below its low-water mark.
+func StartGasTankMonitor(interval time.Duration) {
+	gasTankMu.Lock()
+	wallet := gasTankWallet
+	gasTankMu.Unlock()
+	if wallet == "" {
+		return
+	}
+	startScheduler(schedulerGasTank, interval, true, func(context.Context) (int, error) {
+		var lastErr error
+		chains := blockchain.Chains()
+		for _, chain := range chains {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if reading := checkGasTank(ctx, chain); reading.err != "" {
+				lastErr = fmt.Errorf("%s: %s", chain, reading.err)
+			}
+			cancel()
+		}
+		return len(chains), lastErr
+	})
+}
+
+// checkGasTank reads chain's hot wallet balance, records it and sends an alert when the balance
+// has just fallen below the low-water mark.
+func checkGasTank(ctx context.Context, chain string) gasTankReading {
+	gasTankMu.Lock()
+	wallet, lowWater := gasTankWallet, gasTankLowWater[chain]
+	gasTankMu.Unlock()
+
+	balance, err := blockchain.NativeBalance(ctx, chain, wallet)
+	gasTankMu.Lock()
+	prev := gasTankState[chain]
+	cur := &gasTankReading{checkedAt: time.Now().UTC()}
+	if prev != nil {
+		cur.low = prev.low
+	}
+	if err != nil {
+		cur.err = err.Error()
+		if prev != nil {
+			cur.balance = prev.balance
+		}
+		gasTankState[chain] = cur
+		gasTankMu.Unlock()
+		log.Printf("event=gas_tank_check_failed chain=%s err=%v", chain, err)
+		return *cur
+	}
+	cur.balance = balance
+	cur.low = lowWater != nil && balance.Cmp(lowWater) < 0
+	gasTankState[chain] = cur
+	gasTankMu.Unlock()
+
+	if cur.low && (prev == nil || !prev.low) {
+		atomic.AddInt64(&gasTankAlertsTotal, 1)
+		log.Printf("event=gas_tank_low chain=%s wallet=%s balance_wei=%s low_water_wei=%s", chain, wallet, balance, lowWater)
+		go sendGasTankAlert(chain, wallet, balance, lowWater)
+	}
+	return *cur
+}
+
+type gasTankAlert struct {
+	Chain       string `json:"chain"`
+	Wallet      string `json:"wallet_address"`
+	BalanceWei  string `json:"balance_wei"`
+	LowWaterWei string `json:"low_water_wei"`
+}
+
+// sendGasTankAlert POSTs a gas_tank.low event to the configured alert URL, if any.
+func sendGasTankAlert(chain, wallet string, balance, lowWater *big.Int) {
+	gasTankMu.Lock()
+	url := gasTankAlertURL
+	gasTankMu.Unlock()
+	sendOperatorAlert(url, "gas_tank.low", gasTankAlert{Chain: chain, Wallet: wallet, BalanceWei: balance.String(), LowWaterWei: lowWater.String()})
+}
+
+// gasTankLowChains counts the chains currently below their low-water mark, for /debug/metrics.
+func gasTankLowChains() int64 {
+	gasTankMu.Lock()
+	defer gasTankMu.Unlock()
+	var n int64
+	for _, s := range gasTankState {
+		if s.low {
+			n++
+		}
+	}
+	return n
+}
+
+type gasTankStatus struct {
+	Chain             string   `json:"chain"`
+	NativeAsset       string   `json:"native_asset,omitempty"`
+	WalletAddress     string   `json:"wallet_address"`
+	BalanceWei        string   `json:"balance_wei,omitempty"`
+	Balance           string   `json:"balance,omitempty"` // in the native asset
+	LowWaterWei       string   `json:"low_water_wei,omitempty"`
+	Low               bool     `json:"low"`
+	CheckedAt         string   `json:"checked_at"`
+	Payouts7d         int      `json:"payouts_7d"` // settlement batches and completed refunds on the chain
+	FeePerTransferWei string   `json:"fee_per_transfer_wei,omitempty"`
+	DailyBurnWei      string   `json:"daily_burn_wei,omitempty"`
+	RunwayDays        *float64 `json:"runway_days,omitempty"` // omitted without recent payouts
+	Error             string   `json:"error,omitempty"`
+}
+
+type gasTankResp struct {
+	Chains []gasTankStatus `json:"chains"`
+}
+
+// GasTankHandler godoc
+// @Summary      Show hot wallet gas balances
+// @Description  Reads the hot wallet's native-coin balance on every chain and projects how many days it lasts: the average daily payouts of the last 7 days (settlement batches and completed refunds on the chain) priced at the current gas price of a token transfer. low is set when the balance is under the chain's low-water mark (GAS_TANK_LOW_WATER). Admin only.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  gasTankResp
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/gas-tank [get]
+func GasTankHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	gasTankMu.Lock()
+	wallet := gasTankWallet
+	gasTankMu.Unlock()
+	if wallet == "" {
+		writeProblem(w, http.StatusConflict, CodeGasTankNotConfigured, "set HOT_WALLET_ADDRESS")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	since := time.Now().UTC().Add(-runwayWindow).Format(time.RFC3339)
+	resp := gasTankResp{Chains: []gasTankStatus{}}
+	for _, chain := range blockchain.Chains() {
+		reading := checkGasTank(ctx, chain)
+		st := gasTankStatus{
+			Chain:         chain,
+			NativeAsset:   blockchain.NativeAsset(chain),
+			WalletAddress: wallet,
+			Low:           reading.low,
+			CheckedAt:     reading.checkedAt.Format(time.RFC3339),
+			Error:         reading.err,
+		}
+		gasTankMu.Lock()
+		lowWater := gasTankLowWater[chain]
+		gasTankMu.Unlock()
+		if lowWater != nil {
+			st.LowWaterWei = lowWater.String()
+		}
+		if reading.balance != nil {
+			st.BalanceWei = reading.balance.String()
+			st.Balance = formatUnits(reading.balance, 18)
+		}
+		n, err := recentPayouts(ctx, chain, since)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		st.Payouts7d = n
+		if quote, err := blockchain.SuggestGas(ctx, chain); err == nil {
+			fee := blockchain.TransferFee(quote.GasPrice, 1)
+			st.FeePerTransferWei = fee.String()
+			if n > 0 && reading.balance != nil {
+				burn := new(big.Float).Quo(new(big.Float).SetInt(blockchain.TransferFee(quote.GasPrice, n)), big.NewFloat(runwayWindow.Hours()/24))
+				b, _ := burn.Int(nil)
+				st.DailyBurnWei = b.String()
+				days, _ := new(big.Float).Quo(new(big.Float).SetInt(reading.balance), burn).Float64()
+				days = float64(int64(days*10)) / 10
+				st.RunwayDays = &days
+			}
+		} else if st.Error == "" {
+			st.Error = err.Error()
+		}
+		resp.Chains = append(resp.Chains, st)
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// recentPayouts counts the on-chain payouts sent on chain since the given time: settlement
+// batches of orders on the chain and completed refunds.
+func recentPayouts(ctx context.Context, chain, since string) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT
+		  (SELECT COUNT(*) FROM settlement_batches b
+		    WHERE b.created_at >= ? AND EXISTS (SELECT 1 FROM orders o WHERE o.settlement_batch_id = b.id AND UPPER(o.chain) = ?))
+		+ (SELECT COUNT(*) FROM refunds f JOIN orders o ON o.id = f.order_id
+		    WHERE f.status = 'COMPLETED' AND f.created_at >= ? AND UPPER(o.chain) = ?)
+	`, since, chain, since, chain).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,528 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// An administrator, or the platform a merchant is connected to, can hold part of the merchant's
+// unsettled balance on a chain, e.g. during a risk review. Placing a hold moves the amount from
+// the merchant bucket to balance_hold (BALANCE_HOLD); settlements keep back what the merchant
+// bucket then lacks to pay out their orders (held_minor on the batch, withheld_minor on the hold).
+// Releasing it, by hand or at expires_at, moves it back (BALANCE_RELEASE) and settles what was
+// withheld in a batch of its own.
+const (
+	bucketBalanceHold = "balance_hold"
+
+	eventBalanceHold    = "BALANCE_HOLD"
+	eventBalanceRelease = "BALANCE_RELEASE"
+
+	holdActive   = "ACTIVE"
+	holdReleased = "RELEASED"
+	holdExpired  = "EXPIRED"
+)
+
+type placeHoldReq struct {
+	MerchantID  string `json:"merchant_id" validate:"required"`
+	Asset       string `json:"asset" validate:"required,asset"`
+	Chain       string `json:"chain" validate:"required,chain"`
+	AmountMinor string `json:"amount_minor" validate:"required,amount"`
+	Reason      string `json:"reason" validate:"required,max=500"`      // e.g. "risk review"
+	ExpiresAt   string `json:"expires_at,omitempty" validate:"rfc3339"` // omitted: held until released
+}
+
+type releaseHoldReq struct {
+	Reason string `json:"reason,omitempty" validate:"max=500"`
+}
+
+type balanceHold struct {
+	ID             string  `json:"id"`
+	MerchantID     string  `json:"merchant_id"`
+	Asset          string  `json:"asset"`
+	Chain          string  `json:"chain"`
+	AmountMinor    string  `json:"amount_minor"`
+	Reason         string  `json:"reason"`
+	Status         string  `json:"status"` // ACTIVE, RELEASED or EXPIRED
+	PlacedBy       string  `json:"placed_by"`
+	PlatformID     *string `json:"platform_id,omitempty"`
+	ExpiresAt      *string `json:"expires_at,omitempty"`
+	WithheldMinor  string  `json:"withheld_minor"` // kept back from settlements while active
+	CreatedAt      string  `json:"created_at"`
+	ReleasedAt     *string `json:"released_at,omitempty"`
+	ReleasedBy     *string `json:"released_by,omitempty"`
+	ReleaseReason  *string `json:"release_reason,omitempty"`
+	ReleaseBatchID *string `json:"release_batch_id,omitempty"` // settled the withheld part
+}
+
+const balanceHoldCols = `id, merchant_id, asset, chain, amount_minor, reason, status, placed_by, platform_id, expires_at,
+	withheld_minor, created_at, released_at, released_by, release_reason, release_batch_id`
+
+func scanBalanceHold(row scanner) (balanceHold, error) {
+	var (
+		h                                             balanceHold
+		platformID, expiresAt, releasedAt, releasedBy sql.NullString
+		releaseReason, releaseBatch                   sql.NullString
+	)
+	err := row.Scan(&h.ID, &h.MerchantID, &h.Asset, &h.Chain, &h.AmountMinor, &h.Reason, &h.Status, &h.PlacedBy, &platformID, &expiresAt,
+		&h.WithheldMinor, &h.CreatedAt, &releasedAt, &releasedBy, &releaseReason, &releaseBatch)
+	h.PlatformID, h.ExpiresAt, h.ReleasedAt = nullStringPtr(platformID), nullStringPtr(expiresAt), nullStringPtr(releasedAt)
+	h.ReleasedBy, h.ReleaseReason, h.ReleaseBatchID = nullStringPtr(releasedBy), nullStringPtr(releaseReason), nullStringPtr(releaseBatch)
+	return h, err
+}
+
+// holdLedgerEntries books amount of hold h moving between the merchant and balance_hold buckets.
+func holdLedgerEntries(h balanceHold, event, amount, now string) []store.LedgerEntry {
+	from, to := bucketMerchant, bucketBalanceHold
+	if event == eventBalanceRelease {
+		from, to = to, from
+	}
+	entry := func(bucket, direction string) store.LedgerEntry {
+		return store.LedgerEntry{
+			MerchantID: h.MerchantID, Asset: h.Asset, Chain: h.Chain,
+			AmountMinor: amount, Bucket: bucket, Direction: direction, EventType: event, ReferenceID: h.ID, CreatedAt: now,
+		}
+	}
+	return []store.LedgerEntry{entry(from, dirDebit), entry(to, dirCredit)}
+}
+
+// withholdHolds reduces the per-chain nets of a settlement by what the merchant's balance on the
+// chain lacks to cover them, as far as active holds not yet withheld account for it, so that held
+// funds are not paid out. What is withheld is assigned to the holds, oldest first. It returns the
+// total withheld.
+func withholdHolds(ctx context.Context, tx *sql.Tx, merchantID, asset string, byChain map[string]*big.Int) (*big.Int, error) {
+	withheld := new(big.Int)
+	for chain, net := range byChain {
+		if net.Sign() <= 0 {
+			continue
+		}
+		var balanceMinor string
+		err := tx.QueryRowContext(ctx, `
+			SELECT balance_minor FROM ledger_balances WHERE merchant_id = ? AND asset = ? AND chain = ? AND bucket = ?
+		`, merchantID, asset, chain, bucketMerchant).Scan(&balanceMinor)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		balance, ok := new(big.Int).SetString(balanceMinor, 10)
+		if !ok {
+			balance = new(big.Int)
+		}
+		short := new(big.Int).Sub(net, balance)
+		if short.Cmp(net) > 0 {
+			short.Set(net)
+		}
+		if short.Sign() <= 0 {
+			continue
+		}
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, amount_minor, withheld_minor FROM balance_holds
+			WHERE merchant_id = ? AND asset = ? AND chain = ? AND status = ?
+			ORDER BY created_at, id
+		`, merchantID, asset, chain, holdActive)
+		if err != nil {
+			return nil, err
+		}
+		type holdShare struct {
+			id       string
+			withheld *big.Int
+		}
+		var shares []holdShare
+		for rows.Next() && short.Sign() > 0 {
+			var id, amountMinor, withheldMinor string
+			if err := rows.Scan(&id, &amountMinor, &withheldMinor); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			amount, ok1 := new(big.Int).SetString(amountMinor, 10)
+			done, ok2 := new(big.Int).SetString(withheldMinor, 10)
+			if !ok1 || !ok2 {
+				continue
+			}
+			part := amount.Sub(amount, done)
+			if part.Cmp(short) > 0 {
+				part.Set(short)
+			}
+			if part.Sign() <= 0 {
+				continue
+			}
+			short.Sub(short, part)
+			shares = append(shares, holdShare{id, done.Add(done, part)})
+			net.Sub(net, part)
+			withheld.Add(withheld, part)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		for _, s := range shares {
+			if _, err := tx.ExecContext(ctx, `UPDATE balance_holds SET withheld_minor = ? WHERE id = ?`, s.withheld.String(), s.id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return withheld, nil
+}
+
+// BalanceHoldsHandler godoc
+// @Summary      Place or list balance holds
+// @Description  POST (admin key or platform API key) holds amount_minor of a merchant's unsettled balance of asset on chain, for reason, until it is released or expires_at passes. The amount moves from the merchant to the balance_hold ledger bucket (counted as held in the balances) and settlements keep back what the merchant balance then lacks to pay out their orders, so held funds are not paid out; withheld_minor is how much they kept back. The hold may not exceed the unsettled balance on the chain. Platforms can only hold the balances of their connected merchants. GET lists holds, newest first, filtered by status and (admins, platforms) merchant_id; merchants see the holds on their own balance.
+// @Tags         reconciliation
+// @Accept       json
+// @Produce      json
+// @Param        hold         body   placeHoldReq  false  "Hold (POST only)"
+// @Param        status       query  string        false  "ACTIVE, RELEASED or EXPIRED (GET only)"
+// @Param        merchant_id  query  string        false  "Merchant ID (GET, admin and platforms only)"
+// @Success      200  {array}   balanceHold
+// @Success      201  {object}  balanceHold
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /holds [get]
+// @Router       /admin/holds [get]
+// @Router       /admin/holds [post]
+func BalanceHoldsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	admin, platformID := isAdmin(ctx), platformIDFromContext(ctx)
+	switch r.Method {
+	case http.MethodGet:
+		listBalanceHolds(w, r, admin, platformID)
+	case http.MethodPost:
+		if !admin && platformID == "" {
+			writeProblem(w, http.StatusForbidden, CodeAdminRequired, "holds are placed by an administrator or platform")
+			return
+		}
+		var req placeHoldReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		if platformID != "" {
+			if err := requireConnectedMerchant(ctx, platformID, req.MerchantID); errors.Is(err, errMerchantNotFound) {
+				writeProblem(w, http.StatusForbidden, CodeMerchantNotConnected, "merchant is not connected to this platform")
+				return
+			} else if err != nil {
+				serverErr(w, err)
+				return
+			}
+		}
+		now := time.Now().UTC().Format(time.RFC3339)
+		if req.ExpiresAt != "" {
+			t, _ := time.Parse(time.RFC3339, req.ExpiresAt)
+			if req.ExpiresAt = t.UTC().Format(time.RFC3339); req.ExpiresAt <= now {
+				badReq(w, "expires_at must be in the future")
+				return
+			}
+		}
+		h := balanceHold{
+			ID: "hold_" + uuid.New().String(), MerchantID: req.MerchantID, Asset: strings.ToUpper(req.Asset), Chain: strings.ToUpper(req.Chain),
+			AmountMinor: req.AmountMinor, Reason: strings.TrimSpace(req.Reason), Status: holdActive, PlacedBy: actorFromContext(ctx),
+			ExpiresAt: optionalString(req.ExpiresAt), WithheldMinor: "0", CreatedAt: now,
+		}
+		if platformID != "" {
+			h.PlacedBy, h.PlatformID = "platform:"+platformID, &platformID
+		}
+		if err := placeHold(ctx, h); err != nil {
+			var balErr *holdBalanceError
+			switch {
+			case errors.Is(err, errMerchantNotFound):
+				writeProblem(w, http.StatusNotFound, CodeMerchantNotFound, "merchant not found")
+			case errors.As(err, &balErr):
+				writeProblem(w, http.StatusConflict, CodeHoldExceedsBalance, balErr.Error())
+			default:
+				serverErr(w, err)
+			}
+			return
+		}
+		log.Printf("event=balance_hold_placed hold_id=%s merchant_id=%s asset=%s chain=%s amount_minor=%s by=%s",
+			h.ID, h.MerchantID, h.Asset, h.Chain, h.AmountMinor, h.PlacedBy)
+		writeJSON(w, http.StatusCreated, h)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+// holdBalanceError rejects a hold larger than the unsettled balance it would hold.
+type holdBalanceError struct{ Msg string }
+
+func (e *holdBalanceError) Error() string { return e.Msg }
+
+func placeHold(ctx context.Context, h balanceHold) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var id string
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM merchants WHERE id = ?`, h.MerchantID).Scan(&id); errors.Is(err, sql.ErrNoRows) {
+		return errMerchantNotFound
+	} else if err != nil {
+		return err
+	}
+	var balanceMinor string
+	err = tx.QueryRowContext(ctx, `
+		SELECT balance_minor FROM ledger_balances WHERE merchant_id = ? AND asset = ? AND chain = ? AND bucket = ?
+	`, h.MerchantID, h.Asset, h.Chain, bucketMerchant).Scan(&balanceMinor)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	balance, ok := new(big.Int).SetString(balanceMinor, 10)
+	if !ok {
+		balance = new(big.Int)
+	}
+	if amount, _ := new(big.Int).SetString(h.AmountMinor, 10); amount.Cmp(balance) > 0 {
+		return &holdBalanceError{"the unsettled " + h.Asset + " balance on " + h.Chain + " is " + balance.String()}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO balance_holds (id, merchant_id, asset, chain, amount_minor, reason, status, placed_by, platform_id, expires_at, withheld_minor, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, '0', ?)
+	`, h.ID, h.MerchantID, h.Asset, h.Chain, h.AmountMinor, h.Reason, h.Status, h.PlacedBy, h.PlatformID, h.ExpiresAt, h.CreatedAt); err != nil {
+		return err
+	}
+	if err := txStores(tx).Ledger.Append(ctx, holdLedgerEntries(h, eventBalanceHold, h.AmountMinor, h.CreatedAt)...); err != nil {
+		return err
+	}
+	if err := enqueueEvent(ctx, tx, h.MerchantID, "merchant", h.MerchantID, webhookBalanceHoldPlaced, h); err != nil {
+		return err
+	}
+	recordAudit(ctx, tx, h.PlacedBy, h.MerchantID, "", "balance_hold.placed", h)
+	return tx.Commit()
+}
+
+func listBalanceHolds(w http.ResponseWriter, r *http.Request, admin bool, platformID string) {
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if admin || platformID != "" {
+		merchantID = q.Get("merchant_id")
+	}
+	status := strings.ToUpper(q.Get("status"))
+	if status != "" && status != holdActive && status != holdReleased && status != holdExpired {
+		badReq(w, "status must be ACTIVE, RELEASED or EXPIRED")
+		return
+	}
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+balanceHoldCols+` FROM balance_holds
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR status = ?)
+		  AND (? = '' OR merchant_id IN (SELECT id FROM merchants WHERE platform_id = ?))
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1000
+	`, merchantID, merchantID, status, status, platformID, platformID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	out := []balanceHold{}
+	for rows.Next() {
+		h, err := scanBalanceHold(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		out = append(out, h)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// ReleaseBalanceHoldHandler godoc
+// @Summary      Release a balance hold
+// @Description  Releases an ACTIVE hold before it expires: the amount moves back from the balance_hold to the merchant ledger bucket, and what settlements withheld for it (withheld_minor) is settled right away in a batch of its own (release_batch_id, hold_released_minor), paid out like any other settlement. An optional reason is recorded. Platforms can only release the holds they placed; admins any.
+// @Tags         reconciliation
+// @Accept       json
+// @Produce      json
+// @Param        id       query  string          true   "Hold ID"
+// @Param        release  body   releaseHoldReq  false  "Release"
+// @Success      200  {object}  balanceHold
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /admin/holds/release [post]
+func ReleaseBalanceHoldHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req releaseHoldReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	ctx := r.Context()
+	h, err := scanBalanceHold(db.QueryRowContext(ctx, `SELECT `+balanceHoldCols+` FROM balance_holds WHERE id = ?`, pathID(r)))
+	platformID := platformIDFromContext(ctx)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && platformID != "" && (h.PlatformID == nil || *h.PlatformID != platformID)) {
+		writeProblem(w, http.StatusNotFound, CodeHoldNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	actor := actorFromContext(ctx)
+	if platformID != "" {
+		actor = "platform:" + platformID
+	}
+	released, err := releaseHold(ctx, h, holdReleased, actor, strings.TrimSpace(req.Reason))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if released == nil {
+		writeProblem(w, http.StatusConflict, CodeHoldNotActive, "hold is "+h.Status)
+		return
+	}
+	writeJSON(w, http.StatusOK, released)
+}
+
+// PlatformBalanceHoldsHandler godoc
+// @Summary      Place or list connected merchant balance holds
+// @Description  POST holds part of a connected merchant's unsettled balance, like POST /admin/holds. GET lists the holds on the balances of the platform's connected merchants, newest first, filtered by status and merchant_id.
+// @Tags         platforms
+// @Accept       json
+// @Produce      json
+// @Param        hold         body   placeHoldReq  false  "Hold (POST only)"
+// @Param        status       query  string        false  "ACTIVE, RELEASED or EXPIRED (GET only)"
+// @Param        merchant_id  query  string        false  "Merchant ID (GET only)"
+// @Success      200  {array}   balanceHold
+// @Success      201  {object}  balanceHold
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /platforms/holds [get]
+// @Router       /platforms/holds [post]
+func PlatformBalanceHoldsHandler(w http.ResponseWriter, r *http.Request) {
+	BalanceHoldsHandler(w, r)
+}
+
+// PlatformReleaseBalanceHoldHandler godoc
+// @Summary      Release a connected merchant balance hold
+// @Description  Releases an ACTIVE hold the platform placed, like POST /admin/holds/release.
+// @Tags         platforms
+// @Accept       json
+// @Produce      json
+// @Param        id       query  string          true   "Hold ID"
+// @Param        release  body   releaseHoldReq  false  "Release"
+// @Success      200  {object}  balanceHold
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /platforms/holds/release [post]
+func PlatformReleaseBalanceHoldHandler(w http.ResponseWriter, r *http.Request) {
+	ReleaseBalanceHoldHandler(w, r)
+}
+
+// releaseHold ends active hold h with status (RELEASED or EXPIRED), moving its amount back to the
+// merchant bucket and settling what was withheld for it. It returns nil if the hold was no longer
+// active.
+func releaseHold(ctx context.Context, h balanceHold, status, actor, reason string) (*balanceHold, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// The withheld amount is read again under the write: a settlement may have changed it.
+	res, err := tx.ExecContext(ctx, `
+		UPDATE balance_holds SET status = ?, released_at = ?, released_by = ?, release_reason = ? WHERE id = ? AND status = ?
+	`, status, now, actor, optionalString(reason), h.ID, holdActive)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT withheld_minor FROM balance_holds WHERE id = ?`, h.ID).Scan(&h.WithheldMinor); err != nil {
+		return nil, err
+	}
+	if err := txStores(tx).Ledger.Append(ctx, holdLedgerEntries(h, eventBalanceRelease, h.AmountMinor, now)...); err != nil {
+		return nil, err
+	}
+	h.Status, h.ReleasedAt, h.ReleasedBy, h.ReleaseReason = status, &now, &actor, optionalString(reason)
+	if withheld, ok := new(big.Int).SetString(h.WithheldMinor, 10); ok && withheld.Sign() > 0 {
+		batchID := "batch_" + uuid.New().String()
+		byChain := map[string]*big.Int{h.Chain: withheld}
+		if err := insertSettlementBatch(ctx, tx, batchID, h.MerchantID, h.Asset, withheld, settlementItems{HoldReleasedMinor: withheld.String()}, now); err != nil {
+			return nil, err
+		}
+		if err := writeSettlementLedger(ctx, tx, batchID, h.MerchantID, h.Asset, byChain, now); err != nil {
+			return nil, err
+		}
+		if err := queuePayouts(ctx, tx, batchID, h.MerchantID, h.Asset, byChain); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE balance_holds SET release_batch_id = ? WHERE id = ?`, batchID, h.ID); err != nil {
+			return nil, err
+		}
+		h.ReleaseBatchID = &batchID
+	}
+	if err := enqueueEvent(ctx, tx, h.MerchantID, "merchant", h.MerchantID, webhookBalanceHoldReleased, h); err != nil {
+		return nil, err
+	}
+	recordAudit(ctx, tx, actor, h.MerchantID, "", "balance_hold."+strings.ToLower(status), h)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("event=balance_hold_released hold_id=%s merchant_id=%s status=%s amount_minor=%s withheld_minor=%s by=%s",
+		h.ID, h.MerchantID, status, h.AmountMinor, h.WithheldMinor, actor)
+	return &h, nil
+}
+
+// StartBalanceHoldExpirer releases expired holds every interval.
+func StartBalanceHoldExpirer(interval time.Duration) {
+	startScheduler(schedulerBalanceHolds, interval, false, func(ctx context.Context) (int, error) {
+		return expireBalanceHolds(ctx)
+	})
+}
+
+// expireBalanceHolds releases the active holds past their expires_at and reports how many. A
+// failing hold is logged and skipped; the error of the last failure is returned.
+func expireBalanceHolds(ctx context.Context) (int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+balanceHoldCols+` FROM balance_holds WHERE status = ? AND expires_at IS NOT NULL AND expires_at <= ?
+	`, holdActive, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	var due []balanceHold
+	for rows.Next() {
+		h, err := scanBalanceHold(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	var n int
+	var lastErr error
+	for _, h := range due {
+		released, err := releaseHold(ctx, h, holdExpired, "system", "expired")
+		if err != nil {
+			log.Printf("balance holds: failed to expire %s: %v", h.ID, err)
+			lastErr = err
+			continue
+		}
+		if released != nil {
+			n++
+		}
+	}
+	return n, lastErr
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IdempotencyKeyHeader lets a client retry any POST safely: the first response for a key is stored
+// and replayed for later requests with the same key, credential and body.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyTTL is how long a stored response is replayed; after that the key may be reused.
+const idempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLen bounds the header value; clients are expected to send a UUID.
+const maxIdempotencyKeyLen = 255
+
+// IdempotencyMiddleware replays the stored response of a POST that repeats an Idempotency-Key.
+// Keys are scoped to the credential headers of the request. Reusing a key with a different body is
+// rejected with 422, and a retry that arrives while the first request is still running gets 409.
+// 5xx responses and authentication failures are not stored, so the request can be retried.
+// Responses marked with noStore hold a secret shown once: their body is not stored, and a repeat
+// gets 409 idempotency_response_withheld rather than running the request again. Requests without
+// the header or without a credential, which would have no caller to scope their key to, and other
+// methods pass through unchanged.
+func IdempotencyMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" || db == nil {
+			next(w, r)
+			return
+		}
+		scope := idempotencyScope(r)
+		if scope == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidIdempotencyKey, "Idempotency-Key is longer than 255 characters")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if bodyTooLarge(w, err) {
+			return
+		}
+		if err != nil {
+			badReq(w, "could not read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		reqHash := requestHash(r, body)
+		ctx := r.Context()
+		now := time.Now().UTC()
+		expired := now.Add(-idempotencyTTL).Format(time.RFC3339)
+
+		// Claim the key; a row left by an expired earlier use is replaced
+		if _, err := db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE scope = ? AND key = ? AND created_at < ?`, scope, key, expired); err != nil {
+			serverErr(w, err)
+			return
+		}
+		res, err := db.ExecContext(ctx, `
+			INSERT INTO idempotency_keys (scope, key, request_hash, created_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (scope, key) DO NOTHING
+		`, scope, key, reqHash, now.Format(time.RFC3339))
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			replayIdempotent(w, r, scope, key, reqHash)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			// Release the key if the handler panicked or the response should not be replayed
+			if !completed {
+				if _, err := db.Exec(`DELETE FROM idempotency_keys WHERE scope = ? AND key = ?`, scope, key); err != nil {
+					log.Printf("idempotency: release key: %v", err)
+				}
+			}
+		}()
+		next(rec, r)
+		if !storableStatus(rec.status) {
+			return
+		}
+		contentType, stored, withheld := rec.Header().Get("Content-Type"), rec.body.Bytes(), noStored(rec.Header())
+		if withheld {
+			contentType, stored = "", nil
+		}
+		if _, err := db.Exec(`
+			UPDATE idempotency_keys SET status = ?, content_type = ?, body = ?, withheld = ?
+			WHERE scope = ? AND key = ?
+		`, rec.status, contentType, stored, withheld, scope, key); err != nil {
+			log.Printf("idempotency: store response: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replayIdempotent answers a request whose key is already claimed.
+func replayIdempotent(w http.ResponseWriter, r *http.Request, scope, key, reqHash string) {
+	var (
+		storedHash  string
+		status      sql.NullInt64
+		contentType sql.NullString
+		body        []byte
+		withheld    bool
+	)
+	err := db.QueryRowContext(r.Context(), `
+		SELECT request_hash, status, content_type, body, withheld FROM idempotency_keys WHERE scope = ? AND key = ?
+	`, scope, key).Scan(&storedHash, &status, &contentType, &body, &withheld)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The first request failed and released the key between our insert and this read
+		writeProblem(w, http.StatusConflict, CodeIdempotencyInProgress, "retry the request")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if storedHash != reqHash {
+		writeProblem(w, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request")
+		return
+	}
+	if !status.Valid {
+		writeProblem(w, http.StatusConflict, CodeIdempotencyInProgress, "a request with this Idempotency-Key is still being processed")
+		return
+	}
+	if withheld {
+		writeProblem(w, http.StatusConflict, CodeIdempotencyResponseWithheld,
+			fmt.Sprintf("the request already succeeded with status %d; its response held a secret, which is not stored and cannot be shown again", status.Int64))
+		return
+	}
+	if contentType.String != "" {
+		w.Header().Set("Content-Type", contentType.String)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(int(status.Int64))
+	_, _ = w.Write(body)
+}
+
+// noStore marks a response that returns a secret, such as a new API key, for the only time: no
+// client or cache should keep it, and IdempotencyMiddleware does not store it for replay.
+func noStore(w http.ResponseWriter) { w.Header().Set("Cache-Control", "no-store") }
+
+// noStored reports whether a response was marked with noStore.
+func noStored(h http.Header) bool { return strings.Contains(h.Get("Cache-Control"), "no-store") }
+
+// idempotencyScope hashes the credential headers so keys from different callers never collide
+// and no credential is stored in clear.
+func idempotencyScope(r *http.Request) string {
+	var cred string
+	for _, h := range []string{"X-API-Key", "Authorization", "X-Admin-Key", orgMerchantHeader} {
+		if v := r.Header.Get(h); v != "" {
+			cred += h + ":" + v + "\n"
+		}
+	}
+	if cred == "" {
+		return ""
+	}
+	return hashToken(cred)
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// storableStatus reports whether a response is final for its key. Server errors, rate limiting
+// and authentication failures may succeed on retry, so they are not replayed.
+func storableStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+	return status < 500
+}
+
+// StartIdempotencyPruner deletes expired stored responses every interval.
+func StartIdempotencyPruner(db *sql.DB, interval time.Duration) {
+	startScheduler(schedulerIdempotency, interval, false, func(context.Context) (int, error) {
+		n, err := pruneIdempotencyKeys(db)
+		if n > 0 {
+			log.Printf("idempotency: pruned %d expired keys", n)
+		}
+		return int(n), err
+	})
+}
+
+// pruneIdempotencyKeys deletes stored responses past idempotencyTTL.
+func pruneIdempotencyKeys(db *sql.DB) (int64, error) {
+	res, err := db.Exec(`DELETE FROM idempotency_keys WHERE created_at < ?`, time.Now().UTC().Add(-idempotencyTTL).Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// responseRecorder passes a response through while keeping a copy of its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if !rr.wroteHeader {
+		rr.status, rr.wroteHeader = code, true
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
@@ -0,0 +1,533 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Payment intent states. An intent records that a customer committed to pay; its amount may change
+// until it is captured, which creates the order the customer pays, or voided.
+const (
+	intentRequiresCapture = "REQUIRES_CAPTURE"
+	intentCaptured        = "CAPTURED"
+	intentVoided          = "VOIDED"
+)
+
+type paymentIntentCreateReq struct {
+	AmountMinor     string          `json:"amount_minor" validate:"required,amount"`
+	Asset           string          `json:"asset" validate:"required,asset"`
+	Chain           string          `json:"chain" validate:"required,chain"`
+	Metadata        json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`        // copied to the order
+	ExternalOrderID string          `json:"external_order_id,omitempty" validate:"max=128"` // copied to the order
+	// CouponCode is checked now and redeemed when the intent is captured.
+	CouponCode string `json:"coupon_code,omitempty" validate:"max=64"`
+}
+
+// paymentIntentUpdateReq changes the given fields only.
+type paymentIntentUpdateReq struct {
+	AmountMinor     *string         `json:"amount_minor,omitempty" validate:"amount"`
+	Metadata        json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	ExternalOrderID *string         `json:"external_order_id,omitempty" validate:"max=128"`
+}
+
+type paymentIntentCaptureReq struct {
+	// AmountMinor captures less than the intent's amount; omitted, all of it is captured.
+	AmountMinor           string `json:"amount_minor,omitempty" validate:"amount"`
+	CustomerWalletAddress string `json:"customer_wallet_address,omitempty" validate:"max=128"`
+	CustomerEmail         string `json:"customer_email,omitempty" validate:"max=254"`
+}
+
+type paymentIntentVoidReq struct {
+	Reason string `json:"reason,omitempty" validate:"max=255"`
+}
+
+type paymentIntent struct {
+	ID                  string          `json:"id"`
+	MerchantID          string          `json:"merchant_id"`
+	AmountMinor         string          `json:"amount_minor"`
+	Asset               string          `json:"asset"`
+	Chain               string          `json:"chain"`
+	Status              string          `json:"status"` // REQUIRES_CAPTURE, CAPTURED or VOIDED
+	Metadata            json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	ExternalOrderID     *string         `json:"external_order_id,omitempty"`
+	CouponCode          *string         `json:"coupon_code,omitempty"`
+	OrderID             *string         `json:"order_id,omitempty"`              // set by capture
+	CapturedAmountMinor *string         `json:"captured_amount_minor,omitempty"` // before any coupon discount
+	VoidReason          *string         `json:"void_reason,omitempty"`
+	CreatedAt           string          `json:"created_at"`
+	UpdatedAt           string          `json:"updated_at"`
+	CapturedAt          *string         `json:"captured_at,omitempty"`
+	VoidedAt            *string         `json:"voided_at,omitempty"`
+}
+
+// paymentIntentCaptureResp is a captured intent with the order the customer now pays.
+type paymentIntentCaptureResp struct {
+	PaymentIntent paymentIntent   `json:"payment_intent"`
+	Order         orderCreateResp `json:"order"`
+}
+
+const paymentIntentCols = `id, merchant_id, amount_minor, asset, chain, status, metadata, external_order_id, coupon_code,
+	order_id, captured_amount_minor, void_reason, created_at, updated_at, captured_at, voided_at`
+
+func scanPaymentIntent(row scanner) (paymentIntent, error) {
+	var (
+		pi       paymentIntent
+		metadata sql.NullString
+	)
+	if err := row.Scan(&pi.ID, &pi.MerchantID, &pi.AmountMinor, &pi.Asset, &pi.Chain, &pi.Status, &metadata, &pi.ExternalOrderID,
+		&pi.CouponCode, &pi.OrderID, &pi.CapturedAmountMinor, &pi.VoidReason, &pi.CreatedAt, &pi.UpdatedAt, &pi.CapturedAt, &pi.VoidedAt); err != nil {
+		return paymentIntent{}, err
+	}
+	if metadata.Valid {
+		pi.Metadata = json.RawMessage(metadata.String)
+	}
+	return pi, nil
+}
+
+func getPaymentIntent(ctx context.Context, q queryer, merchantID, id string) (paymentIntent, error) {
+	return scanPaymentIntent(q.QueryRowContext(ctx, `SELECT `+paymentIntentCols+` FROM payment_intents WHERE id = ? AND merchant_id = ?`, id, merchantID))
+}
+
+// PaymentIntentsHandler godoc
+// @Summary      Create or list payment intents
+// @Description  POST records that a customer committed to pay amount_minor of asset on chain, before any order exists: the amount can still be adjusted (POST /payment-intents/update) until the intent is captured, which creates the order the customer pays, or voided. The merchant wallet must be an address on chain, and coupon_code must be redeemable now; it is redeemed on capture. GET lists the merchant's intents, newest first, optionally filtered by status (REQUIRES_CAPTURE, CAPTURED, VOIDED), at most limit (default 50, max 200).
+// @Tags         payment-intents
+// @Accept       json
+// @Produce      json
+// @Param        intent  body   paymentIntentCreateReq  false  "Payment intent (POST only)"
+// @Param        status  query  string                  false  "Intent status (GET only)"
+// @Param        limit   query  int                     false  "Maximum results (GET only)"
+// @Success      200  {array}   paymentIntent
+// @Success      201  {object}  paymentIntent
+// @Failure      400  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /payment-intents [get]
+// @Router       /payment-intents [post]
+func PaymentIntentsHandler(w http.ResponseWriter, r *http.Request) {
+	merchantID := merchantIDFromContext(r.Context())
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	switch r.Method {
+	case http.MethodPost:
+		var req paymentIntentCreateReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		if !isJSONObject(req.Metadata) {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidMetadata, "metadata must be a JSON object")
+			return
+		}
+		merchant, err := stores.Merchants.Get(ctx, merchantID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		if _, err := blockchain.NormalizeAddress(req.Chain, merchant.WalletAddress); err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidWalletAddress, "the merchant wallet is not a "+strings.ToUpper(req.Chain)+" address, so it cannot receive payments on "+strings.ToUpper(req.Chain))
+			return
+		}
+		if req.CouponCode != "" {
+			if _, err := quoteCoupon(ctx, db, merchantID, req.CouponCode, req.Asset, req.AmountMinor); err != nil {
+				writeOrderCreateError(w, err)
+				return
+			}
+		}
+		id := "pi_" + uuid.New().String()
+		now := time.Now().UTC().Format(time.RFC3339)
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO payment_intents (id, merchant_id, amount_minor, asset, chain, status, metadata, external_order_id, coupon_code, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, merchantID, req.AmountMinor, req.Asset, req.Chain, intentRequiresCapture, optionalString(string(req.Metadata)),
+			optionalString(req.ExternalOrderID), optionalString(strings.TrimSpace(req.CouponCode)), now, now); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		pi, err := getPaymentIntent(ctx, db, merchantID, id)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, pi)
+	case http.MethodGet:
+		q := r.URL.Query()
+		limit := 50
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 200 {
+				badReq(w, "limit must be between 1 and 200")
+				return
+			}
+			limit = n
+		}
+		status := strings.ToUpper(q.Get("status"))
+		switch status {
+		case "", intentRequiresCapture, intentCaptured, intentVoided:
+		default:
+			badReq(w, "status must be REQUIRES_CAPTURE, CAPTURED or VOIDED")
+			return
+		}
+		rows, err := db.QueryContext(ctx, `
+			SELECT `+paymentIntentCols+` FROM payment_intents
+			WHERE merchant_id = ? AND (? = '' OR status = ?)
+			ORDER BY created_at DESC, id DESC LIMIT ?
+		`, merchantID, status, status, limit)
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		defer rows.Close()
+		intents := []paymentIntent{}
+		for rows.Next() {
+			pi, err := scanPaymentIntent(rows)
+			if err != nil {
+				writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+				return
+			}
+			intents = append(intents, pi)
+		}
+		writeJSON(w, http.StatusOK, intents)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+// GetPaymentIntentHandler godoc
+// @Summary      Get a payment intent
+// @Description  Returns one of the merchant's payment intents; once captured, order_id is the order the customer pays.
+// @Tags         payment-intents
+// @Produce      json
+// @Param        id  query  string  true  "Payment intent ID"
+// @Success      200  {object}  paymentIntent
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /payment-intents/get [get]
+func GetPaymentIntentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	pi, err := getPaymentIntent(r.Context(), db, merchantIDFromContext(r.Context()), pathID(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodePaymentIntentNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pi)
+}
+
+// UpdatePaymentIntentHandler godoc
+// @Summary      Adjust a payment intent
+// @Description  Changes the amount, metadata or external_order_id of an intent that is not yet captured or voided, e.g. after the customer changed the cart or shipping was added. The new amount_minor is what capture creates the order for.
+// @Tags         payment-intents
+// @Accept       json
+// @Produce      json
+// @Param        id      query  string                  true  "Payment intent ID"
+// @Param        intent  body   paymentIntentUpdateReq  true  "Fields to change"
+// @Success      200  {object}  paymentIntent
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /payment-intents/update [post]
+func UpdatePaymentIntentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req paymentIntentUpdateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if !isJSONObject(req.Metadata) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidMetadata, "metadata must be a JSON object")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	merchantID, id := merchantIDFromContext(r.Context()), pathID(r)
+	pi, ok := openPaymentIntent(ctx, w, merchantID, id)
+	if !ok {
+		return
+	}
+	// An order under the intent's key means a capture is under way or was interrupted; it must
+	// finish with the amount it started with.
+	if _, err := stores.Orders.GetByIdempotencyKey(ctx, merchantID, pi.ID); err == nil {
+		writeProblem(w, http.StatusConflict, CodePaymentIntentFinalized, "the intent is being captured; capture it again to finish")
+		return
+	}
+	amount, metadata, externalID := pi.AmountMinor, optionalString(string(pi.Metadata)), pi.ExternalOrderID
+	if req.AmountMinor != nil {
+		if !isValidAmountString(*req.AmountMinor) {
+			badReq(w, "amount_minor must be a positive integer")
+			return
+		}
+		amount = *req.AmountMinor
+	}
+	if len(req.Metadata) > 0 {
+		metadata = optionalString(string(req.Metadata))
+	}
+	if req.ExternalOrderID != nil {
+		externalID = optionalString(*req.ExternalOrderID)
+	}
+	res, err := db.ExecContext(ctx, `
+		UPDATE payment_intents SET amount_minor = ?, metadata = ?, external_order_id = ?, updated_at = ?
+		WHERE id = ? AND merchant_id = ? AND status = ?
+	`, amount, metadata, externalID, time.Now().UTC().Format(time.RFC3339), id, merchantID, intentRequiresCapture)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusConflict, CodePaymentIntentFinalized, "")
+		return
+	}
+	if pi, err = getPaymentIntent(ctx, db, merchantID, id); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pi)
+}
+
+// CapturePaymentIntentHandler godoc
+// @Summary      Capture a payment intent
+// @Description  Turns an intent into a PENDING order for amount_minor (default: the intent's amount; never more), carrying the intent's metadata, external_order_id and coupon, and returns both. From there the order is paid, credited to the ledger and settled like any other. Order limits, the coupon and the wallets are checked as at order creation. Capturing again after an interrupted capture finishes it with the same order.
+// @Tags         payment-intents
+// @Accept       json
+// @Produce      json
+// @Param        id       query  string                   true   "Payment intent ID"
+// @Param        capture  body   paymentIntentCaptureReq  false  "Amount and customer details"
+// @Success      200  {object}  paymentIntentCaptureResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /payment-intents/capture [post]
+func CapturePaymentIntentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req paymentIntentCaptureReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	merchantID, id := merchantIDFromContext(r.Context()), pathID(r)
+	pi, ok := openPaymentIntent(ctx, w, merchantID, id)
+	if !ok {
+		return
+	}
+	amount := pi.AmountMinor
+	if req.AmountMinor != "" {
+		if !isValidAmountString(req.AmountMinor) {
+			badReq(w, "amount_minor must be a positive integer")
+			return
+		}
+		requested, _ := new(big.Int).SetString(req.AmountMinor, 10)
+		authorized, _ := new(big.Int).SetString(pi.AmountMinor, 10)
+		if requested.Cmp(authorized) > 0 {
+			badReq(w, "amount_minor must not exceed the intent's amount_minor "+pi.AmountMinor)
+			return
+		}
+		amount = req.AmountMinor
+	}
+	orderReq := orderCreateReq{
+		MerchantID:            merchantID,
+		AmountMinor:           amount,
+		Asset:                 pi.Asset,
+		Chain:                 pi.Chain,
+		IdempotencyKey:        pi.ID, // a repeated capture gets the same order
+		CustomerWalletAddress: req.CustomerWalletAddress,
+		CustomerEmail:         req.CustomerEmail,
+		Metadata:              pi.Metadata,
+	}
+	if pi.ExternalOrderID != nil {
+		orderReq.ExternalOrderID = *pi.ExternalOrderID
+	}
+	if pi.CouponCode != nil {
+		orderReq.CouponCode = *pi.CouponCode
+	}
+	order, err := createOrderRecord(ctx, orderReq, "")
+	if err != nil {
+		writeOrderCreateError(w, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		UPDATE payment_intents SET status = ?, order_id = ?, captured_amount_minor = ?, captured_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, intentCaptured, order.OrderID, amount, now, now, pi.ID, intentRequiresCapture)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Captured or voided concurrently. A void may have missed the order created above.
+		if pi, err = getPaymentIntent(ctx, tx, merchantID, id); err != nil {
+			serverErr(w, err)
+			return
+		}
+		if pi.Status == intentCaptured && pi.OrderID != nil && *pi.OrderID == order.OrderID {
+			writeJSON(w, http.StatusOK, paymentIntentCaptureResp{PaymentIntent: pi, Order: order})
+			return
+		}
+		if err := expireIntentOrder(ctx, tx, merchantID, pi.ID); err != nil {
+			serverErr(w, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			serverErr(w, err)
+			return
+		}
+		writeProblem(w, http.StatusConflict, CodePaymentIntentFinalized, "")
+		return
+	}
+	if pi, err = getPaymentIntent(ctx, tx, merchantID, id); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := enqueueEvent(ctx, tx, merchantID, "payment_intent", pi.ID, webhookPaymentIntentCaptured, pi); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, paymentIntentCaptureResp{PaymentIntent: pi, Order: order})
+}
+
+// VoidPaymentIntentHandler godoc
+// @Summary      Void a payment intent
+// @Description  Cancels an intent that is not yet captured, e.g. when the customer abandons the checkout. An order left by an interrupted capture is expired, so it can no longer be paid.
+// @Tags         payment-intents
+// @Accept       json
+// @Produce      json
+// @Param        id    query  string                true   "Payment intent ID"
+// @Param        void  body   paymentIntentVoidReq  false  "Reason"
+// @Success      200  {object}  paymentIntent
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /payment-intents/void [post]
+func VoidPaymentIntentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req paymentIntentVoidReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	merchantID, id := merchantIDFromContext(r.Context()), pathID(r)
+	if _, ok := openPaymentIntent(ctx, w, merchantID, id); !ok {
+		return
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		UPDATE payment_intents SET status = ?, void_reason = ?, voided_at = ?, updated_at = ?
+		WHERE id = ? AND merchant_id = ? AND status = ?
+	`, intentVoided, optionalString(strings.TrimSpace(req.Reason)), now, now, id, merchantID, intentRequiresCapture)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusConflict, CodePaymentIntentFinalized, "")
+		return
+	}
+	if err := expireIntentOrder(ctx, tx, merchantID, id); err != nil {
+		serverErr(w, err)
+		return
+	}
+	pi, err := getPaymentIntent(ctx, tx, merchantID, id)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := enqueueEvent(ctx, tx, merchantID, "payment_intent", pi.ID, webhookPaymentIntentVoided, pi); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pi)
+}
+
+// openPaymentIntent loads an intent that can still change, writing 404 or 409 otherwise.
+func openPaymentIntent(ctx context.Context, w http.ResponseWriter, merchantID, id string) (paymentIntent, bool) {
+	pi, err := getPaymentIntent(ctx, db, merchantID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodePaymentIntentNotFound, "")
+		return paymentIntent{}, false
+	}
+	if err != nil {
+		serverErr(w, err)
+		return paymentIntent{}, false
+	}
+	if pi.Status != intentRequiresCapture {
+		writeProblem(w, http.StatusConflict, CodePaymentIntentFinalized, "the intent is "+pi.Status)
+		return paymentIntent{}, false
+	}
+	return pi, true
+}
+
+// expireIntentOrder expires the unpaid order a capture of the intent created, if any, for an intent
+// that ended up voided.
+func expireIntentOrder(ctx context.Context, tx *sql.Tx, merchantID, intentID string) error {
+	o, err := txStores(tx).Orders.GetByIdempotencyKey(ctx, merchantID, intentID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE orders SET status = 'EXPIRED', expired_at = ? WHERE id = ? AND status = 'PENDING'`,
+		time.Now().UTC().Format(time.RFC3339), o.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	return enqueueOrderEvent(ctx, tx, webhookOrderExpired, o.ID)
+}
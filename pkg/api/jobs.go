@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/jobs"
+)
+
+// jobQueue runs the background work: queued jobs such as payment verification, and the
+// schedulers. It is created by InitStores.
+var jobQueue *jobs.Queue
+
+// jobRetention is how long SUCCEEDED jobs are kept before the pruner deletes them; DEAD jobs stay
+// until retried.
+const jobRetention = 7 * 24 * time.Hour
+
+// StopJobs stops the schedulers and job workers, waiting until ctx is done for the runs in
+// progress. Call it on shutdown; jobs it leaves unfinished are taken again once their lease runs
+// out.
+func StopJobs(ctx context.Context) error {
+	if jobQueue == nil {
+		return nil
+	}
+	return jobQueue.Stop(ctx)
+}
+
+// StartJobsPruner deletes SUCCEEDED jobs older than a week every interval.
+func StartJobsPruner(interval time.Duration) {
+	startScheduler(schedulerJobsPruner, interval, false, func(ctx context.Context) (int, error) {
+		return jobQueue.Prune(ctx, time.Now().Add(-jobRetention))
+	})
+}
+
+type jobRecord struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Status     string          `json:"status"` // PENDING, RUNNING, SUCCEEDED or DEAD
+	Payload    json.RawMessage `json:"payload" swaggertype:"object"`
+	Attempts   int             `json:"attempts"`
+	RunAt      string          `json:"run_at"` // not before; the next retry of a failed job
+	LockedBy   *string         `json:"locked_by"`
+	LastError  *string         `json:"last_error"`
+	CreatedAt  string          `json:"created_at"`
+	UpdatedAt  string          `json:"updated_at"`
+	FinishedAt *string         `json:"finished_at"`
+}
+
+func toJobRecord(j jobs.Info) jobRecord {
+	return jobRecord{
+		ID: j.ID, Type: j.Type, Status: j.Status, Payload: j.Payload, Attempts: j.Attempts, RunAt: j.RunAt,
+		LockedBy: j.LockedBy, LastError: j.LastError, CreatedAt: j.CreatedAt, UpdatedAt: j.UpdatedAt, FinishedAt: j.FinishedAt,
+	}
+}
+
+// AdminJobsHandler godoc
+// @Summary      List background jobs
+// @Description  Returns queued background jobs, most recently updated first, optionally filtered by type (e.g. payment_verification) and status (PENDING, RUNNING, SUCCEEDED, DEAD), with their payload, attempts, next run and last error. DEAD jobs failed permanently or ran out of attempts; retry them with POST /admin/jobs/retry. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        type    query  string  false  "Job type"
+// @Param        status  query  string  false  "Status"
+// @Param        limit   query  int     false  "Page size (default 100, max 500)"
+// @Success      200  {array}   jobRecord
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/jobs [get]
+func AdminJobsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	f := jobs.Filter{Type: q.Get("type"), Status: strings.ToUpper(q.Get("status"))}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			badReq(w, "limit must be between 1 and 500")
+			return
+		}
+		f.Limit = n
+	}
+	list, err := jobQueue.List(r.Context(), f)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	out := make([]jobRecord, 0, len(list))
+	for _, j := range list {
+		out = append(out, toJobRecord(j))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// RetryJobHandler godoc
+// @Summary      Retry a dead job
+// @Description  Queues a DEAD job again to run now, with its attempts counted from zero. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Job ID"
+// @Success      200  {object}  jobRecord
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/jobs/retry [post]
+func RetryJobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing job id")
+		return
+	}
+	switch err := jobQueue.Retry(r.Context(), id); {
+	case errors.Is(err, jobs.ErrNotFound):
+		writeProblem(w, http.StatusNotFound, CodeJobNotFound, "")
+		return
+	case errors.Is(err, jobs.ErrNotDead):
+		writeProblem(w, http.StatusConflict, CodeJobNotDead, "")
+		return
+	case err != nil:
+		serverErr(w, err)
+		return
+	}
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), "", "", "job_retry", map[string]any{"job_id": id})
+	job, err := jobQueue.Get(r.Context(), id)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toJobRecord(job))
+}
+
+// writeJobMetrics writes the queue depth of every job type, by status, and this instance's
+// attempts by outcome.
+func writeJobMetrics(ctx context.Context, b *strings.Builder) {
+	if jobQueue == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	stats, err := jobQueue.Stats(ctx)
+	if err != nil {
+		log.Printf("event=job_stats_failed err=%v", err)
+		return
+	}
+	b.WriteString("# HELP ospay_jobs Queued background jobs by type and status.\n# TYPE ospay_jobs gauge\n")
+	for _, s := range stats {
+		fmt.Fprintf(b, "ospay_jobs{type=%q,status=\"pending\"} %d\n", s.Type, s.Pending)
+		fmt.Fprintf(b, "ospay_jobs{type=%q,status=\"running\"} %d\n", s.Type, s.Running)
+		fmt.Fprintf(b, "ospay_jobs{type=%q,status=\"dead\"} %d\n", s.Type, s.Dead)
+	}
+	b.WriteString("# HELP ospay_jobs_oldest_due_seconds How long the oldest due job of a type has waited for a worker.\n# TYPE ospay_jobs_oldest_due_seconds gauge\n")
+	for _, s := range stats {
+		fmt.Fprintf(b, "ospay_jobs_oldest_due_seconds{type=%q} %g\n", s.Type, s.OldestDue.Seconds())
+	}
+	b.WriteString("# HELP ospay_job_attempts_total Job attempts by type and outcome.\n# TYPE ospay_job_attempts_total counter\n")
+	for _, s := range stats {
+		fmt.Fprintf(b, "ospay_job_attempts_total{type=%q,outcome=\"succeeded\"} %d\n", s.Type, s.SucceededTotal)
+		fmt.Fprintf(b, "ospay_job_attempts_total{type=%q,outcome=\"retried\"} %d\n", s.Type, s.RetriedTotal)
+		fmt.Fprintf(b, "ospay_job_attempts_total{type=%q,outcome=\"dead\"} %d\n", s.Type, s.DeadTotal)
+	}
+}
+
+// jobBacklog returns the PENDING and DEAD jobs of every type, for /debug/metrics, or -1s should
+// the queue be unreadable.
+func jobBacklog(ctx context.Context) (pending, dead int64) {
+	if jobQueue == nil {
+		return -1, -1
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	stats, err := jobQueue.Stats(ctx)
+	if err != nil {
+		return -1, -1
+	}
+	for _, s := range stats {
+		pending += s.Pending
+		dead += s.Dead
+	}
+	return pending, dead
+}
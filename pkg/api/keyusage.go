@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// API key usage is kept per key and source IP in api_key_usage, for merchants to spot keys used
+// from where they should not be, and operators to find keys nobody uses. Uses collect in memory and
+// are added to the table with the counters (FlushCounters).
+type keyUse struct {
+	merchantID, keyID, ip string
+}
+
+type keyUseCount struct {
+	n           int64
+	first, last string
+}
+
+var (
+	keyUseMu      sync.Mutex
+	keyUses       = map[keyUse]*keyUseCount{}
+	trustForwards bool
+)
+
+// SetTrustForwardedFor makes the source IP of API key usage and of the failed-login guard the last
+// address of X-Forwarded-For, for servers behind a proxy that appends it. Otherwise it is the
+// address of the connection.
+func SetTrustForwardedFor(on bool) {
+	keyUseMu.Lock()
+	defer keyUseMu.Unlock()
+	trustForwards = on
+}
+
+// clientIP returns the address a request came from. Of X-Forwarded-For only the last address is
+// used, the one the proxy appended: the client can send the header with any addresses of its own.
+func clientIP(r *http.Request) string {
+	keyUseMu.Lock()
+	forwards := trustForwards
+	keyUseMu.Unlock()
+	if forwards {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			hops := strings.Split(values[len(values)-1], ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// recordKeyUse counts a request authenticated with an API key; credential is "key:primary" or
+// "key:<id>". OAuth tokens are not counted.
+func recordKeyUse(r *http.Request, merchantID, credential string) {
+	keyID, ok := strings.CutPrefix(credential, "key:")
+	if !ok {
+		return
+	}
+	use := keyUse{merchantID: merchantID, keyID: keyID, ip: clientIP(r)}
+	now := time.Now().UTC().Format(time.RFC3339)
+	keyUseMu.Lock()
+	defer keyUseMu.Unlock()
+	c := keyUses[use]
+	if c == nil {
+		c = &keyUseCount{first: now}
+		keyUses[use] = c
+	}
+	c.n++
+	c.last = now
+}
+
+// flushKeyUsage adds the uses collected since the last flush to api_key_usage. Uses that could not
+// be written are kept for the next flush.
+func flushKeyUsage(ctx context.Context) error {
+	keyUseMu.Lock()
+	pending := keyUses
+	keyUses = map[keyUse]*keyUseCount{}
+	keyUseMu.Unlock()
+	for use, c := range pending {
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO api_key_usage (merchant_id, key_id, ip, request_count, first_used_at, last_used_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (merchant_id, key_id, ip) DO UPDATE SET
+				request_count = request_count + excluded.request_count,
+				last_used_at = MAX(last_used_at, excluded.last_used_at)
+		`, use.merchantID, use.keyID, use.ip, c.n, c.first, c.last); err != nil {
+			keyUseMu.Lock()
+			for use, c := range pending {
+				if cur := keyUses[use]; cur != nil {
+					cur.n += c.n
+					cur.first = min(cur.first, c.first)
+				} else {
+					keyUses[use] = c
+				}
+			}
+			keyUseMu.Unlock()
+			return err
+		}
+		delete(pending, use)
+	}
+	return nil
+}
+
+// keySource is one IP an API key was used from.
+type keySource struct {
+	IP           string `json:"ip"`
+	RequestCount int64  `json:"request_count"`
+	FirstUsedAt  string `json:"first_used_at"`
+	LastUsedAt   string `json:"last_used_at"`
+}
+
+// apiKeyUsage is an API key with how it has been used.
+type apiKeyUsage struct {
+	ID           string      `json:"id"` // "primary" for the merchant's own key
+	Label        string      `json:"label"`
+	Scope        string      `json:"scope"`
+	CreatedAt    string      `json:"created_at"`
+	RevokedAt    *string     `json:"revoked_at,omitempty"`
+	LastUsedAt   *string     `json:"last_used_at"` // null if never used
+	LastUsedIP   *string     `json:"last_used_ip"`
+	RequestCount int64       `json:"request_count"`
+	Sources      []keySource `json:"sources"` // the most recently seen IPs, at most maxKeySources
+}
+
+const maxKeySources = 10
+
+// MerchantAPIKeyUsageHandler godoc
+// @Summary      List API keys with their usage
+// @Description  Lists the merchant's API keys, its primary key (id "primary") first, with when each was last used, from which IP, and how many requests it made, along with the IPs it was used from most recently (at most 10). A key used from an unexpected IP may have leaked; one not used for long may be forgotten and is better revoked. Counts are kept from when usage tracking was introduced and may trail by some seconds. Requires the primary API key.
+// @Tags         merchants
+// @Produce      json
+// @Success      200  {array}   apiKeyUsage
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/me/api-keys [get]
+func MerchantAPIKeyUsageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "API key usage can only be viewed with the primary merchant API key")
+		return
+	}
+	merchantID := merchantIDFromContext(r.Context())
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpReport)
+	defer cancel()
+	if err := flushKeyUsage(ctx); err != nil {
+		serverErr(w, err)
+		return
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT 'primary', 'primary', ?, created_at, NULL, 0 FROM merchants WHERE id = ?
+		UNION ALL
+		SELECT id, label, scope, created_at, revoked_at, 1 FROM api_keys WHERE merchant_id = ?
+		ORDER BY 6, 4, 1
+	`, scopeAll, merchantID, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	keys := []apiKeyUsage{}
+	index := map[string]int{}
+	for rows.Next() {
+		var (
+			k         apiKeyUsage
+			revokedAt sql.NullString
+			order     int
+		)
+		if err := rows.Scan(&k.ID, &k.Label, &k.Scope, &k.CreatedAt, &revokedAt, &order); err != nil {
+			rows.Close()
+			serverErr(w, err)
+			return
+		}
+		k.RevokedAt, k.Sources = nullStringPtr(revokedAt), []keySource{}
+		index[k.ID] = len(keys)
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT key_id, ip, request_count, first_used_at, last_used_at FROM api_key_usage
+		WHERE merchant_id = ? ORDER BY last_used_at DESC, ip
+	`, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var keyID string
+		var s keySource
+		if err := rows.Scan(&keyID, &s.IP, &s.RequestCount, &s.FirstUsedAt, &s.LastUsedAt); err != nil {
+			serverErr(w, err)
+			return
+		}
+		i, ok := index[keyID]
+		if !ok {
+			continue
+		}
+		k := &keys[i]
+		if k.LastUsedAt == nil {
+			k.LastUsedAt, k.LastUsedIP = &s.LastUsedAt, &s.IP
+		}
+		k.RequestCount += s.RequestCount
+		if len(k.Sources) < maxKeySources {
+			k.Sources = append(k.Sources, s)
+		}
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// dormantKey is an active API key not used for a while.
+type dormantKey struct {
+	MerchantID   string  `json:"merchant_id"`
+	KeyID        string  `json:"key_id"` // "primary" for the merchant's own key
+	Label        string  `json:"label"`
+	CreatedAt    string  `json:"created_at"`
+	LastUsedAt   *string `json:"last_used_at"` // null if never used
+	RequestCount int64   `json:"request_count"`
+}
+
+// AdminDormantAPIKeysHandler godoc
+// @Summary      List dormant API keys
+// @Description  Lists the API keys, primary and scoped, that are not revoked and have not been used for unused_days (default 90): those last used before then, and those created before then and never used. Least recently used first; at most 500. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        unused_days  query  int  false  "Days without use"
+// @Success      200  {array}   dormantKey
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/api-keys/dormant [get]
+func AdminDormantAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	days := 90
+	if v := r.URL.Query().Get("unused_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			badReq(w, "unused_days must be a positive number of days")
+			return
+		}
+		days = n
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpReport)
+	defer cancel()
+	if err := flushKeyUsage(ctx); err != nil {
+		serverErr(w, err)
+		return
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -days).Format(time.RFC3339)
+	rows, err := db.QueryContext(ctx, `
+		SELECT k.merchant_id, k.key_id, k.label, k.created_at, MAX(u.last_used_at), COALESCE(SUM(u.request_count), 0)
+		FROM (
+			SELECT id AS merchant_id, 'primary' AS key_id, 'primary' AS label, created_at FROM merchants WHERE deleted_at IS NULL
+			UNION ALL
+			SELECT merchant_id, id, label, created_at FROM api_keys WHERE revoked_at IS NULL
+		) k
+		LEFT JOIN api_key_usage u ON u.merchant_id = k.merchant_id AND u.key_id = k.key_id
+		GROUP BY k.merchant_id, k.key_id, k.label, k.created_at
+		HAVING COALESCE(MAX(u.last_used_at), k.created_at) < ?
+		ORDER BY COALESCE(MAX(u.last_used_at), ''), k.created_at
+		LIMIT 500
+	`, cutoff)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	keys := []dormantKey{}
+	for rows.Next() {
+		var k dormantKey
+		var last sql.NullString
+		if err := rows.Scan(&k.MerchantID, &k.KeyID, &k.Label, &k.CreatedAt, &last, &k.RequestCount); err != nil {
+			serverErr(w, err)
+			return
+		}
+		k.LastUsedAt = nullStringPtr(last)
+		keys = append(keys, k)
+	}
+	writeJSONOrders(w, http.StatusOK, keys)
+}
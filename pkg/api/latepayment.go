@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Orders that time out unpaid become EXPIRED. A payment that still arrives within the grace window
+// is credited as usual, or parked in LATE_PAYMENT for review if the merchant asked for that.
+const (
+	statusExpired     = "EXPIRED"
+	statusLatePayment = "LATE_PAYMENT"
+)
+
+// latePaymentGrace is how long after expiry a payment is still accepted; 0 turns late payments away.
+var latePaymentGrace = 24 * time.Hour
+
+// SetLatePaymentGrace is called from main.go with LATE_PAYMENT_GRACE.
+func SetLatePaymentGrace(d time.Duration) { latePaymentGrace = d }
+
+// checkLatePayment decides what to do with a payment for an EXPIRED order: allowed is false once
+// the grace window has passed, and review reports whether the merchant holds late payments for
+// review instead of crediting them.
+func checkLatePayment(ctx context.Context, q queryer, orderID string) (allowed, review bool, err error) {
+	var expiredAt sql.NullString
+	err = q.QueryRowContext(ctx, `
+		SELECT o.expired_at, m.late_payment_review
+		FROM orders o JOIN merchants m ON m.id = o.merchant_id
+		WHERE o.id = ?
+	`, orderID).Scan(&expiredAt, &review)
+	if err != nil {
+		return false, false, err
+	}
+	if latePaymentGrace <= 0 || !expiredAt.Valid {
+		return false, review, nil
+	}
+	t, err := time.Parse(time.RFC3339, expiredAt.String)
+	if err != nil {
+		return false, review, nil
+	}
+	return time.Since(t) <= latePaymentGrace, review, nil
+}
+
+// holdLatePayment routes an accepted late payment to LATE_PAYMENT, keeping any risk findings.
+func holdLatePayment(a *riskAssessment) {
+	const reason = "payment arrived after the order expired"
+	a.Status = statusLatePayment
+	if a.Reason.Valid {
+		a.Reason.String = reason + "; " + a.Reason.String
+	} else {
+		a.Reason = sql.NullString{String: reason, Valid: true}
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ledgerExportFlushRows is how many entries are written between flushes of an export.
+const ledgerExportFlushRows = 500
+
+// ledgerExportLine is one line of /ledger/export.ndjson.
+type ledgerExportLine struct {
+	ID          string  `json:"id"`
+	MerchantID  string  `json:"merchant_id"`
+	OrderID     *string `json:"order_id"`
+	Asset       string  `json:"asset"`
+	Chain       *string `json:"chain"`
+	AmountMinor string  `json:"amount_minor"`
+	Bucket      string  `json:"bucket"`
+	Direction   string  `json:"direction"` // credit or debit
+	EventType   string  `json:"event_type"`
+	TxHash      *string `json:"tx_hash"`
+	ReferenceID *string `json:"reference_id"`  // refund, batch, conversion, ... that produced the entry
+	RateQuoteID *string `json:"rate_quote_id"` // rate quote of a fiat-priced order, see /rates/history
+	CreatedAt   string  `json:"created_at"`
+	// TransactionGroupID is shared by the legs of one double entry, see /ledger/transactions
+	TransactionGroupID *string `json:"transaction_group_id"`
+}
+
+const ledgerLineCols = `id, merchant_id, order_id, asset, chain, amount_minor, bucket, direction, event_type, tx_hash, reference_id, rate_quote_id,
+	created_at, transaction_group_id`
+
+func scanLedgerLine(row scanner) (ledgerExportLine, error) {
+	var (
+		e                                                       ledgerExportLine
+		orderID, chain, txHash, referenceID, rateQuoteID, group sql.NullString
+	)
+	err := row.Scan(&e.ID, &e.MerchantID, &orderID, &e.Asset, &chain, &e.AmountMinor, &e.Bucket, &e.Direction, &e.EventType,
+		&txHash, &referenceID, &rateQuoteID, &e.CreatedAt, &group)
+	e.OrderID, e.Chain, e.TxHash, e.ReferenceID = nullStringPtr(orderID), nullStringPtr(chain), nullStringPtr(txHash), nullStringPtr(referenceID)
+	e.RateQuoteID, e.TransactionGroupID = nullStringPtr(rateQuoteID), nullStringPtr(group)
+	return e, err
+}
+
+// LedgerExportHandler godoc
+// @Summary      Export ledger entries
+// @Description  Streams the merchant's ledger entries as newline-delimited JSON, one entry per line, with the transaction group linking the legs of its double entry, oldest first (by created_at, then id), for loading into a data warehouse. Entries are read and written one at a time, so exports of any size take no memory on the server and go as fast as the client reads. from and to (RFC 3339) bound created_at to [from, to); asset and event_type narrow the export further. Entries moved out by the retention job are represented by their BALANCE_CARRIED entries. To resume an interrupted export, pass the created_at of the last line received as from and skip the lines already received. An export that fails midway is cut off without the final chunk, so it cannot be mistaken for a complete one. Admins pass merchant_id, or leave it out for every merchant.
+// @Tags         reconciliation
+// @Produce      application/x-ndjson
+// @Param        from         query  string  false  "Earliest created_at, RFC 3339"
+// @Param        to           query  string  false  "Created before, RFC 3339"
+// @Param        asset        query  string  false  "Asset symbol"
+// @Param        event_type   query  string  false  "Event type, e.g. PAYMENT_CONFIRMED"
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {object}  ledgerExportLine
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /ledger/export.ndjson [get]
+// @Router       /admin/ledger/export.ndjson [get]
+func LedgerExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	ctx := r.Context()
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(ctx)
+	if isAdmin(ctx) {
+		merchantID = q.Get("merchant_id")
+	}
+	var from, to string
+	for _, b := range []struct {
+		name string
+		out  *string
+	}{{"from", &from}, {"to", &to}} {
+		v := q.Get(b.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, b.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*b.out = t.UTC().Format(time.RFC3339)
+	}
+	if from != "" && to != "" && to <= from {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be after from")
+		return
+	}
+	rows, err := reportDB.QueryContext(ctx, `
+		SELECT `+ledgerLineCols+`
+		FROM ledger_entries
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR created_at >= ?) AND (? = '' OR created_at < ?)
+		  AND (? = '' OR asset = ?) AND (? = '' OR event_type = ?)
+		ORDER BY created_at, id
+	`, merchantID, merchantID, from, from, to, to, q.Get("asset"), q.Get("asset"), q.Get("event_type"), q.Get("event_type"))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+
+	// The export lasts as long as the client takes to read it
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	n := 0
+	for rows.Next() {
+		var e ledgerExportLine
+		if e, err = scanLedgerLine(rows); err != nil {
+			break
+		}
+		if err = enc.Encode(e); err != nil {
+			break
+		}
+		if n++; n%ledgerExportFlushRows == 0 {
+			if err = bw.Flush(); err != nil {
+				break
+			}
+			_ = rc.Flush()
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		if ctx.Err() == nil && !strings.Contains(err.Error(), "broken pipe") {
+			log.Printf("event=ledger_export_failed merchant_id=%s rows=%d err=%v", merchantID, n, err)
+		}
+		// Drop the connection so the client sees an incomplete export rather than a short one
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// ledgerTransaction is one transaction group of the ledger: the legs of a double entry, fee legs
+// included, which net to zero on each asset and chain.
+type ledgerTransaction struct {
+	TransactionGroupID string             `json:"transaction_group_id"`
+	MerchantID         string             `json:"merchant_id"`
+	EventType          string             `json:"event_type"`
+	CreatedAt          string             `json:"created_at"`
+	Entries            []ledgerExportLine `json:"entries"`
+}
+
+// groupLedgerLines gathers lines into transactions in the order their groups first appear.
+func groupLedgerLines(lines []ledgerExportLine) []ledgerTransaction {
+	out := []ledgerTransaction{}
+	index := map[string]int{}
+	for _, e := range lines {
+		group := e.ID
+		if e.TransactionGroupID != nil {
+			group = *e.TransactionGroupID
+		}
+		i, ok := index[group]
+		if !ok {
+			i = len(out)
+			index[group] = i
+			out = append(out, ledgerTransaction{TransactionGroupID: group, MerchantID: e.MerchantID, EventType: e.EventType, CreatedAt: e.CreatedAt})
+		}
+		out[i].Entries = append(out[i].Entries, e)
+	}
+	return out
+}
+
+// ListLedgerTransactionsHandler godoc
+// @Summary      List ledger transactions
+// @Description  Lists the merchant's ledger entries grouped into transactions, newest first: each transaction holds the legs of one double entry (a payment with its application fee leg, a refund, a settlement of a batch, ...), which net to zero on each asset and chain. from and to (RFC 3339) bound created_at to [from, to); event_type, order_id and reference_id (the refund, batch, conversion, ... that booked it) narrow the list, limit (default 100, at most 500) bounds the transactions returned. Entries moved out by the retention job are not listed. Admins pass merchant_id, or leave it out for every merchant.
+// @Tags         reconciliation
+// @Produce      json
+// @Param        from          query  string  false  "Earliest created_at, RFC 3339"
+// @Param        to            query  string  false  "Created before, RFC 3339"
+// @Param        event_type    query  string  false  "Event type, e.g. PAYMENT_CONFIRMED"
+// @Param        order_id      query  string  false  "Order ID"
+// @Param        reference_id  query  string  false  "Refund, batch, conversion, ... that booked the transaction"
+// @Param        limit         query  int     false  "Transactions to return, at most 500"
+// @Param        merchant_id   query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {array}   ledgerTransaction
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /ledger/transactions [get]
+// @Router       /admin/ledger/transactions [get]
+func ListLedgerTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = q.Get("merchant_id")
+	}
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidLimit, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+	var from, to string
+	for _, b := range []struct {
+		name string
+		out  *string
+	}{{"from", &from}, {"to", &to}} {
+		v := q.Get(b.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, b.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*b.out = t.UTC().Format(time.RFC3339)
+	}
+	if from != "" && to != "" && to <= from {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be after from")
+		return
+	}
+	eventType, orderID, referenceID := strings.ToUpper(q.Get("event_type")), q.Get("order_id"), q.Get("reference_id")
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpReport)
+	defer cancel()
+	rows, err := reportDB.QueryContext(ctx, `
+		SELECT `+ledgerLineCols+` FROM ledger_entries
+		WHERE transaction_group_id IN (
+		  SELECT transaction_group_id FROM ledger_entries
+		  WHERE (? = '' OR merchant_id = ?) AND (? = '' OR created_at >= ?) AND (? = '' OR created_at < ?)
+		    AND (? = '' OR event_type = ?) AND (? = '' OR order_id = ?) AND (? = '' OR reference_id = ?)
+		  GROUP BY transaction_group_id
+		  ORDER BY MAX(created_at) DESC, transaction_group_id DESC
+		  LIMIT ?
+		) AND (? = '' OR merchant_id = ?)
+		ORDER BY created_at DESC, transaction_group_id DESC, id
+	`, merchantID, merchantID, from, from, to, to, eventType, eventType, orderID, orderID, referenceID, referenceID, limit, merchantID, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	var lines []ledgerExportLine
+	for rows.Next() {
+		e, err := scanLedgerLine(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		lines = append(lines, e)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, groupLedgerLines(lines))
+}
+
+// GetLedgerTransactionHandler godoc
+// @Summary      Get a ledger transaction
+// @Description  Returns the legs of one ledger transaction group, as linked by the transaction_group_id of ledger entries and exports.
+// @Tags         reconciliation
+// @Produce      json
+// @Param        id  query  string  true  "Transaction group ID"
+// @Success      200  {object}  ledgerTransaction
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /ledger/transactions/get [get]
+// @Router       /admin/ledger/transactions/get [get]
+func GetLedgerTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing transaction group id")
+		return
+	}
+	merchantID := merchantIDFromContext(r.Context())
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+ledgerLineCols+` FROM ledger_entries
+		WHERE transaction_group_id = ? AND (? = '' OR merchant_id = ?)
+		ORDER BY id
+	`, id, merchantID, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	var lines []ledgerExportLine
+	for rows.Next() {
+		e, err := scanLedgerLine(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		lines = append(lines, e)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if len(lines) == 0 {
+		writeProblem(w, http.StatusNotFound, CodeLedgerTransactionNotFound, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, groupLedgerLines(lines)[0])
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// merchantLimits are the per-merchant velocity limits; nil / 0 means unlimited.
+type merchantLimits struct {
+	MaxOrderAmount         *big.Int
+	MaxDailyVolume         *big.Int // per asset, per day in the merchant's timezone
+	MaxWalletOrdersPerHour int64    // per customer wallet
+	Location               *time.Location
+}
+
+// limitError is returned when an order or payment would exceed one of the merchant's limits.
+type limitError struct {
+	Limit string // max_order_amount | max_daily_volume | max_wallet_orders_per_hour
+	Msg   string
+}
+
+func (e *limitError) Error() string { return e.Limit + " exceeded: " + e.Msg }
+
+func loadMerchantLimits(ctx context.Context, q queryer, merchantID string) (merchantLimits, error) {
+	var (
+		l                   merchantLimits
+		maxOrder, maxDaily  sql.NullString
+		maxWalletOrdersHour sql.NullInt64
+		tz                  sql.NullString
+	)
+	err := q.QueryRowContext(ctx, `
+		SELECT max_order_amount_minor, max_daily_volume_minor, max_wallet_orders_per_hour, timezone FROM merchants WHERE id = ?
+	`, merchantID).Scan(&maxOrder, &maxDaily, &maxWalletOrdersHour, &tz)
+	if err != nil {
+		return l, err
+	}
+	if maxOrder.Valid {
+		if v, ok := new(big.Int).SetString(maxOrder.String, 10); ok {
+			l.MaxOrderAmount = v
+		}
+	}
+	if maxDaily.Valid {
+		if v, ok := new(big.Int).SetString(maxDaily.String, 10); ok {
+			l.MaxDailyVolume = v
+		}
+	}
+	l.MaxWalletOrdersPerHour = maxWalletOrdersHour.Int64
+	l.Location = parseLocation(tz.String)
+	return l, nil
+}
+
+// sumAmounts adds up amount_minor values returned by query as big integers.
+func sumAmounts(ctx context.Context, q queryer, query string, args ...any) (*big.Int, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	total := new(big.Int)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, errors.New("invalid amount_minor format")
+		}
+		total.Add(total, v)
+	}
+	return total, rows.Err()
+}
+
+// checkOrderLimits enforces the merchant's limits when an order is created. Daily volume counts
+// every order created today (in the merchant's timezone) that has not failed; the wallet limit only applies when the customer
+// wallet is known up front.
+func checkOrderLimits(ctx context.Context, q queryer, merchantID, asset, amountMinor, wallet string) error {
+	l, err := loadMerchantLimits(ctx, q, merchantID)
+	if err != nil {
+		return err
+	}
+	amount, ok := new(big.Int).SetString(amountMinor, 10)
+	if !ok {
+		return errors.New("invalid amount_minor format")
+	}
+	if l.MaxOrderAmount != nil && amount.Cmp(l.MaxOrderAmount) > 0 {
+		return &limitError{Limit: "max_order_amount", Msg: fmt.Sprintf("order amount %s is above the limit of %s", amount, l.MaxOrderAmount)}
+	}
+	now := time.Now().UTC()
+	if l.MaxDailyVolume != nil {
+		today, err := sumAmounts(ctx, q, `
+			SELECT amount_minor FROM orders WHERE merchant_id = ? AND asset = ? AND created_at >= ? AND status NOT IN ('FAILED', 'EXPIRED') AND deleted_at IS NULL
+		`, merchantID, asset, localDayStart(now, l.Location))
+		if err != nil {
+			return err
+		}
+		if today.Add(today, amount).Cmp(l.MaxDailyVolume) > 0 {
+			return &limitError{Limit: "max_daily_volume", Msg: fmt.Sprintf("today's %s order volume would reach %s, above the limit of %s", asset, today, l.MaxDailyVolume)}
+		}
+	}
+	if l.MaxWalletOrdersPerHour > 0 && wallet != "" {
+		var n int64
+		if err := q.QueryRowContext(ctx, `
+			SELECT COUNT(1) FROM orders WHERE merchant_id = ? AND customer_wallet_address = ? COLLATE NOCASE AND created_at >= ? AND deleted_at IS NULL
+		`, merchantID, wallet, now.Add(-time.Hour).Format(time.RFC3339)).Scan(&n); err != nil {
+			return err
+		}
+		if n >= l.MaxWalletOrdersPerHour {
+			return &limitError{Limit: "max_wallet_orders_per_hour", Msg: fmt.Sprintf("wallet %s already has %d orders in the last hour (limit %d)", wallet, n, l.MaxWalletOrdersPerHour)}
+		}
+	}
+	return nil
+}
+
+// checkPaymentLimits re-checks the limits against confirmed payments. By then the funds have
+// moved on-chain, so callers hold the payment for review instead of rejecting it.
+func checkPaymentLimits(ctx context.Context, q queryer, orderID, merchantID, asset, amountMinor, payer string) error {
+	l, err := loadMerchantLimits(ctx, q, merchantID)
+	if err != nil {
+		return err
+	}
+	amount, ok := new(big.Int).SetString(amountMinor, 10)
+	if !ok {
+		return errors.New("invalid amount_minor format")
+	}
+	if l.MaxOrderAmount != nil && amount.Cmp(l.MaxOrderAmount) > 0 {
+		return &limitError{Limit: "max_order_amount", Msg: fmt.Sprintf("payment amount %s is above the limit of %s", amount, l.MaxOrderAmount)}
+	}
+	now := time.Now().UTC()
+	if l.MaxDailyVolume != nil {
+		today, err := sumAmounts(ctx, q, `
+			SELECT amount_minor FROM orders
+			WHERE merchant_id = ? AND asset = ? AND id != ? AND paid_at >= ? AND status IN ('PAID','PARTIALLY_REFUNDED','REFUNDED','SETTLED')
+		`, merchantID, asset, orderID, localDayStart(now, l.Location))
+		if err != nil {
+			return err
+		}
+		if today.Add(today, amount).Cmp(l.MaxDailyVolume) > 0 {
+			return &limitError{Limit: "max_daily_volume", Msg: fmt.Sprintf("today's %s paid volume would reach %s, above the limit of %s", asset, today, l.MaxDailyVolume)}
+		}
+	}
+	if l.MaxWalletOrdersPerHour > 0 && payer != "" {
+		var n int64
+		if err := q.QueryRowContext(ctx, `
+			SELECT COUNT(1) FROM orders WHERE merchant_id = ? AND id != ? AND customer_wallet_address = ? COLLATE NOCASE AND paid_at >= ?
+		`, merchantID, orderID, payer, now.Add(-time.Hour).Format(time.RFC3339)).Scan(&n); err != nil {
+			return err
+		}
+		if n >= l.MaxWalletOrdersPerHour {
+			return &limitError{Limit: "max_wallet_orders_per_hour", Msg: fmt.Sprintf("wallet %s already paid %d orders in the last hour (limit %d)", payer, n, l.MaxWalletOrdersPerHour)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// maxLineItems caps the items of one order.
+const maxLineItems = 100
+
+// lineItem is one purchased product of an order. AmountMinor, quantity times unit amount, is only
+// set in responses.
+type lineItem struct {
+	Name            string `json:"name" validate:"max=200"`
+	Quantity        int64  `json:"quantity"`
+	UnitAmountMinor string `json:"unit_amount_minor"`
+	AmountMinor     string `json:"amount_minor,omitempty"`
+}
+
+// lineItemsTotal validates items and returns what they add up to. An order's amount_minor, when
+// given next to its items, must equal it.
+func lineItemsTotal(items []lineItem) (string, error) {
+	if len(items) > maxLineItems {
+		return "", fmt.Errorf("at most %d line items", maxLineItems)
+	}
+	total := new(big.Int)
+	for i, it := range items {
+		if strings.TrimSpace(it.Name) == "" || len(it.Name) > 200 {
+			return "", fmt.Errorf("line_items[%d]: name is required and at most 200 characters", i)
+		}
+		if it.Quantity < 1 {
+			return "", fmt.Errorf("line_items[%d]: quantity must be at least 1", i)
+		}
+		unit, ok := new(big.Int).SetString(it.UnitAmountMinor, 10)
+		if !ok || unit.Sign() < 0 {
+			return "", fmt.Errorf("line_items[%d]: unit_amount_minor must be a non-negative integer", i)
+		}
+		total.Add(total, unit.Mul(unit, big.NewInt(it.Quantity)))
+	}
+	return total.String(), nil
+}
+
+// resolveLineItems checks items against amountMinor, filling it in from the items when empty.
+func resolveLineItems(items []lineItem, amountMinor *string) error {
+	if len(items) == 0 {
+		return nil
+	}
+	total, err := lineItemsTotal(items)
+	if err != nil {
+		return err
+	}
+	if *amountMinor == "" {
+		*amountMinor = total
+	} else if *amountMinor != total {
+		return errors.New("amount_minor must equal the sum of the line items (" + total + ")")
+	}
+	return nil
+}
+
+// insertLineItems stores an order's items in their given order.
+func insertLineItems(ctx context.Context, tx *sql.Tx, orderID string, items []lineItem) error {
+	for i, it := range items {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO order_line_items (order_id, position, name, quantity, unit_amount_minor) VALUES (?, ?, ?, ?, ?)
+		`, orderID, i, strings.TrimSpace(it.Name), it.Quantity, it.UnitAmountMinor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLineItems returns the items of the given orders, keyed by order ID.
+func loadLineItems(ctx context.Context, q queryer, orderIDs ...string) (map[string][]lineItem, error) {
+	items := map[string][]lineItem{}
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+	args := make([]any, len(orderIDs))
+	for i, id := range orderIDs {
+		args[i] = id
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT order_id, name, quantity, unit_amount_minor FROM order_line_items
+		WHERE order_id IN (?`+strings.Repeat(", ?", len(orderIDs)-1)+`)
+		ORDER BY order_id, position
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			orderID string
+			it      lineItem
+		)
+		if err := rows.Scan(&orderID, &it.Name, &it.Quantity, &it.UnitAmountMinor); err != nil {
+			return nil, err
+		}
+		if unit, ok := new(big.Int).SetString(it.UnitAmountMinor, 10); ok {
+			it.AmountMinor = unit.Mul(unit, big.NewInt(it.Quantity)).String()
+		}
+		items[orderID] = append(items[orderID], it)
+	}
+	return items, rows.Err()
+}
+
+// createOrder inserts o through the order store, or, with line items, together with them.
+func createOrder(ctx context.Context, o store.Order, items []lineItem) error {
+	if len(items) == 0 {
+		return stores.Orders.Create(ctx, o)
+	}
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := txStores(tx).Orders.Create(ctx, o); err != nil {
+		return err
+	}
+	if err := insertLineItems(ctx, tx, o.ID, items); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
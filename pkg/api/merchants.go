@@ -1,11 +1,19 @@
 package api
 
 import (
+	"cmp"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
 )
 
 // MerchantCreateReq is the request body for creating a merchant
@@ -14,8 +22,8 @@ import (
 // @Param name body string true "Merchant name"
 // @Param merchant_wallet_address body string true "Merchant wallet address"
 type MerchantCreateReq struct {
-	Name                  string `json:"name"`
-	MerchantWalletAddress string `json:"merchant_wallet_address"`
+	Name                  string `json:"name" validate:"required,max=200"`
+	MerchantWalletAddress string `json:"merchant_wallet_address" validate:"required"` // an address, or an ENS name
 }
 
 // MerchantCreateResp is the response for merchant creation
@@ -28,55 +36,348 @@ type MerchantCreateResp struct {
 	ID                    string `json:"id"`
 	APIKey                string `json:"api_key"`
 	MerchantWalletAddress string `json:"merchant_wallet_address"`
+	// WalletENSName is the ENS name merchant_wallet_address was resolved from, if one was given.
+	WalletENSName string `json:"wallet_ens_name,omitempty"`
+	// Status is PENDING_APPROVAL when new merchants wait for an administrator; the API key works
+	// once it is ACTIVE.
+	Status string `json:"status"`
 }
 
 // CreateMerchantHandler godoc
 // @Summary      Create a new merchant
-// @Description  Creates a new merchant and returns the merchant ID and API key
+// @Description  Creates a new merchant and returns the merchant ID and API key. merchant_wallet_address must be an EVM (0x..., EIP-55 checksummed when in mixed case), Tron, Solana or Bitcoin address; EVM addresses are stored and returned checksummed. Orders can only be created on chains the wallet's address format belongs to. An ENS name (e.g. shop.eth) is resolved through ETH_RPC_URL; the response carries the resolved address and wallet_ens_name, and the name is re-resolved periodically, moving the wallet when the name is pointed elsewhere (merchant.wallet_changed). With MERCHANT_APPROVAL_REQUIRED on, the merchant is created PENDING_APPROVAL and its API key is refused with 403 merchant_pending_approval, apart from /merchants/status and the webhook settings, until an administrator approves it.
 // @Tags         merchants
 // @Accept       json
 // @Produce      json
 // @Param        merchant  body  MerchantCreateReq  true  "Merchant info"
 // @Success      201  {object}  MerchantCreateResp
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Failure      502  {object}  Problem
 // @Router       /merchants [post]
 func CreateMerchantHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method_not_allowed"})
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
 		return
 	}
 	if db == nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "db_not_initialized"})
+		writeProblem(w, http.StatusInternalServerError, CodeDBNotInitialized, "")
 		return
 	}
 	var req MerchantCreateReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_json"})
+	if !decodeBody(w, r, &req) {
 		return
 	}
-	if req.Name == "" || req.MerchantWalletAddress == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing_fields"})
+	wallet, ensName, err := merchantWallet(r.Context(), req.MerchantWalletAddress)
+	if err != nil {
+		writeWalletProblem(w, err)
 		return
 	}
 	id := uuid.New().String()
 	apiKey := uuid.New().String()
 	now := time.Now().UTC().Format(time.RFC3339)
-	const insert = `INSERT INTO merchants (id, name, api_key, merchant_wallet_address, created_at) VALUES (?, ?, ?, ?, ?)`
-	_, err := db.Exec(insert, id, req.Name, apiKey, req.MerchantWalletAddress, now)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "db_error"})
+	m := store.Merchant{ID: id, Name: req.Name, WalletAddress: wallet, WalletENSName: ensName, Status: newMerchantStatus(), CreatedAt: now}
+	if err := stores.Merchants.Create(r.Context(), m, hashToken(apiKey)); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, "")
 		return
 	}
+	announceApplication(m)
+	noStore(w)
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(MerchantCreateResp{
 		ID:                    id,
 		APIKey:                apiKey,
-		MerchantWalletAddress: req.MerchantWalletAddress,
+		MerchantWalletAddress: wallet,
+		WalletENSName:         ensName,
+		Status:                m.Status,
 	})
 }
+
+type merchantSettings struct {
+	RefundApprovalRequired *bool `json:"refund_approval_required,omitempty"`
+	// LatePaymentReview holds payments for expired orders in LATE_PAYMENT instead of crediting them.
+	LatePaymentReview *bool `json:"late_payment_review,omitempty"`
+	// Velocity limits; "0" / 0 removes the limit. Only an administrator can change them.
+	MaxOrderAmountMinor    *string `json:"max_order_amount_minor,omitempty"`
+	MaxDailyVolumeMinor    *string `json:"max_daily_volume_minor,omitempty"` // per asset, per day in Timezone
+	MaxWalletOrdersPerHour *int64  `json:"max_wallet_orders_per_hour,omitempty"`
+	// PayoutMode sends settlements on-chain: "hot_wallet" or "safe" ("" back to ledger only).
+	// Only an administrator can change it or the Safe address.
+	PayoutMode        *string `json:"payout_mode,omitempty"`
+	PayoutSafeAddress *string `json:"payout_safe_address,omitempty"`
+	// Settlements received in another asset or on another chain are converted before payout
+	// ("" keeps what was received). MaxSlippageBps limits conversion slippage; default 50.
+	SettlementAsset *string `json:"settlement_asset,omitempty"`
+	SettlementChain *string `json:"settlement_chain,omitempty"`
+	MaxSlippageBps  *int64  `json:"max_slippage_bps,omitempty"`
+	// The merchant's customer and bank account at the off-ramp partner, for fiat payouts. Only an
+	// administrator can link them; KYCStatus is read only.
+	OfframpCustomerID    *string `json:"offramp_customer_id,omitempty"`
+	OfframpBankAccountID *string `json:"offramp_bank_account_id,omitempty"`
+	KYCStatus            *string `json:"kyc_status,omitempty"`
+	// Timezone (IANA, e.g. "Europe/Berlin") sets where the merchant's days start for daily limits,
+	// reports and settlement; with one set, orders settle by local calendar day. "" is UTC.
+	Timezone *string `json:"timezone,omitempty" validate:"max=64"`
+	// AcceptedAssets restricts the asset and chain pairs of new orders; empty accepts any.
+	AcceptedAssets *[]acceptedAsset `json:"accepted_assets,omitempty" validate:"max=50"`
+	// ClawbackMaxBps is the most of each settlement withheld while the balance is negative; default
+	// 10000 (all of it), 0 withholds nothing. Only an administrator can change it.
+	ClawbackMaxBps *int64 `json:"clawback_max_bps,omitempty"`
+}
+
+// MerchantSettingsHandler godoc
+// @Summary      Get or update merchant settings
+// @Description  refund_approval_required makes every refund wait for approval by a second credential. Merchants may turn it on with their primary API key; turning it off, and changing velocity limits, requires the admin key (use /admin/merchants/settings?merchant_id=). late_payment_review holds payments that arrive after an order expired (within the grace window) for review instead of crediting them. payout_mode makes settlements pay out on-chain to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes them as transactions of the multisig at payout_safe_address for its owners to execute; "" keeps settlements ledger only. Payout settings require the admin key. settlement_asset and settlement_chain convert settled funds received in another asset or on another chain (e.g. USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default 50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its customer and bank account at the off-ramp partner for fiat payouts (admin key only); kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g. Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles orders by local calendar day: the orders paid on a day settle after local midnight plus the settlement delay. accepted_assets (e.g. [{"asset":"USDT","chain":"BSC"}]) limits new orders to those asset and chain pairs, each a token known on its chain, and orders in any other are rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of each settlement withheld while the merchant's balance on the chain is negative, e.g. after refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the deficit to be netted by new payments only); admin key only.
+// @Tags         merchants
+// @Accept       json
+// @Produce      json
+// @Param        settings     body   merchantSettings  false  "Settings to change (POST only)"
+// @Param        merchant_id  query  string            false  "Merchant ID (admin route only)"
+// @Success      200  {object}  merchantSettings
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/settings [get]
+// @Router       /merchants/settings [post]
+// @Router       /admin/merchants/settings [get]
+// @Router       /admin/merchants/settings [post]
+func MerchantSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	admin := isAdmin(r.Context())
+	merchantID := merchantIDFromContext(r.Context())
+	if admin {
+		merchantID = r.URL.Query().Get("merchant_id")
+	} else if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "merchant settings can only be changed with the primary merchant API key")
+		return
+	}
+
+	var (
+		approval, lateReview bool
+		maxOrder, maxDaily   sql.NullString
+		maxWalletOrders      sql.NullInt64
+		payoutMode, safeAddr sql.NullString
+		toAsset, toChain     sql.NullString
+		slippage             sql.NullInt64
+		customer, bank, kyc  sql.NullString
+		timezone, accepted   sql.NullString
+		clawbackBps          sql.NullInt64
+	)
+	err := db.QueryRowContext(r.Context(), `
+		SELECT refund_approval_required, late_payment_review, max_order_amount_minor, max_daily_volume_minor, max_wallet_orders_per_hour,
+		       payout_mode, payout_safe_address, settlement_asset, settlement_chain, max_slippage_bps,
+		       offramp_customer_id, offramp_bank_account_id, kyc_status, timezone, accepted_assets, clawback_max_bps
+		FROM merchants WHERE id = ?
+	`, merchantID).Scan(&approval, &lateReview, &maxOrder, &maxDaily, &maxWalletOrders, &payoutMode, &safeAddr, &toAsset, &toChain, &slippage,
+		&customer, &bank, &kyc, &timezone, &accepted, &clawbackBps)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeProblem(w, http.StatusNotFound, CodeMerchantNotFound, "merchant not found")
+			return
+		}
+		serverErr(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req merchantSettings
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		if req.RefundApprovalRequired != nil && *req.RefundApprovalRequired != approval {
+			// Switching the control off must not be possible with the same key it protects against
+			if !*req.RefundApprovalRequired && !admin {
+				writeProblem(w, http.StatusForbidden, CodeAdminRequired, "refund approval can only be disabled by an administrator")
+				return
+			}
+			approval = *req.RefundApprovalRequired
+		}
+		if req.LatePaymentReview != nil {
+			lateReview = *req.LatePaymentReview
+		}
+		if req.MaxOrderAmountMinor != nil || req.MaxDailyVolumeMinor != nil || req.MaxWalletOrdersPerHour != nil {
+			if !admin {
+				writeProblem(w, http.StatusForbidden, CodeAdminRequired, "velocity limits can only be changed by an administrator")
+				return
+			}
+			for _, f := range []struct {
+				in  *string
+				out *sql.NullString
+			}{{req.MaxOrderAmountMinor, &maxOrder}, {req.MaxDailyVolumeMinor, &maxDaily}} {
+				if f.in == nil {
+					continue
+				}
+				if *f.in == "0" {
+					*f.out = sql.NullString{}
+					continue
+				}
+				if !isValidAmountString(*f.in) {
+					writeProblem(w, http.StatusBadRequest, CodeInvalidLimit, "limits must be positive integers in minor units")
+					return
+				}
+				*f.out = sql.NullString{String: *f.in, Valid: true}
+			}
+			if req.MaxWalletOrdersPerHour != nil {
+				if *req.MaxWalletOrdersPerHour < 0 {
+					writeProblem(w, http.StatusBadRequest, CodeInvalidLimit, "max_wallet_orders_per_hour must be >= 0")
+					return
+				}
+				maxWalletOrders = sql.NullInt64{Int64: *req.MaxWalletOrdersPerHour, Valid: *req.MaxWalletOrdersPerHour > 0}
+			}
+		}
+		if req.PayoutMode != nil || req.PayoutSafeAddress != nil {
+			if !admin {
+				writeProblem(w, http.StatusForbidden, CodeAdminRequired, "payout settings can only be changed by an administrator")
+				return
+			}
+			if req.PayoutSafeAddress != nil {
+				if *req.PayoutSafeAddress != "" && !common.IsHexAddress(*req.PayoutSafeAddress) {
+					writeProblem(w, http.StatusBadRequest, CodeInvalidPayoutMode, "payout_safe_address must be an EVM address")
+					return
+				}
+				safeAddr = sql.NullString{String: *req.PayoutSafeAddress, Valid: *req.PayoutSafeAddress != ""}
+			}
+			if req.PayoutMode != nil {
+				switch *req.PayoutMode {
+				case "":
+					payoutMode = sql.NullString{}
+				case payoutModeHotWallet, payoutModeSafe:
+					payoutMode = sql.NullString{String: *req.PayoutMode, Valid: true}
+				default:
+					writeProblem(w, http.StatusBadRequest, CodeInvalidPayoutMode, "payout_mode must be hot_wallet, safe or empty")
+					return
+				}
+			}
+			if payoutMode.String == payoutModeSafe && !safeAddr.Valid {
+				writeProblem(w, http.StatusBadRequest, CodeInvalidPayoutMode, "payout_mode safe requires payout_safe_address")
+				return
+			}
+		}
+		if req.SettlementAsset != nil {
+			toAsset = sql.NullString{String: strings.ToUpper(*req.SettlementAsset), Valid: *req.SettlementAsset != ""}
+		}
+		if req.SettlementChain != nil {
+			toChain = sql.NullString{String: strings.ToUpper(*req.SettlementChain), Valid: *req.SettlementChain != ""}
+		}
+		if toChain.Valid && !slices.Contains(blockchain.TokenChains(), toChain.String) {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidSettlementTarget, "unknown chain "+toChain.String)
+			return
+		}
+		if toAsset.Valid && !blockchain.KnownToken(toChain.String, toAsset.String) {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidSettlementTarget, "no "+toAsset.String+" token known on "+cmp.Or(toChain.String, "any chain"))
+			return
+		}
+		if req.MaxSlippageBps != nil {
+			if *req.MaxSlippageBps < 0 || *req.MaxSlippageBps > maxSlippageBps {
+				writeProblem(w, http.StatusBadRequest, CodeInvalidSettlementTarget, "max_slippage_bps must be between 0 and 1000")
+				return
+			}
+			slippage = sql.NullInt64{Int64: *req.MaxSlippageBps, Valid: *req.MaxSlippageBps > 0}
+		}
+		if req.OfframpCustomerID != nil || req.OfframpBankAccountID != nil {
+			if !admin {
+				writeProblem(w, http.StatusForbidden, CodeAdminRequired, "off-ramp accounts can only be linked by an administrator")
+				return
+			}
+			if req.OfframpCustomerID != nil && *req.OfframpCustomerID != customer.String {
+				// A different customer has its own KYC; it is fetched again on the next check.
+				customer = sql.NullString{String: *req.OfframpCustomerID, Valid: *req.OfframpCustomerID != ""}
+				kyc = sql.NullString{}
+			}
+			if req.OfframpBankAccountID != nil {
+				bank = sql.NullString{String: *req.OfframpBankAccountID, Valid: *req.OfframpBankAccountID != ""}
+			}
+		}
+		if req.Timezone != nil {
+			if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "Local" {
+				writeProblem(w, http.StatusBadRequest, CodeInvalidTimezone, "unknown timezone "+*req.Timezone)
+				return
+			}
+			timezone = sql.NullString{String: *req.Timezone, Valid: *req.Timezone != ""}
+		}
+		if req.AcceptedAssets != nil {
+			if accepted, err = formatAcceptedAssets(*req.AcceptedAssets); err != nil {
+				writeProblem(w, http.StatusBadRequest, CodeInvalidAcceptedAssets, err.Error())
+				return
+			}
+		}
+		if req.ClawbackMaxBps != nil {
+			if !admin {
+				writeProblem(w, http.StatusForbidden, CodeAdminRequired, "clawback_max_bps can only be changed by an administrator")
+				return
+			}
+			if *req.ClawbackMaxBps < 0 || *req.ClawbackMaxBps > defaultClawbackBps {
+				badReq(w, "clawback_max_bps must be between 0 and 10000")
+				return
+			}
+			clawbackBps = sql.NullInt64{Int64: *req.ClawbackMaxBps, Valid: *req.ClawbackMaxBps != defaultClawbackBps}
+		}
+		if _, err := db.ExecContext(r.Context(), `
+			UPDATE merchants
+			SET refund_approval_required = ?, late_payment_review = ?, max_order_amount_minor = ?, max_daily_volume_minor = ?, max_wallet_orders_per_hour = ?,
+			    payout_mode = ?, payout_safe_address = ?, settlement_asset = ?, settlement_chain = ?, max_slippage_bps = ?,
+			    offramp_customer_id = ?, offramp_bank_account_id = ?, kyc_status = ?, timezone = ?, accepted_assets = ?, clawback_max_bps = ?
+			WHERE id = ?
+		`, approval, lateReview, maxOrder, maxDaily, maxWalletOrders, payoutMode, safeAddr, toAsset, toChain, slippage,
+			customer, bank, kyc, timezone, accepted, clawbackBps, merchantID); err != nil {
+			serverErr(w, err)
+			return
+		}
+		recordAudit(r.Context(), db, actorFromContext(r.Context()), merchantID, "", "merchant_settings_updated", req)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	resp := merchantSettings{RefundApprovalRequired: &approval, LatePaymentReview: &lateReview}
+	if maxOrder.Valid {
+		resp.MaxOrderAmountMinor = &maxOrder.String
+	}
+	if maxDaily.Valid {
+		resp.MaxDailyVolumeMinor = &maxDaily.String
+	}
+	if maxWalletOrders.Valid {
+		resp.MaxWalletOrdersPerHour = &maxWalletOrders.Int64
+	}
+	if payoutMode.Valid {
+		resp.PayoutMode = &payoutMode.String
+	}
+	if safeAddr.Valid {
+		resp.PayoutSafeAddress = &safeAddr.String
+	}
+	if toAsset.Valid {
+		resp.SettlementAsset = &toAsset.String
+	}
+	if toChain.Valid {
+		resp.SettlementChain = &toChain.String
+	}
+	if customer.Valid {
+		resp.OfframpCustomerID = &customer.String
+	}
+	if bank.Valid {
+		resp.OfframpBankAccountID = &bank.String
+	}
+	if kyc.Valid {
+		resp.KYCStatus = &kyc.String
+	}
+	if timezone.Valid {
+		resp.Timezone = &timezone.String
+	}
+	if accepted.Valid {
+		pairs := parseAcceptedAssets(accepted.String)
+		resp.AcceptedAssets = &pairs
+	}
+	bps := int64(defaultSlippageBps)
+	if slippage.Valid {
+		bps = slippage.Int64
+	}
+	resp.MaxSlippageBps = &bps
+	clawback := int64(defaultClawbackBps)
+	if clawbackBps.Valid {
+		clawback = clawbackBps.Int64
+	}
+	resp.ClawbackMaxBps = &clawback
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// statusMispaid marks an order whose reported payment reached the deposit address in another
+// known token or on another chain than the order asks for. It stays MISPAID until the merchant
+// and support resolve it by hand (a refund off-platform, a status override), or until a correct
+// payment is reported.
+const statusMispaid = "MISPAID"
+
+// mispayment is the near-miss transfer of a MISPAID order.
+type mispayment struct {
+	Reason      string `json:"reason"` // wrong_token | wrong_chain
+	Asset       string `json:"asset"`
+	Chain       string `json:"chain"`
+	AmountMinor string `json:"amount_minor"` // in the smallest unit of the token sent
+	FromAddress string `json:"from_address"`
+	TxHash      string `json:"tx_hash"`
+}
+
+// findMispayment looks for a transfer of any known token to depositAddress in txHash, on the
+// order's chain and then on the other configured ones. A transfer of the order's own asset on its
+// own chain is not a mispayment (its amount is wrong), and neither is a hash no chain knows.
+func findMispayment(ctx context.Context, chain, asset, depositAddress, txHash string) (*mispayment, error) {
+	chains := []string{strings.ToUpper(chain)}
+	for _, c := range blockchain.Chains() {
+		if c != chains[0] {
+			chains = append(chains, c)
+		}
+	}
+	dest := strings.ToLower(depositAddress)
+	for _, c := range chains {
+		transfers, err := blockchain.TokenTransfersInTx(ctx, c, txHash)
+		if errors.Is(err, ethereum.NotFound) || errors.Is(err, blockchain.ErrUnsupportedChain) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, t := range transfers {
+			if strings.ToLower(t.To.Hex()) != dest {
+				continue
+			}
+			m := &mispayment{
+				Reason: attemptWrongChain, Asset: t.Asset, Chain: c, AmountMinor: t.Amount.String(),
+				FromAddress: t.From.Hex(), TxHash: t.TxHash.Hex(),
+			}
+			if c == chains[0] {
+				if strings.EqualFold(t.Asset, asset) {
+					continue
+				}
+				m.Reason = attemptWrongToken
+			}
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// flagMispayment marks a PENDING (or EXPIRED) order MISPAID when txHash paid its deposit address in
+// the wrong token or on the wrong chain, sending order.mispaid, and records the failed attempt
+// (submitted counts a report, as for recordPaymentAttempt).
+// verifyErr is why verifying the payment failed; only a wrong token or an unknown hash can be a
+// mispayment. It returns the mispayment found, nil if there is none.
+func flagMispayment(ctx context.Context, orderID, merchantID, chain, asset, depositAddress, txHash string, verifyErr error, submitted bool) (*mispayment, error) {
+	if reason := verificationFailureReason(verifyErr); reason != attemptWrongToken && reason != attemptTxNotFound {
+		return nil, nil
+	}
+	m, err := findMispayment(ctx, chain, asset, depositAddress, txHash)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	detail, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.ExecContext(ctx, `
+		UPDATE orders SET status = ?, mispayment_json = ? WHERE id = ? AND status IN ('PENDING', 'EXPIRED') AND deleted_at IS NULL
+	`, statusMispaid, string(detail), orderID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if err := enqueueOrderEvent(ctx, tx, webhookOrderMispaid, orderID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("event=order_mispaid order_id=%s merchant_id=%s tx_hash=%s reason=%s asset=%s chain=%s amount_minor=%s",
+		orderID, merchantID, txHash, m.Reason, m.Asset, m.Chain, m.AmountMinor)
+	logPaymentAttempt(orderID, merchantID, txHash, submitted, attemptFailed, m.Reason, "sent "+m.AmountMinor+" "+m.Asset+" on "+m.Chain)
+	return m, nil
+}
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A merchant balance goes negative when more leaves it than it holds, e.g. a refund of an order
+// that was already settled and paid out. New payments net the deficit in the ledger, and each
+// settlement withholds (claws back) what it would otherwise pay out of it, at most
+// merchants.clawback_max_bps of the settlement. The tracker opens a negative_balances row when a
+// merchant's balance on a chain drops below zero and marks it RECOVERED once it is back.
+const (
+	negativeBalanceOpen      = "OPEN"
+	negativeBalanceRecovered = "RECOVERED"
+
+	defaultClawbackBps = 10000 // withhold the whole settlement until the deficit is recovered
+)
+
+var (
+	negativeBalanceMu       sync.Mutex
+	negativeBalanceAlertURL string
+)
+
+// SetNegativeBalanceAlert sets the URL that balance.negative alerts are POSTed to; "" only logs them.
+func SetNegativeBalanceAlert(alertURL string) {
+	negativeBalanceMu.Lock()
+	defer negativeBalanceMu.Unlock()
+	negativeBalanceAlertURL = alertURL
+}
+
+type negativeBalance struct {
+	ID          string  `json:"id"`
+	MerchantID  string  `json:"merchant_id"`
+	Asset       string  `json:"asset"`
+	Chain       string  `json:"chain"`
+	Status      string  `json:"status"`       // OPEN or RECOVERED
+	AmountMinor string  `json:"amount_minor"` // what the merchant owes, positive; 0 once recovered
+	PeakMinor   string  `json:"peak_minor"`   // the most it owed
+	OpenedAt    string  `json:"opened_at"`
+	UpdatedAt   string  `json:"updated_at"`
+	RecoveredAt *string `json:"recovered_at,omitempty"`
+}
+
+const negativeBalanceCols = `id, merchant_id, asset, chain, status, amount_minor, peak_minor, opened_at, updated_at, recovered_at`
+
+func scanNegativeBalance(row scanner) (negativeBalance, error) {
+	var b negativeBalance
+	var recovered sql.NullString
+	err := row.Scan(&b.ID, &b.MerchantID, &b.Asset, &b.Chain, &b.Status, &b.AmountMinor, &b.PeakMinor, &b.OpenedAt, &b.UpdatedAt, &recovered)
+	b.RecoveredAt = nullStringPtr(recovered)
+	return b, err
+}
+
+// withholdClawback reduces the per-chain nets of a settlement the merchant is about to be paid by
+// what its balance on the chain lacks to cover them, up to clawback_max_bps of each, so that the
+// settlement does not leave the balance below zero. It returns the total withheld, which stays in
+// the merchant bucket against the deficit.
+func withholdClawback(ctx context.Context, tx *sql.Tx, merchantID, asset string, byChain map[string]*big.Int) (*big.Int, error) {
+	withheld := new(big.Int)
+	var maxBps sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT clawback_max_bps FROM merchants WHERE id = ?`, merchantID).Scan(&maxBps); err != nil {
+		return nil, err
+	}
+	bps := int64(defaultClawbackBps)
+	if maxBps.Valid {
+		bps = maxBps.Int64
+	}
+	if bps <= 0 {
+		return withheld, nil
+	}
+	for chain, net := range byChain {
+		if net.Sign() <= 0 {
+			continue
+		}
+		var balanceMinor string
+		err := tx.QueryRowContext(ctx, `
+			SELECT balance_minor FROM ledger_balances WHERE merchant_id = ? AND asset = ? AND chain = ? AND bucket = ?
+		`, merchantID, asset, chain, bucketMerchant).Scan(&balanceMinor)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		balance, ok := new(big.Int).SetString(balanceMinor, 10)
+		if !ok {
+			balance = new(big.Int)
+		}
+		short := new(big.Int).Sub(net, balance)
+		if short.Sign() <= 0 {
+			continue
+		}
+		limit := new(big.Int).Mul(net, big.NewInt(bps))
+		limit.Quo(limit, big.NewInt(10000))
+		if short.Cmp(limit) > 0 {
+			short = limit
+		}
+		net.Sub(net, short)
+		withheld.Add(withheld, short)
+	}
+	return withheld, nil
+}
+
+// StartNegativeBalanceTracker syncs negative_balances with the merchant balances every interval.
+func StartNegativeBalanceTracker(interval time.Duration) {
+	startScheduler(schedulerNegativeBal, interval, true, func(ctx context.Context) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		return trackNegativeBalances(ctx)
+	})
+}
+
+type balanceKey struct{ merchantID, asset, chain string }
+
+// trackNegativeBalances opens a negative balance for every merchant balance below zero without
+// one, updates the open ones and recovers those whose balance is back. It reports how many were
+// opened or recovered. A failing balance is logged and skipped; the error of the last failure is
+// returned.
+func trackNegativeBalances(ctx context.Context) (int, error) {
+	owed := map[balanceKey]*big.Int{}
+	rows, err := db.QueryContext(ctx, `
+		SELECT merchant_id, asset, chain, balance_minor FROM ledger_balances WHERE bucket = ? AND balance_minor LIKE '-%'
+	`, bucketMerchant)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var k balanceKey
+		var balance string
+		if err := rows.Scan(&k.merchantID, &k.asset, &k.chain, &balance); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if v, ok := new(big.Int).SetString(balance, 10); ok && v.Sign() < 0 {
+			owed[k] = v.Neg(v)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	open := map[balanceKey]negativeBalance{}
+	rows, err = db.QueryContext(ctx, `SELECT `+negativeBalanceCols+` FROM negative_balances WHERE status = ?`, negativeBalanceOpen)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		b, err := scanNegativeBalance(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		open[balanceKey{b.MerchantID, b.Asset, b.Chain}] = b
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var changed int
+	var lastErr error
+	for k, amount := range owed {
+		var err error
+		if b, ok := open[k]; ok {
+			err = updateNegativeBalance(ctx, b, amount)
+		} else if err = openNegativeBalance(ctx, k, amount); err == nil {
+			changed++
+		}
+		if err != nil {
+			log.Printf("negative balances: %s %s on %s: %v", k.merchantID, k.asset, k.chain, err)
+			lastErr = err
+		}
+	}
+	for k, b := range open {
+		if owed[k] != nil {
+			continue
+		}
+		if err := recoverNegativeBalance(ctx, b); err != nil {
+			log.Printf("negative balances: %s: %v", b.ID, err)
+			lastErr = err
+			continue
+		}
+		changed++
+	}
+	return changed, lastErr
+}
+
+func openNegativeBalance(ctx context.Context, k balanceKey, amount *big.Int) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	b := negativeBalance{
+		ID: "nb_" + uuid.New().String(), MerchantID: k.merchantID, Asset: k.asset, Chain: k.chain, Status: negativeBalanceOpen,
+		AmountMinor: amount.String(), PeakMinor: amount.String(), OpenedAt: now, UpdatedAt: now,
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO negative_balances (id, merchant_id, asset, chain, status, amount_minor, peak_minor, opened_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, b.ID, b.MerchantID, b.Asset, b.Chain, b.Status, b.AmountMinor, b.PeakMinor, now, now); err != nil {
+		return err
+	}
+	if err := enqueueEvent(ctx, tx, b.MerchantID, "merchant", b.MerchantID, webhookBalanceNegative, b); err != nil {
+		return err
+	}
+	recordAudit(ctx, tx, "system", b.MerchantID, "", "balance.negative", b)
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("event=merchant_balance_negative merchant_id=%s asset=%s chain=%s owed_minor=%s", b.MerchantID, b.Asset, b.Chain, b.AmountMinor)
+	negativeBalanceMu.Lock()
+	url := negativeBalanceAlertURL
+	negativeBalanceMu.Unlock()
+	go sendOperatorAlert(url, webhookBalanceNegative, b)
+	return nil
+}
+
+func updateNegativeBalance(ctx context.Context, b negativeBalance, amount *big.Int) error {
+	if amount.String() == b.AmountMinor {
+		return nil
+	}
+	peak := b.PeakMinor
+	if p, ok := new(big.Int).SetString(peak, 10); !ok || amount.Cmp(p) > 0 {
+		peak = amount.String()
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE negative_balances SET amount_minor = ?, peak_minor = ?, updated_at = ? WHERE id = ? AND status = ?
+	`, amount.String(), peak, time.Now().UTC().Format(time.RFC3339), b.ID, negativeBalanceOpen)
+	return err
+}
+
+func recoverNegativeBalance(ctx context.Context, b negativeBalance) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	b.Status, b.AmountMinor, b.UpdatedAt, b.RecoveredAt = negativeBalanceRecovered, "0", now, &now
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `
+		UPDATE negative_balances SET status = ?, amount_minor = '0', updated_at = ?, recovered_at = ? WHERE id = ? AND status = ?
+	`, negativeBalanceRecovered, now, now, b.ID, negativeBalanceOpen)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := enqueueEvent(ctx, tx, b.MerchantID, "merchant", b.MerchantID, webhookBalanceRecovered, b); err != nil {
+		return err
+	}
+	recordAudit(ctx, tx, "system", b.MerchantID, "", "balance.recovered", b)
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=merchant_balance_recovered merchant_id=%s asset=%s chain=%s peak_minor=%s", b.MerchantID, b.Asset, b.Chain, b.PeakMinor)
+	return nil
+}
+
+// negativeBalancesOpen is the number of merchant balances currently below zero, for /debug/metrics.
+func negativeBalancesOpen(ctx context.Context) int64 {
+	var n int64
+	_ = db.QueryRowContext(ctx, `SELECT COUNT(1) FROM negative_balances WHERE status = ?`, negativeBalanceOpen).Scan(&n)
+	return n
+}
+
+// ListNegativeBalancesHandler godoc
+// @Summary      List negative balances
+// @Description  Lists the times the merchant's balance of an asset on a chain went below zero, newest first: OPEN while it still owes amount_minor, RECOVERED once new payments or clawbacks from settlements brought it back. A balance goes negative when more is taken from it than it holds, e.g. by the refund of an order that was already settled. The tracker checks the balances every NEGATIVE_BALANCE_CHECK_INTERVAL and raises balance.negative and balance.recovered. status narrows the list. Admins pass merchant_id, or leave it out for every merchant.
+// @Tags         reconciliation
+// @Produce      json
+// @Param        status       query  string  false  "OPEN or RECOVERED"
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {array}   negativeBalance
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /negative-balances [get]
+// @Router       /admin/negative-balances [get]
+func ListNegativeBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = q.Get("merchant_id")
+	}
+	status := strings.ToUpper(q.Get("status"))
+	if status != "" && status != negativeBalanceOpen && status != negativeBalanceRecovered {
+		badReq(w, "status must be OPEN or RECOVERED")
+		return
+	}
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+negativeBalanceCols+` FROM negative_balances
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR status = ?)
+		ORDER BY opened_at DESC, id DESC
+		LIMIT 1000
+	`, merchantID, merchantID, status, status)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	out := []negativeBalance{}
+	for rows.Next() {
+		b, err := scanNegativeBalance(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		out = append(out, b)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// Nonces of the hot wallet are handed out by this process, one chain at a time: every send,
+// replacement and cancellation on a chain holds that chain's lock, so concurrent settlements and
+// refunds never sign two transactions with the same nonce.
+var chainLocks sync.Map // chain -> *sync.Mutex
+
+// lockChain takes the send lock of chain and returns its release.
+func lockChain(chain string) func() {
+	m, _ := chainLocks.LoadOrStore(strings.ToUpper(chain), &sync.Mutex{})
+	mu := m.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// nextNonce is the nonce for a new transaction from address: after both the node's pending nonce
+// and the highest one still in flight here, which the node may have dropped from its mempool.
+// Callers hold the chain lock.
+func nextNonce(ctx context.Context, chain string, address common.Address) (uint64, error) {
+	pending, err := blockchain.PendingNonce(ctx, chain, address)
+	if err != nil {
+		return 0, err
+	}
+	var local int64 = -1
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(nonce), -1) FROM chain_transactions
+		WHERE chain = ? AND from_address = ? AND status IN (?, ?)
+	`, chain, address.Hex(), chainTxPending, chainTxCancelling).Scan(&local); err != nil {
+		return 0, err
+	}
+	return max(pending, uint64(local+1)), nil
+}
+
+// checkWalletTxs runs the monitor for one wallet's in-flight transactions on chain, sorted by
+// nonce: records mined ones, marks ones whose nonce was used by someone else DROPPED,
+// rebroadcasts ones the node no longer knows, fills nonce gaps that would hold everything after
+// them, and fee-bumps the rest when they have waited too long.
+func checkWalletTxs(ctx context.Context, chain string, from common.Address, txs []chainTx) {
+	unlock := lockChain(chain)
+	defer unlock()
+	confirmed, err := blockchain.ConfirmedNonce(ctx, chain, from)
+	if err != nil {
+		log.Printf("nonce check %s %s: %v", chain, from.Hex(), err)
+		return
+	}
+	pending, err := blockchain.PendingNonce(ctx, chain, from)
+	if err != nil {
+		log.Printf("nonce check %s %s: %v", chain, from.Hex(), err)
+		return
+	}
+
+	var live []chainTx
+	held := map[uint64]bool{}
+	for _, t := range txs {
+		if mined, err := settleChainTx(ctx, t); err != nil || mined {
+			continue
+		}
+		if t.Nonce < confirmed {
+			// Mined, but by none of our hashes: another transaction took the nonce.
+			log.Printf("event=tx_dropped id=%s chain=%s nonce=%d tx_hash=%s", t.ID, chain, t.Nonce, t.TxHash)
+			_, _ = db.ExecContext(ctx, `
+				UPDATE chain_transactions SET status = ?, last_error = 'nonce used by another transaction' WHERE id = ? AND status IN (?, ?)
+			`, chainTxDropped, t.ID, chainTxPending, chainTxCancelling)
+			continue
+		}
+		held[t.Nonce] = true
+		live = append(live, t)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	if txSigner != nil && txSigner.Address() == from {
+		for n := confirmed; n < live[len(live)-1].Nonce; n++ {
+			if !held[n] {
+				fillNonceGap(ctx, chain, n)
+			}
+		}
+	}
+
+	for _, t := range live {
+		if t.Nonce >= pending && t.LastError == nil {
+			// The node has nothing pending at this nonce: it was dropped from the mempool (or
+			// reorged out), so send it again.
+			if tx, err := t.signedTx(); err == nil {
+				if err := blockchain.SendTx(ctx, chain, tx); err != nil && !strings.Contains(err.Error(), "already known") {
+					log.Printf("event=tx_rebroadcast_failed id=%s chain=%s nonce=%d err=%v", t.ID, chain, t.Nonce, err)
+				} else {
+					log.Printf("event=tx_rebroadcast id=%s chain=%s nonce=%d tx_hash=%s", t.ID, chain, t.Nonce, t.TxHash)
+				}
+			}
+		}
+		profile, _ := blockchain.Profile(blockchain.Urgency(t.Urgency))
+		submitted, _ := time.Parse(time.RFC3339, t.SubmittedAt)
+		if t.LastError == nil && time.Since(submitted) < profile.BumpAfter {
+			continue
+		}
+		if t.LastError == nil && t.Bumps >= maxFeeBumps {
+			log.Printf("event=tx_stuck id=%s chain=%s tx_hash=%s bumps=%d", t.ID, chain, t.TxHash, t.Bumps)
+			continue
+		}
+		if _, err := replaceChainTx(ctx, t, blockchain.Urgency(t.Urgency), t.Status == chainTxCancelling); err != nil {
+			log.Printf("event=tx_bump_failed id=%s chain=%s tx_hash=%s err=%v", t.ID, chain, t.TxHash, err)
+		}
+	}
+}
+
+// fillNonceGap sends a zero-value self-transfer at nonce, which no transaction of ours holds, so
+// the transactions queued behind it can be mined. It is recorded as a cancellation. Callers hold
+// the chain lock.
+func fillNonceGap(ctx context.Context, chain string, nonce uint64) {
+	chainID, err := blockchain.ChainID(ctx, chain)
+	if err != nil {
+		return
+	}
+	fees, err := blockchain.SuggestFees(ctx, chain, blockchain.UrgencyFast)
+	if err != nil {
+		return
+	}
+	fees = capFees(fees)
+	from := txSigner.Address()
+	tx, err := txSigner.SignTx(blockchain.CancelTx(chainID, nonce, from, fees), chainID)
+	if err != nil {
+		return
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return
+	}
+	var tip *string
+	if !fees.Legacy() {
+		s := fees.TipCap.String()
+		tip = &s
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	id, hash := "ctx_"+uuid.New().String(), tx.Hash().Hex()
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO chain_transactions (id, chain, purpose, from_address, nonce, tx_hash, raw_tx, urgency,
+		  max_fee_wei, priority_fee_wei, status, cancel_tx_hash, submitted_at, created_at)
+		VALUES (?, ?, 'nonce_fill', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, chain, from.Hex(), nonce, hash, hex.EncodeToString(raw), string(blockchain.UrgencyFast),
+		fees.MaxFee.String(), tip, chainTxCancelling, hash, now, now); err != nil {
+		log.Printf("nonce gap %s %d: %v", chain, nonce, err)
+		return
+	}
+	err = blockchain.SendTx(ctx, chain, tx)
+	if err != nil {
+		_, _ = db.ExecContext(ctx, `UPDATE chain_transactions SET last_error = ? WHERE id = ?`, err.Error(), id)
+	}
+	log.Printf("event=tx_nonce_gap_filled id=%s chain=%s nonce=%d tx_hash=%s err=%v", id, chain, nonce, hash, err)
+}
+
+// reorgWindow is how long after being mined a transaction is re-checked for having been
+// reorged out.
+const reorgWindow = time.Hour
+
+// checkReorgs puts recently mined transactions whose receipt is gone back in flight, so the
+// monitor rebroadcasts them.
+func checkReorgs(ctx context.Context) {
+	since := time.Now().UTC().Add(-reorgWindow).Format(time.RFC3339)
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, chain, mined_tx_hash FROM chain_transactions
+		WHERE status IN (?, ?, ?) AND confirmed_at >= ? AND mined_tx_hash IS NOT NULL
+	`, chainTxConfirmed, chainTxFailed, chainTxCancelled, since)
+	if err != nil {
+		log.Printf("failed to query mined transactions: %v", err)
+		return
+	}
+	type mined struct{ id, chain, hash string }
+	var recent []mined
+	for rows.Next() {
+		var m mined
+		if err := rows.Scan(&m.id, &m.chain, &m.hash); err == nil {
+			recent = append(recent, m)
+		}
+	}
+	rows.Close()
+	for _, m := range recent {
+		receipt, err := blockchain.Receipt(ctx, m.chain, common.HexToHash(m.hash))
+		if err != nil || receipt != nil {
+			continue
+		}
+		log.Printf("event=tx_reorged id=%s chain=%s tx_hash=%s", m.id, m.chain, m.hash)
+		_, _ = db.ExecContext(ctx, `
+			UPDATE chain_transactions
+			SET status = CASE WHEN cancel_tx_hash IS NULL THEN ? ELSE ? END, mined_tx_hash = NULL, confirmed_at = NULL
+			WHERE id = ?
+		`, chainTxPending, chainTxCancelling, m.id)
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// maxNoteLength caps the body of an order note.
+const maxNoteLength = 4000
+
+type orderNoteReq struct {
+	Body string `json:"body" validate:"required,max=4000"` // at most maxNoteLength
+}
+
+// orderNote is an internal comment on an order by the merchant's team or an operator. Notes are
+// never part of order responses or webhook payloads.
+type orderNote struct {
+	ID        string `json:"id"`
+	OrderID   string `json:"order_id"`
+	Author    string `json:"author"` // credential that wrote it, e.g. "key:primary" or "admin"
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+}
+
+// timelineEntry is one thing that happened to an order.
+type timelineEntry struct {
+	At            string     `json:"at"`
+	Kind          string     `json:"kind"`                     // created | event | note | payment_attempt
+	Event         string     `json:"event,omitempty"`          // webhook event type, for kind event
+	AggregateType string     `json:"aggregate_type,omitempty"` // order | refund | dispute
+	AggregateID   string     `json:"aggregate_id,omitempty"`
+	Note          *orderNote `json:"note,omitempty"`
+	// PaymentAttempt is a reported transaction hash and why it was not taken, for kind payment_attempt.
+	PaymentAttempt *paymentAttempt `json:"payment_attempt,omitempty"`
+}
+
+// OrderNotesHandler godoc
+// @Summary      Add or list order notes
+// @Description  POST adds an internal note (body, at most 4000 characters) to an order, recording the credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible to the merchant's credentials and operators; they are not included in order responses or webhooks.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id    query  string        true   "Order ID"
+// @Param        note  body   orderNoteReq  false  "Note (POST only)"
+// @Success      200  {array}   orderNote
+// @Success      201  {object}  orderNote
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/notes [get]
+// @Router       /orders/notes [post]
+// @Router       /admin/orders/notes [get]
+// @Router       /admin/orders/notes [post]
+func OrderNotesHandler(w http.ResponseWriter, r *http.Request) {
+	orderID := pathID(r)
+	if orderID == "" {
+		badReq(w, "missing query param: id")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	o, err := stores.Orders.Get(ctx, orderID, merchantIDFromContext(r.Context()))
+	if errors.Is(err, store.ErrNotFound) {
+		writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		var req orderNoteReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		req.Body = strings.TrimSpace(req.Body)
+		if req.Body == "" || len(req.Body) > maxNoteLength {
+			badReq(w, "body is required and at most 4000 characters")
+			return
+		}
+		n := orderNote{
+			ID: "note_" + uuid.New().String(), OrderID: o.ID, Author: actorFromContext(r.Context()), Body: req.Body,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO order_notes (id, order_id, merchant_id, author, body, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		`, n.ID, n.OrderID, o.MerchantID, n.Author, n.Body, n.CreatedAt); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, n)
+	case http.MethodGet:
+		notes, err := loadOrderNotes(ctx, db, o.ID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, notes)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func loadOrderNotes(ctx context.Context, q queryer, orderID string) ([]orderNote, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, order_id, author, body, created_at FROM order_notes WHERE order_id = ? ORDER BY created_at, rowid
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	notes := []orderNote{}
+	for rows.Next() {
+		var n orderNote
+		if err := rows.Scan(&n.ID, &n.OrderID, &n.Author, &n.Body, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// OrderTimelineHandler godoc
+// @Summary      Get an order's timeline
+// @Description  Returns what happened to an order, oldest first: its creation, the webhook events raised for it and its refunds and disputes (whether or not the merchant subscribes to them), every transaction hash reported as its payment with the outcome of its verification (failure_reason wrong_amount, wrong_token, wrong_recipient, no_transfer, tx_not_found, order_expired, already_paid or verification_error), and the team's notes. Events pruned by OUTBOX_RETENTION_DAYS no longer appear.
+// @Tags         orders
+// @Produce      json
+// @Param        id  query  string  true  "Order ID"
+// @Success      200  {array}   timelineEntry
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/timeline [get]
+// @Router       /admin/orders/timeline [get]
+func OrderTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	orderID := pathID(r)
+	if orderID == "" {
+		badReq(w, "missing query param: id")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	o, err := stores.Orders.Get(ctx, orderID, merchantIDFromContext(r.Context()))
+	if errors.Is(err, store.ErrNotFound) {
+		writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+
+	timeline := []timelineEntry{{At: o.CreatedAt, Kind: "created"}}
+	// Replays are copies of events already listed.
+	rows, err := db.QueryContext(ctx, `
+		SELECT created_at, event_name, aggregate_type, aggregate_id FROM outbox_events
+		WHERE replay_of IS NULL AND ((aggregate_type = 'order' AND aggregate_id = ?) OR json_extract(payload_json, '$.order_id') = ?)
+		ORDER BY created_at, rowid
+	`, o.ID, o.ID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e := timelineEntry{Kind: "event"}
+		if err := rows.Scan(&e.At, &e.Event, &e.AggregateType, &e.AggregateID); err != nil {
+			serverErr(w, err)
+			return
+		}
+		timeline = append(timeline, e)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	notes, err := loadOrderNotes(ctx, db, o.ID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	for i := range notes {
+		timeline = append(timeline, timelineEntry{At: notes[i].CreatedAt, Kind: "note", Note: &notes[i]})
+	}
+	attempts, err := loadPaymentAttempts(ctx, db, o.ID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	for i := range attempts {
+		timeline = append(timeline, timelineEntry{At: attempts[i].CreatedAt, Kind: "payment_attempt", PaymentAttempt: &attempts[i]})
+	}
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].At < timeline[j].At })
+	writeJSON(w, http.StatusOK, timeline)
+}
@@ -0,0 +1,485 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// OAuth2 scopes a platform can request on behalf of a merchant.
+const (
+	ScopeOrdersRead   = "orders:read"
+	ScopeOrdersWrite  = "orders:write"
+	ScopeRefundsWrite = "refunds:write"
+	ScopeEventsWrite  = "events:write"
+	ScopeBalancesRead = "balances:read"
+
+	ScopeRefundsApprove = "refunds:approve"
+
+	// scopeAll is granted to requests authenticated with the merchant's own API key.
+	scopeAll = "*"
+)
+
+var knownScopes = map[string]bool{
+	ScopeOrdersRead:   true,
+	ScopeOrdersWrite:  true,
+	ScopeRefundsWrite: true,
+	ScopeEventsWrite:  true,
+	ScopeBalancesRead: true,
+
+	ScopeRefundsApprove: true,
+}
+
+const (
+	oauthCodeTTL    = 5 * time.Minute
+	oauthAccessTTL  = time.Hour
+	oauthRefreshTTL = 30 * 24 * time.Hour
+)
+
+const scopesKey ctxKey = "scopes"
+
+type oauthClientCreateReq struct {
+	Name        string `json:"name" validate:"required,max=200"`
+	RedirectURI string `json:"redirect_uri" validate:"max=2000"`
+}
+
+type oauthClientCreateResp struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RedirectURI  string `json:"redirect_uri,omitempty"`
+}
+
+type oauthAuthorizeReq struct {
+	ClientID    string `json:"client_id"`
+	Scope       string `json:"scope"` // space-separated, e.g. "orders:write balances:read"
+	RedirectURI string `json:"redirect_uri,omitempty"`
+	State       string `json:"state,omitempty" validate:"max=500"`
+}
+
+type oauthAuthorizeResp struct {
+	Code        string `json:"code"`
+	ExpiresIn   int64  `json:"expires_in"`
+	RedirectURI string `json:"redirect_uri,omitempty"` // redirect_uri with code and state appended
+}
+
+type oauthTokenResp struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// oauthError writes an RFC 6749 section 5.2 error body. The token and revocation endpoints keep
+// this shape instead of problem+json because OAuth client libraries expect it.
+func oauthError(w http.ResponseWriter, code int, errStr, desc string) {
+	writeJSON(w, code, map[string]string{"error": errStr, "error_description": desc})
+}
+
+// newOpaqueToken returns a random token with the given prefix; only its hash is stored.
+func newOpaqueToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+func hashToken(t string) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseScopes validates a space-separated scope string and returns it normalized.
+func parseScopes(s string) (string, bool) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return "", false
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, f := range fields {
+		if !knownScopes[f] {
+			return "", false
+		}
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return strings.Join(out, " "), true
+}
+
+// hasScope reports whether the authenticated request was granted scope.
+func hasScope(ctx context.Context, scope string) bool {
+	granted, _ := ctx.Value(scopesKey).(string)
+	for _, s := range strings.Fields(granted) {
+		if s == scopeAll || s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope rejects requests whose credentials were not granted scope. Use inside APIKeyAuthMiddleware.
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !hasScope(r.Context(), scope) {
+			writeProblem(w, http.StatusForbidden, CodeInsufficientScope, "token lacks required scope: "+scope)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// lookupAccessToken resolves a bearer token to its grant and the merchant and scopes it was issued for.
+func lookupAccessToken(ctx context.Context, token string) (grantID, merchantID, scope string, err error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	err = db.QueryRowContext(ctx, `
+		SELECT grant_id, merchant_id, scope FROM oauth_tokens
+		WHERE token_hash = ? AND token_type = 'access' AND revoked_at IS NULL AND expires_at > ?
+	`, hashToken(token), now).Scan(&grantID, &merchantID, &scope)
+	return grantID, merchantID, scope, err
+}
+
+// CreateOAuthClientHandler godoc
+// @Summary      Register an OAuth client
+// @Description  Registers an OAuth2 client for the calling platform. The client secret is only returned once.
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Param        client  body  oauthClientCreateReq  true  "Client info"
+// @Success      201  {object}  oauthClientCreateResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /oauth/clients [post]
+func CreateOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req oauthClientCreateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.RedirectURI != "" {
+		if u, err := url.Parse(req.RedirectURI); err != nil || u.Scheme == "" || u.Host == "" {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidRedirectURI, "redirect_uri must be an absolute URL")
+			return
+		}
+	}
+	secret, err := newOpaqueToken("ospay_cs_")
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	id := "client_" + uuid.New().String()
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := db.ExecContext(r.Context(), `
+		INSERT INTO oauth_clients (id, platform_id, name, secret_hash, redirect_uri, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, platformIDFromContext(r.Context()), req.Name, hashToken(secret), req.RedirectURI, now); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	noStore(w)
+	writeJSON(w, http.StatusCreated, oauthClientCreateResp{ClientID: id, ClientSecret: secret, RedirectURI: req.RedirectURI})
+}
+
+// OAuthAuthorizeHandler godoc
+// @Summary      Authorize an OAuth client
+// @Description  Called by the merchant (with their primary API key) to grant a platform client the requested scopes. Scoped keys, OAuth tokens and organization member keys are refused. Returns a short-lived authorization code.
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Param        authorization  body  oauthAuthorizeReq  true  "Authorization request"
+// @Success      200  {object}  oauthAuthorizeResp
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /oauth/authorize [post]
+func OAuthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	// Only the merchant's own key may grant access: a delegated token must not mint further grants,
+	// and neither may an organization member, whose grant would outlive their membership.
+	if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "authorization must be granted with the primary merchant API key")
+		return
+	}
+	var req oauthAuthorizeReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	scope, ok := parseScopes(req.Scope)
+	if req.ClientID == "" || !ok {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidScope, "client_id and a space-separated scope from the supported set are required")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpAuth)
+	defer cancel()
+	var registeredURI string
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(redirect_uri, '') FROM oauth_clients WHERE id = ?`, req.ClientID).Scan(&registeredURI)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidClient, "unknown client_id")
+			return
+		}
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	redirectURI := req.RedirectURI
+	if redirectURI == "" {
+		redirectURI = registeredURI
+	}
+	if registeredURI != "" && redirectURI != registeredURI {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidRedirectURI, "redirect_uri does not match the registered value")
+		return
+	}
+
+	code, err := newOpaqueToken("ospay_ac_")
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	now := time.Now().UTC()
+	// The code keeps the redirect_uri exactly as requested (NULL when left out): the token request
+	// has to repeat it (RFC 6749 §4.1.3)
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO oauth_codes (code_hash, client_id, merchant_id, scope, redirect_uri, expires_at, created_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`, hashToken(code), req.ClientID, merchantIDFromContext(r.Context()), scope, req.RedirectURI,
+		now.Add(oauthCodeTTL).Format(time.RFC3339), now.Format(time.RFC3339)); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+
+	resp := oauthAuthorizeResp{Code: code, ExpiresIn: int64(oauthCodeTTL.Seconds())}
+	if redirectURI != "" {
+		if u, err := url.Parse(redirectURI); err == nil {
+			q := u.Query()
+			q.Set("code", code)
+			if req.State != "" {
+				q.Set("state", req.State)
+			}
+			u.RawQuery = q.Encode()
+			resp.RedirectURI = u.String()
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// authenticateClient checks client_id/client_secret from HTTP Basic auth or the form body.
+func authenticateClient(ctx context.Context, r *http.Request) (string, bool) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientID == "" || secret == "" {
+		return "", false
+	}
+	var secretHash string
+	if err := db.QueryRowContext(ctx, `SELECT secret_hash FROM oauth_clients WHERE id = ?`, clientID).Scan(&secretHash); err != nil {
+		return "", false
+	}
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(hashToken(secret))) != 1 {
+		return "", false
+	}
+	return clientID, true
+}
+
+// issueTokens stores a new access/refresh token pair for grantID inside tx.
+func issueTokens(ctx context.Context, tx *sql.Tx, grantID, clientID, merchantID, scope string) (oauthTokenResp, error) {
+	access, err := newOpaqueToken("ospay_at_")
+	if err != nil {
+		return oauthTokenResp{}, err
+	}
+	refresh, err := newOpaqueToken("ospay_rt_")
+	if err != nil {
+		return oauthTokenResp{}, err
+	}
+	now := time.Now().UTC()
+	const insert = `
+		INSERT INTO oauth_tokens (token_hash, token_type, grant_id, client_id, merchant_id, scope, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := tx.ExecContext(ctx, insert, hashToken(access), "access", grantID, clientID, merchantID, scope,
+		now.Add(oauthAccessTTL).Format(time.RFC3339), now.Format(time.RFC3339)); err != nil {
+		return oauthTokenResp{}, err
+	}
+	if _, err := tx.ExecContext(ctx, insert, hashToken(refresh), "refresh", grantID, clientID, merchantID, scope,
+		now.Add(oauthRefreshTTL).Format(time.RFC3339), now.Format(time.RFC3339)); err != nil {
+		return oauthTokenResp{}, err
+	}
+	return oauthTokenResp{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(oauthAccessTTL.Seconds()),
+		RefreshToken: refresh,
+		Scope:        scope,
+	}, nil
+}
+
+// OAuthTokenHandler godoc
+// @Summary      Issue or refresh OAuth tokens
+// @Description  RFC 6749 token endpoint. Supports grant_type=authorization_code and grant_type=refresh_token; refresh tokens are rotated on use. Client credentials go in HTTP Basic auth or the form body.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code or refresh_token"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect URI sent to /oauth/authorize; required, and identical, if one was sent there"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        client_id      formData  string  false  "Client ID (if not using Basic auth)"
+// @Param        client_secret  formData  string  false  "Client secret (if not using Basic auth)"
+// @Success      200  {object}  oauthTokenResp
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /oauth/token [post]
+func OAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		oauthError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "invalid form body")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpAuth)
+	defer cancel()
+	clientID, ok := authenticateClient(ctx, r)
+	if !ok {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	var (
+		grantID, merchantID, scope string
+	)
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		code := r.PostFormValue("code")
+		var redirectURI, expiresAt string
+		err := tx.QueryRowContext(ctx, `
+			SELECT merchant_id, scope, COALESCE(redirect_uri, ''), expires_at FROM oauth_codes
+			WHERE code_hash = ? AND client_id = ? AND used_at IS NULL
+		`, hashToken(code), clientID).Scan(&merchantID, &scope, &redirectURI, &expiresAt)
+		if err != nil || expiresAt <= now {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "authorization code is invalid, expired, or already used")
+			return
+		}
+		if r.PostFormValue("redirect_uri") != redirectURI {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the one sent to /oauth/authorize")
+			return
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE oauth_codes SET used_at = ? WHERE code_hash = ?`, now, hashToken(code)); err != nil {
+			oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		grantID = "grant_" + uuid.New().String()
+	case "refresh_token":
+		refresh := r.PostFormValue("refresh_token")
+		var expiresAt string
+		err := tx.QueryRowContext(ctx, `
+			SELECT grant_id, merchant_id, scope, expires_at FROM oauth_tokens
+			WHERE token_hash = ? AND token_type = 'refresh' AND client_id = ? AND revoked_at IS NULL
+		`, hashToken(refresh), clientID).Scan(&grantID, &merchantID, &scope, &expiresAt)
+		if err != nil || expiresAt <= now {
+			oauthError(w, http.StatusBadRequest, "invalid_grant", "refresh token is invalid, expired, or revoked")
+			return
+		}
+		// Rotate: the used refresh token and any outstanding access tokens of the grant are retired.
+		if _, err := tx.ExecContext(ctx, `UPDATE oauth_tokens SET revoked_at = ? WHERE grant_id = ? AND revoked_at IS NULL`, now, grantID); err != nil {
+			oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+	default:
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+		return
+	}
+
+	resp, err := issueTokens(ctx, tx, grantID, clientID, merchantID, scope)
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	noStore(w)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// OAuthRevokeHandler godoc
+// @Summary      Revoke an OAuth token
+// @Description  RFC 7009 revocation. Revoking a refresh token also revokes every access token from the same grant. Unknown tokens are accepted silently.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token          formData  string  true   "Access or refresh token"
+// @Param        client_id      formData  string  false  "Client ID (if not using Basic auth)"
+// @Param        client_secret  formData  string  false  "Client secret (if not using Basic auth)"
+// @Success      200  {object}  map[string]bool
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /oauth/revoke [post]
+func OAuthRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		oauthError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "invalid form body")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpAuth)
+	defer cancel()
+	clientID, ok := authenticateClient(ctx, r)
+	if !ok {
+		oauthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	h := hashToken(r.PostFormValue("token"))
+	var grantID, tokenType string
+	err := db.QueryRowContext(ctx, `SELECT grant_id, token_type FROM oauth_tokens WHERE token_hash = ? AND client_id = ?`, h, clientID).Scan(&grantID, &tokenType)
+	if err == nil {
+		q, arg := `UPDATE oauth_tokens SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL`, h
+		if tokenType == "refresh" {
+			q, arg = `UPDATE oauth_tokens SET revoked_at = ? WHERE grant_id = ? AND revoked_at IS NULL`, grantID
+		}
+		if _, err := db.ExecContext(ctx, q, now, arg); err != nil {
+			oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		oauthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"revoked": true})
+}
@@ -0,0 +1,541 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/offramp"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Fiat payout states: REQUESTED until the hot wallet has sent the crypto to the partner, FUNDED
+// while the partner converts and pays the bank, then PAID or FAILED.
+const (
+	fiatPayoutRequested = "REQUESTED"
+	fiatPayoutFunded    = "FUNDED"
+	fiatPayoutPaid      = "PAID"
+	fiatPayoutFailed    = "FAILED"
+)
+
+// Off-ramp ledger buckets. The crypto moves merchant -> offramp_pending when the payout is
+// requested and offramp_pending -> offramp once the partner paid; the fiat paid out is booked in
+// the fiat currency, offramp -> fiat_paid.
+const (
+	bucketOfframpPending = "offramp_pending"
+	bucketOfframp        = "offramp"
+	bucketFiatPaid       = "fiat_paid"
+
+	eventOfframpReserved = "OFFRAMP_RESERVED"
+	eventOfframpReleased = "OFFRAMP_RELEASED"
+	eventOfframpPaid     = "OFFRAMP_PAID"
+	eventFiatPaid        = "FIAT_PAID"
+)
+
+var offrampProvider offramp.Provider
+
+// SetOfframpProvider sets the off-ramp partner; nil turns fiat payouts off.
+func SetOfframpProvider(p offramp.Provider) { offrampProvider = p }
+
+type fiatPayoutRecord struct {
+	ID               string  `json:"id"`
+	MerchantID       string  `json:"merchant_id"`
+	Provider         string  `json:"provider"`
+	ProviderPayoutID *string `json:"provider_payout_id,omitempty"` // the partner's reference
+	Chain            string  `json:"chain"`
+	Asset            string  `json:"asset"`
+	AmountMinor      string  `json:"amount_minor"`
+	FiatCurrency     string  `json:"fiat_currency"`
+	FiatAmountMinor  *string `json:"fiat_amount_minor,omitempty"` // in cents (the currency's minor unit)
+	Rate             *string `json:"rate,omitempty"`
+	DepositAddress   *string `json:"deposit_address,omitempty"`
+	TxHash           *string `json:"tx_hash,omitempty"`
+	Status           string  `json:"status"`
+	LastError        *string `json:"last_error,omitempty"`
+	CreatedAt        string  `json:"created_at"`
+	UpdatedAt        string  `json:"updated_at"`
+	chainTxID        sql.NullString
+}
+
+const fiatPayoutCols = `id, merchant_id, provider, provider_payout_id, chain, asset, amount_minor, fiat_currency,
+	fiat_amount_minor, rate, deposit_address, chain_tx_id, tx_hash, status, last_error, created_at, updated_at`
+
+func scanFiatPayout(row interface{ Scan(...any) error }) (fiatPayoutRecord, error) {
+	var (
+		p                                    fiatPayoutRecord
+		ref, fiat, rate, deposit, txHash, le sql.NullString
+	)
+	err := row.Scan(&p.ID, &p.MerchantID, &p.Provider, &ref, &p.Chain, &p.Asset, &p.AmountMinor, &p.FiatCurrency,
+		&fiat, &rate, &deposit, &p.chainTxID, &txHash, &p.Status, &le, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return p, err
+	}
+	p.ProviderPayoutID, p.FiatAmountMinor, p.Rate = nullStringPtr(ref), nullStringPtr(fiat), nullStringPtr(rate)
+	p.DepositAddress, p.TxHash, p.LastError = nullStringPtr(deposit), nullStringPtr(txHash), nullStringPtr(le)
+	return p, nil
+}
+
+// settledBalance is the part of a merchant's asset balance that has been settled and not yet paid
+// out: settlement batches and conversions into the asset, less on-chain payouts, conversions out
+// of it and fiat payouts. Failed payouts and conversions give their amount back, except fiat
+// payouts whose crypto was already sent to the partner.
+func settledBalance(ctx context.Context, q queryer, merchantID, asset string) (*big.Int, error) {
+	balance := new(big.Int)
+	for _, part := range []struct {
+		sign  int
+		query string
+	}{
+		{1, `SELECT total_amount_minor FROM settlement_batches WHERE merchant_id = ? AND asset = ? AND status = 'EXECUTED'`},
+		{1, `SELECT amount_out_minor FROM conversions WHERE merchant_id = ? AND to_asset = ? AND status = 'COMPLETED'`},
+		{-1, `SELECT amount_minor FROM payouts WHERE merchant_id = ? AND asset = ? AND status != 'FAILED'`},
+		{-1, `SELECT amount_in_minor FROM conversions WHERE merchant_id = ? AND from_asset = ? AND status != 'FAILED'`},
+		{-1, `SELECT amount_minor FROM fiat_payouts WHERE merchant_id = ? AND asset = ? AND (status != 'FAILED' OR chain_tx_id IS NOT NULL)`},
+	} {
+		v, err := sumAmounts(ctx, q, part.query, merchantID, asset)
+		if err != nil {
+			return nil, err
+		}
+		if part.sign < 0 {
+			v.Neg(v)
+		}
+		balance.Add(balance, v)
+	}
+	return balance, nil
+}
+
+// insertOfframpLedger books one balanced pair for fiat payout p; chain is empty for fiat amounts.
+func insertOfframpLedger(ctx context.Context, tx *sql.Tx, p fiatPayoutRecord, chain, asset, amount, eventType, debitBucket, creditBucket, txHash, now string) error {
+	entry := func(bucket, direction string) store.LedgerEntry {
+		return store.LedgerEntry{
+			MerchantID: p.MerchantID, Asset: asset, Chain: chain, AmountMinor: amount,
+			Bucket: bucket, Direction: direction, EventType: eventType, TxHash: txHash, ReferenceID: p.ID, CreatedAt: now,
+		}
+	}
+	return txStores(tx).Ledger.Append(ctx, entry(debitBucket, dirDebit), entry(creditBucket, dirCredit))
+}
+
+type kycResp struct {
+	Provider      string `json:"provider"`
+	CustomerID    string `json:"customer_id,omitempty"`
+	BankAccountID string `json:"bank_account_id,omitempty"`
+	KYCStatus     string `json:"kyc_status"`
+	CheckedAt     string `json:"checked_at,omitempty"`
+}
+
+// refreshKYC asks the partner for the merchant's KYC status and stores it. Merchants without a
+// partner customer ID are NOT_STARTED.
+func refreshKYC(ctx context.Context, merchantID string) (kycResp, error) {
+	resp := kycResp{Provider: offrampProvider.Name(), KYCStatus: offramp.KYCNotStarted}
+	var customer, bank sql.NullString
+	if err := db.QueryRowContext(ctx, `
+		SELECT offramp_customer_id, offramp_bank_account_id FROM merchants WHERE id = ?
+	`, merchantID).Scan(&customer, &bank); err != nil {
+		return resp, err
+	}
+	resp.CustomerID, resp.BankAccountID = customer.String, bank.String
+	if !customer.Valid {
+		return resp, nil
+	}
+	status, err := offrampProvider.KYCStatus(ctx, customer.String)
+	if errors.Is(err, offramp.ErrUnknownCustomer) {
+		status, err = offramp.KYCNotStarted, nil
+	}
+	if err != nil {
+		return resp, err
+	}
+	resp.KYCStatus = status
+	resp.CheckedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err = db.ExecContext(ctx, `UPDATE merchants SET kyc_status = ?, kyc_checked_at = ? WHERE id = ?`, status, resp.CheckedAt, merchantID)
+	return resp, err
+}
+
+// OfframpKYCHandler godoc
+// @Summary      Show off-ramp KYC status
+// @Description  Returns the merchant's KYC status at the off-ramp partner (NOT_STARTED, PENDING, APPROVED or REJECTED), fetched fresh from the partner. KYC itself happens with the partner; an administrator links the merchant to the partner's customer and bank account IDs in the merchant settings (offramp_customer_id, offramp_bank_account_id). Admins pass merchant_id.
+// @Tags         settlements
+// @Produce      json
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {object}  kycResp
+// @Failure      409  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /offramp/kyc [get]
+// @Router       /admin/offramp/kyc [get]
+func OfframpKYCHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	if offrampProvider == nil {
+		writeProblem(w, http.StatusConflict, CodeOfframpNotConfigured, "set OFFRAMP_API_URL")
+		return
+	}
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = r.URL.Query().Get("merchant_id")
+	}
+	resp, err := refreshKYC(r.Context(), merchantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeMerchantNotFound, "merchant not found")
+		return
+	} else if err != nil {
+		writeProblem(w, http.StatusBadGateway, CodeOfframpUnavailable, err.Error())
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+type fiatPayoutReq struct {
+	Chain        string `json:"chain" validate:"required,chain"`
+	Asset        string `json:"asset" validate:"required,asset"`
+	AmountMinor  string `json:"amount_minor" validate:"required"`
+	FiatCurrency string `json:"fiat_currency"` // ISO 4217; default USD
+}
+
+// FiatPayoutsHandler godoc
+// @Summary      Request or list fiat payouts
+// @Description  POST converts part of the merchant's settled balance of asset into a bank payout through the off-ramp partner; it needs the primary API key and approved KYC. The amount is reserved at once (ledger offramp_pending); the hot wallet then sends the crypto on chain to the partner's deposit address, and the payout becomes PAID once the partner has paid the bank, with the fiat amount and rate it applied. The settled balance is settlement batches and conversions into the asset less what was paid out or converted since. GET lists fiat payouts, newest first.
+// @Tags         settlements
+// @Accept       json
+// @Produce      json
+// @Param        payout       body   fiatPayoutReq  false  "Payout (POST only)"
+// @Param        status       query  string         false  "Status filter (GET only)"
+// @Param        merchant_id  query  string         false  "Merchant ID (admin route only)"
+// @Success      200  {array}   fiatPayoutRecord
+// @Success      201  {object}  fiatPayoutRecord
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /offramp/payouts [get]
+// @Router       /offramp/payouts [post]
+// @Router       /admin/offramp/payouts [get]
+func FiatPayoutsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listFiatPayouts(w, r)
+	case http.MethodPost:
+		createFiatPayout(w, r)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+	}
+}
+
+func createFiatPayout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if credentialFromContext(ctx) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "fiat payouts can only be requested with the primary merchant API key")
+		return
+	}
+	if offrampProvider == nil {
+		writeProblem(w, http.StatusConflict, CodeOfframpNotConfigured, "set OFFRAMP_API_URL")
+		return
+	}
+	var req fiatPayoutReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	req.Chain, req.Asset, req.FiatCurrency = strings.ToUpper(req.Chain), strings.ToUpper(req.Asset), strings.ToUpper(req.FiatCurrency)
+	if req.FiatCurrency == "" {
+		req.FiatCurrency = "USD"
+	}
+	if !isValidAmountString(req.AmountMinor) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidAmount, "amount_minor must be a positive integer in minor units")
+		return
+	}
+	if !blockchain.KnownToken(req.Chain, req.Asset) {
+		writeProblem(w, http.StatusBadRequest, CodeUnsupportedChain, "no "+req.Asset+" token known on "+req.Chain)
+		return
+	}
+	if len(req.FiatCurrency) != 3 || strings.Trim(req.FiatCurrency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidCurrency, "fiat_currency must be an ISO 4217 code")
+		return
+	}
+	merchantID := merchantIDFromContext(ctx)
+	kyc, err := refreshKYC(ctx, merchantID)
+	if err != nil {
+		writeProblem(w, http.StatusBadGateway, CodeOfframpUnavailable, err.Error())
+		return
+	}
+	if kyc.KYCStatus != offramp.KYCApproved {
+		writeProblem(w, http.StatusForbidden, CodeKYCNotApproved, "KYC status is "+kyc.KYCStatus)
+		return
+	}
+	if kyc.BankAccountID == "" {
+		writeProblem(w, http.StatusForbidden, CodeKYCNotApproved, "no bank account linked at the off-ramp partner")
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	available, err := settledBalance(ctx, tx, merchantID, req.Asset)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	amount, _ := new(big.Int).SetString(req.AmountMinor, 10)
+	if amount.Cmp(available) > 0 {
+		writeProblem(w, http.StatusConflict, CodeInsufficientBalance, fmt.Sprintf("settled %s balance is %s", req.Asset, available))
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	p := fiatPayoutRecord{
+		ID: "fp_" + uuid.New().String(), MerchantID: merchantID, Provider: offrampProvider.Name(), Chain: req.Chain, Asset: req.Asset,
+		AmountMinor: req.AmountMinor, FiatCurrency: req.FiatCurrency, Status: fiatPayoutRequested, CreatedAt: now, UpdatedAt: now,
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO fiat_payouts (id, merchant_id, provider, chain, asset, amount_minor, fiat_currency, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.ID, p.MerchantID, p.Provider, p.Chain, p.Asset, p.AmountMinor, p.FiatCurrency, p.Status, now, now); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := insertOfframpLedger(ctx, tx, p, p.Chain, p.Asset, p.AmountMinor, eventOfframpReserved, bucketSettlement, bucketOfframpPending, "", now); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(ctx, db, actorFromContext(ctx), merchantID, "", "fiat_payout_requested", req)
+	log.Printf("event=fiat_payout_requested id=%s merchant_id=%s chain=%s asset=%s amount_minor=%s fiat_currency=%s", p.ID, merchantID, p.Chain, p.Asset, p.AmountMinor, p.FiatCurrency)
+	writeJSONOrders(w, http.StatusCreated, p)
+}
+
+func listFiatPayouts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = q.Get("merchant_id")
+	}
+	status := strings.ToUpper(q.Get("status"))
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+fiatPayoutCols+` FROM fiat_payouts
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR status = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 500
+	`, merchantID, merchantID, status, status)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	payouts := []fiatPayoutRecord{}
+	for rows.Next() {
+		p, err := scanFiatPayout(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		payouts = append(payouts, p)
+	}
+	writeJSONOrders(w, http.StatusOK, payouts)
+}
+
+// dispatchFiatPayouts funds requested fiat payouts and follows funded ones at the partner. It runs
+// on the payout dispatcher's tick.
+func dispatchFiatPayouts(ctx context.Context) {
+	if offrampProvider == nil {
+		return
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+fiatPayoutCols+` FROM fiat_payouts WHERE status IN (?, ?) ORDER BY created_at
+	`, fiatPayoutRequested, fiatPayoutFunded)
+	if err != nil {
+		log.Printf("failed to query fiat payouts: %v", err)
+		return
+	}
+	var open []fiatPayoutRecord
+	for rows.Next() {
+		if p, err := scanFiatPayout(rows); err == nil {
+			open = append(open, p)
+		}
+	}
+	rows.Close()
+	for _, p := range open {
+		var err error
+		if p.Status == fiatPayoutRequested {
+			err = fundFiatPayout(ctx, p)
+		} else {
+			err = syncFiatPayout(ctx, p)
+		}
+		if err != nil {
+			log.Printf("event=fiat_payout_error id=%s status=%s err=%v", p.ID, p.Status, err)
+			_, _ = db.ExecContext(ctx, `UPDATE fiat_payouts SET last_error = ?, updated_at = ? WHERE id = ?`, err.Error(), time.Now().UTC().Format(time.RFC3339), p.ID)
+		}
+	}
+}
+
+// fundFiatPayout creates the payout at the partner, once, and sends the crypto to the deposit
+// address it returns.
+func fundFiatPayout(ctx context.Context, p fiatPayoutRecord) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if p.ProviderPayoutID == nil {
+		var customer, bank sql.NullString
+		if err := db.QueryRowContext(ctx, `
+			SELECT offramp_customer_id, offramp_bank_account_id FROM merchants WHERE id = ?
+		`, p.MerchantID).Scan(&customer, &bank); err != nil {
+			return err
+		}
+		amount, _ := new(big.Int).SetString(p.AmountMinor, 10)
+		created, err := offrampProvider.CreatePayout(ctx, offramp.PayoutRequest{
+			Reference: p.ID, CustomerID: customer.String, BankAccountID: bank.String,
+			Chain: p.Chain, Asset: p.Asset, AmountMinor: amount, FiatCurrency: p.FiatCurrency,
+		})
+		if err != nil {
+			return err
+		}
+		if !common.IsHexAddress(created.DepositAddress) {
+			return failFiatPayout(ctx, p, "partner returned no valid deposit address")
+		}
+		var fiat *string
+		if created.FiatAmountMinor != nil {
+			s := created.FiatAmountMinor.String()
+			fiat = &s
+		}
+		if _, err := db.ExecContext(ctx, `
+			UPDATE fiat_payouts SET provider_payout_id = ?, deposit_address = ?, fiat_amount_minor = ?, rate = NULLIF(?, ''), updated_at = ? WHERE id = ?
+		`, created.ID, created.DepositAddress, fiat, created.Rate, now, p.ID); err != nil {
+			return err
+		}
+		p.ProviderPayoutID, p.DepositAddress = &created.ID, &created.DepositAddress
+	}
+	token, ok := blockchain.TokenAddress(p.Chain, p.Asset)
+	if !ok {
+		return failFiatPayout(ctx, p, fmt.Sprintf("no %s contract known on %s", p.Asset, p.Chain))
+	}
+	amount, _ := new(big.Int).SetString(p.AmountMinor, 10)
+	t, err := submitTokenTransfer(ctx, p.Chain, "offramp", p.ID, token, common.HexToAddress(*p.DepositAddress), amount)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		UPDATE fiat_payouts SET status = ?, chain_tx_id = ?, tx_hash = ?, last_error = NULL, updated_at = ? WHERE id = ? AND status = ?
+	`, fiatPayoutFunded, t.ID, t.TxHash, now, p.ID, fiatPayoutRequested)
+	return err
+}
+
+// syncFiatPayout finishes a funded payout once the partner reports it paid or failed.
+func syncFiatPayout(ctx context.Context, p fiatPayoutRecord) error {
+	var txStatus string
+	var mined sql.NullString
+	if err := db.QueryRowContext(ctx, `SELECT status, mined_tx_hash FROM chain_transactions WHERE id = ?`, p.chainTxID).Scan(&txStatus, &mined); err != nil {
+		return err
+	}
+	if txStatus == chainTxFailed || txStatus == chainTxCancelled || txStatus == chainTxDropped {
+		// The crypto never reached the partner: the payout can be funded again.
+		_, err := db.ExecContext(ctx, `
+			UPDATE fiat_payouts SET status = ?, chain_tx_id = NULL, tx_hash = NULL, last_error = ?, updated_at = ? WHERE id = ? AND status = ?
+		`, fiatPayoutRequested, "funding transaction "+strings.ToLower(txStatus), time.Now().UTC().Format(time.RFC3339), p.ID, fiatPayoutFunded)
+		return err
+	}
+	if txStatus != chainTxConfirmed || p.ProviderPayoutID == nil {
+		return nil
+	}
+	st, err := offrampProvider.GetPayout(ctx, *p.ProviderPayoutID)
+	if err != nil {
+		return err
+	}
+	switch st.Status {
+	case offramp.StatusPaid:
+		return completeFiatPayout(ctx, p, mined.String, st)
+	case offramp.StatusFailed:
+		return failFiatPayout(ctx, p, st.Reason)
+	}
+	return nil
+}
+
+// completeFiatPayout books the paid payout: the reserved crypto goes to the partner and the fiat
+// amount is recorded as paid to the bank.
+func completeFiatPayout(ctx context.Context, p fiatPayoutRecord, txHash string, st offramp.Payout) error {
+	if st.FiatAmountMinor == nil {
+		return errors.New("partner reported the payout paid without a fiat amount")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	fiat := st.FiatAmountMinor.String()
+	res, err := tx.ExecContext(ctx, `
+		UPDATE fiat_payouts SET status = ?, fiat_amount_minor = ?, rate = COALESCE(NULLIF(?, ''), rate), last_error = NULL, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, fiatPayoutPaid, fiat, st.Rate, now, p.ID, fiatPayoutFunded)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := insertOfframpLedger(ctx, tx, p, p.Chain, p.Asset, p.AmountMinor, eventOfframpPaid, bucketOfframpPending, bucketOfframp, txHash, now); err != nil {
+		return err
+	}
+	if err := insertOfframpLedger(ctx, tx, p, "", p.FiatCurrency, fiat, eventFiatPaid, bucketOfframp, bucketFiatPaid, "", now); err != nil {
+		return err
+	}
+	updated, err := scanFiatPayout(tx.QueryRowContext(ctx, `SELECT `+fiatPayoutCols+` FROM fiat_payouts WHERE id = ?`, p.ID))
+	if err != nil {
+		return err
+	}
+	if err := enqueueEvent(ctx, tx, p.MerchantID, "fiat_payout", p.ID, webhookFiatPayoutPaid, updated); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=fiat_payout_paid id=%s merchant_id=%s amount_minor=%s %s fiat_amount_minor=%s %s", p.ID, p.MerchantID, p.AmountMinor, p.Asset, fiat, p.FiatCurrency)
+	return nil
+}
+
+// failFiatPayout marks p FAILED. An unfunded payout's reservation goes back to the merchant
+// balance; crypto already sent stays in offramp_pending until the partner returns it.
+func failFiatPayout(ctx context.Context, p fiatPayoutRecord, reason string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		UPDATE fiat_payouts SET status = ?, last_error = ?, updated_at = ? WHERE id = ? AND status = ?
+	`, fiatPayoutFailed, reason, now, p.ID, p.Status)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if !p.chainTxID.Valid {
+		if err := insertOfframpLedger(ctx, tx, p, p.Chain, p.Asset, p.AmountMinor, eventOfframpReleased, bucketOfframpPending, bucketSettlement, "", now); err != nil {
+			return err
+		}
+	}
+	updated, err := scanFiatPayout(tx.QueryRowContext(ctx, `SELECT `+fiatPayoutCols+` FROM fiat_payouts WHERE id = ?`, p.ID))
+	if err != nil {
+		return err
+	}
+	if err := enqueueEvent(ctx, tx, p.MerchantID, "fiat_payout", p.ID, webhookFiatPayoutFailed, updated); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=fiat_payout_failed id=%s merchant_id=%s funded=%t reason=%q", p.ID, p.MerchantID, p.chainTxID.Valid, reason)
+	return nil
+}
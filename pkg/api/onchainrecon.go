@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// custodyBuckets are the ledger buckets of funds the platform's wallets hold: merchants' funds not
+// paid out yet (unsettled, settled, frozen, reserved for fiat payouts or being converted),
+// overpayments not returned yet and the platform's fees.
+var custodyBuckets = []string{bucketMerchant, bucketSettlement, bucketDisputeHold, bucketBalanceHold, bucketOfframpPending, bucketConversion, bucketOverpayment, bucketPlatformFee}
+
+// On-chain reconciliation compares what the ledger says the custody wallets hold with what they
+// hold on-chain, per chain and asset.
+var (
+	reconMu           sync.Mutex
+	reconWallets      = map[string][]string{} // chain ("" for every chain) -> custody wallet addresses
+	reconToleranceBps int64
+	reconAlertURL     string
+	reconDrifting     = map[string]bool{} // chain/asset outside the tolerance at the last check
+
+	reconDriftAlertsTotal int64
+)
+
+// SetOnchainReconciliation configures the custody wallets to reconcile, by chain ("" for wallets
+// used on every chain), the drift tolerated in basis points of the booked balance and the URL
+// drift alerts are POSTed to. Without wallets reconciliation is off.
+func SetOnchainReconciliation(wallets map[string][]string, toleranceBps int64, alertURL string) {
+	reconMu.Lock()
+	defer reconMu.Unlock()
+	reconWallets = wallets
+	reconToleranceBps = toleranceBps
+	reconAlertURL = alertURL
+}
+
+// walletsOn returns the custody wallets on chain, with the cold wallet when balances are swept to
+// it.
+func walletsOn(chain string) []string {
+	reconMu.Lock()
+	wallets := append(append([]string{}, reconWallets[""]...), reconWallets[chain]...)
+	reconMu.Unlock()
+	if cold := coldWalletOn(chain); cold != "" && !slices.ContainsFunc(wallets, func(w string) bool { return strings.EqualFold(w, cold) }) {
+		wallets = append(wallets, cold)
+	}
+	return wallets
+}
+
+func reconConfigured() bool {
+	reconMu.Lock()
+	defer reconMu.Unlock()
+	return len(reconWallets) > 0
+}
+
+// StartOnchainReconciler reconciles the books with the chain every interval, alerting when a
+// chain and asset drift beyond the tolerance.
+func StartOnchainReconciler(interval time.Duration) {
+	if !reconConfigured() {
+		return
+	}
+	startScheduler(schedulerOnchainRecon, interval, false, func(ctx context.Context) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		report, err := reconcileOnchain(ctx)
+		if err != nil {
+			return 0, err
+		}
+		var lastErr error
+		for _, l := range report.Assets {
+			if l.Error != "" {
+				lastErr = fmt.Errorf("%s %s: %s", l.Chain, l.Asset, l.Error)
+			}
+		}
+		return len(report.Assets), lastErr
+	})
+}
+
+type walletBalance struct {
+	Address      string `json:"address"`
+	BalanceMinor string `json:"balance_minor,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type assetReconciliation struct {
+	Chain string `json:"chain"`
+	Asset string `json:"asset"`
+	// BooksMinor is what the custody wallets should hold: the custody buckets less what was sent
+	// out and is not booked yet (payouts SENT or EXECUTED, fiat payouts FUNDED).
+	BooksMinor         string            `json:"books_minor"`
+	Buckets            map[string]string `json:"buckets"` // net of each custody bucket
+	PaidOutMinor       string            `json:"paid_out_minor"`
+	OfframpFundedMinor string            `json:"offramp_funded_minor"`
+	ChainMinor         string            `json:"chain_minor,omitempty"` // sum of the wallets' balances
+	Wallets            []walletBalance   `json:"wallets"`
+	// DriftMinor is chain_minor - books_minor: negative when the wallets hold less than the books.
+	DriftMinor string `json:"drift_minor,omitempty"`
+	DriftBps   *int64 `json:"drift_bps,omitempty"` // of books_minor; omitted when the books are zero or the drift is vast
+	Drifting   bool   `json:"drifting"`            // beyond the tolerance
+	Error      string `json:"error,omitempty"`     // a wallet balance could not be read
+}
+
+type onchainReconResp struct {
+	CheckedAt    string                `json:"checked_at"`
+	ToleranceBps int64                 `json:"tolerance_bps"`
+	Assets       []assetReconciliation `json:"assets"`
+}
+
+// reconcileOnchain builds the drift report, records which chains and assets drift and alerts on
+// those that just started to.
+func reconcileOnchain(ctx context.Context) (onchainReconResp, error) {
+	report, err := onchainReconciliation(ctx)
+	if err != nil {
+		return report, err
+	}
+	reconMu.Lock()
+	alertURL := reconAlertURL
+	reconMu.Unlock()
+	for _, line := range report.Assets {
+		if line.Error != "" {
+			continue // keep the last state until the chain can be read again
+		}
+		id := line.Chain + "/" + line.Asset
+		reconMu.Lock()
+		started := line.Drifting && !reconDrifting[id]
+		reconDrifting[id] = line.Drifting
+		reconMu.Unlock()
+		if started {
+			atomic.AddInt64(&reconDriftAlertsTotal, 1)
+			log.Printf("event=reconciliation_drift chain=%s asset=%s books_minor=%s chain_minor=%s drift_minor=%s",
+				line.Chain, line.Asset, line.BooksMinor, line.ChainMinor, line.DriftMinor)
+			go sendOperatorAlert(alertURL, "reconciliation.drift", line)
+		}
+	}
+	return report, nil
+}
+
+// onchainReconciliation compares the books with the custody wallets' balances, per chain and asset.
+func onchainReconciliation(ctx context.Context) (onchainReconResp, error) {
+	type key struct{ chain, asset string }
+	type books struct {
+		buckets         map[string]*big.Int
+		paidOut, funded *big.Int
+	}
+	byKey := map[key]*books{}
+	get := func(chain, asset string) *books {
+		k := key{strings.ToUpper(chain), strings.ToUpper(asset)}
+		if byKey[k] == nil {
+			byKey[k] = &books{buckets: map[string]*big.Int{}, paidOut: new(big.Int), funded: new(big.Int)}
+			for _, b := range custodyBuckets {
+				byKey[k].buckets[b] = new(big.Int)
+			}
+		}
+		return byKey[k]
+	}
+	add := func(query string, args []any, to func(b *books, bucket string) *big.Int) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var chain, asset, bucket, amount string
+			if err := rows.Scan(&chain, &asset, &bucket, &amount); err != nil {
+				return err
+			}
+			v, ok := new(big.Int).SetString(amount, 10)
+			if !ok {
+				return fmt.Errorf("invalid amount %q", amount)
+			}
+			sum := to(get(chain, asset), bucket)
+			sum.Add(sum, v)
+		}
+		return rows.Err()
+	}
+	in := `(?` + strings.Repeat(`, ?`, len(custodyBuckets)-1) + `)`
+	args := make([]any, len(custodyBuckets))
+	for i, b := range custodyBuckets {
+		args[i] = b
+	}
+	// Fiat amounts are booked without a chain
+	if err := add(`SELECT chain, asset, bucket, balance_minor FROM ledger_balances WHERE chain != '' AND bucket IN `+in, args,
+		func(b *books, bucket string) *big.Int { return b.buckets[bucket] }); err != nil {
+		return onchainReconResp{}, err
+	}
+	if err := add(`SELECT chain, asset, '', amount_minor FROM payouts WHERE status IN (?, ?)`, []any{payoutSent, payoutExecuted},
+		func(b *books, _ string) *big.Int { return b.paidOut }); err != nil {
+		return onchainReconResp{}, err
+	}
+	if err := add(`SELECT chain, asset, '', amount_minor FROM fiat_payouts WHERE status = ?`, []any{fiatPayoutFunded},
+		func(b *books, _ string) *big.Int { return b.funded }); err != nil {
+		return onchainReconResp{}, err
+	}
+
+	reconMu.Lock()
+	tolerance := reconToleranceBps
+	reconMu.Unlock()
+	keys := make([]key, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].chain < keys[j].chain || (keys[i].chain == keys[j].chain && keys[i].asset < keys[j].asset)
+	})
+	report := onchainReconResp{CheckedAt: time.Now().UTC().Format(time.RFC3339), ToleranceBps: tolerance, Assets: []assetReconciliation{}}
+	for _, k := range keys {
+		b := byKey[k]
+		expected := new(big.Int)
+		line := assetReconciliation{Chain: k.chain, Asset: k.asset, Buckets: map[string]string{}, Wallets: []walletBalance{}}
+		for bucket, v := range b.buckets {
+			expected.Add(expected, v)
+			line.Buckets[bucket] = v.String()
+		}
+		expected.Sub(expected, b.paidOut)
+		expected.Sub(expected, b.funded)
+		line.BooksMinor, line.PaidOutMinor, line.OfframpFundedMinor = expected.String(), b.paidOut.String(), b.funded.String()
+
+		onChain := new(big.Int)
+		for _, addr := range walletsOn(k.chain) {
+			wb := walletBalance{Address: addr}
+			v, err := blockchain.AssetBalance(ctx, k.chain, k.asset, addr)
+			if err != nil {
+				wb.Error = err.Error()
+				line.Error = addr + ": " + err.Error()
+			} else {
+				wb.BalanceMinor = v.String()
+				onChain.Add(onChain, v)
+			}
+			line.Wallets = append(line.Wallets, wb)
+		}
+		if len(line.Wallets) == 0 {
+			line.Error = "no custody wallet on " + k.chain
+		}
+		if line.Error == "" {
+			drift := new(big.Int).Sub(onChain, expected)
+			line.ChainMinor, line.DriftMinor = onChain.String(), drift.String()
+			if expected.Sign() != 0 {
+				if bps := new(big.Int).Quo(new(big.Int).Mul(drift, big.NewInt(10000)), expected); bps.IsInt64() {
+					v := bps.Int64()
+					line.DriftBps = &v
+				}
+			}
+			// |drift| / books > tolerance / 10000, without dividing
+			lhs := new(big.Int).Mul(new(big.Int).Abs(drift), big.NewInt(10000))
+			line.Drifting = lhs.Cmp(new(big.Int).Mul(new(big.Int).Abs(expected), big.NewInt(tolerance))) > 0
+		}
+		report.Assets = append(report.Assets, line)
+	}
+	return report, nil
+}
+
+// reconDriftingAssets counts the chains and assets drifting at the last check, for /debug/metrics.
+func reconDriftingAssets() int64 {
+	reconMu.Lock()
+	defer reconMu.Unlock()
+	var n int64
+	for _, drifting := range reconDrifting {
+		if drifting {
+			n++
+		}
+	}
+	return n
+}
+
+// OnchainReconciliationHandler godoc
+// @Summary      Reconcile the books with the chain
+// @Description  Compares, per chain and asset, what the ledger says the custody wallets hold with their balances on-chain, read now through the chain's RPC endpoint. The books are the net of the custody buckets (merchant, settlement, dispute_hold, balance_hold, offramp_pending, conversion and platform_fee, across merchants) less payouts SENT or EXECUTED and fiat payouts FUNDED, which have left the wallets. drift_minor is the on-chain total less the books, negative when the wallets hold less; drifting is set beyond RECONCILE_TOLERANCE_BPS of the books, and a chain and asset that starts drifting is alerted once (reconciliation.drift). The custody wallets are RECONCILE_WALLETS, or the hot wallet. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  onchainReconResp
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/reconciliation/onchain [get]
+func OnchainReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	if !reconConfigured() {
+		writeProblem(w, http.StatusConflict, CodeReconciliationNotConfigured, "set RECONCILE_WALLETS or HOT_WALLET_ADDRESS")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpReconcile)
+	defer cancel()
+	report, err := reconcileOnchain(ctx)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// The order cache answers GET /orders/{id}, which checkout pages poll, from memory. It is off
+// unless SetOrderCacheTTL enables it, and local to the process: with several instances each caches
+// on its own and a change made by another instance shows after at most the TTL.
+//
+// Changes invalidate an order before their transaction commits, usually when enqueueing its
+// event. For orderCacheSettle afterwards reads bypass the cache, so a read racing the commit
+// cannot put the old state back.
+const (
+	orderCacheSettle     = 5 * time.Second
+	orderCacheMaxEntries = 10000
+)
+
+type orderCacheEntry struct {
+	order   store.Order
+	items   []lineItem
+	expires time.Time
+}
+
+var (
+	orderCacheMu      sync.Mutex
+	orderCacheTTL     time.Duration
+	orderCacheEntries = map[string]orderCacheEntry{}
+	orderCacheDirty   = map[string]time.Time{} // order id -> end of its settle window
+	orderCacheFlushed time.Time                // end of the settle window of the last flush, for all orders
+
+	orderCacheHits, orderCacheMisses int64
+)
+
+// SetOrderCacheTTL sets how long order reads are cached; zero turns the cache off.
+func SetOrderCacheTTL(ttl time.Duration) {
+	orderCacheMu.Lock()
+	defer orderCacheMu.Unlock()
+	orderCacheTTL = ttl
+	orderCacheEntries = map[string]orderCacheEntry{}
+}
+
+// cachedOrder returns the order with its line items, from the cache when possible. merchantID
+// scopes the lookup like stores.Orders.Get.
+func cachedOrder(ctx context.Context, id, merchantID string) (store.Order, []lineItem, error) {
+	now := time.Now()
+	orderCacheMu.Lock()
+	ttl := orderCacheTTL
+	e, ok := orderCacheEntries[id]
+	orderCacheMu.Unlock()
+	if ttl > 0 && ok && now.Before(e.expires) && (merchantID == "" || e.order.MerchantID == merchantID) {
+		atomic.AddInt64(&orderCacheHits, 1)
+		return e.order, e.items, nil
+	}
+	if ttl > 0 {
+		atomic.AddInt64(&orderCacheMisses, 1)
+	}
+
+	o, err := stores.Orders.Get(ctx, id, merchantID)
+	if err != nil {
+		return store.Order{}, nil, err
+	}
+	items, err := loadLineItems(ctx, db, o.ID)
+	if err != nil {
+		return store.Order{}, nil, err
+	}
+	if ttl > 0 {
+		orderCacheMu.Lock()
+		if until, dirty := orderCacheDirty[id]; (dirty && now.Before(until)) || now.Before(orderCacheFlushed) {
+			orderCacheMu.Unlock()
+			return o, items[o.ID], nil
+		}
+		delete(orderCacheDirty, id)
+		if len(orderCacheEntries) >= orderCacheMaxEntries {
+			pruneOrderCache(now)
+		}
+		if len(orderCacheEntries) < orderCacheMaxEntries {
+			orderCacheEntries[id] = orderCacheEntry{order: o, items: items[o.ID], expires: now.Add(ttl)}
+		}
+		orderCacheMu.Unlock()
+	}
+	return o, items[o.ID], nil
+}
+
+// invalidateOrder drops cached orders after a change to them.
+func invalidateOrder(ids ...string) {
+	orderCacheMu.Lock()
+	defer orderCacheMu.Unlock()
+	if orderCacheTTL <= 0 {
+		return
+	}
+	now := time.Now()
+	if len(orderCacheDirty) >= orderCacheMaxEntries {
+		pruneOrderCache(now)
+	}
+	until := now.Add(orderCacheSettle)
+	for _, id := range ids {
+		delete(orderCacheEntries, id)
+		orderCacheDirty[id] = until
+	}
+}
+
+// flushOrderCache drops every cached order, e.g. before customer data is erased.
+func flushOrderCache() {
+	orderCacheMu.Lock()
+	defer orderCacheMu.Unlock()
+	orderCacheEntries = map[string]orderCacheEntry{}
+	orderCacheFlushed = time.Now().Add(orderCacheSettle)
+}
+
+// pruneOrderCache drops expired entries and settle windows that ended. Callers hold orderCacheMu.
+func pruneOrderCache(now time.Time) {
+	for id, e := range orderCacheEntries {
+		if !now.Before(e.expires) {
+			delete(orderCacheEntries, id)
+		}
+	}
+	for id, until := range orderCacheDirty {
+		if !now.Before(until) {
+			delete(orderCacheDirty, id)
+		}
+	}
+}
@@ -2,24 +2,61 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"log"
+	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/jobs"
+	"github.com/oxzoid/OSPay/pkg/store"
 )
 
-var ordersCreatedTotal int64
+// db and stores are set by api.Init(database *sql.DB) in main.go. Creating, reading, listing and
+// searching orders, creating merchants and resolving their API keys, and the ledger's postings and
+// balances go through stores; order status changes and every other table are still queried on db
+// directly. reportDB and
+// reportStores serve the lists, searches, stats, exports and reconciliation that only read: they
+// are db and stores unless SetReadReplica moves them to a replica.
+var (
+	db     *sql.DB
+	stores store.Stores
 
-// db is set by api.Init(database *sql.DB) in main.go
-var db *sql.DB
+	reportDB     *sql.DB
+	reportStores store.Stores
+)
+
+// Init is called from main.go after opening the DB connection. It wires the SQL stores on top of
+// database; use InitStores to supply other implementations.
+func Init(database *sql.DB) { InitStores(database, store.NewSQL(database)) }
+
+// InitStores sets the database and the stores the handlers use. Handlers that only read through
+// stores, such as MerchantBalancesHandler, run on fakes with a nil database; most still need one.
+func InitStores(database *sql.DB, s store.Stores) {
+	db, reportDB = database, database
+	stores, reportStores = s, s
+	jobQueue = jobs.New(database)
+}
 
-// Init is called from main.go after opening the DB connection.
-func Init(database *sql.DB) { db = database }
+// SetReadReplica sends the reporting reads to replica, a read-only copy of the database, to keep
+// them off the primary that takes payment writes. What they return may lag the primary by the
+// replication delay; anything that writes, or decides a write, keeps reading the primary.
+func SetReadReplica(replica *sql.DB) {
+	reportDB, reportStores = replica, store.NewSQL(replica)
+}
+
+// txStores returns the stores bound to tx, for writes that must commit together with other
+// statements of the transaction.
+func txStores(tx *sql.Tx) store.Stores { return store.NewSQL(tx) }
 
 // ---------- helpers (scoped to this file to avoid name clashes) ----------
 
@@ -39,16 +76,37 @@ func isValidAmountString(s string) bool {
 
 type orderCreateReq struct {
 	MerchantID     string `json:"merchant_id"`
-	AmountMinor    string `json:"amount_minor"` // String to handle large 18-decimal numbers
-	Asset          string `json:"asset"`        // e.g., "USDC"
-	Chain          string `json:"chain"`        // e.g., "polygon-amoy"
-	IdempotencyKey string `json:"idempotency_key"`
+	AmountMinor    string `json:"amount_minor"`                    // String to handle large 18-decimal numbers
+	Asset          string `json:"asset" validate:"required,asset"` // e.g., "USDC"
+	Chain          string `json:"chain" validate:"required,chain"` // e.g., "POLYGON"
+	IdempotencyKey string `json:"idempotency_key" validate:"max=255"`
+	// CustomerWalletAddress is optional; when given, per-wallet velocity limits apply at creation.
+	CustomerWalletAddress string          `json:"customer_wallet_address,omitempty" validate:"max=128"`
+	CustomerEmail         string          `json:"customer_email,omitempty" validate:"max=254"`
+	Metadata              json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`        // free-form JSON object
+	ExternalOrderID       string          `json:"external_order_id,omitempty" validate:"max=128"` // the merchant's own order reference
+	// CouponCode is one of the merchant's coupons; its discount is taken off amount_minor.
+	CouponCode string `json:"coupon_code,omitempty" validate:"max=64"`
+	// LineItems are what is being bought; amount_minor may then be omitted and defaults to their sum.
+	LineItems []lineItem `json:"line_items,omitempty" validate:"max=100"`
+	// WebhookURL receives the order's events instead of the merchant's webhook URL, signed with
+	// WebhookSecret; one is generated when it is omitted.
+	WebhookURL    string `json:"webhook_url,omitempty" validate:"max=2000"`
+	WebhookSecret string `json:"webhook_secret,omitempty" validate:"max=200"`
+	// RateQuoteID and FiatAmount record a fiat price: the quote_id of the /rates quote between
+	// asset and the fiat currency that amount_minor was converted with, and the price in that
+	// currency. The quote is kept on the order and its ledger entries.
+	RateQuoteID string `json:"rate_quote_id,omitempty" validate:"max=64"`
+	FiatAmount  string `json:"fiat_amount,omitempty" validate:"max=50"`
 }
 
 type orderCreateResp struct {
-	OrderID        string `json:"order_id"`
-	DepositAddress string `json:"deposit_address"`
-	Status         string `json:"status"`
+	OrderID        string  `json:"order_id"`
+	DepositAddress string  `json:"deposit_address"`
+	Status         string  `json:"status"`
+	AmountMinor    string  `json:"amount_minor"`             // amount due, after any discount
+	DiscountMinor  *string `json:"discount_minor,omitempty"` // taken off by coupon_code
+	WebhookSecret  string  `json:"webhook_secret,omitempty"` // generated for webhook_url; only in the response that created the order
 }
 
 type orderGetResp struct {
@@ -60,16 +118,33 @@ type orderGetResp struct {
 	Status         string  `json:"status"`
 	DepositAddress string  `json:"deposit_address"`
 	TxHash         *string `json:"tx_hash,omitempty"`
-	ConfirmedBlock *int64  `json:"confirmed_block,omitempty"`
+	ConfirmedBlock *int64  `json:"confirmed_block,omitempty"` // block that mined the verified payment
+	BlockTimestamp *string `json:"block_timestamp,omitempty"` // and its time
 	PaidAt         *string `json:"paid_at,omitempty"`
 	CreatedAt      string  `json:"created_at"`
-}
+	ExpiresAt      *string `json:"expires_at,omitempty"` // PENDING orders become EXPIRED after this
+	// CustomerWalletAddress is the sender of the verified payment transfer; refunds go back to it.
+	CustomerWalletAddress *string         `json:"customer_wallet_address,omitempty"`
+	CustomerEmail         *string         `json:"customer_email,omitempty"`
+	Metadata              json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	ExternalOrderID       *string         `json:"external_order_id,omitempty"`
+	// Risk fields are set when the payment is confirmed; REVIEW orders wait for an admin decision.
+	RiskReason  *string `json:"risk_reason,omitempty"`
+	RiskScore   *int64  `json:"risk_score,omitempty"`
+	RiskFactors *string `json:"risk_factors,omitempty"`
 
-func writeErrorJSON(w http.ResponseWriter, code int, errStr, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": errStr, "message": msg})
+	ApplicationFeeMinor *string `json:"application_fee_minor,omitempty"`
+	// CouponCode and DiscountMinor are set when a coupon was redeemed; amount_minor is net of the discount.
+	CouponCode    *string `json:"coupon_code,omitempty"`
+	DiscountMinor *string `json:"discount_minor,omitempty"`
+	// Mispayment is the transfer in the wrong token or on the wrong chain of a MISPAID order.
+	Mispayment *mispayment `json:"mispayment,omitempty"`
+	// FiatPricing is the fiat price and rate quote of an order created with rate_quote_id.
+	FiatPricing *fiatPricing `json:"fiat_pricing,omitempty"`
+	LineItems   []lineItem   `json:"line_items,omitempty"`
+	Tags        []string     `json:"tags,omitempty"` // in list, search and batch-get results
 }
+
 func writeJSONOrders(w http.ResponseWriter, code int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -77,11 +152,20 @@ func writeJSONOrders(w http.ResponseWriter, code int, v any) {
 }
 
 func badReq(w http.ResponseWriter, msg string) {
-	writeErrorJSON(w, http.StatusBadRequest, "bad_request", msg)
+	writeProblem(w, http.StatusBadRequest, CodeBadRequest, msg)
 }
 
 func serverErr(w http.ResponseWriter, err error) {
-	writeErrorJSON(w, http.StatusInternalServerError, "internal_error", err.Error())
+	writeProblem(w, http.StatusInternalServerError, CodeInternalError, err.Error())
+}
+
+// pathID returns the {id} path parameter of a /v1 route, or the ?id= query parameter of the
+// legacy route.
+func pathID(r *http.Request) string {
+	if id := r.PathValue("id"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("id")
 }
 
 // a simple placeholder deposit address (looks like 0x + 40 hex chars)
@@ -99,8 +183,8 @@ func makeDepositAddress() string {
 // @Produce      json
 // @Param        order  body  orderCreateReq  true  "Order info"
 // @Success      200  {object}  orderCreateResp
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Security     ApiKeyAuth
 // CreateOrderHandler godoc
 // @Summary      Create a new order
@@ -110,98 +194,249 @@ func makeDepositAddress() string {
 // @Produce      json
 // @Param        order  body  orderCreateReq  true  "Order info"
 // @Success      200  {object}  orderCreateResp
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Security     ApiKeyAuth
 // @Router       /orders [post]
 func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	if db == nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_not_initialized", "db not initialized")
+		writeProblem(w, http.StatusInternalServerError, CodeDBNotInitialized, "db not initialized")
 		return
 	}
 
 	var req orderCreateReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, "invalid_json", "invalid JSON body")
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if authID := merchantIDFromContext(r.Context()); authID != "" {
+		if req.MerchantID == "" {
+			req.MerchantID = authID
+		} else if req.MerchantID != authID {
+			writeProblem(w, http.StatusForbidden, CodeMerchantMismatch, "merchant_id does not match the authenticated merchant")
+			return
+		}
+	}
+	if err := resolveLineItems(req.LineItems, &req.AmountMinor); err != nil {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidLineItems, err.Error())
 		return
 	}
 	if req.MerchantID == "" || !isValidAmountString(req.AmountMinor) || req.Asset == "" || req.Chain == "" {
-		writeErrorJSON(w, http.StatusBadRequest, "missing_fields", "merchant_id, amount_minor (>0), asset, chain are required")
+		writeProblem(w, http.StatusBadRequest, CodeMissingFields, "merchant_id, amount_minor (>0), asset, chain are required")
 		return
 	}
 
 	if req.IdempotencyKey == "" {
-		writeErrorJSON(w, http.StatusBadRequest, "missing_idempotency_key", "idempotency_key is required")
+		writeProblem(w, http.StatusBadRequest, CodeMissingIdempotencyKey, "idempotency_key is required")
+		return
+	}
+	if !isJSONObject(req.Metadata) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidMetadata, "metadata must be a JSON object")
+		return
+	}
+	if req.WebhookURL != "" && !validWebhookURL(req.WebhookURL) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidWebhookURL, "webhook_url must be an absolute http(s) URL of a public host")
+		return
+	}
+	if req.WebhookSecret != "" && (req.WebhookURL == "" || len(req.WebhookSecret) < minWebhookSecretLen) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidWebhookSecret, "webhook_secret needs a webhook_url and at least "+strconv.Itoa(minWebhookSecretLen)+" characters")
 		return
 	}
 
-	// Check for existing order with this idempotency key
-	const sel = `SELECT id, deposit_address, status FROM orders WHERE order_idempotency_key = ? AND merchant_id = ?`
-	var existingID, existingDeposit, existingStatus string
-	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
 	defer cancel()
-	err := db.QueryRowContext(ctx, sel, req.IdempotencyKey, req.MerchantID).Scan(&existingID, &existingDeposit, &existingStatus)
-	if err == nil {
-		// Order already exists, return it
-		writeJSONOrders(w, http.StatusOK, orderCreateResp{
-			OrderID:        existingID,
-			DepositAddress: existingDeposit,
-			Status:         existingStatus,
-		})
+	resp, err := createOrderRecord(ctx, req, "")
+	if err != nil {
+		writeOrderCreateError(w, err)
+		return
+	}
+	if resp.WebhookSecret != "" {
+		noStore(w)
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// writeOrderCreateError maps an error of createOrderRecord to its problem response.
+func writeOrderCreateError(w http.ResponseWriter, err error) {
+	var le *limitError
+	if errors.As(err, &le) {
+		writeProblem(w, http.StatusUnprocessableEntity, CodeLimitExceeded, le.Error())
 		return
-	} else if err != sql.ErrNoRows {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+	}
+	if errors.Is(err, errMerchantNotFound) {
+		writeProblem(w, http.StatusBadRequest, CodeMerchantNotFound, "merchant not found")
 		return
 	}
+	var ce *couponError
+	if errors.As(err, &ce) {
+		writeProblem(w, http.StatusUnprocessableEntity, CodeCouponInvalid, ce.Error())
+		return
+	}
+	var rqe *rateQuoteError
+	if errors.As(err, &rqe) {
+		writeProblem(w, http.StatusUnprocessableEntity, CodeInvalidRateQuote, rqe.Error())
+		return
+	}
+	var ae *assetNotAcceptedError
+	if errors.As(err, &ae) {
+		writeProblem(w, http.StatusBadRequest, CodeAssetNotAccepted, ae.Error())
+		return
+	}
+	var we *walletError
+	if errors.As(err, &we) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidWalletAddress, we.Error())
+		return
+	}
+	writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+}
 
-	id := uuid.New().String()
+var errMerchantNotFound = errors.New("merchant not found")
 
-	var merchantWalletAddress string
-	err = db.QueryRowContext(ctx, `SELECT merchant_wallet_address FROM merchants WHERE id = ?`, req.MerchantID).Scan(&merchantWalletAddress)
-	if err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, "merchant_not_found", "merchant not found")
-		return
+// walletError rejects an order whose customer wallet, or the merchant wallet it is paid to, is not
+// an address on the order's chain.
+type walletError struct{ Msg string }
+
+func (e *walletError) Error() string { return e.Msg }
+
+// isJSONObject reports whether raw is empty or a JSON object.
+func isJSONObject(raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return true
 	}
+	var m map[string]any
+	return json.Unmarshal(raw, &m) == nil && m != nil
+}
 
-	deposit := merchantWalletAddress
-	status := "PENDING"
-	now := time.Now().UTC().Format(time.RFC3339)
+// createOrderRecord inserts a PENDING order, or returns the existing one for a repeated idempotency key.
+// applicationFee is the platform fee (minor units) withheld from the merchant on payment; "" means none.
+func createOrderRecord(ctx context.Context, req orderCreateReq, applicationFee string) (orderCreateResp, error) {
+	// Check for existing order with this idempotency key
+	existing, err := stores.Orders.GetByIdempotencyKey(ctx, req.MerchantID, req.IdempotencyKey)
+	if err == nil {
+		// Order already exists, return it
+		return createdOrder(existing), nil
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return orderCreateResp{}, err
+	}
 
-	const insert = `
-		INSERT INTO orders
-		  (id, merchant_id, amount_minor, asset, chain, status, deposit_address, created_at, order_idempotency_key)
-		VALUES
-		  (?,  ?,           ?,            ?,     ?,     ?,      ?,               ?,      ?)
-	`
-	_, err = db.ExecContext(ctx, insert, id, req.MerchantID, req.AmountMinor, req.Asset, req.Chain, status, deposit, now, req.IdempotencyKey)
+	merchant, err := stores.Merchants.Get(ctx, req.MerchantID)
 	if err != nil {
-		// If unique constraint error, fetch and return existing order
-		if sqliteIsUniqueConstraintError(err) {
-			err2 := db.QueryRowContext(ctx, sel, req.IdempotencyKey, req.MerchantID).Scan(&existingID, &existingDeposit, &existingStatus)
-			if err2 == nil {
-				writeJSONOrders(w, http.StatusOK, orderCreateResp{
-					OrderID:        existingID,
-					DepositAddress: existingDeposit,
-					Status:         existingStatus,
-				})
-				return
+		return orderCreateResp{}, errMerchantNotFound
+	}
+	if _, err := blockchain.NormalizeAddress(req.Chain, merchant.WalletAddress); err != nil {
+		return orderCreateResp{}, &walletError{"the merchant wallet is not a " + strings.ToUpper(req.Chain) + " address, so it cannot receive payments on " + strings.ToUpper(req.Chain)}
+	}
+	if err := checkAcceptedAsset(ctx, db, req.MerchantID, req.Asset, req.Chain); err != nil {
+		return orderCreateResp{}, err
+	}
+	pricing, err := priceOrder(ctx, db, req.MerchantID, req.Asset, req.RateQuoteID, req.FiatAmount)
+	if err != nil {
+		return orderCreateResp{}, err
+	}
+	if req.CustomerWalletAddress != "" {
+		if req.CustomerWalletAddress, err = blockchain.NormalizeAddress(req.Chain, req.CustomerWalletAddress); err != nil {
+			return orderCreateResp{}, &walletError{"customer_wallet_address is not a " + strings.ToUpper(req.Chain) + " address: " + err.Error()}
+		}
+	}
+	// A coupon discount comes off the price before limits apply; amount_minor is stored net of it
+	var quote couponQuote
+	if req.CouponCode != "" {
+		if quote, err = quoteCoupon(ctx, db, req.MerchantID, req.CouponCode, req.Asset, req.AmountMinor); err != nil {
+			return orderCreateResp{}, err
+		}
+		amount, _ := new(big.Int).SetString(req.AmountMinor, 10)
+		req.AmountMinor = amount.Sub(amount, quote.Discount).String()
+	}
+	if err := checkOrderLimits(ctx, db, req.MerchantID, req.Asset, req.AmountMinor, req.CustomerWalletAddress); err != nil {
+		var le *limitError
+		if errors.As(err, &le) {
+			recordAudit(ctx, db, actorFromContext(ctx), req.MerchantID, "", "limit_exceeded", map[string]string{
+				"stage": "order_create", "limit": le.Limit, "message": le.Msg, "amount_minor": req.AmountMinor, "asset": req.Asset,
+			})
+		}
+		return orderCreateResp{}, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(OrderTTL).Format(time.RFC3339)
+	o := store.Order{
+		ID:                    uuid.New().String(),
+		MerchantID:            req.MerchantID,
+		AmountMinor:           req.AmountMinor,
+		Asset:                 req.Asset,
+		Chain:                 req.Chain,
+		Status:                "PENDING",
+		DepositAddress:        merchant.WalletAddress,
+		IdempotencyKey:        req.IdempotencyKey,
+		CreatedAt:             now.Format(time.RFC3339),
+		ExpiresAt:             &expiresAt,
+		ApplicationFeeMinor:   optionalString(applicationFee),
+		CustomerWalletAddress: optionalString(req.CustomerWalletAddress),
+		CustomerEmail:         optionalString(req.CustomerEmail),
+		Metadata:              optionalString(string(req.Metadata)),
+		ExternalOrderID:       optionalString(req.ExternalOrderID),
+		WebhookURL:            optionalString(req.WebhookURL),
+	}
+	if o.FiatPricing, err = fiatPricingJSON(pricing); err != nil {
+		return orderCreateResp{}, err
+	}
+	generatedSecret := ""
+	if req.WebhookURL != "" {
+		if req.WebhookSecret == "" {
+			if req.WebhookSecret, err = newWebhookSecret(); err != nil {
+				return orderCreateResp{}, err
 			}
+			generatedSecret = req.WebhookSecret
+		}
+		o.WebhookSecret = &req.WebhookSecret
+	}
+	if quote.ID != "" {
+		discount := quote.Discount.String()
+		o.CouponCode, o.DiscountMinor = &quote.Code, &discount
+		if err := redeemCoupon(ctx, quote.ID); err != nil {
+			return orderCreateResp{}, err
 		}
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
-		return
 	}
+	if err := createOrder(ctx, o, req.LineItems); err != nil {
+		if quote.ID != "" {
+			releaseCoupon(ctx, quote.ID)
+		}
+		// A concurrent request with the same idempotency key won the insert; return its order
+		if errors.Is(err, store.ErrDuplicate) {
+			if existing, err2 := stores.Orders.GetByIdempotencyKey(ctx, req.MerchantID, req.IdempotencyKey); err2 == nil {
+				return createdOrder(existing), nil
+			}
+		}
+		return orderCreateResp{}, err
+	}
+
+	log.Printf("event=order_created order_id=%s merchant_id=%s asset=%s amount_minor=%s status=%s", o.ID, req.MerchantID, req.Asset, req.AmountMinor, o.Status)
+	ordersCreatedTotal.inc()
+	resp := createdOrder(o)
+	resp.WebhookSecret = generatedSecret
+	return resp, nil
+}
+
+func createdOrder(o store.Order) orderCreateResp {
+	return orderCreateResp{
+		OrderID:        o.ID,
+		DepositAddress: o.DepositAddress,
+		Status:         o.Status,
+		AmountMinor:    o.AmountMinor,
+		DiscountMinor:  o.DiscountMinor,
+	}
+}
 
-	log.Printf("event=order_created order_id=%s merchant_id=%s asset=%s amount_minor=%s status=%s", id, req.MerchantID, req.Asset, req.AmountMinor, status)
-	ordersCreatedTotal++
-	writeJSONOrders(w, http.StatusOK, orderCreateResp{
-		OrderID:        id,
-		DepositAddress: deposit,
-		Status:         status,
-	})
+// optionalString maps "" to nil for nullable store fields.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
 
 // sqliteIsUniqueConstraintError checks if an error is a SQLite unique constraint violation.
@@ -215,20 +450,22 @@ func sqliteIsUniqueConstraintError(err error) bool {
 
 // GetOrderHandler godoc
 // @Summary      Get order by ID
-// @Description  Returns order details for a given order ID
+// @Description  Returns order details for a given order ID. The response carries an ETag computed from the response body, so it changes whenever any field of the order does; pollers sending it back in If-None-Match get 304 Not Modified until then.
 // @Tags         orders
 // @Accept       json
 // @Produce      json
-// @Param        id  query  string  true  "Order ID"
+// @Param        id             query   string  true   "Order ID"
+// @Param        If-None-Match  header  string  false  "ETag of a previous response"
 // @Success      200  {object}  orderGetResp
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Success      304  "Not modified"
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Security     ApiKeyAuth
 // @Router       /orders/get [get]
 func GetOrderHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSONOrders(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
 		return
 	}
 	if db == nil {
@@ -236,69 +473,436 @@ func GetOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id := r.URL.Query().Get("id")
+	id := pathID(r)
 	if id == "" {
 		badReq(w, "missing query param: id")
 		return
 	}
 
-	const sel = `
-		SELECT id, merchant_id, amount_minor, asset, chain, status, deposit_address,
-		       tx_hash, confirmed_block, paid_at, created_at
-		FROM orders
-		WHERE id = ?
-	`
-	var (
-		resp           orderGetResp
-		txHash         sql.NullString
-		confirmedBlock sql.NullInt64
-		paidAt         sql.NullString
-	)
-	ctx2, cancel2 := context.WithTimeout(r.Context(), 3*time.Second)
+	ctx2, cancel2 := store.WithTimeout(r.Context(), store.OpRead)
 	defer cancel2()
-	err := db.QueryRowContext(ctx2, sel, id).Scan(
-		&resp.ID, &resp.MerchantID, &resp.AmountMinor, &resp.Asset, &resp.Chain, &resp.Status, &resp.DepositAddress,
-		&txHash, &confirmedBlock, &paidAt, &resp.CreatedAt,
-	)
+	o, items, err := cachedOrder(ctx2, id, merchantIDFromContext(r.Context()))
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeJSONOrders(w, http.StatusNotFound, map[string]string{"error": "order not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
 			return
 		}
 		serverErr(w, err)
 		return
 	}
 
-	if txHash.Valid {
-		resp.TxHash = &txHash.String
+	resp := orderResponse(o)
+	resp.LineItems = items
+	body, err := json.Marshal(resp)
+	if err != nil {
+		serverErr(w, err)
+		return
 	}
-	if confirmedBlock.Valid {
-		val := confirmedBlock.Int64
-		resp.ConfirmedBlock = &val
+	etag := orderETag(body)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
-	if paidAt.Valid {
-		val := paidAt.String
-		resp.PaidAt = &val
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// orderETag derives a strong ETag from the serialized order, so that two responses with the same
+// tag are byte for byte the same.
+func orderETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag, using the weak
+// comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// orderResponse maps a stored order to its API representation.
+func orderResponse(o store.Order) orderGetResp {
+	resp := orderGetResp{
+		ID:                    o.ID,
+		MerchantID:            o.MerchantID,
+		AmountMinor:           o.AmountMinor,
+		Asset:                 o.Asset,
+		Chain:                 o.Chain,
+		Status:                o.Status,
+		DepositAddress:        o.DepositAddress,
+		TxHash:                o.TxHash,
+		ConfirmedBlock:        o.ConfirmedBlock,
+		BlockTimestamp:        o.BlockTimestamp,
+		PaidAt:                o.PaidAt,
+		CreatedAt:             o.CreatedAt,
+		ExpiresAt:             o.ExpiresAt,
+		CustomerWalletAddress: o.CustomerWalletAddress,
+		CustomerEmail:         o.CustomerEmail,
+		ExternalOrderID:       o.ExternalOrderID,
+		RiskReason:            o.RiskReason,
+		RiskScore:             o.RiskScore,
+		RiskFactors:           o.RiskFactors,
+		ApplicationFeeMinor:   o.ApplicationFeeMinor,
+		CouponCode:            o.CouponCode,
+		DiscountMinor:         o.DiscountMinor,
+	}
+	if o.Metadata != nil {
+		resp.Metadata = json.RawMessage(*o.Metadata)
+	}
+	if o.Mispayment != nil {
+		var m mispayment
+		if json.Unmarshal([]byte(*o.Mispayment), &m) == nil {
+			resp.Mispayment = &m
+		}
+	}
+	if o.FiatPricing != nil {
+		var p fiatPricing
+		if json.Unmarshal([]byte(*o.FiatPricing), &p) == nil {
+			resp.FiatPricing = &p
+		}
 	}
+	return resp
+}
+
+type orderListResp struct {
+	Orders     []orderGetResp `json:"orders"`
+	NextCursor string         `json:"next_cursor,omitempty"` // pass as cursor to fetch the next page
+}
 
+// ListOrdersHandler godoc
+// @Summary      List orders
+// @Description  Returns the authenticated merchant's orders, newest first, optionally filtered by status and tag. Archived orders are not listed but stay readable via /orders/get.
+// @Tags         orders
+// @Produce      json
+// @Param        status  query  string  false  "Order status, e.g. PAID"
+// @Param        tag     query  string  false  "Only orders carrying this tag"
+// @Param        limit   query  int     false  "Page size (default 50, max 200)"
+// @Param        cursor  query  string  false  "next_cursor from the previous page"
+// @Success      200  {object}  orderListResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/list [get]
+func ListOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	f := store.OrderFilter{MerchantID: merchantIDFromContext(r.Context()), Status: q.Get("status"), Limit: 50}
+	if v := q.Get("tag"); v != "" {
+		tag, ok := normalizeTag(v)
+		if !ok {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTag, "")
+			return
+		}
+		f.Tag = tag
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			badReq(w, "limit must be between 1 and 200")
+			return
+		}
+		f.Limit = n
+	}
+	if c := q.Get("cursor"); c != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(c)
+		createdAt, id, ok := strings.Cut(string(raw), "|")
+		if err != nil || !ok {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidCursor, "")
+			return
+		}
+		f.AfterCreatedAt, f.AfterID = createdAt, id
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpSearch)
+	defer cancel()
+	orders, err := reportStores.Orders.List(ctx, f)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	items, err := loadLineItems(ctx, reportDB, ids...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	tags, err := loadOrderTags(ctx, reportDB, ids...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := orderListResp{Orders: []orderGetResp{}}
+	for _, o := range orders {
+		out := orderResponse(o)
+		out.LineItems = items[o.ID]
+		out.Tags = tags[o.ID]
+		resp.Orders = append(resp.Orders, out)
+	}
+	if len(orders) == f.Limit {
+		last := orders[len(orders)-1]
+		resp.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last.CreatedAt + "|" + last.ID))
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+type batchGetOrdersReq struct {
+	OrderIDs []string `json:"order_ids" validate:"required,max=100"`
+}
+
+type batchGetOrdersResp struct {
+	Orders   []orderGetResp `json:"orders"`    // in the order of order_ids, each once
+	NotFound []string       `json:"not_found"` // IDs of no order of the merchant
+}
+
+// BatchGetOrdersHandler godoc
+// @Summary      Get orders by ID
+// @Description  Returns up to 100 of the authenticated merchant's orders by ID in one request, for integrations syncing order status. Orders come in the order of order_ids, archived ones included; IDs given twice are returned once, and IDs that match no order of the merchant are listed in not_found.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        request  body  batchGetOrdersReq  true  "Order IDs"
+// @Success      200  {object}  batchGetOrdersResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/batch-get [post]
+func BatchGetOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	var req batchGetOrdersReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	ids := make([]string, 0, len(req.OrderIDs))
+	seen := map[string]bool{}
+	for _, id := range req.OrderIDs {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+	orders, err := stores.Orders.GetMany(ctx, ids, merchantIDFromContext(r.Context()))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	items, err := loadLineItems(ctx, db, ids...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	tags, err := loadOrderTags(ctx, db, ids...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	byID := make(map[string]store.Order, len(orders))
+	for _, o := range orders {
+		byID[o.ID] = o
+	}
+	resp := batchGetOrdersResp{Orders: []orderGetResp{}, NotFound: []string{}}
+	for _, id := range ids {
+		o, ok := byID[id]
+		if !ok {
+			resp.NotFound = append(resp.NotFound, id)
+			continue
+		}
+		out := orderResponse(o)
+		out.LineItems = items[id]
+		out.Tags = tags[id]
+		resp.Orders = append(resp.Orders, out)
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// SearchOrdersHandler godoc
+// @Summary      Search orders
+// @Description  Finds orders from whatever a customer can tell support: external_order_id, tx_hash, customer_email (the exact address), metadata values, given as metadata.<key>=<value> for top-level metadata keys (compared as text, so metadata.qty=2 matches 2 and "2"), and tag. Every given criterion must match; at least one is required. Archived orders are included. Results are newest first, at most limit (default 50, max 200). Admins may pass merchant_id.
+// @Tags         orders
+// @Produce      json
+// @Param        external_order_id  query  string  false  "The merchant's order reference"
+// @Param        tx_hash            query  string  false  "Payment transaction hash"
+// @Param        customer_email     query  string  false  "Customer email"
+// @Param        metadata.key       query  string  false  "Value of metadata key 'key'; repeat with other keys"
+// @Param        tag                query  string  false  "Tag the order carries"
+// @Param        merchant_id        query  string  false  "Merchant ID (admin route only)"
+// @Param        limit              query  int     false  "Maximum results (default 50, max 200)"
+// @Success      200  {object}  orderListResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/search [get]
+// @Router       /admin/orders/search [get]
+func SearchOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	s := store.OrderSearch{
+		MerchantID:      merchantIDFromContext(r.Context()),
+		ExternalOrderID: q.Get("external_order_id"),
+		TxHash:          q.Get("tx_hash"),
+		CustomerEmail:   strings.TrimSpace(q.Get("customer_email")),
+		Metadata:        map[string]string{},
+		Limit:           50,
+	}
+	if isAdmin(r.Context()) {
+		s.MerchantID = q.Get("merchant_id")
+	}
+	for param, values := range q {
+		if key, ok := strings.CutPrefix(param, "metadata."); ok && key != "" {
+			s.Metadata[key] = values[0]
+		}
+	}
+	if v := q.Get("tag"); v != "" {
+		tag, ok := normalizeTag(v)
+		if !ok {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTag, "")
+			return
+		}
+		s.Tag = tag
+	}
+	if s.ExternalOrderID == "" && s.TxHash == "" && s.CustomerEmail == "" && len(s.Metadata) == 0 && s.Tag == "" {
+		writeProblem(w, http.StatusBadRequest, CodeMissingQueryParam, "one of external_order_id, tx_hash, customer_email, metadata.<key> or tag is required")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			badReq(w, "limit must be between 1 and 200")
+			return
+		}
+		s.Limit = n
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpSearch)
+	defer cancel()
+	orders, err := reportStores.Orders.Search(ctx, s)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	tags, err := loadOrderTags(ctx, reportDB, ids...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := orderListResp{Orders: []orderGetResp{}}
+	for _, o := range orders {
+		out := orderResponse(o)
+		out.Tags = tags[o.ID]
+		resp.Orders = append(resp.Orders, out)
+	}
 	writeJSONOrders(w, http.StatusOK, resp)
 }
 
+const merchantIDKey ctxKey = "merchant_id"
+
+// merchantIDFromContext returns the merchant authenticated by APIKeyAuthMiddleware, or "" if none.
+func merchantIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(merchantIDKey).(string)
+	return id
+}
+
+// APIKeyAuthMiddleware authenticates a merchant by X-API-Key (the primary key, a scoped secondary key, or the key of
+// a member of the merchant's organization with the merchant in X-Merchant-ID), or by an OAuth2 bearer token issued
+// to a platform acting on the merchant's behalf. The merchant ID, granted scopes and credential identity are stored
+// in the request context. Merchants that are not ACTIVE (waiting for approval, or rejected) are refused with 403, and
+// IPs banned for failed attempts (see authFailed) with 429.
 func APIKeyAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return apiKeyAuth(next, false)
+}
+
+// APIKeyAuthAllowPending is APIKeyAuthMiddleware for the endpoints a merchant waiting for approval may use: its
+// status and its webhook settings, which the decision is delivered to.
+func APIKeyAuthAllowPending(next http.HandlerFunc) http.HandlerFunc {
+	return apiKeyAuth(next, true)
+}
+
+func apiKeyAuth(next http.HandlerFunc, allowPending bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if authBlocked(w, r) {
+			return
+		}
+		ctx, cancel := store.WithTimeout(r.Context(), store.OpAuth)
+		defer cancel()
+		authed := func(merchantID, scope, credential string) {
+			if !checkMerchantActive(ctx, w, merchantID, allowPending) {
+				return
+			}
+			setRequestMerchant(r.Context(), merchantID)
+			recordKeyUse(r, merchantID, credential)
+			rctx := context.WithValue(r.Context(), merchantIDKey, merchantID)
+			rctx = context.WithValue(rctx, scopesKey, scope)
+			next(w, r.WithContext(context.WithValue(rctx, credentialKey, credential)))
+		}
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			grantID, merchantID, scope, err := lookupAccessToken(ctx, bearer)
+			if err != nil {
+				authFailed(r)
+				writeProblem(w, http.StatusUnauthorized, CodeInvalidToken, "")
+				return
+			}
+			authed(merchantID, scope, "oauth:"+grantID)
+			return
+		}
 		apiKey := r.Header.Get("X-API-Key")
 		if apiKey == "" {
-			writeJSONOrders(w, http.StatusUnauthorized, map[string]string{"error": "missing X-API-Key header", "message": "API key required"})
+			writeProblem(w, http.StatusUnauthorized, CodeAPIKeyRequired, "missing X-API-Key header")
 			return
 		}
-		var merchantID string
-		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
-		defer cancel()
-		err := db.QueryRowContext(ctx, "SELECT id FROM merchants WHERE api_key = ?", apiKey).Scan(&merchantID)
+		merchantID, err := stores.Merchants.IDByAPIKeyHash(ctx, hashToken(apiKey))
+		if err == nil {
+			authed(merchantID, scopeAll, primaryCredential)
+			return
+		}
+		keyID, merchantID, scope, err := lookupScopedAPIKey(ctx, apiKey)
+		if err == nil {
+			authed(merchantID, scope, "key:"+keyID)
+			return
+		}
+		member, err := lookupOrgMember(ctx, apiKey)
 		if err != nil {
-			writeJSONOrders(w, http.StatusUnauthorized, map[string]string{"error": "invalid API key", "message": "Unauthorized"})
+			authFailed(r)
+			writeProblem(w, http.StatusUnauthorized, CodeInvalidAPIKey, "")
+			return
+		}
+		merchantID = r.Header.Get(orgMerchantHeader)
+		if merchantID == "" {
+			writeProblem(w, http.StatusBadRequest, CodeMerchantHeaderRequired, "organization keys name the merchant to act as in the X-Merchant-ID header")
+			return
+		}
+		if err := requireOrgMerchant(ctx, member.OrganizationID, merchantID); err != nil {
+			if errors.Is(err, errMerchantNotFound) {
+				writeProblem(w, http.StatusForbidden, CodeMerchantNotInOrganization, "")
+				return
+			}
+			serverErr(w, err)
 			return
 		}
-		next(w, r)
+		authed(merchantID, orgRoleScopes[member.Role], "org:"+member.MemberID)
 	}
 }
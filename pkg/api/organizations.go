@@ -0,0 +1,675 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// An organization owns several merchants, e.g. the stores of one company in different regions.
+// Its members each have an API key: with it they manage the organization under /organizations,
+// and act as any of its merchants on the merchant endpoints by naming the merchant in the
+// X-Merchant-ID header. The member's role decides what it may do.
+const (
+	orgRoleOwner  = "owner"  // created with the organization; cannot be removed
+	orgRoleAdmin  = "admin"  // manages members and merchants, full access to the merchants
+	orgRoleViewer = "viewer" // reads the organization and its merchants' orders and balances
+
+	orgMerchantHeader = "X-Merchant-ID"
+)
+
+// orgRoleScopes are the scopes an organization member acts with as one of its merchants. Like
+// scoped API keys, members never count as the merchant's primary key.
+var orgRoleScopes = map[string]string{
+	orgRoleOwner:  scopeAll,
+	orgRoleAdmin:  scopeAll,
+	orgRoleViewer: ScopeOrdersRead + " " + ScopeBalancesRead,
+}
+
+const orgMemberKey ctxKey = "org_member"
+
+// orgCaller is the organization member authenticated by OrgAuthMiddleware.
+type orgCaller struct {
+	OrganizationID string
+	MemberID       string
+	Role           string
+}
+
+func orgCallerFromContext(ctx context.Context) orgCaller {
+	c, _ := ctx.Value(orgMemberKey).(orgCaller)
+	return c
+}
+
+// canManage reports whether the member may change the organization's members and merchants.
+func (c orgCaller) canManage() bool {
+	return c.Role == orgRoleOwner || c.Role == orgRoleAdmin
+}
+
+type orgCreateReq struct {
+	Name       string `json:"name" validate:"required,max=200"`
+	OwnerEmail string `json:"owner_email" validate:"required,max=254"`
+}
+
+type orgCreateResp struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	MemberID string `json:"member_id"` // the owner
+	APIKey   string `json:"api_key"`   // the owner's; shown once
+}
+
+type orgMemberReq struct {
+	Email string `json:"email" validate:"required,max=254"`
+	Role  string `json:"role" validate:"required,oneof=admin viewer"`
+}
+
+type orgMember struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+	APIKey    string `json:"api_key,omitempty"` // only when the member is added
+}
+
+type orgAttachReq struct {
+	APIKey string `json:"api_key" validate:"required"` // the merchant's primary API key
+}
+
+type orgMerchant struct {
+	ID                    string `json:"id"`
+	Name                  string `json:"name"`
+	MerchantWalletAddress string `json:"merchant_wallet_address"`
+	Status                string `json:"status"`
+	CreatedAt             string `json:"created_at"`
+}
+
+type orgMerchantBalances struct {
+	MerchantID string         `json:"merchant_id"`
+	Name       string         `json:"name"`
+	Balances   []assetBalance `json:"balances"`
+}
+
+type orgBalancesResp struct {
+	OrganizationID string                `json:"organization_id"`
+	Totals         []assetBalance        `json:"totals"` // all merchants together, per asset and chain
+	Merchants      []orgMerchantBalances `json:"merchants"`
+}
+
+type orgReportRow struct {
+	MerchantID      string `json:"merchant_id,omitempty"` // empty in totals
+	Asset           string `json:"asset"`
+	OrdersCreated   int64  `json:"orders_created"`
+	OrdersPaid      int64  `json:"orders_paid"`
+	PaidVolumeMinor string `json:"paid_volume_minor"`
+	RefundedMinor   string `json:"refunded_minor"` // completed refunds
+}
+
+type orgReportResp struct {
+	OrganizationID string         `json:"organization_id"`
+	From           string         `json:"from"`
+	To             string         `json:"to"`
+	Totals         []orgReportRow `json:"totals"` // per asset
+	Merchants      []orgReportRow `json:"merchants"`
+}
+
+// lookupOrgMember resolves an organization member's API key.
+func lookupOrgMember(ctx context.Context, key string) (orgCaller, error) {
+	var c orgCaller
+	err := db.QueryRowContext(ctx, `
+		SELECT organization_id, id, role FROM organization_members WHERE key_hash = ? AND removed_at IS NULL
+	`, hashToken(key)).Scan(&c.OrganizationID, &c.MemberID, &c.Role)
+	return c, err
+}
+
+// requireOrgMerchant returns errMerchantNotFound unless merchantID belongs to orgID.
+func requireOrgMerchant(ctx context.Context, orgID, merchantID string) error {
+	var id string
+	err := db.QueryRowContext(ctx, `SELECT id FROM merchants WHERE id = ? AND organization_id = ? AND deleted_at IS NULL`, merchantID, orgID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errMerchantNotFound
+	}
+	return err
+}
+
+// OrgAuthMiddleware authenticates an organization member by its X-API-Key and stores the member in
+// the request context.
+func OrgAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if authBlocked(w, r) {
+			return
+		}
+		apiKey := r.Header.Get("X-API-Key")
+		if apiKey == "" {
+			writeProblem(w, http.StatusUnauthorized, CodeAPIKeyRequired, "missing X-API-Key header")
+			return
+		}
+		ctx, cancel := store.WithTimeout(r.Context(), store.OpAuth)
+		defer cancel()
+		c, err := lookupOrgMember(ctx, apiKey)
+		if err != nil {
+			authFailed(r)
+			writeProblem(w, http.StatusUnauthorized, CodeInvalidOrganizationKey, "")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), orgMemberKey, c)))
+	}
+}
+
+// CreateOrganizationHandler godoc
+// @Summary      Create an organization
+// @Description  Creates an organization to own several merchants, with owner_email as its owner. The response carries the owner's API key, which is not shown again.
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization  body  orgCreateReq  true  "Organization info"
+// @Success      201  {object}  orgCreateResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /organizations [post]
+func CreateOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	var req orgCreateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	resp := orgCreateResp{ID: "org_" + uuid.New().String(), Name: req.Name, MemberID: "mem_" + uuid.New().String(), APIKey: uuid.New().String()}
+	now := time.Now().UTC().Format(time.RFC3339)
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(r.Context(), `INSERT INTO organizations (id, name, created_at) VALUES (?, ?, ?)`, resp.ID, req.Name, now); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if _, err := tx.ExecContext(r.Context(), `
+		INSERT INTO organization_members (id, organization_id, email, role, key_hash, created_at) VALUES (?, ?, ?, ?, ?, ?)
+	`, resp.MemberID, resp.ID, strings.ToLower(req.OwnerEmail), orgRoleOwner, hashToken(resp.APIKey), now); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	noStore(w)
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// OrgMembersHandler godoc
+// @Summary      Add or list organization members
+// @Description  POST adds a member with its own API key, returned once: admins manage members and merchants and have full access to the merchants, viewers read orders and balances. GET lists the members. Adding members needs an owner or admin key.
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        member  body  orgMemberReq  false  "Member (POST only)"
+// @Success      200  {array}   orgMember
+// @Success      201  {object}  orgMember
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /organizations/members [get]
+// @Router       /organizations/members [post]
+func OrgMembersHandler(w http.ResponseWriter, r *http.Request) {
+	caller := orgCallerFromContext(r.Context())
+	switch r.Method {
+	case http.MethodPost:
+		if !caller.canManage() {
+			writeProblem(w, http.StatusForbidden, CodeOrganizationRoleForbidden, "adding members needs an owner or admin key")
+			return
+		}
+		var req orgMemberReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		m := orgMember{ID: "mem_" + uuid.New().String(), Email: strings.ToLower(req.Email), Role: req.Role,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339), APIKey: uuid.New().String()}
+		_, err := db.ExecContext(r.Context(), `
+			INSERT INTO organization_members (id, organization_id, email, role, key_hash, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		`, m.ID, caller.OrganizationID, m.Email, m.Role, hashToken(m.APIKey), m.CreatedAt)
+		if sqliteIsUniqueConstraintError(err) {
+			writeProblem(w, http.StatusConflict, CodeMemberAlreadyExists, m.Email+" is already a member")
+			return
+		}
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		recordAudit(r.Context(), db, "org:"+caller.MemberID, "", "", "organization_member_added",
+			map[string]string{"organization_id": caller.OrganizationID, "member_id": m.ID, "email": m.Email, "role": m.Role})
+		noStore(w)
+		writeJSON(w, http.StatusCreated, m)
+	case http.MethodGet:
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT id, email, role, created_at FROM organization_members
+			WHERE organization_id = ? AND removed_at IS NULL ORDER BY created_at, id
+		`, caller.OrganizationID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		defer rows.Close()
+		members := []orgMember{}
+		for rows.Next() {
+			var m orgMember
+			if err := rows.Scan(&m.ID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+				serverErr(w, err)
+				return
+			}
+			members = append(members, m)
+		}
+		writeJSON(w, http.StatusOK, members)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+	}
+}
+
+// RemoveOrgMemberHandler godoc
+// @Summary      Remove an organization member
+// @Description  Removes a member; its API key stops working at once. The owner cannot be removed. Needs an owner or admin key.
+// @Tags         organizations
+// @Produce      json
+// @Param        id  query  string  true  "Member ID"
+// @Success      200  {object}  map[string]bool
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /organizations/members/remove [post]
+func RemoveOrgMemberHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	caller := orgCallerFromContext(r.Context())
+	if !caller.canManage() {
+		writeProblem(w, http.StatusForbidden, CodeOrganizationRoleForbidden, "removing members needs an owner or admin key")
+		return
+	}
+	id := pathID(r)
+	var role string
+	err := db.QueryRowContext(r.Context(), `
+		SELECT role FROM organization_members WHERE id = ? AND organization_id = ? AND removed_at IS NULL
+	`, id, caller.OrganizationID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeMemberNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if role == orgRoleOwner {
+		writeProblem(w, http.StatusConflict, CodeOwnerNotRemovable, "")
+		return
+	}
+	if _, err := db.ExecContext(r.Context(), `
+		UPDATE organization_members SET removed_at = ? WHERE id = ? AND removed_at IS NULL
+	`, time.Now().UTC().Format(time.RFC3339), id); err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(r.Context(), db, "org:"+caller.MemberID, "", "", "organization_member_removed",
+		map[string]string{"organization_id": caller.OrganizationID, "member_id": id})
+	writeJSON(w, http.StatusOK, map[string]bool{"removed": true})
+}
+
+// OrgMerchantsHandler godoc
+// @Summary      Create or list an organization's merchants
+// @Description  POST creates a merchant owned by the organization, like POST /merchants (the merchant's own API key is returned once, and the merchant may wait for approval); it needs an owner or admin key. GET lists the organization's merchants.
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        merchant  body  MerchantCreateReq  false  "Merchant info (POST only)"
+// @Success      200  {array}   orgMerchant
+// @Success      201  {object}  MerchantCreateResp
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /organizations/merchants [get]
+// @Router       /organizations/merchants [post]
+func OrgMerchantsHandler(w http.ResponseWriter, r *http.Request) {
+	caller := orgCallerFromContext(r.Context())
+	switch r.Method {
+	case http.MethodPost:
+		if !caller.canManage() {
+			writeProblem(w, http.StatusForbidden, CodeOrganizationRoleForbidden, "creating merchants needs an owner or admin key")
+			return
+		}
+		var req MerchantCreateReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		wallet, ensName, err := merchantWallet(r.Context(), req.MerchantWalletAddress)
+		if err != nil {
+			writeWalletProblem(w, err)
+			return
+		}
+		id := uuid.New().String()
+		apiKey := uuid.New().String()
+		now := time.Now().UTC().Format(time.RFC3339)
+		m := store.Merchant{ID: id, Name: req.Name, WalletAddress: wallet, WalletENSName: ensName, OrganizationID: caller.OrganizationID,
+			Status: newMerchantStatus(), CreatedAt: now}
+		if err := stores.Merchants.Create(r.Context(), m, hashToken(apiKey)); err != nil {
+			serverErr(w, err)
+			return
+		}
+		announceApplication(m)
+		recordAudit(r.Context(), db, "org:"+caller.MemberID, id, "", "organization_merchant_created",
+			map[string]string{"organization_id": caller.OrganizationID})
+		noStore(w)
+		writeJSON(w, http.StatusCreated, MerchantCreateResp{
+			ID:                    id,
+			APIKey:                apiKey,
+			MerchantWalletAddress: wallet,
+			WalletENSName:         ensName,
+			Status:                m.Status,
+		})
+	case http.MethodGet:
+		merchants, err := orgMerchants(r.Context(), caller.OrganizationID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, merchants)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+	}
+}
+
+func orgMerchants(ctx context.Context, orgID string) ([]orgMerchant, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, COALESCE(name, ''), COALESCE(merchant_wallet_address, ''), status, created_at
+		FROM merchants WHERE organization_id = ? AND deleted_at IS NULL ORDER BY created_at, id
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	merchants := []orgMerchant{}
+	for rows.Next() {
+		var m orgMerchant
+		if err := rows.Scan(&m.ID, &m.Name, &m.MerchantWalletAddress, &m.Status, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		merchants = append(merchants, m)
+	}
+	return merchants, rows.Err()
+}
+
+// AttachOrgMerchantHandler godoc
+// @Summary      Add an existing merchant to the organization
+// @Description  Moves a merchant that is not in an organization yet into this one; the merchant's primary API key proves it may. The merchant's own keys keep working. Needs an owner or admin key.
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        merchant  body  orgAttachReq  true  "The merchant's primary API key"
+// @Success      200  {object}  orgMerchant
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /organizations/merchants/attach [post]
+func AttachOrgMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	caller := orgCallerFromContext(r.Context())
+	if !caller.canManage() {
+		writeProblem(w, http.StatusForbidden, CodeOrganizationRoleForbidden, "adding merchants needs an owner or admin key")
+		return
+	}
+	var req orgAttachReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	merchantID, err := stores.Merchants.IDByAPIKeyHash(r.Context(), hashToken(req.APIKey))
+	if errors.Is(err, store.ErrNotFound) {
+		authFailed(r)
+		writeProblem(w, http.StatusForbidden, CodeInvalidAPIKey, "api_key is not a merchant's primary API key")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	res, err := db.ExecContext(r.Context(), `
+		UPDATE merchants SET organization_id = ? WHERE id = ? AND organization_id IS NULL
+	`, caller.OrganizationID, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusConflict, CodeMerchantInOrganization, "")
+		return
+	}
+	recordAudit(r.Context(), db, "org:"+caller.MemberID, merchantID, "", "organization_merchant_attached",
+		map[string]string{"organization_id": caller.OrganizationID})
+	var m orgMerchant
+	if err := db.QueryRowContext(r.Context(), `
+		SELECT id, COALESCE(name, ''), COALESCE(merchant_wallet_address, ''), status, created_at FROM merchants WHERE id = ?
+	`, merchantID).Scan(&m.ID, &m.Name, &m.MerchantWalletAddress, &m.Status, &m.CreatedAt); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// DetachOrgMerchantHandler godoc
+// @Summary      Remove a merchant from the organization
+// @Description  Takes a merchant out of the organization; the merchant keeps its account and its own keys, but members no longer act for it. Needs an owner or admin key.
+// @Tags         organizations
+// @Produce      json
+// @Param        id  query  string  true  "Merchant ID"
+// @Success      200  {object}  map[string]bool
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /organizations/merchants/detach [post]
+func DetachOrgMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	caller := orgCallerFromContext(r.Context())
+	if !caller.canManage() {
+		writeProblem(w, http.StatusForbidden, CodeOrganizationRoleForbidden, "removing merchants needs an owner or admin key")
+		return
+	}
+	merchantID := pathID(r)
+	res, err := db.ExecContext(r.Context(), `
+		UPDATE merchants SET organization_id = NULL WHERE id = ? AND organization_id = ?
+	`, merchantID, caller.OrganizationID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusNotFound, CodeMerchantNotInOrganization, "")
+		return
+	}
+	recordAudit(r.Context(), db, "org:"+caller.MemberID, merchantID, "", "organization_merchant_detached",
+		map[string]string{"organization_id": caller.OrganizationID})
+	writeJSON(w, http.StatusOK, map[string]bool{"detached": true})
+}
+
+// OrgBalancesHandler godoc
+// @Summary      Get the organization's balances
+// @Description  Returns each merchant's balances, as GET /merchants/balances does, and their totals per asset and chain.
+// @Tags         organizations
+// @Produce      json
+// @Success      200  {object}  orgBalancesResp
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /organizations/balances [get]
+func OrgBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	caller := orgCallerFromContext(r.Context())
+	merchants, err := orgMerchants(r.Context(), caller.OrganizationID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := orgBalancesResp{OrganizationID: caller.OrganizationID, Merchants: []orgMerchantBalances{}}
+	var all []store.BucketBalance
+	for _, m := range merchants {
+		buckets, err := stores.Ledger.Balances(r.Context(), m.ID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		all = append(all, buckets...)
+		resp.Merchants = append(resp.Merchants, orgMerchantBalances{MerchantID: m.ID, Name: m.Name, Balances: sumBalances(buckets)})
+	}
+	resp.Totals = sumBalances(all)
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// OrgReportHandler godoc
+// @Summary      Get the organization's sales report
+// @Description  Sums, per merchant and asset and over all merchants per asset, the orders created in [from, to), the orders paid and the amount paid in it (by paid_at), and the completed refunds (by created_at). from defaults to 30 days before to, which defaults to now.
+// @Tags         organizations
+// @Produce      json
+// @Param        from  query  string  false  "Range start, RFC 3339"
+// @Param        to    query  string  false  "Range end, RFC 3339"
+// @Success      200  {object}  orgReportResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /organizations/report [get]
+func OrgReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	caller := orgCallerFromContext(r.Context())
+	q := r.URL.Query()
+	to := time.Now().UTC()
+	var err error
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -30)
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "from must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	if !to.After(from) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be after from")
+		return
+	}
+	fromS, toS := from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339)
+
+	type key struct{ merchantID, asset string }
+	type sums struct {
+		created, paid    int64
+		volume, refunded *big.Int
+	}
+	byKey := map[key]*sums{}
+	get := func(k key) *sums {
+		if byKey[k] == nil {
+			byKey[k] = &sums{volume: new(big.Int), refunded: new(big.Int)}
+		}
+		return byKey[k]
+	}
+	// Each query yields (merchant_id, asset, amount_minor) rows; add is given the sums to update.
+	queries := []struct {
+		query string
+		add   func(s *sums, v *big.Int)
+	}{
+		{`SELECT o.merchant_id, o.asset, o.amount_minor FROM orders o JOIN merchants m ON m.id = o.merchant_id
+			WHERE m.organization_id = ? AND o.created_at >= ? AND o.created_at < ? AND o.deleted_at IS NULL`,
+			func(s *sums, _ *big.Int) { s.created++ }},
+		{`SELECT o.merchant_id, o.asset, o.amount_minor FROM orders o JOIN merchants m ON m.id = o.merchant_id
+			WHERE m.organization_id = ? AND o.paid_at >= ? AND o.paid_at < ?
+			  AND o.status IN ('PAID', 'SETTLED', 'REFUNDED', 'PARTIALLY_REFUNDED')`,
+			func(s *sums, v *big.Int) { s.paid++; s.volume.Add(s.volume, v) }},
+		{`SELECT r.merchant_id, o.asset, r.amount_minor FROM refunds r JOIN orders o ON o.id = r.order_id
+			JOIN merchants m ON m.id = r.merchant_id
+			WHERE m.organization_id = ? AND r.created_at >= ? AND r.created_at < ? AND r.status = 'COMPLETED'`,
+			func(s *sums, v *big.Int) { s.refunded.Add(s.refunded, v) }},
+	}
+	for _, qr := range queries {
+		rows, err := db.QueryContext(r.Context(), qr.query, caller.OrganizationID, fromS, toS)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		for rows.Next() {
+			var k key
+			var amount string
+			if err := rows.Scan(&k.merchantID, &k.asset, &amount); err != nil {
+				rows.Close()
+				serverErr(w, err)
+				return
+			}
+			v, ok := new(big.Int).SetString(amount, 10)
+			if !ok {
+				v = new(big.Int)
+			}
+			k.asset = strings.ToUpper(k.asset)
+			qr.add(get(k), v)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+	}
+
+	resp := orgReportResp{OrganizationID: caller.OrganizationID, From: fromS, To: toS, Totals: []orgReportRow{}, Merchants: []orgReportRow{}}
+	totals := map[string]*sums{}
+	for k, s := range byKey {
+		resp.Merchants = append(resp.Merchants, orgReportRow{MerchantID: k.merchantID, Asset: k.asset, OrdersCreated: s.created,
+			OrdersPaid: s.paid, PaidVolumeMinor: s.volume.String(), RefundedMinor: s.refunded.String()})
+		t := totals[k.asset]
+		if t == nil {
+			t = &sums{volume: new(big.Int), refunded: new(big.Int)}
+			totals[k.asset] = t
+		}
+		t.created += s.created
+		t.paid += s.paid
+		t.volume.Add(t.volume, s.volume)
+		t.refunded.Add(t.refunded, s.refunded)
+	}
+	for asset, t := range totals {
+		resp.Totals = append(resp.Totals, orgReportRow{Asset: asset, OrdersCreated: t.created, OrdersPaid: t.paid,
+			PaidVolumeMinor: t.volume.String(), RefundedMinor: t.refunded.String()})
+	}
+	sort.Slice(resp.Merchants, func(i, j int) bool {
+		a, b := resp.Merchants[i], resp.Merchants[j]
+		return a.MerchantID < b.MerchantID || a.MerchantID == b.MerchantID && a.Asset < b.Asset
+	})
+	sort.Slice(resp.Totals, func(i, j int) bool { return resp.Totals[i].Asset < resp.Totals[j].Asset })
+	writeJSONOrders(w, http.StatusOK, resp)
+}
@@ -0,0 +1,466 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Webhook event types. Events are written to outbox_events in the same transaction as the state
+// change they describe, then delivered by the webhook dispatcher.
+const (
+	webhookOrderPaid          = "order.paid"
+	webhookOrderInReview      = "order.in_review"
+	webhookOrderMispaid       = "order.mispaid"
+	webhookOrderOverpaid      = "order.overpaid"
+	webhookOrderFailed        = "order.failed"
+	webhookOrderExpired       = "order.expired"
+	webhookOrderSettled       = "order.settled"
+	webhookRefundRequested    = "refund.requested"
+	webhookRefundCompleted    = "refund.completed"
+	webhookRefundRejected     = "refund.rejected"
+	webhookDisputeOpened      = "dispute.opened"
+	webhookDisputeResolved    = "dispute.resolved"
+	webhookVerificationFailed = "verification.failed"
+	webhookPayoutExecuted     = "payout.executed"
+	webhookPayoutFailed       = "payout.failed"
+
+	webhookConversionCompleted = "conversion.completed"
+	webhookConversionFailed    = "conversion.failed"
+	webhookFiatPayoutPaid      = "fiat_payout.paid"
+	webhookFiatPayoutFailed    = "fiat_payout.failed"
+
+	webhookRefundExecuted        = "refund.executed"
+	webhookRefundExecutionFailed = "refund.execution_failed"
+	webhookRefundJobCompleted    = "refund_job.completed"
+
+	webhookOverpaymentRefunded     = "overpayment.refunded"
+	webhookOverpaymentRefundFailed = "overpayment.refund_failed"
+
+	webhookMerchantWalletChanged = "merchant.wallet_changed"
+	webhookMerchantApproved      = "merchant.approved"
+	webhookMerchantRejected      = "merchant.rejected"
+
+	webhookPaymentIntentCaptured = "payment_intent.captured"
+	webhookPaymentIntentVoided   = "payment_intent.voided"
+
+	webhookAddressTransferReceived = "address.transfer_received"
+
+	webhookBalanceNegative  = "balance.negative"
+	webhookBalanceRecovered = "balance.recovered"
+
+	webhookBalanceHoldPlaced   = "balance_hold.placed"
+	webhookBalanceHoldReleased = "balance_hold.released"
+)
+
+// webhookEventDef describes an event type in the catalog served at GET /events/types. Data is a
+// zero value of the payload type, from which the published schema is generated. Bump Version
+// when a payload change can break consumers: a field removed, renamed or retyped, or a field
+// becoming optional. Adding fields is not breaking.
+type webhookEventDef struct {
+	Type        string
+	Version     int
+	Description string
+	Data        any
+}
+
+// webhookEvents lists every event type a merchant can subscribe to.
+var webhookEvents = []webhookEventDef{
+	{webhookOrderPaid, 1, "The payment was verified and the order is paid.", orderGetResp{}},
+	{webhookOrderInReview, 1, "The payment was received but held for manual review (REVIEW after risk screening, or LATE_PAYMENT).", orderGetResp{}},
+	{webhookOrderMispaid, 1, "A reported payment reached the deposit address in another token or on another chain than the order's; the order is MISPAID until resolved by hand. mispayment describes the transfer.", orderGetResp{}},
+	{webhookOrderOverpaid, 1, "A further payment was reported for an order already paid; it is credited to the refundable balance as an overpayment, which order_id names.", overpaymentRecord{}},
+	{webhookOrderFailed, 1, "The payment was rejected, e.g. after a review.", orderGetResp{}},
+	{webhookOrderExpired, 1, "No payment arrived before the order timed out.", orderGetResp{}},
+	{webhookOrderSettled, 1, "The order's funds were included in a settlement batch.", orderGetResp{}},
+	{webhookRefundRequested, 1, "A refund is waiting for approval.", refundRecord{}},
+	{webhookRefundCompleted, 1, "A refund was recorded against the order.", refundRecord{}},
+	{webhookRefundRejected, 1, "A refund request was rejected.", refundRecord{}},
+	{webhookDisputeOpened, 1, "A dispute was opened and the order's funds are frozen.", disputeRecord{}},
+	{webhookDisputeResolved, 1, "A dispute was decided (WON or LOST).", disputeRecord{}},
+	{webhookVerificationFailed, 1, "On-chain verification of a reported payment failed.", verificationFailedData{}},
+	{webhookPayoutExecuted, 1, "A settlement payout was executed on-chain (by the hot wallet or the merchant's Safe).", payoutRecord{}},
+	{webhookPayoutFailed, 1, "A settlement payout could not be sent or its transaction failed.", payoutRecord{}},
+	{webhookConversionCompleted, 1, "Settled funds were converted into the merchant's settlement asset and chain.", conversionRecord{}},
+	{webhookConversionFailed, 1, "A settlement conversion failed; the funds stay in the received asset.", conversionRecord{}},
+	{webhookFiatPayoutPaid, 1, "The off-ramp partner paid a fiat payout to the merchant's bank account.", fiatPayoutRecord{}},
+	{webhookFiatPayoutFailed, 1, "A fiat payout failed at the off-ramp partner or could not be funded.", fiatPayoutRecord{}},
+	{webhookRefundExecuted, 1, "The hot wallet's transfer of a refund to the customer wallet was mined.", refundRecord{}},
+	{webhookRefundExecutionFailed, 1, "The hot wallet could not send a refund to the customer wallet; the refund stays recorded.", refundRecord{}},
+	{webhookRefundJobCompleted, 1, "Every item of a bulk refund job was refunded or failed.", refundJob{}},
+	{webhookOverpaymentRefunded, 1, "The hot wallet's transfer returning an overpayment to its sender was mined.", overpaymentRecord{}},
+	{webhookOverpaymentRefundFailed, 1, "The hot wallet could not return an overpayment; it is REFUND_FAILED with last_error until it is refunded again.", overpaymentRecord{}},
+	{webhookMerchantWalletChanged, 1, "The merchant wallet's ENS name now points to another address, which orders and payouts use from now on.", walletChange{}},
+	{webhookMerchantApproved, 1, "An administrator approved the merchant; its API keys work from now on.", merchantRecord{}},
+	{webhookMerchantRejected, 1, "An administrator rejected the merchant application, with the reason.", merchantRecord{}},
+	{webhookPaymentIntentCaptured, 1, "A payment intent was captured; order_id is the order the customer now pays.", paymentIntent{}},
+	{webhookPaymentIntentVoided, 1, "A payment intent was voided before it was captured.", paymentIntent{}},
+	{webhookAddressTransferReceived, 1, "A token transfer to a watched address reached the chain's finality depth.", addressTransfer{}},
+	{webhookBalanceNegative, 1, "The merchant's balance of an asset on a chain went below zero, e.g. after a refund of a settled order; amount_minor is owed and is clawed back from later settlements.", negativeBalance{}},
+	{webhookBalanceRecovered, 1, "A negative balance is back at zero or above.", negativeBalance{}},
+	{webhookBalanceHoldPlaced, 1, "An administrator or the merchant's platform held part of its unsettled balance, which is not settled until released.", balanceHold{}},
+	{webhookBalanceHoldReleased, 1, "A balance hold was released by hand (RELEASED) or at its expiry (EXPIRED); release_batch_id settles what it withheld.", balanceHold{}},
+}
+
+func isWebhookEventType(t string) bool {
+	for _, def := range webhookEvents {
+		if def.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Outbox event states. Events for merchants without a webhook URL, or not subscribed to the type,
+// are SKIPPED; they stay in the outbox like delivered ones.
+const (
+	outboxPending    = "PENDING"
+	outboxDelivered  = "DELIVERED"
+	outboxSkipped    = "SKIPPED"
+	outboxDeadLetter = "DEAD_LETTER" // gave up under the webhook retry policy; requeued by POST /events/dead-letter/requeue
+	outboxDiscarded  = "DISCARDED"   // gave up under a webhook retry policy that discards; pruned like delivered ones
+)
+
+// verificationFailedData is the payload of verification.failed.
+type verificationFailedData struct {
+	OrderID string `json:"order_id"`
+	TxHash  string `json:"tx_hash"`
+	Reason  string `json:"reason"`
+}
+
+// enqueueEvent adds an event for merchantID to the outbox. Call it with the transaction that makes
+// the change the event reports, so the two commit or roll back together.
+func enqueueEvent(ctx context.Context, q store.DBTX, merchantID, aggregateType, aggregateID, eventType string, data any) error {
+	if aggregateType == "order" {
+		invalidateOrder(aggregateID)
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	seq, err := nextEventSequence(ctx, q, aggregateType, aggregateID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO outbox_events (id, merchant_id, aggregate_type, aggregate_id, event_name, payload_json, status, created_at, next_attempt_at, sequence)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "evt_"+uuid.New().String(), merchantID, aggregateType, aggregateID, eventType, string(payload), outboxPending, now, now, seq)
+	return err
+}
+
+// nextEventSequence numbers the next event of an aggregate, starting at 1. Called in the
+// enqueuing transaction, the numbers follow commit order.
+func nextEventSequence(ctx context.Context, q store.DBTX, aggregateType, aggregateID string) (int64, error) {
+	var seq int64
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO outbox_sequences (aggregate_type, aggregate_id, last_sequence) VALUES (?, ?, 1)
+		ON CONFLICT (aggregate_type, aggregate_id) DO UPDATE SET last_sequence = last_sequence + 1
+		RETURNING last_sequence
+	`, aggregateType, aggregateID).Scan(&seq)
+	return seq, err
+}
+
+// enqueueOrderEvent enqueues eventType with the order as it reads after the change.
+func enqueueOrderEvent(ctx context.Context, q store.DBTX, eventType, orderID string) error {
+	o, err := store.NewSQL(q).Orders.Get(ctx, orderID, "")
+	if err != nil {
+		return err
+	}
+	return enqueueEvent(ctx, q, o.MerchantID, "order", orderID, eventType, orderResponse(o))
+}
+
+// enqueueRefundEvent enqueues eventType with the refund record as it reads after the change.
+func enqueueRefundEvent(ctx context.Context, q store.DBTX, eventType, refundID string) error {
+	var (
+		rec                                       refundRecord
+		merchantID                                string
+		txHash, requestedBy, decidedBy, decidedAt sql.NullString
+		execution, execErr                        sql.NullString
+	)
+	if err := q.QueryRowContext(ctx, `
+		SELECT id, order_id, merchant_id, amount_minor, status, refund_tx_hash, requested_by, decided_by, decided_at, execution_status, execution_error, created_at
+		FROM refunds WHERE id = ?
+	`, refundID).Scan(&rec.ID, &rec.OrderID, &merchantID, &rec.AmountMinor, &rec.Status, &txHash, &requestedBy, &decidedBy, &decidedAt, &execution, &execErr, &rec.CreatedAt); err != nil {
+		return err
+	}
+	rec.RefundTxHash = nullStringPtr(txHash)
+	rec.RequestedBy = nullStringPtr(requestedBy)
+	rec.DecidedBy = nullStringPtr(decidedBy)
+	rec.DecidedAt = nullStringPtr(decidedAt)
+	rec.ExecutionStatus, rec.ExecutionError = nullStringPtr(execution), nullStringPtr(execErr)
+	return enqueueEvent(ctx, q, merchantID, "refund", refundID, eventType, rec)
+}
+
+// StartWebhookDispatcher delivers pending outbox events every interval. Each merchant's events are
+// sent one at a time in creation order; different merchants are served concurrently.
+func StartWebhookDispatcher(db *sql.DB, interval time.Duration) {
+	startScheduler(schedulerWebhooks, interval, false, func(ctx context.Context) (int, error) {
+		n, err := dispatchWebhooks(ctx, db)
+		alertDeadLetterStreaks(ctx, db)
+		return n, err
+	})
+}
+
+type outboxEvent struct {
+	ID, MerchantID, Type, CreatedAt, ReplayOf string
+	AggregateType, AggregateID                string
+	OrderID                                   string // the order the event is about, if any
+	Sequence                                  int64
+	Payload                                   json.RawMessage
+	Attempts                                  int
+}
+
+// dispatchBatch bounds the events picked up per run; the rest wait for the next tick.
+const dispatchBatch = 200
+
+// dispatchConcurrency bounds how many merchants' receivers are called at once.
+const dispatchConcurrency = 8
+
+// dispatchWebhooks reports how many events it picked up. Events queued behind an earlier event of
+// their aggregate that is backing off are not picked up, so they don't take the batch from others.
+func dispatchWebhooks(ctx context.Context, db *sql.DB) (int, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, COALESCE(merchant_id, ''), event_name, payload_json, created_at, retry_count, COALESCE(replay_of, ''),
+		       aggregate_type, aggregate_id, COALESCE(sequence, 0),
+		       COALESCE(CASE WHEN aggregate_type = 'order' THEN aggregate_id END, json_extract(payload_json, '$.order_id'), '')
+		FROM outbox_events e
+		WHERE status = ? AND next_attempt_at <= ?
+		  AND NOT EXISTS (
+		    SELECT 1 FROM outbox_events p
+		    WHERE p.aggregate_type = e.aggregate_type AND p.aggregate_id = e.aggregate_id AND p.status = ?
+		      AND p.sequence < e.sequence AND p.next_attempt_at > ?
+		  )
+		ORDER BY created_at, rowid
+		LIMIT ?
+	`, outboxPending, now, outboxPending, now, dispatchBatch)
+	if err != nil {
+		return 0, err
+	}
+	byMerchant := map[string][]outboxEvent{}
+	var order []string
+	for rows.Next() {
+		var ev outboxEvent
+		var payload string
+		if err := rows.Scan(&ev.ID, &ev.MerchantID, &ev.Type, &payload, &ev.CreatedAt, &ev.Attempts, &ev.ReplayOf,
+			&ev.AggregateType, &ev.AggregateID, &ev.Sequence, &ev.OrderID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ev.Payload = json.RawMessage(payload)
+		if _, ok := byMerchant[ev.MerchantID]; !ok {
+			order = append(order, ev.MerchantID)
+		}
+		byMerchant[ev.MerchantID] = append(byMerchant[ev.MerchantID], ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, dispatchConcurrency)
+	var wg sync.WaitGroup
+	for _, merchantID := range order {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(merchantID string, events []outboxEvent) {
+			defer func() { <-sem; wg.Done() }()
+			deliverMerchantEvents(ctx, db, merchantID, events)
+		}(merchantID, byMerchant[merchantID])
+	}
+	wg.Wait()
+	n := 0
+	for _, events := range byMerchant {
+		n += len(events)
+	}
+	return n, nil
+}
+
+// outboxBacklog counts the events waiting for delivery, for /debug/metrics; -1 when it cannot be
+// read.
+func outboxBacklog(ctx context.Context) int64 {
+	if db == nil {
+		return -1
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	var n int64
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM outbox_events WHERE status = ?`, outboxPending).Scan(&n); err != nil {
+		return -1
+	}
+	return n
+}
+
+// deliverMerchantEvents sends one merchant's due events, skipping those it is not subscribed to.
+// An event waits while an earlier event of its aggregate is still pending, e.g. backing off after
+// a failed attempt, so each aggregate's events reach the receiver in sequence order. Events of an
+// order created with its own webhook URL go there instead of to the merchant's.
+func deliverMerchantEvents(ctx context.Context, db *sql.DB, merchantID string, events []outboxEvent) {
+	cfg, err := loadWebhook(ctx, db, merchantID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("webhook dispatch: load config for merchant %s: %v", merchantID, err)
+		return
+	}
+	orderWebhooks := map[string]webhookSettings{}
+	for _, ev := range events {
+		if blocked, err := eventBlocked(ctx, db, ev); err != nil {
+			log.Printf("webhook dispatch: check order of event %s: %v", ev.ID, err)
+			continue
+		} else if blocked {
+			continue
+		}
+		target := cfg
+		if ev.OrderID != "" {
+			o, ok := orderWebhooks[ev.OrderID]
+			if !ok {
+				if o, err = loadOrderWebhook(ctx, db, ev.OrderID); err != nil {
+					log.Printf("webhook dispatch: load webhook of order %s: %v", ev.OrderID, err)
+					continue
+				}
+				orderWebhooks[ev.OrderID] = o
+			}
+			if o.URL != "" {
+				target = o
+			}
+		}
+		if target.URL == "" || !cfg.subscribed(ev.Type) {
+			markOutbox(ctx, db, ev.ID, `status = ?, delivered_at = ?`, outboxSkipped, time.Now().UTC().Format(time.RFC3339))
+			continue
+		}
+		res := postWebhook(ctx, target, webhookEvent{
+			ID: ev.ID, Type: ev.Type, CreatedAt: ev.CreatedAt, AggregateType: ev.AggregateType, AggregateID: ev.AggregateID,
+			Sequence: ev.Sequence, ReplayOf: ev.ReplayOf, Data: ev.Payload,
+		})
+		now := time.Now().UTC()
+		if res.Success() {
+			markOutbox(ctx, db, ev.ID, `status = ?, delivered_at = ?, last_error = NULL`, outboxDelivered, now.Format(time.RFC3339))
+			endDeadLetterStreak(ctx, db, merchantID)
+			continue
+		}
+		attempts := ev.Attempts + 1
+		reason := res.Error
+		if reason == "" {
+			reason = "receiver responded " + strconv.Itoa(res.StatusCode)
+		}
+		policy := retryPolicy(retryWebhook)
+		if attempts >= policy.MaxAttempts && policy.Discard {
+			markOutbox(ctx, db, ev.ID, `status = ?, retry_count = ?, last_error = ?, delivered_at = ?`, outboxDiscarded, attempts, reason, now.Format(time.RFC3339))
+			log.Printf("event=webhook_discarded event_id=%s merchant_id=%s type=%s attempts=%d error=%q", ev.ID, merchantID, ev.Type, attempts, reason)
+			continue
+		}
+		if attempts >= policy.MaxAttempts {
+			markOutbox(ctx, db, ev.ID, `status = ?, retry_count = ?, last_error = ?, dead_lettered_at = ?`, outboxDeadLetter, attempts, reason, now.Format(time.RFC3339))
+			startDeadLetterStreak(ctx, db, merchantID, now)
+			log.Printf("event=webhook_dead_lettered event_id=%s merchant_id=%s type=%s attempts=%d error=%q", ev.ID, merchantID, ev.Type, attempts, reason)
+			continue
+		}
+		markOutbox(ctx, db, ev.ID, `retry_count = ?, next_attempt_at = ?, last_error = ?`, attempts, now.Add(policy.Delay(attempts)).Format(time.RFC3339), reason)
+	}
+}
+
+// eventBlocked reports whether an earlier event of ev's aggregate is still pending. Events sent
+// before ev in this run are already marked, so only those left behind block it.
+func eventBlocked(ctx context.Context, db *sql.DB, ev outboxEvent) (bool, error) {
+	if ev.Sequence == 0 {
+		return false, nil
+	}
+	var blocked bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM outbox_events WHERE aggregate_type = ? AND aggregate_id = ? AND status = ? AND sequence < ?)
+	`, ev.AggregateType, ev.AggregateID, outboxPending, ev.Sequence).Scan(&blocked)
+	return blocked, err
+}
+
+func markOutbox(ctx context.Context, db *sql.DB, id, set string, args ...any) {
+	if _, err := db.ExecContext(ctx, `UPDATE outbox_events SET `+set+` WHERE id = ?`, append(args, id)...); err != nil {
+		log.Printf("webhook dispatch: update event %s: %v", id, err)
+	}
+}
+
+// maxReplayEvents bounds one replay request; larger windows must be split.
+const maxReplayEvents = 1000
+
+// replayFilter selects the events POST /events/replay re-delivers. OrderID matches the order's own
+// events and those of its refunds, disputes and verification failures.
+type replayFilter struct {
+	OrderID  string
+	From, To string // RFC 3339, half-open [From, To)
+}
+
+// replayEvents queues a copy of every matching event for merchantID that already left the
+// pending state. The copies are new outbox rows marked with replay_of, so they are delivered
+// (and retried) like fresh events while the originals keep their history. Replays are not
+// themselves replayed. ok is false when more than maxReplayEvents match.
+func replayEvents(ctx context.Context, db *sql.DB, merchantID string, f replayFilter) (n int, ok bool, err error) {
+	where := `merchant_id = ? AND replay_of IS NULL AND status != ?`
+	args := []any{merchantID, outboxPending}
+	if f.OrderID != "" {
+		where += ` AND ((aggregate_type = 'order' AND aggregate_id = ?) OR json_extract(payload_json, '$.order_id') = ?)`
+		args = append(args, f.OrderID, f.OrderID)
+	}
+	if f.From != "" {
+		where += ` AND created_at >= ?`
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where += ` AND created_at < ?`
+		args = append(args, f.To)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, aggregate_type, aggregate_id, event_name, payload_json, sequence
+		FROM outbox_events
+		WHERE `+where+`
+		ORDER BY created_at, rowid
+		LIMIT ?
+	`, append(args, maxReplayEvents+1)...)
+	if err != nil {
+		return 0, false, err
+	}
+	type original struct {
+		id, aggType, aggID, name, payload string
+		seq                               sql.NullInt64
+	}
+	var originals []original
+	for rows.Next() {
+		var o original
+		if err := rows.Scan(&o.id, &o.aggType, &o.aggID, &o.name, &o.payload, &o.seq); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+		originals = append(originals, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+	if len(originals) > maxReplayEvents {
+		return 0, false, nil
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, o := range originals {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO outbox_events (id, merchant_id, aggregate_type, aggregate_id, event_name, payload_json, status, created_at, next_attempt_at, replay_of, sequence)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, "evt_"+uuid.New().String(), merchantID, o.aggType, o.aggID, o.name, o.payload, outboxPending, now, now, o.id, o.seq); err != nil {
+			return 0, false, err
+		}
+	}
+	return len(originals), true, tx.Commit()
+}
@@ -0,0 +1,586 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// An overpayment is a further transfer to the deposit address reported for an order that was
+// already paid, typically a customer paying twice. It is not the merchant's revenue: it is
+// credited to the overpayment bucket, shown as the refundable balance, until the merchant sends it
+// back to the sender with POST /overpayments/{id}/refund.
+const (
+	bucketOverpayment      = "overpayment"
+	overpaymentEvent       = "OVERPAYMENT"
+	overpaymentRefundEvent = "OVERPAYMENT_REFUND"
+)
+
+// Overpayment states. A transfer whose block is not yet final is CONFIRMING and not credited; the
+// confirmation tracker credits it, or drops it if the transaction left the chain. A credited one
+// whose sender was flagged by screening is REVIEW, and only an admin can send it back. The amount
+// leaves the refundable balance when the refund is requested; a refund whose transfer fails is
+// REFUND_FAILED, with the error in last_error, and is sent again when it is refunded again.
+const (
+	overpaymentConfirming   = "CONFIRMING"
+	overpaymentReview       = "REVIEW"
+	overpaymentOpen         = "OPEN"
+	overpaymentRefundQueued = "REFUND_QUEUED" // waiting for the hot wallet to send it
+	overpaymentRefundSent   = "REFUND_SENT"
+	overpaymentRefunded     = "REFUNDED"
+	overpaymentRefundFailed = "REFUND_FAILED"
+)
+
+// overpaidStatuses are the order statuses in which a further payment is an overpayment; orders
+// still in review or confirming keep ignoring other hashes.
+var overpaidStatuses = map[string]bool{"PAID": true, "SETTLED": true, "REFUNDED": true, "PARTIALLY_REFUNDED": true}
+
+type overpaymentRecord struct {
+	ID             string  `json:"id"`
+	OrderID        string  `json:"order_id"`
+	MerchantID     string  `json:"merchant_id"`
+	Asset          string  `json:"asset"`
+	Chain          string  `json:"chain"`
+	AmountMinor    string  `json:"amount_minor"`
+	TxHash         string  `json:"tx_hash"`
+	FromAddress    string  `json:"from_address"` // the refund goes back here
+	Status         string  `json:"status"`       // CONFIRMING | REVIEW | OPEN | REFUND_QUEUED | REFUND_SENT | REFUNDED | REFUND_FAILED
+	ConfirmedBlock *int64  `json:"confirmed_block,omitempty"`
+	RiskReason     *string `json:"risk_reason,omitempty"` // why screening flagged the sender
+	RefundTxHash   *string `json:"refund_tx_hash,omitempty"`
+	LastError      *string `json:"last_error,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	UpdatedAt      string  `json:"updated_at"`
+	RefundedAt     *string `json:"refunded_at,omitempty"`
+}
+
+const overpaymentCols = `id, order_id, merchant_id, asset, chain, amount_minor, tx_hash, from_address, status,
+	confirmed_block, risk_reason, refund_tx_hash, last_error, created_at, updated_at, refunded_at`
+
+func scanOverpayment(row interface{ Scan(...any) error }) (overpaymentRecord, error) {
+	var (
+		o                                             overpaymentRecord
+		block                                         sql.NullInt64
+		riskReason, refundTxHash, lastErr, refundedAt sql.NullString
+	)
+	err := row.Scan(&o.ID, &o.OrderID, &o.MerchantID, &o.Asset, &o.Chain, &o.AmountMinor, &o.TxHash, &o.FromAddress, &o.Status,
+		&block, &riskReason, &refundTxHash, &lastErr, &o.CreatedAt, &o.UpdatedAt, &refundedAt)
+	if block.Valid {
+		o.ConfirmedBlock = &block.Int64
+	}
+	o.RiskReason = nullStringPtr(riskReason)
+	o.RefundTxHash, o.LastError, o.RefundedAt = nullStringPtr(refundTxHash), nullStringPtr(lastErr), nullStringPtr(refundedAt)
+	return o, err
+}
+
+// errOverpaymentSenders refuses a transaction whose transfers to the merchant wallet come from
+// more than one address: there is no single sender to return the overpayment to.
+var errOverpaymentSenders = errors.New("the transaction transfers the asset from more than one sender; its overpayment cannot be returned automatically")
+
+// overpaymentTransfer sums the transfers of asset to wallet among transfers, and returns their
+// sender and block; the amount is zero if there are none.
+func overpaymentTransfer(transfers []blockchain.TokenLog, asset, wallet string) (amount *big.Int, from string, block uint64, err error) {
+	amount = new(big.Int)
+	for _, t := range transfers {
+		if !strings.EqualFold(t.Asset, asset) || !strings.EqualFold(t.To.Hex(), wallet) {
+			continue
+		}
+		if from != "" && !strings.EqualFold(from, t.From.Hex()) {
+			return nil, "", 0, errOverpaymentSenders
+		}
+		amount.Add(amount, t.Amount)
+		from, block = t.From.Hex(), t.BlockNumber
+	}
+	return amount, from, block, nil
+}
+
+// overpaymentFinal reports whether a transfer mined in block has chain's finality depth.
+func overpaymentFinal(ctx context.Context, chain string, block uint64) (bool, error) {
+	depth := blockchain.FinalityDepth(chain)
+	if depth <= 1 {
+		return true, nil
+	}
+	head, err := blockchain.HeadBlock(ctx, chain)
+	if err != nil {
+		return false, err
+	}
+	return int64(head)-int64(block)+1 >= int64(depth), nil
+}
+
+// screenOverpayment screens the sender of a final overpayment, setting the status it is credited
+// in: REVIEW when screening holds the sender, OPEN otherwise.
+func screenOverpayment(ctx context.Context, o *overpaymentRecord) {
+	s := screenPayer(ctx, o.OrderID, o.Chain, o.FromAddress)
+	o.Status, o.RiskReason = overpaymentOpen, nullStringPtr(s.Reason)
+	if s.Hold {
+		o.Status = overpaymentReview
+	}
+}
+
+// creditOverpayment books a final overpayment to the overpayment bucket and sends order.overpaid.
+func creditOverpayment(ctx context.Context, tx *sql.Tx, o overpaymentRecord, now string) error {
+	if err := writeOverpaymentLedger(ctx, tx, o, overpaymentEvent, dirDebit, dirCredit, now); err != nil {
+		return err
+	}
+	return enqueueEvent(ctx, tx, o.MerchantID, "overpayment", o.ID, webhookOrderOverpaid, o)
+}
+
+// overpaymentOf returns the overpayment txHash was credited as, "" if none.
+func overpaymentOf(ctx context.Context, q queryer, txHash string) (string, error) {
+	var id string
+	err := q.QueryRowContext(ctx, `SELECT id FROM overpayments WHERE tx_hash = ?`, strings.ToLower(txHash)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// creditDuplicatePayment records txHash, reported for an order that is already paid, as an
+// overpayment of it if it transferred the order's asset to the merchant wallet on the order's
+// chain; submitted counts a report, as for recordPaymentAttempt. A final transfer is screened and
+// credited at once, sending order.overpaid; one whose block is not yet final is left CONFIRMING for
+// the confirmation tracker. It returns nil when txHash is the order's own payment, another order's,
+// already recorded or pays nothing, errOverpaymentSenders for a transfer from several senders, and
+// an error of blockchain.TokenTransfersInTx (ethereum.NotFound for an unknown hash).
+func creditDuplicatePayment(ctx context.Context, orderID, txHash string, submitted bool) (*overpaymentRecord, error) {
+	var merchantID, asset, chain, wallet string
+	if err := db.QueryRowContext(ctx, `
+		SELECT o.merchant_id, o.asset, UPPER(o.chain), m.merchant_wallet_address
+		FROM orders o JOIN merchants m ON m.id = o.merchant_id WHERE o.id = ?
+	`, orderID).Scan(&merchantID, &asset, &chain, &wallet); err != nil {
+		return nil, err
+	}
+	var used bool
+	if err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM orders WHERE tx_hash IN (?, ?)) OR EXISTS (SELECT 1 FROM overpayments WHERE tx_hash = ?)
+	`, txHash, strings.ToLower(txHash), strings.ToLower(txHash)).Scan(&used); err != nil || used {
+		return nil, err
+	}
+	transfers, err := blockchain.TokenTransfersInTx(ctx, chain, txHash)
+	if errors.Is(err, blockchain.ErrUnsupportedChain) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	amount, from, block, err := overpaymentTransfer(transfers, asset, wallet)
+	if err != nil || amount.Sign() == 0 {
+		return nil, err
+	}
+	final, err := overpaymentFinal(ctx, chain, block)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	confirmedBlock := int64(block)
+	o := overpaymentRecord{
+		ID: "ovp_" + uuid.New().String(), OrderID: orderID, MerchantID: merchantID, Asset: asset, Chain: chain,
+		AmountMinor: amount.String(), TxHash: strings.ToLower(txHash), FromAddress: from, Status: overpaymentConfirming,
+		ConfirmedBlock: &confirmedBlock, CreatedAt: now, UpdatedAt: now,
+	}
+	// Screening calls out to the provider, so it is done before the transaction is opened
+	if final {
+		screenOverpayment(ctx, &o)
+	}
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO overpayments (id, order_id, merchant_id, asset, chain, amount_minor, tx_hash, from_address, status,
+			confirmed_block, risk_reason, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tx_hash) DO NOTHING
+	`, o.ID, o.OrderID, o.MerchantID, o.Asset, o.Chain, o.AmountMinor, o.TxHash, o.FromAddress, o.Status,
+		o.ConfirmedBlock, o.RiskReason, now, now)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+	if final {
+		if err := creditOverpayment(ctx, tx, o, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	detail := "credited as overpayment " + o.ID
+	if !final {
+		detail = "recorded as overpayment " + o.ID + ", credited once final"
+	}
+	log.Printf("event=order_overpaid order_id=%s merchant_id=%s overpayment_id=%s tx_hash=%s amount_minor=%s status=%s",
+		orderID, merchantID, o.ID, txHash, o.AmountMinor, o.Status)
+	logPaymentAttempt(orderID, merchantID, txHash, submitted, attemptSucceeded, "", detail)
+	return &o, nil
+}
+
+// promoteConfirmedOverpayments credits the CONFIRMING overpayments that reached their chain's
+// finality depth, as promoteConfirmedPayments does for orders, and reports how many it credited.
+// The receipt is read again: an overpayment whose transaction left the chain is dropped, one moved
+// to another block waits for that block.
+func promoteConfirmedOverpayments(ctx context.Context, db *sql.DB) (int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+overpaymentCols+` FROM overpayments WHERE status = ? ORDER BY confirmed_block`, overpaymentConfirming)
+	if err != nil {
+		return 0, err
+	}
+	var confirming []overpaymentRecord
+	for rows.Next() {
+		o, err := scanOverpayment(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		confirming = append(confirming, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	credited := 0
+	var lastErr error
+	for i := range confirming {
+		o := &confirming[i]
+		if err := promoteOverpayment(ctx, db, o); err != nil {
+			log.Printf("event=confirmation_error overpayment_id=%s err=%v", o.ID, err)
+			lastErr = err
+		} else if o.Status != overpaymentConfirming {
+			credited++
+		}
+	}
+	return credited, lastErr
+}
+
+// promoteOverpayment credits o if its transfer is final, leaving o's status as it was otherwise.
+func promoteOverpayment(ctx context.Context, db *sql.DB, o *overpaymentRecord) error {
+	if o.ConfirmedBlock == nil {
+		return errors.New("confirming overpayment without a block")
+	}
+	if final, err := overpaymentFinal(ctx, o.Chain, uint64(*o.ConfirmedBlock)); err != nil || !final {
+		return err
+	}
+	var wallet string
+	if err := db.QueryRowContext(ctx, `SELECT merchant_wallet_address FROM merchants WHERE id = ?`, o.MerchantID).Scan(&wallet); err != nil {
+		return err
+	}
+	transfers, err := blockchain.TokenTransfersInTx(ctx, o.Chain, o.TxHash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return err
+	}
+	amount, _, block, err := overpaymentTransfer(transfers, o.Asset, wallet)
+	switch {
+	case err != nil || amount.Sign() == 0:
+		// Never credited, so there is nothing to reverse; reporting the hash again records it anew
+		if _, err := db.ExecContext(ctx, `DELETE FROM overpayments WHERE id = ? AND status = ?`, o.ID, overpaymentConfirming); err != nil {
+			return err
+		}
+		log.Printf("event=overpayment_dropped overpayment_id=%s order_id=%s tx_hash=%s", o.ID, o.OrderID, o.TxHash)
+		return nil
+	case int64(block) != *o.ConfirmedBlock:
+		_, err := db.ExecContext(ctx, `UPDATE overpayments SET confirmed_block = ?, updated_at = ? WHERE id = ? AND status = ?`,
+			int64(block), time.Now().UTC().Format(time.RFC3339), o.ID, overpaymentConfirming)
+		return err
+	}
+
+	screenOverpayment(ctx, o)
+	now := time.Now().UTC().Format(time.RFC3339)
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.ExecContext(ctx, `UPDATE overpayments SET status = ?, risk_reason = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		o.Status, o.RiskReason, now, o.ID, overpaymentConfirming)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		o.Status = overpaymentConfirming
+		return nil
+	}
+	o.UpdatedAt = now
+	if err := creditOverpayment(ctx, tx, *o, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=overpayment_final overpayment_id=%s order_id=%s status=%s", o.ID, o.OrderID, o.Status)
+	return nil
+}
+
+// writeOverpaymentLedger moves o's amount between clearing (clearingDir) and the overpayment
+// bucket (overpaymentDir).
+func writeOverpaymentLedger(ctx context.Context, tx *sql.Tx, o overpaymentRecord, event, clearingDir, overpaymentDir, now string) error {
+	entry := func(bucket, direction string) store.LedgerEntry {
+		return store.LedgerEntry{
+			OrderID: o.OrderID, MerchantID: o.MerchantID,
+			Asset: o.Asset, Chain: o.Chain, AmountMinor: o.AmountMinor, Bucket: bucket, Direction: direction, EventType: event,
+			TxHash: o.TxHash, ReferenceID: o.ID, CreatedAt: now,
+		}
+	}
+	return txStores(tx).Ledger.Append(ctx, entry(bucketClearing, clearingDir), entry(bucketOverpayment, overpaymentDir))
+}
+
+// ListOverpaymentsHandler godoc
+// @Summary      List overpayments
+// @Description  Returns the most recent overpayments (newest first), optionally filtered by status (CONFIRMING, REVIEW, OPEN, REFUND_QUEUED, REFUND_SENT, REFUNDED, REFUND_FAILED) or order_id. An overpayment is a further transfer of the order's asset to the deposit address reported for an order that is already PAID, SETTLED or (partially) refunded, e.g. a customer paying twice. It is credited to the refundable balance rather than the merchant's once its block is final (CONFIRMING until then), and sent back to the sender with POST /overpayments/{id}/refund. Admins see every merchant's, or one with merchant_id.
+// @Tags         orders
+// @Produce      json
+// @Param        status       query  string  false  "Status"
+// @Param        order_id     query  string  false  "Order ID"
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {array}   overpaymentRecord
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /overpayments [get]
+// @Router       /admin/overpayments [get]
+func ListOverpaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = q.Get("merchant_id")
+	}
+	status, orderID := strings.ToUpper(q.Get("status")), q.Get("order_id")
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+overpaymentCols+` FROM overpayments
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR status = ?) AND (? = '' OR order_id = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 500
+	`, merchantID, merchantID, status, status, orderID, orderID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	overpayments := []overpaymentRecord{}
+	for rows.Next() {
+		o, err := scanOverpayment(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		overpayments = append(overpayments, o)
+	}
+	writeJSONOrders(w, http.StatusOK, overpayments)
+}
+
+// RefundOverpaymentHandler godoc
+// @Summary      Refund an overpayment
+// @Description  Sends an OPEN overpayment back to the address it came from, from the hot wallet; one in REVIEW, whose sender was flagged by screening, only on the admin route. The amount leaves the refundable balance at once and the overpayment is REFUND_QUEUED; it is REFUNDED, with refund_tx_hash, once the transfer is mined (overpayment.refunded). A transfer that fails leaves it REFUND_FAILED with last_error (overpayment.refund_failed); refunding it again sends it again. An overpayment whose sender was erased (see POST /privacy/erasure) cannot be refunded.
+// @Tags         orders
+// @Produce      json
+// @Param        id  query  string  true  "Overpayment ID"
+// @Success      200  {object}  overpaymentRecord
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /overpayments/refund [post]
+// @Router       /admin/overpayments/refund [post]
+func RefundOverpaymentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing overpayment id")
+		return
+	}
+	ctx := r.Context()
+	o, err := scanOverpayment(db.QueryRowContext(ctx, `SELECT `+overpaymentCols+` FROM overpayments WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) || err == nil && !authorizedFor(ctx, o.MerchantID) {
+		writeProblem(w, http.StatusNotFound, CodeOverpaymentNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	// A sender flagged by screening only gets the funds back on an operator's decision
+	if o.Status != overpaymentOpen && o.Status != overpaymentRefundFailed && !(o.Status == overpaymentReview && isAdmin(ctx)) {
+		writeProblem(w, http.StatusConflict, CodeOverpaymentNotOpen, "overpayment is "+o.Status)
+		return
+	}
+	if !common.IsHexAddress(o.FromAddress) {
+		writeProblem(w, http.StatusConflict, CodeOverpaymentSenderErased, "the sender's address was erased at the customer's request")
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		UPDATE overpayments SET status = ?, last_error = NULL, updated_at = ? WHERE id = ? AND status = ?
+	`, overpaymentRefundQueued, now, id, o.Status)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusConflict, CodeOverpaymentNotOpen, "")
+		return
+	}
+	// a failed refund left the ledger as booked when it was first requested
+	if o.Status != overpaymentRefundFailed {
+		if err := writeOverpaymentLedger(ctx, tx, o, overpaymentRefundEvent, dirCredit, dirDebit, now); err != nil {
+			serverErr(w, err)
+			return
+		}
+	}
+	recordAudit(ctx, tx, actorFromContext(ctx), o.MerchantID, o.OrderID, "overpayment_refund_requested",
+		map[string]any{"overpayment_id": id, "amount_minor": o.AmountMinor, "to_address": o.FromAddress, "retry": o.Status == overpaymentRefundFailed})
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=overpayment_refund_queued overpayment_id=%s order_id=%s merchant_id=%s amount_minor=%s", id, o.OrderID, o.MerchantID, o.AmountMinor)
+	o, err = scanOverpayment(db.QueryRowContext(ctx, `SELECT `+overpaymentCols+` FROM overpayments WHERE id = ?`, id))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, o)
+}
+
+// dispatchOverpaymentRefunds sends the queued overpayment refunds from the hot wallet and finishes
+// sent ones once their transaction is final. It runs with the payout dispatcher; errors sending a
+// refund are kept in last_error and the send is tried again on the next run.
+func dispatchOverpaymentRefunds(ctx context.Context) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+overpaymentCols+` FROM overpayments WHERE status IN (?, ?) ORDER BY created_at
+	`, overpaymentRefundQueued, overpaymentRefundSent)
+	if err != nil {
+		log.Printf("event=overpayment_refund_error err=%v", err)
+		return
+	}
+	var open []overpaymentRecord
+	for rows.Next() {
+		if o, err := scanOverpayment(rows); err == nil {
+			open = append(open, o)
+		}
+	}
+	rows.Close()
+	for _, o := range open {
+		dispatchOverpaymentRefund(o)
+	}
+}
+
+// dispatchOverpaymentRefund sends or follows the refund of o, with a timeout of its own like a
+// payout's.
+func dispatchOverpaymentRefund(o overpaymentRecord) {
+	ctx, cancel := context.WithTimeout(context.Background(), payoutTimeout)
+	defer cancel()
+	var err error
+	if o.Status == overpaymentRefundQueued {
+		err = sendOverpaymentRefund(ctx, o)
+	} else {
+		err = syncOverpaymentRefund(ctx, o)
+	}
+	if err != nil {
+		log.Printf("event=overpayment_refund_error overpayment_id=%s status=%s err=%v", o.ID, o.Status, err)
+		_, _ = db.ExecContext(ctx, `UPDATE overpayments SET last_error = ? WHERE id = ?`, err.Error(), o.ID)
+	}
+}
+
+// sendOverpaymentRefund sends the refund of o, referenced by the overpayment, and marks it sent.
+// When marking it failed after the transfer went out, the next run marks it sent by that same
+// transfer, which submitTokenTransfer returns instead of paying the sender twice.
+func sendOverpaymentRefund(ctx context.Context, o overpaymentRecord) error {
+	token, ok := blockchain.TokenAddress(o.Chain, o.Asset)
+	amount, ok2 := new(big.Int).SetString(o.AmountMinor, 10)
+	if !ok || !ok2 || !common.IsHexAddress(o.FromAddress) {
+		return finishOverpaymentRefund(ctx, o, "", "no token contract, amount or sender to send the refund with")
+	}
+	c, err := submitTokenTransfer(ctx, o.Chain, "overpayment_refund", o.ID, token, common.HexToAddress(o.FromAddress), amount)
+	if err != nil {
+		return err
+	}
+	log.Printf("event=overpayment_refund_sent overpayment_id=%s order_id=%s chain=%s tx_hash=%s", o.ID, o.OrderID, o.Chain, c.TxHash)
+	_, err = db.ExecContext(ctx, `
+		UPDATE overpayments SET status = ?, chain_tx_id = ?, last_error = NULL, updated_at = ? WHERE id = ? AND status = ?
+	`, overpaymentRefundSent, c.ID, time.Now().UTC().Format(time.RFC3339), o.ID, overpaymentRefundQueued)
+	return err
+}
+
+// syncOverpaymentRefund finishes a sent refund once its transaction is mined, replaced for good or
+// dropped.
+func syncOverpaymentRefund(ctx context.Context, o overpaymentRecord) error {
+	var status string
+	var mined sql.NullString
+	if err := db.QueryRowContext(ctx, `
+		SELECT c.status, c.mined_tx_hash FROM overpayments p JOIN chain_transactions c ON c.id = p.chain_tx_id WHERE p.id = ?
+	`, o.ID).Scan(&status, &mined); err != nil {
+		return err
+	}
+	switch status {
+	case chainTxConfirmed:
+		return finishOverpaymentRefund(ctx, o, mined.String, "")
+	case chainTxFailed, chainTxCancelled, chainTxDropped:
+		return finishOverpaymentRefund(ctx, o, "", "transaction "+strings.ToLower(status))
+	}
+	return nil
+}
+
+// finishOverpaymentRefund marks o REFUNDED by txHash, or REFUND_FAILED given a reason, and
+// enqueues overpayment.refunded or overpayment.refund_failed.
+func finishOverpaymentRefund(ctx context.Context, o overpaymentRecord, txHash, reason string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	status, event := overpaymentRefunded, webhookOverpaymentRefunded
+	if reason != "" {
+		status, event = overpaymentRefundFailed, webhookOverpaymentRefundFailed
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE overpayments SET status = ?, refund_tx_hash = NULLIF(?, ''), last_error = NULLIF(?, ''), updated_at = ?,
+		  refunded_at = CASE WHEN ? = ? THEN ? END
+		WHERE id = ? AND status = ?
+	`, status, txHash, reason, now, status, overpaymentRefunded, now, o.ID, o.Status)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	done, err := scanOverpayment(tx.QueryRowContext(ctx, `SELECT `+overpaymentCols+` FROM overpayments WHERE id = ?`, o.ID))
+	if err != nil {
+		return err
+	}
+	if err := enqueueEvent(ctx, tx, o.MerchantID, "overpayment", o.ID, event, done); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=overpayment_refund_%s overpayment_id=%s order_id=%s chain=%s tx_hash=%s reason=%q",
+		strings.ToLower(status), o.ID, o.OrderID, o.Chain, txHash, reason)
+	return nil
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// eventStatusOverride marks ledger entries an admin status override wrote to keep the ledger in
+// line with the forced status.
+const eventStatusOverride = "STATUS_OVERRIDE"
+
+// minOverrideReason is the shortest reason accepted for a status override.
+const minOverrideReason = 10
+
+type orderStatusOverrideReq struct {
+	Status string `json:"status" validate:"required,oneof=PAID FAILED EXPIRED"`
+	Reason string `json:"reason"`            // why, e.g. the block explorer link of a payment verified by hand
+	TxHash string `json:"tx_hash,omitempty"` // the payment, recorded when forcing PAID
+}
+
+type orderStatusOverrideResp struct {
+	OrderID        string `json:"order_id"`
+	OverrideID     string `json:"override_id"`
+	PreviousStatus string `json:"previous_status"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason"`
+	LedgerEntries  int    `json:"ledger_entries"` // written to credit or reverse the payment
+}
+
+// ForceOrderStatusHandler godoc
+// @Summary      Force an order's status
+// @Description  Support override for stuck orders. PAID credits the payment like a verified one (ledger entries, order.paid), optionally recording tx_hash; FAILED or EXPIRED reverses the payment of a PAID order with STATUS_OVERRIDE ledger entries. Orders that were settled, refunded or are disputed, or have refunds awaiting approval, cannot be overridden. reason (at least 10 characters) is mandatory and the override is written to the audit log as order_status_forced. Admin only.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id        query  string                  true  "Order ID"
+// @Param        override  body   orderStatusOverrideReq  true  "Target status and reason"
+// @Success      200  {object}  orderStatusOverrideResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/orders/status [post]
+func ForceOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	orderID := pathID(r)
+	var req orderStatusOverrideReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	req.Status = strings.ToUpper(strings.TrimSpace(req.Status))
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Status != "PAID" && req.Status != "FAILED" && req.Status != statusExpired {
+		badReq(w, "status must be PAID, FAILED or EXPIRED")
+		return
+	}
+	if len(req.Reason) < minOverrideReason {
+		badReq(w, "reason is required and must explain the override")
+		return
+	}
+	if req.TxHash != "" && (req.Status != "PAID" || !blockchain.IsTxHash(req.TxHash)) {
+		badReq(w, "tx_hash must be a transaction hash and is only recorded when forcing PAID")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var merchantID, amountMinor, asset, status string
+	var txHash sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT merchant_id, amount_minor, asset, status, tx_hash FROM orders WHERE id = ? AND deleted_at IS NULL
+	`, orderID).Scan(&merchantID, &amountMinor, &asset, &status, &txHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "order not found")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	switch {
+	case status == req.Status:
+		writeProblem(w, http.StatusConflict, CodeStatusOverrideNotAllowed, "order is already "+status)
+		return
+	case status == "SETTLED" || status == "REFUNDED" || status == "PARTIALLY_REFUNDED":
+		writeProblem(w, http.StatusConflict, CodeStatusOverrideNotAllowed, "order is "+status+"; its funds moved on and must be corrected with refunds or disputes")
+		return
+	}
+	var blocked bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM disputes WHERE order_id = ? AND status = 'OPEN')
+		    OR EXISTS (SELECT 1 FROM refunds WHERE order_id = ? AND status = ?)
+	`, orderID, orderID, refundStatusRequested).Scan(&blocked); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if blocked {
+		writeProblem(w, http.StatusConflict, CodeStatusOverrideNotAllowed, "order has an open dispute or a refund awaiting approval")
+		return
+	}
+
+	overrideID := "ovr_" + uuid.New().String()
+	now := time.Now().UTC().Format(time.RFC3339)
+	if req.TxHash != "" {
+		txHash = sql.NullString{String: req.TxHash, Valid: true}
+	}
+	entries := 0
+	event := webhookOrderPaid
+	if req.Status == "PAID" {
+		if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = ?, paid_at = ?, tx_hash = ? WHERE id = ? AND status = ?`,
+			req.Status, now, txHash, orderID, status); err != nil {
+			if sqliteIsUniqueConstraintError(err) {
+				writeProblem(w, http.StatusConflict, CodeTxAlreadyUsed, "tx_hash is recorded on another order")
+				return
+			}
+			serverErr(w, err)
+			return
+		}
+		if entries, err = overridePaymentLedger(ctx, tx, overrideID, orderID, merchantID, asset, amountMinor, txHash.String, now, false); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		if err := recordCustomerPayment(ctx, tx, orderID, now); err != nil {
+			serverErr(w, err)
+			return
+		}
+	} else {
+		event = webhookOrderFailed
+		if req.Status == statusExpired {
+			event = webhookOrderExpired
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE orders SET status = ?, paid_at = NULL, expired_at = CASE WHEN ? = 'EXPIRED' THEN COALESCE(expired_at, ?) ELSE expired_at END
+			WHERE id = ? AND status = ?
+		`, req.Status, req.Status, now, orderID, status); err != nil {
+			serverErr(w, err)
+			return
+		}
+		if status == "PAID" {
+			if entries, err = overridePaymentLedger(ctx, tx, overrideID, orderID, merchantID, asset, amountMinor, txHash.String, now, true); err != nil {
+				writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+				return
+			}
+		}
+	}
+	if err := enqueueOrderEvent(ctx, tx, event, orderID); err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := orderStatusOverrideResp{
+		OrderID: orderID, OverrideID: overrideID, PreviousStatus: status, Status: req.Status, Reason: req.Reason, LedgerEntries: entries,
+	}
+	// The audit entry commits with the override: an override without its record must not happen.
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_log (id, actor, merchant_id, order_id, action, detail_json, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, "aud_"+uuid.New().String(), actorFromContext(r.Context()), merchantID, orderID, "order_status_forced", auditDetail(resp, txHash.String), now); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=order_status_forced order_id=%s override_id=%s merchant_id=%s from=%s to=%s ledger_entries=%d reason=%q",
+		orderID, overrideID, merchantID, status, req.Status, entries, req.Reason)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// auditDetail is the detail_json of an order_status_forced audit entry.
+func auditDetail(resp orderStatusOverrideResp, txHash string) string {
+	b, _ := json.Marshal(struct {
+		orderStatusOverrideResp
+		TxHash string `json:"tx_hash,omitempty"`
+	}{resp, txHash})
+	return string(b)
+}
+
+// overridePaymentLedger credits an order's payment for an override to PAID, or reverses it when
+// reverse is set, and reports how many entries it wrote. A first credit is an ordinary
+// PAYMENT_CONFIRMED; anything after that is booked as STATUS_OVERRIDE entries under overrideID, so
+// a payment reversed and credited again nets out in the ledger.
+func overridePaymentLedger(ctx context.Context, tx *sql.Tx, overrideID, orderID, merchantID, asset, amountMinor, txHash, now string, reverse bool) (int, error) {
+	var credited bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM ledger_entries WHERE order_id = ? AND event_type = ?)
+	`, orderID, eventPaymentConfirmed).Scan(&credited); err != nil {
+		return 0, err
+	}
+	entries, err := paymentLedgerEntries(ctx, tx, orderID, merchantID, asset, amountMinor, txHash, now)
+	if err != nil {
+		return 0, err
+	}
+	if credited || reverse {
+		for i := range entries {
+			e := &entries[i]
+			e.EventType, e.ReferenceID = eventStatusOverride, overrideID
+			if reverse {
+				if e.Direction == dirCredit {
+					e.Direction = dirDebit
+				} else {
+					e.Direction = dirCredit
+				}
+			}
+		}
+	}
+	return len(entries), txStores(tx).Ledger.Append(ctx, entries...)
+}
@@ -0,0 +1,815 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// maxEstimateTransfers caps the transfers param of a payout estimate.
+const maxEstimateTransfers = 10_000
+
+type gasEstimate struct {
+	Chain             string `json:"chain"`
+	NativeAsset       string `json:"native_asset,omitempty"`
+	GasPriceWei       string `json:"gas_price_wei,omitempty"`
+	BaseFeeWei        string `json:"base_fee_wei,omitempty"`     // EIP-1559 chains only
+	PriorityFeeWei    string `json:"priority_fee_wei,omitempty"` // EIP-1559 chains only
+	Transfers         int    `json:"transfers"`
+	TransferGas       int64  `json:"transfer_gas"`
+	FeePerTransferWei string `json:"fee_per_transfer_wei,omitempty"`
+	FeePerTransfer    string `json:"fee_per_transfer,omitempty"` // in the native asset, e.g. "0.000195"
+	IndividualFeeWei  string `json:"individual_fee_wei,omitempty"`
+	BatchGas          int64  `json:"batch_gas"`
+	BatchFeeWei       string `json:"batch_fee_wei,omitempty"`
+	BatchSavingsWei   string `json:"batch_savings_wei,omitempty"`
+	Cheaper           string `json:"cheaper,omitempty"` // "batch" or "individual"
+	FetchedAt         string `json:"fetched_at,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+type payoutEstimateResp struct {
+	Estimates []gasEstimate `json:"estimates"`
+}
+
+// PayoutEstimateHandler godoc
+// @Summary      Estimate payout gas costs
+// @Description  Returns each chain's current gas price from its RPC endpoint and what the next payouts would cost: the fee of one token transfer, of sending them one by one, and of a single multi-send batch. transfers defaults to the number of settlement payouts currently due on the chain (merchant/asset pairs with paid, unsettled orders). A chain whose RPC endpoint cannot be reached is listed with an error; asking for that chain alone returns 502.
+// @Tags         settlements
+// @Produce      json
+// @Param        chain      query  string  false  "Only this chain, e.g. BSC"
+// @Param        transfers  query  int     false  "Number of payouts to price"
+// @Success      200  {object}  payoutEstimateResp
+// @Failure      400  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /payouts/estimate [get]
+// @Router       /admin/payouts/estimate [get]
+func PayoutEstimateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	transfers := 0
+	if v := q.Get("transfers"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxEstimateTransfers {
+			badReq(w, "transfers must be between 1 and 10000")
+			return
+		}
+		transfers = n
+	}
+	chains := blockchain.Chains()
+	if c := strings.ToUpper(q.Get("chain")); c != "" {
+		if !slices.Contains(chains, c) {
+			writeProblem(w, http.StatusBadRequest, CodeUnsupportedChain, "unsupported chain: "+c)
+			return
+		}
+		chains = []string{c}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	merchantID := merchantIDFromContext(ctx)
+	resp := payoutEstimateResp{Estimates: []gasEstimate{}}
+	for _, chain := range chains {
+		n := transfers
+		if n == 0 {
+			due, err := duePayouts(ctx, merchantID, chain)
+			if err != nil {
+				serverErr(w, err)
+				return
+			}
+			n = max(due, 1)
+		}
+		e := estimatePayoutGas(ctx, chain, n)
+		if e.Error != "" && len(chains) == 1 {
+			writeProblem(w, http.StatusBadGateway, CodeRPCUnavailable, chain+": "+e.Error)
+			return
+		}
+		resp.Estimates = append(resp.Estimates, e)
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// estimatePayoutGas prices n payouts on chain at its current gas price. RPC failures are reported
+// in the estimate's Error rather than returned.
+func estimatePayoutGas(ctx context.Context, chain string, n int) gasEstimate {
+	e := gasEstimate{
+		Chain:       chain,
+		NativeAsset: blockchain.NativeAsset(chain),
+		Transfers:   n,
+		TransferGas: blockchain.GasTokenTransfer,
+		BatchGas:    blockchain.GasBatchBase + int64(n)*blockchain.GasBatchPerTransfer,
+	}
+	quote, err := blockchain.SuggestGas(ctx, chain)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	perTransfer := blockchain.TransferFee(quote.GasPrice, 1)
+	individual := blockchain.TransferFee(quote.GasPrice, n)
+	batch := blockchain.BatchFee(quote.GasPrice, n)
+	e.GasPriceWei = quote.GasPrice.String()
+	if quote.BaseFee != nil {
+		e.BaseFeeWei = quote.BaseFee.String()
+	}
+	if quote.PriorityFee != nil {
+		e.PriorityFeeWei = quote.PriorityFee.String()
+	}
+	e.FeePerTransferWei = perTransfer.String()
+	e.FeePerTransfer = formatUnits(perTransfer, 18)
+	e.IndividualFeeWei = individual.String()
+	e.BatchFeeWei = batch.String()
+	e.BatchSavingsWei = new(big.Int).Sub(individual, batch).String()
+	e.Cheaper = "individual"
+	if batch.Cmp(individual) < 0 {
+		e.Cheaper = "batch"
+	}
+	e.FetchedAt = quote.FetchedAt.Format(time.RFC3339)
+	return e
+}
+
+// duePayouts counts the settlement payouts a run would make now on chain: one per merchant and
+// asset with paid, unsettled orders. An empty merchantID counts every merchant.
+func duePayouts(ctx context.Context, merchantID, chain string) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT DISTINCT merchant_id, asset FROM orders
+			WHERE status IN ('PAID','PARTIALLY_REFUNDED') AND UPPER(chain) = ? AND (? = '' OR merchant_id = ?)
+			  AND merchant_id NOT IN (SELECT id FROM merchants WHERE deleted_at IS NOT NULL)
+		)`, chain, merchantID, merchantID).Scan(&n)
+	return n, err
+}
+
+// formatUnits renders an integer amount of base units as a decimal with the given number of
+// decimals, trimming trailing zeros: formatUnits(1500000000000000, 18) is "0.0015".
+func formatUnits(v *big.Int, decimals int) string {
+	s := new(big.Int).Abs(v).String()
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	if v.Sign() < 0 {
+		whole = "-" + whole
+	}
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// Payout modes. Merchants without one are settled in the ledger only and paid out off-platform.
+const (
+	payoutModeHotWallet = "hot_wallet" // sent by the hot wallet signer
+	payoutModeSafe      = "safe"       // proposed to the merchant's Safe; its owners execute it
+)
+
+// Payout states. Hot wallet payouts go QUEUED -> SENT -> EXECUTED, Safe payouts QUEUED ->
+// PROPOSED -> EXECUTED; either can end FAILED.
+const (
+	payoutQueued   = "QUEUED"
+	payoutSent     = "SENT"
+	payoutProposed = "PROPOSED"
+	payoutExecuted = "EXECUTED"
+	payoutFailed   = "FAILED"
+)
+
+type payoutRecord struct {
+	ID          string  `json:"id"`
+	BatchID     string  `json:"batch_id"`
+	MerchantID  string  `json:"merchant_id"`
+	Chain       string  `json:"chain"`
+	Asset       string  `json:"asset"`
+	AmountMinor string  `json:"amount_minor"`
+	ToAddress   string  `json:"to_address"`
+	Mode        string  `json:"mode"`
+	Status      string  `json:"status"`
+	SafeAddress *string `json:"safe_address,omitempty"`
+	SafeNonce   *int64  `json:"safe_nonce,omitempty"`
+	SafeTxHash  *string `json:"safe_tx_hash,omitempty"`
+	TxHash      *string `json:"tx_hash,omitempty"`
+	LastError   *string `json:"last_error,omitempty"`
+	// Failed tries to send or propose the payout, retried as the payout retry policy says
+	Attempts       int     `json:"attempts"`
+	NextAttemptAt  *string `json:"next_attempt_at,omitempty"`
+	DeadLetteredAt *string `json:"dead_lettered_at,omitempty"` // out of attempts, held until retried
+	CreatedAt      string  `json:"created_at"`
+	UpdatedAt      string  `json:"updated_at"`
+}
+
+const payoutCols = `id, batch_id, merchant_id, chain, asset, amount_minor, to_address, mode, status,
+	safe_address, safe_nonce, safe_tx_hash, tx_hash, last_error, attempts, next_attempt_at, dead_lettered_at, created_at, updated_at`
+
+func scanPayout(row interface{ Scan(...any) error }) (payoutRecord, error) {
+	var (
+		p                               payoutRecord
+		safe, safeHash, txHash, lastErr sql.NullString
+		nextAttempt, deadLettered       sql.NullString
+		safeNonce                       sql.NullInt64
+	)
+	err := row.Scan(&p.ID, &p.BatchID, &p.MerchantID, &p.Chain, &p.Asset, &p.AmountMinor, &p.ToAddress, &p.Mode, &p.Status,
+		&safe, &safeNonce, &safeHash, &txHash, &lastErr, &p.Attempts, &nextAttempt, &deadLettered, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return p, err
+	}
+	p.SafeAddress, p.SafeTxHash, p.TxHash, p.LastError = nullStringPtr(safe), nullStringPtr(safeHash), nullStringPtr(txHash), nullStringPtr(lastErr)
+	p.NextAttemptAt, p.DeadLetteredAt = nullStringPtr(nextAttempt), nullStringPtr(deadLettered)
+	if safeNonce.Valid {
+		p.SafeNonce = &safeNonce.Int64
+	}
+	return p, nil
+}
+
+// queuePayouts records the on-chain side of a settlement batch, per chain with a positive net: a
+// conversion when the merchant settles in another asset or chain, otherwise a payout when the
+// merchant has a payout mode. It runs in the settlement transaction; the payout dispatcher sends
+// them.
+func queuePayouts(ctx context.Context, tx *sql.Tx, batchID, merchantID, asset string, byChain map[string]*big.Int) error {
+	var toAsset, toChain sql.NullString
+	var slippage sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT settlement_asset, settlement_chain, max_slippage_bps FROM merchants WHERE id = ?
+	`, merchantID).Scan(&toAsset, &toChain, &slippage); err != nil {
+		return err
+	}
+	for _, chain := range slices.Sorted(maps.Keys(byChain)) {
+		amount := byChain[chain]
+		if amount.Sign() <= 0 {
+			continue
+		}
+		target := settlementTarget{chain: chain, asset: asset}
+		if toChain.Valid {
+			target.chain = toChain.String
+		}
+		if toAsset.Valid {
+			target.asset = toAsset.String
+		}
+		var err error
+		if target.chain != chain || !strings.EqualFold(target.asset, asset) {
+			err = queueConversion(ctx, tx, batchID, merchantID, chain, asset, target, amount, slippage)
+		} else {
+			err = queuePayout(ctx, tx, batchID, merchantID, chain, asset, amount)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queuePayout records a payout of amount to the merchant wallet when the merchant has a payout
+// mode.
+func queuePayout(ctx context.Context, tx *sql.Tx, batchID, merchantID, chain, asset string, amount *big.Int) error {
+	var mode, wallet, safe sql.NullString
+	if err := tx.QueryRowContext(ctx, `
+		SELECT payout_mode, merchant_wallet_address, payout_safe_address FROM merchants WHERE id = ?
+	`, merchantID).Scan(&mode, &wallet, &safe); err != nil {
+		return err
+	}
+	if !mode.Valid || !wallet.Valid || wallet.String == "" {
+		return nil
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO payouts (id, batch_id, merchant_id, chain, asset, amount_minor, to_address, mode, status, safe_address, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "po_"+uuid.New().String(), batchID, merchantID, chain, asset, amount.String(), wallet.String, mode.String, payoutQueued,
+		safe, now, now)
+	return err
+}
+
+// StartPayoutDispatcher sends queued payouts and follows sent ones until they execute, every
+// interval.
+func StartPayoutDispatcher(interval time.Duration) {
+	startScheduler(schedulerPayouts, interval, false, func(context.Context) (int, error) {
+		return dispatchPayouts()
+	})
+}
+
+// payoutTimeout bounds the work on one payout, or one multi-send, in a dispatch run: each gets its
+// own, so a slow RPC call on one cannot leave the next sent but unrecorded.
+const payoutTimeout = time.Minute
+
+// dispatchPayouts reports how many open payouts it worked on. A failing payout keeps its error in
+// last_error; the error of the last failure is returned. Queued payouts that failed wait out the
+// payout retry policy's backoff, and are held once they run out of attempts.
+func dispatchPayouts() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	dispatchConversions(ctx)
+	dispatchFiatPayouts(ctx)
+	dispatchRefundTransfers(ctx)
+	dispatchOverpaymentRefunds(ctx)
+	ctx, cancel = store.WithTimeout(context.Background(), store.OpSearch)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+payoutCols+` FROM payouts
+		WHERE (status = ? AND dead_lettered_at IS NULL AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR status IN (?, ?)
+		ORDER BY created_at
+	`, payoutQueued, time.Now().UTC().Format(time.RFC3339), payoutSent, payoutProposed)
+	if err != nil {
+		return 0, err
+	}
+	var open []payoutRecord
+	for rows.Next() {
+		if p, err := scanPayout(rows); err == nil {
+			open = append(open, p)
+		}
+	}
+	rows.Close()
+	n := len(open)
+	open = dispatchMultiSends(holdUnprovenPayouts(ctx, open))
+	var lastErr error
+	for _, p := range open {
+		if err := dispatchPayout(p); err != nil {
+			lastErr = err
+		}
+	}
+	return n, lastErr
+}
+
+// dispatchPayout moves p on by one step, recording a failure on it.
+func dispatchPayout(p payoutRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), payoutTimeout)
+	defer cancel()
+	var err error
+	switch {
+	case p.Status == payoutQueued && p.Mode == payoutModeSafe:
+		err = proposeSafePayout(ctx, p)
+	case p.Status == payoutQueued:
+		err = sendHotWalletPayout(ctx, p)
+	case p.Status == payoutSent:
+		err = syncHotWalletPayout(ctx, p)
+	case p.Status == payoutProposed:
+		err = syncSafePayout(ctx, p)
+	}
+	if err != nil {
+		payoutAttemptFailed(ctx, p, err)
+	}
+	return err
+}
+
+// payoutAttemptFailed records err on p. A QUEUED payout that failed to be sent or proposed counts
+// an attempt: it is tried again after the payout retry policy's backoff, and once out of attempts
+// it is held for POST /admin/payouts/{id}/retry, or failed if the policy discards. Errors
+// following SENT and PROPOSED payouts only read the chain, and are tried again on the next run.
+func payoutAttemptFailed(ctx context.Context, p payoutRecord, err error) {
+	log.Printf("event=payout_error payout_id=%s status=%s err=%v", p.ID, p.Status, err)
+	now := time.Now().UTC()
+	if p.Status != payoutQueued {
+		_, _ = db.ExecContext(ctx, `UPDATE payouts SET last_error = ?, updated_at = ? WHERE id = ?`, err.Error(), now.Format(time.RFC3339), p.ID)
+		return
+	}
+	policy := retryPolicy(retryPayout)
+	attempts := p.Attempts + 1
+	switch {
+	case attempts >= policy.MaxAttempts && policy.Discard:
+		if ferr := finishPayout(ctx, p, payoutFailed, "", fmt.Sprintf("gave up after %d attempts: %v", attempts, err)); ferr != nil {
+			log.Printf("event=payout_error payout_id=%s status=%s err=%v", p.ID, p.Status, ferr)
+		}
+	case attempts >= policy.MaxAttempts:
+		log.Printf("event=payout_dead_lettered payout_id=%s attempts=%d err=%v", p.ID, attempts, err)
+		_, _ = db.ExecContext(ctx, `
+			UPDATE payouts SET attempts = ?, last_error = ?, dead_lettered_at = ?, updated_at = ? WHERE id = ? AND status = ?
+		`, attempts, err.Error(), now.Format(time.RFC3339), now.Format(time.RFC3339), p.ID, payoutQueued)
+	default:
+		_, _ = db.ExecContext(ctx, `
+			UPDATE payouts SET attempts = ?, last_error = ?, next_attempt_at = ?, updated_at = ? WHERE id = ? AND status = ?
+		`, attempts, err.Error(), now.Add(policy.Delay(attempts)).Format(time.RFC3339), now.Format(time.RFC3339), p.ID, payoutQueued)
+	}
+}
+
+// payoutMultiSend pays queued hot wallet payouts of the same chain and asset in one multi-send
+// transaction; off, each payout is its own transfer.
+var payoutMultiSend = true
+
+// SetPayoutMultiSend turns batching hot wallet payouts into multi-send transactions on or off.
+func SetPayoutMultiSend(on bool) { payoutMultiSend = on }
+
+// dispatchMultiSends sends the queued hot wallet payouts that share a chain and asset with at
+// least one other, on chains with a multi-send contract, and returns the payouts left to handle
+// one by one. A queued payout that a multi-send already pays, because recording it failed after
+// the transaction was sent, is marked sent by that transaction instead, multi-sends on or off.
+func dispatchMultiSends(open []payoutRecord) []payoutRecord {
+	groups := map[string][]payoutRecord{}
+	batched := map[string]bool{}
+	for _, p := range open {
+		if p.Status != payoutQueued || p.Mode != payoutModeHotWallet {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), payoutTimeout)
+		sent, err := recordMultiSent(ctx, p)
+		if err != nil {
+			payoutAttemptFailed(ctx, p, err)
+		}
+		cancel()
+		if sent || err != nil {
+			batched[p.ID] = true
+			continue
+		}
+		if !payoutMultiSend {
+			continue
+		}
+		if _, ok := blockchain.DisperseContract(p.Chain); !ok {
+			continue
+		}
+		key := p.Chain + "/" + p.Asset
+		groups[key] = append(groups[key], p)
+	}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		for _, p := range group {
+			batched[p.ID] = true
+		}
+		for chunk := range slices.Chunk(group, blockchain.MaxDisperseRecipients) {
+			ctx, cancel := context.WithTimeout(context.Background(), payoutTimeout)
+			if err := sendMultiSend(ctx, chunk); err != nil {
+				for _, p := range chunk {
+					payoutAttemptFailed(ctx, p, err)
+				}
+			}
+			cancel()
+		}
+	}
+	return slices.DeleteFunc(open, func(p payoutRecord) bool { return batched[p.ID] })
+}
+
+// recordMultiSent marks p sent when an in-flight or mined multi-send pays it, and reports whether
+// one does. Multi-sends list the payouts they pay in reference_id, comma-separated.
+func recordMultiSent(ctx context.Context, p payoutRecord) (bool, error) {
+	t, err := scanChainTx(db.QueryRowContext(ctx, `
+		SELECT `+chainTxCols+` FROM chain_transactions
+		WHERE chain = ? AND purpose = 'payout_multisend' AND status IN (?, ?, ?)
+		  AND instr(',' || reference_id || ',', ',' || ? || ',') > 0
+		ORDER BY created_at DESC LIMIT 1
+	`, p.Chain, chainTxPending, chainTxCancelling, chainTxConfirmed, p.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	log.Printf("event=payout_multisend_reused payout_id=%s chain_tx_id=%s tx_hash=%s", p.ID, t.ID, t.TxHash)
+	return true, markPayoutSent(ctx, p, t)
+}
+
+// sendMultiSend pays payouts, all on one chain and asset, with a single disperseToken call. The
+// contract spends the hot wallet's tokens through an allowance: without enough of it, an unlimited
+// approval is sent first and the payouts stay QUEUED until it is mined.
+func sendMultiSend(ctx context.Context, payouts []payoutRecord) error {
+	chain, asset := payouts[0].Chain, payouts[0].Asset
+	if txSigner == nil {
+		return errors.New("no hot wallet signer configured")
+	}
+	contract, _ := blockchain.DisperseContract(chain)
+	token, ok := blockchain.TokenAddress(chain, asset)
+	if !ok {
+		for _, p := range payouts {
+			if err := finishPayout(ctx, p, payoutFailed, "", fmt.Sprintf("no %s contract known on %s", asset, chain)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	recipients := make([]common.Address, 0, len(payouts))
+	amounts := make([]*big.Int, 0, len(payouts))
+	total := new(big.Int)
+	for _, p := range payouts {
+		_, to, amount, err := payoutTransfer(p)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, to)
+		amounts = append(amounts, amount)
+		total.Add(total, amount)
+	}
+
+	if ready, err := ensureAllowance(ctx, chain, token, contract, total); !ready || err != nil {
+		return err
+	}
+
+	ids := make([]string, len(payouts))
+	for i, p := range payouts {
+		ids[i] = p.ID
+	}
+	t, err := submitContractCall(ctx, chain, "payout_multisend", strings.Join(ids, ","), contract,
+		blockchain.DisperseTokenData(token, recipients, amounts), blockchain.DisperseGasLimit(len(payouts)))
+	if err != nil {
+		return err
+	}
+	log.Printf("event=payout_multisend chain=%s asset=%s payouts=%d total_minor=%s tx_hash=%s", chain, asset, len(payouts), total, t.TxHash)
+	for _, p := range payouts {
+		if err := markPayoutSent(ctx, p, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureAllowance reports whether spender may move amount of token for the hot wallet. When it
+// may not, an unlimited approval is sent, unless one is already in flight, and the caller tries
+// again once it is mined.
+func ensureAllowance(ctx context.Context, chain string, token, spender common.Address, amount *big.Int) (bool, error) {
+	ref := token.Hex() + ":" + spender.Hex()
+	var approving int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM chain_transactions WHERE chain = ? AND purpose = 'approve' AND reference_id = ? AND status IN (?, ?)
+	`, chain, ref, chainTxPending, chainTxCancelling).Scan(&approving); err != nil {
+		return false, err
+	}
+	if approving > 0 {
+		return false, nil
+	}
+	allowance, err := blockchain.Allowance(ctx, chain, token, txSigner.Address(), spender)
+	if err != nil {
+		return false, err
+	}
+	if allowance.Cmp(amount) >= 0 {
+		return true, nil
+	}
+	t, err := submitContractCall(ctx, chain, "approve", ref, token, blockchain.ApproveData(spender, blockchain.MaxAllowance), blockchain.GasApprove)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("event=token_approve chain=%s token=%s spender=%s tx_hash=%s", chain, token.Hex(), spender.Hex(), t.TxHash)
+	return false, nil
+}
+
+// payoutTransfer returns the ERC-20 contract and transfer arguments of p.
+func payoutTransfer(p payoutRecord) (token, to common.Address, amount *big.Int, err error) {
+	token, ok := blockchain.TokenAddress(p.Chain, p.Asset)
+	if !ok {
+		return token, to, nil, fmt.Errorf("no %s contract known on %s", p.Asset, p.Chain)
+	}
+	amount, ok = new(big.Int).SetString(p.AmountMinor, 10)
+	if !ok {
+		return token, to, nil, fmt.Errorf("invalid amount %q", p.AmountMinor)
+	}
+	return token, common.HexToAddress(p.ToAddress), amount, nil
+}
+
+func sendHotWalletPayout(ctx context.Context, p payoutRecord) error {
+	token, to, amount, err := payoutTransfer(p)
+	if err != nil {
+		return finishPayout(ctx, p, payoutFailed, "", err.Error())
+	}
+	t, err := submitTokenTransfer(ctx, p.Chain, "payout", p.ID, token, to, amount)
+	if err != nil {
+		return err
+	}
+	return markPayoutSent(ctx, p, t)
+}
+
+// markPayoutSent records that t pays p, on the payout and on its settlement batch.
+func markPayoutSent(ctx context.Context, p payoutRecord, t chainTx) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := db.ExecContext(ctx, `
+		UPDATE payouts SET status = ?, chain_tx_id = ?, tx_hash = ?, last_error = NULL, updated_at = ? WHERE id = ? AND status = ?
+	`, payoutSent, t.ID, t.TxHash, now, p.ID, payoutQueued); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `UPDATE settlement_batches SET payout_tx_hash = ? WHERE id = ?`, t.TxHash, p.BatchID)
+	return err
+}
+
+// syncHotWalletPayout finishes a sent payout once its transaction is mined, replaced for good or
+// dropped.
+func syncHotWalletPayout(ctx context.Context, p payoutRecord) error {
+	var status string
+	var mined sql.NullString
+	if err := db.QueryRowContext(ctx, `
+		SELECT c.status, c.mined_tx_hash FROM payouts p JOIN chain_transactions c ON c.id = p.chain_tx_id WHERE p.id = ?
+	`, p.ID).Scan(&status, &mined); err != nil {
+		return err
+	}
+	switch status {
+	case chainTxConfirmed:
+		return finishPayout(ctx, p, payoutExecuted, mined.String, "")
+	case chainTxFailed, chainTxCancelled, chainTxDropped:
+		return finishPayout(ctx, p, payoutFailed, mined.String, "transaction "+strings.ToLower(status))
+	}
+	return nil
+}
+
+// proposeSafePayout proposes p as a token transfer from the merchant's Safe. The hot wallet
+// signer proposes it and must be an owner or delegate of the Safe.
+func proposeSafePayout(ctx context.Context, p payoutRecord) error {
+	if txSigner == nil {
+		return errors.New("no hot wallet signer configured to propose Safe transactions")
+	}
+	if p.SafeAddress == nil {
+		return finishPayout(ctx, p, payoutFailed, "", "merchant has no payout_safe_address")
+	}
+	token, to, amount, err := payoutTransfer(p)
+	if err != nil {
+		return finishPayout(ctx, p, payoutFailed, "", err.Error())
+	}
+	safe := common.HexToAddress(*p.SafeAddress)
+	unlock := lockChain(p.Chain)
+	defer unlock()
+	info, err := blockchain.GetSafe(ctx, p.Chain, safe)
+	if err != nil {
+		return err
+	}
+	// Proposals not executed yet hold the nonces after the Safe's current one.
+	nonce := int64(info.Nonce)
+	var proposed sql.NullInt64
+	if err := db.QueryRowContext(ctx, `
+		SELECT MAX(safe_nonce) FROM payouts WHERE chain = ? AND safe_address = ? AND status = ?
+	`, p.Chain, *p.SafeAddress, payoutProposed).Scan(&proposed); err != nil {
+		return err
+	}
+	if proposed.Valid && proposed.Int64 >= nonce {
+		nonce = proposed.Int64 + 1
+	}
+	hash, err := blockchain.ProposeSafeTx(ctx, p.Chain, safe, blockchain.SafeTx{To: token, Data: blockchain.TokenTransferData(to, amount), Nonce: uint64(nonce)}, txSigner)
+	if err != nil {
+		return err
+	}
+	log.Printf("event=payout_proposed payout_id=%s chain=%s safe=%s nonce=%d safe_tx_hash=%s", p.ID, p.Chain, safe.Hex(), nonce, hash.Hex())
+	_, err = db.ExecContext(ctx, `
+		UPDATE payouts SET status = ?, safe_nonce = ?, safe_tx_hash = ?, last_error = NULL, updated_at = ? WHERE id = ? AND status = ?
+	`, payoutProposed, nonce, hash.Hex(), time.Now().UTC().Format(time.RFC3339), p.ID, payoutQueued)
+	return err
+}
+
+// syncSafePayout finishes a proposed payout once the Safe's owners executed it.
+func syncSafePayout(ctx context.Context, p payoutRecord) error {
+	if p.SafeTxHash == nil {
+		return nil
+	}
+	st, err := blockchain.GetSafeTx(ctx, p.Chain, common.HexToHash(*p.SafeTxHash))
+	if err != nil {
+		return err
+	}
+	if !st.Executed {
+		return nil
+	}
+	if !st.Successful {
+		return finishPayout(ctx, p, payoutFailed, st.TxHash, "Safe transaction reverted")
+	}
+	return finishPayout(ctx, p, payoutExecuted, st.TxHash, "")
+}
+
+// finishPayout moves p to EXECUTED or FAILED and enqueues payout.executed or payout.failed.
+func finishPayout(ctx context.Context, p payoutRecord, status, txHash, reason string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.ExecContext(ctx, `
+		UPDATE payouts SET status = ?, tx_hash = COALESCE(NULLIF(?, ''), tx_hash), last_error = NULLIF(?, ''), updated_at = ?
+		WHERE id = ? AND status = ?
+	`, status, txHash, reason, time.Now().UTC().Format(time.RFC3339), p.ID, p.Status)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if status == payoutExecuted && txHash != "" {
+		if _, err := tx.ExecContext(ctx, `UPDATE settlement_batches SET payout_tx_hash = ? WHERE id = ?`, txHash, p.BatchID); err != nil {
+			return err
+		}
+	}
+	updated, err := scanPayout(tx.QueryRowContext(ctx, `SELECT `+payoutCols+` FROM payouts WHERE id = ?`, p.ID))
+	if err != nil {
+		return err
+	}
+	event := webhookPayoutExecuted
+	if status == payoutFailed {
+		event = webhookPayoutFailed
+	}
+	if err := enqueueEvent(ctx, tx, p.MerchantID, "payout", p.ID, event, updated); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("event=payout_%s payout_id=%s batch_id=%s chain=%s tx_hash=%s reason=%q", strings.ToLower(status), p.ID, p.BatchID, p.Chain, txHash, reason)
+	return nil
+}
+
+// ListPayoutsHandler godoc
+// @Summary      List on-chain payouts
+// @Description  Returns the most recent payouts of settlement batches (newest first), optionally filtered by status (QUEUED, SENT, PROPOSED, EXECUTED, FAILED) or batch_id. A queued payout that fails to be sent or proposed is retried as the payout retry policy says (see /admin/retry-policies); attempts counts the failures, and dead_lettered_at is set on a payout held after running out of them. Payouts exist for merchants with a payout_mode: hot_wallet payouts are sent by OSPay, safe payouts are proposed to the merchant's Safe and stay PROPOSED until its owners execute them. Admins see every merchant's, or one with merchant_id.
+// @Tags         settlements
+// @Produce      json
+// @Param        status       query  string  false  "Status"
+// @Param        batch_id     query  string  false  "Settlement batch"
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {array}   payoutRecord
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /payouts [get]
+// @Router       /admin/payouts [get]
+func ListPayoutsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = q.Get("merchant_id")
+	}
+	status, batchID := strings.ToUpper(q.Get("status")), q.Get("batch_id")
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpSearch)
+	defer cancel()
+	rows, err := reportDB.QueryContext(ctx, `
+		SELECT `+payoutCols+` FROM payouts
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR status = ?) AND (? = '' OR batch_id = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 500
+	`, merchantID, merchantID, status, status, batchID, batchID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	payouts := []payoutRecord{}
+	for rows.Next() {
+		p, err := scanPayout(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		payouts = append(payouts, p)
+	}
+	writeJSONOrders(w, http.StatusOK, payouts)
+}
+
+// RetryPayoutHandler godoc
+// @Summary      Retry a held payout
+// @Description  Sends or proposes again a QUEUED payout held after running out of attempts under the payout retry policy, with its attempts counted from zero. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Payout ID"
+// @Success      200  {object}  payoutRecord
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/payouts/retry [post]
+func RetryPayoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing payout id")
+		return
+	}
+	ctx := r.Context()
+	p, err := scanPayout(db.QueryRowContext(ctx, `SELECT `+payoutCols+` FROM payouts WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodePayoutNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if p.Status != payoutQueued || p.DeadLetteredAt == nil {
+		detail := ""
+		if p.Status != payoutQueued {
+			detail = "payout is " + p.Status
+		}
+		writeProblem(w, http.StatusConflict, CodePayoutNotDeadLettered, detail)
+		return
+	}
+	if _, err := db.ExecContext(ctx, `
+		UPDATE payouts SET attempts = 0, next_attempt_at = NULL, dead_lettered_at = NULL, updated_at = ?
+		WHERE id = ? AND status = ? AND dead_lettered_at IS NOT NULL
+	`, time.Now().UTC().Format(time.RFC3339), id, payoutQueued); err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(ctx, db, actorFromContext(ctx), p.MerchantID, "", "payout_retried", map[string]any{"payout_id": id, "last_error": p.LastError})
+	p, err = scanPayout(db.QueryRowContext(ctx, `SELECT `+payoutCols+` FROM payouts WHERE id = ?`, id))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, p)
+}
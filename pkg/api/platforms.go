@@ -0,0 +1,343 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+type ctxKey string
+
+const platformIDKey ctxKey = "platform_id"
+
+// platformIDFromContext returns the platform authenticated by PlatformAuthMiddleware.
+func platformIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(platformIDKey).(string)
+	return id
+}
+
+type platformCreateReq struct {
+	Name string `json:"name" validate:"required,max=200"`
+}
+
+type platformCreateResp struct {
+	ID     string `json:"id"`
+	APIKey string `json:"api_key"`
+}
+
+type connectedMerchant struct {
+	ID                    string `json:"id"`
+	Name                  string `json:"name"`
+	MerchantWalletAddress string `json:"merchant_wallet_address"`
+	CreatedAt             string `json:"created_at"`
+}
+
+type platformOrderCreateReq struct {
+	MerchantID            string          `json:"merchant_id" validate:"required"`
+	AmountMinor           string          `json:"amount_minor"` // String to handle large 18-decimal numbers
+	Asset                 string          `json:"asset" validate:"required,asset"`
+	Chain                 string          `json:"chain" validate:"required,chain"`
+	IdempotencyKey        string          `json:"idempotency_key" validate:"max=255"`
+	ApplicationFeeMinor   string          `json:"application_fee_minor,omitempty"` // withheld for the platform on payment
+	CustomerWalletAddress string          `json:"customer_wallet_address,omitempty" validate:"max=128"`
+	CustomerEmail         string          `json:"customer_email,omitempty" validate:"max=254"`
+	Metadata              json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	LineItems             []lineItem      `json:"line_items,omitempty" validate:"max=100"` // amount_minor defaults to their sum
+	ExternalOrderID       string          `json:"external_order_id,omitempty" validate:"max=128"`
+}
+
+type connectedBalance struct {
+	MerchantID           string `json:"merchant_id"`
+	MerchantBalanceMinor int64  `json:"merchant_balance_minor"`
+	PlatformFeeMinor     int64  `json:"platform_fee_minor"`
+}
+
+type platformBalancesResp struct {
+	PlatformID            string             `json:"platform_id"`
+	Asset                 string             `json:"asset"`
+	PlatformFeeTotalMinor int64              `json:"platform_fee_total_minor"`
+	Merchants             []connectedBalance `json:"merchants"`
+}
+
+// CreatePlatformHandler godoc
+// @Summary      Create a new platform
+// @Description  Creates a marketplace platform that can onboard connected merchant accounts
+// @Tags         platforms
+// @Accept       json
+// @Produce      json
+// @Param        platform  body  platformCreateReq  true  "Platform info"
+// @Success      201  {object}  platformCreateResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /platforms [post]
+func CreatePlatformHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if db == nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBNotInitialized, "db not initialized")
+		return
+	}
+	var req platformCreateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	id := uuid.New().String()
+	apiKey := uuid.New().String()
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := db.ExecContext(r.Context(), `INSERT INTO platforms (id, name, api_key, created_at) VALUES (?, ?, ?, ?)`, id, req.Name, hashToken(apiKey), now); err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	noStore(w)
+	writeJSON(w, http.StatusCreated, platformCreateResp{ID: id, APIKey: apiKey})
+}
+
+// PlatformAuthMiddleware authenticates a platform by its X-API-Key and stores the platform ID in the request context.
+func PlatformAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey := r.Header.Get("X-API-Key")
+		if apiKey == "" {
+			writeProblem(w, http.StatusUnauthorized, CodeAPIKeyRequired, "missing X-API-Key header")
+			return
+		}
+		var platformID string
+		ctx, cancel := store.WithTimeout(r.Context(), store.OpAuth)
+		defer cancel()
+		err := db.QueryRowContext(ctx, "SELECT id FROM platforms WHERE api_key = ?", hashToken(apiKey)).Scan(&platformID)
+		if err != nil {
+			writeProblem(w, http.StatusUnauthorized, CodeInvalidPlatformKey, "")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), platformIDKey, platformID)))
+	}
+}
+
+// ConnectedMerchantsHandler godoc
+// @Summary      Create or list connected merchants
+// @Description  POST creates a merchant account connected to the calling platform; GET lists them
+// @Tags         platforms
+// @Accept       json
+// @Produce      json
+// @Param        merchant  body  MerchantCreateReq  false  "Merchant info (POST only)"
+// @Success      200  {array}   connectedMerchant
+// @Success      201  {object}  MerchantCreateResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /platforms/merchants [get]
+// @Router       /platforms/merchants [post]
+func ConnectedMerchantsHandler(w http.ResponseWriter, r *http.Request) {
+	platformID := platformIDFromContext(r.Context())
+	switch r.Method {
+	case http.MethodPost:
+		var req MerchantCreateReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		wallet, ensName, err := merchantWallet(r.Context(), req.MerchantWalletAddress)
+		if err != nil {
+			writeWalletProblem(w, err)
+			return
+		}
+		id := uuid.New().String()
+		apiKey := uuid.New().String()
+		now := time.Now().UTC().Format(time.RFC3339)
+		m := store.Merchant{ID: id, Name: req.Name, WalletAddress: wallet, WalletENSName: ensName, PlatformID: platformID, Status: newMerchantStatus(), CreatedAt: now}
+		if err := stores.Merchants.Create(r.Context(), m, hashToken(apiKey)); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		announceApplication(m)
+		noStore(w)
+		writeJSON(w, http.StatusCreated, MerchantCreateResp{
+			ID:                    id,
+			APIKey:                apiKey,
+			MerchantWalletAddress: wallet,
+			WalletENSName:         ensName,
+			Status:                m.Status,
+		})
+	case http.MethodGet:
+		ctx, cancel := store.WithTimeout(r.Context(), store.OpSearch)
+		defer cancel()
+		rows, err := db.QueryContext(ctx, `
+			SELECT id, COALESCE(name, ''), COALESCE(merchant_wallet_address, ''), created_at
+			FROM merchants
+			WHERE platform_id = ? AND deleted_at IS NULL
+			ORDER BY created_at
+		`, platformID)
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		defer rows.Close()
+		merchants := []connectedMerchant{}
+		for rows.Next() {
+			var m connectedMerchant
+			if err := rows.Scan(&m.ID, &m.Name, &m.MerchantWalletAddress, &m.CreatedAt); err != nil {
+				writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+				return
+			}
+			merchants = append(merchants, m)
+		}
+		writeJSON(w, http.StatusOK, merchants)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+// PlatformCreateOrderHandler godoc
+// @Summary      Create an order for a connected merchant
+// @Description  Creates a payment order on behalf of a connected merchant, optionally withholding an application fee for the platform
+// @Tags         platforms
+// @Accept       json
+// @Produce      json
+// @Param        order  body  platformOrderCreateReq  true  "Order info"
+// @Success      200  {object}  orderCreateResp
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /platforms/orders [post]
+func PlatformCreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req platformOrderCreateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if err := resolveLineItems(req.LineItems, &req.AmountMinor); err != nil {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidLineItems, err.Error())
+		return
+	}
+	if req.MerchantID == "" || !isValidAmountString(req.AmountMinor) || req.Asset == "" || req.Chain == "" {
+		writeProblem(w, http.StatusBadRequest, CodeMissingFields, "merchant_id, amount_minor (>0), asset, chain are required")
+		return
+	}
+	if req.IdempotencyKey == "" {
+		writeProblem(w, http.StatusBadRequest, CodeMissingIdempotencyKey, "idempotency_key is required")
+		return
+	}
+	if !isJSONObject(req.Metadata) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidMetadata, "metadata must be a JSON object")
+		return
+	}
+	if req.ApplicationFeeMinor != "" {
+		fee, ok1 := new(big.Int).SetString(req.ApplicationFeeMinor, 10)
+		amount, ok2 := new(big.Int).SetString(req.AmountMinor, 10)
+		if !ok1 || !ok2 || fee.Sign() < 0 || fee.Cmp(amount) > 0 {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidApplicationFee, "application_fee_minor must be a non-negative integer not exceeding amount_minor")
+			return
+		}
+	}
+
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	if err := requireConnectedMerchant(ctx, platformIDFromContext(r.Context()), req.MerchantID); err != nil {
+		if errors.Is(err, errMerchantNotFound) {
+			writeProblem(w, http.StatusForbidden, CodeMerchantNotConnected, "merchant is not connected to this platform")
+			return
+		}
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	if !checkMerchantActive(ctx, w, req.MerchantID, false) {
+		return
+	}
+
+	resp, err := createOrderRecord(ctx, orderCreateReq{
+		MerchantID:            req.MerchantID,
+		AmountMinor:           req.AmountMinor,
+		Asset:                 req.Asset,
+		Chain:                 req.Chain,
+		IdempotencyKey:        req.IdempotencyKey,
+		CustomerWalletAddress: req.CustomerWalletAddress,
+		CustomerEmail:         req.CustomerEmail,
+		Metadata:              req.Metadata,
+		LineItems:             req.LineItems,
+		ExternalOrderID:       req.ExternalOrderID,
+	}, req.ApplicationFeeMinor)
+	if err != nil {
+		writeOrderCreateError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// requireConnectedMerchant returns errMerchantNotFound unless merchantID belongs to platformID.
+func requireConnectedMerchant(ctx context.Context, platformID, merchantID string) error {
+	var id string
+	err := db.QueryRowContext(ctx, `SELECT id FROM merchants WHERE id = ? AND platform_id = ? AND deleted_at IS NULL`, merchantID, platformID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errMerchantNotFound
+	}
+	return err
+}
+
+// PlatformBalancesHandler godoc
+// @Summary      Get connected merchant balances
+// @Description  Returns each connected merchant's ledger balance and the platform fees collected for an asset
+// @Tags         platforms
+// @Produce      json
+// @Param        asset  query  string  true  "Asset symbol"
+// @Success      200  {object}  platformBalancesResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /platforms/balances [get]
+func PlatformBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	asset := r.URL.Query().Get("asset")
+	if asset == "" {
+		badReq(w, "missing query param: asset")
+		return
+	}
+	platformID := platformIDFromContext(r.Context())
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpRead)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.id,
+		       COALESCE(SUM(CASE WHEN l.bucket IN ('merchant','settlement') AND l.direction='credit' THEN l.amount_minor
+		                         WHEN l.bucket IN ('merchant','settlement') THEN -l.amount_minor ELSE 0 END),0),
+		       COALESCE(SUM(CASE WHEN l.bucket='platform_fee' AND l.direction='credit' THEN l.amount_minor
+		                         WHEN l.bucket='platform_fee' THEN -l.amount_minor ELSE 0 END),0)
+		FROM merchants m
+		LEFT JOIN ledger_entries l ON l.merchant_id = m.id AND l.asset = ?
+		WHERE m.platform_id = ?
+		GROUP BY m.id
+		ORDER BY m.id
+	`, asset, platformID)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	resp := platformBalancesResp{PlatformID: platformID, Asset: asset, Merchants: []connectedBalance{}}
+	for rows.Next() {
+		var b connectedBalance
+		if err := rows.Scan(&b.MerchantID, &b.MerchantBalanceMinor, &b.PlatformFeeMinor); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		resp.PlatformFeeTotalMinor += b.PlatformFeeMinor
+		resp.Merchants = append(resp.Merchants, b)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
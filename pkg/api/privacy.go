@@ -0,0 +1,505 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/secrets"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Customer data requests identify the data subject by wallet and/or email. Ledger entries never
+// hold customer fields, so erasure only touches orders, live and archived, the overpayments and
+// watched-address transfers the subject sent (and audit details that quote a wallet); amounts,
+// statuses and on-chain tx hashes are kept so balances still reconcile.
+
+type privacySubject struct {
+	CustomerWalletAddress string `json:"customer_wallet_address,omitempty"`
+	CustomerEmail         string `json:"customer_email,omitempty"`
+}
+
+type privacyOrder struct {
+	ID                    string          `json:"id"`
+	MerchantID            string          `json:"merchant_id"`
+	AmountMinor           string          `json:"amount_minor"`
+	Asset                 string          `json:"asset"`
+	Chain                 string          `json:"chain"`
+	Status                string          `json:"status"`
+	TxHash                *string         `json:"tx_hash,omitempty"`
+	CustomerWalletAddress *string         `json:"customer_wallet_address,omitempty"`
+	CustomerEmail         *string         `json:"customer_email,omitempty"`
+	Metadata              json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	CreatedAt             string          `json:"created_at"`
+	PaidAt                *string         `json:"paid_at,omitempty"`
+	LineItems             []lineItem      `json:"line_items,omitempty"`
+	Refunds               []refundRecord  `json:"refunds,omitempty"`
+}
+
+type privacyExportResp struct {
+	Subject    privacySubject `json:"subject"`
+	Timezone   string         `json:"timezone"` // timestamps are in the merchant's timezone
+	ExportedAt string         `json:"exported_at"`
+	Orders     []privacyOrder `json:"orders"`
+	// Overpayments of those orders, and any other sent from the subject's wallets
+	Overpayments []overpaymentRecord `json:"overpayments"`
+	// Transfers from the subject's wallets to the merchant's watched addresses
+	AddressTransfers []addressTransfer `json:"address_transfers"`
+}
+
+type privacyErasureResp struct {
+	OrdersErased           int64  `json:"orders_erased"`
+	OverpaymentsErased     int64  `json:"overpayments_erased"`
+	AddressTransfersErased int64  `json:"address_transfers_erased"`
+	Pseudonym              string `json:"pseudonym,omitempty"` // replaces the wallet address on erased orders, overpayments and transfers
+	ErasedAt               string `json:"erased_at"`
+}
+
+// subjectFilter builds the WHERE clause matching a subject's orders within the caller's scope.
+func subjectFilter(ctx context.Context, s privacySubject) (string, []any) {
+	merchantID := merchantIDFromContext(ctx)
+	clause := `(? = '' OR merchant_id = ?) AND erased_at IS NULL AND (`
+	args := []any{merchantID, merchantID}
+	var conds []string
+	if s.CustomerWalletAddress != "" {
+		conds = append(conds, `customer_wallet_address = ? COLLATE NOCASE`)
+		args = append(args, s.CustomerWalletAddress)
+	}
+	if s.CustomerEmail != "" {
+		// Encrypted emails are matched on their blind index; rows without one are still plaintext
+		cond := `(customer_email_hash IS NULL AND customer_email = ? COLLATE NOCASE)`
+		if hashes := secrets.BlindIndexes(s.CustomerEmail); len(hashes) > 0 {
+			cond = `customer_email_hash IN (?` + strings.Repeat(`, ?`, len(hashes)-1) + `) OR ` + cond
+			for _, h := range hashes {
+				args = append(args, h)
+			}
+		}
+		conds = append(conds, cond)
+		args = append(args, s.CustomerEmail)
+	}
+	return clause + strings.Join(conds, " OR ") + `)`, args
+}
+
+// subjectPayers returns the addresses a subject may have paid from: the wallet they named and those
+// of their orders, each once whatever its letter case.
+func subjectPayers(s privacySubject, wallets []string) []string {
+	seen := map[string]bool{}
+	var payers []string
+	for _, a := range append([]string{s.CustomerWalletAddress}, wallets...) {
+		if a == "" || seen[strings.ToLower(a)] {
+			continue
+		}
+		seen[strings.ToLower(a)] = true
+		payers = append(payers, a)
+	}
+	return payers
+}
+
+// overpaymentFilter builds the WHERE clause matching the overpayments of a subject's orders, and
+// those sent from one of their addresses, within the caller's scope. It has to run before the
+// orders are erased, which stops subjectFilter matching them.
+func overpaymentFilter(ctx context.Context, s privacySubject, payers []string) (string, []any) {
+	merchantID := merchantIDFromContext(ctx)
+	where, args := subjectFilter(ctx, s)
+	clause := `(? = '' OR merchant_id = ?) AND (order_id IN (
+		SELECT id FROM orders WHERE ` + where + ` UNION ALL SELECT id FROM orders_archive WHERE ` + where + `)`
+	all := append([]any{merchantID, merchantID}, args...)
+	all = append(all, args...)
+	if len(payers) > 0 {
+		clause += ` OR lower(from_address) IN (lower(?)` + strings.Repeat(`, lower(?)`, len(payers)-1) + `)`
+		for _, p := range payers {
+			all = append(all, p)
+		}
+	}
+	return clause + `)`, all
+}
+
+// subjectOverpayments loads the overpayments matching an overpaymentFilter clause, oldest first.
+func subjectOverpayments(ctx context.Context, q queryer, where string, args []any) ([]overpaymentRecord, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+overpaymentCols+` FROM overpayments WHERE `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	overpayments := []overpaymentRecord{}
+	for rows.Next() {
+		o, err := scanOverpayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		overpayments = append(overpayments, o)
+	}
+	return overpayments, rows.Err()
+}
+
+// payerFilter builds the WHERE clause matching the address_transfers rows sent from one of payers,
+// within the caller's scope.
+func payerFilter(ctx context.Context, payers []string) (string, []any) {
+	merchantID := merchantIDFromContext(ctx)
+	if len(payers) == 0 {
+		return `0`, nil
+	}
+	args := []any{merchantID, merchantID}
+	for _, p := range payers {
+		args = append(args, p)
+	}
+	return `(? = '' OR merchant_id = ?) AND lower(from_address) IN (lower(?)` + strings.Repeat(`, lower(?)`, len(payers)-1) + `)`, args
+}
+
+// subjectAddressTransfers loads the watched-address transfers matching a payerFilter clause, oldest first.
+func subjectAddressTransfers(ctx context.Context, q queryer, where string, args []any) ([]addressTransfer, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, watch_id, chain, asset, address, from_address, amount_minor, tx_hash, log_index, block_number, created_at
+		FROM address_transfers WHERE `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	transfers := []addressTransfer{}
+	for rows.Next() {
+		var t addressTransfer
+		if err := rows.Scan(&t.ID, &t.WatchID, &t.Chain, &t.Asset, &t.Address, &t.FromAddress, &t.AmountMinor, &t.TxHash,
+			&t.LogIndex, &t.BlockNumber, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// redactAudit replaces value with pseudonym in every audit detail that quotes it, in any letter
+// case: a wallet is quoted checksummed in one entry and lowercased in another, and SQLite's REPLACE
+// only matches the exact case.
+func redactAudit(ctx context.Context, tx *sql.Tx, value, pseudonym string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, detail_json FROM audit_log WHERE instr(lower(detail_json), lower(?)) > 0`, value)
+	if err != nil {
+		return err
+	}
+	type entry struct{ id, detail string }
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.detail); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(value))
+	for _, e := range entries {
+		if _, err := tx.ExecContext(ctx, `UPDATE audit_log SET detail_json = ? WHERE id = ?`, re.ReplaceAllLiteralString(e.detail, pseudonym), e.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrivacyExportHandler godoc
+// @Summary      Export a customer's data
+// @Description  Returns every order (with refunds) tied to the given customer wallet and/or email, the overpayments of those orders or sent from the customer's wallets, and the transfers from those wallets to watched addresses, within the authenticated merchant. Admins see all merchants. The export is recorded in the audit log.
+// @Tags         privacy
+// @Produce      json
+// @Param        customer_wallet_address  query  string  false  "Customer wallet address"
+// @Param        customer_email           query  string  false  "Customer email"
+// @Success      200  {object}  privacyExportResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /privacy/export [get]
+// @Router       /admin/privacy/export [get]
+func PrivacyExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	subject := privacySubject{
+		CustomerWalletAddress: r.URL.Query().Get("customer_wallet_address"),
+		CustomerEmail:         r.URL.Query().Get("customer_email"),
+	}
+	if subject.CustomerWalletAddress == "" && subject.CustomerEmail == "" {
+		badReq(w, "customer_wallet_address or customer_email is required")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpReport)
+	defer cancel()
+
+	where, args := subjectFilter(r.Context(), subject)
+	const cols = `id, merchant_id, amount_minor, asset, chain, status, tx_hash, customer_wallet_address, customer_email, metadata_json, created_at, paid_at`
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+cols+` FROM orders WHERE `+where+`
+		UNION ALL
+		SELECT `+cols+` FROM orders_archive WHERE `+where+`
+		ORDER BY created_at, id`, append(args, args...)...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	orders := []privacyOrder{}
+	for rows.Next() {
+		var (
+			o                            privacyOrder
+			txHash, wallet, meta, paidAt sql.NullString
+			email                        secrets.EncryptedString
+		)
+		if err := rows.Scan(&o.ID, &o.MerchantID, &o.AmountMinor, &o.Asset, &o.Chain, &o.Status, &txHash, &wallet, &email, &meta, &o.CreatedAt, &paidAt); err != nil {
+			rows.Close()
+			serverErr(w, err)
+			return
+		}
+		o.TxHash = nullStringPtr(txHash)
+		o.CustomerWalletAddress = nullStringPtr(wallet)
+		if email.Valid {
+			o.CustomerEmail = &email.String
+		}
+		if meta.Valid {
+			o.Metadata = json.RawMessage(meta.String)
+		}
+		o.PaidAt = nullStringPtr(paidAt)
+		orders = append(orders, o)
+	}
+	rows.Close()
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	items, err := loadLineItems(ctx, db, ids...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	for i := range orders {
+		orders[i].LineItems = items[orders[i].ID]
+		refunds, err := db.QueryContext(ctx, `
+			SELECT id, order_id, amount_minor, status, refund_tx_hash, created_at FROM refunds WHERE order_id = ?
+			UNION ALL
+			SELECT id, order_id, amount_minor, status, refund_tx_hash, created_at FROM refunds_archive WHERE order_id = ?
+			ORDER BY created_at, id
+		`, orders[i].ID, orders[i].ID)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		for refunds.Next() {
+			var (
+				rec    refundRecord
+				txHash sql.NullString
+			)
+			if err := refunds.Scan(&rec.ID, &rec.OrderID, &rec.AmountMinor, &rec.Status, &txHash, &rec.CreatedAt); err != nil {
+				refunds.Close()
+				serverErr(w, err)
+				return
+			}
+			rec.RefundTxHash = nullStringPtr(txHash)
+			orders[i].Refunds = append(orders[i].Refunds, rec)
+		}
+		refunds.Close()
+	}
+
+	var wallets []string
+	for _, o := range orders {
+		if o.CustomerWalletAddress != nil {
+			wallets = append(wallets, *o.CustomerWalletAddress)
+		}
+	}
+	payers := subjectPayers(subject, wallets)
+	opWhere, opArgs := overpaymentFilter(r.Context(), subject, payers)
+	overpayments, err := subjectOverpayments(ctx, db, opWhere, opArgs)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	trWhere, trArgs := payerFilter(r.Context(), payers)
+	transfers, err := subjectAddressTransfers(ctx, db, trWhere, trArgs)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+
+	// The audit entry records that an export happened, not who the subject was
+	recordAudit(ctx, db, actorFromContext(r.Context()), merchantIDFromContext(r.Context()), "", "privacy_export", map[string]any{
+		"by_wallet": subject.CustomerWalletAddress != "", "by_email": subject.CustomerEmail != "", "orders": len(orders),
+		"overpayments": len(overpayments), "address_transfers": len(transfers),
+	})
+	loc := time.UTC
+	if merchantID := merchantIDFromContext(ctx); merchantID != "" {
+		if loc, err = merchantLocation(ctx, db, merchantID); err != nil {
+			serverErr(w, err)
+			return
+		}
+	}
+	for i := range orders {
+		o := &orders[i]
+		o.CreatedAt = localTimestamp(o.CreatedAt, loc)
+		if o.PaidAt != nil {
+			*o.PaidAt = localTimestamp(*o.PaidAt, loc)
+		}
+		for j := range o.Refunds {
+			o.Refunds[j].CreatedAt = localTimestamp(o.Refunds[j].CreatedAt, loc)
+		}
+	}
+	for i := range overpayments {
+		o := &overpayments[i]
+		o.CreatedAt = localTimestamp(o.CreatedAt, loc)
+		o.UpdatedAt = localTimestamp(o.UpdatedAt, loc)
+		if o.RefundedAt != nil {
+			*o.RefundedAt = localTimestamp(*o.RefundedAt, loc)
+		}
+	}
+	for i := range transfers {
+		transfers[i].CreatedAt = localTimestamp(transfers[i].CreatedAt, loc)
+	}
+	writeJSON(w, http.StatusOK, privacyExportResp{
+		Subject: subject, Timezone: loc.String(), ExportedAt: time.Now().In(loc).Format(time.RFC3339), Orders: orders,
+		Overpayments: overpayments, AddressTransfers: transfers,
+	})
+}
+
+// PrivacyErasureHandler godoc
+// @Summary      Erase a customer's data
+// @Description  Pseudonymizes the customer's wallet address and removes email and metadata from every matching order within the authenticated merchant (admins: all merchants), pseudonymizes the sender of the overpayments of those orders or sent from the customer's wallets and of the transfers from those wallets to watched addresses, and deletes the matching customer profiles. Amounts, statuses, tx hashes and ledger entries are kept. An erased overpayment can no longer be refunded, and the erasure fails with 409 while one of them is being refunded. The erasure is recorded in the audit log.
+// @Tags         privacy
+// @Accept       json
+// @Produce      json
+// @Param        subject  body  privacySubject  true  "Customer to erase"
+// @Success      200  {object}  privacyErasureResp
+// @Failure      400  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /privacy/erasure [post]
+// @Router       /admin/privacy/erasure [post]
+func PrivacyErasureHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var subject privacySubject
+	if !decodeBody(w, r, &subject) {
+		return
+	}
+	if subject.CustomerWalletAddress == "" && subject.CustomerEmail == "" {
+		badReq(w, "customer_wallet_address or customer_email is required")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	where, args := subjectFilter(r.Context(), subject)
+	// Collect the wallets first: an email match can reveal the wallet, which also has to go from audit details
+	rows, err := tx.QueryContext(ctx, `
+		SELECT customer_wallet_address FROM orders WHERE `+where+` AND customer_wallet_address IS NOT NULL
+		UNION
+		SELECT customer_wallet_address FROM orders_archive WHERE `+where+` AND customer_wallet_address IS NOT NULL
+	`, append(args, args...)...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	var wallets []string
+	for rows.Next() {
+		var wlt string
+		if err := rows.Scan(&wlt); err != nil {
+			rows.Close()
+			serverErr(w, err)
+			return
+		}
+		wallets = append(wallets, wlt)
+	}
+	rows.Close()
+
+	// Overpayments are matched through the orders, so they are found before the orders are erased.
+	// One being sent back still needs its sender until the transfer is final.
+	payers := subjectPayers(subject, wallets)
+	opWhere, opArgs := overpaymentFilter(r.Context(), subject, payers)
+	overpayments, err := subjectOverpayments(ctx, tx, opWhere, opArgs)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	for _, o := range overpayments {
+		if o.Status == overpaymentRefundQueued || o.Status == overpaymentRefundSent {
+			writeProblem(w, http.StatusConflict, CodeOverpaymentRefundInFlight, "overpayment "+o.ID+" is being refunded; retry once it is "+overpaymentRefunded+" or "+overpaymentRefundFailed)
+			return
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	pseudonym := "erased_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	var n, erasedOverpayments, erasedTransfers int64
+	for _, o := range overpayments {
+		if strings.HasPrefix(o.FromAddress, "erased_") {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE overpayments SET from_address = ?, updated_at = ? WHERE id = ?`, pseudonym, now, o.ID); err != nil {
+			serverErr(w, err)
+			return
+		}
+		erasedOverpayments++
+		payers = subjectPayers(subject, append(payers, o.FromAddress))
+	}
+	trWhere, trArgs := payerFilter(r.Context(), payers)
+	res, err := tx.ExecContext(ctx, `UPDATE address_transfers SET from_address = ? WHERE `+trWhere, append([]any{pseudonym}, trArgs...)...)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	erasedTransfers, _ = res.RowsAffected()
+	// The erased orders are not known by id; none may be served from the cache with the data.
+	flushOrderCache()
+	for _, table := range []string{"orders", "orders_archive"} {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE `+table+`
+			SET customer_wallet_address = CASE WHEN customer_wallet_address IS NULL THEN NULL ELSE ? END,
+			    customer_email = NULL, customer_email_hash = NULL, metadata_json = NULL, erased_at = ?
+			WHERE `+where, append([]any{pseudonym, now}, args...)...)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		affected, _ := res.RowsAffected()
+		n += affected
+	}
+	for _, wlt := range payers {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM customers WHERE wallet_address = ? AND (? = '' OR merchant_id = ?)
+		`, wlt, merchantIDFromContext(r.Context()), merchantIDFromContext(r.Context())); err != nil {
+			serverErr(w, err)
+			return
+		}
+		if err := redactAudit(ctx, tx, wlt, pseudonym); err != nil {
+			serverErr(w, err)
+			return
+		}
+	}
+	if (n == 0 || len(wallets) == 0) && erasedOverpayments == 0 && erasedTransfers == 0 {
+		pseudonym = ""
+	}
+	recordAudit(ctx, tx, actorFromContext(r.Context()), merchantIDFromContext(r.Context()), "", "privacy_erasure", map[string]any{
+		"by_wallet": subject.CustomerWalletAddress != "", "by_email": subject.CustomerEmail != "", "orders": n,
+		"overpayments": erasedOverpayments, "address_transfers": erasedTransfers, "pseudonym": pseudonym,
+	})
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=privacy_erasure orders=%d overpayments=%d address_transfers=%d pseudonym=%s", n, erasedOverpayments, erasedTransfers, pseudonym)
+	writeJSON(w, http.StatusOK, privacyErasureResp{
+		OrdersErased: n, OverpaymentsErased: erasedOverpayments, AddressTransfersErased: erasedTransfers, Pseudonym: pseudonym, ErasedAt: now,
+	})
+}
@@ -0,0 +1,412 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// Error responses are RFC 7807 problem details (application/problem+json). Clients branch on
+// "code", a stable identifier from the catalog below; "detail" is human-readable and may change.
+// The catalog is served at GET /v1/problems, and each problem's "type" points into it.
+
+// ErrorCode is a stable, machine-readable error code. Codes are never renamed or reused.
+type ErrorCode string
+
+const (
+	CodeBadRequest                  ErrorCode = "bad_request"
+	CodeInternalError               ErrorCode = "internal_error"
+	CodeDBError                     ErrorCode = "db_error"
+	CodeDBNotInitialized            ErrorCode = "db_not_initialized"
+	CodeMethodNotAllowed            ErrorCode = "method_not_allowed"
+	CodeInvalidJSON                 ErrorCode = "invalid_json"
+	CodeMissingFields               ErrorCode = "missing_fields"
+	CodeMissingQueryParam           ErrorCode = "missing_query_param"
+	CodeMissingIdempotencyKey       ErrorCode = "missing_idempotency_key"
+	CodeInvalidMetadata             ErrorCode = "invalid_metadata"
+	CodeInvalidAmount               ErrorCode = "invalid_amount"
+	CodeInvalidLimit                ErrorCode = "invalid_limit"
+	CodeLimitExceeded               ErrorCode = "limit_exceeded"
+	CodeAPIKeyRequired              ErrorCode = "api_key_required"
+	CodeInvalidAPIKey               ErrorCode = "invalid_api_key"
+	CodeInvalidToken                ErrorCode = "invalid_token"
+	CodeInvalidPlatformKey          ErrorCode = "invalid_platform_key"
+	CodeAPIKeyNotFound              ErrorCode = "api_key_not_found"
+	CodePrimaryKeyRequired          ErrorCode = "primary_key_required"
+	CodeInsufficientScope           ErrorCode = "insufficient_scope"
+	CodeInvalidScope                ErrorCode = "invalid_scope"
+	CodeAdminDisabled               ErrorCode = "admin_disabled"
+	CodeInvalidAdminKey             ErrorCode = "invalid_admin_key"
+	CodeAdminRequired               ErrorCode = "admin_required"
+	CodeInvalidClient               ErrorCode = "invalid_client"
+	CodeInvalidRedirectURI          ErrorCode = "invalid_redirect_uri"
+	CodeMerchantNotFound            ErrorCode = "merchant_not_found"
+	CodeMerchantMismatch            ErrorCode = "merchant_mismatch"
+	CodeMerchantNotConnected        ErrorCode = "merchant_not_connected"
+	CodeMissingWalletAddress        ErrorCode = "missing_wallet_address"
+	CodeInvalidApplicationFee       ErrorCode = "invalid_application_fee"
+	CodeOrderNotFound               ErrorCode = "order_not_found"
+	CodeOrderNotPaid                ErrorCode = "order_not_paid"
+	CodeOrderNotRefundable          ErrorCode = "order_not_refundable"
+	CodeOrderNotInReview            ErrorCode = "order_not_in_review"
+	CodeOrderNotDisputable          ErrorCode = "order_not_disputable"
+	CodeOnchainVerificationFailed   ErrorCode = "onchain_verification_failed"
+	CodeCustomerWalletUnknown       ErrorCode = "customer_wallet_unknown"
+	CodeAlreadyRefunded             ErrorCode = "already_refunded"
+	CodeCannotRefundSettled         ErrorCode = "cannot_refund_settled"
+	CodeMissingRefundAmount         ErrorCode = "missing_refund_amount"
+	CodeInvalidRefundAmount         ErrorCode = "invalid_refund_amount"
+	CodeRefundExceedsOrder          ErrorCode = "refund_exceeds_order"
+	CodeInvalidRefundTx             ErrorCode = "invalid_refund_tx"
+	CodeRefundTxAlreadyUsed         ErrorCode = "refund_tx_already_used"
+	CodeRefundVerificationFailed    ErrorCode = "refund_verification_failed"
+	CodeRefundNotFound              ErrorCode = "refund_not_found"
+	CodeRefundNotPending            ErrorCode = "refund_not_pending"
+	CodeApproverMustDiffer          ErrorCode = "approver_must_differ"
+	CodeInvalidDecision             ErrorCode = "invalid_decision"
+	CodeDisputeNotFound             ErrorCode = "dispute_not_found"
+	CodeDisputeOpen                 ErrorCode = "dispute_open"
+	CodeDisputeClosed               ErrorCode = "dispute_closed"
+	CodeDisputeAlreadyOpen          ErrorCode = "dispute_already_open"
+	CodeInvalidDisputeAmount        ErrorCode = "invalid_dispute_amount"
+	CodeInvalidOutcome              ErrorCode = "invalid_outcome"
+	CodeRetentionDisabled           ErrorCode = "retention_disabled"
+	CodeInvalidCursor               ErrorCode = "invalid_cursor"
+	CodeInvalidIdempotencyKey       ErrorCode = "invalid_idempotency_key"
+	CodeIdempotencyKeyReused        ErrorCode = "idempotency_key_reused"
+	CodeIdempotencyInProgress       ErrorCode = "idempotency_in_progress"
+	CodeInvalidWebhookURL           ErrorCode = "invalid_webhook_url"
+	CodeWebhookNotConfigured        ErrorCode = "webhook_not_configured"
+	CodeInvalidEventType            ErrorCode = "invalid_event_type"
+	CodeInvalidTimeRange            ErrorCode = "invalid_time_range"
+	CodeOrderExpired                ErrorCode = "order_expired"
+	CodeOrderNotPending             ErrorCode = "order_not_pending"
+	CodeExtensionLimitExceeded      ErrorCode = "extension_limit_exceeded"
+	CodeCustomerNotFound            ErrorCode = "customer_not_found"
+	CodeUnsupportedChain            ErrorCode = "unsupported_chain"
+	CodeRPCUnavailable              ErrorCode = "rpc_unavailable"
+	CodeGasTankNotConfigured        ErrorCode = "gas_tank_not_configured"
+	CodeTransactionNotFound         ErrorCode = "transaction_not_found"
+	CodeTransactionNotPending       ErrorCode = "transaction_not_pending"
+	CodeFeeCapReached               ErrorCode = "fee_cap_reached"
+	CodeInvalidPayoutMode           ErrorCode = "invalid_payout_mode"
+	CodeConversionNotFound          ErrorCode = "conversion_not_found"
+	CodeConversionNotFailed         ErrorCode = "conversion_not_failed"
+	CodeInvalidSettlementTarget     ErrorCode = "invalid_settlement_target"
+	CodeOfframpNotConfigured        ErrorCode = "offramp_not_configured"
+	CodeKYCNotApproved              ErrorCode = "kyc_not_approved"
+	CodeInsufficientBalance         ErrorCode = "insufficient_balance"
+	CodeInvalidCurrency             ErrorCode = "invalid_currency"
+	CodeOfframpUnavailable          ErrorCode = "offramp_unavailable"
+	CodeRateUnavailable             ErrorCode = "rate_unavailable"
+	CodeStaleRate                   ErrorCode = "stale_rate"
+	CodeUnsupportedRatePair         ErrorCode = "unsupported_rate_pair"
+	CodeInvalidTimezone             ErrorCode = "invalid_timezone"
+	CodeInvalidMetric               ErrorCode = "invalid_metric"
+	CodeSchedulerNotFound           ErrorCode = "scheduler_not_found"
+	CodeCouponNotFound              ErrorCode = "coupon_not_found"
+	CodeCouponExists                ErrorCode = "coupon_exists"
+	CodeCouponInvalid               ErrorCode = "coupon_invalid"
+	CodeInvalidLineItems            ErrorCode = "invalid_line_items"
+	CodeHotWalletUnavailable        ErrorCode = "hot_wallet_unavailable"
+	CodeRefundJobNotFound           ErrorCode = "refund_job_not_found"
+	CodeStatusOverrideNotAllowed    ErrorCode = "status_override_not_allowed"
+	CodeTxAlreadyUsed               ErrorCode = "tx_already_used"
+	CodeInvalidWalletAddress        ErrorCode = "invalid_wallet_address"
+	CodeWalletChallengeNotFound     ErrorCode = "wallet_challenge_not_found"
+	CodeWalletChallengeExpired      ErrorCode = "wallet_challenge_expired"
+	CodeWalletProofInvalid          ErrorCode = "wallet_proof_invalid"
+	CodeWalletProofUnsupported      ErrorCode = "wallet_proof_unsupported"
+	CodeMerchantPendingApproval     ErrorCode = "merchant_pending_approval"
+	CodeMerchantRejected            ErrorCode = "merchant_rejected"
+	CodeMerchantAlreadyDecided      ErrorCode = "merchant_already_decided"
+	CodeAuthBlocked                 ErrorCode = "auth_blocked"
+	CodeAuthBanNotFound             ErrorCode = "auth_ban_not_found"
+	CodeValidationFailed            ErrorCode = "validation_failed"
+	CodeRequestTooLarge             ErrorCode = "request_too_large"
+	CodeUnsupportedMediaType        ErrorCode = "unsupported_media_type"
+	CodePaymentIntentNotFound       ErrorCode = "payment_intent_not_found"
+	CodePaymentIntentFinalized      ErrorCode = "payment_intent_finalized"
+	CodeJobNotFound                 ErrorCode = "job_not_found"
+	CodeJobNotDead                  ErrorCode = "job_not_dead"
+	CodeRetryPolicyNotFound         ErrorCode = "retry_policy_not_found"
+	CodeInvalidRetryPolicy          ErrorCode = "invalid_retry_policy"
+	CodePayoutNotFound              ErrorCode = "payout_not_found"
+	CodePayoutNotDeadLettered       ErrorCode = "payout_not_dead_lettered"
+	CodeVerificationQueueFull       ErrorCode = "verification_queue_full"
+	CodeReconciliationNotConfigured ErrorCode = "reconciliation_not_configured"
+	CodeAttestationNotFound         ErrorCode = "attestation_not_found"
+	CodeSweepNotFound               ErrorCode = "sweep_not_found"
+	CodeSweepNotPending             ErrorCode = "sweep_not_pending"
+	CodeAddressAlreadyWatched       ErrorCode = "address_already_watched"
+	CodeWatchLimitReached           ErrorCode = "watch_limit_reached"
+	CodeWatchedAddressNotFound      ErrorCode = "watched_address_not_found"
+	CodeInvalidOrganizationKey      ErrorCode = "invalid_organization_key"
+	CodeOrganizationRoleForbidden   ErrorCode = "organization_role_forbidden"
+	CodeMemberNotFound              ErrorCode = "member_not_found"
+	CodeMemberAlreadyExists         ErrorCode = "member_already_exists"
+	CodeOwnerNotRemovable           ErrorCode = "owner_not_removable"
+	CodeMerchantNotInOrganization   ErrorCode = "merchant_not_in_organization"
+	CodeMerchantInOrganization      ErrorCode = "merchant_in_organization"
+	CodeMerchantHeaderRequired      ErrorCode = "merchant_header_required"
+	CodeInvalidWebhookSecret        ErrorCode = "invalid_webhook_secret"
+	CodeInvalidTag                  ErrorCode = "invalid_tag"
+	CodeTooManyTags                 ErrorCode = "too_many_tags"
+	CodeOverpaymentNotFound         ErrorCode = "overpayment_not_found"
+	CodeOverpaymentNotOpen          ErrorCode = "overpayment_not_open"
+	CodeAssetNotAccepted            ErrorCode = "asset_not_accepted"
+	CodeInvalidAcceptedAssets       ErrorCode = "invalid_accepted_assets"
+	CodeInvalidRateQuote            ErrorCode = "invalid_rate_quote"
+	CodeHoldNotFound                ErrorCode = "hold_not_found"
+	CodeHoldNotActive               ErrorCode = "hold_not_active"
+	CodeHoldExceedsBalance          ErrorCode = "hold_exceeds_balance"
+	CodeLedgerTransactionNotFound   ErrorCode = "ledger_transaction_not_found"
+	CodeMerchantDeleted             ErrorCode = "merchant_deleted"
+	CodeMerchantNotDeleted          ErrorCode = "merchant_not_deleted"
+	CodeOrderNotDeleted             ErrorCode = "order_not_deleted"
+	CodeOrderHasPayment             ErrorCode = "order_has_payment"
+	CodeIdempotencyResponseWithheld ErrorCode = "idempotency_response_withheld"
+	CodeOverpaymentRefundInFlight   ErrorCode = "overpayment_refund_in_flight"
+	CodeOverpaymentSenderErased     ErrorCode = "overpayment_sender_erased"
+	CodeNotFound                    ErrorCode = "not_found"
+)
+
+// problemTitles is the error code catalog: every code a handler may return, with its title.
+var problemTitles = map[ErrorCode]string{
+	CodeBadRequest:                  "The request is invalid",
+	CodeInternalError:               "Internal server error",
+	CodeDBError:                     "Database error",
+	CodeDBNotInitialized:            "Database not initialized",
+	CodeMethodNotAllowed:            "Method not allowed",
+	CodeInvalidJSON:                 "The request body is not valid JSON",
+	CodeMissingFields:               "Required fields are missing",
+	CodeMissingQueryParam:           "A required query parameter is missing",
+	CodeMissingIdempotencyKey:       "An idempotency key is required",
+	CodeInvalidMetadata:             "Metadata must be a JSON object",
+	CodeInvalidAmount:               "The amount is not a valid minor-unit integer",
+	CodeInvalidLimit:                "The limit value is invalid",
+	CodeLimitExceeded:               "A velocity limit was exceeded",
+	CodeAPIKeyRequired:              "An API key is required",
+	CodeInvalidAPIKey:               "The API key is invalid",
+	CodeInvalidToken:                "The access token is invalid, expired or revoked",
+	CodeInvalidPlatformKey:          "The platform API key is invalid",
+	CodeAPIKeyNotFound:              "API key not found",
+	CodePrimaryKeyRequired:          "The primary merchant API key is required",
+	CodeInsufficientScope:           "The credential lacks the required scope",
+	CodeInvalidScope:                "The requested scope is invalid",
+	CodeAdminDisabled:               "Admin endpoints are disabled",
+	CodeInvalidAdminKey:             "The admin key is invalid",
+	CodeAdminRequired:               "Only an administrator can do this",
+	CodeInvalidClient:               "OAuth client authentication failed",
+	CodeInvalidRedirectURI:          "The redirect URI is not registered",
+	CodeMerchantNotFound:            "Merchant not found",
+	CodeMerchantMismatch:            "The merchant does not match the authenticated merchant",
+	CodeMerchantNotConnected:        "The merchant is not connected to this platform",
+	CodeMissingWalletAddress:        "The merchant wallet address is not set",
+	CodeInvalidApplicationFee:       "The application fee is invalid",
+	CodeOrderNotFound:               "Order not found",
+	CodeOrderNotPaid:                "The order is not paid",
+	CodeOrderNotRefundable:          "The order cannot be refunded",
+	CodeOrderNotInReview:            "The order is not in review",
+	CodeOrderNotDisputable:          "The order cannot be disputed",
+	CodeOnchainVerificationFailed:   "On-chain verification failed",
+	CodeCustomerWalletUnknown:       "The customer wallet is unknown",
+	CodeAlreadyRefunded:             "The order is already fully refunded",
+	CodeCannotRefundSettled:         "Settled orders cannot be refunded",
+	CodeMissingRefundAmount:         "A refund amount is required",
+	CodeInvalidRefundAmount:         "The refund amount is invalid",
+	CodeRefundExceedsOrder:          "The refund exceeds the refundable amount",
+	CodeInvalidRefundTx:             "The refund transaction hash is invalid",
+	CodeRefundTxAlreadyUsed:         "The refund transaction was already recorded",
+	CodeRefundVerificationFailed:    "The refund transaction could not be verified",
+	CodeRefundNotFound:              "Refund not found",
+	CodeRefundNotPending:            "The refund is not awaiting a decision",
+	CodeApproverMustDiffer:          "The approver must differ from the requester",
+	CodeInvalidDecision:             "The decision is invalid",
+	CodeDisputeNotFound:             "Dispute not found",
+	CodeDisputeOpen:                 "The order has an open dispute",
+	CodeDisputeClosed:               "The dispute is closed",
+	CodeDisputeAlreadyOpen:          "A dispute is already open for this order",
+	CodeInvalidDisputeAmount:        "The dispute amount is invalid",
+	CodeInvalidOutcome:              "The dispute outcome is invalid",
+	CodeRetentionDisabled:           "Retention is disabled",
+	CodeInvalidCursor:               "The pagination cursor is invalid",
+	CodeInvalidIdempotencyKey:       "The Idempotency-Key header is invalid",
+	CodeIdempotencyKeyReused:        "The Idempotency-Key was used with a different request",
+	CodeIdempotencyInProgress:       "A request with this Idempotency-Key is in progress",
+	CodeInvalidWebhookURL:           "The webhook URL is invalid",
+	CodeWebhookNotConfigured:        "No webhook URL is configured",
+	CodeInvalidEventType:            "The event type is unknown",
+	CodeInvalidTimeRange:            "The time range is invalid",
+	CodeOrderExpired:                "The order has expired",
+	CodeOrderNotPending:             "The order is not pending",
+	CodeExtensionLimitExceeded:      "The order cannot be extended that far",
+	CodeCustomerNotFound:            "Customer not found",
+	CodeUnsupportedChain:            "The chain is not supported",
+	CodeRPCUnavailable:              "The chain's RPC endpoint is unavailable",
+	CodeGasTankNotConfigured:        "No hot wallet is configured",
+	CodeTransactionNotFound:         "The transaction does not exist",
+	CodeTransactionNotPending:       "The transaction is no longer pending",
+	CodeFeeCapReached:               "The fee ceiling was reached",
+	CodeInvalidPayoutMode:           "The payout mode is invalid",
+	CodeConversionNotFound:          "Conversion not found",
+	CodeConversionNotFailed:         "The conversion has not failed",
+	CodeInvalidSettlementTarget:     "The settlement asset or chain is invalid",
+	CodeOfframpNotConfigured:        "No off-ramp partner is configured",
+	CodeKYCNotApproved:              "The merchant has not passed KYC with the off-ramp partner",
+	CodeInsufficientBalance:         "The settled balance is insufficient",
+	CodeInvalidCurrency:             "The fiat currency is invalid",
+	CodeOfframpUnavailable:          "The off-ramp partner could not be reached",
+	CodeRateUnavailable:             "No rate could be read",
+	CodeStaleRate:                   "The rate is stale",
+	CodeUnsupportedRatePair:         "No rate source for the pair",
+	CodeInvalidTimezone:             "The timezone is not a known IANA timezone",
+	CodeInvalidMetric:               "The metric or interval is not supported",
+	CodeSchedulerNotFound:           "The scheduler was not found",
+	CodeCouponNotFound:              "Coupon not found",
+	CodeCouponExists:                "Coupon code already exists",
+	CodeCouponInvalid:               "Coupon cannot be applied",
+	CodeInvalidLineItems:            "Invalid line items",
+	CodeHotWalletUnavailable:        "No hot wallet signer is configured",
+	CodeRefundJobNotFound:           "Refund job not found",
+	CodeStatusOverrideNotAllowed:    "The order's status cannot be overridden",
+	CodeTxAlreadyUsed:               "The transaction is already recorded on another order",
+	CodeInvalidWalletAddress:        "The wallet address is not valid for the chain",
+	CodeWalletChallengeNotFound:     "The wallet challenge was not found",
+	CodeWalletChallengeExpired:      "The wallet challenge has expired or was used",
+	CodeWalletProofInvalid:          "The signature does not prove ownership of the wallet",
+	CodeWalletProofUnsupported:      "Ownership proofs are not supported for this wallet",
+	CodeMerchantPendingApproval:     "The merchant is waiting for approval",
+	CodeMerchantRejected:            "The merchant application was rejected",
+	CodeMerchantAlreadyDecided:      "The merchant application was already decided",
+	CodeAuthBlocked:                 "Too many failed authentication attempts",
+	CodeAuthBanNotFound:             "No ban for this IP",
+	CodeValidationFailed:            "The request has invalid fields",
+	CodeRequestTooLarge:             "The request body is too large",
+	CodeUnsupportedMediaType:        "The request body must be JSON",
+	CodePaymentIntentNotFound:       "Payment intent not found",
+	CodePaymentIntentFinalized:      "Payment intent is already captured or voided",
+	CodeJobNotFound:                 "The job was not found",
+	CodeJobNotDead:                  "Only dead jobs can be retried",
+	CodeRetryPolicyNotFound:         "No retry policy exists for the job type",
+	CodeInvalidRetryPolicy:          "The retry policy is invalid",
+	CodePayoutNotFound:              "Payout not found",
+	CodePayoutNotDeadLettered:       "Only dead-lettered payouts can be retried",
+	CodeVerificationQueueFull:       "Too many payments are waiting for verification",
+	CodeReconciliationNotConfigured: "On-chain reconciliation is not configured",
+	CodeAttestationNotFound:         "Attestation not found",
+	CodeSweepNotFound:               "Sweep not found",
+	CodeSweepNotPending:             "The sweep is not awaiting approval",
+	CodeAddressAlreadyWatched:       "The address is already watched",
+	CodeWatchLimitReached:           "Too many watched addresses",
+	CodeWatchedAddressNotFound:      "Watched address not found",
+	CodeInvalidOrganizationKey:      "The organization API key is invalid",
+	CodeOrganizationRoleForbidden:   "The member's role does not allow this",
+	CodeMemberNotFound:              "Member not found",
+	CodeMemberAlreadyExists:         "The member already exists",
+	CodeOwnerNotRemovable:           "The organization owner cannot be removed",
+	CodeMerchantNotInOrganization:   "The merchant does not belong to this organization",
+	CodeMerchantInOrganization:      "The merchant already belongs to an organization",
+	CodeMerchantHeaderRequired:      "The X-Merchant-ID header is required",
+	CodeInvalidWebhookSecret:        "The webhook secret is invalid",
+	CodeInvalidTag:                  "Invalid tag",
+	CodeTooManyTags:                 "Too many tags",
+	CodeOverpaymentNotFound:         "Overpayment not found",
+	CodeOverpaymentNotOpen:          "The overpayment was already refunded or is being refunded",
+	CodeAssetNotAccepted:            "The merchant does not accept this asset on this chain",
+	CodeInvalidAcceptedAssets:       "The accepted assets are invalid",
+	CodeInvalidRateQuote:            "The rate quote cannot price the order",
+	CodeHoldNotFound:                "Balance hold not found",
+	CodeHoldNotActive:               "The balance hold is no longer active",
+	CodeHoldExceedsBalance:          "The hold exceeds the unsettled balance",
+	CodeLedgerTransactionNotFound:   "Ledger transaction not found",
+	CodeMerchantDeleted:             "The merchant was deleted",
+	CodeMerchantNotDeleted:          "The merchant is not deleted",
+	CodeOrderNotDeleted:             "The order is not deleted",
+	CodeOrderHasPayment:             "The order has a payment",
+	CodeIdempotencyResponseWithheld: "The stored response held a secret and is not replayed",
+	CodeOverpaymentRefundInFlight:   "Overpayment refund in flight",
+	CodeOverpaymentSenderErased:     "Overpayment sender erased",
+	CodeNotFound:                    "Not found",
+}
+
+// Problem is an RFC 7807 problem details body. Errors lists the fields of a request body that are
+// missing or invalid, when there are any.
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Code   ErrorCode    `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is one missing or invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. "line_items[0].name"
+	Rule    string `json:"rule"`  // required | type | max | min | oneof | asset | chain | amount | rfc3339
+	Message string `json:"message"`
+}
+
+func problemType(code ErrorCode) string { return "/v1/problems/" + string(code) }
+
+// writeProblem writes an application/problem+json error response.
+func writeProblem(w http.ResponseWriter, status int, code ErrorCode, detail string) {
+	title, ok := problemTitles[code]
+	if !ok {
+		title = http.StatusText(status)
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Problem{Type: problemType(code), Title: title, Status: status, Detail: detail, Code: code})
+}
+
+// writeFieldProblem writes a 400 problem listing the fields at fault.
+func writeFieldProblem(w http.ResponseWriter, code ErrorCode, detail string, errs []FieldError) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(Problem{
+		Type: problemType(code), Title: problemTitles[code], Status: http.StatusBadRequest, Detail: detail, Code: code, Errors: errs,
+	})
+}
+
+type problemCatalogEntry struct {
+	Code  ErrorCode `json:"code"`
+	Type  string    `json:"type"`
+	Title string    `json:"title"`
+}
+
+// ProblemCatalogHandler godoc
+// @Summary      List error codes
+// @Description  Returns the catalog of error codes used in problem+json responses.
+// @Tags         meta
+// @Produce      json
+// @Success      200  {array}   problemCatalogEntry
+// @Router       /v1/problems [get]
+func ProblemCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	entries := make([]problemCatalogEntry, 0, len(problemTitles))
+	for code, title := range problemTitles {
+		entries = append(entries, problemCatalogEntry{Code: code, Type: problemType(code), Title: title})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	writeJSONOrders(w, http.StatusOK, entries)
+}
+
+// ProblemHandler godoc
+// @Summary      Get an error code
+// @Description  Returns the catalog entry of one error code; the type URI of a problem+json response points here.
+// @Tags         meta
+// @Produce      json
+// @Param        code  path  string  true  "Error code"
+// @Success      200  {object}  problemCatalogEntry
+// @Failure      404  {object}  Problem
+// @Router       /v1/problems/{code} [get]
+func ProblemHandler(w http.ResponseWriter, r *http.Request) {
+	code := ErrorCode(r.PathValue("code"))
+	title, ok := problemTitles[code]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, CodeNotFound, "unknown error code")
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, problemCatalogEntry{Code: code, Type: problemType(code), Title: title})
+}
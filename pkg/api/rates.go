@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/rates"
+)
+
+var rateProvider rates.Provider
+
+// SetRateProvider sets where exchange rates come from; nil turns the rates endpoint off.
+func SetRateProvider(p rates.Provider) { rateProvider = p }
+
+// rateResp is a rate as served, recorded in rate_quotes under QuoteID.
+type rateResp struct {
+	QuoteID   string `json:"quote_id"` // prices an order in fiat (rate_quote_id) and finds the rate later
+	Base      string `json:"base"`
+	Quote     string `json:"quote"`
+	Rate      string `json:"rate"` // quote per unit of base, as a decimal
+	UpdatedAt string `json:"updated_at"`
+	Provider  string `json:"provider"`
+	Source    string `json:"source,omitempty"`
+	QuotedAt  string `json:"quoted_at"` // when it was served
+}
+
+const rateQuoteCols = `id, base, quote, rate, rate_updated_at, provider, source, created_at`
+
+func scanRateQuote(row scanner) (rateResp, error) {
+	var (
+		q      rateResp
+		source sql.NullString
+	)
+	err := row.Scan(&q.QuoteID, &q.Base, &q.Quote, &q.Rate, &q.UpdatedAt, &q.Provider, &source, &q.QuotedAt)
+	q.Source = source.String
+	return q, err
+}
+
+// recordRateQuote stores rate as served to merchantID ("" for an admin) and returns it with its
+// quote ID.
+func recordRateQuote(ctx context.Context, q execer, merchantID, provider string, rate rates.Rate) (rateResp, error) {
+	rq := rateResp{
+		QuoteID: "rq_" + uuid.New().String(), Base: rate.Base, Quote: rate.Quote, Rate: rate.String(),
+		UpdatedAt: rate.UpdatedAt.UTC().Format(time.RFC3339), Provider: provider, Source: rate.Source,
+		QuotedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO rate_quotes (id, merchant_id, base, quote, rate, provider, source, rate_updated_at, created_at)
+		VALUES (?, NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`, rq.QuoteID, merchantID, rq.Base, rq.Quote, rq.Rate, rq.Provider, rq.Source, rq.UpdatedAt, rq.QuotedAt)
+	return rq, err
+}
+
+// RateHandler godoc
+// @Summary      Get an exchange rate
+// @Description  Returns the current rate of base in quote (e.g. base=USDT&quote=USD) from the configured rate provider. Every rate served is recorded under its quote_id, which prices an order in fiat (rate_quote_id) and stays listed in /rates/history. With RATE_PROVIDER=chainlink rates are read from Chainlink price feed contracts over the chains' RPC endpoints; a feed whose latest answer is older than its staleness limit is refused with 503 stale_rate rather than served.
+// @Tags         rates
+// @Produce      json
+// @Param        base   query  string  true  "Asset or currency priced, e.g. USDT"
+// @Param        quote  query  string  true  "Currency the price is in, e.g. USD"
+// @Success      200  {object}  rateResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      502  {object}  Problem
+// @Failure      503  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /rates [get]
+func RateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	if rateProvider == nil {
+		writeProblem(w, http.StatusServiceUnavailable, CodeRateUnavailable, "no rate provider is configured; set RATE_PROVIDER")
+		return
+	}
+	base, quote := strings.ToUpper(r.URL.Query().Get("base")), strings.ToUpper(r.URL.Query().Get("quote"))
+	if base == "" || quote == "" {
+		writeProblem(w, http.StatusBadRequest, CodeMissingFields, "base and quote are required")
+		return
+	}
+	rate, err := rateProvider.Rate(r.Context(), base, quote)
+	switch {
+	case errors.Is(err, rates.ErrUnsupportedPair):
+		writeProblem(w, http.StatusNotFound, CodeUnsupportedRatePair, "no rate source for "+base+"/"+quote)
+		return
+	case errors.Is(err, rates.ErrStale):
+		writeProblem(w, http.StatusServiceUnavailable, CodeStaleRate, err.Error())
+		return
+	case err != nil:
+		writeProblem(w, http.StatusBadGateway, CodeRateUnavailable, err.Error())
+		return
+	}
+	rq, err := recordRateQuote(r.Context(), db, merchantIDFromContext(r.Context()), rateProvider.Name(), rate)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, rq)
+}
+
+type rateHistoryResp struct {
+	Quotes []rateResp `json:"quotes"`
+}
+
+// RateHistoryHandler godoc
+// @Summary      List rates served
+// @Description  Lists the rates /rates served the merchant, newest first, so the conversion behind a fiat-priced order can be checked after the fact: base and quote narrow it to a pair, from and to (RFC 3339) bound when they were served to [from, to), and limit caps the list (default 100, max 1000). A quote is kept as served, with the provider, its source and when the provider last updated it. Admins see every merchant's quotes, or one merchant's with merchant_id.
+// @Tags         rates
+// @Produce      json
+// @Param        base         query  string  false  "Asset or currency priced, e.g. USDT"
+// @Param        quote        query  string  false  "Currency the price is in, e.g. USD"
+// @Param        from         query  string  false  "Served at or after, RFC 3339"
+// @Param        to           query  string  false  "Served before, RFC 3339"
+// @Param        limit        query  int     false  "Maximum quotes (default 100, max 1000)"
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {object}  rateHistoryResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /rates/history [get]
+// @Router       /admin/rates/history [get]
+func RateHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = q.Get("merchant_id")
+	}
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidLimit, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+	var from, to string
+	for _, b := range []struct {
+		name string
+		out  *string
+	}{{"from", &from}, {"to", &to}} {
+		v := q.Get(b.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, b.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*b.out = t.UTC().Format(time.RFC3339)
+	}
+	if from != "" && to != "" && to <= from {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be after from")
+		return
+	}
+	base, quote := strings.ToUpper(q.Get("base")), strings.ToUpper(q.Get("quote"))
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+rateQuoteCols+` FROM rate_quotes
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR created_at >= ?) AND (? = '' OR created_at < ?)
+		  AND (? = '' OR base = ?) AND (? = '' OR quote = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, merchantID, merchantID, from, from, to, to, base, base, quote, quote, limit)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	resp := rateHistoryResp{Quotes: []rateResp{}}
+	for rows.Next() {
+		rq, err := scanRateQuote(rows)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		resp.Quotes = append(resp.Quotes, rq)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
@@ -5,184 +5,721 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"math/big"
 	"net/http"
+	"strings"
 	"time"
-)
 
-var refundsProcessedTotal int64
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
 
 type refundResp struct {
-	OrderID string `json:"order_id"`
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	OrderID            string `json:"order_id"`
+	RefundID           string `json:"refund_id,omitempty"`
+	Status             string `json:"status"`                         // order status
+	RefundStatus       string `json:"refund_status,omitempty"`        // REQUESTED | COMPLETED | REJECTED
+	AmountMinor        string `json:"amount_minor,omitempty"`         // this refund
+	RefundedTotalMinor string `json:"refunded_total_minor,omitempty"` // all completed refunds on the order
+	RefundableMinor    string `json:"refundable_minor,omitempty"`     // what is left to refund
+	Message            string `json:"message"`
 }
 type refundReq struct {
-	OrderID              string `json:"order_id"`
-	AmountMinor          *int64 `json:"amount_minor,omitempty"`
-	RefundTxHash         string `json:"refundtxhash,omitempty"`
-	RefundIdempotencyKey string `json:"refund_idempotency_key"`
+	OrderID              string       `json:"order_id"`
+	AmountMinor          *json.Number `json:"amount_minor,omitempty"` // number or string; omitted means the full remaining balance
+	RefundTxHash         string       `json:"refundtxhash,omitempty" validate:"max=100"`
+	RefundIdempotencyKey string       `json:"refund_idempotency_key" validate:"max=255"`
+}
+
+type refundRecord struct {
+	ID           string  `json:"id"`
+	OrderID      string  `json:"order_id"`
+	AmountMinor  string  `json:"amount_minor"`
+	Status       string  `json:"status"`
+	RefundTxHash *string `json:"refund_tx_hash,omitempty"`
+	RequestedBy  *string `json:"requested_by,omitempty"`
+	DecidedBy    *string `json:"decided_by,omitempty"`
+	DecidedAt    *string `json:"decided_at,omitempty"`
+	// Set for refunds the hot wallet sends to the customer: QUEUED | SENT | EXECUTED | FAILED
+	ExecutionStatus *string `json:"execution_status,omitempty"`
+	ExecutionError  *string `json:"execution_error,omitempty"`
+	CreatedAt       string  `json:"created_at"`
 }
 
 const (
 	refundEvent = "REFUND"
+
+	refundStatusRequested = "REQUESTED"
+	refundStatusCompleted = "COMPLETED"
+	refundStatusRejected  = "REJECTED"
+
+	// Execution states of refunds sent by the hot wallet. Only COMPLETED refunds are sent.
+	refundExecQueued   = "QUEUED"
+	refundExecSent     = "SENT"
+	refundExecExecuted = "EXECUTED"
+	refundExecFailed   = "FAILED"
 )
 
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// refundsTotal sums an order's refunds in the given statuses. Amounts are summed as big integers in Go
+// because SQLite's SUM overflows on 18-decimal values.
+func refundsTotal(ctx context.Context, q queryer, orderID string, statuses ...string) (*big.Int, error) {
+	query := `SELECT amount_minor FROM refunds WHERE order_id = ? AND status IN (?` + strings.Repeat(`, ?`, len(statuses)-1) + `)`
+	args := []any{orderID}
+	for _, st := range statuses {
+		args = append(args, st)
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	total := new(big.Int)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, errors.New("invalid refund amount_minor format")
+		}
+		total.Add(total, v)
+	}
+	return total, rows.Err()
+}
+
+var (
+	errRefundTxInvalid       = errors.New("refundtxhash is not a valid transaction hash")
+	errRefundTxNoMatch       = errors.New("refund transaction does not transfer the refund amount of the order token to the customer wallet")
+	errCustomerWalletUnknown = errors.New("customer wallet address for the order is unknown")
+)
+
+// verifyRefundTx checks that txHash transfers exactly amount of the order's token to the paying customer's wallet.
+// If the order has no customer wallet on record it is recovered from the sender of the original payment.
+func verifyRefundTx(ctx context.Context, orderID, txHash string, amount *big.Int) error {
+	if !blockchain.IsTxHash(txHash) {
+		return errRefundTxInvalid
+	}
+	var (
+		merchantID, asset, chain, depositAddress string
+		customerWallet, paymentTx                sql.NullString
+	)
+	if err := db.QueryRowContext(ctx, `
+		SELECT merchant_id, asset, chain, deposit_address, customer_wallet_address, tx_hash FROM orders WHERE id = ?
+	`, orderID).Scan(&merchantID, &asset, &chain, &depositAddress, &customerWallet, &paymentTx); err != nil {
+		return err
+	}
+	if !authorizedFor(ctx, merchantID) {
+		return sql.ErrNoRows
+	}
+	// Only BSC-USD on BSC has a verifier today; other chains are accepted like the payment path does
+	if strings.ToUpper(asset) != "USDT" || strings.ToUpper(chain) != "BSC" {
+		log.Printf("Skipping on-chain refund verification for %s on %s chain (order %s)", asset, chain, orderID)
+		return nil
+	}
+
+	customer := customerWallet.String
+	// Orders paid before the sender was captured at verification time: recover it from the payment tx
+	if customer == "" && paymentTx.Valid {
+		transfers, err := blockchain.BSCUSDTransfers(paymentTx.String)
+		if err != nil {
+			return fmt.Errorf("load payment transaction: %w", err)
+		}
+		for _, t := range transfers {
+			if strings.EqualFold(t.To.Hex(), depositAddress) {
+				customer = t.From.Hex()
+				break
+			}
+		}
+		if customer != "" {
+			invalidateOrder(orderID)
+			_, _ = db.ExecContext(ctx, `UPDATE orders SET customer_wallet_address = ? WHERE id = ? AND customer_wallet_address IS NULL`, customer, orderID)
+		}
+	}
+	if customer == "" {
+		return errCustomerWalletUnknown
+	}
+
+	transfers, err := blockchain.BSCUSDTransfers(txHash)
+	if err != nil {
+		return fmt.Errorf("load refund transaction: %w", err)
+	}
+	for _, t := range transfers {
+		if strings.EqualFold(t.To.Hex(), customer) && t.Amount.Cmp(amount) == 0 {
+			log.Printf("refund verification passed order=%s tx=%s to=%s amount=%s", orderID, txHash, customer, amount.String())
+			return nil
+		}
+	}
+	return errRefundTxNoMatch
+}
+
 // RefundHandler godoc
 // @Summary      Refund an order
-// @Description  Refunds a paid order by ID
+// @Description  Records a full or partial refund of a paid order. Multiple partial refunds are allowed up to the remaining refundable amount; each needs its own refund_idempotency_key. If the merchant refunded on-chain, refundtxhash and amount_minor must be provided and the transfer to the customer wallet is verified first.
 // @Tags         orders
 // @Accept       json
 // @Produce      json
 // @Param        id  query  string  true  "Order ID"
 // @Param        refund  body  refundReq  true  "Refund info"
 // @Success      200  {object}  refundResp
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Security     ApiKeyAuth
 // @Router       /orders/refund [post]
 func RefundHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	if db == nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_not_initialized", "db not initialized")
+		writeProblem(w, http.StatusInternalServerError, CodeDBNotInitialized, "db not initialized")
 		return
 	}
 
-	orderID := r.URL.Query().Get("id")
+	orderID := pathID(r)
 	if orderID == "" {
-		writeErrorJSON(w, http.StatusBadRequest, "missing_query_param", "missing query param")
+		writeProblem(w, http.StatusBadRequest, CodeMissingQueryParam, "missing query param")
 		return
 	}
 	var req refundReq
-	if r.Body != nil {
-		_ = json.NewDecoder(r.Body).Decode(&req)
+	if !decodeBody(w, r, &req) {
+		return
 	}
 	if req.RefundIdempotencyKey == "" {
-		writeErrorJSON(w, http.StatusBadRequest, "missing_idempotency_key", "refund_idempotency_key is required")
+		writeProblem(w, http.StatusBadRequest, CodeMissingIdempotencyKey, "refund_idempotency_key is required")
 		return
 	}
 
 	// Check for existing refund with this idempotency key
-	const sel = `SELECT id, status FROM orders WHERE refund_idempotency_key = ? AND id = ?`
-	var existingID, existingStatus string
-	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	const sel = `SELECT id, amount_minor, status FROM refunds WHERE idempotency_key = ? AND order_id = ?`
+	var existingID, existingAmount, existingStatus string
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
 	defer cancel()
-	err := db.QueryRowContext(ctx, sel, req.RefundIdempotencyKey, orderID).Scan(&existingID, &existingStatus)
+	err := db.QueryRowContext(ctx, sel, req.RefundIdempotencyKey, orderID).Scan(&existingID, &existingAmount, &existingStatus)
 	if err == nil {
+		var orderStatus string
+		_ = db.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ?`, orderID).Scan(&orderStatus)
 		// Refund already exists, return it
 		writeJSON(w, http.StatusOK, refundResp{
-			OrderID: existingID,
-			Status:  existingStatus,
-			Message: "no-op (already refunded)",
+			OrderID:      orderID,
+			RefundID:     existingID,
+			Status:       orderStatus,
+			RefundStatus: existingStatus,
+			AmountMinor:  existingAmount,
+			Message:      "no-op (already refunded)",
 		})
 		return
 	} else if err != sql.ErrNoRows {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
 		return
 	}
 
+	// Merchant-executed on-chain refunds must prove the transfer before anything is recorded
+	if req.RefundTxHash != "" {
+		if req.AmountMinor == nil {
+			writeProblem(w, http.StatusBadRequest, CodeMissingRefundAmount, "amount_minor is required when refundtxhash is provided")
+			return
+		}
+		claimed, ok := new(big.Int).SetString(req.AmountMinor.String(), 10)
+		if !ok || claimed.Sign() <= 0 {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidRefundAmount, "refund amount must be > 0")
+			return
+		}
+		if err := verifyRefundTx(ctx, orderID, req.RefundTxHash, claimed); err != nil {
+			switch {
+			case errors.Is(err, errRefundTxInvalid):
+				writeProblem(w, http.StatusBadRequest, CodeInvalidRefundTx, err.Error())
+			case errors.Is(err, errCustomerWalletUnknown):
+				writeProblem(w, http.StatusConflict, CodeCustomerWalletUnknown, err.Error())
+			case errors.Is(err, sql.ErrNoRows):
+				writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
+			default:
+				writeProblem(w, http.StatusBadRequest, CodeRefundVerificationFailed, err.Error())
+			}
+			return
+		}
+		// On-chain verification can take a while; give the DB transaction a fresh deadline.
+		ctx, cancel = store.WithTimeout(r.Context(), store.OpWrite)
+		defer cancel()
+	}
+
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		serverErr(w, err)
 		return
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	rec, err := recordRefund(ctx, tx, refundRequest{
+		OrderID:        orderID,
+		MerchantID:     merchantIDFromContext(r.Context()),
+		AmountMinor:    req.AmountMinor,
+		TxHash:         req.RefundTxHash,
+		IdempotencyKey: req.RefundIdempotencyKey,
+		RequestedBy:    credentialFromContext(r.Context()),
+	})
+	var re *refundError
+	if errors.As(err, &re) {
+		writeProblem(w, re.Status, re.Code, re.Msg)
+		return
+	} else if err != nil {
+		writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+		return
+	}
+
+	// Commit atomically
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+
+	if rec.RefundStatus == refundStatusRequested {
+		log.Printf("event=refund_requested order_id=%s refund_id=%s merchant_id=%s asset=%s amount_minor=%s", orderID, rec.RefundID, rec.MerchantID, rec.Asset, rec.AmountMinor)
+		writeJSON(w, http.StatusAccepted, rec.refundResp)
+		return
+	}
+	log.Printf("event=refund_processed order_id=%s refund_id=%s merchant_id=%s asset=%s amount_minor=%s status=%s", orderID, rec.RefundID, rec.MerchantID, rec.Asset, rec.AmountMinor, rec.Status)
+	refundsProcessedTotal.inc()
+	writeJSON(w, http.StatusOK, rec.refundResp)
+
+}
+
+// refundError is a refund recordRefund refused, with the problem to answer it with.
+type refundError struct {
+	Status int
+	Code   ErrorCode
+	Msg    string
+}
+
+func (e *refundError) Error() string { return e.Msg }
+
+// refundRequest is a refund to record against an order.
+type refundRequest struct {
+	OrderID        string
+	MerchantID     string       // when set, the order must belong to this merchant
+	AmountMinor    *json.Number // nil refunds the full remaining balance
+	TxHash         string       // the merchant's verified refund transaction, if any
+	IdempotencyKey string
+	RequestedBy    string
+	Execute        bool // queue the transfer to the customer wallet for the hot wallet
+}
+
+// recordedRefund is what recordRefund wrote, with the order's merchant and asset for logging.
+type recordedRefund struct {
+	refundResp
+	MerchantID, Asset string
+}
+
+// recordRefund checks that the order can refund req and records it in tx: COMPLETED with its
+// ledger entries, or REQUESTED when the merchant requires approval. Refusals are *refundError.
+func recordRefund(ctx context.Context, tx *sql.Tx, req refundRequest) (recordedRefund, error) {
 	var (
-		merchantID string
-		orderAmt   int64
-		asset      string
-		status     string
+		merchantID  string
+		amountMinor string
+		asset       string
+		chain       string
+		status      string
+		customer    sql.NullString
 	)
-	err = tx.QueryRowContext(ctx, `
-		SELECT merchant_id, amount_minor, asset, status
+	err := tx.QueryRowContext(ctx, `
+		SELECT merchant_id, amount_minor, asset, chain, status, customer_wallet_address
 		FROM orders
 		WHERE id = ?
-	`, orderID).Scan(&merchantID, &orderAmt, &asset, &status)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "order not found"})
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
+	`, req.OrderID).Scan(&merchantID, &amountMinor, &asset, &chain, &status, &customer)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && req.MerchantID != "" && req.MerchantID != merchantID) {
+		return recordedRefund{}, &refundError{http.StatusNotFound, CodeOrderNotFound, ""}
+	} else if err != nil {
+		return recordedRefund{}, err
 	}
+	// Only an order whose payment was credited to the merchant can be refunded from the merchant's
+	// bucket; a settled order's refund can take the merchant balance negative, to be clawed back from
+	// later settlements
 	switch status {
+	case "PAID", "PARTIALLY_REFUNDED", "SETTLED":
 	case "REFUNDED":
-		// _ = tx.Commit()
-		writeJSON(w, http.StatusOK, refundResp{
-			OrderID: orderID, Status: "REFUNDED", Message: "no-op (already refunded)",
-		})
-		return
-	case "SETTLED":
-		writeErrorJSON(w, http.StatusConflict, "cannot_refund_settled", "cannot refund a SETTLED order")
+		return recordedRefund{}, &refundError{http.StatusConflict, CodeAlreadyRefunded, "order is already fully refunded"}
+	case statusReview:
+		return recordedRefund{}, &refundError{http.StatusConflict, CodeOrderNotPaid, "order is held for risk review; its payment is not credited until it is approved"}
+	case statusLatePayment:
+		return recordedRefund{}, &refundError{http.StatusConflict, CodeOrderNotPaid, "late payment awaits review; it is not credited until it is accepted"}
+	case statusMispaid:
+		return recordedRefund{}, &refundError{http.StatusConflict, CodeOrderNotPaid, "order was paid in the wrong token or on the wrong chain and was not credited; resolve the mispayment instead"}
+	default:
+		return recordedRefund{}, &refundError{http.StatusConflict, CodeOrderNotPaid, "order not paid yet; cannot refund"}
+	}
+	if req.Execute {
+		if !customer.Valid || customer.String == "" {
+			return recordedRefund{}, &refundError{http.StatusConflict, CodeCustomerWalletUnknown, errCustomerWalletUnknown.Error()}
+		}
+		if _, ok := blockchain.TokenAddress(strings.ToUpper(chain), asset); !ok {
+			return recordedRefund{}, &refundError{http.StatusUnprocessableEntity, CodeUnsupportedChain, "no " + asset + " contract known on " + chain}
+		}
+	}
+
+	if open, err := hasOpenDispute(ctx, tx, req.OrderID); err != nil {
+		return recordedRefund{}, err
+	} else if open {
+		return recordedRefund{}, &refundError{http.StatusConflict, CodeDisputeOpen, "order has an open dispute; refunds are blocked until it is resolved"}
+	}
+
+	orderAmt, ok := new(big.Int).SetString(amountMinor, 10)
+	if !ok {
+		return recordedRefund{}, &refundError{http.StatusInternalServerError, CodeInvalidAmount, "invalid order amount_minor format"}
+	}
+	// Refunds still awaiting approval reserve their amount so concurrent requests can't over-refund;
+	// lost disputes have already returned their amount to the customer.
+	reserved, err := refundsTotal(ctx, tx, req.OrderID, refundStatusCompleted, refundStatusRequested)
+	if err != nil {
+		return recordedRefund{}, err
+	}
+	lost, err := lostDisputesTotal(ctx, tx, req.OrderID)
+	if err != nil {
+		return recordedRefund{}, err
+	}
+	refundable := new(big.Int).Sub(orderAmt, reserved)
+	refundable.Sub(refundable, lost)
+
+	amt := new(big.Int).Set(refundable)
+	if req.AmountMinor != nil {
+		if _, ok := amt.SetString(req.AmountMinor.String(), 10); !ok {
+			return recordedRefund{}, &refundError{http.StatusBadRequest, CodeInvalidRefundAmount, "refund amount must be an integer in minor units"}
+		}
+	}
+	if amt.Sign() <= 0 {
+		return recordedRefund{}, &refundError{http.StatusBadRequest, CodeInvalidRefundAmount, "refund amount must be > 0"}
+	}
+	if amt.Cmp(refundable) > 0 {
+		return recordedRefund{}, &refundError{http.StatusBadRequest, CodeRefundExceedsOrder, "refund amount cannot exceed the remaining refundable amount (" + refundable.String() + ")"}
+	}
+
+	var approvalRequired bool
+	if err := tx.QueryRowContext(ctx, `SELECT refund_approval_required FROM merchants WHERE id = ?`, merchantID).Scan(&approvalRequired); err != nil {
+		return recordedRefund{}, err
+	}
+	refundStatus := refundStatusCompleted
+	if approvalRequired {
+		refundStatus = refundStatusRequested
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	refundID := "rfd_" + uuid.New().String()
+	var refundTx, execution sql.NullString
+	if req.TxHash != "" {
+		refundTx = sql.NullString{String: req.TxHash, Valid: true}
+	}
+	if req.Execute {
+		execution = sql.NullString{String: refundExecQueued, Valid: true}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refunds (id, order_id, merchant_id, amount_minor, status, refund_tx_hash, idempotency_key, requested_by, execution_status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, refundID, req.OrderID, merchantID, amt.String(), refundStatus, refundTx, req.IdempotencyKey, req.RequestedBy, execution, now); err != nil {
+		if sqliteIsUniqueConstraintError(err) && refundTx.Valid {
+			return recordedRefund{}, &refundError{http.StatusConflict, CodeRefundTxAlreadyUsed, "refund transaction already recorded for another refund"}
+		}
+		return recordedRefund{}, err
+	}
+
+	if approvalRequired {
+		if err := enqueueRefundEvent(ctx, tx, webhookRefundRequested, refundID); err != nil {
+			return recordedRefund{}, err
+		}
+		return recordedRefund{refundResp: refundResp{
+			OrderID:      req.OrderID,
+			RefundID:     refundID,
+			Status:       status,
+			RefundStatus: refundStatusRequested,
+			AmountMinor:  amt.String(),
+			Message:      "refund requested; awaiting approval by a second credential",
+		}, MerchantID: merchantID, Asset: asset}, nil
+	}
+
+	resp, err := applyRefund(ctx, tx, refundID, req.OrderID, merchantID, asset, orderAmt, amt, req.TxHash, now)
+	if err != nil {
+		return recordedRefund{}, err
+	}
+	return recordedRefund{refundResp: resp, MerchantID: merchantID, Asset: asset}, nil
+}
+
+// applyRefund writes the REFUND double entry for a COMPLETED refund row and moves the order to
+// PARTIALLY_REFUNDED or REFUNDED depending on what remains.
+func applyRefund(ctx context.Context, tx *sql.Tx, refundID, orderID, merchantID, asset string, orderAmt, amt *big.Int, refundTxHash, now string) (refundResp, error) {
+	entry := func(bucket, direction string) store.LedgerEntry {
+		return store.LedgerEntry{
+			OrderID: orderID, MerchantID: merchantID, Asset: asset,
+			AmountMinor: amt.String(), Bucket: bucket, Direction: direction, EventType: refundEvent, TxHash: refundTxHash,
+			ReferenceID: refundID, CreatedAt: now,
+		}
+	}
+	if err := txStores(tx).Ledger.Append(ctx, entry(bucketMerchant, dirDebit), entry(bucketClearing, dirCredit)); err != nil {
+		return refundResp{}, err
+	}
+
+	refunded, err := refundsTotal(ctx, tx, orderID, refundStatusCompleted)
+	if err != nil {
+		return refundResp{}, err
+	}
+	lost, err := lostDisputesTotal(ctx, tx, orderID)
+	if err != nil {
+		return refundResp{}, err
+	}
+	remaining := new(big.Int).Sub(orderAmt, refunded)
+	remaining.Sub(remaining, lost)
+	newStatus := "PARTIALLY_REFUNDED"
+	if remaining.Sign() == 0 {
+		newStatus = "REFUNDED"
+	}
+	invalidateOrder(orderID)
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = ? WHERE id = ?`, newStatus, orderID); err != nil {
+		return refundResp{}, err
+	}
+	if err := enqueueRefundEvent(ctx, tx, webhookRefundCompleted, refundID); err != nil {
+		return refundResp{}, err
+	}
+	return refundResp{
+		OrderID:            orderID,
+		RefundID:           refundID,
+		Status:             newStatus,
+		RefundStatus:       refundStatusCompleted,
+		AmountMinor:        amt.String(),
+		RefundedTotalMinor: refunded.String(),
+		RefundableMinor:    remaining.String(),
+		Message:            "refund recorded with double-entry ledger",
+	}, nil
+}
+
+// ApproveRefundHandler godoc
+// @Summary      Approve a requested refund
+// @Description  Executes a REQUESTED refund. Must be called with the admin key or a merchant credential holding refunds:approve of a different origin than the one that requested it: the merchant's primary key and the scoped API keys it created count as one, while each organization member's key and each OAuth grant is its own.
+// @Tags         orders
+// @Produce      json
+// @Param        id  query  string  true  "Refund ID"
+// @Success      200  {object}  refundResp
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /refunds/approve [post]
+// @Router       /admin/refunds/approve [post]
+func ApproveRefundHandler(w http.ResponseWriter, r *http.Request) {
+	decideRefund(w, r, true)
+}
+
+// RejectRefundHandler godoc
+// @Summary      Reject a requested refund
+// @Description  Rejects a REQUESTED refund, releasing its reserved amount. Same credential rules as approval.
+// @Tags         orders
+// @Produce      json
+// @Param        id  query  string  true  "Refund ID"
+// @Success      200  {object}  refundResp
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /refunds/reject [post]
+// @Router       /admin/refunds/reject [post]
+func RejectRefundHandler(w http.ResponseWriter, r *http.Request) {
+	decideRefund(w, r, false)
+}
+
+func decideRefund(w http.ResponseWriter, r *http.Request, approve bool) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
 		return
-	case "PENDING", "CONFIRMING":
-		writeErrorJSON(w, http.StatusConflict, "order_not_paid", "order not paid yet; cannot refund")
+	}
+	refundID := pathID(r)
+	if refundID == "" {
+		badReq(w, "missing query param: id")
 		return
-		// case "PAID": allowed
 	}
-	amt := orderAmt
-	if req.AmountMinor != nil && *req.AmountMinor > 0 {
-		amt = *req.AmountMinor
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
 	}
-	if amt <= 0 {
-		writeErrorJSON(w, http.StatusBadRequest, "invalid_refund_amount", "refund amount must be > 0")
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		orderID, merchantID, amountMinor, refundStatus, requestedBy string
+		refundTx                                                    sql.NullString
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT order_id, merchant_id, amount_minor, status, refund_tx_hash, COALESCE(requested_by, '') FROM refunds WHERE id = ?
+	`, refundID).Scan(&orderID, &merchantID, &amountMinor, &refundStatus, &refundTx, &requestedBy)
+	if err != nil || (!isAdmin(r.Context()) && !authorizedFor(r.Context(), merchantID)) {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			serverErr(w, err)
+			return
+		}
+		writeProblem(w, http.StatusNotFound, CodeRefundNotFound, "refund not found")
 		return
 	}
-	if amt > orderAmt {
-		writeErrorJSON(w, http.StatusBadRequest, "refund_exceeds_order", "refund amount cannot exceed order amount")
+	decidedBy := "admin"
+	if !isAdmin(r.Context()) {
+		decidedBy = credentialFromContext(r.Context())
+		deciderOrigin, err := credentialOrigin(ctx, tx, decidedBy)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		requesterOrigin, err := credentialOrigin(ctx, tx, requestedBy)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		if deciderOrigin == requesterOrigin {
+			writeProblem(w, http.StatusForbidden, CodeApproverMustDiffer, "a refund must be decided by a credential of a different origin than the one that requested it; scoped API keys count as the primary key that created them")
+			return
+		}
+	}
+	if refundStatus != refundStatusRequested {
+		writeProblem(w, http.StatusConflict, CodeRefundNotPending, "refund is "+refundStatus+", not "+refundStatusRequested)
 		return
 	}
-
 	now := time.Now().UTC().Format(time.RFC3339)
 
-	const insLedger = `
-		INSERT INTO ledger_entries
-		  (id, order_id, merchant_id, asset, amount_minor, bucket, direction, event_type, tx_hash, created_at)
-		VALUES
-		  (?,  ?,        ?,           ?,     ?,            ?,      ?,         ?,          ?,      ?)
-	`
-	lidA := "led_" + now + "_refund_a_" + orderID
-	lidB := "led_" + now + "_refund_b_" + orderID
+	if !approve {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE refunds SET status = ?, decided_by = ?, decided_at = ?, execution_status = NULL WHERE id = ?
+		`, refundStatusRejected, decidedBy, now, refundID); err != nil {
+			serverErr(w, err)
+			return
+		}
+		if err := enqueueRefundEvent(ctx, tx, webhookRefundRejected, refundID); err != nil {
+			serverErr(w, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			serverErr(w, err)
+			return
+		}
+		log.Printf("event=refund_rejected order_id=%s refund_id=%s decided_by=%s", orderID, refundID, decidedBy)
+		var orderStatus string
+		_ = db.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ?`, orderID).Scan(&orderStatus)
+		writeJSON(w, http.StatusOK, refundResp{OrderID: orderID, RefundID: refundID, Status: orderStatus, RefundStatus: refundStatusRejected, AmountMinor: amountMinor, Message: "refund rejected"})
+		return
+	}
 
-	if _, err := tx.ExecContext(ctx, insLedger,
-		lidA, orderID, merchantID, asset, amt, bucketMerchant, dirDebit, refundEvent, req.RefundTxHash, now,
-	); err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+	var orderAmount, asset, orderStatus string
+	if err := tx.QueryRowContext(ctx, `SELECT amount_minor, asset, status FROM orders WHERE id = ?`, orderID).Scan(&orderAmount, &asset, &orderStatus); err != nil {
+		serverErr(w, err)
 		return
 	}
-	if _, err := tx.ExecContext(ctx, insLedger,
-		lidB, orderID, merchantID, asset, amt, bucketClearing, dirCredit, refundEvent, req.RefundTxHash, now,
-	); err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+	if orderStatus != "PAID" && orderStatus != "PARTIALLY_REFUNDED" && orderStatus != "SETTLED" {
+		writeProblem(w, http.StatusConflict, CodeOrderNotRefundable, "order is "+orderStatus+" and can no longer be refunded")
 		return
 	}
-	if _, err := tx.ExecContext(ctx, `
-		   UPDATE orders
-		   SET status = ?, refund_idempotency_key = ?
-		   WHERE id = ?
-	   `, "REFUNDED", req.RefundIdempotencyKey, orderID); err != nil {
-		writeErrorJSON(w, http.StatusInternalServerError, "db_error", err.Error())
+	if open, err := hasOpenDispute(ctx, tx, orderID); err != nil {
+		serverErr(w, err)
+		return
+	} else if open {
+		writeProblem(w, http.StatusConflict, CodeDisputeOpen, "order has an open dispute; refunds are blocked until it is resolved")
+		return
+	}
+	orderAmt, ok1 := new(big.Int).SetString(orderAmount, 10)
+	amt, ok2 := new(big.Int).SetString(amountMinor, 10)
+	if !ok1 || !ok2 {
+		writeProblem(w, http.StatusInternalServerError, CodeInvalidAmount, "invalid amount_minor format")
+		return
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE refunds SET status = ?, decided_by = ?, decided_at = ? WHERE id = ?`, refundStatusCompleted, decidedBy, now, refundID); err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp, err := applyRefund(ctx, tx, refundID, orderID, merchantID, asset, orderAmt, amt, refundTx.String, now)
+	if err != nil {
+		serverErr(w, err)
 		return
 	}
-
-	// 4) Commit atomically
 	if err := tx.Commit(); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		serverErr(w, err)
 		return
 	}
+	log.Printf("event=refund_processed order_id=%s refund_id=%s merchant_id=%s asset=%s amount_minor=%s status=%s decided_by=%s", orderID, refundID, merchantID, asset, amountMinor, resp.Status, decidedBy)
+	refundsProcessedTotal.inc()
+	resp.Message = "refund approved and recorded with double-entry ledger"
+	writeJSON(w, http.StatusOK, resp)
+}
 
-	log.Printf("event=refund_processed order_id=%s merchant_id=%s asset=%s amount_minor=%d status=REFUNDED", orderID, merchantID, asset, amt)
-	refundsProcessedTotal++
-	writeJSON(w, http.StatusOK, refundResp{
-		OrderID: orderID,
-		Status:  "REFUNDED",
-		Message: "refund recorded with double-entry ledger",
-	})
+// ListRefundsHandler godoc
+// @Summary      List refunds for an order
+// @Description  Returns every refund recorded against an order, oldest first
+// @Tags         orders
+// @Produce      json
+// @Param        id  query  string  true  "Order ID"
+// @Success      200  {array}   refundRecord
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/refunds [get]
+func ListRefundsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	orderID := pathID(r)
+	if orderID == "" {
+		badReq(w, "missing query param: id")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpSearch)
+	defer cancel()
+	var merchantID string
+	if err := db.QueryRowContext(ctx, `SELECT merchant_id FROM orders WHERE id = ?`, orderID).Scan(&merchantID); err != nil || !authorizedFor(r.Context(), merchantID) {
+		writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "order not found")
+		return
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, order_id, amount_minor, status, refund_tx_hash, requested_by, decided_by, decided_at, execution_status, execution_error, created_at
+		FROM refunds
+		WHERE order_id = ?
+		ORDER BY created_at, id
+	`, orderID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	refunds := []refundRecord{}
+	for rows.Next() {
+		var (
+			rec                                       refundRecord
+			txHash, requestedBy, decidedBy, decidedAt sql.NullString
+			execution, execErr                        sql.NullString
+		)
+		if err := rows.Scan(&rec.ID, &rec.OrderID, &rec.AmountMinor, &rec.Status, &txHash, &requestedBy, &decidedBy, &decidedAt, &execution, &execErr, &rec.CreatedAt); err != nil {
+			serverErr(w, err)
+			return
+		}
+		rec.RefundTxHash = nullStringPtr(txHash)
+		rec.RequestedBy = nullStringPtr(requestedBy)
+		rec.DecidedBy = nullStringPtr(decidedBy)
+		rec.DecidedAt = nullStringPtr(decidedAt)
+		rec.ExecutionStatus, rec.ExecutionError = nullStringPtr(execution), nullStringPtr(execErr)
+		refunds = append(refunds, rec)
+	}
+	writeJSON(w, http.StatusOK, refunds)
+}
 
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
 }
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// eventBalanceCarried marks the entry that replaces archived ledger rows in the hot table: one per
+// merchant, asset and bucket, carrying their net so balances and reconciliation are unchanged.
+const eventBalanceCarried = "BALANCE_CARRIED"
+
+// retentionBatch bounds the orders moved per transaction so the writer lock is held briefly.
+const retentionBatch = 500
+
+// retentionPolicy controls how long rows stay in the hot tables; zero keeps them forever.
+type retentionPolicy struct {
+	OrderMonths   int  // terminal orders, their refunds and ledger rows older than this move to *_archive
+	OutboxDays    int  // delivered and skipped outbox events older than this are deleted
+	OutboxArchive bool // move those events to outbox_events_archive instead of deleting them
+}
+
+var retention retentionPolicy
+
+// SetRetentionPolicy configures the retention job; see StartRetentionScheduler.
+func SetRetentionPolicy(orderMonths, outboxDays int, archiveOutbox bool) {
+	retention = retentionPolicy{OrderMonths: orderMonths, OutboxDays: outboxDays, OutboxArchive: archiveOutbox}
+}
+
+type retentionResult struct {
+	OrdersArchived  int `json:"orders_archived"`
+	RefundsArchived int `json:"refunds_archived"`
+	LedgerArchived  int `json:"ledger_entries_archived"`
+	OutboxPruned    int `json:"outbox_events_pruned"`   // removed from outbox_events
+	OutboxArchived  int `json:"outbox_events_archived"` // of those, copied to outbox_events_archive
+}
+
+// StartRetentionScheduler runs the retention policy every interval. It does nothing unless
+// SetRetentionPolicy enabled at least one part of it.
+func StartRetentionScheduler(db *sql.DB, interval time.Duration) {
+	if retention.OrderMonths <= 0 && retention.OutboxDays <= 0 {
+		return
+	}
+	startScheduler(schedulerRetention, interval, false, func(ctx context.Context) (int, error) {
+		res, err := runRetention(ctx, db, retention)
+		if res.OrdersArchived > 0 || res.LedgerArchived > 0 || res.OutboxPruned > 0 {
+			log.Printf("event=retention orders_archived=%d refunds_archived=%d ledger_archived=%d outbox_pruned=%d outbox_archived=%d",
+				res.OrdersArchived, res.RefundsArchived, res.LedgerArchived, res.OutboxPruned, res.OutboxArchived)
+		}
+		return res.OrdersArchived + res.RefundsArchived + res.LedgerArchived + res.OutboxPruned, err
+	})
+}
+
+// runRetention archives terminal orders (SETTLED, REFUNDED, FAILED, EXPIRED) created before the cutoff,
+// together with their refunds and ledger rows, then old ledger rows not tied to an order, and
+// deletes or archives delivered and skipped outbox events. Orders with disputes or refunds still awaiting
+// approval or the hot wallet stay in place.
+func runRetention(ctx context.Context, db *sql.DB, p retentionPolicy) (retentionResult, error) {
+	var res retentionResult
+	now := time.Now().UTC()
+	if p.OrderMonths > 0 {
+		cutoff := now.AddDate(0, -p.OrderMonths, 0).Format(time.RFC3339)
+		for {
+			orders, refunds, ledger, err := archiveOrderBatch(ctx, db, cutoff)
+			if err != nil {
+				return res, err
+			}
+			res.OrdersArchived += orders
+			res.RefundsArchived += refunds
+			res.LedgerArchived += ledger
+			if orders < retentionBatch {
+				break
+			}
+		}
+		n, err := archiveOrphanLedger(ctx, db, cutoff)
+		if err != nil {
+			return res, err
+		}
+		res.LedgerArchived += n
+	}
+	if p.OutboxDays > 0 {
+		cutoff := now.AddDate(0, 0, -p.OutboxDays).Format(time.RFC3339)
+		for {
+			n, err := pruneOutboxBatch(ctx, db, cutoff, p.OutboxArchive)
+			if err != nil {
+				return res, err
+			}
+			res.OutboxPruned += n
+			if p.OutboxArchive {
+				res.OutboxArchived += n
+			}
+			if n < retentionBatch {
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// pruneOutboxBatch removes up to retentionBatch delivered or skipped events delivered before the
+// cutoff, copying them to outbox_events_archive first when archive is set. Pending and
+// dead-lettered events stay, so they can still be delivered or requeued.
+func pruneOutboxBatch(ctx context.Context, db *sql.DB, cutoff string, archive bool) (int, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	where := `id IN (SELECT id FROM outbox_events WHERE status IN ('DELIVERED', 'SKIPPED', 'DISCARDED') AND delivered_at < ? ORDER BY delivered_at, rowid LIMIT ?)`
+	args := []any{cutoff, retentionBatch}
+	var n int
+	if archive {
+		if n, err = archiveRows(ctx, tx, "outbox_events", where, args); err != nil {
+			return 0, err
+		}
+	} else {
+		res, err := tx.ExecContext(ctx, `DELETE FROM outbox_events WHERE `+where, args...)
+		if err != nil {
+			return 0, err
+		}
+		affected, _ := res.RowsAffected()
+		n = int(affected)
+	}
+	return n, tx.Commit()
+}
+
+func archiveOrderBatch(ctx context.Context, db *sql.DB, cutoff string) (orders, refunds, ledger int, err error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM orders o
+		WHERE status IN ('SETTLED','REFUNDED','FAILED','EXPIRED') AND created_at < ?
+		  AND NOT EXISTS (SELECT 1 FROM disputes d WHERE d.order_id = o.id)
+		  AND NOT EXISTS (SELECT 1 FROM refunds f WHERE f.order_id = o.id AND (f.status = ? OR f.execution_status IN (?, ?)))
+		  AND NOT EXISTS (SELECT 1 FROM overpayments p WHERE p.order_id = o.id AND p.status <> ?)
+		ORDER BY created_at
+		LIMIT ?
+	`, cutoff, refundStatusRequested, refundExecQueued, refundExecSent, overpaymentRefunded, retentionBatch)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var ids []any
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, 0, 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, 0, err
+	}
+	if len(ids) == 0 {
+		return 0, 0, 0, nil
+	}
+	in := `(?` + strings.Repeat(`, ?`, len(ids)-1) + `)`
+	if ledger, err = archiveLedger(ctx, tx, `order_id IN `+in, ids); err != nil {
+		return 0, 0, 0, err
+	}
+	// refunds reference orders, so they go first
+	if refunds, err = archiveRows(ctx, tx, "refunds", `order_id IN `+in, ids); err != nil {
+		return 0, 0, 0, err
+	}
+	if orders, err = archiveRows(ctx, tx, "orders", `id IN `+in, ids); err != nil {
+		return 0, 0, 0, err
+	}
+	return orders, refunds, ledger, tx.Commit()
+}
+
+// archiveOrphanLedger archives old ledger rows without an order, such as earlier carried balances.
+func archiveOrphanLedger(ctx context.Context, db *sql.DB, cutoff string) (int, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	n, err := archiveLedger(ctx, tx, `order_id IS NULL AND created_at < ?`, []any{cutoff})
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// archiveLedger moves the matching ledger rows to ledger_entries_archive and books their net per
+// merchant, asset, chain and bucket as a BALANCE_CARRIED entry.
+func archiveLedger(ctx context.Context, tx *sql.Tx, where string, args []any) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT merchant_id, asset, COALESCE(chain, ''), bucket, direction, amount_minor FROM ledger_entries WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	type balanceKey struct{ merchantID, asset, chain, bucket string }
+	net := map[balanceKey]*big.Int{}
+	var keys []balanceKey
+	for rows.Next() {
+		var (
+			k                 balanceKey
+			direction, amount string
+		)
+		if err := rows.Scan(&k.merchantID, &k.asset, &k.chain, &k.bucket, &direction, &amount); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		v, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			rows.Close()
+			return 0, errors.New("invalid amount_minor format")
+		}
+		if direction == "debit" {
+			v.Neg(v)
+		}
+		if net[k] == nil {
+			net[k] = new(big.Int)
+			keys = append(keys, k)
+		}
+		net[k].Add(net[k], v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := archiveRows(ctx, tx, "ledger_entries", where, args)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	runID := "ret_" + uuid.New().String()
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, k := range keys {
+		v := net[k]
+		if v.Sign() == 0 {
+			continue
+		}
+		direction := "credit"
+		if v.Sign() < 0 {
+			direction = "debit"
+		}
+		if err := txStores(tx).Ledger.Append(ctx, store.LedgerEntry{
+			MerchantID: k.merchantID, Asset: k.asset, Chain: k.chain, AmountMinor: new(big.Int).Abs(v).String(),
+			Bucket: k.bucket, Direction: direction, EventType: eventBalanceCarried, ReferenceID: runID, CreatedAt: now, Carried: true,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// archiveRows copies the matching rows of table into <table>_archive and deletes them.
+func archiveRows(ctx context.Context, tx *sql.Tx, table, where string, args []any) (int, error) {
+	cols, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return 0, err
+	}
+	list := strings.Join(cols, ", ")
+	if _, err := tx.ExecContext(ctx, `INSERT INTO `+table+`_archive (`+list+`, archived_at) SELECT `+list+`, ? FROM `+table+` WHERE `+where,
+		append([]any{time.Now().UTC().Format(time.RFC3339)}, args...)...); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func tableColumns(ctx context.Context, q queryer, table string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols = append(cols, name)
+	}
+	return cols, rows.Err()
+}
+
+// RunRetentionHandler godoc
+// @Summary      Run the retention job now
+// @Description  Archives terminal orders, refunds and ledger rows past RETENTION_MONTHS and prunes delivered outbox events past OUTBOX_RETENTION_DAYS, into outbox_events_archive with OUTBOX_ARCHIVE=on. Archived ledger rows are replaced by BALANCE_CARRIED entries, so balances do not change. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  retentionResult
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/retention/run [post]
+func RunRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if retention.OrderMonths <= 0 && retention.OutboxDays <= 0 {
+		writeProblem(w, http.StatusConflict, CodeRetentionDisabled, "set RETENTION_MONTHS or OUTBOX_RETENTION_DAYS to enable retention")
+		return
+	}
+	res, err := runRetention(r.Context(), db, retention)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), "", "", "retention_run", res)
+	writeJSON(w, http.StatusOK, res)
+}
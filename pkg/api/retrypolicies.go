@@ -0,0 +1,346 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/jobs"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Kinds of background work with a retry policy, as listed by /admin/retry-policies.
+const (
+	retryVerification = "verification" // payment_verification jobs
+	retryWebhook      = "webhook"      // webhook deliveries
+	retryPayout       = "payout"       // sending or proposing queued payouts
+)
+
+// RetryPolicyTypes lists the kinds of work with a retry policy, for configuring them with
+// SetRetryPolicy.
+func RetryPolicyTypes() []string { return []string{retryVerification, retryWebhook, retryPayout} }
+
+// What becomes of work that runs out of attempts: kept for an operator to retry (DEAD jobs,
+// DEAD_LETTER events, held payouts) or given up (jobs deleted, events DISCARDED, payouts FAILED).
+const (
+	deadLetterKeep    = "keep"
+	deadLetterDiscard = "discard"
+)
+
+var defaultRetryPolicies = map[string]jobs.RetryPolicy{
+	retryVerification: {MaxAttempts: 5, BaseDelay: 5 * time.Second, MaxDelay: 10 * time.Minute, Jitter: 0.2},
+	// ten attempts spread over roughly a day
+	retryWebhook: {MaxAttempts: 10, BaseDelay: 30 * time.Second, MaxDelay: 6 * time.Hour},
+	retryPayout:  {MaxAttempts: 20, BaseDelay: time.Minute, MaxDelay: 30 * time.Minute, Jitter: 0.2},
+}
+
+// retryPolicyRefresh is how often the policies set through the admin API are reread, so that
+// every instance applies them.
+const retryPolicyRefresh = 30 * time.Second
+
+var (
+	retryPoliciesMu         sync.Mutex
+	configuredRetryPolicies = map[string]jobs.RetryPolicy{} // SetRetryPolicy
+	adminRetryPolicies      = map[string]jobs.RetryPolicy{} // the retry_policies table
+	adminRetryPoliciesAt    time.Time
+)
+
+// SetRetryPolicy replaces the default retry policy of typ (see RetryPolicyTypes) with spec, a
+// comma-separated list of settings such as "max_attempts=8,base_delay=1m,max_delay=2h,jitter=0.1,
+// dead_letter=discard". Settings left out keep their defaults. Policies set through the admin API
+// take precedence.
+func SetRetryPolicy(typ, spec string) error {
+	base, ok := defaultRetryPolicies[typ]
+	if !ok {
+		return fmt.Errorf("unknown retry policy type %q", typ)
+	}
+	p, err := parseRetryPolicy(base, spec)
+	if err != nil {
+		return err
+	}
+	retryPoliciesMu.Lock()
+	configuredRetryPolicies[typ] = p
+	retryPoliciesMu.Unlock()
+	return nil
+}
+
+func parseRetryPolicy(p jobs.RetryPolicy, spec string) (jobs.RetryPolicy, error) {
+	for _, setting := range strings.Split(spec, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		name, v, _ := strings.Cut(setting, "=")
+		var err error
+		switch strings.TrimSpace(name) {
+		case "max_attempts":
+			p.MaxAttempts, err = strconv.Atoi(v)
+		case "base_delay":
+			p.BaseDelay, err = time.ParseDuration(v)
+		case "max_delay":
+			p.MaxDelay, err = time.ParseDuration(v)
+		case "jitter":
+			p.Jitter, err = strconv.ParseFloat(v, 64)
+		case "dead_letter":
+			switch v {
+			case deadLetterKeep, deadLetterDiscard:
+				p.Discard = v == deadLetterDiscard
+			default:
+				err = errors.New("must be keep or discard")
+			}
+		default:
+			return p, fmt.Errorf("unknown setting %q", name)
+		}
+		if err != nil {
+			return p, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return p, checkRetryPolicy(p)
+}
+
+func checkRetryPolicy(p jobs.RetryPolicy) error {
+	switch {
+	case p.MaxAttempts < 1 || p.MaxAttempts > 100:
+		return errors.New("max_attempts must be between 1 and 100")
+	case p.BaseDelay < time.Second:
+		return errors.New("base_delay must be at least 1s")
+	case p.MaxDelay < p.BaseDelay:
+		return errors.New("max_delay must be at least base_delay")
+	case p.MaxDelay > 7*24*time.Hour:
+		return errors.New("max_delay must be at most 168h")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return errors.New("jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// retryPolicyOf returns the policy in effect for typ and where it comes from: the admin API,
+// configuration or the default.
+func retryPolicyOf(typ string) (jobs.RetryPolicy, string) {
+	retryPoliciesMu.Lock()
+	stale := time.Since(adminRetryPoliciesAt) > retryPolicyRefresh
+	retryPoliciesMu.Unlock()
+	if stale {
+		if err := loadRetryPolicies(context.Background()); err != nil {
+			log.Printf("event=retry_policy_load_failed err=%v", err)
+		}
+	}
+	retryPoliciesMu.Lock()
+	defer retryPoliciesMu.Unlock()
+	if p, ok := adminRetryPolicies[typ]; ok {
+		return p, "admin"
+	}
+	if p, ok := configuredRetryPolicies[typ]; ok {
+		return p, "config"
+	}
+	return defaultRetryPolicies[typ], "default"
+}
+
+// retryPolicy is retryPolicyOf without the source.
+func retryPolicy(typ string) jobs.RetryPolicy {
+	p, _ := retryPolicyOf(typ)
+	return p
+}
+
+// loadRetryPolicies rereads the policies set through the admin API. On failure the ones read last
+// stay in effect until the next try.
+func loadRetryPolicies(ctx context.Context) error {
+	retryPoliciesMu.Lock()
+	adminRetryPoliciesAt = time.Now()
+	retryPoliciesMu.Unlock()
+	if db == nil {
+		return nil
+	}
+	ctx, cancel := store.WithTimeout(ctx, store.OpRead)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT job_type, max_attempts, base_delay_ms, max_delay_ms, jitter, dead_letter FROM retry_policies`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	policies := map[string]jobs.RetryPolicy{}
+	for rows.Next() {
+		var typ, deadLetter string
+		var baseMs, maxMs int64
+		var p jobs.RetryPolicy
+		if err := rows.Scan(&typ, &p.MaxAttempts, &baseMs, &maxMs, &p.Jitter, &deadLetter); err != nil {
+			return err
+		}
+		p.BaseDelay, p.MaxDelay = time.Duration(baseMs)*time.Millisecond, time.Duration(maxMs)*time.Millisecond
+		p.Discard = deadLetter == deadLetterDiscard
+		policies[typ] = p
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	retryPoliciesMu.Lock()
+	adminRetryPolicies = policies
+	retryPoliciesMu.Unlock()
+	return nil
+}
+
+type retryPolicyResp struct {
+	Type        string  `json:"type"`         // verification, webhook or payout
+	MaxAttempts int     `json:"max_attempts"` // including the first
+	BaseDelay   string  `json:"base_delay"`   // before the first retry, doubling after each, e.g. "30s"
+	MaxDelay    string  `json:"max_delay"`
+	Jitter      float64 `json:"jitter"`      // up to this fraction of the delay is added at random
+	DeadLetter  string  `json:"dead_letter"` // keep or discard
+	Source      string  `json:"source"`      // default, config or admin
+}
+
+func retryPolicyRespOf(typ string) retryPolicyResp {
+	p, source := retryPolicyOf(typ)
+	deadLetter := deadLetterKeep
+	if p.Discard {
+		deadLetter = deadLetterDiscard
+	}
+	return retryPolicyResp{
+		Type: typ, MaxAttempts: p.MaxAttempts, BaseDelay: p.BaseDelay.String(), MaxDelay: p.MaxDelay.String(),
+		Jitter: p.Jitter, DeadLetter: deadLetter, Source: source,
+	}
+}
+
+// RetryPoliciesHandler godoc
+// @Summary      List retry policies
+// @Description  Returns the retry policy of each kind of background work: verification (payment_verification jobs), webhook (webhook deliveries) and payout (sending or proposing queued payouts). A failed attempt is retried after base_delay, doubling up to max_delay, with up to jitter of the delay added at random, until max_attempts. Work that runs out of attempts is kept with dead_letter keep (DEAD jobs, DEAD_LETTER events, payouts held until retried) or given up with discard (jobs deleted, events DISCARDED, payouts FAILED). source says whether the policy is the default, from RETRY_POLICY_<TYPE> or set through the admin API. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   retryPolicyResp
+// @Router       /admin/retry-policies [get]
+func RetryPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	out := []retryPolicyResp{}
+	for _, typ := range RetryPolicyTypes() {
+		out = append(out, retryPolicyRespOf(typ))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+type retryPolicyUpdateReq struct {
+	MaxAttempts *int     `json:"max_attempts,omitempty" validate:"min=1,max=100"`
+	BaseDelay   *string  `json:"base_delay,omitempty"` // Go duration, e.g. "30s"
+	MaxDelay    *string  `json:"max_delay,omitempty"`
+	Jitter      *float64 `json:"jitter,omitempty"`
+	DeadLetter  *string  `json:"dead_letter,omitempty" validate:"oneof=keep discard"`
+}
+
+// UpdateRetryPolicyHandler godoc
+// @Summary      Change a retry policy
+// @Description  Sets the retry policy of verification, webhook or payout work. Fields left out keep their current value. The policy is stored in the database, takes precedence over RETRY_POLICY_<TYPE>, applies from the next failed attempt on and reaches every instance within 30 seconds. Admin only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      query  string                true  "Type: verification, webhook or payout"
+// @Param        policy  body   retryPolicyUpdateReq  true  "Settings to change"
+// @Success      200  {object}  retryPolicyResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/retry-policies/update [post]
+func UpdateRetryPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	typ := pathID(r)
+	if _, ok := defaultRetryPolicies[typ]; !ok {
+		writeProblem(w, http.StatusNotFound, CodeRetryPolicyNotFound, "")
+		return
+	}
+	var req retryPolicyUpdateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	p := retryPolicy(typ)
+	if req.MaxAttempts != nil {
+		p.MaxAttempts = *req.MaxAttempts
+	}
+	for _, d := range []struct {
+		name  string
+		value *string
+		to    *time.Duration
+	}{{"base_delay", req.BaseDelay, &p.BaseDelay}, {"max_delay", req.MaxDelay, &p.MaxDelay}} {
+		if d.value == nil {
+			continue
+		}
+		v, err := time.ParseDuration(*d.value)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidRetryPolicy, d.name+" must be a duration such as 30s or 5m")
+			return
+		}
+		*d.to = v
+	}
+	if req.Jitter != nil {
+		p.Jitter = *req.Jitter
+	}
+	if req.DeadLetter != nil {
+		p.Discard = strings.EqualFold(*req.DeadLetter, deadLetterDiscard)
+	}
+	if err := checkRetryPolicy(p); err != nil {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidRetryPolicy, err.Error())
+		return
+	}
+	deadLetter := deadLetterKeep
+	if p.Discard {
+		deadLetter = deadLetterDiscard
+	}
+	if _, err := db.ExecContext(r.Context(), `
+		INSERT INTO retry_policies (job_type, max_attempts, base_delay_ms, max_delay_ms, jitter, dead_letter, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (job_type) DO UPDATE SET max_attempts = excluded.max_attempts, base_delay_ms = excluded.base_delay_ms,
+			max_delay_ms = excluded.max_delay_ms, jitter = excluded.jitter, dead_letter = excluded.dead_letter, updated_at = excluded.updated_at
+	`, typ, p.MaxAttempts, p.BaseDelay.Milliseconds(), p.MaxDelay.Milliseconds(), p.Jitter, deadLetter, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := loadRetryPolicies(r.Context()); err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := retryPolicyRespOf(typ)
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), "", "", "retry_policy_updated", resp)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ResetRetryPolicyHandler godoc
+// @Summary      Reset a retry policy
+// @Description  Drops the policy set through the admin API for verification, webhook or payout work, going back to RETRY_POLICY_<TYPE> or the default. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Type: verification, webhook or payout"
+// @Success      200  {object}  retryPolicyResp
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/retry-policies/reset [post]
+func ResetRetryPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	typ := pathID(r)
+	if _, ok := defaultRetryPolicies[typ]; !ok {
+		writeProblem(w, http.StatusNotFound, CodeRetryPolicyNotFound, "")
+		return
+	}
+	if _, err := db.ExecContext(r.Context(), `DELETE FROM retry_policies WHERE job_type = ?`, typ); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := loadRetryPolicies(r.Context()); err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := retryPolicyRespOf(typ)
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), "", "", "retry_policy_reset", resp)
+	writeJSON(w, http.StatusOK, resp)
+}
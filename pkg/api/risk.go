@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/risk"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// riskScreener checks payer addresses before a payment is credited; nil disables screening.
+var riskScreener *risk.Screener
+
+// SetRiskScreener is called from main.go with the configured providers.
+func SetRiskScreener(s *risk.Screener) { riskScreener = s }
+
+// riskScorer scores every payment; nil disables scoring.
+var riskScorer = risk.DefaultScorer()
+
+// SetRiskScorer is called from main.go with the configured weights and thresholds.
+func SetRiskScorer(s *risk.Scorer) { riskScorer = s }
+
+const statusReview = "REVIEW"
+
+// minAmountHistory is how many earlier payments a merchant needs before amount percentiles count.
+const minAmountHistory = 20
+
+// riskAssessment is what gets written to the order when a payment is confirmed.
+type riskAssessment struct {
+	Status  string         // PAID or REVIEW
+	Reason  sql.NullString // why the payment was flagged or held
+	Score   sql.NullInt64
+	Factors sql.NullString // comma-separated scoring rules that fired
+}
+
+// payerScreening is the outcome of screening the sender of a payment.
+type payerScreening struct {
+	Reason sql.NullString // why the sender was flagged, or could not be screened
+	Hold   bool           // the payment waits in REVIEW
+}
+
+// screenPayer screens the verified sender of a payment with the configured providers. It calls out
+// over the network, so callers run it before opening the transaction that credits the payment.
+func screenPayer(ctx context.Context, orderID, chain, payer string) payerScreening {
+	var s payerScreening
+	if riskScreener == nil || payer == "" {
+		return s
+	}
+	res, err := riskScreener.Screen(ctx, chain, payer)
+	if err != nil {
+		// Unscreened payments are held rather than credited
+		log.Printf("event=payment_risk_screen_failed order_id=%s address=%s err=%v", orderID, payer, err)
+		res = risk.Result{Flagged: true, Reason: "screening failed: " + err.Error()}
+	} else if res.Flagged {
+		log.Printf("event=payment_risk_flagged order_id=%s address=%s source=%s reason=%q", orderID, payer, res.Source, res.Reason)
+	}
+	if res.Flagged {
+		s.Reason = sql.NullString{String: res.Reason, Valid: true}
+		if res.Source != "" {
+			s.Reason.String = res.Source + ": " + res.Reason
+		}
+		s.Hold = riskScreener.Hold
+	}
+	return s
+}
+
+// assessPayment takes the screening of a payment's sender, scores the payment and checks the
+// merchant's velocity limits. A screening hit (when the screener holds), a score over the review
+// threshold or an exceeded limit routes the order to REVIEW.
+func assessPayment(ctx context.Context, tx *sql.Tx, screening payerScreening, orderID, merchantID, asset, chain, amountMinor, payer string) riskAssessment {
+	a := riskAssessment{Status: "PAID", Reason: screening.Reason}
+	if screening.Hold {
+		a.Status = statusReview
+	}
+
+	if err := checkPaymentLimits(ctx, tx, orderID, merchantID, asset, amountMinor, payer); err != nil {
+		var le *limitError
+		if !errors.As(err, &le) {
+			log.Printf("event=payment_limit_check_failed order_id=%s err=%v", orderID, err)
+		} else {
+			log.Printf("event=payment_limit_exceeded order_id=%s limit=%s", orderID, le.Limit)
+			recordAudit(ctx, tx, "system", merchantID, orderID, "limit_exceeded", map[string]string{
+				"stage": "payment_confirm", "limit": le.Limit, "message": le.Msg, "amount_minor": amountMinor, "asset": asset,
+			})
+			a.Status = statusReview
+			if !a.Reason.Valid {
+				a.Reason = sql.NullString{String: "limit_exceeded: " + le.Error(), Valid: true}
+			}
+		}
+	}
+
+	if riskScorer == nil {
+		return a
+	}
+	sig, err := paymentSignals(ctx, tx, orderID, merchantID, asset, chain, amountMinor, payer)
+	if err != nil {
+		log.Printf("event=payment_risk_score_failed order_id=%s err=%v", orderID, err)
+		return a
+	}
+	score, factors := riskScorer.Score(sig)
+	a.Score = sql.NullInt64{Int64: int64(score), Valid: true}
+	if len(factors) > 0 {
+		a.Factors = sql.NullString{String: strings.Join(factors, ","), Valid: true}
+	}
+	if riskScorer.NeedsReview(score) {
+		log.Printf("event=payment_risk_score_high order_id=%s score=%d factors=%s", orderID, score, a.Factors.String)
+		a.Status = statusReview
+		if !a.Reason.Valid {
+			a.Reason = sql.NullString{String: fmt.Sprintf("risk score %d (%s)", score, a.Factors.String), Valid: true}
+		}
+	}
+	return a
+}
+
+// paymentSignals gathers the scoring inputs for a payment from the merchant's order history.
+func paymentSignals(ctx context.Context, q queryer, orderID, merchantID, asset, chain, amountMinor, payer string) (risk.Signals, error) {
+	sig := risk.Signals{AmountPercentile: -1, Chain: chain}
+	amount, ok := new(big.Int).SetString(amountMinor, 10)
+	if !ok {
+		return sig, errors.New("invalid amount_minor format")
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT amount_minor FROM orders
+		WHERE merchant_id = ? AND asset = ? AND id != ? AND status IN ('PAID','PARTIALLY_REFUNDED','REFUNDED','SETTLED')
+	`, merchantID, asset, orderID)
+	if err != nil {
+		return sig, err
+	}
+	var total, smaller int
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			rows.Close()
+			return sig, err
+		}
+		if v, ok := new(big.Int).SetString(s, 10); ok {
+			total++
+			if v.Cmp(amount) < 0 {
+				smaller++
+			}
+		}
+	}
+	rows.Close()
+	if total >= minAmountHistory {
+		sig.AmountPercentile = float64(smaller) / float64(total)
+	}
+
+	if payer == "" {
+		return sig, nil
+	}
+	var seen int
+	if err := q.QueryRowContext(ctx, `
+		SELECT COUNT(1) FROM orders
+		WHERE merchant_id = ? AND id != ? AND customer_wallet_address = ? COLLATE NOCASE AND paid_at IS NOT NULL
+	`, merchantID, orderID, payer).Scan(&seen); err != nil {
+		return sig, err
+	}
+	sig.NewSender = seen == 0
+	since := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	if err := q.QueryRowContext(ctx, `
+		SELECT COUNT(1) FROM orders
+		WHERE id != ? AND customer_wallet_address = ? COLLATE NOCASE AND paid_at >= ?
+	`, orderID, payer, since).Scan(&sig.SenderPaymentsLastHour); err != nil {
+		return sig, err
+	}
+	return sig, nil
+}
+
+type orderReviewReq struct {
+	Decision string `json:"decision"` // "approve" credits the payment, "reject" fails the order
+}
+
+// ReviewOrderHandler godoc
+// @Summary      Release or reject a payment held for review
+// @Description  Orders whose payer address was flagged during screening wait in REVIEW; late payments for expired orders wait in LATE_PAYMENT when the merchant chose to review them. "approve" marks the order PAID and writes the ledger; "reject" marks it FAILED. Admin only.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id        query  string          true  "Order ID"
+// @Param        decision  body   orderReviewReq  true  "Decision"
+// @Success      200  {object}  paymentDetectedResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/orders/review [post]
+func ReviewOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	orderID := pathID(r)
+	var req orderReviewReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Decision != "approve" && req.Decision != "reject" {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidDecision, `decision must be "approve" or "reject"`)
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var merchantID, amountMinor, asset, status string
+	var txHash sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT merchant_id, amount_minor, asset, status, tx_hash FROM orders WHERE id = ? AND deleted_at IS NULL
+	`, orderID).Scan(&merchantID, &amountMinor, &asset, &status, &txHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "order not found")
+			return
+		}
+		serverErr(w, err)
+		return
+	}
+	if status != statusReview && status != statusLatePayment {
+		writeProblem(w, http.StatusConflict, CodeOrderNotInReview, "order is "+status+", not held for review")
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	newStatus, msg := "FAILED", "payment rejected after review"
+	if req.Decision == "approve" {
+		newStatus, msg = "PAID", "payment approved after review"
+	}
+	// paid_at restarts the settlement delay from the moment funds are released
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE orders SET status = ?, paid_at = CASE WHEN ? = 'PAID' THEN ? ELSE paid_at END WHERE id = ? AND status = ?
+	`, newStatus, newStatus, now, orderID, status); err != nil {
+		serverErr(w, err)
+		return
+	}
+	event := webhookOrderFailed
+	if newStatus == "PAID" {
+		if err := writePaymentLedger(ctx, tx, orderID, merchantID, asset, amountMinor, txHash.String, now); err != nil {
+			writeProblem(w, http.StatusInternalServerError, CodeDBError, err.Error())
+			return
+		}
+		if err := recordCustomerPayment(ctx, tx, orderID, now); err != nil {
+			serverErr(w, err)
+			return
+		}
+		event = webhookOrderPaid
+	}
+	if err := enqueueOrderEvent(ctx, tx, event, orderID); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=payment_review_decided order_id=%s decision=%s status=%s", orderID, req.Decision, newStatus)
+	writeJSON(w, http.StatusOK, paymentDetectedResp{OrderID: orderID, Status: newStatus, Message: msg})
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request duration histograms.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Durations are kept per route and status class; request counts also per merchant. Merchants only
+// label counters, so the number of series grows with merchants times routes, not times buckets.
+type routeClass struct{ route, class string }
+
+type routeMerchantClass struct{ route, merchant, class string }
+
+type latencyHistogram struct {
+	buckets []uint64 // cumulative counts are computed when exposed
+	count   uint64
+	sum     float64
+}
+
+var (
+	routeMetricsMu sync.Mutex
+	routeLatency   = map[routeClass]*latencyHistogram{}
+	routeRequests  = map[routeMerchantClass]uint64{}
+)
+
+// requestLabels carries the merchant of a request back out to RouteMetricsMiddleware, which runs
+// before authentication has identified it.
+type requestLabels struct{ merchantID string }
+
+const requestLabelsKey ctxKey = "request_labels"
+
+// setRequestMerchant records the authenticated merchant for the request's metrics.
+func setRequestMerchant(ctx context.Context, merchantID string) {
+	if l, ok := ctx.Value(requestLabelsKey).(*requestLabels); ok {
+		l.merchantID = merchantID
+	}
+}
+
+// RouteMetricsMiddleware records the count, duration and status class of every request to route,
+// a label such as "GET /v1/orders/{id}", for the Prometheus endpoint.
+func RouteMetricsMiddleware(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		labels := &requestLabels{}
+		sw := &statusWriter{ResponseWriter: w}
+		next(sw, r.WithContext(context.WithValue(r.Context(), requestLabelsKey, labels)))
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		observeRequest(route, labels.merchantID, strconv.Itoa(status/100)+"xx", time.Since(start))
+	}
+}
+
+func observeRequest(route, merchantID, class string, d time.Duration) {
+	routeMetricsMu.Lock()
+	defer routeMetricsMu.Unlock()
+	h := routeLatency[routeClass{route, class}]
+	if h == nil {
+		h = &latencyHistogram{buckets: make([]uint64, len(latencyBuckets))}
+		routeLatency[routeClass{route, class}] = h
+	}
+	secs := d.Seconds()
+	if i := sort.SearchFloat64s(latencyBuckets, secs); i < len(latencyBuckets) {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += secs
+	routeRequests[routeMerchantClass{route, merchantID, class}]++
+}
+
+// statusWriter remembers the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if sw.status == 0 {
+		sw.status = code
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush.
+func (sw *statusWriter) Unwrap() http.ResponseWriter { return sw.ResponseWriter }
+
+// PrometheusMetricsHandler godoc
+// @Summary      Get Prometheus metrics
+// @Description  Prometheus text exposition of the request metrics — ospay_http_requests_total by route, merchant and status class (code="2xx" etc.) and ospay_http_request_duration_seconds histograms by route and status class — of the background jobs — ospay_jobs by type and status and ospay_job_attempts_total by type and outcome — of database transactions — ospay_db_transaction_duration_seconds histograms by outcome — and of the counters and gauges of /debug/metrics, prefixed with ospay_.
+// @Tags         debug
+// @Produce      plain
+// @Success      200  {string}  string
+// @Router       /metrics [get]
+func PrometheusMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	writeRouteMetrics(&b)
+	writeJobMetrics(r.Context(), &b)
+	writeDBMetrics(&b)
+
+	metrics := debugMetrics(r.Context())
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		kind := "gauge"
+		if strings.HasSuffix(name, "_total") {
+			kind = "counter"
+		}
+		fmt.Fprintf(&b, "# TYPE ospay_%s %s\nospay_%s %d\n", name, kind, name, metrics[name])
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func writeRouteMetrics(b *strings.Builder) {
+	routeMetricsMu.Lock()
+	defer routeMetricsMu.Unlock()
+
+	requests := make([]routeMerchantClass, 0, len(routeRequests))
+	for k := range routeRequests {
+		requests = append(requests, k)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		a, c := requests[i], requests[j]
+		if a.route != c.route {
+			return a.route < c.route
+		}
+		if a.merchant != c.merchant {
+			return a.merchant < c.merchant
+		}
+		return a.class < c.class
+	})
+	b.WriteString("# HELP ospay_http_requests_total Requests by route, merchant and status class.\n# TYPE ospay_http_requests_total counter\n")
+	for _, k := range requests {
+		fmt.Fprintf(b, "ospay_http_requests_total{route=%q,merchant=%q,code=%q} %d\n", k.route, k.merchant, k.class, routeRequests[k])
+	}
+
+	latencies := make([]routeClass, 0, len(routeLatency))
+	for k := range routeLatency {
+		latencies = append(latencies, k)
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		if latencies[i].route != latencies[j].route {
+			return latencies[i].route < latencies[j].route
+		}
+		return latencies[i].class < latencies[j].class
+	})
+	b.WriteString("# HELP ospay_http_request_duration_seconds Request durations by route and status class.\n# TYPE ospay_http_request_duration_seconds histogram\n")
+	for _, k := range latencies {
+		h := routeLatency[k]
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(b, "ospay_http_request_duration_seconds_bucket{route=%q,code=%q,le=%q} %d\n", k.route, k.class, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(b, "ospay_http_request_duration_seconds_bucket{route=%q,code=%q,le=\"+Inf\"} %d\n", k.route, k.class, h.count)
+		fmt.Fprintf(b, "ospay_http_request_duration_seconds_sum{route=%q,code=%q} %g\n", k.route, k.class, h.sum)
+		fmt.Fprintf(b, "ospay_http_request_duration_seconds_count{route=%q,code=%q} %d\n", k.route, k.class, h.count)
+	}
+}
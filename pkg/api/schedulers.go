@@ -0,0 +1,209 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/jobs"
+)
+
+// Names of the background schedulers, as listed by /admin/schedulers.
+const (
+	schedulerSettlement    = "settlement"
+	schedulerOrderTimeout  = "order_timeout"
+	schedulerTxMonitor     = "tx_monitor"
+	schedulerPayouts       = "payouts"
+	schedulerGasTank       = "gas_tank"
+	schedulerIdempotency   = "idempotency_pruner"
+	schedulerWebhooks      = "webhooks"
+	schedulerRetention     = "retention"
+	schedulerConfirmations = "confirmations"
+	schedulerRefundJobs    = "refund_jobs"
+	schedulerENS           = "ens_refresh"
+	schedulerJobsPruner    = "jobs_pruner"
+	schedulerCounters      = "counter_flush"
+	schedulerOnchainRecon  = "onchain_reconciliation"
+	schedulerAttestations  = "reserve_attestation"
+	schedulerColdSweep     = "cold_sweep"
+	schedulerAddressWatch  = "address_watch"
+	schedulerNegativeBal   = "negative_balances"
+	schedulerBalanceHolds  = "balance_holds"
+)
+
+// SchedulerNames lists the background schedulers, for configuring them with SetSchedule.
+func SchedulerNames() []string {
+	return []string{
+		schedulerSettlement, schedulerOrderTimeout, schedulerTxMonitor, schedulerPayouts,
+		schedulerGasTank, schedulerIdempotency, schedulerWebhooks, schedulerRetention,
+		schedulerConfirmations, schedulerRefundJobs, schedulerENS, schedulerJobsPruner, schedulerCounters,
+		schedulerOnchainRecon, schedulerAttestations, schedulerColdSweep, schedulerAddressWatch, schedulerNegativeBal, schedulerBalanceHolds,
+	}
+}
+
+var (
+	schedulersMu      sync.Mutex
+	scheduleOverrides = map[string]jobs.Schedule{}
+)
+
+// SetSchedule replaces the default interval of scheduler name with spec, a cron expression such
+// as "0 2 * * *" (02:00 UTC every day) or "@every 10m". Call it before the scheduler is started.
+func SetSchedule(name, spec string) error {
+	sched, err := jobs.ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	schedulersMu.Lock()
+	scheduleOverrides[name] = sched
+	schedulersMu.Unlock()
+	return nil
+}
+
+// startScheduler registers job under name as a periodic job, run every interval or on the
+// schedule set with SetSchedule, starting with an immediate run when immediate is set. With
+// several instances, one at a time runs it.
+func startScheduler(name string, interval time.Duration, immediate bool, job jobs.PeriodicFunc) {
+	startPeriodic(name, interval, jobs.PeriodicOptions{Immediate: immediate}, job)
+}
+
+func startPeriodic(name string, interval time.Duration, opts jobs.PeriodicOptions, job jobs.PeriodicFunc) {
+	schedulersMu.Lock()
+	sched, ok := scheduleOverrides[name]
+	schedulersMu.Unlock()
+	if !ok {
+		sched = jobs.Every(interval)
+	}
+	opts.Schedule = sched
+	jobQueue.Periodic(name, opts, job)
+}
+
+type schedulerStatus struct {
+	Name           string  `json:"name"`
+	Schedule       string  `json:"schedule"`
+	NextRunAt      *string `json:"next_run_at"`
+	Paused         bool    `json:"paused"`
+	Running        bool    `json:"running"`
+	LastRunAt      *string `json:"last_run_at"`
+	LastDurationMs int64   `json:"last_duration_ms"`
+	LastRows       int     `json:"last_rows"`
+	LastError      *string `json:"last_error"`
+	Runs           int64   `json:"runs"`
+	Failures       int64   `json:"failures"`
+	Skipped        int64   `json:"skipped"`
+}
+
+func schedulerStatusOf(p *jobs.Periodic) schedulerStatus {
+	ps := p.Status()
+	st := schedulerStatus{
+		Name:           ps.Name,
+		Schedule:       ps.Schedule,
+		Paused:         ps.Paused,
+		Running:        ps.Running,
+		LastDurationMs: ps.LastDuration.Milliseconds(),
+		LastRows:       ps.LastRows,
+		Runs:           ps.Runs,
+		Failures:       ps.Failures,
+		Skipped:        ps.Skipped,
+	}
+	if !ps.NextRunAt.IsZero() {
+		at := ps.NextRunAt.UTC().Format(time.RFC3339)
+		st.NextRunAt = &at
+	}
+	if !ps.LastRunAt.IsZero() {
+		at := ps.LastRunAt.Format(time.RFC3339)
+		st.LastRunAt = &at
+	}
+	if ps.LastError != "" {
+		e := ps.LastError
+		st.LastError = &e
+	}
+	return st
+}
+
+// SchedulersHandler godoc
+// @Summary      List background schedulers
+// @Description  Lists the background schedulers started by this instance with their schedule and next run, whether they are paused or running, and the outcome of their last run: when it started, how long it took, the rows it processed and its error. skipped counts the runs this instance left out because another instance was running the scheduler. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   schedulerStatus
+// @Router       /admin/schedulers [get]
+func SchedulersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	periodic := jobQueue.PeriodicJobs()
+	out := make([]schedulerStatus, 0, len(periodic))
+	for _, p := range periodic {
+		out = append(out, schedulerStatusOf(p))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// PauseSchedulerHandler godoc
+// @Summary      Pause a scheduler
+// @Description  Stops a scheduler's runs until it is resumed; a run in progress finishes. The paused state lasts until the process restarts. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Scheduler name"
+// @Success      200  {object}  schedulerStatus
+// @Failure      404  {object}  Problem
+// @Router       /admin/schedulers/pause [post]
+func PauseSchedulerHandler(w http.ResponseWriter, r *http.Request) {
+	controlSchedulerHandler(w, r, "pause")
+}
+
+// ResumeSchedulerHandler godoc
+// @Summary      Resume a scheduler
+// @Description  Lets a paused scheduler run again on its interval. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Scheduler name"
+// @Success      200  {object}  schedulerStatus
+// @Failure      404  {object}  Problem
+// @Router       /admin/schedulers/resume [post]
+func ResumeSchedulerHandler(w http.ResponseWriter, r *http.Request) {
+	controlSchedulerHandler(w, r, "resume")
+}
+
+// RunSchedulerHandler godoc
+// @Summary      Run a scheduler now
+// @Description  Starts a run of the scheduler now, even when it is paused, and returns without waiting for it; poll /admin/schedulers for the outcome. Admin only.
+// @Tags         admin
+// @Produce      json
+// @Param        id  query  string  true  "Scheduler name"
+// @Success      202  {object}  schedulerStatus
+// @Failure      404  {object}  Problem
+// @Router       /admin/schedulers/run [post]
+func RunSchedulerHandler(w http.ResponseWriter, r *http.Request) {
+	controlSchedulerHandler(w, r, "run")
+}
+
+func controlSchedulerHandler(w http.ResponseWriter, r *http.Request, action string) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	name := pathID(r)
+	if name == "" {
+		badReq(w, "missing scheduler name")
+		return
+	}
+	p := jobQueue.PeriodicJob(name)
+	if p == nil {
+		writeProblem(w, http.StatusNotFound, CodeSchedulerNotFound, "")
+		return
+	}
+	status := http.StatusOK
+	switch action {
+	case "pause":
+		p.Pause()
+	case "resume":
+		p.Resume()
+	case "run":
+		p.RunNow()
+		status = http.StatusAccepted
+	}
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), "", "", "scheduler_"+action, map[string]any{"scheduler": name})
+	writeJSON(w, status, schedulerStatusOf(p))
+}
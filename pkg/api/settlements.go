@@ -0,0 +1,133 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// settlementRecord is a settlement batch as listed and exported in statements.
+type settlementRecord struct {
+	BatchID          string `json:"batch_id"`
+	MerchantID       string `json:"merchant_id"`
+	Asset            string `json:"asset"`
+	Status           string `json:"status"`
+	TotalAmountMinor string `json:"total_amount_minor"` // net payout
+	settlementItems
+	PayoutTxHash *string `json:"payout_tx_hash,omitempty"`
+	SettledAt    string  `json:"settled_at"`
+}
+
+// ListSettlementsHandler godoc
+// @Summary      List settlement batches
+// @Description  Lists the merchant's settlement batches, newest first, each itemized into the gross volume of its orders, the application fees withheld, the completed refunds and lost disputes netted, what was clawed back against a negative balance or withheld for balance holds, and the net payout (total_amount_minor, what the payout sends). Releasing a hold settles what it withheld in a batch of its own (hold_released_minor). Batches settled before itemization was stored only carry the net. from and to (RFC 3339) bound settled_at to [from, to), asset narrows the list. With format=csv the list is returned as a statement, one line per batch, for accounting. Admins pass merchant_id, or leave it out for every merchant.
+// @Tags         reconciliation
+// @Produce      json,text/csv
+// @Param        from         query  string  false  "Earliest settled_at, RFC 3339"
+// @Param        to           query  string  false  "Settled before, RFC 3339"
+// @Param        asset        query  string  false  "Asset symbol"
+// @Param        format       query  string  false  "json (default) or csv"
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {array}   settlementRecord
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /settlements [get]
+// @Router       /admin/settlements [get]
+func ListSettlementsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(r.Context())
+	if isAdmin(r.Context()) {
+		merchantID = q.Get("merchant_id")
+	}
+	format := strings.ToLower(q.Get("format"))
+	if format != "" && format != "json" && format != "csv" {
+		badReq(w, "format must be json or csv")
+		return
+	}
+	var from, to string
+	for _, b := range []struct {
+		name string
+		out  *string
+	}{{"from", &from}, {"to", &to}} {
+		v := q.Get(b.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, b.name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*b.out = t.UTC().Format(time.RFC3339)
+	}
+	if from != "" && to != "" && to <= from {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be after from")
+		return
+	}
+	asset := strings.ToUpper(q.Get("asset"))
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpReport)
+	defer cancel()
+	rows, err := reportDB.QueryContext(ctx, `
+		SELECT id, merchant_id, asset, status, total_amount_minor, gross_amount_minor, fees_minor, refunds_minor,
+		       disputes_lost_minor, clawback_minor, held_minor, hold_released_minor, payout_tx_hash, COALESCE(executed_at, created_at) AS settled_at
+		FROM settlement_batches
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR asset = ?)
+		  AND (? = '' OR COALESCE(executed_at, created_at) >= ?) AND (? = '' OR COALESCE(executed_at, created_at) < ?)
+		ORDER BY settled_at DESC, id DESC
+		LIMIT 1000
+	`, merchantID, merchantID, asset, asset, from, from, to, to)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	batches := []settlementRecord{}
+	for rows.Next() {
+		var (
+			b                                               settlementRecord
+			gross, fees, refunds, disputes, clawback, payTx sql.NullString
+			held, released                                  sql.NullString
+		)
+		if err := rows.Scan(&b.BatchID, &b.MerchantID, &b.Asset, &b.Status, &b.TotalAmountMinor, &gross, &fees, &refunds,
+			&disputes, &clawback, &held, &released, &payTx, &b.SettledAt); err != nil {
+			serverErr(w, err)
+			return
+		}
+		b.GrossAmountMinor, b.FeesMinor, b.RefundsMinor, b.DisputesLostMinor = gross.String, fees.String, refunds.String, disputes.String
+		b.ClawbackMinor, b.HeldMinor, b.HoldReleasedMinor = clawback.String, held.String, released.String
+		b.PayoutTxHash = nullStringPtr(payTx)
+		batches = append(batches, b)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if format != "csv" {
+		writeJSON(w, http.StatusOK, batches)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="settlements.csv"`)
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"batch_id", "merchant_id", "asset", "status", "settled_at", "gross_amount_minor", "fees_minor", "refunds_minor",
+		"disputes_lost_minor", "clawback_minor", "held_minor", "hold_released_minor", "net_payout_minor", "payout_tx_hash"})
+	for _, b := range batches {
+		payTx := ""
+		if b.PayoutTxHash != nil {
+			payTx = *b.PayoutTxHash
+		}
+		_ = cw.Write([]string{b.BatchID, b.MerchantID, b.Asset, b.Status, b.SettledAt, b.GrossAmountMinor, b.FeesMinor, b.RefundsMinor,
+			b.DisputesLostMinor, b.ClawbackMinor, b.HeldMinor, b.HoldReleasedMinor, b.TotalAmountMinor, payTx})
+	}
+	cw.Flush()
+}
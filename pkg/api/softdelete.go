@@ -0,0 +1,262 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Merchants and orders are deleted by setting deleted_at, never by removing the row, so that an
+// administrator can restore them. Ledger entries are not touched either way: a deleted merchant's
+// balance stays on the ledger (it is no longer settled), and only orders that never received a
+// payment, and so have nothing on the ledger, can be deleted. A payment being verified counts: the
+// verification job would not find the order once it is deleted.
+
+type softDeleteReq struct {
+	Reason string `json:"reason" validate:"max=1000"` // kept in the audit log
+}
+
+// DeleteMerchantHandler godoc
+// @Summary      Delete a merchant
+// @Description  Soft-deletes a merchant, e.g. when offboarding it: its API keys, OAuth tokens and platform or organization access are refused with 403 merchant_deleted, it is left out of merchant lists, and its balance is no longer settled. Nothing is removed, the ledger included; POST /admin/merchants/{id}/restore undoes the deletion. Admin only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      query  string         true   "Merchant ID"
+// @Param        delete  body   softDeleteReq  false  "Optional reason, for the audit log"
+// @Success      200  {object}  merchantRecord
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/merchants/delete [post]
+func DeleteMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	setMerchantDeleted(w, r, true)
+}
+
+// RestoreMerchantHandler godoc
+// @Summary      Restore a deleted merchant
+// @Description  Undoes the deletion of a merchant: its API keys and tokens work again, and settlement picks up its balance on the next run. Admin only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       query  string         true   "Merchant ID"
+// @Param        restore  body   softDeleteReq  false  "Optional reason, for the audit log"
+// @Success      200  {object}  merchantRecord
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/merchants/restore [post]
+func RestoreMerchantHandler(w http.ResponseWriter, r *http.Request) {
+	setMerchantDeleted(w, r, false)
+}
+
+func setMerchantDeleted(w http.ResponseWriter, r *http.Request, deleted bool) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	var req softDeleteReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	id := pathID(r)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer tx.Rollback()
+	var deletedAt sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT deleted_at FROM merchants WHERE id = ?`, id).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeMerchantNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if deleted && deletedAt.Valid {
+		writeProblem(w, http.StatusConflict, CodeMerchantDeleted, "the merchant was deleted at "+deletedAt.String)
+		return
+	}
+	if !deleted && !deletedAt.Valid {
+		writeProblem(w, http.StatusConflict, CodeMerchantNotDeleted, "")
+		return
+	}
+	actor, action := actorFromContext(ctx), "merchant_restored"
+	at, by := sql.NullString{}, sql.NullString{}
+	if deleted {
+		action = "merchant_deleted"
+		at = sql.NullString{String: time.Now().UTC().Format(time.RFC3339), Valid: true}
+		by = sql.NullString{String: actor, Valid: true}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE merchants SET deleted_at = ?, deleted_by = ? WHERE id = ?`, at, by, id); err != nil {
+		serverErr(w, err)
+		return
+	}
+	m, err := scanMerchantRecord(tx.QueryRowContext(ctx, `SELECT `+merchantRecordCols+` FROM merchants WHERE id = ?`, id))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(ctx, tx, actor, id, "", action, map[string]string{"reason": strings.TrimSpace(req.Reason)})
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=%s merchant_id=%s by=%s", action, id, actor)
+	writeJSON(w, http.StatusOK, m)
+}
+
+// DeleteOrderHandler godoc
+// @Summary      Delete an order
+// @Description  Soft-deletes an order that never received a payment, e.g. one created by mistake: it is no longer returned by any order read, list or search, a payment reported for it is refused as for an unknown order, and it does not expire or count toward limits. Orders with a payment stay on the books, since their ledger entries cannot be undone; refund them instead (409 order_has_payment). So do MISPAID orders and orders with a payment still being verified. The idempotency key stays used. Only an administrator can restore a deleted order, with POST /admin/orders/{id}/restore.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id      query  string         true   "Order ID"
+// @Param        delete  body   softDeleteReq  false  "Optional reason, for the audit log"
+// @Success      200  {object}  map[string]bool
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/delete [post]
+// @Router       /admin/orders/delete [post]
+func DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	var req softDeleteReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	id := pathID(r)
+	if id == "" {
+		badReq(w, "missing order id")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer tx.Rollback()
+	var (
+		merchantID string
+		hasPayment bool
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT merchant_id, paid_at IS NOT NULL OR tx_hash IS NOT NULL OR status = ?
+		       OR EXISTS (SELECT 1 FROM ledger_entries WHERE order_id = orders.id)
+		       OR EXISTS (SELECT 1 FROM payment_attempts WHERE order_id = orders.id AND status = ?)
+		FROM orders WHERE id = ? AND (? = '' OR merchant_id = ?) AND deleted_at IS NULL
+	`, statusMispaid, attemptPending, id, merchantIDFromContext(r.Context()), merchantIDFromContext(r.Context())).Scan(&merchantID, &hasPayment)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if hasPayment {
+		writeProblem(w, http.StatusConflict, CodeOrderHasPayment, "an order that received a payment, or is verifying one, cannot be deleted; refund it instead")
+		return
+	}
+	actor := actorFromContext(ctx)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE orders SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at IS NULL
+	`, time.Now().UTC().Format(time.RFC3339), actor, id); err != nil {
+		serverErr(w, err)
+		return
+	}
+	invalidateOrder(id)
+	recordAudit(ctx, tx, actor, merchantID, id, "order_deleted", map[string]string{"reason": strings.TrimSpace(req.Reason)})
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=order_deleted order_id=%s merchant_id=%s by=%s", id, merchantID, actor)
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// RestoreOrderHandler godoc
+// @Summary      Restore a deleted order
+// @Description  Undoes the deletion of an order and returns it. A pending order past its expiry is expired by the next expiry run. Admin only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       query  string         true   "Order ID"
+// @Param        restore  body   softDeleteReq  false  "Optional reason, for the audit log"
+// @Success      200  {object}  orderGetResp
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/orders/restore [post]
+func RestoreOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	var req softDeleteReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	id := pathID(r)
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	var (
+		merchantID string
+		deletedAt  sql.NullString
+	)
+	err := db.QueryRowContext(ctx, `SELECT merchant_id, deleted_at FROM orders WHERE id = ?`, id).Scan(&merchantID, &deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if !deletedAt.Valid {
+		writeProblem(w, http.StatusConflict, CodeOrderNotDeleted, "")
+		return
+	}
+	res, err := db.ExecContext(ctx, `UPDATE orders SET deleted_at = NULL, deleted_by = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusConflict, CodeOrderNotDeleted, "")
+		return
+	}
+	actor := actorFromContext(ctx)
+	recordAudit(ctx, db, actor, merchantID, id, "order_restored", map[string]string{"reason": strings.TrimSpace(req.Reason)})
+	log.Printf("event=order_restored order_id=%s merchant_id=%s by=%s", id, merchantID, actor)
+	o, err := stores.Orders.Get(ctx, id, "")
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	items, err := loadLineItems(ctx, db, o.ID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := orderResponse(o)
+	resp.LineItems = items[o.ID]
+	writeJSON(w, http.StatusOK, resp)
+}
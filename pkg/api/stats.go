@@ -0,0 +1,217 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Time-series metrics. Volumes are sums of amount_minor and need an asset; conversion_rate is the
+// share of the orders created in a bucket that were paid.
+const (
+	metricOrdersCreated  = "orders_created"
+	metricPaidVolume     = "paid_volume"
+	metricRefunds        = "refunds"
+	metricConversionRate = "conversion_rate"
+	metricDiscounts      = "discounts"
+)
+
+// maxSeriesPoints caps a series: 31 days of hours or a year of days.
+var maxSeriesPoints = map[string]int{"hour": 31 * 24, "day": 366}
+
+type seriesPoint struct {
+	Start string `json:"start"` // bucket start in the merchant's timezone
+	Value string `json:"value"` // a count, an amount in minor units or a ratio, as a decimal
+}
+
+type timeseriesResp struct {
+	Metric   string        `json:"metric"`
+	Interval string        `json:"interval"`
+	Asset    string        `json:"asset,omitempty"`
+	Timezone string        `json:"timezone"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Points   []seriesPoint `json:"points"`
+}
+
+// TimeseriesHandler godoc
+// @Summary      Get a metric as a time series
+// @Description  Buckets a metric by hour or day over [from, to): orders_created (count), paid_volume (amount paid, by paid_at; needs asset), discounts (coupon discounts given on those payments; needs asset), refunds (amount refunded by completed refunds; needs asset) and conversion_rate (paid share of the orders created in the bucket). Buckets start at hour or midnight boundaries in the merchant's timezone and every bucket in the range is returned, zero-filled. from defaults to 24 hours (hour) or 30 days (day) before to, which defaults to now; a series has at most 744 hourly or 366 daily points. Admins pass merchant_id.
+// @Tags         stats
+// @Produce      json
+// @Param        metric       query  string  true   "orders_created, paid_volume, discounts, refunds or conversion_rate"
+// @Param        interval     query  string  false  "hour or day (default hour)"
+// @Param        asset        query  string  false  "Asset symbol; required for paid_volume, discounts and refunds"
+// @Param        from         query  string  false  "Range start, RFC 3339"
+// @Param        to           query  string  false  "Range end, RFC 3339"
+// @Param        merchant_id  query  string  false  "Merchant ID (admin route only)"
+// @Success      200  {object}  timeseriesResp
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /stats/timeseries [get]
+// @Router       /admin/stats/timeseries [get]
+func TimeseriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpReport)
+	defer cancel()
+	q := r.URL.Query()
+	merchantID := merchantIDFromContext(ctx)
+	if isAdmin(ctx) {
+		merchantID = q.Get("merchant_id")
+	}
+	if merchantID == "" {
+		writeProblem(w, http.StatusBadRequest, CodeMissingQueryParam, "merchant_id is required")
+		return
+	}
+	metric, interval, asset := q.Get("metric"), q.Get("interval"), strings.ToUpper(q.Get("asset"))
+	if interval == "" {
+		interval = "hour"
+	}
+	if _, ok := maxSeriesPoints[interval]; !ok {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidMetric, "interval must be hour or day")
+		return
+	}
+	var query string
+	switch metric {
+	case metricOrdersCreated, metricConversionRate:
+		query = `SELECT created_at, CASE WHEN paid_at IS NULL THEN '0' ELSE '1' END FROM orders
+			WHERE merchant_id = ? AND created_at >= ? AND created_at < ? AND (? = '' OR asset = ?) AND deleted_at IS NULL`
+	case metricPaidVolume:
+		query = `SELECT paid_at, amount_minor FROM orders
+			WHERE merchant_id = ? AND paid_at >= ? AND paid_at < ? AND asset = ? AND ? != ''
+			  AND status IN ('PAID', 'SETTLED', 'REFUNDED', 'PARTIALLY_REFUNDED')`
+	case metricDiscounts:
+		query = `SELECT paid_at, discount_minor FROM orders
+			WHERE merchant_id = ? AND paid_at >= ? AND paid_at < ? AND asset = ? AND ? != '' AND discount_minor IS NOT NULL
+			  AND status IN ('PAID', 'SETTLED', 'REFUNDED', 'PARTIALLY_REFUNDED')`
+	case metricRefunds:
+		query = `SELECT r.created_at, r.amount_minor FROM refunds r JOIN orders o ON o.id = r.order_id
+			WHERE r.merchant_id = ? AND r.created_at >= ? AND r.created_at < ? AND o.asset = ? AND ? != '' AND r.status = 'COMPLETED'`
+	default:
+		writeProblem(w, http.StatusBadRequest, CodeInvalidMetric, "metric must be orders_created, paid_volume, discounts, refunds or conversion_rate")
+		return
+	}
+	if asset == "" && (metric == metricPaidVolume || metric == metricDiscounts || metric == metricRefunds) {
+		writeProblem(w, http.StatusBadRequest, CodeMissingQueryParam, "asset is required for "+metric)
+		return
+	}
+
+	loc, err := merchantLocation(ctx, db, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	to := time.Now()
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	from := to.Add(-24 * time.Hour)
+	if interval == "day" {
+		from = to.AddDate(0, 0, -30)
+	}
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "from must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	if !to.After(from) {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be after from")
+		return
+	}
+
+	// Buckets are aligned to local hours or days; the first one may start before from.
+	var starts []time.Time
+	for t := bucketStart(from, interval, loc); t.Before(to); t = nextBucket(t, interval, loc) {
+		if len(starts) == maxSeriesPoints[interval] {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, fmt.Sprintf("the range spans more than %d %ss", maxSeriesPoints[interval], interval))
+			return
+		}
+		starts = append(starts, t)
+	}
+	sums := make([]*big.Int, len(starts))
+	counts := make([]int64, len(starts))
+	for i := range sums {
+		sums[i] = new(big.Int)
+	}
+	rows, err := reportDB.QueryContext(ctx, query, merchantID, starts[0].UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339), asset, asset)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ts, value string
+		if err := rows.Scan(&ts, &value); err != nil {
+			serverErr(w, err)
+			return
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		v, ok := new(big.Int).SetString(value, 10)
+		if err != nil || !ok {
+			continue
+		}
+		// Rows arrive unordered; the bucket is the last one starting at or before t.
+		i := sort.Search(len(starts), func(i int) bool { return starts[i].After(t) }) - 1
+		if i < 0 {
+			continue
+		}
+		counts[i]++
+		sums[i].Add(sums[i], v)
+	}
+	if err := rows.Err(); err != nil {
+		serverErr(w, err)
+		return
+	}
+
+	resp := timeseriesResp{
+		Metric: metric, Interval: interval, Asset: asset, Timezone: loc.String(),
+		From: starts[0].Format(time.RFC3339), To: to.In(loc).Format(time.RFC3339), Points: make([]seriesPoint, len(starts)),
+	}
+	for i, t := range starts {
+		p := seriesPoint{Start: t.Format(time.RFC3339)}
+		switch metric {
+		case metricOrdersCreated:
+			p.Value = fmt.Sprint(counts[i])
+		case metricConversionRate:
+			p.Value = "0"
+			if counts[i] > 0 {
+				p.Value = new(big.Rat).SetFrac(sums[i], big.NewInt(counts[i])).FloatString(4)
+			}
+		default:
+			p.Value = sums[i].String()
+		}
+		resp.Points[i] = p
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// bucketStart truncates t to the start of its local hour or day.
+func bucketStart(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	y, m, d := t.Date()
+	if interval == "day" {
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+	return time.Date(y, m, d, t.Hour(), 0, 0, 0, loc)
+}
+
+func nextBucket(t time.Time, interval string, loc *time.Location) time.Time {
+	if interval == "day" {
+		y, m, d := t.Date()
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+	return t.Add(time.Hour)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Tags group a merchant's orders, e.g. by campaign, channel or batch. They are compared in lower
+// case; an order carries at most maxOrderTags of them.
+const (
+	maxOrderTags   = 20
+	maxOrderTagLen = 64
+)
+
+type orderTagsReq struct {
+	Tags []string `json:"tags" validate:"required,max=20"`
+}
+
+type orderTagsResp struct {
+	OrderID string   `json:"order_id"`
+	Tags    []string `json:"tags"` // sorted
+}
+
+// normalizeTag trims and lower-cases a tag; ok is false unless it is 1 to maxOrderTagLen
+// characters without control characters.
+func normalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > maxOrderTagLen || strings.IndexFunc(tag, unicode.IsControl) >= 0 {
+		return "", false
+	}
+	return tag, true
+}
+
+// OrderTagsHandler godoc
+// @Summary      Add or list order tags
+// @Description  POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64 characters, trimmed and compared in lower case; tags the order already has are ignored and an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags stay on the order when it is archived.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id    query  string        true   "Order ID"
+// @Param        tags  body   orderTagsReq  false  "Tags to add (POST only)"
+// @Success      200  {object}  orderTagsResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/tags [get]
+// @Router       /orders/tags [post]
+// @Router       /admin/orders/tags [get]
+// @Router       /admin/orders/tags [post]
+func OrderTagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	orderTagsChange(w, r, true)
+}
+
+// RemoveOrderTagsHandler godoc
+// @Summary      Remove order tags
+// @Description  Removes tags from an order; tags it does not carry are ignored. Returns the tags left.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id    query  string        true  "Order ID"
+// @Param        tags  body   orderTagsReq  true  "Tags to remove"
+// @Success      200  {object}  orderTagsResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /orders/tags/remove [post]
+// @Router       /admin/orders/tags/remove [post]
+func RemoveOrderTagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	orderTagsChange(w, r, false)
+}
+
+// orderTagsChange adds (add) or removes the request's tags, or only lists them for a GET.
+func orderTagsChange(w http.ResponseWriter, r *http.Request, add bool) {
+	orderID := pathID(r)
+	if orderID == "" {
+		badReq(w, "missing query param: id")
+		return
+	}
+	var tags []string
+	if r.Method == http.MethodPost {
+		var req orderTagsReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		seen := map[string]bool{}
+		for i, t := range req.Tags {
+			tag, ok := normalizeTag(t)
+			if !ok {
+				writeProblem(w, http.StatusBadRequest, CodeInvalidTag, "tags["+strconv.Itoa(i)+"]: tags are 1 to 64 characters without control characters")
+				return
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	ctx, cancel := store.WithTimeout(r.Context(), store.OpWrite)
+	defer cancel()
+	o, err := stores.Orders.Get(ctx, orderID, merchantIDFromContext(r.Context()))
+	if errors.Is(err, store.ErrNotFound) {
+		writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
+		return
+	} else if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if len(tags) > 0 {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			serverErr(w, err)
+			return
+		}
+		defer tx.Rollback()
+		if add {
+			now := time.Now().UTC().Format(time.RFC3339)
+			for _, tag := range tags {
+				if _, err := tx.ExecContext(ctx, `
+					INSERT INTO order_tags (order_id, merchant_id, tag, created_at) VALUES (?, ?, ?, ?)
+					ON CONFLICT (order_id, tag) DO NOTHING
+				`, o.ID, o.MerchantID, tag, now); err != nil {
+					serverErr(w, err)
+					return
+				}
+			}
+			var n int
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM order_tags WHERE order_id = ?`, o.ID).Scan(&n); err != nil {
+				serverErr(w, err)
+				return
+			}
+			if n > maxOrderTags {
+				writeProblem(w, http.StatusConflict, CodeTooManyTags, "an order carries at most "+strconv.Itoa(maxOrderTags)+" tags")
+				return
+			}
+		} else {
+			for _, tag := range tags {
+				if _, err := tx.ExecContext(ctx, `DELETE FROM order_tags WHERE order_id = ? AND tag = ?`, o.ID, tag); err != nil {
+					serverErr(w, err)
+					return
+				}
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			serverErr(w, err)
+			return
+		}
+	}
+	all, err := loadOrderTags(ctx, db, o.ID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	tagsOut := all[o.ID]
+	if tagsOut == nil {
+		tagsOut = []string{}
+	}
+	writeJSON(w, http.StatusOK, orderTagsResp{OrderID: o.ID, Tags: tagsOut})
+}
+
+// loadOrderTags returns the tags of the given orders, keyed by order ID.
+func loadOrderTags(ctx context.Context, q queryer, orderIDs ...string) (map[string][]string, error) {
+	tags := map[string][]string{}
+	if len(orderIDs) == 0 {
+		return tags, nil
+	}
+	args := make([]any, len(orderIDs))
+	for i, id := range orderIDs {
+		args[i] = id
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT order_id, tag FROM order_tags
+		WHERE order_id IN (?`+strings.Repeat(", ?", len(orderIDs)-1)+`)
+		ORDER BY order_id, tag
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var orderID, tag string
+		if err := rows.Scan(&orderID, &tag); err != nil {
+			return nil, err
+		}
+		tags[orderID] = append(tags[orderID], tag)
+	}
+	return tags, rows.Err()
+}
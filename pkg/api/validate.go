@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// Request bodies declare their rules in validate struct tags, which swag also reads into the API
+// docs. Rules are separated by commas:
+//
+//	required    the field is present and not empty (non-zero for numbers)
+//	max=N       at most N characters, N items, or a number of at most N
+//	min=N       at least N characters, N items, or a number of at least N
+//	oneof=a b   one of the space-separated values, in any case
+//	asset       a known asset symbol, in any case
+//	chain       a known chain, in any case
+//	amount      a positive integer in minor units
+//	rfc3339     an RFC 3339 timestamp
+//
+// Rules other than required only apply to fields that are given. Nested structs, slices of them
+// and pointers to them are validated too.
+
+// decodeBody decodes the JSON request body into v and validates it, answering 400 with the fields
+// at fault if it is not valid. An empty body decodes as {}. It reports whether the request may go on.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+	case bodyTooLarge(w, err):
+		return false
+	case errors.As(err, &syntaxErr):
+		writeProblem(w, http.StatusBadRequest, CodeInvalidJSON, fmt.Sprintf("invalid JSON at byte %d: %s", syntaxErr.Offset, syntaxErr.Error()))
+		return false
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		fe := FieldError{Field: typeErr.Field, Rule: "type", Message: typeErr.Field + " must be " + jsonTypeName(typeErr.Type)}
+		writeFieldProblem(w, CodeInvalidJSON, fe.Message, []FieldError{fe})
+		return false
+	default:
+		writeProblem(w, http.StatusBadRequest, CodeInvalidJSON, "invalid JSON body: "+strings.TrimPrefix(err.Error(), "json: "))
+		return false
+	}
+	errs := validateStruct(v)
+	if len(errs) == 0 {
+		return true
+	}
+	code := CodeMissingFields
+	msgs := make([]string, len(errs))
+	for i, fe := range errs {
+		msgs[i] = fe.Message
+		if fe.Rule != "required" {
+			code = CodeValidationFailed
+		}
+	}
+	writeFieldProblem(w, code, strings.Join(msgs, "; "), errs)
+	return false
+}
+
+// jsonTypeName names the JSON type a Go value decodes from.
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(json.Number("")) {
+		return "a number"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// validateStruct checks v, a pointer to a struct, against its validate tags.
+func validateStruct(v any) []FieldError {
+	var errs []FieldError
+	validateValue(reflect.ValueOf(v), "", &errs)
+	return errs
+}
+
+func validateValue(v reflect.Value, path string, errs *[]FieldError) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			fv := v.Field(i)
+			if tag := f.Tag.Get("validate"); tag != "" {
+				if fe, ok := checkRules(fv, name, tag); !ok {
+					*errs = append(*errs, fe)
+					continue
+				}
+			}
+			validateValue(fv, name, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem() == reflect.TypeOf(byte(0)) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	}
+}
+
+// checkRules applies the rules of tag to the field v and returns the first one it breaks.
+func checkRules(v reflect.Value, field, tag string) (FieldError, bool) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if slices.Contains(strings.Split(tag, ","), "required") {
+				return FieldError{Field: field, Rule: "required", Message: field + " is required"}, false
+			}
+			return FieldError{}, true
+		}
+		v = v.Elem()
+	}
+	if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+		if slices.Contains(strings.Split(tag, ","), "required") {
+			return FieldError{Field: field, Rule: "required", Message: field + " is required"}, false
+		}
+		return FieldError{}, true
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		if msg := checkRule(v, name, arg); msg != "" {
+			return FieldError{Field: field, Rule: name, Message: field + " " + msg}, false
+		}
+	}
+	return FieldError{}, true
+}
+
+// checkRule returns what is wrong with v under one rule, or "" if nothing.
+func checkRule(v reflect.Value, rule, arg string) string {
+	str := ""
+	if v.Kind() == reflect.String {
+		str = v.String()
+	}
+	switch rule {
+	case "required":
+	case "max", "min":
+		n, _ := strconv.ParseInt(arg, 10, 64)
+		var size int64
+		unit := ""
+		switch v.Kind() {
+		case reflect.String:
+			size, unit = int64(utf8.RuneCountInString(str)), " characters"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			size, unit = int64(v.Len()), " items"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			size = v.Int()
+		default:
+			return ""
+		}
+		if rule == "max" && size > n {
+			return "must be at most " + arg + unit
+		}
+		if rule == "min" && size < n {
+			return "must be at least " + arg + unit
+		}
+	case "oneof":
+		values := strings.Fields(arg)
+		if !slices.ContainsFunc(values, func(s string) bool { return strings.EqualFold(s, str) }) {
+			return "must be one of " + strings.Join(values, ", ")
+		}
+	case "asset":
+		if !slices.Contains(blockchain.KnownAssets(), strings.ToUpper(str)) {
+			return "must be a known asset: " + strings.Join(blockchain.KnownAssets(), ", ")
+		}
+	case "chain":
+		if blockchain.AddressFormat(str) == "" {
+			return "must be a known chain: " + strings.Join(blockchain.KnownChains(), ", ")
+		}
+	case "amount":
+		if !isValidAmountString(str) {
+			return "must be a positive integer in minor units"
+		}
+	case "rfc3339":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return "must be an RFC 3339 timestamp"
+		}
+	default:
+		panic("validate: unknown rule " + rule)
+	}
+	return ""
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+// A merchant proves control of its payout wallet by signing a challenge issued here, either as an
+// EIP-191 personal message or as EIP-712 typed data. Until the current wallet is proven, payouts
+// to it wait in QUEUED, so a mistyped or swapped address never receives funds.
+var walletProofRequired = true
+
+// SetWalletProofRequired turns holding payouts to unproven wallets on or off.
+func SetWalletProofRequired(on bool) { walletProofRequired = on }
+
+// walletChallengeTTL is how long a challenge can be signed.
+const walletChallengeTTL = 15 * time.Minute
+
+// The EIP-712 domain and type of the typed-data variant of a challenge.
+var (
+	walletProofDomainType = crypto.Keccak256([]byte("EIP712Domain(string name,string version)"))
+	walletProofType       = crypto.Keccak256([]byte("WalletVerification(string merchantId,address wallet,string nonce,string issuedAt)"))
+	walletProofDomain     = crypto.Keccak256(walletProofDomainType, crypto.Keccak256([]byte("OSPay")), crypto.Keccak256([]byte("1")))
+)
+
+type walletChallenge struct {
+	ID            string          `json:"challenge_id"`
+	WalletAddress string          `json:"wallet_address"`
+	Nonce         string          `json:"nonce"`
+	Message       string          `json:"message"`                         // sign with personal_sign (EIP-191)
+	TypedData     json.RawMessage `json:"typed_data" swaggertype:"object"` // or with eth_signTypedData_v4 (EIP-712)
+	IssuedAt      string          `json:"issued_at"`
+	ExpiresAt     string          `json:"expires_at"`
+}
+
+type walletVerifyReq struct {
+	ChallengeID string `json:"challenge_id"`
+	Signature   string `json:"signature"` // 65 bytes, 0x-prefixed hex
+}
+
+type walletStatus struct {
+	WalletAddress string  `json:"wallet_address"`
+	WalletENSName string  `json:"wallet_ens_name,omitempty"`
+	Verified      bool    `json:"verified"` // the merchant proved control of wallet_address
+	VerifiedAt    *string `json:"verified_at,omitempty"`
+	Method        string  `json:"method,omitempty"` // eip191 or eip712, in a verify response
+	// ProofRequired is whether payouts wait until the wallet is verified.
+	ProofRequired bool `json:"proof_required"`
+}
+
+func walletProofMessage(merchantID, wallet, nonce, issuedAt string) string {
+	return "OSPay wallet verification\n\n" +
+		"Sign this message to prove that you control the payout wallet of merchant " + merchantID + ". " +
+		"Signing does not send a transaction or cost gas.\n\n" +
+		"Wallet: " + wallet + "\nNonce: " + nonce + "\nIssued at: " + issuedAt
+}
+
+func walletProofTypedData(merchantID, wallet, nonce, issuedAt string) json.RawMessage {
+	b, _ := json.Marshal(map[string]any{
+		"types": map[string]any{
+			"EIP712Domain": []map[string]string{{"name": "name", "type": "string"}, {"name": "version", "type": "string"}},
+			"WalletVerification": []map[string]string{
+				{"name": "merchantId", "type": "string"}, {"name": "wallet", "type": "address"},
+				{"name": "nonce", "type": "string"}, {"name": "issuedAt", "type": "string"},
+			},
+		},
+		"primaryType": "WalletVerification",
+		"domain":      map[string]string{"name": "OSPay", "version": "1"},
+		"message":     map[string]string{"merchantId": merchantID, "wallet": wallet, "nonce": nonce, "issuedAt": issuedAt},
+	})
+	return b
+}
+
+// walletProofTypedHash is the EIP-712 digest of the typed-data challenge.
+func walletProofTypedHash(merchantID, wallet, nonce, issuedAt string) []byte {
+	structHash := crypto.Keccak256(walletProofType,
+		crypto.Keccak256([]byte(merchantID)), common.LeftPadBytes(common.HexToAddress(wallet).Bytes(), 32),
+		crypto.Keccak256([]byte(nonce)), crypto.Keccak256([]byte(issuedAt)))
+	return blockchain.TypedDataHash(walletProofDomain, structHash)
+}
+
+// MerchantWalletHandler godoc
+// @Summary      Get the merchant wallet and its verification
+// @Description  Returns the payout wallet, its ENS name if it was given as one, and whether the merchant has proved control of it (POST /merchants/wallet/challenge, then /merchants/wallet/verify). While proof_required is set, payouts to an unverified wallet wait in QUEUED.
+// @Tags         merchants
+// @Produce      json
+// @Success      200  {object}  walletStatus
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/wallet [get]
+func MerchantWalletHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	st, err := loadWalletStatus(r.Context(), merchantIDFromContext(r.Context()))
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func loadWalletStatus(ctx context.Context, merchantID string) (walletStatus, error) {
+	var (
+		wallet                 string
+		ensName, proven, proof sql.NullString
+	)
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(merchant_wallet_address, ''), wallet_ens_name, wallet_verified_address, wallet_verified_at FROM merchants WHERE id = ?
+	`, merchantID).Scan(&wallet, &ensName, &proven, &proof); err != nil {
+		return walletStatus{}, err
+	}
+	st := walletStatus{WalletAddress: wallet, WalletENSName: ensName.String, ProofRequired: walletProofRequired}
+	if wallet != "" && strings.EqualFold(proven.String, wallet) {
+		st.Verified, st.VerifiedAt = true, nullStringPtr(proof)
+	}
+	return st, nil
+}
+
+// WalletChallengeHandler godoc
+// @Summary      Issue a wallet ownership challenge
+// @Description  Issues a single-use challenge for the merchant's current payout wallet, valid for 15 minutes. Sign message with personal_sign (EIP-191), or typed_data with eth_signTypedData_v4 (EIP-712), from the wallet and send the signature to /merchants/wallet/verify. Only EVM wallets can be verified. Primary API key only.
+// @Tags         merchants
+// @Produce      json
+// @Success      201  {object}  walletChallenge
+// @Failure      403  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/wallet/challenge [post]
+func WalletChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "wallet ownership can only be proved with the primary merchant API key")
+		return
+	}
+	ctx := r.Context()
+	merchantID := merchantIDFromContext(ctx)
+	m, err := stores.Merchants.Get(ctx, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if _, format, err := blockchain.NormalizeAnyAddress(m.WalletAddress); err != nil || format != blockchain.FormatEVM {
+		writeProblem(w, http.StatusUnprocessableEntity, CodeWalletProofUnsupported, "only EVM wallets can be verified; payouts are sent on EVM chains")
+		return
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		serverErr(w, err)
+		return
+	}
+	now := time.Now().UTC()
+	c := walletChallenge{
+		ID:            "wch_" + uuid.New().String(),
+		WalletAddress: m.WalletAddress,
+		Nonce:         hex.EncodeToString(raw),
+		IssuedAt:      now.Format(time.RFC3339),
+		ExpiresAt:     now.Add(walletChallengeTTL).Format(time.RFC3339),
+	}
+	c.Message = walletProofMessage(merchantID, c.WalletAddress, c.Nonce, c.IssuedAt)
+	c.TypedData = walletProofTypedData(merchantID, c.WalletAddress, c.Nonce, c.IssuedAt)
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO wallet_challenges (id, merchant_id, wallet_address, nonce, message, issued_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, c.ID, merchantID, c.WalletAddress, c.Nonce, c.Message, c.IssuedAt, c.ExpiresAt); err != nil {
+		serverErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// WalletVerifyHandler godoc
+// @Summary      Prove control of the merchant wallet
+// @Description  Checks a signature of a challenge from /merchants/wallet/challenge, as an EIP-191 personal message or as EIP-712 typed data. When it was made by the wallet's key the wallet is verified and payouts held for it go out. The challenge is used up; it fails with 409 once expired, used, or when the wallet changed since it was issued. Primary API key only.
+// @Tags         merchants
+// @Accept       json
+// @Produce      json
+// @Param        proof  body  walletVerifyReq  true  "Challenge and signature"
+// @Success      200  {object}  walletStatus
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /merchants/wallet/verify [post]
+func WalletVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "wallet ownership can only be proved with the primary merchant API key")
+		return
+	}
+	var req walletVerifyReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	sig, err := hexutil.Decode(strings.TrimSpace(req.Signature))
+	if req.ChallengeID == "" || err != nil {
+		badReq(w, "challenge_id and signature (0x-prefixed hex) are required")
+		return
+	}
+	ctx := r.Context()
+	merchantID := merchantIDFromContext(ctx)
+
+	var wallet, nonce, message, issuedAt, expiresAt, current string
+	var usedAt sql.NullString
+	err = db.QueryRowContext(ctx, `
+		SELECT c.wallet_address, c.nonce, c.message, c.issued_at, c.expires_at, c.used_at, COALESCE(m.merchant_wallet_address, '')
+		FROM wallet_challenges c JOIN merchants m ON m.id = c.merchant_id
+		WHERE c.id = ? AND c.merchant_id = ?
+	`, req.ChallengeID, merchantID).Scan(&wallet, &nonce, &message, &issuedAt, &expiresAt, &usedAt, &current)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeProblem(w, http.StatusNotFound, CodeWalletChallengeNotFound, "")
+		return
+	}
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	now := time.Now().UTC()
+	switch {
+	case usedAt.Valid:
+		writeProblem(w, http.StatusConflict, CodeWalletChallengeExpired, "the challenge was already used; request a new one")
+		return
+	case now.Format(time.RFC3339) > expiresAt:
+		writeProblem(w, http.StatusConflict, CodeWalletChallengeExpired, "the challenge expired at "+expiresAt+"; request a new one")
+		return
+	case !strings.EqualFold(wallet, current):
+		writeProblem(w, http.StatusConflict, CodeWalletChallengeExpired, "the wallet changed since the challenge was issued; request a new one")
+		return
+	}
+
+	// The signature may cover either form of the challenge
+	method := ""
+	digests := map[string][]byte{
+		"eip191": blockchain.PersonalMessageHash([]byte(message)),
+		"eip712": walletProofTypedHash(merchantID, wallet, nonce, issuedAt),
+	}
+	for _, m := range []string{"eip191", "eip712"} {
+		if signer, err := blockchain.RecoverSigner(digests[m], sig); err == nil && strings.EqualFold(signer.Hex(), wallet) {
+			method = m
+			break
+		}
+	}
+	if method == "" {
+		writeProblem(w, http.StatusUnprocessableEntity, CodeWalletProofInvalid, "the signature was not made by "+wallet+" over the challenge's message or typed data")
+		return
+	}
+
+	verifiedAt := now.Format(time.RFC3339)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `UPDATE wallet_challenges SET used_at = ? WHERE id = ? AND used_at IS NULL`, verifiedAt, req.ChallengeID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeProblem(w, http.StatusConflict, CodeWalletChallengeExpired, "the challenge was already used; request a new one")
+		return
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE merchants SET wallet_verified_address = ?, wallet_verified_at = ? WHERE id = ?
+	`, wallet, verifiedAt, merchantID); err != nil {
+		serverErr(w, err)
+		return
+	}
+	recordAudit(ctx, tx, actorFromContext(ctx), merchantID, "", "merchant.wallet_verified", map[string]string{"wallet_address": wallet, "method": method})
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	log.Printf("event=merchant_wallet_verified merchant_id=%s wallet=%s method=%s", merchantID, wallet, method)
+	st, err := loadWalletStatus(ctx, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	st.Method = method
+	writeJSON(w, http.StatusOK, st)
+}
+
+// holdUnprovenPayouts keeps queued payouts whose destination the merchant has not proved control
+// of, noting why on the payout, and returns the rest.
+func holdUnprovenPayouts(ctx context.Context, open []payoutRecord) []payoutRecord {
+	if !walletProofRequired {
+		return open
+	}
+	proven := map[string]string{} // merchant -> verified wallet
+	ready := open[:0]
+	for _, p := range open {
+		if p.Status != payoutQueued {
+			ready = append(ready, p)
+			continue
+		}
+		wallet, ok := proven[p.MerchantID]
+		if !ok {
+			var v sql.NullString
+			if err := db.QueryRowContext(ctx, `SELECT wallet_verified_address FROM merchants WHERE id = ?`, p.MerchantID).Scan(&v); err != nil {
+				continue
+			}
+			wallet = v.String
+			proven[p.MerchantID] = wallet
+		}
+		if wallet != "" && strings.EqualFold(wallet, p.ToAddress) {
+			ready = append(ready, p)
+			continue
+		}
+		msg := "waiting for the merchant to prove control of " + p.ToAddress + " (POST /v1/merchants/wallet/challenge)"
+		_, _ = db.ExecContext(ctx, `
+			UPDATE payouts SET last_error = ?, updated_at = ? WHERE id = ? AND COALESCE(last_error, '') != ?
+		`, msg, time.Now().UTC().Format(time.RFC3339), p.ID, msg)
+	}
+	return ready
+}
@@ -0,0 +1,762 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/secrets"
+	"github.com/oxzoid/OSPay/pkg/store"
+)
+
+// Webhook deliveries are signed like pkg/client.VerifyWebhook expects: the signature header is
+// "t=<unix seconds>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>". During a secret rotation's grace
+// period it carries one v1 per valid secret, newest first, and the key version header lists their
+// versions in the same order.
+const (
+	webhookSignatureHeader  = "X-OSPay-Signature"
+	webhookEventHeader      = "X-OSPay-Event"
+	webhookDeliveryHeader   = "X-OSPay-Delivery"
+	webhookKeyVersionHeader = "X-OSPay-Key-Version"
+)
+
+// Rotating the webhook secret keeps the previous one signing for a grace period, so receivers can
+// switch secrets without rejecting deliveries.
+const (
+	defaultWebhookRotationGrace = 24 * time.Hour
+	maxWebhookRotationGrace     = 7 * 24 * time.Hour
+)
+
+// minWebhookSecretLen is the shortest secret accepted for an order's webhook override.
+const minWebhookSecretLen = 16
+
+// webhookTimeout bounds one delivery attempt, including reading the receiver's response.
+const webhookTimeout = 10 * time.Second
+
+// webhookHTTPClient delivers to URLs merchants choose. It only connects to public addresses,
+// checked on the address actually dialed so a name resolving to an internal host is caught too, and
+// does not follow redirects, which could otherwise send the request on to such a host.
+var webhookHTTPClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 5 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// webhookPrivateNetworks lets webhooks reach loopback and private addresses, for development.
+var webhookPrivateNetworks bool
+
+// SetWebhookPrivateNetworks allows or refuses webhook URLs on loopback, private and link-local
+// addresses. They are refused by default: the server could otherwise be made to call its own
+// network, cloud metadata endpoints included.
+func SetWebhookPrivateNetworks(allow bool) { webhookPrivateNetworks = allow }
+
+// nonPublicPrefixes are ranges netip does not classify as private but that are not on the public
+// internet either.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, and some clouds' metadata services
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// publicAddr reports whether webhooks may be sent to a.
+func publicAddr(a netip.Addr) bool {
+	if webhookPrivateNetworks {
+		return true
+	}
+	a = a.Unmap()
+	if !a.IsGlobalUnicast() || a.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// errWebhookAddress is returned for webhook connections to a non-public address.
+var errWebhookAddress = errors.New("webhook URL resolves to a non-public address")
+
+// dialPublicOnly refuses connections of webhookHTTPClient to non-public addresses.
+func dialPublicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	a, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(a) {
+		return errWebhookAddress
+	}
+	return nil
+}
+
+// webhookEvent is the envelope POSTed to a merchant's webhook URL.
+// Sequence numbers an aggregate's events from 1 in the order they happened; a receiver that has
+// processed an aggregate's sequence n can discard anything at or below n as a duplicate or stale.
+type webhookEvent struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	CreatedAt     string          `json:"created_at"`
+	AggregateType string          `json:"aggregate_type,omitempty"` // order | refund | dispute | ...
+	AggregateID   string          `json:"aggregate_id,omitempty"`
+	Sequence      int64           `json:"sequence,omitempty"`
+	Test          bool            `json:"test,omitempty"`      // sample events from POST /webhooks/test
+	ReplayOf      string          `json:"replay_of,omitempty"` // id of the original event, on re-deliveries from POST /events/replay; the sequence is the original's
+	Data          json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// webhookConfig is a merchant's webhook endpoint. Secret is only returned when it is generated,
+// which happens the first time a URL is set. Events lists the subscribed event types; ["*"] means
+// all of them.
+type webhookConfig struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+// webhookConfigReq changes a webhook config; omitted fields keep their value.
+type webhookConfigReq struct {
+	URL    *string  `json:"url,omitempty" validate:"max=2000"` // empty string disables delivery
+	Events []string `json:"events,omitempty"`                  // event types, or ["*"] for all
+}
+
+// webhookSettings is a merchant's stored webhook configuration. A nil Events subscribes to all
+// event types. PreviousSecret is only set while a rotation's grace period lasts.
+type webhookSettings struct {
+	URL            string
+	Secret         string
+	Version        int
+	PreviousSecret string
+	Events         []string
+}
+
+func (s webhookSettings) subscribed(eventType string) bool {
+	if s.Events == nil {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhook returns the signature header value for body sent at ts, with a v1 signature per
+// secret.
+func signWebhook(ts time.Time, body []byte, keys ...string) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	sig := "t=" + t
+	for _, secret := range keys {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(t + "."))
+		mac.Write(body)
+		sig += ",v1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return sig
+}
+
+// signingKeys returns the secrets deliveries are signed with and their versions, newest first.
+func (s webhookSettings) signingKeys() ([]string, string) {
+	if s.PreviousSecret == "" {
+		return []string{s.Secret}, strconv.Itoa(s.Version)
+	}
+	return []string{s.Secret, s.PreviousSecret}, strconv.Itoa(s.Version) + "," + strconv.Itoa(s.Version-1)
+}
+
+// webhookResult is the outcome of one delivery attempt.
+type webhookResult struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMS  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Success reports whether the receiver acknowledged the delivery with a 2xx.
+func (res webhookResult) Success() bool { return res.StatusCode >= 200 && res.StatusCode < 300 }
+
+// postWebhook signs and sends one event to cfg's URL. Transport errors are returned in the result,
+// not as err.
+func postWebhook(ctx context.Context, cfg webhookSettings, ev webhookEvent) webhookResult {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return webhookResult{Error: err.Error()}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return webhookResult{Error: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OSPay-Webhooks/1.0")
+	req.Header.Set(webhookEventHeader, ev.Type)
+	req.Header.Set(webhookDeliveryHeader, ev.ID)
+	keys, versions := cfg.signingKeys()
+	req.Header.Set(webhookSignatureHeader, signWebhook(time.Now(), body, keys...))
+	req.Header.Set(webhookKeyVersionHeader, versions)
+
+	start := time.Now()
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return webhookResult{LatencyMS: time.Since(start).Milliseconds(), Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1024)) // lets the connection be reused
+	return webhookResult{StatusCode: resp.StatusCode, LatencyMS: time.Since(start).Milliseconds()}
+}
+
+// loadWebhook returns the merchant's webhook settings; URL is empty when none is configured.
+func loadWebhook(ctx context.Context, q queryer, merchantID string) (webhookSettings, error) {
+	var u, events, previousExpires sql.NullString
+	var secret, previous secrets.EncryptedString
+	s := webhookSettings{}
+	err := q.QueryRowContext(ctx, `
+		SELECT webhook_url, webhook_secret, webhook_secret_version, webhook_previous_secret, webhook_previous_secret_expires_at, webhook_events
+		FROM merchants WHERE id = ?
+	`, merchantID).Scan(&u, &secret, &s.Version, &previous, &previousExpires, &events)
+	s.URL, s.Secret = u.String, secret.String
+	if previous.Valid && previousExpires.String > time.Now().UTC().Format(time.RFC3339) {
+		s.PreviousSecret = previous.String
+	}
+	if events.Valid {
+		s.Events = []string{}
+		if events.String != "" {
+			s.Events = strings.Split(events.String, ",")
+		}
+	}
+	return s, err
+}
+
+// loadOrderWebhook returns the webhook an order was created with; URL is empty when it has none.
+// Its secret signs as version 1.
+func loadOrderWebhook(ctx context.Context, db *sql.DB, orderID string) (webhookSettings, error) {
+	var u sql.NullString
+	var secret secrets.EncryptedString
+	err := db.QueryRowContext(ctx, `
+		SELECT webhook_url, webhook_secret FROM orders WHERE id = ?
+		UNION ALL
+		SELECT webhook_url, webhook_secret FROM orders_archive WHERE id = ?
+		LIMIT 1
+	`, orderID, orderID).Scan(&u, &secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookSettings{}, nil
+	}
+	return webhookSettings{URL: u.String, Secret: secret.String, Version: 1}, err
+}
+
+// validWebhookURL reports whether s is an absolute http(s) URL whose host is not a non-public IP
+// address or localhost. Names are only resolved when delivering, by dialPublicOnly.
+func validWebhookURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if a, err := netip.ParseAddr(host); err == nil {
+		return publicAddr(a)
+	}
+	return webhookPrivateNetworks || (host != "localhost" && !strings.HasSuffix(host, ".localhost"))
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// WebhookConfigHandler godoc
+// @Summary      Get or set the webhook endpoint
+// @Description  POST sets the URL events are delivered to (an empty url disables delivery) and the event types to receive: order.paid, order.in_review, order.mispaid, order.failed, order.expired, order.settled, refund.requested, refund.completed, refund.rejected, dispute.opened, dispute.resolved, verification.failed, or ["*"] for all (the default). A signing secret is generated the first time a URL is set and only returned in that response. Requires the primary API key.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        config  body  webhookConfigReq  false  "Fields to change (POST only)"
+// @Success      200  {object}  webhookConfig
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /webhooks [get]
+// @Router       /webhooks [post]
+func WebhookConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "webhooks can only be configured with the primary merchant API key")
+		return
+	}
+	merchantID := merchantIDFromContext(r.Context())
+	cfg, err := loadWebhook(r.Context(), db, merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeProblem(w, http.StatusNotFound, CodeMerchantNotFound, "")
+			return
+		}
+		serverErr(w, err)
+		return
+	}
+
+	var newSecret bool
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req webhookConfigReq
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		if req.URL != nil {
+			if *req.URL != "" && !validWebhookURL(*req.URL) {
+				writeProblem(w, http.StatusBadRequest, CodeInvalidWebhookURL, "url must be an absolute http(s) URL of a public host")
+				return
+			}
+			cfg.URL = *req.URL
+		}
+		if req.Events != nil {
+			events, ok := normalizeEventTypes(req.Events)
+			if !ok {
+				writeProblem(w, http.StatusBadRequest, CodeInvalidEventType, "events must be known event types or [\"*\"]")
+				return
+			}
+			cfg.Events = events
+		}
+		if cfg.Secret == "" && cfg.URL != "" {
+			if cfg.Secret, err = newWebhookSecret(); err != nil {
+				serverErr(w, err)
+				return
+			}
+			newSecret = true
+		}
+		var events sql.NullString
+		if cfg.Events != nil {
+			events = sql.NullString{String: strings.Join(cfg.Events, ","), Valid: true}
+		}
+		if _, err := db.ExecContext(r.Context(), `UPDATE merchants SET webhook_url = ?, webhook_secret = ?, webhook_events = ? WHERE id = ?`,
+			optionalString(cfg.URL), secrets.EncryptedString{String: cfg.Secret, Valid: cfg.Secret != ""}, events, merchantID); err != nil {
+			serverErr(w, err)
+			return
+		}
+		recordAudit(r.Context(), db, actorFromContext(r.Context()), merchantID, "", "webhook_updated", map[string]any{"url": cfg.URL, "events": cfg.Events})
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	resp := webhookConfig{URL: cfg.URL, Events: cfg.Events}
+	if resp.Events == nil {
+		resp.Events = []string{"*"}
+	}
+	if newSecret {
+		resp.Secret = cfg.Secret
+		noStore(w)
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+type webhookSecretRotateReq struct {
+	GracePeriodHours *int `json:"grace_period_hours,omitempty"` // how long the previous secret keeps signing; defaults to 24, at most 168, 0 retires it at once
+}
+
+type webhookSecretRotateResp struct {
+	Secret                  string `json:"secret"`
+	Version                 int    `json:"version"`
+	PreviousVersion         int    `json:"previous_version"`
+	PreviousSecretExpiresAt string `json:"previous_secret_expires_at"`
+}
+
+// WebhookSecretRotateHandler godoc
+// @Summary      Rotate the webhook signing secret
+// @Description  Generates a new signing secret and returns it; it is not shown again. Until previous_secret_expires_at (grace_period_hours, default 24, at most 168) deliveries are signed with both the new and the previous secret, so a receiver verifying either keeps accepting them while it switches. X-OSPay-Key-Version names the versions that signed a delivery, newest first, in the order of the v1 signatures. Rotating again within a grace period retires the oldest secret immediately. Requires the primary API key.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        request  body  webhookSecretRotateReq  false  "Grace period"
+// @Success      200  {object}  webhookSecretRotateResp
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /webhooks/secret/rotate [post]
+func WebhookSecretRotateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	if credentialFromContext(r.Context()) != primaryCredential {
+		writeProblem(w, http.StatusForbidden, CodePrimaryKeyRequired, "webhook secrets can only be rotated with the primary merchant API key")
+		return
+	}
+	var req webhookSecretRotateReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	grace := defaultWebhookRotationGrace
+	if req.GracePeriodHours != nil {
+		grace = time.Duration(*req.GracePeriodHours) * time.Hour
+		if grace < 0 || grace > maxWebhookRotationGrace {
+			badReq(w, "grace_period_hours must be between 0 and 168")
+			return
+		}
+	}
+	merchantID := merchantIDFromContext(r.Context())
+	cfg, err := loadWebhook(r.Context(), db, merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeProblem(w, http.StatusNotFound, CodeMerchantNotFound, "")
+			return
+		}
+		serverErr(w, err)
+		return
+	}
+	if cfg.Secret == "" {
+		writeProblem(w, http.StatusConflict, CodeWebhookNotConfigured, "set a webhook URL first")
+		return
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp := webhookSecretRotateResp{Secret: secret, PreviousSecretExpiresAt: time.Now().UTC().Add(grace).Format(time.RFC3339)}
+	tx, err := db.BeginTx(r.Context(), &sql.TxOptions{})
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+	// The previous secret is copied in SQL rather than from cfg, so concurrent rotations cannot drop
+	// a secret a receiver may still be using.
+	if _, err := tx.ExecContext(r.Context(), `
+		UPDATE merchants SET webhook_previous_secret = webhook_secret, webhook_previous_secret_expires_at = ?,
+		    webhook_secret = ?, webhook_secret_version = webhook_secret_version + 1
+		WHERE id = ?
+	`, resp.PreviousSecretExpiresAt, secrets.EncryptedString{String: secret, Valid: true}, merchantID); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.QueryRowContext(r.Context(), `SELECT webhook_secret_version FROM merchants WHERE id = ?`, merchantID).Scan(&resp.Version); err != nil {
+		serverErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverErr(w, err)
+		return
+	}
+	resp.PreviousVersion = resp.Version - 1
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), merchantID, "", "webhook_secret_rotated",
+		map[string]any{"version": resp.Version, "previous_secret_expires_at": resp.PreviousSecretExpiresAt})
+	noStore(w)
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// normalizeEventTypes validates a subscription list. ["*"] subscribes to everything and is stored
+// as nil; duplicates are dropped.
+func normalizeEventTypes(in []string) ([]string, bool) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, e := range in {
+		e = strings.TrimSpace(e)
+		if e == "*" {
+			if len(in) != 1 {
+				return nil, false
+			}
+			return nil, true
+		}
+		if !isWebhookEventType(e) {
+			return nil, false
+		}
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return out, true
+}
+
+type webhookTestReq struct {
+	EventType string `json:"event_type,omitempty"` // defaults to order.paid
+}
+
+type webhookTestResp struct {
+	URL       string `json:"url"`
+	EventID   string `json:"event_id"`
+	EventType string `json:"event_type"`
+	Success   bool   `json:"success"`
+	webhookResult
+}
+
+// WebhookTestHandler godoc
+// @Summary      Send a test webhook
+// @Description  Sends a signed sample event (marked "test": true) to the configured webhook URL and reports the receiver's status code and latency, not its body. Redirects are not followed, and URLs resolving to loopback, private or link-local addresses are refused. Nothing is stored or retried.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        request  body  webhookTestReq  false  "Event type to sample"
+// @Success      200  {object}  webhookTestResp
+// @Failure      400  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /webhooks/test [post]
+func WebhookTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	var req webhookTestReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.EventType == "" {
+		req.EventType = "order.paid"
+	}
+	merchantID := merchantIDFromContext(r.Context())
+	data, ok := sampleEventData(req.EventType, merchantID)
+	if !ok {
+		writeProblem(w, http.StatusBadRequest, CodeInvalidEventType, "unknown event_type")
+		return
+	}
+	cfg, err := loadWebhook(r.Context(), db, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if cfg.URL == "" {
+		writeProblem(w, http.StatusConflict, CodeWebhookNotConfigured, "set a webhook URL first")
+		return
+	}
+
+	ev := webhookEvent{
+		ID:        "evt_test_" + uuid.New().String(),
+		Type:      req.EventType,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Test:      true,
+		Data:      data,
+	}
+	res := postWebhook(r.Context(), cfg, ev)
+	writeJSONOrders(w, http.StatusOK, webhookTestResp{URL: cfg.URL, EventID: ev.ID, EventType: ev.Type, Success: res.Success(), webhookResult: res})
+}
+
+type eventReplayReq struct {
+	OrderID string `json:"order_id,omitempty"`
+	From    string `json:"from,omitempty"` // RFC 3339, inclusive
+	To      string `json:"to,omitempty"`   // RFC 3339, exclusive; defaults to now
+}
+
+type eventReplayResp struct {
+	Replayed int    `json:"replayed"`
+	OrderID  string `json:"order_id,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+}
+
+// ReplayEventsHandler godoc
+// @Summary      Replay webhook events
+// @Description  Queues every event already sent (or skipped or failed) for an order, a time window, or both for delivery again, e.g. after the receiver was down. Each copy gets a new id and carries "replay_of" with the original event id; subscriptions apply as for new events. At most 1000 events per request.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        request  body  eventReplayReq  true  "order_id and/or from, to"
+// @Success      202  {object}  eventReplayResp
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Security     ApiKeyAuth
+// @Router       /events/replay [post]
+func ReplayEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+		return
+	}
+	var req eventReplayReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.OrderID == "" && req.From == "" {
+		writeProblem(w, http.StatusBadRequest, CodeMissingFields, "order_id or from is required")
+		return
+	}
+	f := replayFilter{OrderID: req.OrderID}
+	var from, to time.Time
+	var err error
+	if req.From != "" {
+		if from, err = time.Parse(time.RFC3339, req.From); err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "from must be an RFC 3339 timestamp")
+			return
+		}
+		f.From = from.UTC().Format(time.RFC3339)
+	}
+	if req.To != "" {
+		if to, err = time.Parse(time.RFC3339, req.To); err != nil {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be an RFC 3339 timestamp")
+			return
+		}
+		if !from.IsZero() && !to.After(from) {
+			writeProblem(w, http.StatusBadRequest, CodeInvalidTimeRange, "to must be after from")
+			return
+		}
+		f.To = to.UTC().Format(time.RFC3339)
+	}
+
+	merchantID := merchantIDFromContext(r.Context())
+	if f.OrderID != "" {
+		if _, err := stores.Orders.Get(r.Context(), f.OrderID, merchantID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				writeProblem(w, http.StatusNotFound, CodeOrderNotFound, "")
+				return
+			}
+			serverErr(w, err)
+			return
+		}
+	}
+	cfg, err := loadWebhook(r.Context(), db, merchantID)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if cfg.URL == "" {
+		writeProblem(w, http.StatusConflict, CodeWebhookNotConfigured, "set a webhook URL first")
+		return
+	}
+
+	n, ok, err := replayEvents(r.Context(), db, merchantID, f)
+	if err != nil {
+		serverErr(w, err)
+		return
+	}
+	if !ok {
+		writeProblem(w, http.StatusBadRequest, CodeLimitExceeded, "more than 1000 events match; narrow the time window")
+		return
+	}
+	recordAudit(r.Context(), db, actorFromContext(r.Context()), merchantID, f.OrderID, "events_replayed", map[string]any{"from": f.From, "to": f.To, "events": n})
+	writeJSONOrders(w, http.StatusAccepted, eventReplayResp{Replayed: n, OrderID: f.OrderID, From: f.From, To: f.To})
+}
+
+// eventTypeInfo is one entry of the event catalog; Schema describes the envelope's data field.
+type eventTypeInfo struct {
+	Type        string         `json:"type"`
+	Version     int            `json:"version"`
+	Description string         `json:"description"`
+	Schema      map[string]any `json:"schema" swaggertype:"object"`
+}
+
+type eventCatalogResp struct {
+	Envelope map[string]any  `json:"envelope" swaggertype:"object"` // schema of the delivered body
+	Types    []eventTypeInfo `json:"types"`
+}
+
+// EventTypesHandler godoc
+// @Summary      List webhook event types
+// @Description  Returns every event type with its payload version and a JSON Schema (draft 2020-12) of its data, generated from the types the server encodes, plus the schema of the delivery envelope. A version changes only when a payload changes incompatibly.
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {object}  eventCatalogResp
+// @Router       /events/types [get]
+func EventTypesHandler(w http.ResponseWriter, r *http.Request) {
+	resp := eventCatalogResp{Envelope: jsonSchema(reflect.TypeOf(webhookEvent{}))}
+	resp.Envelope["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	for _, def := range webhookEvents {
+		schema := jsonSchema(reflect.TypeOf(def.Data))
+		schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+		resp.Types = append(resp.Types, eventTypeInfo{Type: def.Type, Version: def.Version, Description: def.Description, Schema: schema})
+	}
+	writeJSONOrders(w, http.StatusOK, resp)
+}
+
+// sampleEventData returns example data for a test delivery of eventType.
+func sampleEventData(eventType, merchantID string) (json.RawMessage, bool) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	txHash := "0x" + hex.EncodeToString(make([]byte, 32))
+	order := orderGetResp{
+		ID:             "order_test_" + uuid.New().String(),
+		MerchantID:     merchantID,
+		AmountMinor:    "1000000",
+		Asset:          "USDT",
+		Chain:          "BSC",
+		Status:         "PAID",
+		DepositAddress: "0x0000000000000000000000000000000000000000",
+		TxHash:         &txHash,
+		PaidAt:         &now,
+		CreatedAt:      now,
+	}
+	requestedBy := primaryCredential
+	refund := refundRecord{
+		ID: "rfd_test_" + uuid.New().String(), OrderID: order.ID, AmountMinor: "250000",
+		Status: refundStatusCompleted, RequestedBy: &requestedBy, CreatedAt: now,
+	}
+	dispute := disputeRecord{
+		ID: "dsp_test_" + uuid.New().String(), OrderID: order.ID, MerchantID: merchantID, Asset: order.Asset,
+		AmountMinor: order.AmountMinor, Reason: "customer reports goods not received", Status: disputeStatusOpen, CreatedAt: now,
+	}
+	var v any
+	switch eventType {
+	case webhookOrderPaid:
+		v = order
+	case webhookOrderInReview:
+		reason := "sender on denylist"
+		order.Status, order.RiskReason = statusReview, &reason
+		v = order
+	case webhookOrderMispaid:
+		order.Status, order.TxHash, order.PaidAt = statusMispaid, nil, nil
+		order.Mispayment = &mispayment{
+			Reason: attemptWrongToken, Asset: "USDC", Chain: order.Chain, AmountMinor: order.AmountMinor,
+			FromAddress: "0x0000000000000000000000000000000000000000", TxHash: txHash,
+		}
+		v = order
+	case webhookOrderFailed:
+		order.Status, order.TxHash, order.PaidAt = "FAILED", nil, nil
+		v = order
+	case webhookOrderExpired:
+		order.Status, order.TxHash, order.PaidAt = statusExpired, nil, nil
+		v = order
+	case webhookOrderSettled:
+		order.Status = "SETTLED"
+		v = order
+	case webhookRefundRequested:
+		refund.Status = refundStatusRequested
+		v = refund
+	case webhookRefundCompleted:
+		v = refund
+	case webhookRefundRejected:
+		refund.Status = refundStatusRejected
+		v = refund
+	case webhookDisputeOpened:
+		v = dispute
+	case webhookDisputeResolved:
+		dispute.Status, dispute.ResolvedAt = disputeStatusWon, &now
+		v = dispute
+	case webhookVerificationFailed:
+		v = verificationFailedData{OrderID: order.ID, TxHash: txHash, Reason: "transfer not found"}
+	default:
+		return nil, false
+	}
+	b, err := json.Marshal(v)
+	return b, err == nil
+}
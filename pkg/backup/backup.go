@@ -0,0 +1,112 @@
+// Package backup takes consistent snapshots of the live SQLite database and restores them.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite" // SQLite driver
+)
+
+// requiredTables must exist in a snapshot for it to count as an OSPay database.
+var requiredTables = []string{"merchants", "orders", "ledger_entries", "refunds"}
+
+// Snapshot writes a consistent copy of the database to dest with VACUUM INTO, which reads inside
+// a single transaction and so is safe while the server keeps writing. dest must not exist. The
+// snapshot is verified before returning.
+func Snapshot(ctx context.Context, db *sql.DB, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%s already exists", dest)
+	}
+	if dir := filepath.Dir(dest); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, dest); err != nil {
+		return fmt.Errorf("vacuum into %s: %w", dest, err)
+	}
+	if err := Verify(ctx, dest); err != nil {
+		_ = os.Remove(dest)
+		return err
+	}
+	return nil
+}
+
+// Verify opens a snapshot read-only, runs PRAGMA integrity_check and checks the core tables exist.
+func Verify(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	snap, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer snap.Close()
+	var result string
+	if err := snap.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+	for _, table := range requiredTables {
+		var n int
+		if err := snap.QueryRowContext(ctx, `SELECT COUNT(1) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("not an OSPay database: table %s is missing", table)
+		}
+	}
+	return nil
+}
+
+// Restore replaces the database at dest with a verified copy of src. The server must be stopped:
+// the copy is written next to dest and renamed into place, and the previous database is kept as
+// dest+".pre-restore".
+func Restore(ctx context.Context, src, dest string) error {
+	if err := Verify(ctx, src); err != nil {
+		return fmt.Errorf("refusing to restore %s: %w", src, err)
+	}
+	tmp := dest + ".restore-tmp"
+	if err := copyFile(src, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	// Move the current database aside together with its WAL/SHM, so it stays openable and SQLite
+	// does not replay the old WAL onto the restored file
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(dest+suffix, dest+".pre-restore"+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, dest)
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,244 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Address formats, by the chains that use them.
+const (
+	FormatEVM    = "evm"
+	FormatTron   = "tron"
+	FormatSolana = "solana"
+	FormatBTC    = "btc"
+)
+
+var addressFormats = map[string]string{
+	"BSC": FormatEVM, "ETH": FormatEVM, "POLYGON": FormatEVM,
+	"TRON": FormatTron, "SOLANA": FormatSolana, "BTC": FormatBTC,
+}
+
+// AddressFormat returns the address format of chain, or "" for a chain OSPay does not know.
+func AddressFormat(chain string) string {
+	return addressFormats[strings.ToUpper(chain)]
+}
+
+// KnownChains returns the chains OSPay knows the address format of, sorted.
+func KnownChains() []string {
+	return slices.Sorted(maps.Keys(addressFormats))
+}
+
+// NormalizeAddress checks addr against chain's address format and returns it in canonical form:
+// EIP-55 checksummed for EVM chains, lower case for bech32. A mixed-case EVM address must carry a
+// valid checksum. Addresses on chains without a known format are returned as they are.
+func NormalizeAddress(chain, addr string) (string, error) {
+	format := AddressFormat(chain)
+	if format == "" {
+		return strings.TrimSpace(addr), nil
+	}
+	return normalizeAs(format, strings.TrimSpace(addr))
+}
+
+// NormalizeAnyAddress accepts an address in any known format, for wallets not tied to a chain,
+// and reports the format it is in.
+func NormalizeAnyAddress(addr string) (normalized, format string, err error) {
+	addr = strings.TrimSpace(addr)
+	var candidates []string
+	switch {
+	case strings.HasPrefix(addr, "0x") || strings.HasPrefix(addr, "0X"):
+		candidates = []string{FormatEVM}
+	case strings.HasPrefix(strings.ToLower(addr), "bc1"):
+		candidates = []string{FormatBTC}
+	case strings.HasPrefix(addr, "T"):
+		candidates = []string{FormatTron, FormatSolana}
+	default:
+		// Legacy Bitcoin and Solana addresses are both base58
+		candidates = []string{FormatBTC, FormatSolana}
+	}
+	var first error
+	for _, format := range candidates {
+		normalized, err = normalizeAs(format, addr)
+		if err == nil {
+			return normalized, format, nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if len(candidates) == 1 {
+		return "", "", first
+	}
+	// Report why the most likely format was rejected
+	return "", "", errors.New("not an EVM (0x...), Tron (T...), Solana or Bitcoin address: " + first.Error())
+}
+
+func normalizeAs(format, addr string) (string, error) {
+	switch format {
+	case FormatEVM:
+		return normalizeEVM(addr)
+	case FormatTron:
+		payload, err := decodeBase58Check(addr)
+		if err != nil {
+			return "", err
+		}
+		if len(payload) != 21 || payload[0] != 0x41 {
+			return "", errors.New("not a Tron address: expected a T... address of 34 characters")
+		}
+		return addr, nil
+	case FormatSolana:
+		b, err := decodeBase58(addr)
+		if err != nil {
+			return "", err
+		}
+		if len(b) != 32 {
+			return "", fmt.Errorf("not a Solana address: decodes to %d bytes, expected 32", len(b))
+		}
+		return addr, nil
+	case FormatBTC:
+		return normalizeBTC(addr)
+	}
+	return addr, nil
+}
+
+func normalizeEVM(addr string) (string, error) {
+	hex, ok := strings.CutPrefix(addr, "0x")
+	if !ok {
+		hex, ok = strings.CutPrefix(addr, "0X")
+	}
+	if !ok || len(hex) != 40 || !common.IsHexAddress(addr) {
+		return "", errors.New("not an EVM address: expected 0x followed by 40 hex characters")
+	}
+	checksummed := common.HexToAddress(addr).Hex()
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) && "0x"+hex != checksummed {
+		return "", fmt.Errorf("EIP-55 checksum mismatch (a typo?); the checksummed form of this address would be %s", checksummed)
+	}
+	return checksummed, nil
+}
+
+func normalizeBTC(addr string) (string, error) {
+	if strings.HasPrefix(strings.ToLower(addr), "bc1") {
+		if err := checkSegwit(addr); err != nil {
+			return "", err
+		}
+		return strings.ToLower(addr), nil
+	}
+	payload, err := decodeBase58Check(addr)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) != 21 || (payload[0] != 0x00 && payload[0] != 0x05) {
+		return "", errors.New("not a Bitcoin mainnet address")
+	}
+	return addr, nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func decodeBase58(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty address")
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		i := strings.IndexRune(base58Alphabet, c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, radix).Add(n, big.NewInt(int64(i)))
+	}
+	b := n.Bytes()
+	// Each leading '1' stands for a zero byte
+	zeros := len(s) - len(strings.TrimLeft(s, "1"))
+	return append(make([]byte, zeros), b...), nil
+}
+
+// decodeBase58Check decodes a base58 string whose last 4 bytes are the double-SHA256 checksum of
+// the rest, and returns the rest.
+func decodeBase58Check(s string) ([]byte, error) {
+	b, err := decodeBase58(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 5 {
+		return nil, errors.New("address too short")
+	}
+	payload, sum := b[:len(b)-4], b[len(b)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if string(second[:4]) != string(sum) {
+		return nil, errors.New("base58 checksum mismatch (a typo?)")
+	}
+	return payload, nil
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// checkSegwit validates a bc1 address: bech32 (BIP 173) for witness version 0, bech32m (BIP 350)
+// for later versions.
+func checkSegwit(addr string) error {
+	if strings.ToLower(addr) != addr && strings.ToUpper(addr) != addr {
+		return errors.New("bech32 addresses must not mix upper and lower case")
+	}
+	addr = strings.ToLower(addr)
+	sep := strings.LastIndexByte(addr, '1')
+	if len(addr) > 90 || sep < 1 || sep+7 > len(addr) {
+		return errors.New("malformed bech32 address")
+	}
+	hrp, data := addr[:sep], make([]byte, 0, len(addr)-sep-1)
+	if hrp != "bc" {
+		return errors.New("not a Bitcoin mainnet address")
+	}
+	for _, c := range addr[sep+1:] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return fmt.Errorf("invalid bech32 character %q", c)
+		}
+		data = append(data, byte(i))
+	}
+	values := make([]byte, 0, 2*len(hrp)+1+len(data))
+	for _, c := range []byte(hrp) {
+		values = append(values, c>>5)
+	}
+	values = append(values, 0)
+	for _, c := range []byte(hrp) {
+		values = append(values, c&31)
+	}
+	values = append(values, data...)
+	const bech32Const, bech32mConst = 1, 0x2bc830a3
+	want := uint32(bech32Const)
+	if data[0] != 0 {
+		want = bech32mConst
+	}
+	if bech32Polymod(values) != want {
+		return errors.New("bech32 checksum mismatch (a typo?)")
+	}
+	// The witness program, regrouped from 5-bit to 8-bit, is 20 or 32 bytes for version 0
+	bits := (len(data) - 7) * 5
+	if data[0] > 16 || bits/8 < 2 || bits/8 > 40 || (data[0] == 0 && bits/8 != 20 && bits/8 != 32) {
+		return errors.New("invalid witness program length")
+	}
+	return nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"math/big"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -19,23 +18,86 @@ const (
 	BSC_USD_ADDRESS = "0x55d398326f99059fF775485246999027B3197955" // BSC-USD (BUSD-T)
 )
 
+// limit concurrent RPC verifications to avoid overloading public RPC
+var verifySem = make(chan struct{}, 20)
+
+func getClient() (*ethclient.Client, error) {
+	return Client("BSC")
+}
+
+// Transfer is a decoded BSC-USD Transfer(address,address,uint256) event.
+type Transfer struct {
+	From   common.Address
+	To     common.Address
+	Amount *big.Int
+}
+
+var transferSigHash = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// IsTxHash reports whether s looks like an EVM transaction hash (0x + 64 hex chars).
+func IsTxHash(s string) bool {
+	if len(s) != 66 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	for _, c := range s[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// BSCUSDTransfers returns every BSC-USD transfer in a successful transaction.
+func BSCUSDTransfers(txHash string) ([]Transfer, error) {
+	verifySem <- struct{}{}
+	defer func() { <-verifySem }()
+
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	receipt, err := client.TransactionReceipt(ctx, common.HexToHash(txHash))
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != 1 {
+		return nil, errors.New("transaction reverted")
+	}
+	bscUsdAddr := common.HexToAddress(BSC_USD_ADDRESS)
+	var transfers []Transfer
+	for _, vLog := range receipt.Logs {
+		if vLog.Address == bscUsdAddr && len(vLog.Topics) == 3 && vLog.Topics[0] == transferSigHash {
+			transfers = append(transfers, Transfer{
+				From:   common.HexToAddress(vLog.Topics[1].Hex()),
+				To:     common.HexToAddress(vLog.Topics[2].Hex()),
+				Amount: new(big.Int).SetBytes(vLog.Data),
+			})
+		}
+	}
+	return transfers, nil
+}
+
+// Why VerifyBSCUSDTransfer matched no transfer in a transaction that it could read.
 var (
-	clientOnce sync.Once
-	bscClient  *ethclient.Client
-	clientErr  error
-	// limit concurrent RPC verifications to avoid overloading public RPC
-	verifySem = make(chan struct{}, 20)
+	ErrWrongAmount    = errors.New("BSC-USD transfer to the recipient is for a different amount")
+	ErrWrongRecipient = errors.New("BSC-USD transfer is to a different address")
+	ErrWrongToken     = errors.New("transfer to the recipient is of a token other than BSC-USD")
+	ErrNoTransfer     = errors.New("transaction has no token transfer")
 )
 
-func getClient() (*ethclient.Client, error) {
-	clientOnce.Do(func() {
-		bscClient, clientErr = ethclient.Dial(BSC_RPC_URL)
-	})
-	return bscClient, clientErr
+// VerifiedTransfer is the transfer VerifyBSCUSDTransfer matched and the block that mined it.
+type VerifiedTransfer struct {
+	From        string // the paying customer's wallet
+	BlockNumber uint64
+	BlockTime   time.Time // zero if the block header could not be read
 }
 
-// VerifyBSCUSDTransfer checks if the given txHash is a BSC-USD transfer to destAddress with the expected amount (in wei)
-func VerifyBSCUSDTransfer(txHash string, destAddress string, expectedAmount *big.Int) (bool, error) {
+// VerifyBSCUSDTransfer checks if the given txHash is a BSC-USD transfer to destAddress with the expected amount (in wei).
+// On success it also returns the sender of the matching transfer, i.e. the paying customer's wallet, and its block.
+// A readable transaction without a match fails with ErrWrongAmount, ErrWrongToken, ErrWrongRecipient or ErrNoTransfer.
+func VerifyBSCUSDTransfer(txHash string, destAddress string, expectedAmount *big.Int) (transfer VerifiedTransfer, ok bool, err error) {
 	// throttle concurrent calls
 	verifySem <- struct{}{}
 	defer func() { <-verifySem }()
@@ -44,7 +106,7 @@ func VerifyBSCUSDTransfer(txHash string, destAddress string, expectedAmount *big
 
 	client, err := getClient()
 	if err != nil {
-		return false, err
+		return VerifiedTransfer{}, false, err
 	}
 
 	hash := common.HexToHash(txHash)
@@ -53,7 +115,7 @@ func VerifyBSCUSDTransfer(txHash string, destAddress string, expectedAmount *big
 	receipt, err := client.TransactionReceipt(ctx, hash)
 	if err != nil {
 		log.Printf("BSC verification: failed to get receipt for %s: %v", txHash, err)
-		return false, err
+		return VerifiedTransfer{}, false, err
 	}
 
 	log.Printf("BSC verification: got receipt with %d logs", len(receipt.Logs))
@@ -63,12 +125,14 @@ func VerifyBSCUSDTransfer(txHash string, destAddress string, expectedAmount *big
 
 	log.Printf("BSC verification: looking for transfers from BSC-USD contract %s to dest %s", bscUsdAddr.Hex(), destAddr.Hex())
 
-	transferSig := []byte("Transfer(address,address,uint256)")
-	transferSigHash := crypto.Keccak256Hash(transferSig)
-
+	failure := ErrNoTransfer
 	for i, vLog := range receipt.Logs {
 		log.Printf("BSC verification: log[%d] address=%s topics=%d", i, vLog.Address.Hex(), len(vLog.Topics))
 
+		if vLog.Address != bscUsdAddr && len(vLog.Topics) == 3 && vLog.Topics[0] == transferSigHash &&
+			common.HexToAddress(vLog.Topics[2].Hex()) == destAddr && failure != ErrWrongAmount {
+			failure = ErrWrongToken
+		}
 		if vLog.Address == bscUsdAddr && len(vLog.Topics) == 3 && vLog.Topics[0] == transferSigHash {
 			to := common.HexToAddress(vLog.Topics[2].Hex())
 			amount := new(big.Int).SetBytes(vLog.Data)
@@ -78,16 +142,27 @@ func VerifyBSCUSDTransfer(txHash string, destAddress string, expectedAmount *big
 			if strings.EqualFold(to.Hex(), destAddr.Hex()) {
 				log.Printf("BSC verification: address matches, comparing amounts: %s vs %s", amount.String(), expectedAmount.String())
 				if amount.Cmp(expectedAmount) == 0 {
-					log.Printf("BSC verification: SUCCESS - amounts match exactly")
-					return true, nil
+					from := common.HexToAddress(vLog.Topics[1].Hex())
+					log.Printf("BSC verification: SUCCESS - amounts match exactly (from=%s)", from.Hex())
+					transfer = VerifiedTransfer{From: from.Hex(), BlockNumber: receipt.BlockNumber.Uint64()}
+					if header, err := client.HeaderByNumber(ctx, receipt.BlockNumber); err != nil {
+						log.Printf("BSC verification: failed to get block %s: %v", receipt.BlockNumber, err)
+					} else {
+						transfer.BlockTime = time.Unix(int64(header.Time), 0).UTC()
+					}
+					return transfer, true, nil
 				} else {
 					log.Printf("BSC verification: FAIL - amount mismatch")
+					failure = ErrWrongAmount
 				}
 			} else {
 				log.Printf("BSC verification: address mismatch: %s vs %s", to.Hex(), destAddr.Hex())
+				if failure == ErrNoTransfer {
+					failure = ErrWrongRecipient
+				}
 			}
 		}
 	}
-	log.Printf("BSC verification: no matching BSC-USD transfer found")
-	return false, errors.New("no matching BSC-USD transfer found")
+	log.Printf("BSC verification: no matching BSC-USD transfer found: %v", failure)
+	return VerifiedTransfer{}, false, failure
 }
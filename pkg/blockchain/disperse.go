@@ -0,0 +1,110 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// MaxDisperseRecipients caps the transfers in one multi-send so its gas stays well under the block
+// limit.
+const MaxDisperseRecipients = 200
+
+// DisperseGasLimit is the gas limit of a multi-send to n recipients. It leaves room above the
+// GasBatchPerTransfer estimate for recipients that never held the token; unused gas is not charged.
+func DisperseGasLimit(n int) uint64 {
+	return uint64(GasBatchBase + n*55_000)
+}
+
+// Disperse (disperse.app) contracts per chain. disperseToken pulls the total from the sender with
+// transferFrom, so the hot wallet must have approved the contract for the token first.
+var (
+	disperseMu        sync.Mutex
+	disperseContracts = map[string]common.Address{
+		"ETH":     common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150"),
+		"BSC":     common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150"),
+		"POLYGON": common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150"),
+	}
+)
+
+var (
+	disperseTokenSelector = crypto.Keccak256([]byte("disperseToken(address,address[],uint256[])"))[:4]
+	approveSelector       = crypto.Keccak256([]byte("approve(address,uint256)"))[:4]
+	allowanceSelector     = crypto.Keccak256([]byte("allowance(address,address)"))[:4]
+)
+
+// MaxAllowance is the unlimited ERC-20 approval.
+var MaxAllowance = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// SetDisperseContract points chain's multi-sends at a Disperse-compatible contract; the zero
+// address turns multi-sends off on the chain.
+func SetDisperseContract(chain string, addr common.Address) {
+	disperseMu.Lock()
+	defer disperseMu.Unlock()
+	if addr == (common.Address{}) {
+		delete(disperseContracts, strings.ToUpper(chain))
+		return
+	}
+	disperseContracts[strings.ToUpper(chain)] = addr
+}
+
+// DisperseContract returns chain's multi-send contract, if it has one.
+func DisperseContract(chain string) (common.Address, bool) {
+	disperseMu.Lock()
+	defer disperseMu.Unlock()
+	addr, ok := disperseContracts[strings.ToUpper(chain)]
+	return addr, ok
+}
+
+// DisperseTokenData is the calldata of disperseToken(token, recipients, values), paying each
+// recipient its amount of token in one call.
+func DisperseTokenData(token common.Address, recipients []common.Address, amounts []*big.Int) []byte {
+	word := func(b []byte) []byte { return common.LeftPadBytes(b, 32) }
+	n := int64(len(recipients))
+	data := append([]byte{}, disperseTokenSelector...)
+	data = append(data, word(token.Bytes())...)
+	// Offsets of the two dynamic arrays, counted from the start of the arguments.
+	data = append(data, word(big.NewInt(3*32).Bytes())...)
+	data = append(data, word(big.NewInt(4*32+n*32).Bytes())...)
+	data = append(data, word(big.NewInt(n).Bytes())...)
+	for _, r := range recipients {
+		data = append(data, word(r.Bytes())...)
+	}
+	data = append(data, word(big.NewInt(n).Bytes())...)
+	for _, a := range amounts {
+		data = append(data, word(a.Bytes())...)
+	}
+	return data
+}
+
+// ApproveData is the calldata of an ERC-20 approve(spender, amount).
+func ApproveData(spender common.Address, amount *big.Int) []byte {
+	data := append([]byte{}, approveSelector...)
+	data = append(data, common.LeftPadBytes(spender.Bytes(), 32)...)
+	return append(data, common.LeftPadBytes(amount.Bytes(), 32)...)
+}
+
+// Allowance returns how much of token spender may still move for owner.
+func Allowance(ctx context.Context, chain string, token, owner, spender common.Address) (*big.Int, error) {
+	client, err := Client(chain)
+	if err != nil {
+		return nil, err
+	}
+	data := append([]byte{}, allowanceSelector...)
+	data = append(data, common.LeftPadBytes(owner.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(spender.Bytes(), 32)...)
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) < 32 {
+		return nil, errors.New("allowance: short return data")
+	}
+	return new(big.Int).SetBytes(out[:32]), nil
+}
@@ -0,0 +1,89 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ensRegistry is the ENS registry on Ethereum mainnet.
+var ensRegistry = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+var (
+	ensResolverSelector = crypto.Keccak256([]byte("resolver(bytes32)"))[:4]
+	ensAddrSelector     = crypto.Keccak256([]byte("addr(bytes32)"))[:4]
+)
+
+// ErrENSNotFound is returned for an ENS name without a resolver or without an address set.
+var ErrENSNotFound = errors.New("ENS name has no address")
+
+// IsENSName reports whether s looks like an ENS name (e.g. "shop.eth") rather than an address.
+// None of the supported address formats contain a dot.
+func IsENSName(s string) bool {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, ".") || strings.ContainsAny(s, " /:@") {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeENSName lower-cases name. Full UTS-46 normalization is left to the merchant's wallet
+// software; names are expected in the form they were registered in.
+func NormalizeENSName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// namehash is the ENS node of name (EIP-137).
+func namehash(name string) []byte {
+	node := make([]byte, 32)
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256(node, crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// ResolveENS looks up the Ethereum address name points to, through the registry and the name's
+// resolver on ETH. It needs an RPC endpoint for ETH.
+func ResolveENS(ctx context.Context, name string) (common.Address, error) {
+	client, err := Client("ETH")
+	if err != nil {
+		return common.Address{}, err
+	}
+	node := namehash(NormalizeENSName(name))
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &ensRegistry, Data: append(append([]byte{}, ensResolverSelector...), node...)}, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(out) < 32 {
+		return common.Address{}, errors.New("resolver: short return data")
+	}
+	resolver := common.BytesToAddress(out[:32])
+	if resolver == (common.Address{}) {
+		return common.Address{}, ErrENSNotFound
+	}
+	out, err = client.CallContract(ctx, ethereum.CallMsg{To: &resolver, Data: append(append([]byte{}, ensAddrSelector...), node...)}, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(out) < 32 {
+		return common.Address{}, errors.New("addr: short return data")
+	}
+	addr := common.BytesToAddress(out[:32])
+	if addr == (common.Address{}) {
+		return common.Address{}, ErrENSNotFound
+	}
+	return addr, nil
+}
@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Urgency selects the fee profile of an outgoing transaction.
+type Urgency string
+
+const (
+	UrgencySlow     Urgency = "slow"
+	UrgencyStandard Urgency = "standard"
+	UrgencyFast     Urgency = "fast"
+)
+
+// FeeProfile turns a gas quote into the fees of an outgoing transaction. On EIP-1559 chains the
+// priority fee is TipPercent of the node's suggested tip and maxFeePerGas leaves room for the base
+// fee to grow by BaseFeeMultiplier; on legacy chains the gas price is TipPercent of the suggested
+// price. A transaction still pending after BumpAfter is replaced with higher fees.
+type FeeProfile struct {
+	TipPercent        int64
+	BaseFeeMultiplier int64
+	BumpAfter         time.Duration
+}
+
+var (
+	feeMu       sync.Mutex
+	feeProfiles = map[Urgency]FeeProfile{
+		UrgencySlow:     {TipPercent: 80, BaseFeeMultiplier: 2, BumpAfter: 15 * time.Minute},
+		UrgencyStandard: {TipPercent: 100, BaseFeeMultiplier: 2, BumpAfter: 5 * time.Minute},
+		UrgencyFast:     {TipPercent: 150, BaseFeeMultiplier: 3, BumpAfter: 2 * time.Minute},
+	}
+)
+
+// SetFeeProfile replaces (or adds) the profile for u.
+func SetFeeProfile(u Urgency, p FeeProfile) {
+	feeMu.Lock()
+	defer feeMu.Unlock()
+	feeProfiles[u] = p
+}
+
+// Profile returns the fee profile for u.
+func Profile(u Urgency) (FeeProfile, bool) {
+	feeMu.Lock()
+	defer feeMu.Unlock()
+	p, ok := feeProfiles[u]
+	return p, ok
+}
+
+// Fees are the gas fees of a transaction in wei. For a legacy transaction MaxFee is the gas price
+// and TipCap is nil.
+type Fees struct {
+	MaxFee *big.Int
+	TipCap *big.Int
+}
+
+// Legacy reports whether the fees are for a pre-EIP-1559 transaction.
+func (f Fees) Legacy() bool { return f.TipCap == nil }
+
+// SuggestFees prices an outgoing transaction on chain with u's profile.
+func SuggestFees(ctx context.Context, chain string, u Urgency) (Fees, error) {
+	p, ok := Profile(u)
+	if !ok {
+		p, _ = Profile(UrgencyStandard)
+	}
+	q, err := SuggestGas(ctx, chain)
+	if err != nil {
+		return Fees{}, err
+	}
+	if q.BaseFee == nil || q.PriorityFee == nil {
+		return Fees{MaxFee: percent(q.GasPrice, p.TipPercent)}, nil
+	}
+	tip := percent(q.PriorityFee, p.TipPercent)
+	maxFee := new(big.Int).Mul(q.BaseFee, big.NewInt(p.BaseFeeMultiplier))
+	return Fees{MaxFee: maxFee.Add(maxFee, tip), TipCap: tip}, nil
+}
+
+// minBumpPercent is how much a replacement must raise both fees for nodes to accept it in place
+// of the pending transaction (geth requires 10%).
+const minBumpPercent = 112
+
+// BumpFees returns the fees for replacing a transaction sent with prev: the current suggestion
+// next, raised where needed to at least 12% above prev.
+func BumpFees(prev, next Fees) Fees {
+	out := Fees{MaxFee: maxBig(next.MaxFee, percent(prev.MaxFee, minBumpPercent))}
+	if !prev.Legacy() || !next.Legacy() {
+		prevTip := prev.TipCap
+		if prevTip == nil {
+			prevTip = prev.MaxFee
+		}
+		nextTip := next.TipCap
+		if nextTip == nil {
+			nextTip = next.MaxFee
+		}
+		out.TipCap = maxBig(nextTip, percent(prevTip, minBumpPercent))
+		if out.TipCap.Cmp(out.MaxFee) > 0 {
+			out.MaxFee = new(big.Int).Set(out.TipCap)
+		}
+	}
+	return out
+}
+
+// percent returns v*p/100, rounded up so small values still grow.
+func percent(v *big.Int, p int64) *big.Int {
+	n := new(big.Int).Mul(v, big.NewInt(p))
+	n.Add(n, big.NewInt(99))
+	return n.Div(n, big.NewInt(100))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
@@ -0,0 +1,38 @@
+package blockchain
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+var (
+	finalityMu sync.Mutex
+	// finalityDepths is how many confirmations a payment needs before it is credited, the block
+	// that mined it included.
+	finalityDepths = map[string]int{"BSC": 15, "ETH": 12, "POLYGON": 128}
+)
+
+// SetFinalityDepth sets the confirmations payments on chain wait for; 0 or 1 credits them as
+// soon as they are mined.
+func SetFinalityDepth(chain string, depth int) {
+	finalityMu.Lock()
+	defer finalityMu.Unlock()
+	finalityDepths[strings.ToUpper(chain)] = depth
+}
+
+// FinalityDepth returns the confirmations payments on chain wait for.
+func FinalityDepth(chain string) int {
+	finalityMu.Lock()
+	defer finalityMu.Unlock()
+	return finalityDepths[strings.ToUpper(chain)]
+}
+
+// HeadBlock returns the number of chain's latest block.
+func HeadBlock(ctx context.Context, chain string) (uint64, error) {
+	client, err := Client(chain)
+	if err != nil {
+		return 0, err
+	}
+	return client.BlockNumber(ctx)
+}
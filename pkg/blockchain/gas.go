@@ -0,0 +1,89 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Gas used by the token transfers OSPay sends. They are budgeting estimates: the exact gas of a
+// transaction is only known once it is mined.
+const (
+	GasTokenTransfer    = 65_000 // one ERC-20 transfer, including the 21,000 intrinsic cost
+	GasBatchBase        = 50_000 // a multi-send call's own overhead
+	GasBatchPerTransfer = 35_000 // each transfer inside a multi-send
+	GasApprove          = 60_000 // an ERC-20 approve
+)
+
+// gasQuoteTTL is how long a chain's gas quote is reused, so estimate requests don't hit the RPC
+// on every call.
+const gasQuoteTTL = 15 * time.Second
+
+// GasQuote is a chain's current gas pricing. BaseFee and PriorityFee are nil on chains without
+// EIP-1559 fees.
+type GasQuote struct {
+	Chain       string
+	GasPrice    *big.Int // wei per gas for a transaction sent now
+	BaseFee     *big.Int
+	PriorityFee *big.Int
+	FetchedAt   time.Time
+}
+
+var (
+	gasMu     sync.Mutex
+	gasQuotes = map[string]GasQuote{}
+)
+
+// SuggestGas returns chain's current gas price, fetched from its RPC endpoint or reused from the
+// last quote when it is recent.
+func SuggestGas(ctx context.Context, chain string) (GasQuote, error) {
+	chain = strings.ToUpper(chain)
+	gasMu.Lock()
+	q, ok := gasQuotes[chain]
+	gasMu.Unlock()
+	if ok && time.Since(q.FetchedAt) < gasQuoteTTL {
+		return q, nil
+	}
+	client, err := Client(chain)
+	if err != nil {
+		return GasQuote{}, err
+	}
+	price, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return GasQuote{}, err
+	}
+	q = GasQuote{Chain: chain, GasPrice: price, FetchedAt: time.Now().UTC()}
+	if head, err := client.HeaderByNumber(ctx, nil); err == nil && head.BaseFee != nil {
+		q.BaseFee = head.BaseFee
+		if tip, err := client.SuggestGasTipCap(ctx); err == nil {
+			q.PriorityFee = tip
+		}
+	}
+	gasMu.Lock()
+	gasQuotes[chain] = q
+	gasMu.Unlock()
+	return q, nil
+}
+
+// TransferFee is the cost in wei of n individual token transfers at gasPrice.
+func TransferFee(gasPrice *big.Int, n int) *big.Int {
+	return new(big.Int).Mul(gasPrice, big.NewInt(int64(n)*GasTokenTransfer))
+}
+
+// BatchFee is the cost in wei of one multi-send carrying n token transfers at gasPrice.
+func BatchFee(gasPrice *big.Int, n int) *big.Int {
+	return new(big.Int).Mul(gasPrice, big.NewInt(GasBatchBase+int64(n)*GasBatchPerTransfer))
+}
+
+// NativeBalance returns address's balance of chain's gas coin in wei.
+func NativeBalance(ctx context.Context, chain, address string) (*big.Int, error) {
+	client, err := Client(chain)
+	if err != nil {
+		return nil, err
+	}
+	return client.BalanceAt(ctx, common.HexToAddress(address), nil)
+}
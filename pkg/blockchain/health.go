@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	blockTimeMu sync.Mutex
+	// blockTimes is how often each chain is expected to produce a block.
+	blockTimes = map[string]time.Duration{"BSC": 750 * time.Millisecond, "ETH": 12 * time.Second, "POLYGON": 2 * time.Second}
+)
+
+// SetBlockTime sets how often chain is expected to produce a block.
+func SetBlockTime(chain string, d time.Duration) {
+	blockTimeMu.Lock()
+	defer blockTimeMu.Unlock()
+	blockTimes[strings.ToUpper(chain)] = d
+}
+
+// BlockTime returns how often chain is expected to produce a block, or 0 when unknown.
+func BlockTime(chain string) time.Duration {
+	blockTimeMu.Lock()
+	defer blockTimeMu.Unlock()
+	return blockTimes[strings.ToUpper(chain)]
+}
+
+// Provider names the RPC endpoint of chain by its host, leaving out the path and query where
+// providers put API keys; "" when the chain has none.
+func Provider(chain string) string {
+	_, host := rpcEndpoint(chain)
+	return host
+}
+
+func rpcEndpoint(chain string) (raw, host string) {
+	rpcMu.Lock()
+	raw = rpcURLs[strings.ToUpper(chain)]
+	rpcMu.Unlock()
+	if u, err := url.Parse(raw); err == nil {
+		host = u.Host
+	}
+	return raw, host
+}
+
+// Head is the latest block of a chain as its RPC endpoint reports it.
+type Head struct {
+	Number  uint64
+	Time    time.Time
+	Latency time.Duration // of the RPC call
+}
+
+// LatestHead fetches chain's latest block header.
+func LatestHead(ctx context.Context, chain string) (Head, error) {
+	client, err := Client(chain)
+	if err != nil {
+		return Head{}, err
+	}
+	start := time.Now()
+	h, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		// Transport errors quote the endpoint URL, API key included
+		if raw, host := rpcEndpoint(chain); raw != "" && strings.Contains(err.Error(), raw) {
+			return Head{}, errors.New(strings.ReplaceAll(err.Error(), raw, host))
+		}
+		return Head{}, err
+	}
+	return Head{Number: h.Number.Uint64(), Time: time.Unix(int64(h.Time), 0).UTC(), Latency: time.Since(start)}, nil
+}
@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TokenLog is a Transfer event of one of a chain's known tokens.
+type TokenLog struct {
+	Transfer
+	Asset       string
+	TxHash      common.Hash
+	LogIndex    uint
+	BlockNumber uint64
+}
+
+// TokenTransfersTo returns the transfers of chain's known tokens to any of recipients mined in
+// blocks from through to, in chain order.
+func TokenTransfersTo(ctx context.Context, chain string, from, to uint64, recipients []common.Address) ([]TokenLog, error) {
+	tokens := tokenContracts[strings.ToUpper(chain)]
+	if len(tokens) == 0 || len(recipients) == 0 {
+		return nil, nil
+	}
+	client, err := Client(chain)
+	if err != nil {
+		return nil, err
+	}
+	assets := map[common.Address]string{}
+	contracts := make([]common.Address, 0, len(tokens))
+	for asset, addr := range tokens {
+		a := common.HexToAddress(addr)
+		assets[a] = asset
+		contracts = append(contracts, a)
+	}
+	to32 := make([]common.Hash, len(recipients))
+	for i, r := range recipients {
+		to32[i] = common.BytesToHash(r.Bytes())
+	}
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from), ToBlock: new(big.Int).SetUint64(to),
+		Addresses: contracts, Topics: [][]common.Hash{{transferSigHash}, nil, to32},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []TokenLog
+	for _, l := range logs {
+		if l.Removed || len(l.Topics) != 3 || l.Topics[0] != transferSigHash {
+			continue
+		}
+		out = append(out, TokenLog{
+			Transfer: Transfer{
+				From:   common.HexToAddress(l.Topics[1].Hex()),
+				To:     common.HexToAddress(l.Topics[2].Hex()),
+				Amount: new(big.Int).SetBytes(l.Data),
+			},
+			Asset: assets[l.Address], TxHash: l.TxHash, LogIndex: l.Index, BlockNumber: l.BlockNumber,
+		})
+	}
+	return out, nil
+}
+
+// TokenTransfersInTx returns the transfers of chain's known tokens in the transaction txHash, none
+// if it reverted. A transaction chain does not know fails with ethereum.NotFound.
+func TokenTransfersInTx(ctx context.Context, chain, txHash string) ([]TokenLog, error) {
+	tokens := tokenContracts[strings.ToUpper(chain)]
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	client, err := Client(chain)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := client.TransactionReceipt(ctx, common.HexToHash(txHash))
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != 1 {
+		return nil, nil
+	}
+	assets := map[common.Address]string{}
+	for asset, addr := range tokens {
+		assets[common.HexToAddress(addr)] = asset
+	}
+	var out []TokenLog
+	for _, l := range receipt.Logs {
+		asset, ok := assets[l.Address]
+		if !ok || len(l.Topics) != 3 || l.Topics[0] != transferSigHash {
+			continue
+		}
+		out = append(out, TokenLog{
+			Transfer: Transfer{
+				From:   common.HexToAddress(l.Topics[1].Hex()),
+				To:     common.HexToAddress(l.Topics[2].Hex()),
+				Amount: new(big.Int).SetBytes(l.Data),
+			},
+			Asset: asset, TxHash: l.TxHash, LogIndex: l.Index, BlockNumber: l.BlockNumber,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrUnsupportedChain is returned for a chain without a configured RPC endpoint.
+var ErrUnsupportedChain = errors.New("unsupported chain")
+
+var (
+	rpcMu      sync.Mutex
+	rpcURLs    = map[string]string{"BSC": BSC_RPC_URL}
+	rpcClients = map[string]*ethclient.Client{}
+)
+
+// nativeAssets names the coin each chain charges gas in.
+var nativeAssets = map[string]string{
+	"BSC": "BNB", "ETH": "ETH", "POLYGON": "POL", "TRON": "TRX", "SOLANA": "SOL", "BTC": "BTC",
+}
+
+// SetRPCURL points chain at a JSON-RPC endpoint, replacing the default. An empty url removes the
+// chain. Call it at startup, before any RPC is made.
+func SetRPCURL(chain, url string) {
+	chain = strings.ToUpper(chain)
+	rpcMu.Lock()
+	defer rpcMu.Unlock()
+	if c, ok := rpcClients[chain]; ok {
+		c.Close()
+		delete(rpcClients, chain)
+	}
+	if url == "" {
+		delete(rpcURLs, chain)
+		return
+	}
+	rpcURLs[chain] = url
+}
+
+// Chains returns the chains with an RPC endpoint, sorted.
+func Chains() []string {
+	rpcMu.Lock()
+	defer rpcMu.Unlock()
+	chains := make([]string, 0, len(rpcURLs))
+	for c := range rpcURLs {
+		chains = append(chains, c)
+	}
+	sort.Strings(chains)
+	return chains
+}
+
+// NativeAsset returns the symbol of chain's gas coin, or "" when unknown.
+func NativeAsset(chain string) string {
+	return nativeAssets[strings.ToUpper(chain)]
+}
+
+// Client returns the shared RPC client for chain, dialing it on first use.
+func Client(chain string) (*ethclient.Client, error) {
+	chain = strings.ToUpper(chain)
+	rpcMu.Lock()
+	defer rpcMu.Unlock()
+	if c, ok := rpcClients[chain]; ok {
+		return c, nil
+	}
+	url, ok := rpcURLs[chain]
+	if !ok {
+		return nil, ErrUnsupportedChain
+	}
+	c, err := ethclient.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	rpcClients[chain] = c
+	return c, nil
+}
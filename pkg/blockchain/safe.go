@@ -0,0 +1,235 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SafeTx is a Safe (Gnosis Safe) multisig transaction: a call the Safe makes once enough owners
+// have confirmed it. Gas refund fields are always zero here, so executing owners pay their own gas.
+type SafeTx struct {
+	To    common.Address
+	Value *big.Int
+	Data  []byte
+	Nonce uint64
+}
+
+var (
+	safeDomainTypeHash = crypto.Keccak256([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))
+	safeTxTypeHash     = crypto.Keccak256([]byte("SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)"))
+)
+
+// Hash is the EIP-712 hash of tx for safe on chainID, which owners sign and the transaction
+// service uses as the proposal's id.
+func (tx SafeTx) Hash(chainID *big.Int, safe common.Address) common.Hash {
+	word := func(b []byte) []byte { return common.LeftPadBytes(b, 32) }
+	value := tx.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	domain := crypto.Keccak256(safeDomainTypeHash, word(chainID.Bytes()), word(safe.Bytes()))
+	zero := word(nil)
+	// operation (CALL), safeTxGas, baseGas, gasPrice, gasToken and refundReceiver are all zero.
+	body := crypto.Keccak256(safeTxTypeHash,
+		word(tx.To.Bytes()), word(value.Bytes()), crypto.Keccak256(tx.Data),
+		zero, zero, zero, zero, zero, zero,
+		word(new(big.Int).SetUint64(tx.Nonce).Bytes()))
+	return common.BytesToHash(crypto.Keccak256([]byte{0x19, 0x01}, domain, body))
+}
+
+// Safe transaction service endpoints per chain. They can be replaced with SetSafeServiceURL, e.g.
+// for a self-hosted service.
+var (
+	safeMu          sync.Mutex
+	safeServiceURLs = map[string]string{
+		"ETH":     "https://safe-transaction-mainnet.safe.global",
+		"BSC":     "https://safe-transaction-bsc.safe.global",
+		"POLYGON": "https://safe-transaction-polygon.safe.global",
+	}
+	safeAPIKey string
+)
+
+var safeHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// SetSafeServiceURL points chain at a Safe transaction service.
+func SetSafeServiceURL(chain, url string) {
+	safeMu.Lock()
+	defer safeMu.Unlock()
+	safeServiceURLs[strings.ToUpper(chain)] = strings.TrimRight(url, "/")
+}
+
+// SetSafeAPIKey sets the bearer token sent to the transaction service; "" sends none.
+func SetSafeAPIKey(key string) {
+	safeMu.Lock()
+	defer safeMu.Unlock()
+	safeAPIKey = key
+}
+
+// safeRequest calls the transaction service of chain and decodes a JSON response into out.
+func safeRequest(ctx context.Context, chain, method, path string, in, out any) error {
+	safeMu.Lock()
+	base, ok := safeServiceURLs[strings.ToUpper(chain)]
+	key := safeAPIKey
+	safeMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no Safe transaction service for chain %s", chain)
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, base+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := safeHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrSafeNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("safe transaction service: %s %s: %d %s", method, path, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
+
+// ErrSafeNotFound is returned when the transaction service does not know the Safe or proposal.
+var ErrSafeNotFound = errors.New("not found on the Safe transaction service")
+
+// flexUint decodes a number the service sends either as a JSON number or as a string.
+type flexUint uint64
+
+func (n *flexUint) UnmarshalJSON(b []byte) error {
+	v, err := strconv.ParseUint(strings.Trim(string(b), `"`), 10, 64)
+	*n = flexUint(v)
+	return err
+}
+
+// SafeInfo is the part of a Safe's state OSPay needs.
+type SafeInfo struct {
+	Nonce     uint64 // next nonce to be executed
+	Threshold int
+	Owners    []common.Address
+}
+
+// GetSafe returns the state of safe on chain.
+func GetSafe(ctx context.Context, chain string, safe common.Address) (SafeInfo, error) {
+	var resp struct {
+		Nonce     flexUint `json:"nonce"`
+		Threshold int      `json:"threshold"`
+		Owners    []string `json:"owners"`
+	}
+	if err := safeRequest(ctx, chain, http.MethodGet, "/api/v1/safes/"+safe.Hex()+"/", nil, &resp); err != nil {
+		return SafeInfo{}, err
+	}
+	info := SafeInfo{Nonce: uint64(resp.Nonce), Threshold: resp.Threshold}
+	for _, o := range resp.Owners {
+		info.Owners = append(info.Owners, common.HexToAddress(o))
+	}
+	return info, nil
+}
+
+// ProposeSafeTx submits tx to the transaction service as a proposal by signer, which must be an
+// owner or delegate of safe, and returns its hash. The signature counts as signer's confirmation
+// when signer is an owner.
+func ProposeSafeTx(ctx context.Context, chain string, safe common.Address, tx SafeTx, signer Signer) (common.Hash, error) {
+	chainID, err := ChainID(ctx, chain)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash := tx.Hash(chainID, safe)
+	sig, err := signer.SignHash(hash.Bytes())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	value := tx.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	zero := common.Address{}.Hex()
+	body := map[string]any{
+		"to":                      tx.To.Hex(),
+		"value":                   value.String(),
+		"data":                    hexutil.Encode(tx.Data),
+		"operation":               0,
+		"safeTxGas":               "0",
+		"baseGas":                 "0",
+		"gasPrice":                "0",
+		"gasToken":                zero,
+		"refundReceiver":          zero,
+		"nonce":                   tx.Nonce,
+		"contractTransactionHash": hash.Hex(),
+		"sender":                  signer.Address().Hex(),
+		"signature":               hexutil.Encode(sig),
+		"origin":                  "OSPay",
+	}
+	if err := safeRequest(ctx, chain, http.MethodPost, "/api/v1/safes/"+safe.Hex()+"/multisig-transactions/", body, nil); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+// SafeTxStatus is the state of a proposal on the transaction service.
+type SafeTxStatus struct {
+	Executed              bool
+	Successful            bool
+	TxHash                string // set once executed
+	Confirmations         int
+	ConfirmationsRequired int
+}
+
+// GetSafeTx returns the state of the proposal with the given Safe transaction hash.
+func GetSafeTx(ctx context.Context, chain string, safeTxHash common.Hash) (SafeTxStatus, error) {
+	var resp struct {
+		IsExecuted            bool              `json:"isExecuted"`
+		IsSuccessful          *bool             `json:"isSuccessful"`
+		TransactionHash       *string           `json:"transactionHash"`
+		Confirmations         []json.RawMessage `json:"confirmations"`
+		ConfirmationsRequired int               `json:"confirmationsRequired"`
+	}
+	if err := safeRequest(ctx, chain, http.MethodGet, "/api/v1/multisig-transactions/"+safeTxHash.Hex()+"/", nil, &resp); err != nil {
+		return SafeTxStatus{}, err
+	}
+	st := SafeTxStatus{
+		Executed:              resp.IsExecuted,
+		Successful:            resp.IsSuccessful != nil && *resp.IsSuccessful,
+		Confirmations:         len(resp.Confirmations),
+		ConfirmationsRequired: resp.ConfirmationsRequired,
+	}
+	if resp.TransactionHash != nil {
+		st.TxHash = *resp.TransactionHash
+	}
+	return st, nil
+}
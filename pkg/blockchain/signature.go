@@ -0,0 +1,38 @@
+package blockchain
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// PersonalMessageHash is the digest an EIP-191 personal_sign signature covers.
+func PersonalMessageHash(message []byte) []byte {
+	prefix := "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(message))
+	return crypto.Keccak256([]byte(prefix), message)
+}
+
+// TypedDataHash is the digest an EIP-712 signature covers, given the domain separator and the hash
+// of the signed struct.
+func TypedDataHash(domainSeparator, structHash []byte) []byte {
+	return crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+}
+
+// RecoverSigner returns the address whose key produced the 65-byte signature sig over digest. The
+// recovery id may be 0/1 or, as wallets return it, 27/28.
+func RecoverSigner(digest, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errors.New("signature must be 65 bytes")
+	}
+	sig = append([]byte{}, sig...)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	pub, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
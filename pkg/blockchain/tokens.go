@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// tokenContracts maps chain and asset symbol to the ERC-20 contract payouts are sent in. Order
+// amounts are in the token's smallest unit, so they are transferred as they are.
+var tokenContracts = map[string]map[string]string{
+	"BSC": {
+		"USDT": BSC_USD_ADDRESS,
+		"USDC": "0x8AC76a51cc950d9822D68b83fE1Ad97B32Cd580d",
+	},
+	"ETH": {
+		"USDT": "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+		"USDC": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+	},
+	"POLYGON": {
+		"USDT": "0xc2132D05D31c914a87C6611C10748AEb04B58e8F",
+		"USDC": "0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359",
+	},
+}
+
+// TokenAddress returns the contract of asset on chain.
+func TokenAddress(chain, asset string) (common.Address, bool) {
+	addr, ok := tokenContracts[strings.ToUpper(chain)][strings.ToUpper(asset)]
+	if !ok {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(addr), true
+}
+
+// KnownToken reports whether asset has a contract on chain, or on any chain when chain is "".
+func KnownToken(chain, asset string) bool {
+	if chain != "" {
+		_, ok := TokenAddress(chain, asset)
+		return ok
+	}
+	for _, tokens := range tokenContracts {
+		if _, ok := tokens[strings.ToUpper(asset)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenChains returns the chains with known token contracts, sorted.
+func TokenChains() []string {
+	return slices.Sorted(maps.Keys(tokenContracts))
+}
+
+// KnownAssets returns the symbols of the tokens with a known contract and of the chains' native
+// coins, sorted.
+func KnownAssets() []string {
+	var assets []string
+	for _, tokens := range tokenContracts {
+		for asset := range tokens {
+			assets = append(assets, asset)
+		}
+	}
+	for _, coin := range nativeAssets {
+		assets = append(assets, coin)
+	}
+	slices.Sort(assets)
+	return slices.Compact(assets)
+}
+
+var balanceOfSelector = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+
+// AssetBalance returns owner's balance of asset on chain in the asset's smallest unit: the token
+// balance for assets with a known contract, the wei balance for the chain's native coin.
+func AssetBalance(ctx context.Context, chain, asset, owner string) (*big.Int, error) {
+	if coin := NativeAsset(chain); coin != "" && strings.EqualFold(asset, coin) {
+		return NativeBalance(ctx, chain, owner)
+	}
+	token, ok := TokenAddress(chain, asset)
+	if !ok {
+		return nil, fmt.Errorf("no %s contract known on %s", asset, chain)
+	}
+	client, err := Client(chain)
+	if err != nil {
+		return nil, err
+	}
+	data := append([]byte{}, balanceOfSelector...)
+	data = append(data, common.LeftPadBytes(common.HexToAddress(owner).Bytes(), 32)...)
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) < 32 {
+		return nil, errors.New("balanceOf: short return data")
+	}
+	return new(big.Int).SetBytes(out[:32]), nil
+}
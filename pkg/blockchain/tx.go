@@ -0,0 +1,149 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs outgoing transactions for the hot wallet. KeySigner holds the key in memory; other
+// implementations can delegate to a KMS or HSM.
+type Signer interface {
+	Address() common.Address
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignHash returns the 65-byte [R || S || V] signature of a 32-byte digest, with V 27 or 28.
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// KeySigner signs with a private key held in process memory.
+type KeySigner struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+// NewKeySigner parses a hex-encoded secp256k1 private key, with or without 0x.
+func NewKeySigner(hexKey string) (*KeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return &KeySigner{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+func (s *KeySigner) Address() common.Address { return s.addr }
+
+func (s *KeySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *KeySigner) SignHash(hash []byte) ([]byte, error) {
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// transferSelector is the ERC-20 transfer(address,uint256) function selector.
+var transferSelector = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+
+// TokenTransferData is the calldata of an ERC-20 transfer of amount to to.
+func TokenTransferData(to common.Address, amount *big.Int) []byte {
+	data := make([]byte, 0, 4+32+32)
+	data = append(data, transferSelector...)
+	data = append(data, common.LeftPadBytes(to.Bytes(), 32)...)
+	return append(data, common.LeftPadBytes(amount.Bytes(), 32)...)
+}
+
+// NewTx builds an unsigned contract call with the given fees: an EIP-1559 transaction unless the
+// fees are legacy.
+func NewTx(chainID *big.Int, nonce uint64, to common.Address, data []byte, gas uint64, fees Fees) *types.Transaction {
+	if fees.Legacy() {
+		return types.NewTx(&types.LegacyTx{Nonce: nonce, To: &to, Gas: gas, GasPrice: fees.MaxFee, Data: data})
+	}
+	return types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: nonce, To: &to, Gas: gas, GasFeeCap: fees.MaxFee, GasTipCap: fees.TipCap, Data: data})
+}
+
+// WithFees rebuilds tx with new fees and the same nonce, recipient, value and calldata, for
+// replacing it while it is pending.
+func WithFees(chainID *big.Int, tx *types.Transaction, fees Fees) *types.Transaction {
+	if fees.Legacy() {
+		return types.NewTx(&types.LegacyTx{Nonce: tx.Nonce(), To: tx.To(), Gas: tx.Gas(), GasPrice: fees.MaxFee, Value: tx.Value(), Data: tx.Data()})
+	}
+	return types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: tx.Nonce(), To: tx.To(), Gas: tx.Gas(), GasFeeCap: fees.MaxFee, GasTipCap: fees.TipCap, Value: tx.Value(), Data: tx.Data()})
+}
+
+// TxFees returns the fees tx was built with.
+func TxFees(tx *types.Transaction) Fees {
+	if tx.Type() == types.LegacyTxType {
+		return Fees{MaxFee: tx.GasPrice()}
+	}
+	return Fees{MaxFee: tx.GasFeeCap(), TipCap: tx.GasTipCap()}
+}
+
+// ChainID returns chain's EIP-155 chain ID.
+func ChainID(ctx context.Context, chain string) (*big.Int, error) {
+	client, err := Client(chain)
+	if err != nil {
+		return nil, err
+	}
+	return client.ChainID(ctx)
+}
+
+// PendingNonce returns the next nonce of address on chain, counting pending transactions.
+func PendingNonce(ctx context.Context, chain string, address common.Address) (uint64, error) {
+	client, err := Client(chain)
+	if err != nil {
+		return 0, err
+	}
+	return client.PendingNonceAt(ctx, address)
+}
+
+// SendTx broadcasts a signed transaction.
+func SendTx(ctx context.Context, chain string, tx *types.Transaction) error {
+	client, err := Client(chain)
+	if err != nil {
+		return err
+	}
+	return client.SendTransaction(ctx, tx)
+}
+
+// Receipt returns the receipt of a mined transaction, or nil while it is not mined.
+func Receipt(ctx context.Context, chain string, hash common.Hash) (*types.Receipt, error) {
+	client, err := Client(chain)
+	if err != nil {
+		return nil, err
+	}
+	r, err := client.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ConfirmedNonce returns the nonce of address as of the latest block, i.e. the number of its
+// mined transactions.
+func ConfirmedNonce(ctx context.Context, chain string, address common.Address) (uint64, error) {
+	client, err := Client(chain)
+	if err != nil {
+		return 0, err
+	}
+	return client.NonceAt(ctx, address, nil)
+}
+
+// GasCancel is the gas of a plain zero-value transfer, used to cancel a pending transaction.
+const GasCancel = 21_000
+
+// CancelTx builds the zero-value self-transfer that takes nonce, so the transaction pending at
+// that nonce is dropped once it is mined.
+func CancelTx(chainID *big.Int, nonce uint64, self common.Address, fees Fees) *types.Transaction {
+	return NewTx(chainID, nonce, self, nil, GasCancel, fees)
+}
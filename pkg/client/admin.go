@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// SettlementBatch is one merchant/asset payout, as created by RunSettlement or listed by
+// ListSettlements. TotalAmountMinor is the net payout; the other amounts itemize it and are empty
+// for batches settled before they were stored.
+type SettlementBatch struct {
+	BatchID           string  `json:"batch_id"`
+	MerchantID        string  `json:"merchant_id"`
+	Asset             string  `json:"asset"`
+	Orders            int     `json:"orders,omitempty"` // set by RunSettlement
+	TotalAmountMinor  string  `json:"total_amount_minor"`
+	GrossAmountMinor  string  `json:"gross_amount_minor,omitempty"`
+	FeesMinor         string  `json:"fees_minor,omitempty"`
+	RefundsMinor      string  `json:"refunds_minor,omitempty"`
+	DisputesLostMinor string  `json:"disputes_lost_minor,omitempty"`
+	ClawbackMinor     string  `json:"clawback_minor,omitempty"`
+	HeldMinor         string  `json:"held_minor,omitempty"`
+	HoldReleasedMinor string  `json:"hold_released_minor,omitempty"`
+	Status            string  `json:"status,omitempty"` // set by ListSettlements, as are the fields below
+	PayoutTxHash      *string `json:"payout_tx_hash,omitempty"`
+	SettledAt         string  `json:"settled_at,omitempty"`
+}
+
+// ListSettlements returns the merchant's settlement batches, newest first; an empty asset does not
+// filter.
+func (c *Client) ListSettlements(ctx context.Context, asset string) ([]SettlementBatch, error) {
+	q := url.Values{}
+	if asset != "" {
+		q.Set("asset", asset)
+	}
+	var batches []SettlementBatch
+	if err := c.do(ctx, http.MethodGet, "/v1/settlements", q, nil, &batches); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// NegativeBalance is a time the merchant's balance of an asset on a chain went below zero. It is
+// OPEN while AmountMinor is owed and RECOVERED once the balance is back.
+type NegativeBalance struct {
+	ID          string  `json:"id"`
+	MerchantID  string  `json:"merchant_id"`
+	Asset       string  `json:"asset"`
+	Chain       string  `json:"chain"`
+	Status      string  `json:"status"`
+	AmountMinor string  `json:"amount_minor"`
+	PeakMinor   string  `json:"peak_minor"`
+	OpenedAt    string  `json:"opened_at"`
+	UpdatedAt   string  `json:"updated_at"`
+	RecoveredAt *string `json:"recovered_at,omitempty"`
+}
+
+// ListNegativeBalances returns the merchant's negative balances, newest first; an empty status
+// does not filter.
+func (c *Client) ListNegativeBalances(ctx context.Context, status string) ([]NegativeBalance, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	var out []NegativeBalance
+	if err := c.do(ctx, http.MethodGet, "/v1/negative-balances", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BalanceHold holds part of a merchant's unsettled balance of an asset on a chain out of
+// settlements. WithheldMinor is what settlements kept back for it; a release settles that amount
+// in ReleaseBatchID.
+type BalanceHold struct {
+	ID             string  `json:"id"`
+	MerchantID     string  `json:"merchant_id"`
+	Asset          string  `json:"asset"`
+	Chain          string  `json:"chain"`
+	AmountMinor    string  `json:"amount_minor"`
+	Reason         string  `json:"reason"`
+	Status         string  `json:"status"` // ACTIVE, RELEASED or EXPIRED
+	PlacedBy       string  `json:"placed_by"`
+	PlatformID     *string `json:"platform_id,omitempty"`
+	ExpiresAt      *string `json:"expires_at,omitempty"`
+	WithheldMinor  string  `json:"withheld_minor"`
+	CreatedAt      string  `json:"created_at"`
+	ReleasedAt     *string `json:"released_at,omitempty"`
+	ReleasedBy     *string `json:"released_by,omitempty"`
+	ReleaseReason  *string `json:"release_reason,omitempty"`
+	ReleaseBatchID *string `json:"release_batch_id,omitempty"`
+}
+
+// PlaceHoldRequest is the body of PlaceHold. ExpiresAt (RFC 3339) is optional.
+type PlaceHoldRequest struct {
+	MerchantID  string `json:"merchant_id"`
+	Asset       string `json:"asset"`
+	Chain       string `json:"chain"`
+	AmountMinor string `json:"amount_minor"`
+	Reason      string `json:"reason"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+}
+
+// ListHolds returns the holds on the merchant's balance, newest first; an empty status does not
+// filter.
+func (c *Client) ListHolds(ctx context.Context, status string) ([]BalanceHold, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	var out []BalanceHold
+	if err := c.do(ctx, http.MethodGet, "/v1/holds", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PlaceHold holds part of a merchant's unsettled balance. It needs WithAdminKey.
+func (c *Client) PlaceHold(ctx context.Context, req PlaceHoldRequest) (*BalanceHold, error) {
+	var out BalanceHold
+	if err := c.do(ctx, http.MethodPost, "/v1/admin/holds", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReleaseHold releases an active hold, with an optional reason. It needs WithAdminKey.
+func (c *Client) ReleaseHold(ctx context.Context, holdID, reason string) (*BalanceHold, error) {
+	var out BalanceHold
+	body := map[string]string{}
+	if reason != "" {
+		body["reason"] = reason
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/admin/holds/"+url.PathEscape(holdID)+"/release", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RunSettlement settles paid orders now, for one merchant or (merchantID "") all of them. It needs
+// WithAdminKey.
+func (c *Client) RunSettlement(ctx context.Context, merchantID string) ([]SettlementBatch, error) {
+	q := url.Values{}
+	if merchantID != "" {
+		q.Set("merchant_id", merchantID)
+	}
+	var batches []SettlementBatch
+	if err := c.do(ctx, http.MethodPost, "/v1/admin/settlements/run", q, nil, &batches); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
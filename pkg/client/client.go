@@ -0,0 +1,215 @@
+// Package client is a typed Go client for the OSPay HTTP API.
+//
+//	c := client.New("https://pay.example.com", os.Getenv("OSPAY_API_KEY"))
+//	order, err := c.CreateOrder(ctx, client.CreateOrderRequest{AmountMinor: "1000000", Asset: "USDT", Chain: "bsc"})
+//
+// Every call takes a context. Requests that fail with a network error, 429 or a 5xx response are
+// retried with exponential backoff; writes are safe to retry because every POST carries an
+// Idempotency-Key header, generated once per call, so the server replays the first outcome.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Client talks to one OSPay server with one credential. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	bearer     string
+	adminKey   string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces the default http.Client (30s timeout).
+func WithHTTPClient(hc *http.Client) Option { return func(c *Client) { c.httpClient = hc } }
+
+// WithRetries sets how many times a retryable failure is retried (default 3) and the first
+// backoff delay (default 250ms), which doubles on each attempt.
+func WithRetries(max int, backoff time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.backoff = max, backoff }
+}
+
+// WithBearerToken authenticates with an OAuth access token instead of an API key.
+func WithBearerToken(token string) Option { return func(c *Client) { c.bearer = token } }
+
+// WithAdminKey adds the operator key (X-Admin-Key) needed by the admin calls.
+func WithAdminKey(key string) Option { return func(c *Client) { c.adminKey = key } }
+
+// New returns a client for baseURL (e.g. "http://localhost:8080") using the merchant API key; the
+// key may be empty for calls that need none, such as CreateMerchant.
+func New(baseURL, apiKey string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		maxRetries: 3,
+		backoff:    250 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error is a non-2xx API response, decoded from its problem+json body. Code is the stable error
+// code (see GET /v1/problems); Title and Detail are human-readable.
+type Error struct {
+	StatusCode int          `json:"status"`
+	Code       string       `json:"code"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	Fields     []FieldError `json:"errors"` // the request fields at fault, for validation errors
+}
+
+// FieldError is one missing or invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	if msg != "" {
+		return fmt.Sprintf("ospay: %d %s: %s", e.StatusCode, e.Code, msg)
+	}
+	return fmt.Sprintf("ospay: %d %s", e.StatusCode, e.Code)
+}
+
+// IsNotFound reports whether err is a 404 from the API.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// do sends the request, retrying retryable failures, and decodes a 2xx body into out (if non-nil).
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var idemKey string
+	if method == http.MethodPost {
+		idemKey = uuid.New().String()
+	}
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.send(ctx, method, u, idemKey, payload, out)
+		if err == nil || attempt >= c.maxRetries || !retryable(err) {
+			return err
+		}
+		wait := delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// send makes one attempt. It returns the server's Retry-After, if any, with the error.
+func (c *Client) send(ctx context.Context, method, u, idemKey string, payload []byte, out any) (time.Duration, error) {
+	var rd io.Reader
+	if payload != nil {
+		rd = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return 0, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	if idemKey != "" {
+		req.Header.Set("Idempotency-Key", idemKey)
+	}
+	if c.adminKey != "" {
+		req.Header.Set("X-Admin-Key", c.adminKey)
+	}
+	switch {
+	case c.bearer != "":
+		req.Header.Set("Authorization", "Bearer "+c.bearer)
+	case c.apiKey != "":
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, &netError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(apiErr)
+		apiErr.StatusCode = resp.StatusCode
+		if apiErr.Code == "" {
+			apiErr.Code = http.StatusText(resp.StatusCode)
+		}
+		var retryAfter time.Duration
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(s) * time.Second
+		}
+		return retryAfter, apiErr
+	}
+	if out == nil {
+		return 0, nil
+	}
+	// Decode only the first JSON value: some endpoints append a metrics object after the body
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("ospay: decode response: %w", err)
+	}
+	return 0, nil
+}
+
+// netError marks a transport failure, which is always safe to retry for idempotent requests.
+type netError struct{ err error }
+
+func (e *netError) Error() string { return "ospay: " + e.err.Error() }
+func (e *netError) Unwrap() error { return e.err }
+
+func retryable(err error) bool {
+	var ne *netError
+	if errors.As(err, &ne) {
+		return true
+	}
+	var e *Error
+	return errors.As(err, &e) && (e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500)
+}
@@ -0,0 +1,434 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateMerchantRequest is the body of POST /v1/merchants.
+type CreateMerchantRequest struct {
+	Name                  string `json:"name"`
+	MerchantWalletAddress string `json:"merchant_wallet_address"`
+}
+
+// Merchant is a newly created merchant. APIKey is only returned at creation.
+type Merchant struct {
+	ID                    string `json:"id"`
+	APIKey                string `json:"api_key"`
+	MerchantWalletAddress string `json:"merchant_wallet_address"`
+}
+
+// CreateOrderRequest is the body of POST /v1/orders. Amounts are decimal strings in minor units.
+// An empty IdempotencyKey is filled with a random one.
+type CreateOrderRequest struct {
+	MerchantID            string          `json:"merchant_id,omitempty"` // defaults to the authenticated merchant
+	AmountMinor           string          `json:"amount_minor"`
+	Asset                 string          `json:"asset"`
+	Chain                 string          `json:"chain"`
+	IdempotencyKey        string          `json:"idempotency_key"`
+	CustomerWalletAddress string          `json:"customer_wallet_address,omitempty"`
+	CustomerEmail         string          `json:"customer_email,omitempty"`
+	Metadata              json.RawMessage `json:"metadata,omitempty"`
+	CouponCode            string          `json:"coupon_code,omitempty"`
+	ExternalOrderID       string          `json:"external_order_id,omitempty"`
+	LineItems             []LineItem      `json:"line_items,omitempty"` // AmountMinor may be left empty to charge their sum
+	// WebhookURL receives the order's events instead of the merchant's webhook URL; an empty
+	// WebhookSecret has the server generate one, returned in CreatedOrder.WebhookSecret.
+	WebhookURL    string `json:"webhook_url,omitempty"`
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+	// RateQuoteID (a RateQuote.QuoteID from GetRate) and FiatAmount record that AmountMinor was
+	// converted from a price in fiat; both or neither are given.
+	RateQuoteID string `json:"rate_quote_id,omitempty"`
+	FiatAmount  string `json:"fiat_amount,omitempty"`
+}
+
+// LineItem is one product on an order. AmountMinor, the quantity times the unit amount, is set by
+// the server.
+type LineItem struct {
+	Name            string `json:"name"`
+	Quantity        int64  `json:"quantity"`
+	UnitAmountMinor string `json:"unit_amount_minor"`
+	AmountMinor     string `json:"amount_minor,omitempty"`
+}
+
+// CreatedOrder is the response of CreateOrder.
+type CreatedOrder struct {
+	OrderID        string  `json:"order_id"`
+	DepositAddress string  `json:"deposit_address"`
+	Status         string  `json:"status"`
+	AmountMinor    string  `json:"amount_minor"`
+	DiscountMinor  *string `json:"discount_minor,omitempty"`
+	WebhookSecret  string  `json:"webhook_secret,omitempty"`
+}
+
+// Order is an order as returned by GetOrder and ListOrders.
+type Order struct {
+	ID                    string          `json:"id"`
+	MerchantID            string          `json:"merchant_id"`
+	AmountMinor           string          `json:"amount_minor"`
+	Asset                 string          `json:"asset"`
+	Chain                 string          `json:"chain"`
+	Status                string          `json:"status"`
+	DepositAddress        string          `json:"deposit_address"`
+	TxHash                *string         `json:"tx_hash,omitempty"`
+	ConfirmedBlock        *int64          `json:"confirmed_block,omitempty"`
+	BlockTimestamp        *string         `json:"block_timestamp,omitempty"`
+	PaidAt                *string         `json:"paid_at,omitempty"`
+	CreatedAt             string          `json:"created_at"`
+	ExpiresAt             *string         `json:"expires_at,omitempty"`
+	CustomerWalletAddress *string         `json:"customer_wallet_address,omitempty"`
+	CustomerEmail         *string         `json:"customer_email,omitempty"`
+	Metadata              json.RawMessage `json:"metadata,omitempty"`
+	ExternalOrderID       *string         `json:"external_order_id,omitempty"`
+	RiskReason            *string         `json:"risk_reason,omitempty"`
+	RiskScore             *int64          `json:"risk_score,omitempty"`
+	RiskFactors           *string         `json:"risk_factors,omitempty"`
+	ApplicationFeeMinor   *string         `json:"application_fee_minor,omitempty"`
+	CouponCode            *string         `json:"coupon_code,omitempty"`
+	DiscountMinor         *string         `json:"discount_minor,omitempty"`
+	Mispayment            *Mispayment     `json:"mispayment,omitempty"`
+	FiatPricing           *FiatPricing    `json:"fiat_pricing,omitempty"`
+	LineItems             []LineItem      `json:"line_items,omitempty"`
+	Tags                  []string        `json:"tags,omitempty"` // set by ListOrders, GetOrders and SearchOrders
+}
+
+// Mispayment is the transfer in the wrong token or on the wrong chain that made an order MISPAID.
+type Mispayment struct {
+	Reason      string `json:"reason"` // wrong_token or wrong_chain
+	Asset       string `json:"asset"`
+	Chain       string `json:"chain"`
+	AmountMinor string `json:"amount_minor"` // in the smallest unit of the token sent
+	FromAddress string `json:"from_address"`
+	TxHash      string `json:"tx_hash"`
+}
+
+// FiatPricing is the fiat price of an order created with a rate quote, and the quote.
+type FiatPricing struct {
+	FiatAmount   string    `json:"fiat_amount"`
+	FiatCurrency string    `json:"fiat_currency"`
+	RateQuote    RateQuote `json:"rate_quote"`
+}
+
+// ListOrdersParams filters ListOrders. Zero values mean no filter and the server's default page size.
+type ListOrdersParams struct {
+	Status string
+	Tag    string
+	Limit  int
+	Cursor string // NextCursor of the previous page
+}
+
+// OrderList is one page of orders. NextCursor is empty on the last page.
+type OrderList struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// SearchOrdersParams are the criteria of SearchOrders; every non-empty one must match.
+type SearchOrdersParams struct {
+	ExternalOrderID string
+	TxHash          string
+	CustomerEmail   string
+	Metadata        map[string]string // top-level metadata key to value
+	Tag             string
+	Limit           int
+}
+
+// RefundRequest is the body of a refund. A nil AmountMinor refunds the remaining balance; an empty
+// IdempotencyKey is filled with a random one.
+type RefundRequest struct {
+	AmountMinor    *string `json:"amount_minor,omitempty"`
+	RefundTxHash   string  `json:"refundtxhash,omitempty"`
+	IdempotencyKey string  `json:"refund_idempotency_key"`
+}
+
+// Refund is the outcome of a refund request. RefundStatus is REQUESTED when the merchant requires
+// a second approval.
+type Refund struct {
+	OrderID            string `json:"order_id"`
+	RefundID           string `json:"refund_id,omitempty"`
+	Status             string `json:"status"`
+	RefundStatus       string `json:"refund_status,omitempty"`
+	AmountMinor        string `json:"amount_minor,omitempty"`
+	RefundedTotalMinor string `json:"refunded_total_minor,omitempty"`
+	RefundableMinor    string `json:"refundable_minor,omitempty"`
+	Message            string `json:"message"`
+}
+
+// CreateMerchant registers a merchant. It needs no credential.
+func (c *Client) CreateMerchant(ctx context.Context, req CreateMerchantRequest) (*Merchant, error) {
+	var m Merchant
+	if err := c.do(ctx, http.MethodPost, "/v1/merchants", nil, req, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// CreateOrder creates a PENDING order, or returns the existing one when the idempotency key was
+// already used.
+func (c *Client) CreateOrder(ctx context.Context, req CreateOrderRequest) (*CreatedOrder, error) {
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = uuid.New().String()
+	}
+	var o CreatedOrder
+	if err := c.do(ctx, http.MethodPost, "/v1/orders", nil, req, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// GetOrder fetches an order, including archived ones. A missing order reports IsNotFound.
+func (c *Client) GetOrder(ctx context.Context, id string) (*Order, error) {
+	var o Order
+	if err := c.do(ctx, http.MethodGet, "/v1/orders/"+url.PathEscape(id), nil, nil, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// OrderBatch is the outcome of GetOrders: the orders found, in the order asked for, and the IDs
+// of none.
+type OrderBatch struct {
+	Orders   []Order  `json:"orders"`
+	NotFound []string `json:"not_found"`
+}
+
+// GetOrders fetches up to 100 orders, archived ones included, in one request.
+func (c *Client) GetOrders(ctx context.Context, ids []string) (*OrderBatch, error) {
+	var b OrderBatch
+	if err := c.do(ctx, http.MethodPost, "/v1/orders/batch-get", nil, map[string][]string{"order_ids": ids}, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// OrderExtension is the new expiry of an order after ExtendOrder.
+type OrderExtension struct {
+	OrderID   string `json:"order_id"`
+	Status    string `json:"status"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// ExtendOrder pushes out the expiry of a PENDING order by minutes (0 for the server default of 30).
+func (c *Client) ExtendOrder(ctx context.Context, id string, minutes int) (*OrderExtension, error) {
+	body := map[string]int{}
+	if minutes > 0 {
+		body["minutes"] = minutes
+	}
+	var e OrderExtension
+	if err := c.do(ctx, http.MethodPost, "/v1/orders/"+url.PathEscape(id)+"/extend", nil, body, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// OrderTags are the tags of an order, sorted.
+type OrderTags struct {
+	OrderID string   `json:"order_id"`
+	Tags    []string `json:"tags"`
+}
+
+// AddOrderTags tags an order; tags are compared in lower case and ones it has are ignored.
+func (c *Client) AddOrderTags(ctx context.Context, id string, tags ...string) (*OrderTags, error) {
+	var t OrderTags
+	if err := c.do(ctx, http.MethodPost, "/v1/orders/"+url.PathEscape(id)+"/tags", nil, map[string][]string{"tags": tags}, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RemoveOrderTags removes tags from an order and returns the ones left.
+func (c *Client) RemoveOrderTags(ctx context.Context, id string, tags ...string) (*OrderTags, error) {
+	var t OrderTags
+	if err := c.do(ctx, http.MethodPost, "/v1/orders/"+url.PathEscape(id)+"/tags/remove", nil, map[string][]string{"tags": tags}, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListOrders returns one page of the merchant's orders, newest first.
+func (c *Client) ListOrders(ctx context.Context, p ListOrdersParams) (*OrderList, error) {
+	q := url.Values{}
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.Tag != "" {
+		q.Set("tag", p.Tag)
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	}
+	var l OrderList
+	if err := c.do(ctx, http.MethodGet, "/v1/orders", q, nil, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// SearchOrders finds the merchant's orders, archived ones included, by reference, payment hash,
+// customer email, metadata or tag.
+func (c *Client) SearchOrders(ctx context.Context, p SearchOrdersParams) ([]Order, error) {
+	q := url.Values{}
+	for param, v := range map[string]string{"external_order_id": p.ExternalOrderID, "tx_hash": p.TxHash, "customer_email": p.CustomerEmail, "tag": p.Tag} {
+		if v != "" {
+			q.Set(param, v)
+		}
+	}
+	for k, v := range p.Metadata {
+		q.Set("metadata."+k, v)
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	var l OrderList
+	if err := c.do(ctx, http.MethodGet, "/v1/orders/search", q, nil, &l); err != nil {
+		return nil, err
+	}
+	return l.Orders, nil
+}
+
+// Refund refunds all or part of a paid order.
+func (c *Client) Refund(ctx context.Context, orderID string, req RefundRequest) (*Refund, error) {
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = uuid.New().String()
+	}
+	var rf Refund
+	if err := c.do(ctx, http.MethodPost, "/v1/orders/"+url.PathEscape(orderID)+"/refunds", nil, req, &rf); err != nil {
+		return nil, err
+	}
+	return &rf, nil
+}
+
+// Overpayment is a further transfer reported for an order that was already paid, refundable to
+// FromAddress.
+type Overpayment struct {
+	ID           string  `json:"id"`
+	OrderID      string  `json:"order_id"`
+	MerchantID   string  `json:"merchant_id"`
+	Asset        string  `json:"asset"`
+	Chain        string  `json:"chain"`
+	AmountMinor  string  `json:"amount_minor"`
+	TxHash       string  `json:"tx_hash"`
+	FromAddress  string  `json:"from_address"`
+	Status       string  `json:"status"` // OPEN, REFUND_QUEUED, REFUND_SENT, REFUNDED or REFUND_FAILED
+	RefundTxHash *string `json:"refund_tx_hash,omitempty"`
+	LastError    *string `json:"last_error,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+	RefundedAt   *string `json:"refunded_at,omitempty"`
+}
+
+// ListOverpayments returns the merchant's overpayments, newest first; an empty status or orderID
+// does not filter.
+func (c *Client) ListOverpayments(ctx context.Context, status, orderID string) ([]Overpayment, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	if orderID != "" {
+		q.Set("order_id", orderID)
+	}
+	var l []Overpayment
+	if err := c.do(ctx, http.MethodGet, "/v1/overpayments", q, nil, &l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// RefundOverpayment sends an OPEN overpayment back to its sender from the hot wallet, or sends a
+// REFUND_FAILED one again.
+func (c *Client) RefundOverpayment(ctx context.Context, id string) (*Overpayment, error) {
+	var o Overpayment
+	if err := c.do(ctx, http.MethodPost, "/v1/overpayments/"+url.PathEscape(id)+"/refund", nil, nil, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// PaymentEvent is a payment reported for an order. A nil AmountMinor means the order's amount.
+type PaymentEvent struct {
+	OrderID     string  `json:"order_id"`
+	TxHash      string  `json:"tx_hash"`
+	AmountMinor *string `json:"amount_minor,omitempty"`
+}
+
+// PaymentResult is the server's answer to ReportPayment. Status is PENDING while the payment
+// waits for verification in the background; poll GetOrder for the outcome.
+type PaymentResult struct {
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// ReportPayment notifies the server of an on-chain payment for an order, which it verifies before
+// crediting the order.
+func (c *Client) ReportPayment(ctx context.Context, ev PaymentEvent) (*PaymentResult, error) {
+	var res PaymentResult
+	if err := c.do(ctx, http.MethodPost, "/v1/events/payment-detected", nil, ev, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// RateQuote is an exchange rate as the server served it.
+type RateQuote struct {
+	QuoteID   string `json:"quote_id"`
+	Base      string `json:"base"`
+	Quote     string `json:"quote"`
+	Rate      string `json:"rate"` // quote per unit of base, as a decimal
+	UpdatedAt string `json:"updated_at"`
+	Provider  string `json:"provider"`
+	Source    string `json:"source,omitempty"`
+	QuotedAt  string `json:"quoted_at"`
+}
+
+// GetRate returns the current rate of base in quote, recorded by the server under its QuoteID.
+func (c *Client) GetRate(ctx context.Context, base, quote string) (*RateQuote, error) {
+	var rq RateQuote
+	if err := c.do(ctx, http.MethodGet, "/v1/rates", url.Values{"base": {base}, "quote": {quote}}, nil, &rq); err != nil {
+		return nil, err
+	}
+	return &rq, nil
+}
+
+// RateHistoryParams filters RateHistory. Zero values mean no filter and the server's default limit.
+type RateHistoryParams struct {
+	Base, Quote string
+	From, To    time.Time
+	Limit       int
+}
+
+// RateHistory returns the rates served to the merchant, newest first.
+func (c *Client) RateHistory(ctx context.Context, p RateHistoryParams) ([]RateQuote, error) {
+	q := url.Values{}
+	if p.Base != "" {
+		q.Set("base", p.Base)
+	}
+	if p.Quote != "" {
+		q.Set("quote", p.Quote)
+	}
+	if !p.From.IsZero() {
+		q.Set("from", p.From.UTC().Format(time.RFC3339))
+	}
+	if !p.To.IsZero() {
+		q.Set("to", p.To.UTC().Format(time.RFC3339))
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	var resp struct {
+		Quotes []RateQuote `json:"quotes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/rates/history", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Quotes, nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the webhook signature: "t=<unix seconds>,v1=<hex>", where v1 is
+// HMAC-SHA256 over "<t>.<raw body>" keyed with the merchant's webhook secret. During a secret
+// rotation a delivery may carry several v1 values; any one of them matching is enough.
+const SignatureHeader = "X-OSPay-Signature"
+
+// DefaultTolerance is the maximum accepted age of a webhook signature.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrSignatureMissing = errors.New("ospay: webhook signature missing or malformed")
+	ErrSignatureInvalid = errors.New("ospay: webhook signature does not match")
+	ErrSignatureExpired = errors.New("ospay: webhook timestamp outside tolerance")
+)
+
+// VerifyWebhook checks the SignatureHeader value of a delivery against its raw body. A tolerance
+// of zero uses DefaultTolerance. Verify before parsing the body, and use the raw bytes as
+// received: re-encoded JSON will not match.
+func VerifyWebhook(payload []byte, header, secret string, tolerance time.Duration) error {
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			if b, err := hex.DecodeString(v); err == nil {
+				sigs = append(sigs, b)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrSignatureMissing
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(payload)
+	want := mac.Sum(nil)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+	return ErrSignatureInvalid
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WebhookTestResult is the outcome of TestWebhook: how the merchant's receiver answered a signed
+// sample event.
+type WebhookTestResult struct {
+	URL        string `json:"url"`
+	EventID    string `json:"event_id"`
+	EventType  string `json:"event_type"`
+	Success    bool   `json:"success"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMS  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+	Response   string `json:"response,omitempty"`
+}
+
+// TestWebhook sends a sample event of eventType (empty for order.paid) to the configured webhook
+// URL. A receiver that answers with a non-2xx status is reported in the result, not as an error.
+func (c *Client) TestWebhook(ctx context.Context, eventType string) (*WebhookTestResult, error) {
+	var res WebhookTestResult
+	body := map[string]string{}
+	if eventType != "" {
+		body["event_type"] = eventType
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/webhooks/test", nil, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ReplayRequest selects the events ReplayEvents delivers again: those of one order, those created
+// in [From, To), or both. A zero To means now.
+type ReplayRequest struct {
+	OrderID string
+	From    time.Time
+	To      time.Time
+}
+
+// ReplayResult reports how many events were queued for re-delivery.
+type ReplayResult struct {
+	Replayed int    `json:"replayed"`
+	OrderID  string `json:"order_id,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+}
+
+// ReplayEvents queues past events for delivery again. Replayed deliveries carry "replay_of" with
+// the id of the original event.
+func (c *Client) ReplayEvents(ctx context.Context, req ReplayRequest) (*ReplayResult, error) {
+	body := map[string]string{}
+	if req.OrderID != "" {
+		body["order_id"] = req.OrderID
+	}
+	if !req.From.IsZero() {
+		body["from"] = req.From.UTC().Format(time.RFC3339)
+	}
+	if !req.To.IsZero() {
+		body["to"] = req.To.UTC().Format(time.RFC3339)
+	}
+	var res ReplayResult
+	if err := c.do(ctx, http.MethodPost, "/v1/events/replay", nil, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
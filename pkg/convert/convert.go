@@ -0,0 +1,49 @@
+// Package convert swaps settled funds into the asset and chain a merchant wants to be paid in,
+// through a DEX or bridge aggregator.
+package convert
+
+import (
+	"context"
+	"math/big"
+)
+
+// Request asks for a conversion of AmountIn of FromToken on FromChain into ToToken on ToChain.
+// Tokens are ERC-20 contract addresses; From sends the input and To receives the output.
+type Request struct {
+	FromChain, ToChain string // OSPay chain names, e.g. "BSC"
+	FromToken, ToToken string
+	AmountIn           *big.Int
+	From, To           string
+	SlippageBps        int64 // the most the output may fall below the quote, in basis points
+}
+
+// Quote is a provider's offer for a Request together with the transaction that executes it.
+type Quote struct {
+	ID           string
+	AmountOut    *big.Int // expected output in ToToken's smallest unit
+	AmountOutMin *big.Int // guaranteed output; the swap reverts below it
+	// The call the sender makes on FromChain, after approving Spender for AmountIn of FromToken.
+	Spender string
+	TxTo    string
+	TxData  []byte
+	TxGas   uint64
+}
+
+// Status is the progress of an executed conversion, which for cross-chain routes completes some
+// time after the source transaction is mined.
+type Status struct {
+	Done      bool
+	Failed    bool
+	AmountOut *big.Int // what To received, once done
+	TxHash    string   // the receiving transaction on ToChain, once done
+	Reason    string
+}
+
+// Provider quotes conversions and follows them. Implementations wrap an aggregator API (LI.FI,
+// 1inch, ...) or an exchange.
+type Provider interface {
+	Name() string
+	Quote(ctx context.Context, req Request) (Quote, error)
+	// Status reports the conversion started by txHash, the mined source transaction.
+	Status(ctx context.Context, req Request, txHash string) (Status, error)
+}
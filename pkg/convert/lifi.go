@@ -0,0 +1,153 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const lifiURL = "https://li.quest/v1"
+
+// lifiChainIDs maps OSPay chain names to the chain IDs LI.FI identifies chains by.
+var lifiChainIDs = map[string]int{"ETH": 1, "BSC": 56, "POLYGON": 137}
+
+// LiFi converts through the LI.FI aggregator, which routes same-chain swaps through DEXes and
+// cross-chain ones through bridges.
+type LiFi struct {
+	APIKey  string // optional; raises the rate limit
+	BaseURL string // defaults to the public API
+	Client  *http.Client
+}
+
+func (l *LiFi) Name() string { return "lifi" }
+
+func (l *LiFi) get(ctx context.Context, path string, q url.Values, out any) error {
+	base := l.BaseURL
+	if base == "" {
+		base = lifiURL
+	}
+	client := l.Client
+	if client == nil {
+		client = &http.Client{Timeout: 20 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if l.APIKey != "" {
+		req.Header.Set("x-lifi-api-key", l.APIKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("lifi %s: status %d %s", path, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return json.Unmarshal(b, out)
+}
+
+func chainParams(req Request) (url.Values, error) {
+	from, ok1 := lifiChainIDs[req.FromChain]
+	to, ok2 := lifiChainIDs[req.ToChain]
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("lifi: unsupported route %s -> %s", req.FromChain, req.ToChain)
+	}
+	return url.Values{"fromChain": {strconv.Itoa(from)}, "toChain": {strconv.Itoa(to)}}, nil
+}
+
+func (l *LiFi) Quote(ctx context.Context, req Request) (Quote, error) {
+	q, err := chainParams(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	q.Set("fromToken", req.FromToken)
+	q.Set("toToken", req.ToToken)
+	q.Set("fromAmount", req.AmountIn.String())
+	q.Set("fromAddress", req.From)
+	q.Set("toAddress", req.To)
+	q.Set("slippage", strconv.FormatFloat(float64(req.SlippageBps)/10000, 'f', -1, 64))
+	var body struct {
+		ID       string `json:"id"`
+		Estimate struct {
+			ToAmount        string `json:"toAmount"`
+			ToAmountMin     string `json:"toAmountMin"`
+			ApprovalAddress string `json:"approvalAddress"`
+		} `json:"estimate"`
+		TransactionRequest struct {
+			To       string `json:"to"`
+			Data     string `json:"data"`
+			Value    string `json:"value"`
+			GasLimit string `json:"gasLimit"`
+		} `json:"transactionRequest"`
+	}
+	if err := l.get(ctx, "/quote", q, &body); err != nil {
+		return Quote{}, err
+	}
+	out, ok1 := new(big.Int).SetString(body.Estimate.ToAmount, 10)
+	min, ok2 := new(big.Int).SetString(body.Estimate.ToAmountMin, 10)
+	if !ok1 || !ok2 {
+		return Quote{}, fmt.Errorf("lifi: invalid quote amounts %q, %q", body.Estimate.ToAmount, body.Estimate.ToAmountMin)
+	}
+	if v, err := hexutil.DecodeBig(body.TransactionRequest.Value); err == nil && v.Sign() != 0 {
+		return Quote{}, fmt.Errorf("lifi: quote requires sending %s wei of native coin", v)
+	}
+	data, err := hexutil.Decode(body.TransactionRequest.Data)
+	if err != nil {
+		return Quote{}, fmt.Errorf("lifi: invalid transaction data: %w", err)
+	}
+	gas, err := hexutil.DecodeUint64(body.TransactionRequest.GasLimit)
+	if err != nil {
+		return Quote{}, fmt.Errorf("lifi: invalid gas limit: %w", err)
+	}
+	return Quote{
+		ID: body.ID, AmountOut: out, AmountOutMin: min, Spender: body.Estimate.ApprovalAddress,
+		TxTo: body.TransactionRequest.To, TxData: data, TxGas: gas,
+	}, nil
+}
+
+func (l *LiFi) Status(ctx context.Context, req Request, txHash string) (Status, error) {
+	q, err := chainParams(req)
+	if err != nil {
+		return Status{}, err
+	}
+	q.Set("txHash", txHash)
+	var body struct {
+		Status           string `json:"status"`    // NOT_FOUND | PENDING | DONE | FAILED
+		Substatus        string `json:"substatus"` // with DONE: COMPLETED | PARTIAL | REFUNDED
+		SubstatusMessage string `json:"substatusMessage"`
+		Receiving        struct {
+			TxHash string `json:"txHash"`
+			Amount string `json:"amount"`
+		} `json:"receiving"`
+	}
+	if err := l.get(ctx, "/status", q, &body); err != nil {
+		return Status{}, err
+	}
+	switch {
+	case body.Status == "FAILED":
+		return Status{Failed: true, Reason: body.SubstatusMessage}, nil
+	case body.Status == "DONE" && body.Substatus != "COMPLETED":
+		// PARTIAL delivers another token, REFUNDED returns the input: not the requested conversion.
+		return Status{Failed: true, Reason: strings.ToLower(body.Substatus) + ": " + body.SubstatusMessage}, nil
+	case body.Status == "DONE":
+		amount, ok := new(big.Int).SetString(body.Receiving.Amount, 10)
+		if !ok {
+			return Status{}, fmt.Errorf("lifi: invalid received amount %q", body.Receiving.Amount)
+		}
+		return Status{Done: true, AmountOut: amount, TxHash: body.Receiving.TxHash}, nil
+	}
+	return Status{}, nil
+}
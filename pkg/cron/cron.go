@@ -0,0 +1,169 @@
+// Package cron parses standard five-field cron expressions and computes when they next fire.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields take *, numbers, ranges (1-5), steps (*/15, 0-30/10) and comma-separated lists; months
+// and weekdays also take names (JAN, MON). Sunday is 0 or 7. As in Vixie cron, when both day
+// fields are restricted a day matches either of them. The macros @yearly, @monthly, @weekly,
+// @daily and @hourly are accepted, and a leading CRON_TZ=<zone> evaluates the expression in that
+// time zone instead of UTC.
+type Schedule struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+	loc                           *time.Location
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	dayNames   = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+// Parse parses a cron expression.
+func Parse(expr string) (*Schedule, error) {
+	s := &Schedule{expr: strings.TrimSpace(expr), loc: time.UTC}
+	spec := s.expr
+	if strings.HasPrefix(spec, "CRON_TZ=") {
+		zone, rest, _ := strings.Cut(strings.TrimPrefix(spec, "CRON_TZ="), " ")
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("cron: %w", err)
+		}
+		s.loc, spec = loc, strings.TrimSpace(rest)
+	}
+	if m, ok := macros[spec]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: %q: want 5 fields, got %d", expr, len(fields))
+	}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*" || fields[2] == "?"
+	s.dowAny = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+// parseField returns the set of values a field matches as a bitset.
+func parseField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("cron: invalid step in %q", part)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" && rng != "?" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = fieldValue(a, min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = fieldValue(b, min, max, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("cron: invalid range %q", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func fieldValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("cron: %q is not in %d-%d", s, min, max)
+	}
+	return v, nil
+}
+
+func (s *Schedule) String() string { return s.expr }
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t the schedule fires, or the zero time if it never does
+// (e.g. 30 February).
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, s.loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.loc).Add(time.Hour)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
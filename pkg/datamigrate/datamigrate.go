@@ -0,0 +1,359 @@
+// Package datamigrate copies an OSPay SQLite database into another SQL backend (Postgres) and
+// checks the copy before committing it.
+package datamigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Tables is the preferred copy order of the core tables. Every table of the source is copied, not
+// only these: Copy lists them from sqlite_master and orders them so that a table comes after the
+// tables its foreign keys reference, then as here, then by name.
+var Tables = []string{
+	"platforms", "merchants", "oauth_clients", "oauth_codes", "oauth_tokens", "api_keys",
+	"settlement_batches", "orders", "refunds", "disputes", "dispute_evidence", "ledger_entries", "ledger_balances",
+	"outbox_events", "outbox_sequences", "metric_counters", "audit_log", "orders_archive", "refunds_archive", "ledger_entries_archive",
+	"outbox_events_archive",
+}
+
+// sourceTables returns the tables of the SQLite database src in copy order (see Tables).
+func sourceTables(ctx context.Context, src *sql.DB) ([]string, error) {
+	rows, err := src.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rank := func(name string) int {
+		for i, t := range Tables {
+			if t == name {
+				return i
+			}
+		}
+		return len(Tables)
+	}
+	slices.SortStableFunc(names, func(a, b string) int { return rank(a) - rank(b) })
+
+	parents := map[string][]string{}
+	for _, name := range names {
+		fks, err := src.QueryContext(ctx, `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?)`, name)
+		if err != nil {
+			return nil, err
+		}
+		for fks.Next() {
+			var parent string
+			if err := fks.Scan(&parent); err != nil {
+				fks.Close()
+				return nil, err
+			}
+			if parent != name {
+				parents[name] = append(parents[name], parent)
+			}
+		}
+		fks.Close()
+		if err := fks.Err(); err != nil {
+			return nil, err
+		}
+	}
+	ordered := make([]string, 0, len(names))
+	state := map[string]int{} // 1 while visiting, 2 once placed
+	var visit func(string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("foreign keys of %s form a cycle", name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, p := range parents[name] {
+			if slices.Contains(names, p) {
+				if err := visit(p); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = 2
+		ordered = append(ordered, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Options controls a copy. Placeholder is the target's bind-parameter style: "$" for Postgres
+// ($1, $2, ...), anything else for "?".
+type Options struct {
+	DryRun      bool
+	Placeholder string
+	BatchSize   int
+}
+
+// TableReport is the per-table outcome.
+type TableReport struct {
+	Table       string   `json:"table"`
+	SourceRows  int64    `json:"source_rows"`
+	CopiedRows  int64    `json:"copied_rows"`
+	TargetRows  int64    `json:"target_rows"`
+	SkippedCols []string `json:"skipped_columns,omitempty"` // source columns the target does not have
+}
+
+// Report summarizes a run. Balances maps "merchant/asset/bucket" to the net ledger balance.
+type Report struct {
+	DryRun   bool              `json:"dry_run"`
+	Tables   []TableReport     `json:"tables"`
+	Balances map[string]string `json:"balances"`
+}
+
+// Copy copies every table of the SQLite database src into dst inside one target transaction. The
+// target schema must already exist, with every source table, and be empty. Before committing it compares row counts and the per-merchant ledger
+// balances of both sides; any mismatch rolls the copy back. With DryRun nothing is written: the
+// source is read, the target tables are checked, and the report shows what would be copied.
+func Copy(ctx context.Context, src, dst *sql.DB, opts Options) (Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	rep := Report{DryRun: opts.DryRun}
+
+	tx, err := dst.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return rep, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	tables, err := sourceTables(ctx, src)
+	if err != nil {
+		return rep, fmt.Errorf("source tables: %w", err)
+	}
+	for _, table := range tables {
+		srcCols, err := columns(ctx, src, table)
+		if err != nil {
+			return rep, fmt.Errorf("source %s: %w", table, err)
+		}
+		dstCols, err := columns(ctx, tx, table)
+		if err != nil {
+			return rep, fmt.Errorf("target %s: %w", table, err)
+		}
+		if dstCols == nil {
+			return rep, fmt.Errorf("target is missing table %s; create the schema first", table)
+		}
+		tr := TableReport{Table: table}
+		if tr.SourceRows, err = count(ctx, src, table); err != nil {
+			return rep, err
+		}
+		existing, err := count(ctx, tx, table)
+		if err != nil {
+			return rep, err
+		}
+		if existing > 0 {
+			return rep, fmt.Errorf("target table %s is not empty (%d rows)", table, existing)
+		}
+		var cols []string
+		for _, c := range srcCols {
+			if contains(dstCols, c) {
+				cols = append(cols, c)
+			} else {
+				tr.SkippedCols = append(tr.SkippedCols, c)
+			}
+		}
+		if !opts.DryRun {
+			if tr.CopiedRows, err = copyTable(ctx, src, tx, table, cols, opts); err != nil {
+				return rep, fmt.Errorf("copy %s: %w", table, err)
+			}
+			if tr.TargetRows, err = count(ctx, tx, table); err != nil {
+				return rep, err
+			}
+			if tr.TargetRows != tr.SourceRows {
+				return rep, fmt.Errorf("row count mismatch for %s: source %d, target %d", table, tr.SourceRows, tr.TargetRows)
+			}
+		}
+		rep.Tables = append(rep.Tables, tr)
+	}
+
+	if rep.Balances, err = balances(ctx, src); err != nil {
+		return rep, fmt.Errorf("source balances: %w", err)
+	}
+	if opts.DryRun {
+		return rep, nil
+	}
+	got, err := balances(ctx, tx)
+	if err != nil {
+		return rep, fmt.Errorf("target balances: %w", err)
+	}
+	for key, want := range rep.Balances {
+		if got[key] != want {
+			return rep, fmt.Errorf("ledger balance mismatch for %s: source %s, target %s", key, want, got[key])
+		}
+	}
+	if len(got) != len(rep.Balances) {
+		return rep, errors.New("ledger balance mismatch: target has balances the source does not")
+	}
+	return rep, tx.Commit()
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// columns returns the table's column names, or nil when the table does not exist. It works on any
+// backend by reading the result metadata of an empty select.
+func columns(ctx context.Context, q queryer, table string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT * FROM `+table+` WHERE 1 = 0`)
+	if err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// isMissingTable recognizes the SQLite and Postgres "no such table" errors.
+func isMissingTable(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "no such table") || (strings.Contains(msg, "relation") && strings.Contains(msg, "does not exist"))
+}
+
+func count(ctx context.Context, q queryer, table string) (int64, error) {
+	var n int64
+	err := q.QueryRowContext(ctx, `SELECT COUNT(1) FROM `+table).Scan(&n)
+	return n, err
+}
+
+func copyTable(ctx context.Context, src *sql.DB, tx *sql.Tx, table string, cols []string, opts Options) (int64, error) {
+	list := strings.Join(cols, ", ")
+	rows, err := src.QueryContext(ctx, `SELECT `+list+` FROM `+table)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var (
+		copied int64
+		batch  [][]any
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var (
+			sb   strings.Builder
+			args []any
+		)
+		sb.WriteString(`INSERT INTO ` + table + ` (` + list + `) VALUES `)
+		for i, row := range batch {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("(")
+			for j := range row {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				args = append(args, row[j])
+				if opts.Placeholder == "$" {
+					sb.WriteString("$" + strconv.Itoa(len(args)))
+				} else {
+					sb.WriteString("?")
+				}
+			}
+			sb.WriteString(")")
+		}
+		if _, err := tx.ExecContext(ctx, sb.String(), args...); err != nil {
+			return err
+		}
+		copied += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return copied, err
+		}
+		batch = append(batch, vals)
+		if len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return copied, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return copied, err
+	}
+	return copied, flush()
+}
+
+// balances sums ledger_entries per merchant, asset and bucket with arbitrary precision, since
+// amount_minor is stored as text.
+func balances(ctx context.Context, q queryer) (map[string]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT merchant_id, asset, bucket, direction, amount_minor FROM ledger_entries`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sums := map[string]*big.Int{}
+	for rows.Next() {
+		var merchantID, asset, bucket, direction, amount string
+		if err := rows.Scan(&merchantID, &asset, &bucket, &direction, &amount); err != nil {
+			return nil, err
+		}
+		v, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount_minor %q", amount)
+		}
+		if direction == "debit" {
+			v.Neg(v)
+		}
+		key := merchantID + "/" + asset + "/" + bucket
+		if sums[key] == nil {
+			sums[key] = new(big.Int)
+		}
+		sums[key].Add(sums[key], v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(sums))
+	for k, v := range sums {
+		out[k] = v.String()
+	}
+	return out, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package db
+
+import "database/sql"
+
+// archivedTables are copied into <table>_archive by the retention job. Archive tables carry the
+// same columns without the hot table's constraints, plus archived_at.
+var archivedTables = []string{"orders", "refunds", "ledger_entries", "outbox_events"}
+
+// ensureArchiveTables creates the archive tables and adds any column the hot table has gained
+// since, so rows can be copied column-for-column.
+func ensureArchiveTables(db *sql.DB) error {
+	for _, table := range archivedTables {
+		archive := table + "_archive"
+		if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + archive + ` AS SELECT * FROM ` + table + ` WHERE 0`); err != nil {
+			return err
+		}
+		rows, err := db.Query(`SELECT name, type FROM pragma_table_info(?)`, table)
+		if err != nil {
+			return err
+		}
+		var cols [][2]string
+		for rows.Next() {
+			var name, typ string
+			if err := rows.Scan(&name, &typ); err != nil {
+				rows.Close()
+				return err
+			}
+			cols = append(cols, [2]string{name, typ})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, c := range cols {
+			if err := addColumnIfMissing(db, archive, c[0], c[1]); err != nil {
+				return err
+			}
+		}
+		if err := addColumnIfMissing(db, archive, "archived_at", "TEXT"); err != nil {
+			return err
+		}
+	}
+	_, err := db.Exec(`
+CREATE INDEX IF NOT EXISTS idx_orders_archive_id ON orders_archive(id);
+CREATE INDEX IF NOT EXISTS idx_orders_archive_merchant ON orders_archive(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_archive_customer_email_hash ON orders_archive(customer_email_hash);
+CREATE INDEX IF NOT EXISTS idx_orders_archive_tx_hash ON orders_archive(tx_hash);
+CREATE INDEX IF NOT EXISTS idx_orders_archive_external ON orders_archive(merchant_id, external_order_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_archive_order ON refunds_archive(order_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_archive_order ON ledger_entries_archive(order_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_archive_merchant ON outbox_events_archive(merchant_id, created_at);
+`)
+	return err
+}
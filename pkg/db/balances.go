@@ -0,0 +1,147 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// backfillLedgerBalances materializes ledger_balances from ledger_entries when the table is new.
+// Amounts are summed with arbitrary precision since they can exceed SQL integers.
+func backfillLedgerBalances(db *sql.DB) error {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(1) FROM ledger_balances`).Scan(&n); err != nil || n > 0 {
+		return err
+	}
+	rows, err := db.Query(`SELECT merchant_id, asset, COALESCE(chain, ''), bucket, direction, amount_minor FROM ledger_entries`)
+	if err != nil {
+		return err
+	}
+	type key struct{ merchantID, asset, chain, bucket string }
+	sums := map[key]*big.Int{}
+	var keys []key
+	for rows.Next() {
+		var (
+			k                 key
+			direction, amount string
+		)
+		if err := rows.Scan(&k.merchantID, &k.asset, &k.chain, &k.bucket, &direction, &amount); err != nil {
+			rows.Close()
+			return err
+		}
+		v, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			rows.Close()
+			return fmt.Errorf("invalid amount_minor %q", amount)
+		}
+		if direction == "debit" {
+			v.Neg(v)
+		}
+		if sums[k] == nil {
+			sums[k] = new(big.Int)
+			keys = append(keys, k)
+		}
+		sums[k].Add(sums[k], v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(keys) == 0 {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, k := range keys {
+		if _, err := tx.Exec(`
+			INSERT INTO ledger_balances (merchant_id, asset, chain, bucket, balance_minor, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		`, k.merchantID, k.asset, k.chain, k.bucket, sums[k].String(), now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// backfillSettlementEntries moves funds settled before settlements were booked in the ledger out of
+// the merchant bucket: everything in it except the entries of orders still PAID or
+// PARTIALLY_REFUNDED goes to the settlement bucket, one SETTLEMENT pair per merchant, asset and
+// chain. It runs once, while no SETTLEMENT entry exists, and has ledger_balances rebuilt.
+func backfillSettlementEntries(db *sql.DB) error {
+	var pending int
+	if err := db.QueryRow(`
+		SELECT COUNT(1) FROM settlement_batches
+		WHERE status = 'EXECUTED' AND NOT EXISTS (SELECT 1 FROM ledger_entries WHERE event_type = 'SETTLEMENT')
+	`).Scan(&pending); err != nil || pending == 0 {
+		return err
+	}
+	rows, err := db.Query(`
+		SELECT l.merchant_id, l.asset, COALESCE(l.chain, ''), l.direction, l.amount_minor
+		FROM ledger_entries l LEFT JOIN orders o ON o.id = l.order_id
+		WHERE l.bucket = 'merchant' AND (o.status IS NULL OR o.status NOT IN ('PAID', 'PARTIALLY_REFUNDED'))
+	`)
+	if err != nil {
+		return err
+	}
+	type key struct{ merchantID, asset, chain string }
+	sums := map[key]*big.Int{}
+	var keys []key
+	for rows.Next() {
+		var (
+			k                 key
+			direction, amount string
+		)
+		if err := rows.Scan(&k.merchantID, &k.asset, &k.chain, &direction, &amount); err != nil {
+			rows.Close()
+			return err
+		}
+		v, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			rows.Close()
+			return fmt.Errorf("invalid amount_minor %q", amount)
+		}
+		if direction == "debit" {
+			v.Neg(v)
+		}
+		if sums[k] == nil {
+			sums[k] = new(big.Int)
+			keys = append(keys, k)
+		}
+		sums[k].Add(sums[k], v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, k := range keys {
+		amount := sums[k]
+		if amount.Sign() == 0 {
+			continue
+		}
+		from, to := "merchant", "settlement"
+		if amount.Sign() < 0 {
+			from, to = to, from
+		}
+		for _, e := range []struct{ side, bucket, direction string }{{"a", from, "debit"}, {"b", to, "credit"}} {
+			group := "ltx_settlement_backfill_" + k.merchantID + "_" + k.asset + "_" + k.chain
+			id := "led_settlement_backfill_" + e.side + "_" + k.merchantID + "_" + k.asset + "_" + k.chain
+			if _, err := tx.Exec(`
+				INSERT INTO ledger_entries (id, merchant_id, asset, chain, amount_minor, bucket, direction, event_type, transaction_group_id, created_at)
+				VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, 'SETTLEMENT', ?, ?)
+			`, id, k.merchantID, k.asset, k.chain, new(big.Int).Abs(amount).String(), e.bucket, e.direction, group, now); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM ledger_balances`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
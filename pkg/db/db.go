@@ -3,18 +3,23 @@ package db
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
+	"github.com/oxzoid/OSPay/pkg/store"
 	_ "modernc.org/sqlite" // SQLite driver
 )
 
 type DB = sql.DB
 
 func Open(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite", dsn) // e.g., "file:ospay.db?_pragma=busy_timeout=5000"
+	sqlite, err := sql.Open("sqlite", dsn) // e.g., "file:ospay.db?_pragma=busy_timeout=5000"
 	if err != nil {
 		return nil, err
 	}
+	// Connect through the SQLite driver with statements retried on SQLITE_BUSY and transactions timed
+	db := sql.OpenDB(store.Instrument(sqlite.Driver(), dsn))
+	_ = sqlite.Close()
 	// Harden SQLite for concurrent access: WAL, reasonable sync and busy timeout
 	// Note: Still a single writer. For heavy write loads, consider Postgres.
 	_, err = db.Exec(`
@@ -39,6 +44,29 @@ func Open(dsn string) (*sql.DB, error) {
 	return db, nil
 }
 
+// OpenReplica opens a read-only replica of the database, such as a LiteFS replica of ospay.db,
+// for reporting reads. Its connections are query_only, so nothing can write to it by mistake.
+func OpenReplica(dsn string) (*sql.DB, error) {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", dsn+sep+"_pragma=query_only=1")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
 func EnsureSchema(db *sql.DB) error {
 	ddl := `
 CREATE TABLE IF NOT EXISTS orders (
@@ -60,10 +88,45 @@ CREATE TABLE IF NOT EXISTS orders (
 CREATE TABLE IF NOT EXISTS merchants (
   id TEXT PRIMARY KEY,
   name TEXT,
-  api_key TEXT NOT NULL UNIQUE,
+  api_key TEXT NOT NULL UNIQUE,      -- sha256 of the primary API key
   merchant_wallet_address TEXT,
   created_at TEXT NOT NULL DEFAULT (datetime('now'))
 );
+CREATE TABLE IF NOT EXISTS platforms (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  api_key TEXT NOT NULL UNIQUE,      -- sha256 of the platform API key
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  id TEXT PRIMARY KEY,
+  platform_id TEXT NOT NULL REFERENCES platforms(id),
+  name TEXT NOT NULL,
+  secret_hash TEXT NOT NULL,       -- sha256 of the client secret
+  redirect_uri TEXT,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+CREATE TABLE IF NOT EXISTS oauth_codes (
+  code_hash TEXT PRIMARY KEY,
+  client_id TEXT NOT NULL REFERENCES oauth_clients(id),
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  scope TEXT NOT NULL,
+  redirect_uri TEXT,
+  expires_at TEXT NOT NULL,
+  used_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+CREATE TABLE IF NOT EXISTS oauth_tokens (
+  token_hash TEXT PRIMARY KEY,
+  token_type TEXT NOT NULL,        -- 'access' | 'refresh'
+  grant_id TEXT NOT NULL,          -- shared by all tokens from one authorization
+  client_id TEXT NOT NULL REFERENCES oauth_clients(id),
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  scope TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  revoked_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
 CREATE TABLE IF NOT EXISTS ledger_entries (
   id TEXT PRIMARY KEY,
   order_id TEXT,
@@ -77,6 +140,69 @@ CREATE TABLE IF NOT EXISTS ledger_entries (
   created_at TEXT NOT NULL DEFAULT (datetime('now'))
 );
 
+-- Net of every merchant bucket per asset and chain, maintained with each ledger entry
+CREATE TABLE IF NOT EXISTS ledger_balances (
+  merchant_id TEXT NOT NULL,
+  asset TEXT NOT NULL,
+  chain TEXT NOT NULL DEFAULT '',  -- '' for entries not tied to a chain
+  bucket TEXT NOT NULL,
+  balance_minor TEXT NOT NULL,     -- credits minus debits
+  updated_at TEXT NOT NULL,
+  PRIMARY KEY (merchant_id, asset, chain, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS refunds (
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id),
+  merchant_id TEXT NOT NULL,
+  amount_minor TEXT NOT NULL,     -- String to handle arbitrarily large 18-decimal numbers
+  status TEXT NOT NULL,           -- 'REQUESTED' | 'COMPLETED' | 'REJECTED'
+  refund_tx_hash TEXT,
+  idempotency_key TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),
+  UNIQUE (order_id, idempotency_key)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  key_hash TEXT UNIQUE NOT NULL,   -- sha256 of the key; the plaintext is only returned at creation
+  label TEXT NOT NULL DEFAULT '',
+  scope TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),
+  revoked_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS disputes (
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id),
+  merchant_id TEXT NOT NULL,
+  asset TEXT NOT NULL,
+  amount_minor TEXT NOT NULL,      -- String to handle arbitrarily large 18-decimal numbers
+  reason TEXT NOT NULL,
+  status TEXT NOT NULL,            -- 'OPEN' | 'WON' | 'LOST'
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),
+  resolved_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS dispute_evidence (
+  id TEXT PRIMARY KEY,
+  dispute_id TEXT NOT NULL REFERENCES disputes(id),
+  author TEXT NOT NULL,            -- 'merchant' | 'admin'
+  note TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+  id TEXT PRIMARY KEY,
+  actor TEXT NOT NULL,             -- 'admin' | 'key:<id>' | 'oauth:<grant>' | 'system'
+  merchant_id TEXT,
+  order_id TEXT,
+  action TEXT NOT NULL,            -- e.g. 'limit_exceeded'
+  detail_json TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
 CREATE TABLE IF NOT EXISTS settlement_batches (
   id TEXT PRIMARY KEY,
   merchant_id TEXT NOT NULL,
@@ -90,7 +216,7 @@ CREATE TABLE IF NOT EXISTS settlement_batches (
 
 CREATE TABLE IF NOT EXISTS outbox_events (
   id TEXT PRIMARY KEY,
-  aggregate_type TEXT NOT NULL,    -- 'order' | 'batch'
+  aggregate_type TEXT NOT NULL,    -- 'order' | 'refund' | 'dispute'
   aggregate_id TEXT NOT NULL,
   event_name TEXT NOT NULL,
   payload_json TEXT NOT NULL,
@@ -98,12 +224,536 @@ CREATE TABLE IF NOT EXISTS outbox_events (
   delivered_at TEXT,
   retry_count INTEGER NOT NULL DEFAULT 0
 );
+
+CREATE TABLE IF NOT EXISTS customers (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  wallet_address TEXT NOT NULL COLLATE NOCASE, -- payer of verified payments; EVM addresses differ only in case
+  first_paid_at TEXT NOT NULL,
+  last_paid_at TEXT NOT NULL,
+  payment_count INTEGER NOT NULL DEFAULT 0,
+  UNIQUE (merchant_id, wallet_address)
+);
+
+CREATE TABLE IF NOT EXISTS payouts (
+  id TEXT PRIMARY KEY,
+  batch_id TEXT NOT NULL REFERENCES settlement_batches(id),
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  chain TEXT NOT NULL,
+  asset TEXT NOT NULL,
+  amount_minor TEXT NOT NULL,      -- the batch's net payout for orders on this chain
+  to_address TEXT NOT NULL,
+  mode TEXT NOT NULL,              -- 'hot_wallet' | 'safe'
+  status TEXT NOT NULL,            -- 'QUEUED' | 'SENT' | 'PROPOSED' | 'EXECUTED' | 'FAILED'
+  chain_tx_id TEXT,                -- hot wallet: the chain_transactions row
+  safe_address TEXT,
+  safe_nonce INTEGER,
+  safe_tx_hash TEXT,               -- safe: the proposal on the Safe transaction service
+  tx_hash TEXT,                    -- the executed transaction
+  last_error TEXT,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS conversions (
+  id TEXT PRIMARY KEY,
+  batch_id TEXT NOT NULL REFERENCES settlement_batches(id),
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  from_chain TEXT NOT NULL,
+  from_asset TEXT NOT NULL,
+  to_chain TEXT NOT NULL,
+  to_asset TEXT NOT NULL,
+  amount_in_minor TEXT NOT NULL,
+  quoted_out_minor TEXT,
+  min_out_minor TEXT,              -- quoted output less max_slippage_bps; the swap reverts below it
+  amount_out_minor TEXT,           -- what was delivered
+  max_slippage_bps INTEGER NOT NULL,
+  provider TEXT,                   -- e.g. 'lifi'
+  status TEXT NOT NULL,            -- 'QUEUED' | 'SUBMITTED' | 'COMPLETED' | 'FAILED'
+  chain_tx_id TEXT,                -- the swap's chain_transactions row
+  tx_hash TEXT,
+  receive_tx_hash TEXT,            -- delivery on to_chain
+  last_error TEXT,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS fiat_payouts (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  provider TEXT NOT NULL,
+  provider_payout_id TEXT,         -- the off-ramp partner's reference
+  chain TEXT NOT NULL,
+  asset TEXT NOT NULL,
+  amount_minor TEXT NOT NULL,
+  fiat_currency TEXT NOT NULL,     -- ISO 4217
+  fiat_amount_minor TEXT,          -- quoted, then what the partner paid
+  rate TEXT,
+  deposit_address TEXT,            -- where the partner takes the crypto
+  chain_tx_id TEXT,                -- the funding chain_transactions row
+  tx_hash TEXT,
+  status TEXT NOT NULL,            -- 'REQUESTED' | 'FUNDED' | 'PAID' | 'FAILED'
+  last_error TEXT,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS chain_transactions (
+  id TEXT PRIMARY KEY,
+  chain TEXT NOT NULL,
+  purpose TEXT NOT NULL,           -- 'payout' | 'payout_multisend' | 'approve' | 'refund' | 'overpayment_refund' | 'conversion' | 'cold_sweep' | 'offramp'
+  reference_id TEXT,               -- settlement batch or refund the transaction pays out
+  from_address TEXT NOT NULL,
+  nonce INTEGER NOT NULL,
+  tx_hash TEXT NOT NULL,           -- latest broadcast; earlier ones are in replaced_hashes
+  replaced_hashes TEXT NOT NULL DEFAULT '', -- comma-separated hashes of fee-bumped predecessors
+  raw_tx TEXT NOT NULL,            -- hex of the latest signed transaction
+  urgency TEXT NOT NULL,           -- fee profile: 'slow' | 'standard' | 'fast'
+  max_fee_wei TEXT NOT NULL,       -- maxFeePerGas, or the gas price of a legacy transaction
+  priority_fee_wei TEXT,           -- NULL for legacy transactions
+  bumps INTEGER NOT NULL DEFAULT 0,
+  status TEXT NOT NULL,            -- 'PENDING' | 'CANCELLING' | 'CONFIRMED' | 'FAILED' | 'CANCELLED' | 'DROPPED'
+  last_error TEXT,
+  submitted_at TEXT NOT NULL,      -- of the latest broadcast
+  created_at TEXT NOT NULL,
+  confirmed_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  scope TEXT NOT NULL,             -- hash of the request's credential
+  key TEXT NOT NULL,               -- Idempotency-Key header value
+  request_hash TEXT NOT NULL,      -- sha256 of method, path and body
+  status INTEGER,                  -- NULL while the first request is still running
+  content_type TEXT,
+  body BLOB,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (scope, key)
+);
+
+CREATE TABLE IF NOT EXISTS order_line_items (
+  order_id TEXT NOT NULL,          -- no foreign key: items stay when the retention job archives the order
+  position INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  quantity INTEGER NOT NULL,
+  unit_amount_minor TEXT NOT NULL,
+  PRIMARY KEY (order_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS coupons (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  code TEXT NOT NULL COLLATE NOCASE,
+  percent_off INTEGER,             -- 1-100; set for percentage coupons
+  amount_off_minor TEXT,           -- set for fixed coupons, in asset's minor units
+  asset TEXT,                      -- fixed coupons only apply to orders in this asset
+  max_redemptions INTEGER,         -- NULL: unlimited
+  redemptions INTEGER NOT NULL DEFAULT 0,
+  expires_at TEXT,
+  active INTEGER NOT NULL DEFAULT 1,
+  created_at TEXT NOT NULL,
+  UNIQUE (merchant_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS order_notes (
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL,          -- no foreign key: notes stay when the retention job archives the order
+  merchant_id TEXT NOT NULL,
+  author TEXT NOT NULL,            -- credential that wrote the note, or 'admin'
+  body TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS payment_attempts (
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL,          -- no foreign key: attempts stay when the retention job archives the order
+  merchant_id TEXT NOT NULL,
+  tx_hash TEXT NOT NULL,           -- lower case
+  status TEXT NOT NULL,            -- pending | succeeded | failed
+  failure_reason TEXT,             -- wrong_amount | wrong_token | wrong_chain | wrong_recipient | no_transfer | tx_not_found | order_expired | already_paid | tx_already_used | verification_error
+  detail TEXT,
+  submissions INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  UNIQUE (order_id, tx_hash)
+);
+
+CREATE TABLE IF NOT EXISTS overpayments (
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL,          -- the already paid order the transfer was reported for
+  merchant_id TEXT NOT NULL,
+  asset TEXT NOT NULL,
+  chain TEXT NOT NULL,
+  amount_minor TEXT NOT NULL,
+  tx_hash TEXT NOT NULL UNIQUE,    -- lower case
+  from_address TEXT NOT NULL,      -- sender, where the refund goes
+  status TEXT NOT NULL,            -- 'CONFIRMING' | 'REVIEW' | 'OPEN' | 'REFUND_QUEUED' | 'REFUND_SENT' | 'REFUNDED' | 'REFUND_FAILED'
+  chain_tx_id TEXT,                -- hot wallet transaction of the refund
+  refund_tx_hash TEXT,
+  last_error TEXT,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  refunded_at TEXT
+);
+
+-- Rates served by GET /rates, kept so the conversion behind a fiat-priced order can be checked later
+CREATE TABLE IF NOT EXISTS rate_quotes (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT,                -- who asked for it; NULL when an admin did
+  base TEXT NOT NULL,
+  quote TEXT NOT NULL,
+  rate TEXT NOT NULL,              -- quote per unit of base, as a decimal
+  provider TEXT NOT NULL,
+  source TEXT,                     -- e.g. the feed contract the rate was read from
+  rate_updated_at TEXT NOT NULL,   -- when the provider last updated the rate
+  created_at TEXT NOT NULL
+);
+
+-- Merchant balances that went below zero, e.g. after a refund of a settled order; one OPEN row per
+-- merchant, asset and chain until the balance is back at zero or above
+CREATE TABLE IF NOT EXISTS negative_balances (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  asset TEXT NOT NULL,
+  chain TEXT NOT NULL,
+  status TEXT NOT NULL,            -- 'OPEN' | 'RECOVERED'
+  amount_minor TEXT NOT NULL,      -- what the merchant owes, last seen; positive
+  peak_minor TEXT NOT NULL,        -- the most it owed
+  opened_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  recovered_at TEXT
+);
+
+-- Parts of a merchant's unsettled balance held by an administrator or platform, e.g. during a risk
+-- review; the funds sit in the balance_hold bucket and are not settled until released
+CREATE TABLE IF NOT EXISTS balance_holds (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  asset TEXT NOT NULL,
+  chain TEXT NOT NULL,
+  amount_minor TEXT NOT NULL,
+  reason TEXT NOT NULL,
+  status TEXT NOT NULL,            -- 'ACTIVE' | 'RELEASED' | 'EXPIRED'
+  placed_by TEXT NOT NULL,         -- 'admin' or 'platform:<id>'
+  platform_id TEXT,                -- the platform that placed it; only it (or an admin) releases it
+  expires_at TEXT,                 -- released automatically then; NULL holds until released
+  withheld_minor TEXT NOT NULL DEFAULT '0', -- how much of it settlements kept back
+  created_at TEXT NOT NULL,
+  released_at TEXT,
+  released_by TEXT,
+  release_reason TEXT,
+  release_batch_id TEXT            -- settlement batch that paid out the withheld part on release
+);
+
+CREATE TABLE IF NOT EXISTS order_tags (
+  order_id TEXT NOT NULL,          -- no foreign key: tags stay when the retention job archives the order
+  merchant_id TEXT NOT NULL,
+  tag TEXT NOT NULL,               -- trimmed and lower-cased
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (order_id, tag)
+);
+
+CREATE TABLE IF NOT EXISTS refund_jobs (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  status TEXT NOT NULL,            -- 'QUEUED' | 'RUNNING' | 'COMPLETED'
+  requested_by TEXT,               -- credential that submitted the job
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  completed_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS refund_job_items (
+  job_id TEXT NOT NULL REFERENCES refund_jobs(id),
+  position INTEGER NOT NULL,
+  order_id TEXT NOT NULL,
+  amount_minor TEXT,               -- NULL: the order's full remaining balance
+  idempotency_key TEXT NOT NULL,
+  status TEXT NOT NULL,            -- 'PENDING' | 'REFUNDED' | 'FAILED'
+  refund_id TEXT,
+  error TEXT,
+  PRIMARY KEY (job_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS outbox_sequences (
+  aggregate_type TEXT NOT NULL,
+  aggregate_id TEXT NOT NULL,
+  last_sequence INTEGER NOT NULL,  -- kept here so pruning old events never reuses a number
+  PRIMARY KEY (aggregate_type, aggregate_id)
+);
+
+CREATE TABLE IF NOT EXISTS metric_counters (
+  name TEXT PRIMARY KEY,
+  value INTEGER NOT NULL,  -- every instance adds its increments, so this is the total across them
+  updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS wallet_challenges (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  wallet_address TEXT NOT NULL,  -- the wallet the challenge was issued for
+  nonce TEXT NOT NULL,
+  message TEXT NOT NULL,         -- the EIP-191 message to sign
+  issued_at TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  used_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  key_id TEXT NOT NULL,            -- 'primary' for the merchant's own key, or an api_keys id
+  ip TEXT NOT NULL,                -- source IP the key was used from
+  request_count INTEGER NOT NULL,
+  first_used_at TEXT NOT NULL,
+  last_used_at TEXT NOT NULL,
+  PRIMARY KEY (merchant_id, key_id, ip)
+);
+
+CREATE TABLE IF NOT EXISTS payment_intents (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL REFERENCES merchants(id),
+  amount_minor TEXT NOT NULL,      -- may change until the intent is captured or voided
+  asset TEXT NOT NULL,
+  chain TEXT NOT NULL,
+  status TEXT NOT NULL,            -- REQUIRES_CAPTURE, CAPTURED or VOIDED
+  metadata TEXT,
+  external_order_id TEXT,
+  coupon_code TEXT,                -- redeemed when the intent is captured
+  order_id TEXT,                   -- the order created by capture
+  captured_amount_minor TEXT,      -- at most amount_minor
+  void_reason TEXT,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  captured_at TEXT,
+  voided_at TEXT
+);
+
+-- Background jobs of pkg/jobs
+CREATE TABLE IF NOT EXISTS jobs (
+  id TEXT PRIMARY KEY,
+  type TEXT NOT NULL,
+  payload TEXT NOT NULL,           -- JSON
+  dedupe_key TEXT,                 -- at most one PENDING or RUNNING job of the type per key
+  status TEXT NOT NULL,            -- PENDING, RUNNING, SUCCEEDED or DEAD
+  attempts INTEGER NOT NULL DEFAULT 0,
+  run_at TEXT NOT NULL,            -- not before; the next retry of a failed job
+  locked_by TEXT,                  -- instance working the job
+  locked_until TEXT,               -- its lease; past it, another instance may take the job
+  last_error TEXT,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  finished_at TEXT
+);
+
+-- Retry policies set through the admin API; they take precedence over RETRY_POLICY_<TYPE>
+CREATE TABLE IF NOT EXISTS retry_policies (
+  job_type TEXT PRIMARY KEY,       -- verification, webhook or payout
+  max_attempts INTEGER NOT NULL,
+  base_delay_ms INTEGER NOT NULL,
+  max_delay_ms INTEGER NOT NULL,
+  jitter REAL NOT NULL,
+  dead_letter TEXT NOT NULL,       -- keep or discard
+  updated_at TEXT NOT NULL
+);
+
+-- Locks of periodic jobs, so that one instance at a time runs each
+CREATE TABLE IF NOT EXISTS job_locks (
+  name TEXT PRIMARY KEY,
+  owner TEXT NOT NULL,
+  locked_until TEXT NOT NULL
+);
+
+-- Signed proof-of-reserves reports; report is the exact JSON the signature covers
+CREATE TABLE IF NOT EXISTS reserve_attestations (
+  id TEXT PRIMARY KEY,
+  report TEXT NOT NULL,
+  signature TEXT NOT NULL,
+  signer TEXT NOT NULL,
+  fully_backed INTEGER NOT NULL,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reserve_attestations_created ON reserve_attestations(created_at);
+
+-- Moves of hot wallet balances over their limit to the cold wallet
+CREATE TABLE IF NOT EXISTS cold_sweeps (
+  id TEXT PRIMARY KEY,
+  chain TEXT NOT NULL,
+  asset TEXT NOT NULL,
+  amount_minor TEXT NOT NULL,
+  from_address TEXT NOT NULL,
+  to_address TEXT NOT NULL,
+  status TEXT NOT NULL,            -- PENDING_APPROVAL, QUEUED, SENT, EXECUTED, REJECTED or FAILED
+  balance_minor TEXT NOT NULL,     -- hot wallet balance when the sweep was started
+  limit_minor TEXT NOT NULL,
+  keep_minor TEXT NOT NULL,
+  chain_tx_id TEXT,
+  tx_hash TEXT,
+  last_error TEXT,
+  decided_by TEXT,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_cold_sweeps_status ON cold_sweeps(status, chain, asset);
+
+-- Organizations own several merchants; their members sign in with their own API keys
+CREATE TABLE IF NOT EXISTS organizations (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+  id TEXT PRIMARY KEY,
+  organization_id TEXT NOT NULL REFERENCES organizations(id),
+  email TEXT NOT NULL,
+  role TEXT NOT NULL, -- owner | admin | viewer
+  key_hash TEXT NOT NULL UNIQUE,
+  created_at TEXT NOT NULL,
+  removed_at TEXT
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_members_email ON organization_members(organization_id, email) WHERE removed_at IS NULL;
+
+-- Addresses merchants watch for incoming token transfers, apart from orders; deleted_at stops it
+CREATE TABLE IF NOT EXISTS watched_addresses (
+  id TEXT PRIMARY KEY,
+  merchant_id TEXT NOT NULL,
+  chain TEXT NOT NULL,
+  address TEXT NOT NULL,
+  label TEXT,
+  created_at TEXT NOT NULL,
+  deleted_at TEXT
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_watched_addresses_unique ON watched_addresses(merchant_id, chain, address) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_watched_addresses_chain ON watched_addresses(chain) WHERE deleted_at IS NULL;
+
+-- Transfers the watcher found to watched addresses
+CREATE TABLE IF NOT EXISTS address_transfers (
+  id TEXT PRIMARY KEY,
+  watch_id TEXT NOT NULL,
+  merchant_id TEXT NOT NULL,
+  chain TEXT NOT NULL,
+  asset TEXT NOT NULL,
+  address TEXT NOT NULL,
+  from_address TEXT NOT NULL,
+  amount_minor TEXT NOT NULL,
+  tx_hash TEXT NOT NULL,
+  log_index INTEGER NOT NULL,
+  block_number INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  UNIQUE (watch_id, tx_hash, log_index)
+);
+
+-- Last block the watcher scanned per chain
+CREATE TABLE IF NOT EXISTS address_watch_cursors (
+  chain TEXT PRIMARY KEY,
+  last_block INTEGER NOT NULL,
+  updated_at TEXT NOT NULL
+);
 `
 	_, err := db.Exec(ddl)
 	if err != nil {
 		return err
 	}
 
+	// Columns added after the initial schema; CREATE TABLE IF NOT EXISTS won't add them to existing DBs
+	columns := []struct{ table, column, decl string }{
+		{"merchants", "platform_id", "TEXT REFERENCES platforms(id)"},
+		{"orders", "application_fee_minor", "TEXT"}, // platform fee withheld from the merchant credit
+		{"orders", "settlement_batch_id", "TEXT"},
+		{"ledger_entries", "reference_id", "TEXT"}, // refund/batch/etc. that produced the entry, when an order has several
+		{"merchants", "refund_approval_required", "INTEGER NOT NULL DEFAULT 0"},
+		{"refunds", "requested_by", "TEXT"}, // credential that requested the refund, e.g. "key:primary"
+		{"refunds", "decided_by", "TEXT"},
+		{"refunds", "decided_at", "TEXT"},
+		{"refunds", "execution_status", "TEXT"}, // hot wallet refunds: QUEUED | SENT | EXECUTED | FAILED
+		{"refunds", "chain_tx_id", "TEXT"},
+		{"refunds", "execution_error", "TEXT"},
+		{"orders", "risk_reason", "TEXT"},               // why the payer address was flagged during screening
+		{"orders", "risk_score", "INTEGER"},             // 0-100, from the rules-based scorer
+		{"orders", "risk_factors", "TEXT"},              // comma-separated scoring rules that fired
+		{"merchants", "max_order_amount_minor", "TEXT"}, // velocity limits; NULL means unlimited
+		{"merchants", "max_daily_volume_minor", "TEXT"},
+		{"merchants", "max_wallet_orders_per_hour", "INTEGER"},
+		{"orders", "customer_email", "TEXT"}, // encrypted when FIELD_ENCRYPTION_KEYS is set
+		{"orders", "metadata_json", "TEXT"},
+		{"orders", "erased_at", "TEXT"},           // set when customer data was pseudonymized on request
+		{"orders", "customer_email_hash", "TEXT"}, // blind index for looking up encrypted emails
+		{"merchants", "webhook_url", "TEXT"},
+		{"merchants", "webhook_secret", "TEXT"}, // HMAC key for signing deliveries; encrypted when FIELD_ENCRYPTION_KEYS is set
+		{"merchants", "webhook_events", "TEXT"}, // comma-separated subscribed event types; NULL means all
+		{"merchants", "webhook_secret_version", "INTEGER NOT NULL DEFAULT 1"},
+		{"merchants", "webhook_previous_secret", "TEXT"}, // keeps signing after a rotation until webhook_previous_secret_expires_at; encrypted like webhook_secret
+		{"merchants", "webhook_previous_secret_expires_at", "TEXT"},
+		{"merchants", "webhook_dead_letter_since", "TEXT"}, // first dead-lettered event since the last successful delivery
+		{"merchants", "webhook_dead_letter_alerted_at", "TEXT"},
+		{"outbox_events", "merchant_id", "TEXT"},
+		{"outbox_events", "status", "TEXT NOT NULL DEFAULT 'PENDING'"}, // PENDING | DELIVERED | SKIPPED | DEAD_LETTER | DISCARDED
+		{"outbox_events", "next_attempt_at", "TEXT"},
+		{"outbox_events", "last_error", "TEXT"},
+		{"outbox_events", "replay_of", "TEXT"},                             // original event id when the row was queued by POST /events/replay
+		{"outbox_events", "dead_lettered_at", "TEXT"},                      // when delivery was given up; cleared when the event is requeued
+		{"outbox_events", "sequence", "INTEGER"},                           // position in the aggregate's event stream; replays repeat the original's
+		{"orders", "expires_at", "TEXT"},                                   // PENDING orders expire at this time; pushed out by POST /orders/{id}/extend
+		{"orders", "expired_at", "TEXT"},                                   // when the order timed out unpaid; starts the late payment grace window
+		{"merchants", "late_payment_review", "INTEGER NOT NULL DEFAULT 0"}, // hold late payments in LATE_PAYMENT instead of crediting them
+		{"chain_transactions", "cancel_tx_hash", "TEXT"},                   // first cancelling self-transfer; it and later replacements cancel the original
+		{"chain_transactions", "mined_tx_hash", "TEXT"},                    // the broadcast hash that was mined
+		{"merchants", "payout_mode", "TEXT"},                               // NULL: settle in the ledger only; 'hot_wallet' or 'safe' also pays out on-chain
+		{"merchants", "payout_safe_address", "TEXT"},                       // Safe that holds the merchant's funds in 'safe' mode
+		{"settlement_batches", "payout_tx_hash", "TEXT"},                   // transaction paying the batch out; a multi-send shared with other batches
+		{"settlement_batches", "gross_amount_minor", "TEXT"},               // itemization of total_amount_minor, the net payout; NULL on older batches
+		{"settlement_batches", "fees_minor", "TEXT"},                       // application fees withheld
+		{"settlement_batches", "refunds_minor", "TEXT"},                    // completed refunds netted
+		{"settlement_batches", "disputes_lost_minor", "TEXT"},              // lost disputes netted
+		{"settlement_batches", "clawback_minor", "TEXT"},                   // withheld against a negative merchant balance
+		{"settlement_batches", "held_minor", "TEXT"},                       // withheld for balance holds
+		{"settlement_batches", "hold_released_minor", "TEXT"},              // withheld funds of a released hold, settled now
+		{"ledger_entries", "transaction_group_id", "TEXT"},                 // shared by the legs of one double entry
+		{"merchants", "clawback_max_bps", "INTEGER"},                       // most of a settlement withheld against a negative balance; NULL means all
+		{"merchants", "settlement_asset", "TEXT"},                          // convert settlements into this asset; NULL keeps the received one
+		{"merchants", "settlement_chain", "TEXT"},                          // and pay them out on this chain
+		{"merchants", "max_slippage_bps", "INTEGER"},                       // conversion slippage limit; NULL means the default of 50
+		{"merchants", "offramp_customer_id", "TEXT"},                       // the merchant's customer at the off-ramp partner
+		{"merchants", "offramp_bank_account_id", "TEXT"},                   // bank account fiat payouts go to
+		{"merchants", "kyc_status", "TEXT"},                                // last KYC status reported by the partner
+		{"merchants", "kyc_checked_at", "TEXT"},
+		{"ledger_entries", "chain", "TEXT"},              // the order's chain, or the chain the funds moved on
+		{"merchants", "timezone", "TEXT"},                // IANA name; daily windows start at local midnight. NULL is UTC
+		{"merchants", "accepted_assets", "TEXT"},         // comma-separated ASSET:CHAIN pairs orders may use; NULL means any
+		{"orders", "block_timestamp", "TEXT"},            // time of the block that mined the verified payment (confirmed_block)
+		{"orders", "coupon_code", "TEXT"},                // coupon redeemed at creation
+		{"orders", "discount_minor", "TEXT"},             // taken off the price by the coupon; amount_minor is what is due
+		{"orders", "external_order_id", "TEXT"},          // the merchant's own reference, for support lookups
+		{"merchants", "wallet_ens_name", "TEXT"},         // ENS name merchant_wallet_address was resolved from; re-resolved periodically
+		{"merchants", "wallet_ens_checked_at", "TEXT"},   // last time the name was resolved
+		{"merchants", "wallet_verified_address", "TEXT"}, // wallet the merchant proved control of by signing a challenge
+		{"merchants", "wallet_verified_at", "TEXT"},
+		{"merchants", "status", "TEXT NOT NULL DEFAULT 'ACTIVE'"}, // PENDING_APPROVAL while MERCHANT_APPROVAL_REQUIRED holds new merchants; REJECTED
+		{"merchants", "status_reason", "TEXT"},                    // given with a rejection
+		{"merchants", "status_decided_by", "TEXT"},
+		{"merchants", "status_decided_at", "TEXT"},
+		{"payouts", "attempts", "INTEGER NOT NULL DEFAULT 0"}, // failed tries to send or propose a QUEUED payout
+		{"payouts", "next_attempt_at", "TEXT"},                // not tried again before; NULL is now
+		{"payouts", "dead_lettered_at", "TEXT"},               // out of attempts; held QUEUED until POST /admin/payouts/{id}/retry
+		{"merchants", "organization_id", "TEXT REFERENCES organizations(id)"},
+		{"orders", "webhook_url", "TEXT"},           // receives the order's events instead of the merchant's webhook URL
+		{"orders", "webhook_secret", "TEXT"},        // signs them; encrypted like merchants.webhook_secret
+		{"orders", "mispayment_json", "TEXT"},       // the wrong-token or wrong-chain transfer that made the order MISPAID
+		{"orders", "fiat_pricing_json", "TEXT"},     // the rate quote a fiat-priced order was converted with
+		{"ledger_entries", "rate_quote_id", "TEXT"}, // rate quote of the fiat-priced order the entry is for
+		{"merchants", "deleted_at", "TEXT"},         // soft-deleted: keys refused and hidden from lists until an admin restores it
+		{"merchants", "deleted_by", "TEXT"},
+		{"orders", "deleted_at", "TEXT"}, // soft-deleted: hidden from every read until an admin restores it
+		{"orders", "deleted_by", "TEXT"},
+		{"api_keys", "created_by", "TEXT"},                             // credential that created the key; NULL on older keys, all created by the primary key
+		{"idempotency_keys", "withheld", "INTEGER NOT NULL DEFAULT 0"}, // 1: the response held a secret and its body was not stored
+		{"overpayments", "confirmed_block", "INTEGER"},                 // block the transfer was mined in
+		{"overpayments", "risk_reason", "TEXT"},                        // why screening flagged the sender
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.column, c.decl); err != nil {
+			return err
+		}
+	}
+
 	// Add indexes and constraints
 	indexDDL := `
 CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_txhash_notnull
@@ -111,10 +761,155 @@ CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_txhash_notnull
 
 DROP INDEX IF EXISTS idx_ledger_unique_event;
 CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_unique_event
-  ON ledger_entries(order_id, event_type, bucket);
+  ON ledger_entries(order_id, event_type, bucket, COALESCE(reference_id, ''));
 
 CREATE INDEX IF NOT EXISTS idx_ledger_order ON ledger_entries(order_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_merchant_created ON ledger_entries(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_ledger_transaction_group ON ledger_entries(transaction_group_id);
+CREATE INDEX IF NOT EXISTS idx_wallet_challenges_merchant ON wallet_challenges(merchant_id, issued_at);
+CREATE INDEX IF NOT EXISTS idx_merchants_platform ON merchants(platform_id);
+CREATE INDEX IF NOT EXISTS idx_merchants_organization ON merchants(organization_id);
+CREATE INDEX IF NOT EXISTS idx_merchants_status ON merchants(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_oauth_tokens_grant ON oauth_tokens(grant_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_order ON refunds(order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refunds_txhash_notnull
+  ON refunds(refund_tx_hash) WHERE refund_tx_hash IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_one_open
+  ON disputes(order_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_dispute_evidence_dispute ON dispute_evidence(dispute_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_merchant ON audit_log(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_email_hash ON orders(customer_email_hash);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_due ON outbox_events(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_delivered ON outbox_events(status, delivered_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending_aggregate
+  ON outbox_events(aggregate_type, aggregate_id, sequence) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_outbox_events_dead_letter
+  ON outbox_events(merchant_id, created_at) WHERE status = 'DEAD_LETTER';
+CREATE INDEX IF NOT EXISTS idx_customers_last_paid ON customers(merchant_id, last_paid_at);
+CREATE INDEX IF NOT EXISTS idx_payouts_status ON payouts(status);
+CREATE INDEX IF NOT EXISTS idx_conversions_status ON conversions(status);
+CREATE INDEX IF NOT EXISTS idx_fiat_payouts_status ON fiat_payouts(status);
+CREATE INDEX IF NOT EXISTS idx_fiat_payouts_merchant ON fiat_payouts(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_payouts_merchant ON payouts(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_chain_transactions_status ON chain_transactions(status, submitted_at);
+CREATE INDEX IF NOT EXISTS idx_chain_transactions_reference ON chain_transactions(purpose, reference_id);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_wallet ON orders(merchant_id, customer_wallet_address COLLATE NOCASE);
+CREATE INDEX IF NOT EXISTS idx_orders_merchant_created ON orders(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_merchant_paid ON orders(merchant_id, paid_at);
+CREATE INDEX IF NOT EXISTS idx_refunds_merchant_created ON refunds(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_refunds_execution ON refunds(execution_status) WHERE execution_status IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_order_notes_order ON order_notes(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_order_tags_merchant_tag ON order_tags(merchant_id, tag);
+CREATE INDEX IF NOT EXISTS idx_overpayments_merchant ON overpayments(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_overpayments_order ON overpayments(order_id);
+CREATE INDEX IF NOT EXISTS idx_rate_quotes_pair ON rate_quotes(base, quote, created_at);
+CREATE INDEX IF NOT EXISTS idx_settlement_batches_merchant ON settlement_batches(merchant_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_negative_balances_open
+  ON negative_balances(merchant_id, asset, chain) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_negative_balances_merchant ON negative_balances(merchant_id, opened_at);
+CREATE INDEX IF NOT EXISTS idx_balance_holds_active ON balance_holds(merchant_id, asset, chain, created_at) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_balance_holds_merchant ON balance_holds(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_refund_jobs_status ON refund_jobs(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_payment_intents_merchant ON payment_intents(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_claim ON jobs(type, status, run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_finished ON jobs(status, finished_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_dedupe
+  ON jobs(type, dedupe_key) WHERE dedupe_key IS NOT NULL AND status IN ('PENDING', 'RUNNING');
+CREATE INDEX IF NOT EXISTS idx_orders_merchant_external
+  ON orders(merchant_id, external_order_id) WHERE external_order_id IS NOT NULL;
 `
-	_, err = db.Exec(indexDDL)
+	if _, err = db.Exec(indexDDL); err != nil {
+		return err
+	}
+	if err := hashLegacyAPIKeys(db); err != nil {
+		return err
+	}
+	if err := ensureArchiveTables(db); err != nil {
+		return err
+	}
+
+	// Backfill refunds recorded before the refunds table existed (one per order, keyed on the order row)
+	backfillDDL := `
+INSERT OR IGNORE INTO refunds (id, order_id, merchant_id, amount_minor, status, refund_tx_hash, idempotency_key, created_at)
+SELECT 'rfd_legacy_' || o.id, o.id, o.merchant_id, l.amount_minor, 'COMPLETED', NULLIF(l.tx_hash, ''), o.refund_idempotency_key, l.created_at
+FROM orders o
+JOIN ledger_entries l ON l.order_id = o.id AND l.event_type = 'REFUND' AND l.bucket = 'merchant' AND l.reference_id IS NULL
+WHERE o.refund_idempotency_key IS NOT NULL;
+
+UPDATE ledger_entries SET reference_id = 'rfd_legacy_' || order_id
+WHERE event_type = 'REFUND' AND reference_id IS NULL;
+
+-- Customer profiles from payments credited before the customers table existed
+INSERT OR IGNORE INTO customers (id, merchant_id, wallet_address, first_paid_at, last_paid_at, payment_count)
+SELECT 'cus_' || lower(hex(randomblob(16))), merchant_id, customer_wallet_address, MIN(paid_at), MAX(paid_at), COUNT(1)
+FROM orders
+WHERE status IN ('PAID', 'SETTLED', 'REFUNDED', 'PARTIALLY_REFUNDED')
+  AND customer_wallet_address IS NOT NULL AND paid_at IS NOT NULL AND erased_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM customers)
+GROUP BY merchant_id, customer_wallet_address COLLATE NOCASE;
+
+-- Chains of ledger entries booked before entries recorded one
+UPDATE ledger_entries SET chain = (SELECT UPPER(chain) FROM orders WHERE orders.id = ledger_entries.order_id)
+WHERE chain IS NULL AND order_id IS NOT NULL;
+
+-- Transaction groups of entries booked before entries recorded one: the legs one event booked at
+-- the same time for the same refund, batch, ... or else order
+UPDATE ledger_entries SET transaction_group_id =
+  'ltx_legacy_' || lower(event_type) || '_' || COALESCE(reference_id, order_id, merchant_id) || '_' || created_at
+WHERE transaction_group_id IS NULL;
+
+-- Events given up on before dead-lettering, when they were marked FAILED
+UPDATE outbox_events SET status = 'DEAD_LETTER', dead_lettered_at = next_attempt_at WHERE status = 'FAILED';
+
+-- Sequence numbers of events queued before events were numbered per aggregate
+UPDATE outbox_events SET sequence = (
+  SELECT COUNT(1) FROM outbox_events p
+  WHERE p.aggregate_type = outbox_events.aggregate_type AND p.aggregate_id = outbox_events.aggregate_id AND p.rowid <= outbox_events.rowid
+)
+WHERE sequence IS NULL;
+INSERT INTO outbox_sequences (aggregate_type, aggregate_id, last_sequence)
+SELECT aggregate_type, aggregate_id, MAX(sequence) FROM outbox_events
+WHERE NOT EXISTS (SELECT 1 FROM outbox_sequences)
+GROUP BY aggregate_type, aggregate_id;
+
+-- Counters kept in memory before they were persisted, counted from what the database still holds
+INSERT OR IGNORE INTO metric_counters (name, value, updated_at)
+SELECT 'orders_created_total', (SELECT COUNT(1) FROM orders) + (SELECT COUNT(1) FROM orders_archive), strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+UNION ALL
+SELECT 'payments_detected_total', (SELECT COUNT(1) FROM orders WHERE paid_at IS NOT NULL) + (SELECT COUNT(1) FROM orders_archive WHERE paid_at IS NOT NULL), strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+UNION ALL
+SELECT 'refunds_processed_total', (SELECT COUNT(1) FROM refunds WHERE status = 'COMPLETED') + (SELECT COUNT(1) FROM refunds_archive WHERE status = 'COMPLETED'), strftime('%Y-%m-%dT%H:%M:%SZ', 'now');
+`
+	if _, err = db.Exec(backfillDDL); err != nil {
+		return err
+	}
+	if err := backfillSettlementEntries(db); err != nil {
+		return err
+	}
+	return backfillLedgerBalances(db)
+}
+
+// addColumnIfMissing runs ALTER TABLE ADD COLUMN unless the column already exists.
+func addColumnIfMissing(db *sql.DB, table, column, decl string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + decl)
 	return err
 }
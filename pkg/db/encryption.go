@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/oxzoid/OSPay/pkg/secrets"
+)
+
+// encryptedColumns lists the columns written through secrets.EncryptedString, with the blind-index
+// column used to look them up (if any).
+var encryptedColumns = []struct{ table, column, index string }{
+	{"orders", "customer_email", "customer_email_hash"},
+	{"orders_archive", "customer_email", "customer_email_hash"},
+	{"merchants", "webhook_secret", ""},
+	{"merchants", "webhook_previous_secret", ""}, // copied from webhook_secret, still sealed, on rotation
+	{"orders", "webhook_secret", ""},
+	{"orders_archive", "webhook_secret", ""},
+}
+
+// ReencryptFields rewrites every encrypted column that is still plaintext or sealed with an older
+// key using the current key, and refreshes its blind index. Run after adding a key to the front of
+// the keyring; once it reports no remaining rows the old key can be dropped. It is a no-op when
+// encryption is disabled.
+func ReencryptFields(ctx context.Context, db *sql.DB) (int, error) {
+	kr := secrets.DefaultKeyring()
+	if kr == nil {
+		return 0, nil
+	}
+	total := 0
+	for _, c := range encryptedColumns {
+		rows, err := db.QueryContext(ctx, `SELECT rowid, `+c.column+` FROM `+c.table+` WHERE `+c.column+` IS NOT NULL`)
+		if err != nil {
+			return total, err
+		}
+		type pending struct {
+			rowid int64
+			value string
+		}
+		var stale []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.rowid, &p.value); err != nil {
+				rows.Close()
+				return total, err
+			}
+			if !kr.SealedWithCurrent(p.value) {
+				stale = append(stale, p)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+		for _, p := range stale {
+			plain, err := kr.Decrypt(p.value)
+			if err != nil {
+				return total, fmt.Errorf("%s.%s row %d: %w", c.table, c.column, p.rowid, err)
+			}
+			sealed, err := kr.Encrypt(plain)
+			if err != nil {
+				return total, err
+			}
+			query, args := `UPDATE `+c.table+` SET `+c.column+` = ? WHERE rowid = ?`, []any{sealed, p.rowid}
+			if c.index != "" {
+				query, args = `UPDATE `+c.table+` SET `+c.column+` = ?, `+c.index+` = ? WHERE rowid = ?`, []any{sealed, kr.BlindIndex(plain), p.rowid}
+			}
+			if _, err := db.ExecContext(ctx, query, args...); err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+	return total, nil
+}
+
+// hashLegacyAPIKeys replaces primary API keys stored in plaintext with their sha256, matching how
+// scoped keys and OAuth secrets are stored. Hashes are 64 hex characters; the keys are UUIDs.
+func hashLegacyAPIKeys(db *sql.DB) error {
+	for _, table := range []string{"merchants", "platforms"} {
+		rows, err := db.Query(`SELECT id, api_key FROM ` + table + ` WHERE length(api_key) != 64`)
+		if err != nil {
+			return err
+		}
+		keys := map[string]string{}
+		for rows.Next() {
+			var id, key string
+			if err := rows.Scan(&id, &key); err != nil {
+				rows.Close()
+				return err
+			}
+			keys[id] = key
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for id, key := range keys {
+			sum := sha256.Sum256([]byte(key))
+			if _, err := db.Exec(`UPDATE `+table+` SET api_key = ? WHERE id = ?`, hex.EncodeToString(sum[:]), id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,531 @@
+// Package jobs runs background work on the database: a durable queue of one-off jobs, worked by a
+// pool of workers per job type with retries, backoff and dead-lettering, and periodic jobs run on
+// an interval or cron schedule. Queued jobs are claimed with a lease, so with several instances each
+// job is worked by one of them, and a job whose instance died is taken again once its lease runs
+// out. Periodic runs take a lock for the same reason. The tables are created by db.EnsureSchema.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job states. DEAD jobs failed permanently or ran out of attempts, unless their RetryPolicy discards
+// them; Retry queues them again.
+const (
+	StatusPending   = "PENDING"
+	StatusRunning   = "RUNNING"
+	StatusSucceeded = "SUCCEEDED"
+	StatusDead      = "DEAD"
+)
+
+var (
+	// ErrNotFound is returned for an unknown job ID.
+	ErrNotFound = errors.New("job not found")
+	// ErrNotDead is returned by Retry for a job that has not failed.
+	ErrNotDead = errors.New("job is not dead")
+)
+
+// DBTX is what Enqueue writes with: the *sql.DB, or the *sql.Tx of the change the job follows
+// from, so that the job is only queued if the change commits.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Job is a claimed job as its Handler sees it.
+type Job struct {
+	ID      string
+	Type    string
+	Payload json.RawMessage
+	Attempt int  // 1 on the first run
+	Last    bool // the retry policy allows no further attempt
+}
+
+// Handler works on one job. A returned error is retried as the type's RetryPolicy says, unless it
+// is Permanent.
+type Handler func(ctx context.Context, job Job) error
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the job fails for good at once.
+func Permanent(err error) error { return permanentError{err} }
+
+// RetryPolicy says how often and how soon a failed job is tried again, and what becomes of it
+// when it fails for good. Zero fields take the defaults.
+type RetryPolicy struct {
+	MaxAttempts int           // including the first; default 5
+	BaseDelay   time.Duration // before the first retry, doubling after each; default 10s
+	MaxDelay    time.Duration // default 1h
+	Jitter      float64       // up to this fraction of the delay is added at random (0-1)
+	Discard     bool          // delete jobs that fail for good instead of keeping them DEAD
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 5
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 10 * time.Second
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = time.Hour
+	}
+	p.Jitter = min(max(p.Jitter, 0), 1)
+	return p
+}
+
+// Delay is the wait after the attempt-th failed attempt.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	p = p.withDefaults()
+	d := p.BaseDelay << max(attempt-1, 0)
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// Options configure a job type. Zero fields take the defaults.
+type Options struct {
+	Workers int           // concurrent jobs of the type on this instance; default 1
+	Timeout time.Duration // bounds an attempt; default 1m. The lease lasts a minute longer.
+	Poll    time.Duration // how often idle workers look for due jobs; default 1s
+	Retry   RetryPolicy
+	// RetryFunc, when set, is asked for the policy on every attempt instead of Retry, for policies
+	// that change while the workers run.
+	RetryFunc func() RetryPolicy
+}
+
+type jobType struct {
+	name    string
+	handler Handler
+	opts    Options
+	wake    chan struct{}
+
+	succeeded, retried, dead atomic.Int64
+}
+
+// NewJob describes a job to enqueue.
+type NewJob struct {
+	Type    string
+	Payload any       // encoded as JSON
+	RunAt   time.Time // not before; zero is now
+	// Key, when set, dedupes: the job is not queued while a PENDING or RUNNING job of the type
+	// has the same key.
+	Key string
+}
+
+// Queue is the job queue and periodic job registry of one instance.
+type Queue struct {
+	db     *sql.DB
+	owner  string // locked_by of the jobs and locks this instance holds
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	types    map[string]*jobType
+	periodic map[string]*Periodic
+}
+
+// New returns a queue on database. Its workers start as job types are registered and stop with
+// Stop.
+func New(database *sql.DB) *Queue {
+	host, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		db:       database,
+		owner:    fmt.Sprintf("%s:%d:%s", host, os.Getpid(), uuid.New().String()[:8]),
+		ctx:      ctx,
+		cancel:   cancel,
+		types:    map[string]*jobType{},
+		periodic: map[string]*Periodic{},
+	}
+}
+
+// Register makes handler work the jobs of type typ and starts its workers. Jobs of types no
+// instance registered stay PENDING.
+func (q *Queue) Register(typ string, handler Handler, opts Options) {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+	if opts.Poll <= 0 {
+		opts.Poll = time.Second
+	}
+	opts.Retry = opts.Retry.withDefaults()
+	t := &jobType{name: typ, handler: handler, opts: opts, wake: make(chan struct{}, 1)}
+	q.mu.Lock()
+	q.types[typ] = t
+	q.mu.Unlock()
+	for range opts.Workers {
+		q.wg.Add(1)
+		go q.work(t)
+	}
+}
+
+// Enqueue adds a job. It reports false, without error, when the job was deduped by its Key.
+func (q *Queue) Enqueue(ctx context.Context, ex DBTX, j NewJob) (string, bool, error) {
+	payload, err := json.Marshal(j.Payload)
+	if err != nil {
+		return "", false, err
+	}
+	now := time.Now().UTC()
+	runAt := j.RunAt
+	if runAt.IsZero() {
+		runAt = now
+	}
+	id := "job_" + uuid.New().String()
+	var key any
+	if j.Key != "" {
+		key = j.Key
+	}
+	res, err := ex.ExecContext(ctx, `
+		INSERT OR IGNORE INTO jobs (id, type, payload, dedupe_key, status, run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, j.Type, string(payload), key, StatusPending, stamp(runAt), stamp(now), stamp(now))
+	if err != nil {
+		return "", false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", false, nil
+	}
+	q.mu.Lock()
+	t := q.types[j.Type]
+	q.mu.Unlock()
+	if t != nil && !runAt.After(now) {
+		select {
+		case t.wake <- struct{}{}:
+		default:
+		}
+	}
+	return id, true, nil
+}
+
+// Stop stops claiming jobs and periodic runs and waits, until ctx is done, for those in progress.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.cancel()
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work(t *jobType) {
+	defer q.wg.Done()
+	for {
+		if q.ctx.Err() != nil {
+			return
+		}
+		job, ok, err := q.claim(t)
+		if err != nil {
+			log.Printf("event=job_claim_failed type=%s err=%v", t.name, err)
+		}
+		if ok {
+			q.run(t, job)
+			continue
+		}
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-t.wake:
+		case <-time.After(t.opts.Poll):
+		}
+	}
+}
+
+// claim takes the next due job of t, or one whose lease ran out. The status is checked again in
+// the outer WHERE, so of two instances racing for a job only one gets it.
+func (q *Queue) claim(t *jobType) (Job, bool, error) {
+	now := time.Now().UTC()
+	lease := stamp(now.Add(t.opts.Timeout + time.Minute))
+	due := `(status = 'PENDING' AND run_at <= ?) OR (status = 'RUNNING' AND locked_until <= ?)`
+	job := Job{Type: t.name}
+	var payload string
+	err := q.db.QueryRowContext(q.ctx, `
+		UPDATE jobs SET status = 'RUNNING', attempts = attempts + 1, locked_by = ?, locked_until = ?, updated_at = ?
+		WHERE id = (SELECT id FROM jobs WHERE type = ? AND (`+due+`) ORDER BY run_at, id LIMIT 1) AND (`+due+`)
+		RETURNING id, payload, attempts
+	`, q.owner, lease, stamp(now), t.name, stamp(now), stamp(now), stamp(now), stamp(now)).Scan(&job.ID, &payload, &job.Attempt)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return Job{}, false, nil
+	}
+	if err != nil {
+		return Job{}, false, err
+	}
+	job.Payload = json.RawMessage(payload)
+	return job, true, nil
+}
+
+func (q *Queue) run(t *jobType, job Job) {
+	// Jobs in progress finish on Stop, so they do not run under q.ctx
+	ctx, cancel := context.WithTimeout(context.Background(), t.opts.Timeout)
+	defer cancel()
+	retry := t.retryPolicy()
+	job.Last = job.Attempt >= retry.MaxAttempts
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return t.handler(ctx, job)
+	}()
+	q.finish(t, job, retry, err)
+}
+
+func (t *jobType) retryPolicy() RetryPolicy {
+	if t.opts.RetryFunc != nil {
+		return t.opts.RetryFunc().withDefaults()
+	}
+	return t.opts.Retry
+}
+
+func (q *Queue) finish(t *jobType, job Job, retry RetryPolicy, jobErr error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	var err error
+	var permanent permanentError
+	switch {
+	case jobErr == nil:
+		t.succeeded.Add(1)
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = 'SUCCEEDED', locked_by = NULL, locked_until = NULL, last_error = NULL, updated_at = ?, finished_at = ?
+			WHERE id = ? AND locked_by = ?
+		`, stamp(now), stamp(now), job.ID, q.owner)
+	case (errors.As(jobErr, &permanent) || job.Attempt >= retry.MaxAttempts) && retry.Discard:
+		t.dead.Add(1)
+		log.Printf("event=job_discarded type=%s job_id=%s attempts=%d err=%q", t.name, job.ID, job.Attempt, jobErr.Error())
+		_, err = q.db.ExecContext(ctx, `DELETE FROM jobs WHERE id = ? AND locked_by = ?`, job.ID, q.owner)
+	case errors.As(jobErr, &permanent) || job.Attempt >= retry.MaxAttempts:
+		t.dead.Add(1)
+		log.Printf("event=job_dead type=%s job_id=%s attempts=%d err=%q", t.name, job.ID, job.Attempt, jobErr.Error())
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = 'DEAD', locked_by = NULL, locked_until = NULL, last_error = ?, updated_at = ?, finished_at = ?
+			WHERE id = ? AND locked_by = ?
+		`, jobErr.Error(), stamp(now), stamp(now), job.ID, q.owner)
+	default:
+		t.retried.Add(1)
+		delay := retry.Delay(job.Attempt)
+		log.Printf("event=job_retry type=%s job_id=%s attempt=%d retry_in=%s err=%q", t.name, job.ID, job.Attempt, delay, jobErr.Error())
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = 'PENDING', locked_by = NULL, locked_until = NULL, last_error = ?, run_at = ?, updated_at = ?
+			WHERE id = ? AND locked_by = ?
+		`, jobErr.Error(), stamp(now.Add(delay)), stamp(now), job.ID, q.owner)
+	}
+	if err != nil {
+		// The lease runs out and the job is taken again
+		log.Printf("event=job_finish_failed type=%s job_id=%s err=%v", t.name, job.ID, err)
+	}
+}
+
+// Info is a job as listed by List. Times are RFC 3339 UTC.
+type Info struct {
+	ID         string
+	Type       string
+	Status     string
+	Payload    json.RawMessage
+	Attempts   int
+	RunAt      string
+	LockedBy   *string // while RUNNING
+	LastError  *string
+	CreatedAt  string
+	UpdatedAt  string
+	FinishedAt *string // once SUCCEEDED or DEAD
+}
+
+// Filter selects jobs for List; empty fields match any.
+type Filter struct {
+	Type   string
+	Status string
+	Limit  int // default 100
+}
+
+const infoCols = `id, type, status, payload, attempts, run_at, locked_by, last_error, created_at, updated_at, finished_at`
+
+func scanInfo(row interface{ Scan(...any) error }) (Info, error) {
+	var j Info
+	var payload string
+	if err := row.Scan(&j.ID, &j.Type, &j.Status, &payload, &j.Attempts, &j.RunAt, &j.LockedBy, &j.LastError, &j.CreatedAt, &j.UpdatedAt, &j.FinishedAt); err != nil {
+		return Info{}, err
+	}
+	j.Payload = json.RawMessage(payload)
+	return j, nil
+}
+
+// Get returns the job id.
+func (q *Queue) Get(ctx context.Context, id string) (Info, error) {
+	j, err := scanInfo(q.db.QueryRowContext(ctx, `SELECT `+infoCols+` FROM jobs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Info{}, ErrNotFound
+	}
+	return j, err
+}
+
+// List returns jobs matching f, most recently updated first.
+func (q *Queue) List(ctx context.Context, f Filter) ([]Info, error) {
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+infoCols+`
+		FROM jobs WHERE (? = '' OR type = ?) AND (? = '' OR status = ?)
+		ORDER BY updated_at DESC, id DESC LIMIT ?
+	`, f.Type, f.Type, f.Status, f.Status, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Info{}
+	for rows.Next() {
+		j, err := scanInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// Retry queues a DEAD job again, with its attempts counted from zero.
+func (q *Queue) Retry(ctx context.Context, id string) error {
+	now := stamp(time.Now().UTC())
+	res, err := q.db.ExecContext(ctx, `
+		UPDATE jobs SET status = 'PENDING', attempts = 0, run_at = ?, updated_at = ?, finished_at = NULL
+		WHERE id = ? AND status = 'DEAD'
+	`, now, now, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			// A newer job with the same dedupe key is already queued
+			return ErrNotDead
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil
+	}
+	if _, err := q.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrNotDead
+}
+
+// Prune deletes SUCCEEDED jobs that finished before cutoff and reports how many.
+func (q *Queue) Prune(ctx context.Context, cutoff time.Time) (int, error) {
+	res, err := q.db.ExecContext(ctx, `DELETE FROM jobs WHERE status = 'SUCCEEDED' AND finished_at < ?`, stamp(cutoff.UTC()))
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// TypeStats are the queue depth and outcomes of a job type. Pending, Running and Dead count the
+// jobs in the database, of every instance; the totals count this instance's attempts since it
+// started.
+type TypeStats struct {
+	Type           string
+	Pending        int64
+	Running        int64
+	Dead           int64
+	SucceededTotal int64
+	RetriedTotal   int64
+	DeadTotal      int64
+	// OldestDue is how long the oldest PENDING job that is due has waited for a worker; zero when
+	// the workers keep up.
+	OldestDue time.Duration
+}
+
+// Stats returns the stats of every registered job type and every type with jobs in the queue,
+// by type name.
+func (q *Queue) Stats(ctx context.Context) ([]TypeStats, error) {
+	byType := map[string]*TypeStats{}
+	q.mu.Lock()
+	for name, t := range q.types {
+		byType[name] = &TypeStats{
+			Type: name, SucceededTotal: t.succeeded.Load(), RetriedTotal: t.retried.Load(), DeadTotal: t.dead.Load(),
+		}
+	}
+	q.mu.Unlock()
+	now := time.Now().UTC()
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT type, status, COUNT(*), MIN(CASE WHEN run_at <= ? THEN run_at END)
+		FROM jobs WHERE status IN ('PENDING', 'RUNNING', 'DEAD') GROUP BY type, status
+	`, stamp(now))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var typ, status string
+		var n int64
+		var oldest sql.NullString
+		if err := rows.Scan(&typ, &status, &n, &oldest); err != nil {
+			return nil, err
+		}
+		s := byType[typ]
+		if s == nil {
+			s = &TypeStats{Type: typ}
+			byType[typ] = s
+		}
+		switch status {
+		case StatusPending:
+			s.Pending = n
+			if t, err := time.Parse(time.RFC3339, oldest.String); oldest.Valid && err == nil {
+				s.OldestDue = max(now.Sub(t), 0)
+			}
+		case StatusRunning:
+			s.Running = n
+		case StatusDead:
+			s.Dead = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]TypeStats, 0, len(byType))
+	for _, s := range byType {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out, nil
+}
+
+// Depth returns the number of jobs of typ waiting or running, including failed ones waiting for
+// their next attempt.
+func (q *Queue) Depth(ctx context.Context, typ string) (int64, error) {
+	var n int64
+	err := q.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE type = ? AND status IN ('PENDING', 'RUNNING')`, typ).Scan(&n)
+	return n, err
+}
+
+// stamp formats t as the RFC 3339 UTC timestamps the tables hold, which sort as text.
+func stamp(t time.Time) string { return t.UTC().Format(time.RFC3339) }
@@ -0,0 +1,283 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oxzoid/OSPay/pkg/cron"
+)
+
+// Schedule decides when a periodic job runs next. A zero time means never.
+type Schedule interface {
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// Every runs a job at a fixed interval after the previous run.
+type Every time.Duration
+
+func (e Every) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+func (e Every) String() string             { return "@every " + time.Duration(e).String() }
+
+// ParseSchedule accepts a cron expression (see cron.Parse) or "@every <duration>".
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid interval %q", d)
+		}
+		return Every(interval), nil
+	}
+	return cron.Parse(spec)
+}
+
+// PeriodicFunc does one run of a periodic job and reports how many rows it processed. An error is
+// recorded as the job's last error; the next run happens on schedule regardless.
+type PeriodicFunc func(ctx context.Context) (int, error)
+
+// PeriodicOptions configure a periodic job.
+type PeriodicOptions struct {
+	Schedule  Schedule
+	Immediate bool // run once when registered, then on schedule
+	// Local jobs run on every instance, for work on the instance's own state such as flushing
+	// in-memory counters. Other runs take a lock, so no two instances run the job at once; a
+	// run that finds the lock taken is skipped.
+	Local bool
+}
+
+// lockLease is how long a periodic run holds its lock before renewing it; a lock left by a dead
+// instance is free after at most this long.
+const lockLease = 2 * time.Minute
+
+// Periodic is a registered periodic job.
+type Periodic struct {
+	q      *Queue
+	name   string
+	opts   PeriodicOptions
+	fn     PeriodicFunc
+	runNow chan struct{}
+
+	mu           sync.Mutex
+	nextRunAt    time.Time
+	paused       bool
+	running      bool
+	lastRunAt    time.Time
+	lastDuration time.Duration
+	lastRows     int
+	lastErr      string
+	runs         int64
+	failures     int64
+	skipped      int64
+}
+
+// Periodic registers fn under name and runs it in a background goroutine on opts.Schedule, until
+// Stop. Runs never overlap: a RunNow made while the job is running waits for it to finish.
+func (q *Queue) Periodic(name string, opts PeriodicOptions, fn PeriodicFunc) *Periodic {
+	p := &Periodic{q: q, name: name, opts: opts, fn: fn, runNow: make(chan struct{}, 1)}
+	q.mu.Lock()
+	q.periodic[name] = p
+	q.mu.Unlock()
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		if opts.Immediate {
+			p.run(false)
+		}
+		for {
+			next := p.opts.Schedule.Next(time.Now())
+			p.mu.Lock()
+			p.nextRunAt = next
+			p.mu.Unlock()
+			var timer *time.Timer
+			var fire <-chan time.Time // nil, never firing, for a schedule that never comes round
+			if !next.IsZero() {
+				timer = time.NewTimer(time.Until(next))
+				fire = timer.C
+			}
+			select {
+			case <-q.ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case <-fire:
+				p.run(false)
+			case <-p.runNow:
+				if timer != nil {
+					timer.Stop()
+				}
+				p.run(true)
+			}
+		}
+	}()
+	return p
+}
+
+// PeriodicJob returns the periodic job registered under name, or nil.
+func (q *Queue) PeriodicJob(name string) *Periodic {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.periodic[name]
+}
+
+// PeriodicJobs returns the registered periodic jobs by name.
+func (q *Queue) PeriodicJobs() []*Periodic {
+	q.mu.Lock()
+	out := make([]*Periodic, 0, len(q.periodic))
+	for _, p := range q.periodic {
+		out = append(out, p)
+	}
+	q.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// Pause stops the job's runs until Resume; a run in progress finishes. It lasts until the process
+// restarts.
+func (p *Periodic) Pause() { p.setPaused(true) }
+
+// Resume lets a paused job run on schedule again.
+func (p *Periodic) Resume() { p.setPaused(false) }
+
+func (p *Periodic) setPaused(paused bool) {
+	p.mu.Lock()
+	p.paused = paused
+	p.mu.Unlock()
+}
+
+// RunNow starts a run now, even when paused, without waiting for it.
+func (p *Periodic) RunNow() {
+	select {
+	case p.runNow <- struct{}{}:
+	default: // a run is already requested
+	}
+}
+
+// run does one run of the job unless it is paused; forced runs (RunNow) happen even when paused.
+func (p *Periodic) run(forced bool) {
+	p.mu.Lock()
+	if p.paused && !forced {
+		p.mu.Unlock()
+		return
+	}
+	p.running = true
+	p.mu.Unlock()
+
+	start := time.Now()
+	var (
+		rows int
+		err  error
+	)
+	locked := p.opts.Local
+	if !locked {
+		var release func()
+		if release, locked = p.q.lock(p.name); locked {
+			rows, err = p.fn(context.Background())
+			release()
+		}
+	} else {
+		rows, err = p.fn(context.Background())
+	}
+	elapsed := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+	if !locked {
+		p.skipped++
+		return
+	}
+	p.lastRunAt = start.UTC()
+	p.lastDuration = elapsed
+	p.lastRows = rows
+	p.runs++
+	p.lastErr = ""
+	if err != nil {
+		p.lastErr = err.Error()
+		p.failures++
+		log.Printf("event=scheduler_error scheduler=%s rows=%d err=%v", p.name, rows, err)
+	}
+}
+
+// lock takes the lock of a periodic job, renewing it while the run lasts. It reports false when
+// another instance holds it.
+func (q *Queue) lock(name string) (release func(), ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	res, err := q.db.ExecContext(ctx, `
+		INSERT INTO job_locks (name, owner, locked_until) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, locked_until = excluded.locked_until
+		WHERE job_locks.owner = excluded.owner OR job_locks.locked_until <= ?
+	`, name, q.owner, stamp(now.Add(lockLease)), stamp(now))
+	if err != nil {
+		// Without the database the job would fail anyway; let it run and report that
+		log.Printf("event=job_lock_failed scheduler=%s err=%v", name, err)
+		return func() {}, true
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, false
+	}
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(lockLease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				_, _ = q.db.ExecContext(context.Background(), `UPDATE job_locks SET locked_until = ? WHERE name = ? AND owner = ?`,
+					stamp(time.Now().Add(lockLease)), name, q.owner)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		_, _ = q.db.ExecContext(context.Background(), `DELETE FROM job_locks WHERE name = ? AND owner = ?`, name, q.owner)
+	}, true
+}
+
+// PeriodicStatus is the state of a periodic job and the outcome of its last run.
+type PeriodicStatus struct {
+	Name         string
+	Schedule     string
+	Local        bool
+	NextRunAt    time.Time // zero if never
+	Paused       bool
+	Running      bool
+	LastRunAt    time.Time // zero before the first run
+	LastDuration time.Duration
+	LastRows     int
+	LastError    string
+	Runs         int64
+	Failures     int64
+	Skipped      int64 // runs skipped because another instance held the lock
+}
+
+// Status returns the job's state.
+func (p *Periodic) Status() PeriodicStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PeriodicStatus{
+		Name:         p.name,
+		Schedule:     p.opts.Schedule.String(),
+		Local:        p.opts.Local,
+		NextRunAt:    p.nextRunAt,
+		Paused:       p.paused,
+		Running:      p.running,
+		LastRunAt:    p.lastRunAt,
+		LastDuration: p.lastDuration,
+		LastRows:     p.lastRows,
+		LastError:    p.lastErr,
+		Runs:         p.runs,
+		Failures:     p.failures,
+		Skipped:      p.skipped,
+	}
+}
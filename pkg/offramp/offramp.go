@@ -0,0 +1,57 @@
+// Package offramp turns settled crypto into bank payouts through an off-ramp partner.
+package offramp
+
+import (
+	"context"
+	"errors"
+	"math/big"
+)
+
+// KYC states of a merchant at the partner. Only APPROVED merchants can be paid out.
+const (
+	KYCNotStarted = "NOT_STARTED"
+	KYCPending    = "PENDING"
+	KYCApproved   = "APPROVED"
+	KYCRejected   = "REJECTED"
+)
+
+// Payout states at the partner.
+const (
+	StatusAwaitingFunds = "AWAITING_FUNDS" // waiting for the crypto at DepositAddress
+	StatusProcessing    = "PROCESSING"     // funds received, bank transfer under way
+	StatusPaid          = "PAID"
+	StatusFailed        = "FAILED"
+)
+
+// ErrUnknownCustomer is returned for a customer ID the partner does not know.
+var ErrUnknownCustomer = errors.New("offramp: unknown customer")
+
+// PayoutRequest asks the partner to buy AmountMinor of Asset from OSPay and pay the fiat proceeds
+// to the customer's bank account. Reference is OSPay's ID for the payout and makes the request
+// idempotent.
+type PayoutRequest struct {
+	Reference     string
+	CustomerID    string
+	BankAccountID string
+	Chain, Asset  string
+	AmountMinor   *big.Int
+	FiatCurrency  string // ISO 4217, e.g. "USD"
+}
+
+// Payout is the partner's view of a payout.
+type Payout struct {
+	ID              string
+	Status          string
+	DepositAddress  string   // where the crypto is to be sent
+	FiatAmountMinor *big.Int // in the currency's minor unit (cents); nil until quoted
+	Rate            string   // fiat per unit of the asset, as quoted
+	Reason          string   // why it failed
+}
+
+// Provider is an off-ramp partner. KYC is done by the partner; OSPay only keeps the status.
+type Provider interface {
+	Name() string
+	KYCStatus(ctx context.Context, customerID string) (string, error)
+	CreatePayout(ctx context.Context, req PayoutRequest) (Payout, error)
+	GetPayout(ctx context.Context, id string) (Payout, error)
+}
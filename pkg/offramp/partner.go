@@ -0,0 +1,126 @@
+package offramp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Partner talks to an off-ramp partner's REST API:
+//
+//	GET  /customers/{id}  -> {"kyc_status": "APPROVED"}
+//	POST /payouts         <- {"reference", "customer_id", "bank_account_id", "chain", "asset", "amount", "fiat_currency"}
+//	GET  /payouts/{id}    -> {"id", "status", "deposit_address", "fiat_amount", "rate", "failure_reason"}
+//
+// Amounts are decimal strings in minor units. Requests carry the API key as a bearer token and
+// POST /payouts an Idempotency-Key with the payout reference.
+type Partner struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+}
+
+func (p *Partner) Name() string { return "partner" }
+
+func (p *Partner) do(ctx context.Context, method, path string, in, out any, idempotencyKey string) error {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(p.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == http.StatusNotFound && strings.HasPrefix(path, "/customers/") {
+		return ErrUnknownCustomer
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("offramp %s %s: status %d %s", method, path, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return json.Unmarshal(b, out)
+}
+
+func (p *Partner) KYCStatus(ctx context.Context, customerID string) (string, error) {
+	var body struct {
+		KYCStatus string `json:"kyc_status"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/customers/"+url.PathEscape(customerID), nil, &body, ""); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(body.KYCStatus), nil
+}
+
+type partnerPayout struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	DepositAddress string `json:"deposit_address"`
+	FiatAmount     string `json:"fiat_amount"`
+	Rate           string `json:"rate"`
+	FailureReason  string `json:"failure_reason"`
+}
+
+func (pp partnerPayout) payout() (Payout, error) {
+	out := Payout{ID: pp.ID, Status: strings.ToUpper(pp.Status), DepositAddress: pp.DepositAddress, Rate: pp.Rate, Reason: pp.FailureReason}
+	if pp.FiatAmount != "" {
+		v, ok := new(big.Int).SetString(pp.FiatAmount, 10)
+		if !ok {
+			return Payout{}, fmt.Errorf("offramp: invalid fiat_amount %q", pp.FiatAmount)
+		}
+		out.FiatAmountMinor = v
+	}
+	return out, nil
+}
+
+func (p *Partner) CreatePayout(ctx context.Context, req PayoutRequest) (Payout, error) {
+	in := map[string]string{
+		"reference":       req.Reference,
+		"customer_id":     req.CustomerID,
+		"bank_account_id": req.BankAccountID,
+		"chain":           req.Chain,
+		"asset":           req.Asset,
+		"amount":          req.AmountMinor.String(),
+		"fiat_currency":   req.FiatCurrency,
+	}
+	var body partnerPayout
+	if err := p.do(ctx, http.MethodPost, "/payouts", in, &body, req.Reference); err != nil {
+		return Payout{}, err
+	}
+	return body.payout()
+}
+
+func (p *Partner) GetPayout(ctx context.Context, id string) (Payout, error) {
+	var body partnerPayout
+	if err := p.do(ctx, http.MethodGet, "/payouts/"+url.PathEscape(id), nil, &body, ""); err != nil {
+		return Payout{}, err
+	}
+	return body.payout()
+}
@@ -0,0 +1,115 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/oxzoid/OSPay/pkg/blockchain"
+)
+
+var (
+	latestRoundDataSelector = crypto.Keccak256([]byte("latestRoundData()"))[:4]
+	decimalsSelector        = crypto.Keccak256([]byte("decimals()"))[:4]
+)
+
+// Feed is a Chainlink aggregator (proxy) contract. MaxAge is how old its latest answer may be;
+// it should exceed the feed's heartbeat, after which Chainlink updates even without a price move.
+type Feed struct {
+	Chain   string
+	Address common.Address
+	MaxAge  time.Duration
+}
+
+// DefaultChainlinkFeeds are Chainlink's Ethereum and BNB Chain mainnet USD feeds, keyed
+// "BASE/QUOTE".
+func DefaultChainlinkFeeds() map[string]Feed {
+	return map[string]Feed{
+		"USDT/USD": {"ETH", common.HexToAddress("0x3E7d1eAB13ad0104d2750B8863b489D65364e32D"), 25 * time.Hour},
+		"USDC/USD": {"ETH", common.HexToAddress("0x8fFfFfd4AfB6115b954Bd326cbe7B4BA576818f6"), 25 * time.Hour},
+		"ETH/USD":  {"ETH", common.HexToAddress("0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"), 2 * time.Hour},
+		"BNB/USD":  {"BSC", common.HexToAddress("0x0567F2323251f0Aab15c8dFb1967E4e8A7D42aeE"), 2 * time.Hour},
+	}
+}
+
+// Chainlink reads rates from Chainlink price feeds over the chains' RPC endpoints, with no
+// off-chain rate API involved. A pair without a feed of its own is served from the inverse feed.
+type Chainlink struct {
+	Feeds map[string]Feed // "BASE/QUOTE" -> feed
+	Now   func() time.Time
+}
+
+func (c *Chainlink) Name() string { return "chainlink" }
+
+func (c *Chainlink) Rate(ctx context.Context, base, quote string) (Rate, error) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	if feed, ok := c.Feeds[base+"/"+quote]; ok {
+		return c.read(ctx, feed, base, quote)
+	}
+	feed, ok := c.Feeds[quote+"/"+base]
+	if !ok {
+		return Rate{}, ErrUnsupportedPair
+	}
+	r, err := c.read(ctx, feed, quote, base)
+	if err != nil {
+		return Rate{}, err
+	}
+	// 1/(answer/10^d) with d digits: 10^(2d) / answer.
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(2*r.Decimals)), nil)
+	r.Base, r.Quote, r.Answer = base, quote, scale.Quo(scale, r.Answer)
+	return r, nil
+}
+
+func (c *Chainlink) read(ctx context.Context, feed Feed, base, quote string) (Rate, error) {
+	client, err := blockchain.Client(feed.Chain)
+	if err != nil {
+		return Rate{}, err
+	}
+	call := func(selector []byte) ([]byte, error) {
+		return client.CallContract(ctx, ethereum.CallMsg{To: &feed.Address, Data: selector}, nil)
+	}
+	out, err := call(decimalsSelector)
+	if err != nil {
+		return Rate{}, fmt.Errorf("chainlink %s decimals: %w", feed.Address.Hex(), err)
+	}
+	if len(out) != 32 {
+		return Rate{}, fmt.Errorf("chainlink %s: decimals returned %d bytes", feed.Address.Hex(), len(out))
+	}
+	decimals := new(big.Int).SetBytes(out)
+	// latestRoundData() returns (uint80 roundId, int256 answer, uint256 startedAt,
+	// uint256 updatedAt, uint80 answeredInRound).
+	out, err = call(latestRoundDataSelector)
+	if err != nil {
+		return Rate{}, fmt.Errorf("chainlink %s latestRoundData: %w", feed.Address.Hex(), err)
+	}
+	if len(out) != 5*32 || !decimals.IsUint64() || decimals.Uint64() > 36 {
+		return Rate{}, fmt.Errorf("chainlink %s: unexpected response", feed.Address.Hex())
+	}
+	word := func(i int) []byte { return out[i*32 : (i+1)*32] }
+	roundID, answeredIn := new(big.Int).SetBytes(word(0)), new(big.Int).SetBytes(word(4))
+	answer := new(big.Int).SetBytes(word(1))
+	if answer.Bit(255) == 1 || answer.Sign() == 0 {
+		return Rate{}, fmt.Errorf("chainlink %s: non-positive answer", feed.Address.Hex())
+	}
+	updated := new(big.Int).SetBytes(word(3))
+	if !updated.IsInt64() || updated.Sign() == 0 || answeredIn.Cmp(roundID) < 0 {
+		return Rate{}, fmt.Errorf("%w: %s round %s is incomplete", ErrStale, feed.Address.Hex(), roundID)
+	}
+	updatedAt := time.Unix(updated.Int64(), 0).UTC()
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	if age := now().Sub(updatedAt); feed.MaxAge > 0 && age > feed.MaxAge {
+		return Rate{}, fmt.Errorf("%w: %s/%s updated %s ago, limit %s", ErrStale, base, quote, age.Round(time.Second), feed.MaxAge)
+	}
+	return Rate{
+		Base: base, Quote: quote, Answer: answer, Decimals: uint8(decimals.Uint64()),
+		UpdatedAt: updatedAt, Source: "chainlink:" + feed.Chain + ":" + feed.Address.Hex(),
+	}, nil
+}
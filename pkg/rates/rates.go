@@ -0,0 +1,48 @@
+// Package rates provides exchange rates between assets and currencies.
+package rates
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupportedPair is returned for a pair the provider has no rate for.
+	ErrUnsupportedPair = errors.New("rates: unsupported pair")
+	// ErrStale is returned when the newest rate is older than the provider accepts.
+	ErrStale = errors.New("rates: rate is stale")
+)
+
+// Rate is the price of one unit of Base in Quote: Answer / 10^Decimals.
+type Rate struct {
+	Base, Quote string
+	Answer      *big.Int
+	Decimals    uint8
+	UpdatedAt   time.Time
+	Source      string // e.g. the feed contract the rate was read from
+}
+
+// String formats the rate as a decimal number, e.g. "0.99985000".
+func (r Rate) String() string {
+	s := new(big.Int).Abs(r.Answer).String()
+	d := int(r.Decimals)
+	if d > 0 {
+		if len(s) <= d {
+			s = strings.Repeat("0", d-len(s)+1) + s
+		}
+		s = s[:len(s)-d] + "." + s[len(s)-d:]
+	}
+	if r.Answer.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Provider returns the current rate of base in quote, such as USDT in USD.
+type Provider interface {
+	Name() string
+	Rate(ctx context.Context, base, quote string) (Rate, error)
+}
@@ -0,0 +1,65 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const chainalysisSanctionsURL = "https://public.chainalysis.com/api/v1/address/"
+
+// Chainalysis queries the Chainalysis sanctions screening API. Any identification returned for
+// the address means it appears on a sanctions list.
+type Chainalysis struct {
+	APIKey  string
+	BaseURL string // defaults to the public sanctions endpoint
+	Client  *http.Client
+}
+
+func (c *Chainalysis) Name() string { return "chainalysis" }
+
+func (c *Chainalysis) Screen(ctx context.Context, _ string, address string) (Result, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = chainalysisSanctionsURL
+	}
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+url.PathEscape(address), nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("X-API-Key", c.APIKey)
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var body struct {
+		Identifications []struct {
+			Category string `json:"category"`
+			Name     string `json:"name"`
+		} `json:"identifications"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Result{}, err
+	}
+	if len(body.Identifications) == 0 {
+		return Result{}, nil
+	}
+	names := make([]string, 0, len(body.Identifications))
+	for _, id := range body.Identifications {
+		names = append(names, id.Category+": "+id.Name)
+	}
+	return Result{Flagged: true, Reason: strings.Join(names, "; ")}, nil
+}
@@ -0,0 +1,95 @@
+// Package risk screens payer wallet addresses before a payment is credited.
+package risk
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Result is the outcome of screening one address.
+type Result struct {
+	Flagged bool
+	Source  string // provider that flagged the address, e.g. "denylist" or "chainalysis"
+	Reason  string
+}
+
+// Provider screens an address on a chain. Implementations wrap a local list or a third-party API
+// (Chainalysis, TRM, ...).
+type Provider interface {
+	Name() string
+	Screen(ctx context.Context, chain, address string) (Result, error)
+}
+
+// Screener runs every configured provider and reports the first hit.
+type Screener struct {
+	Providers []Provider
+	// Hold puts flagged payments into REVIEW instead of crediting them; when false they are credited
+	// and only flagged on the order.
+	Hold bool
+}
+
+// Screen returns the first flagged result. A provider error aborts screening so callers can treat
+// the payment as unscreened rather than clean.
+func (s *Screener) Screen(ctx context.Context, chain, address string) (Result, error) {
+	for _, p := range s.Providers {
+		res, err := p.Screen(ctx, chain, address)
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		if res.Flagged {
+			if res.Source == "" {
+				res.Source = p.Name()
+			}
+			return res, nil
+		}
+	}
+	return Result{}, nil
+}
+
+// Denylist flags addresses from a static, operator-maintained list. Matching is case-insensitive
+// and ignores the chain, since EVM addresses are shared across chains.
+type Denylist struct {
+	addrs map[string]struct{}
+}
+
+// NewDenylist builds a denylist from the given addresses.
+func NewDenylist(addrs []string) *Denylist {
+	d := &Denylist{addrs: make(map[string]struct{}, len(addrs))}
+	for _, a := range addrs {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+			d.addrs[a] = struct{}{}
+		}
+	}
+	return d
+}
+
+// LoadDenylistFile reads one address per line; blank lines and lines starting with # are skipped.
+func LoadDenylistFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var addrs []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addrs = append(addrs, line)
+	}
+	return addrs, sc.Err()
+}
+
+func (d *Denylist) Name() string { return "denylist" }
+
+func (d *Denylist) Screen(_ context.Context, _ string, address string) (Result, error) {
+	if _, ok := d.addrs[strings.ToLower(address)]; ok {
+		return Result{Flagged: true, Reason: "address is on the denylist"}, nil
+	}
+	return Result{}, nil
+}
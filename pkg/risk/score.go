@@ -0,0 +1,75 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Signals are the per-payment facts the scorer works from. AmountPercentile is the share of the
+// merchant's earlier payments in the same asset that were smaller than this one, or -1 when there
+// is not enough history.
+type Signals struct {
+	AmountPercentile       float64
+	NewSender              bool
+	SenderPaymentsLastHour int
+	Chain                  string
+}
+
+// Scorer turns Signals into a 0-100 score with a fixed weight per rule.
+type Scorer struct {
+	AmountPercentile float64 // payments at or above this percentile get AmountWeight
+	AmountWeight     int
+	NewSenderWeight  int
+	VelocityLimit    int // payments from one sender in the last hour before VelocityWeight applies
+	VelocityWeight   int
+	ChainWeights     map[string]int // extra weight per chain, e.g. chains without sender verification
+	// ReviewThreshold routes payments scoring at or above it to REVIEW; 0 only records the score.
+	ReviewThreshold int
+}
+
+// DefaultScorer flags large payments from new, busy senders; any two rules alone stay below review.
+func DefaultScorer() *Scorer {
+	return &Scorer{
+		AmountPercentile: 0.95,
+		AmountWeight:     30,
+		NewSenderWeight:  20,
+		VelocityLimit:    5,
+		VelocityWeight:   30,
+		ChainWeights:     map[string]int{},
+		ReviewThreshold:  70,
+	}
+}
+
+// Score returns the payment's score and the rules that contributed to it.
+func (s *Scorer) Score(sig Signals) (int, []string) {
+	score := 0
+	var factors []string
+	if sig.AmountPercentile >= 0 && sig.AmountPercentile >= s.AmountPercentile {
+		score += s.AmountWeight
+		factors = append(factors, fmt.Sprintf("amount_p%.0f", sig.AmountPercentile*100))
+	}
+	if sig.NewSender {
+		score += s.NewSenderWeight
+		factors = append(factors, "new_sender")
+	}
+	if s.VelocityLimit > 0 && sig.SenderPaymentsLastHour >= s.VelocityLimit {
+		score += s.VelocityWeight
+		factors = append(factors, fmt.Sprintf("velocity_%d_per_hour", sig.SenderPaymentsLastHour))
+	}
+	if w := s.ChainWeights[strings.ToUpper(sig.Chain)]; w != 0 {
+		score += w
+		factors = append(factors, "chain_"+strings.ToLower(sig.Chain))
+	}
+	if score > 100 {
+		score = 100
+	}
+	if score < 0 {
+		score = 0
+	}
+	return score, factors
+}
+
+// NeedsReview reports whether a score should hold the payment for manual review.
+func (s *Scorer) NeedsReview(score int) bool {
+	return s.ReviewThreshold > 0 && score >= s.ReviewThreshold
+}
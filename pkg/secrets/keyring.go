@@ -0,0 +1,207 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encrypted values are stored as "enc:v1:<key id>:<base64(nonce || ciphertext)>". Anything without
+// the prefix is treated as a plaintext value written before encryption was enabled.
+const ciphertextPrefix = "enc:v1:"
+
+// Keyring holds the AES-256-GCM keys for field-level encryption. Values are sealed with the current
+// key; older keys stay available for decrypting until the data has been re-encrypted.
+type Keyring struct {
+	current string
+	order   []string // key ids, current first
+	aeads   map[string]cipher.AEAD
+	index   map[string][]byte // per-key HMAC keys for blind indexes
+}
+
+// ParseKeyring parses "id:base64key,id:base64key". The first entry is the current key; each key
+// must decode to 32 bytes.
+func ParseKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{aeads: map[string]cipher.AEAD{}, index: map[string][]byte{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key entry %q, want id:base64key", entry)
+		}
+		if _, dup := k.aeads[id]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, base64-encoded", id)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		mac := hmac.New(sha256.New, raw)
+		mac.Write([]byte("ospay blind index"))
+		k.aeads[id] = aead
+		k.index[id] = mac.Sum(nil)
+		k.order = append(k.order, id)
+	}
+	if len(k.order) == 0 {
+		return nil, errors.New("no keys configured")
+	}
+	k.current = k.order[0]
+	return k, nil
+}
+
+// CurrentKeyID returns the id of the key new values are sealed with.
+func (k *Keyring) CurrentKeyID() string { return k.current }
+
+// Encrypt seals plaintext with the current key.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead := k.aeads[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return ciphertextPrefix + k.current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt with whichever key sealed it. Plaintext values are
+// returned unchanged.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, ciphertextPrefix) {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, ciphertextPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key %q", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt with key %q: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// SealedWithCurrent reports whether value is already encrypted with the current key.
+func (k *Keyring) SealedWithCurrent(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix+k.current+":")
+}
+
+// BlindIndex returns a keyed hash of value (case-insensitive) for equality lookups on encrypted
+// columns, computed with the current key.
+func (k *Keyring) BlindIndex(value string) string {
+	return blindIndex(k.index[k.current], value)
+}
+
+// BlindIndexes returns value's blind index under every key, current first, so lookups still match
+// rows written before a rotation.
+func (k *Keyring) BlindIndexes(value string) []string {
+	out := make([]string, 0, len(k.order))
+	for _, id := range k.order {
+		out = append(out, blindIndex(k.index[id], value))
+	}
+	return out
+}
+
+func blindIndex(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// keyring is the process-wide keyring used by EncryptedString; nil disables encryption.
+var keyring *Keyring
+
+// SetKeyring installs the keyring used for field encryption. Passing nil stores fields in plaintext.
+func SetKeyring(k *Keyring) { keyring = k }
+
+// DefaultKeyring returns the installed keyring, or nil when encryption is disabled.
+func DefaultKeyring() *Keyring { return keyring }
+
+// EncryptedString is a nullable string column that is encrypted with the installed keyring when
+// written and decrypted when scanned, so callers handle plaintext only.
+type EncryptedString struct {
+	String string
+	Valid  bool
+}
+
+// Scan implements sql.Scanner.
+func (e *EncryptedString) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+		*e = EncryptedString{}
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("EncryptedString: unsupported type %T", src)
+	}
+	if keyring != nil {
+		plain, err := keyring.Decrypt(raw)
+		if err != nil {
+			return err
+		}
+		raw = plain
+	} else if strings.HasPrefix(raw, ciphertextPrefix) {
+		return errors.New("encrypted value found but no encryption keys are configured")
+	}
+	*e = EncryptedString{String: raw, Valid: true}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (e EncryptedString) Value() (driver.Value, error) {
+	if !e.Valid {
+		return nil, nil
+	}
+	if keyring == nil {
+		return e.String, nil
+	}
+	return keyring.Encrypt(e.String)
+}
+
+// BlindIndex returns the installed keyring's blind index for value, or nil when encryption is
+// disabled or value is empty, for storing next to an EncryptedString column.
+func BlindIndex(value string) any {
+	if keyring == nil || value == "" {
+		return nil
+	}
+	return keyring.BlindIndex(value)
+}
+
+// BlindIndexes returns value's blind indexes under every installed key (none when encryption is
+// disabled).
+func BlindIndexes(value string) []string {
+	if keyring == nil || value == "" {
+		return nil
+	}
+	return keyring.BlindIndexes(value)
+}
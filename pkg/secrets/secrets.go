@@ -0,0 +1,31 @@
+// Package secrets loads secret material (encryption keys, credentials) and encrypts sensitive
+// fields before they are written to the database.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Provider returns the secret stored under name, or "" when it is not set. Implementations wrap
+// the process environment, mounted files or a secret manager (Vault, AWS Secrets Manager, ...).
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Env reads secrets from the environment. NAME_FILE, when set, names a file holding the value
+// (Docker/Kubernetes secret mounts) and takes precedence over NAME.
+type Env struct{}
+
+func (Env) Get(_ context.Context, name string) (string, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return os.Getenv(name), nil
+}
@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SQLite has one writer at a time. A statement that waits longer than busy_timeout for the write
+// lock fails with SQLITE_BUSY; the connections returned by Instrument try such statements again
+// where that is safe, and count what they retried and gave up on.
+
+// sqliteBusy is SQLITE_BUSY. Extended codes such as SQLITE_BUSY_SNAPSHOT, a transaction whose
+// snapshot is out of date, are not retried: trying the statement again cannot succeed.
+const sqliteBusy = 5
+
+var (
+	busyRetries = 3
+	busyBackoff = 50 * time.Millisecond
+
+	busyRetriesTotal int64 // statements tried again after SQLITE_BUSY
+	busyErrorsTotal  int64 // statements still busy after every retry, or not retried
+)
+
+// TxDurationBuckets are the upper bounds, in seconds, of the transaction duration histograms.
+var TxDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// TxDurations is the histogram of the transactions that ended in Outcome: commit, rollback or
+// error (a commit that failed).
+type TxDurations struct {
+	Outcome string
+	Buckets []uint64 // cumulative, one per TxDurationBuckets
+	Count   uint64
+	Sum     float64 // seconds
+}
+
+// ContentionStats are the counts behind the database metrics, since the process started.
+type ContentionStats struct {
+	BusyRetries  int64
+	BusyErrors   int64
+	Transactions []TxDurations // by outcome
+}
+
+var (
+	txMetricsMu sync.Mutex
+	txDurations = map[string]*TxDurations{}
+)
+
+// SetBusyRetry sets how many times a statement failing with SQLITE_BUSY is tried again, pausing
+// backoff before the first retry and twice as long before each next one.
+func SetBusyRetry(retries int, backoff time.Duration) {
+	busyRetries, busyBackoff = retries, backoff
+}
+
+// Contention returns the busy and transaction counts of the instrumented connections.
+func Contention() ContentionStats {
+	s := ContentionStats{BusyRetries: atomic.LoadInt64(&busyRetriesTotal), BusyErrors: atomic.LoadInt64(&busyErrorsTotal)}
+	txMetricsMu.Lock()
+	defer txMetricsMu.Unlock()
+	for _, h := range txDurations {
+		c := *h
+		c.Buckets = make([]uint64, len(h.Buckets))
+		var cumulative uint64
+		for i, n := range h.Buckets {
+			cumulative += n
+			c.Buckets[i] = cumulative
+		}
+		s.Transactions = append(s.Transactions, c)
+	}
+	sort.Slice(s.Transactions, func(i, j int) bool { return s.Transactions[i].Outcome < s.Transactions[j].Outcome })
+	return s
+}
+
+func observeTx(outcome string, d time.Duration) {
+	txMetricsMu.Lock()
+	defer txMetricsMu.Unlock()
+	h := txDurations[outcome]
+	if h == nil {
+		h = &TxDurations{Outcome: outcome, Buckets: make([]uint64, len(TxDurationBuckets))}
+		txDurations[outcome] = h
+	}
+	secs := d.Seconds()
+	if i := sort.SearchFloat64s(TxDurationBuckets, secs); i < len(TxDurationBuckets) {
+		h.Buckets[i]++
+	}
+	h.Count++
+	h.Sum += secs
+}
+
+// WALSize is the size in bytes of the write-ahead log of q's main database, 0 when it has none
+// (a database not in WAL mode, or one that is not a SQLite file).
+func WALSize(ctx context.Context, q DBTX) int64 {
+	rows, err := q.QueryContext(ctx, `PRAGMA database_list`)
+	if err != nil {
+		return 0
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			seq        int
+			name, file string
+		)
+		if rows.Scan(&seq, &name, &file) != nil || name != "main" || file == "" {
+			continue
+		}
+		if fi, err := os.Stat(file + "-wal"); err == nil {
+			return fi.Size()
+		}
+	}
+	return 0
+}
+
+// Instrument returns a connector opening dsn with d whose connections retry SQLITE_BUSY and time
+// transactions, for sql.OpenDB.
+func Instrument(d driver.Driver, dsn string) driver.Connector {
+	return instrumentedConnector{d, dsn}
+}
+
+type instrumentedConnector struct {
+	d   driver.Driver
+	dsn string
+}
+
+func (c instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.d.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if sc, ok := conn.(sqlConn); ok {
+		return &instrumentedConn{sqlConn: sc}, nil
+	}
+	return conn, nil
+}
+
+func (c instrumentedConnector) Driver() driver.Driver { return c.d }
+
+// sqlConn is what the SQLite driver's connections implement.
+type sqlConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// instrumentedConn is used by one goroutine at a time, as database/sql guarantees, so tx needs no
+// lock.
+type instrumentedConn struct {
+	sqlConn
+	tx *instrumentedTx // open transaction, if any
+}
+
+type instrumentedTx struct {
+	driver.Tx
+	conn       *instrumentedConn
+	start      time.Time
+	statements int
+}
+
+// retryBusy runs op, and again after a growing pause while it fails with SQLITE_BUSY and trying
+// again is safe: outside a transaction, or for the first statement of one, before it has read
+// anything that a retry could find changed.
+func (c *instrumentedConn) retryBusy(ctx context.Context, op func() error) error {
+	retryable := true
+	if c.tx != nil {
+		retryable = c.tx.statements == 0
+		c.tx.statements++
+	}
+	err := op()
+	pause := busyBackoff
+	for attempt := 0; retryable && isBusy(err) && attempt < busyRetries; attempt++ {
+		atomic.AddInt64(&busyRetriesTotal, 1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(pause):
+		}
+		pause *= 2
+		err = op()
+	}
+	if isBusy(err) {
+		atomic.AddInt64(&busyErrorsTotal, 1)
+	}
+	return err
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var res driver.Result
+	err := c.retryBusy(ctx, func() (err error) {
+		res, err = c.sqlConn.ExecContext(ctx, query, args)
+		return err
+	})
+	return res, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := c.retryBusy(ctx, func() (err error) {
+		rows, err = c.sqlConn.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	err := c.retryBusy(ctx, func() (err error) {
+		tx, err = c.sqlConn.BeginTx(ctx, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.tx = &instrumentedTx{Tx: tx, conn: c, start: time.Now()}
+	return c.tx, nil
+}
+
+// Commit is tried again while the database is busy: a COMMIT failing with SQLITE_BUSY leaves the
+// transaction open for another try.
+func (t *instrumentedTx) Commit() error {
+	t.statements = 0
+	err := t.conn.retryBusy(context.Background(), t.Tx.Commit)
+	outcome := "commit"
+	if err != nil {
+		outcome = "error"
+	}
+	t.end(outcome)
+	return err
+}
+
+func (t *instrumentedTx) Rollback() error {
+	err := t.Tx.Rollback()
+	t.end("rollback")
+	return err
+}
+
+func (t *instrumentedTx) end(outcome string) {
+	if t.conn.tx == t {
+		t.conn.tx = nil
+	}
+	observeTx(outcome, time.Since(t.start))
+}
+
+func isBusy(err error) bool {
+	var coded interface{ Code() int }
+	return errors.As(err, &coded) && coded.Code() == sqliteBusy
+}
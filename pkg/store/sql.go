@@ -0,0 +1,453 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/oxzoid/OSPay/pkg/secrets"
+)
+
+// NewSQL returns the SQL-backed stores. q is usually the *sql.DB; pass a *sql.Tx to run the
+// store calls inside that transaction.
+func NewSQL(q DBTX) Stores {
+	return Stores{
+		Orders:    sqlOrders{q},
+		Merchants: sqlMerchants{q},
+		Ledger:    sqlLedger{q},
+	}
+}
+
+// isUniqueViolation recognizes unique constraint errors from SQLite and Postgres.
+func isUniqueViolation(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") || strings.Contains(msg, "duplicate key value")
+}
+
+func nullable(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func strPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func int64Ptr(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
+
+type sqlOrders struct{ q DBTX }
+
+const orderCols = `id, merchant_id, amount_minor, asset, chain, status, deposit_address, COALESCE(order_idempotency_key, ''),
+	created_at, tx_hash, confirmed_block, paid_at, application_fee_minor, customer_wallet_address, customer_email,
+	metadata_json, risk_reason, risk_score, risk_factors, expires_at, block_timestamp, coupon_code, discount_minor,
+	external_order_id, mispayment_json, fiat_pricing_json`
+
+func (s sqlOrders) Create(ctx context.Context, o Order) error {
+	var email, webhookSecret secrets.EncryptedString
+	var emailHash any
+	if o.CustomerEmail != nil {
+		email = secrets.EncryptedString{String: *o.CustomerEmail, Valid: true}
+		emailHash = secrets.BlindIndex(*o.CustomerEmail)
+	}
+	if o.WebhookSecret != nil {
+		webhookSecret = secrets.EncryptedString{String: *o.WebhookSecret, Valid: true}
+	}
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO orders
+		  (id, merchant_id, amount_minor, asset, chain, status, deposit_address, created_at, order_idempotency_key, application_fee_minor,
+		   customer_wallet_address, customer_email, customer_email_hash, metadata_json, expires_at, coupon_code, discount_minor,
+		   external_order_id, webhook_url, webhook_secret, fiat_pricing_json)
+		VALUES
+		  (?,  ?,           ?,            ?,     ?,     ?,      ?,               ?,          ?,                     ?,
+		   ?,                       ?,              ?,                   ?,             ?,          ?,           ?,
+		   ?,                 ?,           ?,              ?)
+	`, o.ID, o.MerchantID, o.AmountMinor, o.Asset, o.Chain, o.Status, o.DepositAddress, o.CreatedAt, o.IdempotencyKey, o.ApplicationFeeMinor,
+		o.CustomerWalletAddress, email, emailHash, o.Metadata, o.ExpiresAt, o.CouponCode, o.DiscountMinor,
+		o.ExternalOrderID, o.WebhookURL, webhookSecret, o.FiatPricing)
+	if err != nil && isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s sqlOrders) Get(ctx context.Context, id, merchantID string) (Order, error) {
+	// Orders moved out by the retention job are still served from the archive
+	return s.scan(s.q.QueryRowContext(ctx, `
+		SELECT `+orderCols+` FROM orders WHERE id = ? AND (? = '' OR merchant_id = ?) AND deleted_at IS NULL
+		UNION ALL
+		SELECT `+orderCols+` FROM orders_archive WHERE id = ? AND (? = '' OR merchant_id = ?) AND deleted_at IS NULL
+		LIMIT 1
+	`, id, merchantID, merchantID, id, merchantID, merchantID))
+}
+
+func (s sqlOrders) GetMany(ctx context.Context, ids []string, merchantID string) ([]Order, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	in := `id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `) AND (? = '' OR merchant_id = ?) AND deleted_at IS NULL`
+	args := make([]any, 0, len(ids)+2)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, merchantID, merchantID)
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT `+orderCols+` FROM orders WHERE `+in+`
+		UNION ALL
+		SELECT `+orderCols+` FROM orders_archive WHERE `+in+`
+	`, append(args, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var orders []Order
+	for rows.Next() {
+		o, err := s.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (s sqlOrders) GetByIdempotencyKey(ctx context.Context, merchantID, key string) (Order, error) {
+	return s.scan(s.q.QueryRowContext(ctx, `SELECT `+orderCols+` FROM orders WHERE order_idempotency_key = ? AND merchant_id = ?`, key, merchantID))
+}
+
+func (s sqlOrders) List(ctx context.Context, f OrderFilter) ([]Order, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT `+orderCols+` FROM orders
+		WHERE (? = '' OR merchant_id = ?) AND (? = '' OR status = ?) AND deleted_at IS NULL
+		  AND (? = '' OR customer_wallet_address = ? COLLATE NOCASE)
+		  AND (NOT ? OR status IN ('PAID', 'SETTLED', 'REFUNDED', 'PARTIALLY_REFUNDED'))
+		  AND (? = '' OR EXISTS (SELECT 1 FROM order_tags t WHERE t.order_id = orders.id AND t.tag = ?))
+		  AND (? = '' OR created_at < ? OR (created_at = ? AND id < ?))
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, f.MerchantID, f.MerchantID, f.Status, f.Status, f.CustomerWalletAddress, f.CustomerWalletAddress, f.Credited,
+		f.Tag, f.Tag, f.AfterID, f.AfterCreatedAt, f.AfterCreatedAt, f.AfterID, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var orders []Order
+	for rows.Next() {
+		o, err := s.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (s sqlOrders) Search(ctx context.Context, q OrderSearch) ([]Order, error) {
+	where := []string{`(? = '' OR merchant_id = ?)`, `deleted_at IS NULL`}
+	args := []any{q.MerchantID, q.MerchantID}
+	if q.ExternalOrderID != "" {
+		where = append(where, `external_order_id = ?`)
+		args = append(args, q.ExternalOrderID)
+	}
+	if q.TxHash != "" {
+		// Hashes are stored as reported; most clients report them in lower case
+		where = append(where, `tx_hash IN (?, ?)`)
+		args = append(args, q.TxHash, strings.ToLower(q.TxHash))
+	}
+	if q.CustomerEmail != "" {
+		// Encrypted emails are matched on their blind index; rows without one are still plaintext
+		cond := `(customer_email_hash IS NULL AND customer_email = ? COLLATE NOCASE)`
+		var hashArgs []any
+		if hashes := secrets.BlindIndexes(q.CustomerEmail); len(hashes) > 0 {
+			cond = `(customer_email_hash IN (?` + strings.Repeat(`, ?`, len(hashes)-1) + `) OR ` + cond + `)`
+			for _, h := range hashes {
+				hashArgs = append(hashArgs, h)
+			}
+		}
+		where = append(where, cond)
+		args = append(append(args, hashArgs...), q.CustomerEmail)
+	}
+	keys := make([]string, 0, len(q.Metadata))
+	for k := range q.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// JSON1; the key is quoted into the path so dots and brackets in it are taken literally
+		where = append(where, `CAST(json_extract(metadata_json, ?) AS TEXT) = ?`)
+		args = append(args, `$."`+strings.ReplaceAll(k, `"`, `""`)+`"`, q.Metadata[k])
+	}
+	if q.Tag != "" {
+		// order_tags has no id column, so id is the order of whichever table is queried
+		where = append(where, `EXISTS (SELECT 1 FROM order_tags t WHERE t.order_id = id AND t.tag = ?)`)
+		args = append(args, q.Tag)
+	}
+	cond := strings.Join(where, " AND ")
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT `+orderCols+` FROM orders WHERE `+cond+`
+		UNION ALL
+		SELECT `+orderCols+` FROM orders_archive WHERE `+cond+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, append(append(args, args...), q.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var orders []Order
+	for rows.Next() {
+		o, err := s.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (sqlOrders) scan(row scanner) (Order, error) {
+	var (
+		o                                 Order
+		txHash, paidAt, fee, wallet, meta sql.NullString
+		expiresAt, blockTimestamp         sql.NullString
+		couponCode, discount, externalID  sql.NullString
+		mispayment, fiatPricing           sql.NullString
+		riskReason, riskFactors           sql.NullString
+		confirmedBlock, riskScore         sql.NullInt64
+		email                             secrets.EncryptedString
+	)
+	err := row.Scan(&o.ID, &o.MerchantID, &o.AmountMinor, &o.Asset, &o.Chain, &o.Status, &o.DepositAddress, &o.IdempotencyKey,
+		&o.CreatedAt, &txHash, &confirmedBlock, &paidAt, &fee, &wallet, &email,
+		&meta, &riskReason, &riskScore, &riskFactors, &expiresAt, &blockTimestamp, &couponCode, &discount,
+		&externalID, &mispayment, &fiatPricing)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Order{}, ErrNotFound
+	}
+	if err != nil {
+		return Order{}, err
+	}
+	o.TxHash = strPtr(txHash)
+	o.ConfirmedBlock = int64Ptr(confirmedBlock)
+	o.BlockTimestamp = strPtr(blockTimestamp)
+	o.CouponCode = strPtr(couponCode)
+	o.DiscountMinor = strPtr(discount)
+	o.ExternalOrderID = strPtr(externalID)
+	o.Mispayment = strPtr(mispayment)
+	o.FiatPricing = strPtr(fiatPricing)
+	o.PaidAt = strPtr(paidAt)
+	o.ExpiresAt = strPtr(expiresAt)
+	o.ApplicationFeeMinor = strPtr(fee)
+	o.CustomerWalletAddress = strPtr(wallet)
+	if email.Valid {
+		o.CustomerEmail = &email.String
+	}
+	o.Metadata = strPtr(meta)
+	o.RiskReason = strPtr(riskReason)
+	o.RiskScore = int64Ptr(riskScore)
+	o.RiskFactors = strPtr(riskFactors)
+	return o, nil
+}
+
+type sqlMerchants struct{ q DBTX }
+
+func (s sqlMerchants) Create(ctx context.Context, m Merchant, apiKeyHash string) error {
+	_, err := s.q.ExecContext(ctx, `
+		INSERT INTO merchants (id, name, api_key, merchant_wallet_address, wallet_ens_name, wallet_ens_checked_at, platform_id, organization_id, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, 'ACTIVE'), ?)
+	`, m.ID, m.Name, apiKeyHash, m.WalletAddress, nullable(m.WalletENSName), sql.NullString{String: m.CreatedAt, Valid: m.WalletENSName != ""},
+		nullable(m.PlatformID), nullable(m.OrganizationID), nullable(m.Status), m.CreatedAt)
+	if err != nil && isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s sqlMerchants) Get(ctx context.Context, id string) (Merchant, error) {
+	var m Merchant
+	err := s.q.QueryRowContext(ctx, `
+		SELECT id, COALESCE(name, ''), COALESCE(merchant_wallet_address, ''), COALESCE(wallet_ens_name, ''), COALESCE(platform_id, ''),
+		       COALESCE(organization_id, ''), status, created_at
+		FROM merchants WHERE id = ?
+	`, id).Scan(&m.ID, &m.Name, &m.WalletAddress, &m.WalletENSName, &m.PlatformID, &m.OrganizationID, &m.Status, &m.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Merchant{}, ErrNotFound
+	}
+	return m, err
+}
+
+func (s sqlMerchants) IDByAPIKeyHash(ctx context.Context, hash string) (string, error) {
+	var id string
+	err := s.q.QueryRowContext(ctx, `SELECT id FROM merchants WHERE api_key = ?`, hash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return id, err
+}
+
+type sqlLedger struct{ q DBTX }
+
+func (s sqlLedger) Append(ctx context.Context, entries ...LedgerEntry) error {
+	const insert = `
+		INSERT INTO ledger_entries
+		  (id, order_id, merchant_id, asset, chain, amount_minor, bucket, direction, event_type, tx_hash, reference_id, rate_quote_id,
+		   transaction_group_id, created_at)
+		VALUES
+		  (?,  ?,        ?,           ?,     ?,     ?,            ?,      ?,         ?,          ?,       ?,            ?,
+		   ?,                    ?)
+	`
+	entries = append([]LedgerEntry(nil), entries...)
+	group := "ltx_" + uuid.New().String()
+	for i := range entries {
+		e := &entries[i]
+		if e.Chain == "" && e.OrderID != "" {
+			if err := s.q.QueryRowContext(ctx, `SELECT UPPER(chain) FROM orders WHERE id = ?`, e.OrderID).Scan(&e.Chain); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+		if e.ID == "" {
+			id, err := newLedgerEntryID()
+			if err != nil {
+				return err
+			}
+			e.ID = id
+		}
+		if e.TransactionGroupID == "" {
+			e.TransactionGroupID = group
+		}
+	}
+	if err := checkBalanced(entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := s.q.ExecContext(ctx, insert,
+			e.ID, nullable(e.OrderID), e.MerchantID, e.Asset, nullable(e.Chain), e.AmountMinor, e.Bucket, e.Direction, e.EventType,
+			nullable(e.TxHash), nullable(e.ReferenceID), nullable(e.RateQuoteID), e.TransactionGroupID, e.CreatedAt,
+		); err != nil {
+			return err
+		}
+		if !e.Carried {
+			if err := s.addBalance(ctx, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newLedgerEntryID returns the ID of a new ledger entry: "led_" and a UUIDv7, so that IDs made
+// in the same second, or the same millisecond, never repeat and still sort in the order they were
+// made. IDs of entries written before, led_ and the time they were written, are left as they are:
+// a UUIDv7 never reads like a timestamp, so the two cannot collide, and listings order by
+// created_at before id.
+func newLedgerEntryID() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return "led_" + id.String(), nil
+}
+
+// checkBalanced verifies that the credits and debits of each transaction group among entries are
+// equal on every asset and chain. Carried entries stand for archived ones and are not checked.
+func checkBalanced(entries []LedgerEntry) error {
+	type key struct{ group, asset, chain string }
+	nets := map[key]*big.Int{}
+	var keys []key
+	for _, e := range entries {
+		if e.Carried {
+			continue
+		}
+		v, ok := new(big.Int).SetString(e.AmountMinor, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount_minor %q", e.AmountMinor)
+		}
+		if e.Direction == "debit" {
+			v.Neg(v)
+		}
+		k := key{e.TransactionGroupID, e.Asset, e.Chain}
+		if nets[k] == nil {
+			nets[k] = new(big.Int)
+			keys = append(keys, k)
+		}
+		nets[k].Add(nets[k], v)
+	}
+	for _, k := range keys {
+		if nets[k].Sign() != 0 {
+			return fmt.Errorf("%w: group %s nets %s %s on chain %q", ErrUnbalanced, k.group, nets[k], k.asset, k.chain)
+		}
+	}
+	return nil
+}
+
+// addBalance applies e to the materialized ledger_balances row of its bucket. Amounts can exceed
+// what SQL integers hold, so the sum is taken here.
+func (s sqlLedger) addBalance(ctx context.Context, e LedgerEntry) error {
+	delta, ok := new(big.Int).SetString(e.AmountMinor, 10)
+	if !ok {
+		return fmt.Errorf("invalid amount_minor %q", e.AmountMinor)
+	}
+	if e.Direction == "debit" {
+		delta.Neg(delta)
+	}
+	var current string
+	err := s.q.QueryRowContext(ctx, `
+		SELECT balance_minor FROM ledger_balances WHERE merchant_id = ? AND asset = ? AND chain = ? AND bucket = ?
+	`, e.MerchantID, e.Asset, e.Chain, e.Bucket).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if v, ok := new(big.Int).SetString(current, 10); ok {
+		delta.Add(delta, v)
+	}
+	_, err = s.q.ExecContext(ctx, `
+		INSERT INTO ledger_balances (merchant_id, asset, chain, bucket, balance_minor, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (merchant_id, asset, chain, bucket) DO UPDATE SET balance_minor = excluded.balance_minor, updated_at = excluded.updated_at
+	`, e.MerchantID, e.Asset, e.Chain, e.Bucket, delta.String(), e.CreatedAt)
+	return err
+}
+
+func (s sqlLedger) Balances(ctx context.Context, merchantID string) ([]BucketBalance, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT asset, chain, bucket, balance_minor FROM ledger_balances WHERE merchant_id = ? ORDER BY asset, chain, bucket
+	`, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []BucketBalance
+	for rows.Next() {
+		var b BucketBalance
+		if err := rows.Scan(&b.Asset, &b.Chain, &b.Bucket, &b.AmountMinor); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func (s sqlLedger) Balance(ctx context.Context, merchantID, asset, bucket string) (int64, error) {
+	var balance int64
+	err := s.q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN direction='credit' THEN amount_minor ELSE -amount_minor END),0)
+		FROM ledger_entries
+		WHERE merchant_id = ? AND asset = ? AND bucket = ?
+	`, merchantID, asset, bucket).Scan(&balance)
+	return balance, err
+}
@@ -0,0 +1,184 @@
+// Package store holds persistence interfaces for orders, merchants and the ledger, together with
+// their SQL implementations. They cover the API's order reads and creation, merchant creation and
+// API key lookup, and its ledger postings and balances; its other queries, order updates among
+// them, still run on the database directly, so moving to another backend means extending these
+// interfaces first.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist (or is outside the caller's scope).
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when an insert violates a unique constraint.
+	ErrDuplicate = errors.New("duplicate")
+	// ErrUnbalanced is returned when the legs of a ledger transaction group do not net to zero.
+	ErrUnbalanced = errors.New("unbalanced ledger transaction")
+)
+
+// DBTX is the subset of *sql.DB and *sql.Tx the SQL stores use, so the same store works inside and
+// outside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Order is a payment order. Nil pointers are NULL columns. Amounts are decimal strings in the
+// asset's minor units.
+type Order struct {
+	ID                    string
+	MerchantID            string
+	AmountMinor           string
+	Asset                 string
+	Chain                 string
+	Status                string
+	DepositAddress        string
+	IdempotencyKey        string
+	CreatedAt             string
+	ExpiresAt             *string // nil on orders created before expiry was stored
+	TxHash                *string
+	ConfirmedBlock        *int64
+	BlockTimestamp        *string
+	PaidAt                *string
+	ApplicationFeeMinor   *string
+	CustomerWalletAddress *string
+	CustomerEmail         *string // plaintext; encrypted at rest when a keyring is configured
+	Metadata              *string // JSON object
+	RiskReason            *string
+	RiskScore             *int64
+	RiskFactors           *string
+	CouponCode            *string
+	DiscountMinor         *string // taken off the price by the coupon; AmountMinor is already net of it
+	ExternalOrderID       *string // the merchant's own reference
+	Mispayment            *string // JSON object: the transfer in the wrong token or on the wrong chain of a MISPAID order
+	FiatPricing           *string // JSON object: the fiat price and rate quote of an order priced in fiat
+	// WebhookURL and WebhookSecret override the merchant's webhook for the order's events. Create
+	// writes them (the secret encrypted like CustomerEmail); the order reads do not return them.
+	WebhookURL    *string
+	WebhookSecret *string
+}
+
+// Merchant is a merchant account. PlatformID is empty for merchants not connected to a platform,
+// OrganizationID for merchants not in an organization.
+type Merchant struct {
+	ID             string
+	Name           string
+	WalletAddress  string
+	WalletENSName  string // ENS name WalletAddress was resolved from, if any
+	PlatformID     string
+	OrganizationID string
+	Status         string // ACTIVE, PENDING_APPROVAL or REJECTED; Create stores "" as ACTIVE
+	CreatedAt      string
+}
+
+// LedgerEntry is one side of a double entry. Empty OrderID, TxHash, ReferenceID and RateQuoteID are
+// stored as NULL.
+// LedgerStore.Append gives entries without an ID a new one. An empty Chain is taken from the order, if any, and
+// an empty TransactionGroupID is the one Append gives the entries appended together.
+type LedgerEntry struct {
+	ID          string
+	OrderID     string
+	MerchantID  string
+	Asset       string
+	Chain       string
+	AmountMinor string
+	Bucket      string
+	Direction   string // "credit" or "debit"
+	EventType   string
+	TxHash      string
+	ReferenceID string
+	RateQuoteID string // rate quote of a fiat-priced order's entries
+	CreatedAt   string
+	// TransactionGroupID links the legs of one double entry, fee legs included.
+	TransactionGroupID string
+	// Carried entries replace archived ones whose amounts the materialized balances already hold,
+	// so they are not added to them again.
+	Carried bool
+}
+
+// BucketBalance is the materialized net of a merchant's bucket for one asset on one chain; Chain is
+// empty for entries not tied to a chain, such as fiat amounts.
+type BucketBalance struct {
+	Asset       string
+	Chain       string
+	Bucket      string
+	AmountMinor string
+}
+
+// OrderFilter selects orders for OrderStore.List. Orders come newest first; a non-empty AfterID
+// (with its AfterCreatedAt) continues after that order.
+type OrderFilter struct {
+	MerchantID            string
+	Status                string
+	CustomerWalletAddress string // compared case-insensitively
+	Credited              bool   // only orders whose payment was credited: PAID, SETTLED or (partially) refunded
+	Tag                   string // only orders carrying this (normalized) tag
+	Limit                 int
+	AfterCreatedAt        string
+	AfterID               string
+}
+
+// OrderStore persists orders.
+type OrderStore interface {
+	// Create inserts a new order; ErrDuplicate means the idempotency key is already used.
+	Create(ctx context.Context, o Order) error
+	// Get returns an order, including archived ones but not deleted ones. A non-empty merchantID
+	// restricts the lookup to that merchant.
+	Get(ctx context.Context, id, merchantID string) (Order, error)
+	// GetMany returns the orders among ids that exist and are not deleted, archived ones included,
+	// in no particular order. A non-empty merchantID restricts the lookup to that merchant.
+	GetMany(ctx context.Context, ids []string, merchantID string) ([]Order, error)
+	// GetByIdempotencyKey returns the merchant's order created with key, even a deleted one: the key
+	// stays used, and repeating the request replays its response.
+	GetByIdempotencyKey(ctx context.Context, merchantID, key string) (Order, error)
+	// List returns a page of live (neither archived nor deleted) orders matching f.
+	List(ctx context.Context, f OrderFilter) ([]Order, error)
+	// Search returns the orders that are not deleted, archived ones included, matching every
+	// criterion of q, newest first.
+	Search(ctx context.Context, q OrderSearch) ([]Order, error)
+}
+
+// OrderSearch finds orders from what a customer can tell support. Empty fields are not matched on;
+// Metadata matches top-level metadata keys against their value as text.
+type OrderSearch struct {
+	MerchantID      string
+	ExternalOrderID string
+	TxHash          string // compared case-insensitively
+	CustomerEmail   string // exact address, matched on its blind index when emails are encrypted
+	Metadata        map[string]string
+	Tag             string // normalized tag the order carries
+	Limit           int
+}
+
+// MerchantStore persists merchant accounts. API keys are only ever handled as hashes.
+type MerchantStore interface {
+	Create(ctx context.Context, m Merchant, apiKeyHash string) error
+	Get(ctx context.Context, id string) (Merchant, error)
+	// IDByAPIKeyHash resolves a primary API key hash to its merchant.
+	IDByAPIKeyHash(ctx context.Context, hash string) (string, error)
+}
+
+// LedgerStore persists ledger entries.
+type LedgerStore interface {
+	// Append writes entries as one transaction group: those without a TransactionGroupID get a new
+	// one, and every group must net to zero on each asset and chain (carried entries aside), or
+	// nothing is written and the error wraps ErrUnbalanced.
+	Append(ctx context.Context, entries ...LedgerEntry) error
+	// Balance is the net (credits minus debits) of a merchant's bucket for asset.
+	Balance(ctx context.Context, merchantID, asset, bucket string) (int64, error)
+	// Balances returns the materialized balances of all of a merchant's buckets, kept up to date
+	// by Append.
+	Balances(ctx context.Context, merchantID string) ([]BucketBalance, error)
+}
+
+// Stores bundles the stores handed to the API layer.
+type Stores struct {
+	Orders    OrderStore
+	Merchants MerchantStore
+	Ledger    LedgerStore
+}
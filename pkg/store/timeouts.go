@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Op is a class of database work. Each class has its own query timeout, so that a key lookup on
+// every request gives up fast while an export or a reconciliation gets the time it needs.
+type Op string
+
+const (
+	OpAuth      Op = "auth"      // API key, token and session lookups in front of every request
+	OpRead      Op = "read"      // reading one resource or a few
+	OpWrite     Op = "write"     // creating or changing resources, ledger entries included
+	OpSearch    Op = "search"    // searches and filtered lists over many rows
+	OpReport    Op = "report"    // exports, usage reports and other aggregates
+	OpReconcile Op = "reconcile" // balance and reconciliation checks across the ledger
+)
+
+var defaultTimeouts = map[Op]time.Duration{
+	OpAuth:      time.Second,
+	OpRead:      3 * time.Second,
+	OpWrite:     5 * time.Second, // as long as busy_timeout, so a write waiting for the lock is not cut short
+	OpSearch:    5 * time.Second,
+	OpReport:    30 * time.Second,
+	OpReconcile: 30 * time.Second,
+}
+
+var (
+	timeoutsMu sync.RWMutex
+	timeouts   = map[Op]time.Duration{} // SetTimeout
+)
+
+// Ops lists the operation classes, for configuring their timeouts with SetTimeout.
+func Ops() []Op { return []Op{OpAuth, OpRead, OpWrite, OpSearch, OpReport, OpReconcile} }
+
+// SetTimeout replaces the default query timeout of op.
+func SetTimeout(op Op, d time.Duration) error {
+	if _, ok := defaultTimeouts[op]; !ok {
+		return fmt.Errorf("unknown operation class %q", op)
+	}
+	if d <= 0 {
+		return fmt.Errorf("timeout of %s must be positive", op)
+	}
+	timeoutsMu.Lock()
+	timeouts[op] = d
+	timeoutsMu.Unlock()
+	return nil
+}
+
+// Timeout returns how long the queries of op may run.
+func Timeout(op Op) time.Duration {
+	timeoutsMu.RLock()
+	d, ok := timeouts[op]
+	timeoutsMu.RUnlock()
+	if ok {
+		return d
+	}
+	return defaultTimeouts[op]
+}
+
+// WithTimeout returns a context for the queries of op, canceled after the timeout of op or when
+// ctx is, whichever comes first: a request whose client has gone away stops its queries too.
+func WithTimeout(ctx context.Context, op Op) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, Timeout(op))
+}
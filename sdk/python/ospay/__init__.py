@@ -0,0 +1,30 @@
+"""Typed client for the OSPay API, with webhook signature verification.
+
+    from ospay import Client
+
+    client = Client("https://pay.example.com", api_key=os.environ["OSPAY_API_KEY"])
+    order = client.create_order({"amount_minor": "1000000", "asset": "USDT", "chain": "BSC"})
+"""
+
+from ._http import NetworkError, OSPayError
+from .client import Client
+from .webhook import (
+    DEFAULT_TOLERANCE,
+    SIGNATURE_HEADER,
+    WebhookSignatureError,
+    construct_event,
+    verify_webhook,
+)
+
+__version__ = "0.1.0"
+
+__all__ = [
+    "Client",
+    "DEFAULT_TOLERANCE",
+    "NetworkError",
+    "OSPayError",
+    "SIGNATURE_HEADER",
+    "WebhookSignatureError",
+    "construct_event",
+    "verify_webhook",
+]
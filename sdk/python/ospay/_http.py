@@ -0,0 +1,164 @@
+"""Sending requests: credentials, idempotency keys, retries and errors."""
+
+from __future__ import annotations
+
+import json
+import random
+import socket
+import time
+import urllib.error
+import urllib.parse
+import urllib.request
+import uuid
+from typing import Any, Dict, List, Mapping, Optional
+
+
+class OSPayError(Exception):
+    """A non-2xx API response, decoded from its problem+json body.
+
+    code is the stable error code (see GET /v1/problems); title and detail are human-readable, and
+    fields lists the request fields at fault for validation errors.
+    """
+
+    def __init__(self, status: int, problem: Mapping[str, Any], retry_after: float = 0.0) -> None:
+        self.status = status
+        # OAuth endpoints answer with RFC 6749 error and error_description instead
+        self.code: str = problem.get("code") or problem.get("error") or str(status)
+        self.title: str = problem.get("title") or ""
+        self.detail: str = problem.get("detail") or problem.get("error_description") or ""
+        self.fields: List[Dict[str, str]] = problem.get("errors") or []
+        self.retry_after = retry_after
+        text = self.detail or self.title
+        message = f"ospay: {status} {self.code}"
+        super().__init__(f"{message}: {text}" if text else message)
+
+
+class NetworkError(Exception):
+    """A transport failure, which is always safe to retry."""
+
+
+class Transport:
+    """Talks to one OSPay server with one credential.
+
+    api_key is a merchant or platform API key, sent as X-API-Key; calls such as create_merchant
+    need none. bearer_token, an OAuth access token, is sent instead of it, and admin_key as
+    X-Admin-Key for the admin calls. A network error, 429 or 5xx response is retried max_retries
+    times, after backoff seconds doubling on each retry; timeout bounds each attempt.
+    """
+
+    def __init__(
+        self,
+        base_url: str,
+        api_key: Optional[str] = None,
+        *,
+        bearer_token: Optional[str] = None,
+        admin_key: Optional[str] = None,
+        max_retries: int = 3,
+        backoff: float = 0.25,
+        timeout: float = 30.0,
+    ) -> None:
+        self.base_url = base_url.rstrip("/")
+        self.api_key = api_key
+        self.bearer_token = bearer_token
+        self.admin_key = admin_key
+        self.max_retries = max_retries
+        self.backoff = backoff
+        self.timeout = timeout
+
+    def _request(
+        self,
+        method: str,
+        path: str,
+        *,
+        body: Any = None,
+        form: Optional[Mapping[str, Optional[str]]] = None,
+        query: Optional[Mapping[str, Any]] = None,
+        idempotency_key: Optional[str] = None,
+        idempotency_field: Optional[str] = None,
+    ) -> Any:
+        url = self.base_url + path
+        params = []
+        for name, value in (query or {}).items():
+            if value is None:
+                continue
+            if isinstance(value, Mapping):
+                # metadata={"plan": "pro"} is sent as metadata.plan=pro
+                params.extend((f"{name}.{key}", str(v)) for key, v in value.items())
+            elif isinstance(value, bool):
+                params.append((name, "true" if value else "false"))
+            else:
+                params.append((name, str(value)))
+        if params:
+            url += "?" + urllib.parse.urlencode(params)
+
+        headers = {"Accept": "application/json, application/problem+json"}
+        if method == "POST":
+            # One key per call, reused by its retries, so the server replays the first outcome
+            idempotency_key = idempotency_key or str(uuid.uuid4())
+            headers["Idempotency-Key"] = idempotency_key
+            if idempotency_field and isinstance(body, Mapping) and not body.get(idempotency_field):
+                # Bodies with a key field of their own, like order creation, use the same key
+                body = {**body, idempotency_field: idempotency_key}
+        payload = None
+        if form is not None:
+            fields = {k: v for k, v in form.items() if v is not None}
+            payload = urllib.parse.urlencode(fields).encode()
+            headers["Content-Type"] = "application/x-www-form-urlencoded"
+        elif body is not None:
+            payload = json.dumps(body).encode()
+            headers["Content-Type"] = "application/json"
+        if self.admin_key:
+            headers["X-Admin-Key"] = self.admin_key
+        if self.bearer_token:
+            headers["Authorization"] = f"Bearer {self.bearer_token}"
+        elif self.api_key:
+            headers["X-API-Key"] = self.api_key
+
+        delay = self.backoff
+        attempt = 0
+        while True:
+            try:
+                return self._send(method, url, headers, payload)
+            except (NetworkError, OSPayError) as err:
+                if attempt >= self.max_retries or not _retryable(err):
+                    raise
+                wait = delay + random.uniform(0, delay / 2)
+                if isinstance(err, OSPayError) and err.retry_after > wait:
+                    wait = err.retry_after
+                time.sleep(wait)
+                delay *= 2
+                attempt += 1
+
+    def _send(
+        self, method: str, url: str, headers: Dict[str, str], payload: Optional[bytes]
+    ) -> Any:
+        req = urllib.request.Request(url, data=payload, headers=headers, method=method)
+        try:
+            with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                text = resp.read().decode()
+        except urllib.error.HTTPError as err:
+            raw = err.read().decode(errors="replace")
+            try:
+                problem = json.loads(raw)
+            except ValueError:
+                problem = {}  # not problem+json, e.g. from a proxy
+            try:
+                retry_after = float(err.headers.get("Retry-After") or 0)
+            except ValueError:
+                retry_after = 0.0
+            if not isinstance(problem, dict):
+                problem = {}
+            raise OSPayError(err.code, problem, retry_after) from None
+        except (urllib.error.URLError, socket.timeout, ConnectionError) as err:
+            raise NetworkError(f"ospay: {err}") from err
+        if not text.strip():
+            return None
+        # Decode only the first JSON value: some endpoints append a metrics object after the body
+        value, _ = json.JSONDecoder().raw_decode(text.lstrip())
+        return value
+
+
+def _retryable(err: Exception) -> bool:
+    if isinstance(err, NetworkError):
+        return True
+    return isinstance(err, OSPayError) and (err.status == 429 or err.status >= 500)
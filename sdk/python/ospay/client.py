@@ -0,0 +1,3231 @@
+# Code generated by sdkgen from docs/swagger.json. DO NOT EDIT.
+
+"""The OSPay API client, with a method per /v1 endpoint."""
+
+from __future__ import annotations
+
+from typing import Any, Dict, List, Literal, Optional
+from urllib.parse import quote
+
+from . import models as m
+from ._http import Transport
+
+
+class Client(Transport):
+    """A typed client for the OSPay API.
+
+    The constructor takes the server URL and the credentials; see Transport for retries and
+    timeouts::
+
+        client = Client("https://pay.example.com", api_key=os.environ["OSPAY_API_KEY"])
+        order = client.create_order({"amount_minor": "1000000", "asset": "USDT", "chain": "BSC"})
+    """
+
+    def create_order(
+        self,
+        body: m.OrderCreateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderCreateResp:
+        """Create a new order
+
+        Creates a new payment order for a merchant
+        """
+        return self._request(
+            "POST",
+            "/v1/orders",
+            body=body,
+            idempotency_key=idempotency_key,
+            idempotency_field="idempotency_key",
+        )
+
+    def list_orders(
+        self,
+        *,
+        status: Optional[str] = None,
+        tag: Optional[str] = None,
+        limit: Optional[int] = None,
+        cursor: Optional[str] = None,
+    ) -> m.OrderListResp:
+        """List orders
+
+        Returns the authenticated merchant's orders, newest first, optionally filtered by status and
+        tag. Archived orders are not listed but stay readable via /orders/get.
+        """
+        return self._request(
+            "GET",
+            "/v1/orders",
+            query={"status": status, "tag": tag, "limit": limit, "cursor": cursor},
+        )
+
+    def search_orders(
+        self,
+        *,
+        external_order_id: Optional[str] = None,
+        tx_hash: Optional[str] = None,
+        customer_email: Optional[str] = None,
+        metadata: Optional[Dict[str, str]] = None,
+        tag: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+        limit: Optional[int] = None,
+    ) -> m.OrderListResp:
+        """Search orders
+
+        Finds orders from whatever a customer can tell support: external_order_id, tx_hash,
+        customer_email (the exact address), metadata values, given as metadata.<key>=<value> for
+        top-level metadata keys (compared as text, so metadata.qty=2 matches 2 and "2"), and tag.
+        Every given criterion must match; at least one is required. Archived orders are included.
+        Results are newest first, at most limit (default 50, max 200). Admins may pass merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/orders/search",
+            query={
+                "external_order_id": external_order_id,
+                "tx_hash": tx_hash,
+                "customer_email": customer_email,
+                "metadata": metadata,
+                "tag": tag,
+                "merchant_id": merchant_id,
+                "limit": limit,
+            },
+        )
+
+    def batch_get_orders(
+        self,
+        body: m.BatchGetOrdersReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.BatchGetOrdersResp:
+        """Get orders by ID
+
+        Returns up to 100 of the authenticated merchant's orders by ID in one request, for
+        integrations syncing order status. Orders come in the order of order_ids, archived ones
+        included; IDs given twice are returned once, and IDs that match no order of the merchant are
+        listed in not_found.
+        """
+        return self._request(
+            "POST",
+            "/v1/orders/batch-get",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def get_order(self, id: str) -> m.OrderGetResp:
+        """Get order by ID
+
+        Returns order details for a given order ID. The response carries an ETag computed from the
+        response body, so it changes whenever any field of the order does; pollers sending it back
+        in If-None-Match get 304 Not Modified until then.
+        """
+        return self._request("GET", f"/v1/orders/{quote(id, safe='')}")
+
+    def extend_order(
+        self,
+        id: str,
+        body: Optional[m.OrderExtendReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderExtendResp:
+        """Extend a pending order
+
+        Pushes out expires_at of a PENDING order by minutes (default 30), counted from now if that
+        is later than the current expiry, so a checkout stays open while the customer is still
+        paying. An order cannot be extended past 24 hours after it was created.
+        """
+        return self._request(
+            "POST",
+            f"/v1/orders/{quote(id, safe='')}/extend",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def refund(
+        self,
+        id: str,
+        body: m.RefundReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.RefundResp:
+        """Refund an order
+
+        Records a full or partial refund of a paid order. Multiple partial refunds are allowed up to
+        the remaining refundable amount; each needs its own refund_idempotency_key. If the merchant
+        refunded on-chain, refundtxhash and amount_minor must be provided and the transfer to the
+        customer wallet is verified first.
+        """
+        return self._request(
+            "POST",
+            f"/v1/orders/{quote(id, safe='')}/refunds",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def list_refunds(self, id: str) -> List[m.RefundRecord]:
+        """List refunds for an order
+
+        Returns every refund recorded against an order, oldest first
+        """
+        return self._request("GET", f"/v1/orders/{quote(id, safe='')}/refunds")
+
+    def create_order_notes(
+        self,
+        id: str,
+        body: Optional[m.OrderNoteReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderNote:
+        """Add or list order notes
+
+        POST adds an internal note (body, at most 4000 characters) to an order, recording the
+        credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible
+        to the merchant's credentials and operators; they are not included in order responses or
+        webhooks.
+        """
+        return self._request(
+            "POST",
+            f"/v1/orders/{quote(id, safe='')}/notes",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def list_order_notes(self, id: str) -> List[m.OrderNote]:
+        """Add or list order notes
+
+        POST adds an internal note (body, at most 4000 characters) to an order, recording the
+        credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible
+        to the merchant's credentials and operators; they are not included in order responses or
+        webhooks.
+        """
+        return self._request("GET", f"/v1/orders/{quote(id, safe='')}/notes")
+
+    def update_order_tags(
+        self,
+        id: str,
+        body: Optional[m.OrderTagsReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderTagsResp:
+        """Add or list order tags
+
+        POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64
+        characters, trimmed and compared in lower case; tags the order already has are ignored and
+        an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags
+        stay on the order when it is archived.
+        """
+        return self._request(
+            "POST",
+            f"/v1/orders/{quote(id, safe='')}/tags",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def get_order_tags(self, id: str) -> m.OrderTagsResp:
+        """Add or list order tags
+
+        POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64
+        characters, trimmed and compared in lower case; tags the order already has are ignored and
+        an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags
+        stay on the order when it is archived.
+        """
+        return self._request("GET", f"/v1/orders/{quote(id, safe='')}/tags")
+
+    def remove_order_tags(
+        self,
+        id: str,
+        body: m.OrderTagsReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderTagsResp:
+        """Remove order tags
+
+        Removes tags from an order; tags it does not carry are ignored. Returns the tags left.
+        """
+        return self._request(
+            "POST",
+            f"/v1/orders/{quote(id, safe='')}/tags/remove",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def order_timeline(self, id: str) -> List[m.TimelineEntry]:
+        """Get an order's timeline
+
+        Returns what happened to an order, oldest first: its creation, the webhook events raised for
+        it and its refunds and disputes (whether or not the merchant subscribes to them), every
+        transaction hash reported as its payment with the outcome of its verification
+        (failure_reason wrong_amount, wrong_token, wrong_recipient, no_transfer, tx_not_found,
+        order_expired, already_paid or verification_error), and the team's notes. Events pruned by
+        OUTBOX_RETENTION_DAYS no longer appear.
+        """
+        return self._request("GET", f"/v1/orders/{quote(id, safe='')}/timeline")
+
+    def delete_order(
+        self,
+        id: str,
+        body: Optional[m.SoftDeleteReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> Dict[str, bool]:
+        """Delete an order
+
+        Soft-deletes an order that never received a payment, e.g. one created by mistake: it is no
+        longer returned by any order read, list or search, a payment reported for it is refused as
+        for an unknown order, and it does not expire or count toward limits. Orders with a payment
+        stay on the books, since their ledger entries cannot be undone; refund them instead (409
+        order_has_payment). So do MISPAID orders and orders with a payment still being verified. The
+        idempotency key stays used. Only an administrator can restore a deleted order, with POST
+        /admin/orders/{id}/restore.
+        """
+        return self._request(
+            "POST",
+            f"/v1/orders/{quote(id, safe='')}/delete",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def approve_refund(self, id: str, *, idempotency_key: Optional[str] = None) -> m.RefundResp:
+        """Approve a requested refund
+
+        Executes a REQUESTED refund. Must be called with the admin key or a merchant credential
+        holding refunds:approve of a different origin than the one that requested it: the merchant's
+        primary key and the scoped API keys it created count as one, while each organization
+        member's key and each OAuth grant is its own.
+        """
+        return self._request(
+            "POST",
+            f"/v1/refunds/{quote(id, safe='')}/approve",
+            idempotency_key=idempotency_key,
+        )
+
+    def reject_refund(self, id: str, *, idempotency_key: Optional[str] = None) -> m.RefundResp:
+        """Reject a requested refund
+
+        Rejects a REQUESTED refund, releasing its reserved amount. Same credential rules as
+        approval.
+        """
+        return self._request(
+            "POST",
+            f"/v1/refunds/{quote(id, safe='')}/reject",
+            idempotency_key=idempotency_key,
+        )
+
+    def bulk_refund(
+        self,
+        body: m.BulkRefundReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.RefundJob:
+        """Refund many orders
+
+        Queues a job refunding up to 1000 orders, each by amount_minor or, when omitted, its full
+        remaining balance, and returns it with 202. The job runs in the background: each item is
+        checked like a single refund, recorded (REQUESTED when the merchant requires approval) and
+        its transfer to the customer wallet queued for the hot wallet. Items that cannot be refunded
+        fail on their own with an error; follow the job with GET /refunds/bulk/get. A
+        refund_idempotency_key already used on the order refunds it only once.
+        """
+        return self._request("POST", "/v1/refunds/bulk", body=body, idempotency_key=idempotency_key)
+
+    def get_bulk_refund(self, id: str) -> m.RefundJob:
+        """Get a bulk refund job
+
+        Returns a bulk refund job with per-item outcomes: the refund recorded for each order, how
+        far its on-chain transfer got (execution_status, refund_tx_hash), or why the item failed.
+        """
+        return self._request("GET", f"/v1/refunds/bulk/{quote(id, safe='')}")
+
+    def list_overpayments(
+        self,
+        *,
+        status: Optional[str] = None,
+        order_id: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.OverpaymentRecord]:
+        """List overpayments
+
+        Returns the most recent overpayments (newest first), optionally filtered by status
+        (CONFIRMING, REVIEW, OPEN, REFUND_QUEUED, REFUND_SENT, REFUNDED, REFUND_FAILED) or order_id.
+        An overpayment is a further transfer of the order's asset to the deposit address reported
+        for an order that is already PAID, SETTLED or (partially) refunded, e.g. a customer paying
+        twice. It is credited to the refundable balance rather than the merchant's once its block is
+        final (CONFIRMING until then), and sent back to the sender with POST
+        /overpayments/{id}/refund. Admins see every merchant's, or one with merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/overpayments",
+            query={"status": status, "order_id": order_id, "merchant_id": merchant_id},
+        )
+
+    def refund_overpayment(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OverpaymentRecord:
+        """Refund an overpayment
+
+        Sends an OPEN overpayment back to the address it came from, from the hot wallet; one in
+        REVIEW, whose sender was flagged by screening, only on the admin route. The amount leaves
+        the refundable balance at once and the overpayment is REFUND_QUEUED; it is REFUNDED, with
+        refund_tx_hash, once the transfer is mined (overpayment.refunded). A transfer that fails
+        leaves it REFUND_FAILED with last_error (overpayment.refund_failed); refunding it again
+        sends it again. An overpayment whose sender was erased (see POST /privacy/erasure) cannot be
+        refunded.
+        """
+        return self._request(
+            "POST",
+            f"/v1/overpayments/{quote(id, safe='')}/refund",
+            idempotency_key=idempotency_key,
+        )
+
+    def reconciliation(self, *, merchant_id: str, asset: str) -> Dict[str, Any]:
+        """Get reconciliation data
+
+        Returns balance and settlement data for a merchant and asset
+        """
+        return self._request(
+            "GET",
+            "/v1/reconciliation",
+            query={"merchant_id": merchant_id, "asset": asset},
+        )
+
+    def list_ledger_transactions(
+        self,
+        *,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        event_type: Optional[str] = None,
+        order_id: Optional[str] = None,
+        reference_id: Optional[str] = None,
+        limit: Optional[int] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.LedgerTransaction]:
+        """List ledger transactions
+
+        Lists the merchant's ledger entries grouped into transactions, newest first: each
+        transaction holds the legs of one double entry (a payment with its application fee leg, a
+        refund, a settlement of a batch, ...), which net to zero on each asset and chain. from and
+        to (RFC 3339) bound created_at to [from, to); event_type, order_id and reference_id (the
+        refund, batch, conversion, ... that booked it) narrow the list, limit (default 100, at most
+        500) bounds the transactions returned. Entries moved out by the retention job are not
+        listed. Admins pass merchant_id, or leave it out for every merchant.
+        """
+        return self._request(
+            "GET",
+            "/v1/ledger/transactions",
+            query={
+                "from": from_,
+                "to": to,
+                "event_type": event_type,
+                "order_id": order_id,
+                "reference_id": reference_id,
+                "limit": limit,
+                "merchant_id": merchant_id,
+            },
+        )
+
+    def get_ledger_transaction(self, id: str) -> m.LedgerTransaction:
+        """Get a ledger transaction
+
+        Returns the legs of one ledger transaction group, as linked by the transaction_group_id of
+        ledger entries and exports.
+        """
+        return self._request("GET", f"/v1/ledger/transactions/{quote(id, safe='')}")
+
+    def list_settlements(
+        self,
+        *,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        asset: Optional[str] = None,
+        format: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.SettlementRecord]:
+        """List settlement batches
+
+        Lists the merchant's settlement batches, newest first, each itemized into the gross volume
+        of its orders, the application fees withheld, the completed refunds and lost disputes
+        netted, what was clawed back against a negative balance or withheld for balance holds, and
+        the net payout (total_amount_minor, what the payout sends). Releasing a hold settles what it
+        withheld in a batch of its own (hold_released_minor). Batches settled before itemization was
+        stored only carry the net. from and to (RFC 3339) bound settled_at to [from, to), asset
+        narrows the list. With format=csv the list is returned as a statement, one line per batch,
+        for accounting. Admins pass merchant_id, or leave it out for every merchant.
+        """
+        return self._request(
+            "GET",
+            "/v1/settlements",
+            query={
+                "from": from_,
+                "to": to,
+                "asset": asset,
+                "format": format,
+                "merchant_id": merchant_id,
+            },
+        )
+
+    def list_negative_balances(
+        self,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.NegativeBalance]:
+        """List negative balances
+
+        Lists the times the merchant's balance of an asset on a chain went below zero, newest first:
+        OPEN while it still owes amount_minor, RECOVERED once new payments or clawbacks from
+        settlements brought it back. A balance goes negative when more is taken from it than it
+        holds, e.g. by the refund of an order that was already settled. The tracker checks the
+        balances every NEGATIVE_BALANCE_CHECK_INTERVAL and raises balance.negative and
+        balance.recovered. status narrows the list. Admins pass merchant_id, or leave it out for
+        every merchant.
+        """
+        return self._request(
+            "GET",
+            "/v1/negative-balances",
+            query={"status": status, "merchant_id": merchant_id},
+        )
+
+    def list_balance_holds(
+        self,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.BalanceHold]:
+        """Place or list balance holds
+
+        POST (admin key or platform API key) holds amount_minor of a merchant's unsettled balance of
+        asset on chain, for reason, until it is released or expires_at passes. The amount moves from
+        the merchant to the balance_hold ledger bucket (counted as held in the balances) and
+        settlements keep back what the merchant balance then lacks to pay out their orders, so held
+        funds are not paid out; withheld_minor is how much they kept back. The hold may not exceed
+        the unsettled balance on the chain. Platforms can only hold the balances of their connected
+        merchants. GET lists holds, newest first, filtered by status and (admins, platforms)
+        merchant_id; merchants see the holds on their own balance.
+        """
+        return self._request(
+            "GET",
+            "/v1/holds",
+            query={"status": status, "merchant_id": merchant_id},
+        )
+
+    def list_attestations(
+        self,
+        *,
+        fully_backed: Optional[bool] = None,
+        limit: Optional[int] = None,
+    ) -> List[m.ReserveAttestation]:
+        """List reserve attestations
+
+        Returns the signed reserve attestations, newest first. Each carries the report that was
+        signed, per chain and asset what merchants are owed next to what the custody wallets hold,
+        with its signature and signer; see POST /admin/attestations/generate. fully_backed narrows
+        the list to attestations where every asset was (true) or was not (false) fully backed.
+        """
+        return self._request(
+            "GET",
+            "/v1/attestations",
+            query={"fully_backed": fully_backed, "limit": limit},
+        )
+
+    def get_attestation(self, id: str, *, format: Optional[str] = None) -> m.ReserveAttestation:
+        """Get a reserve attestation
+
+        Returns a signed reserve attestation. With format=csv the report is returned as CSV instead,
+        one line per chain and asset, for spreadsheets; the signature, which covers the JSON report,
+        is in the X-Attestation-Signature and X-Attestation-Signer headers.
+        """
+        return self._request(
+            "GET",
+            f"/v1/attestations/{quote(id, safe='')}",
+            query={"format": format},
+        )
+
+    def list_payouts(
+        self,
+        *,
+        status: Optional[str] = None,
+        batch_id: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.PayoutRecord]:
+        """List on-chain payouts
+
+        Returns the most recent payouts of settlement batches (newest first), optionally filtered by
+        status (QUEUED, SENT, PROPOSED, EXECUTED, FAILED) or batch_id. A queued payout that fails to
+        be sent or proposed is retried as the payout retry policy says (see /admin/retry-policies);
+        attempts counts the failures, and dead_lettered_at is set on a payout held after running out
+        of them. Payouts exist for merchants with a payout_mode: hot_wallet payouts are sent by
+        OSPay, safe payouts are proposed to the merchant's Safe and stay PROPOSED until its owners
+        execute them. Admins see every merchant's, or one with merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/payouts",
+            query={"status": status, "batch_id": batch_id, "merchant_id": merchant_id},
+        )
+
+    def list_conversions(
+        self,
+        *,
+        status: Optional[str] = None,
+        batch_id: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.ConversionRecord]:
+        """List settlement conversions
+
+        Returns the most recent conversions (newest first), optionally filtered by status (QUEUED,
+        SUBMITTED, COMPLETED, FAILED) or batch_id. A conversion is queued at settlement when the
+        merchant's settlement_asset or settlement_chain differs from what was received;
+        quoted_out_minor and min_out_minor are the provider's quote, min_out_minor being the floor
+        set by max_slippage_bps. Admins see every merchant's, or one with merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/conversions",
+            query={"status": status, "batch_id": batch_id, "merchant_id": merchant_id},
+        )
+
+    def offramp_kyc(self, *, merchant_id: Optional[str] = None) -> m.KycResp:
+        """Show off-ramp KYC status
+
+        Returns the merchant's KYC status at the off-ramp partner (NOT_STARTED, PENDING, APPROVED or
+        REJECTED), fetched fresh from the partner. KYC itself happens with the partner; an
+        administrator links the merchant to the partner's customer and bank account IDs in the
+        merchant settings (offramp_customer_id, offramp_bank_account_id). Admins pass merchant_id.
+        """
+        return self._request("GET", "/v1/offramp/kyc", query={"merchant_id": merchant_id})
+
+    def list_fiat_payouts(
+        self,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.FiatPayoutRecord]:
+        """Request or list fiat payouts
+
+        POST converts part of the merchant's settled balance of asset into a bank payout through the
+        off-ramp partner; it needs the primary API key and approved KYC. The amount is reserved at
+        once (ledger offramp_pending); the hot wallet then sends the crypto on chain to the
+        partner's deposit address, and the payout becomes PAID once the partner has paid the bank,
+        with the fiat amount and rate it applied. The settled balance is settlement batches and
+        conversions into the asset less what was paid out or converted since. GET lists fiat
+        payouts, newest first.
+        """
+        return self._request(
+            "GET",
+            "/v1/offramp/payouts",
+            query={"status": status, "merchant_id": merchant_id},
+        )
+
+    def create_fiat_payouts(
+        self,
+        body: Optional[m.FiatPayoutReq] = None,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> m.FiatPayoutRecord:
+        """Request or list fiat payouts
+
+        POST converts part of the merchant's settled balance of asset into a bank payout through the
+        off-ramp partner; it needs the primary API key and approved KYC. The amount is reserved at
+        once (ledger offramp_pending); the hot wallet then sends the crypto on chain to the
+        partner's deposit address, and the payout becomes PAID once the partner has paid the bank,
+        with the fiat amount and rate it applied. The settled balance is settlement batches and
+        conversions into the asset less what was paid out or converted since. GET lists fiat
+        payouts, newest first.
+        """
+        return self._request(
+            "POST",
+            "/v1/offramp/payouts",
+            query={"status": status, "merchant_id": merchant_id},
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def timeseries(
+        self,
+        *,
+        metric: str,
+        interval: Optional[str] = None,
+        asset: Optional[str] = None,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> m.TimeseriesResp:
+        """Get a metric as a time series
+
+        Buckets a metric by hour or day over [from, to): orders_created (count), paid_volume (amount
+        paid, by paid_at; needs asset), discounts (coupon discounts given on those payments; needs
+        asset), refunds (amount refunded by completed refunds; needs asset) and conversion_rate
+        (paid share of the orders created in the bucket). Buckets start at hour or midnight
+        boundaries in the merchant's timezone and every bucket in the range is returned,
+        zero-filled. from defaults to 24 hours (hour) or 30 days (day) before to, which defaults to
+        now; a series has at most 744 hourly or 366 daily points. Admins pass merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/stats/timeseries",
+            query={
+                "metric": metric,
+                "interval": interval,
+                "asset": asset,
+                "from": from_,
+                "to": to,
+                "merchant_id": merchant_id,
+            },
+        )
+
+    def rate(self, *, base: str, quote: str) -> m.RateResp:
+        """Get an exchange rate
+
+        Returns the current rate of base in quote (e.g. base=USDT&quote=USD) from the configured
+        rate provider. Every rate served is recorded under its quote_id, which prices an order in
+        fiat (rate_quote_id) and stays listed in /rates/history. With RATE_PROVIDER=chainlink rates
+        are read from Chainlink price feed contracts over the chains' RPC endpoints; a feed whose
+        latest answer is older than its staleness limit is refused with 503 stale_rate rather than
+        served.
+        """
+        return self._request("GET", "/v1/rates", query={"base": base, "quote": quote})
+
+    def rate_history(
+        self,
+        *,
+        base: Optional[str] = None,
+        quote: Optional[str] = None,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        limit: Optional[int] = None,
+        merchant_id: Optional[str] = None,
+    ) -> m.RateHistoryResp:
+        """List rates served
+
+        Lists the rates /rates served the merchant, newest first, so the conversion behind a
+        fiat-priced order can be checked after the fact: base and quote narrow it to a pair, from
+        and to (RFC 3339) bound when they were served to [from, to), and limit caps the list
+        (default 100, max 1000). A quote is kept as served, with the provider, its source and when
+        the provider last updated it. Admins see every merchant's quotes, or one merchant's with
+        merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/rates/history",
+            query={
+                "base": base,
+                "quote": quote,
+                "from": from_,
+                "to": to,
+                "limit": limit,
+                "merchant_id": merchant_id,
+            },
+        )
+
+    def payout_estimate(
+        self,
+        *,
+        chain: Optional[str] = None,
+        transfers: Optional[int] = None,
+    ) -> m.PayoutEstimateResp:
+        """Estimate payout gas costs
+
+        Returns each chain's current gas price from its RPC endpoint and what the next payouts would
+        cost: the fee of one token transfer, of sending them one by one, and of a single multi-send
+        batch. transfers defaults to the number of settlement payouts currently due on the chain
+        (merchant/asset pairs with paid, unsettled orders). A chain whose RPC endpoint cannot be
+        reached is listed with an error; asking for that chain alone returns 502.
+        """
+        return self._request(
+            "GET",
+            "/v1/payouts/estimate",
+            query={"chain": chain, "transfers": transfers},
+        )
+
+    def payment_detected(
+        self,
+        body: m.PaymentDetectedReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PaymentDetectedResp:
+        """Detect payment event
+
+        Notify the system of an on-chain payment for an order. The payment is verified in the
+        background and the report answered with 202; when too many payments are already waiting for
+        verification, the report is refused with 503 and Retry-After, and should be sent again after
+        that many seconds.
+        """
+        return self._request(
+            "POST",
+            "/v1/events/payment-detected",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def list_coupons(self) -> List[m.Coupon]:
+        """Create or list coupons
+
+        POST creates a discount code customers can redeem at order creation (coupon_code): type
+        percent takes percent_off (1-100) percent off the amount, type fixed takes amount_off_minor
+        off orders in asset. max_redemptions limits how many orders may use it and expires_at when
+        it stops being accepted. Codes are unique per merchant, ignoring case. GET lists the
+        merchant's coupons with their redemption counts.
+        """
+        return self._request("GET", "/v1/coupons")
+
+    def create_coupons(
+        self,
+        body: Optional[m.CouponCreateReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.Coupon:
+        """Create or list coupons
+
+        POST creates a discount code customers can redeem at order creation (coupon_code): type
+        percent takes percent_off (1-100) percent off the amount, type fixed takes amount_off_minor
+        off orders in asset. max_redemptions limits how many orders may use it and expires_at when
+        it stops being accepted. Codes are unique per merchant, ignoring case. GET lists the
+        merchant's coupons with their redemption counts.
+        """
+        return self._request("POST", "/v1/coupons", body=body, idempotency_key=idempotency_key)
+
+    def get_coupon(self, id: str) -> m.Coupon:
+        """Get a coupon
+
+        Returns one of the merchant's coupons with its redemption count.
+        """
+        return self._request("GET", f"/v1/coupons/{quote(id, safe='')}")
+
+    def update_coupon(
+        self,
+        id: str,
+        body: m.CouponUpdateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.Coupon:
+        """Update a coupon
+
+        Changes a coupon's redemption limit (0 removes it), expiry ("" removes it) or whether it is
+        active; inactive coupons are refused at order creation. The code and discount cannot change,
+        as orders keep the discount they were given.
+        """
+        return self._request(
+            "POST",
+            f"/v1/coupons/{quote(id, safe='')}",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def delete_coupon(self, id: str, *, idempotency_key: Optional[str] = None) -> Dict[str, bool]:
+        """Delete a coupon
+
+        Deletes a coupon so its code can no longer be redeemed (and may be reused). Orders that
+        redeemed it keep their coupon_code and discount.
+        """
+        return self._request(
+            "POST",
+            f"/v1/coupons/{quote(id, safe='')}/delete",
+            idempotency_key=idempotency_key,
+        )
+
+    def list_watched_addresses(self) -> List[m.WatchedAddress]:
+        """Watch an address, or list watched addresses
+
+        POST registers an address for the watcher to report incoming token transfers to,
+        independently of orders, e.g. one handed out on a legacy invoice. Transfers of the chain's
+        known tokens (USDT, USDC) are reported once their block has the chain's finality depth, from
+        the next scan on, with an address.transfer_received webhook each; GET
+        /watch/addresses/transfers lists them. An address is watched once per merchant and chain; a
+        merchant can watch up to 1000. GET lists the merchant's watched addresses, oldest first.
+        """
+        return self._request("GET", "/v1/watch/addresses")
+
+    def create_watched_addresses(
+        self,
+        body: Optional[m.WatchAddressReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.WatchedAddress:
+        """Watch an address, or list watched addresses
+
+        POST registers an address for the watcher to report incoming token transfers to,
+        independently of orders, e.g. one handed out on a legacy invoice. Transfers of the chain's
+        known tokens (USDT, USDC) are reported once their block has the chain's finality depth, from
+        the next scan on, with an address.transfer_received webhook each; GET
+        /watch/addresses/transfers lists them. An address is watched once per merchant and chain; a
+        merchant can watch up to 1000. GET lists the merchant's watched addresses, oldest first.
+        """
+        return self._request(
+            "POST",
+            "/v1/watch/addresses",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def delete_watched_address(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> Dict[str, bool]:
+        """Stop watching an address
+
+        Stops reporting transfers to a watched address. The transfers already reported stay listed.
+        """
+        return self._request(
+            "POST",
+            f"/v1/watch/addresses/{quote(id, safe='')}/delete",
+            idempotency_key=idempotency_key,
+        )
+
+    def address_transfers(self, id: str, *, limit: Optional[int] = None) -> List[m.AddressTransfer]:
+        """List transfers to a watched address
+
+        Returns the token transfers the watcher found to a watched address, newest first (by block,
+        then log index), including those found before the address was removed.
+        """
+        return self._request(
+            "GET",
+            f"/v1/watch/addresses/{quote(id, safe='')}/transfers",
+            query={"limit": limit},
+        )
+
+    def list_payment_intents(
+        self,
+        *,
+        status: Optional[str] = None,
+        limit: Optional[int] = None,
+    ) -> List[m.PaymentIntent]:
+        """Create or list payment intents
+
+        POST records that a customer committed to pay amount_minor of asset on chain, before any
+        order exists: the amount can still be adjusted (POST /payment-intents/update) until the
+        intent is captured, which creates the order the customer pays, or voided. The merchant
+        wallet must be an address on chain, and coupon_code must be redeemable now; it is redeemed
+        on capture. GET lists the merchant's intents, newest first, optionally filtered by status
+        (REQUIRES_CAPTURE, CAPTURED, VOIDED), at most limit (default 50, max 200).
+        """
+        return self._request("GET", "/v1/payment-intents", query={"status": status, "limit": limit})
+
+    def create_payment_intents(
+        self,
+        body: Optional[m.PaymentIntentCreateReq] = None,
+        *,
+        status: Optional[str] = None,
+        limit: Optional[int] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PaymentIntent:
+        """Create or list payment intents
+
+        POST records that a customer committed to pay amount_minor of asset on chain, before any
+        order exists: the amount can still be adjusted (POST /payment-intents/update) until the
+        intent is captured, which creates the order the customer pays, or voided. The merchant
+        wallet must be an address on chain, and coupon_code must be redeemable now; it is redeemed
+        on capture. GET lists the merchant's intents, newest first, optionally filtered by status
+        (REQUIRES_CAPTURE, CAPTURED, VOIDED), at most limit (default 50, max 200).
+        """
+        return self._request(
+            "POST",
+            "/v1/payment-intents",
+            query={"status": status, "limit": limit},
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def get_payment_intent(self, id: str) -> m.PaymentIntent:
+        """Get a payment intent
+
+        Returns one of the merchant's payment intents; once captured, order_id is the order the
+        customer pays.
+        """
+        return self._request("GET", f"/v1/payment-intents/{quote(id, safe='')}")
+
+    def update_payment_intent(
+        self,
+        id: str,
+        body: m.PaymentIntentUpdateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PaymentIntent:
+        """Adjust a payment intent
+
+        Changes the amount, metadata or external_order_id of an intent that is not yet captured or
+        voided, e.g. after the customer changed the cart or shipping was added. The new amount_minor
+        is what capture creates the order for.
+        """
+        return self._request(
+            "POST",
+            f"/v1/payment-intents/{quote(id, safe='')}",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def capture_payment_intent(
+        self,
+        id: str,
+        body: Optional[m.PaymentIntentCaptureReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PaymentIntentCaptureResp:
+        """Capture a payment intent
+
+        Turns an intent into a PENDING order for amount_minor (default: the intent's amount; never
+        more), carrying the intent's metadata, external_order_id and coupon, and returns both. From
+        there the order is paid, credited to the ledger and settled like any other. Order limits,
+        the coupon and the wallets are checked as at order creation. Capturing again after an
+        interrupted capture finishes it with the same order.
+        """
+        return self._request(
+            "POST",
+            f"/v1/payment-intents/{quote(id, safe='')}/capture",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def void_payment_intent(
+        self,
+        id: str,
+        body: Optional[m.PaymentIntentVoidReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PaymentIntent:
+        """Void a payment intent
+
+        Cancels an intent that is not yet captured, e.g. when the customer abandons the checkout. An
+        order left by an interrupted capture is expired, so it can no longer be paid.
+        """
+        return self._request(
+            "POST",
+            f"/v1/payment-intents/{quote(id, safe='')}/void",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def list_customers(
+        self,
+        *,
+        wallet_address: Optional[str] = None,
+        limit: Optional[int] = None,
+        cursor: Optional[str] = None,
+    ) -> m.CustomerListResp:
+        """List customers
+
+        Returns the merchant's returning-customer profiles, most recently paying first. Profiles are
+        created from verified payments whose payer wallet is known; wallet_address looks one up.
+        """
+        return self._request(
+            "GET",
+            "/v1/customers",
+            query={"wallet_address": wallet_address, "limit": limit, "cursor": cursor},
+        )
+
+    def get_customer(self, id: str) -> m.CustomerDetailResp:
+        """Get a customer with payment history
+
+        Returns a customer profile, the total paid per asset and up to 200 of their credited
+        payments, newest first. Archived orders are not included.
+        """
+        return self._request("GET", f"/v1/customers/{quote(id, safe='')}")
+
+    def list_disputes(
+        self,
+        *,
+        order_id: Optional[str] = None,
+        status: Optional[str] = None,
+    ) -> List[m.DisputeRecord]:
+        """Open or list disputes
+
+        POST (admin only) opens a dispute against a PAID, PARTIALLY_REFUNDED or SETTLED order and
+        freezes the disputed amount of the merchant balance. GET lists disputes with their evidence,
+        optionally filtered by order_id and status; merchants only see their own.
+        """
+        return self._request("GET", "/v1/disputes", query={"order_id": order_id, "status": status})
+
+    def dispute_evidence(
+        self,
+        id: str,
+        body: m.DisputeEvidenceReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.DisputeEvidence:
+        """Add dispute evidence
+
+        Attaches an evidence note to an open dispute. Available to the merchant and to admins.
+        """
+        return self._request(
+            "POST",
+            f"/v1/disputes/{quote(id, safe='')}/evidence",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def privacy_export(
+        self,
+        *,
+        customer_wallet_address: Optional[str] = None,
+        customer_email: Optional[str] = None,
+    ) -> m.PrivacyExportResp:
+        """Export a customer's data
+
+        Returns every order (with refunds) tied to the given customer wallet and/or email, the
+        overpayments of those orders or sent from the customer's wallets, and the transfers from
+        those wallets to watched addresses, within the authenticated merchant. Admins see all
+        merchants. The export is recorded in the audit log.
+        """
+        return self._request(
+            "GET",
+            "/v1/privacy/export",
+            query={
+                "customer_wallet_address": customer_wallet_address,
+                "customer_email": customer_email,
+            },
+        )
+
+    def privacy_erasure(
+        self,
+        body: m.PrivacySubject,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PrivacyErasureResp:
+        """Erase a customer's data
+
+        Pseudonymizes the customer's wallet address and removes email and metadata from every
+        matching order within the authenticated merchant (admins: all merchants), pseudonymizes the
+        sender of the overpayments of those orders or sent from the customer's wallets and of the
+        transfers from those wallets to watched addresses, and deletes the matching customer
+        profiles. Amounts, statuses, tx hashes and ledger entries are kept. An erased overpayment
+        can no longer be refunded, and the erasure fails with 409 while one of them is being
+        refunded. The erasure is recorded in the audit log.
+        """
+        return self._request(
+            "POST",
+            "/v1/privacy/erasure",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def create_merchant(
+        self,
+        body: m.MerchantCreateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantCreateResp:
+        """Create a new merchant
+
+        Creates a new merchant and returns the merchant ID and API key. merchant_wallet_address must
+        be an EVM (0x..., EIP-55 checksummed when in mixed case), Tron, Solana or Bitcoin address;
+        EVM addresses are stored and returned checksummed. Orders can only be created on chains the
+        wallet's address format belongs to. An ENS name (e.g. shop.eth) is resolved through
+        ETH_RPC_URL; the response carries the resolved address and wallet_ens_name, and the name is
+        re-resolved periodically, moving the wallet when the name is pointed elsewhere
+        (merchant.wallet_changed). With MERCHANT_APPROVAL_REQUIRED on, the merchant is created
+        PENDING_APPROVAL and its API key is refused with 403 merchant_pending_approval, apart from
+        /merchants/status and the webhook settings, until an administrator approves it.
+        """
+        return self._request("POST", "/v1/merchants", body=body, idempotency_key=idempotency_key)
+
+    def merchant_balances(self, *, merchant_id: Optional[str] = None) -> m.BalancesResp:
+        """Get merchant balances
+
+        Returns the merchant's balance per asset and chain, read from the materialized ledger
+        balances: available (settled funds, the settlement bucket), pending (the merchant's share of
+        PAID and PARTIALLY_REFUNDED orders not yet settled, the merchant bucket), held (frozen by
+        open disputes or balance holds, or reserved for fiat payouts) and refundable (overpayments
+        of already paid orders, to send back to customers with POST /overpayments/{id}/refund; not
+        the merchant's funds). Admins pass merchant_id.
+        """
+        return self._request("GET", "/v1/merchants/me/balances", query={"merchant_id": merchant_id})
+
+    def get_merchant_settings(self, *, merchant_id: Optional[str] = None) -> m.MerchantSettings:
+        """Get or update merchant settings
+
+        refund_approval_required makes every refund wait for approval by a second credential.
+        Merchants may turn it on with their primary API key; turning it off, and changing velocity
+        limits, requires the admin key (use /admin/merchants/settings?merchant_id=).
+        late_payment_review holds payments that arrive after an order expired (within the grace
+        window) for review instead of crediting them. payout_mode makes settlements pay out on-chain
+        to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes
+        them as transactions of the multisig at payout_safe_address for its owners to execute; ""
+        keeps settlements ledger only. Payout settings require the admin key. settlement_asset and
+        settlement_chain convert settled funds received in another asset or on another chain (e.g.
+        USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default
+        50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its
+        customer and bank account at the off-ramp partner for fiat payouts (admin key only);
+        kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g.
+        Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles
+        orders by local calendar day: the orders paid on a day settle after local midnight plus the
+        settlement delay. accepted_assets (e.g. [{"asset":"USDT","chain":"BSC"}]) limits new orders
+        to those asset and chain pairs, each a token known on its chain, and orders in any other are
+        rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of
+        each settlement withheld while the merchant's balance on the chain is negative, e.g. after
+        refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the
+        deficit to be netted by new payments only); admin key only.
+        """
+        return self._request("GET", "/v1/merchants/settings", query={"merchant_id": merchant_id})
+
+    def merchant_status(self) -> m.MerchantRecord:
+        """Get the merchant's approval status
+
+        Returns the merchant and its status: ACTIVE, PENDING_APPROVAL while compliance mode
+        (MERCHANT_APPROVAL_REQUIRED) holds it for an administrator, or REJECTED with the reason. A
+        pending merchant's API key only works here and for the webhook settings; the decision is
+        also sent as a merchant.approved or merchant.rejected webhook.
+        """
+        return self._request("GET", "/v1/merchants/status")
+
+    def merchant_wallet(self) -> m.WalletStatus:
+        """Get the merchant wallet and its verification
+
+        Returns the payout wallet, its ENS name if it was given as one, and whether the merchant has
+        proved control of it (POST /merchants/wallet/challenge, then /merchants/wallet/verify).
+        While proof_required is set, payouts to an unverified wallet wait in QUEUED.
+        """
+        return self._request("GET", "/v1/merchants/wallet")
+
+    def wallet_challenge(self, *, idempotency_key: Optional[str] = None) -> m.WalletChallenge:
+        """Issue a wallet ownership challenge
+
+        Issues a single-use challenge for the merchant's current payout wallet, valid for 15
+        minutes. Sign message with personal_sign (EIP-191), or typed_data with eth_signTypedData_v4
+        (EIP-712), from the wallet and send the signature to /merchants/wallet/verify. Only EVM
+        wallets can be verified. Primary API key only.
+        """
+        return self._request(
+            "POST",
+            "/v1/merchants/wallet/challenge",
+            idempotency_key=idempotency_key,
+        )
+
+    def wallet_verify(
+        self,
+        body: m.WalletVerifyReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.WalletStatus:
+        """Prove control of the merchant wallet
+
+        Checks a signature of a challenge from /merchants/wallet/challenge, as an EIP-191 personal
+        message or as EIP-712 typed data. When it was made by the wallet's key the wallet is
+        verified and payouts held for it go out. The challenge is used up; it fails with 409 once
+        expired, used, or when the wallet changed since it was issued. Primary API key only.
+        """
+        return self._request(
+            "POST",
+            "/v1/merchants/wallet/verify",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def update_merchant_settings(
+        self,
+        body: Optional[m.MerchantSettings] = None,
+        *,
+        merchant_id: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantSettings:
+        """Get or update merchant settings
+
+        refund_approval_required makes every refund wait for approval by a second credential.
+        Merchants may turn it on with their primary API key; turning it off, and changing velocity
+        limits, requires the admin key (use /admin/merchants/settings?merchant_id=).
+        late_payment_review holds payments that arrive after an order expired (within the grace
+        window) for review instead of crediting them. payout_mode makes settlements pay out on-chain
+        to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes
+        them as transactions of the multisig at payout_safe_address for its owners to execute; ""
+        keeps settlements ledger only. Payout settings require the admin key. settlement_asset and
+        settlement_chain convert settled funds received in another asset or on another chain (e.g.
+        USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default
+        50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its
+        customer and bank account at the off-ramp partner for fiat payouts (admin key only);
+        kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g.
+        Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles
+        orders by local calendar day: the orders paid on a day settle after local midnight plus the
+        settlement delay. accepted_assets (e.g. [{"asset":"USDT","chain":"BSC"}]) limits new orders
+        to those asset and chain pairs, each a token known on its chain, and orders in any other are
+        rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of
+        each settlement withheld while the merchant's balance on the chain is negative, e.g. after
+        refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the
+        deficit to be netted by new payments only); admin key only.
+        """
+        return self._request(
+            "POST",
+            "/v1/merchants/settings",
+            query={"merchant_id": merchant_id},
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def list_api_keys(self) -> List[m.ApiKeyInfo]:
+        """Create or list scoped API keys
+
+        POST creates an additional merchant API key limited to the given scopes, e.g. a read-only
+        key for a reporting job; GET lists them. Requires the primary API key. A scoped key counts
+        as the primary key for refund approval: whoever holds the primary key can create any scoped
+        key, so a second approver needs a credential of their own (an organization member's key, an
+        OAuth grant or the admin key).
+        """
+        return self._request("GET", "/v1/merchants/api-keys")
+
+    def create_api_keys(
+        self,
+        body: Optional[m.ApiKeyCreateReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.ApiKeyCreateResp:
+        """Create or list scoped API keys
+
+        POST creates an additional merchant API key limited to the given scopes, e.g. a read-only
+        key for a reporting job; GET lists them. Requires the primary API key. A scoped key counts
+        as the primary key for refund approval: whoever holds the primary key can create any scoped
+        key, so a second approver needs a credential of their own (an organization member's key, an
+        OAuth grant or the admin key).
+        """
+        return self._request(
+            "POST",
+            "/v1/merchants/api-keys",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def merchant_api_key_usage(self) -> List[m.ApiKeyUsage]:
+        """List API keys with their usage
+
+        Lists the merchant's API keys, its primary key (id "primary") first, with when each was last
+        used, from which IP, and how many requests it made, along with the IPs it was used from most
+        recently (at most 10). A key used from an unexpected IP may have leaked; one not used for
+        long may be forgotten and is better revoked. Counts are kept from when usage tracking was
+        introduced and may trail by some seconds. Requires the primary API key.
+        """
+        return self._request("GET", "/v1/merchants/me/api-keys")
+
+    def revoke_api_key(self, id: str, *, idempotency_key: Optional[str] = None) -> Dict[str, bool]:
+        """Revoke a scoped API key
+
+        Revokes one of the merchant's scoped API keys. Requires the primary API key.
+        """
+        return self._request(
+            "POST",
+            f"/v1/merchants/api-keys/{quote(id, safe='')}/revoke",
+            idempotency_key=idempotency_key,
+        )
+
+    def get_webhook_config(self) -> m.WebhookConfig:
+        """Get or set the webhook endpoint
+
+        POST sets the URL events are delivered to (an empty url disables delivery) and the event
+        types to receive: order.paid, order.in_review, order.mispaid, order.failed, order.expired,
+        order.settled, refund.requested, refund.completed, refund.rejected, dispute.opened,
+        dispute.resolved, verification.failed, or ["*"] for all (the default). A signing secret is
+        generated the first time a URL is set and only returned in that response. Requires the
+        primary API key.
+        """
+        return self._request("GET", "/v1/webhooks")
+
+    def update_webhook_config(
+        self,
+        body: Optional[m.WebhookConfigReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.WebhookConfig:
+        """Get or set the webhook endpoint
+
+        POST sets the URL events are delivered to (an empty url disables delivery) and the event
+        types to receive: order.paid, order.in_review, order.mispaid, order.failed, order.expired,
+        order.settled, refund.requested, refund.completed, refund.rejected, dispute.opened,
+        dispute.resolved, verification.failed, or ["*"] for all (the default). A signing secret is
+        generated the first time a URL is set and only returned in that response. Requires the
+        primary API key.
+        """
+        return self._request("POST", "/v1/webhooks", body=body, idempotency_key=idempotency_key)
+
+    def webhook_test(
+        self,
+        body: Optional[m.WebhookTestReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.WebhookTestResp:
+        """Send a test webhook
+
+        Sends a signed sample event (marked "test": true) to the configured webhook URL and reports
+        the receiver's status code and latency, not its body. Redirects are not followed, and URLs
+        resolving to loopback, private or link-local addresses are refused. Nothing is stored or
+        retried.
+        """
+        return self._request(
+            "POST",
+            "/v1/webhooks/test",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def webhook_secret_rotate(
+        self,
+        body: Optional[m.WebhookSecretRotateReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.WebhookSecretRotateResp:
+        """Rotate the webhook signing secret
+
+        Generates a new signing secret and returns it; it is not shown again. Until
+        previous_secret_expires_at (grace_period_hours, default 24, at most 168) deliveries are
+        signed with both the new and the previous secret, so a receiver verifying either keeps
+        accepting them while it switches. X-OSPay-Key-Version names the versions that signed a
+        delivery, newest first, in the order of the v1 signatures. Rotating again within a grace
+        period retires the oldest secret immediately. Requires the primary API key.
+        """
+        return self._request(
+            "POST",
+            "/v1/webhooks/secret/rotate",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def replay_events(
+        self,
+        body: m.EventReplayReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.EventReplayResp:
+        """Replay webhook events
+
+        Queues every event already sent (or skipped or failed) for an order, a time window, or both
+        for delivery again, e.g. after the receiver was down. Each copy gets a new id and carries
+        "replay_of" with the original event id; subscriptions apply as for new events. At most 1000
+        events per request.
+        """
+        return self._request(
+            "POST",
+            "/v1/events/replay",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def dead_letter_events(
+        self,
+        *,
+        limit: Optional[int] = None,
+        cursor: Optional[str] = None,
+    ) -> m.DeadLetterListResp:
+        """List dead-lettered webhook events
+
+        Returns the authenticated merchant's events that were given up on after 10 failed delivery
+        attempts, newest first, with the attempt count and the last error. They stay dead-lettered
+        until requeued with POST /events/dead-letter/requeue.
+        """
+        return self._request(
+            "GET",
+            "/v1/events/dead-letter",
+            query={"limit": limit, "cursor": cursor},
+        )
+
+    def requeue_dead_letters(
+        self,
+        body: m.DeadLetterRequeueReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.DeadLetterRequeueResp:
+        """Requeue dead-lettered webhook events
+
+        Puts the given dead-lettered events (event_ids), or all of them with "all": true, back in
+        the delivery queue with a fresh set of attempts, oldest first, up to 1000 per request.
+        Unlike a replay the events keep their id. IDs that are not dead-lettered events of the
+        merchant are ignored.
+        """
+        return self._request(
+            "POST",
+            "/v1/events/dead-letter/requeue",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def create_platform(
+        self,
+        body: m.PlatformCreateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PlatformCreateResp:
+        """Create a new platform
+
+        Creates a marketplace platform that can onboard connected merchant accounts
+        """
+        return self._request("POST", "/v1/platforms", body=body, idempotency_key=idempotency_key)
+
+    def list_connected_merchants(self) -> List[m.ConnectedMerchant]:
+        """Create or list connected merchants
+
+        POST creates a merchant account connected to the calling platform; GET lists them
+        """
+        return self._request("GET", "/v1/platforms/merchants")
+
+    def create_connected_merchants(
+        self,
+        body: Optional[m.MerchantCreateReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantCreateResp:
+        """Create or list connected merchants
+
+        POST creates a merchant account connected to the calling platform; GET lists them
+        """
+        return self._request(
+            "POST",
+            "/v1/platforms/merchants",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def platform_create_order(
+        self,
+        body: m.PlatformOrderCreateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderCreateResp:
+        """Create an order for a connected merchant
+
+        Creates a payment order on behalf of a connected merchant, optionally withholding an
+        application fee for the platform
+        """
+        return self._request(
+            "POST",
+            "/v1/platforms/orders",
+            body=body,
+            idempotency_key=idempotency_key,
+            idempotency_field="idempotency_key",
+        )
+
+    def platform_balances(self, *, asset: str) -> m.PlatformBalancesResp:
+        """Get connected merchant balances
+
+        Returns each connected merchant's ledger balance and the platform fees collected for an
+        asset
+        """
+        return self._request("GET", "/v1/platforms/balances", query={"asset": asset})
+
+    def list_platform_balance_holds(
+        self,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.BalanceHold]:
+        """Place or list connected merchant balance holds
+
+        POST holds part of a connected merchant's unsettled balance, like POST /admin/holds. GET
+        lists the holds on the balances of the platform's connected merchants, newest first,
+        filtered by status and merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/platforms/holds",
+            query={"status": status, "merchant_id": merchant_id},
+        )
+
+    def create_platform_balance_holds(
+        self,
+        body: Optional[m.PlaceHoldReq] = None,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> m.BalanceHold:
+        """Place or list connected merchant balance holds
+
+        POST holds part of a connected merchant's unsettled balance, like POST /admin/holds. GET
+        lists the holds on the balances of the platform's connected merchants, newest first,
+        filtered by status and merchant_id.
+        """
+        return self._request(
+            "POST",
+            "/v1/platforms/holds",
+            query={"status": status, "merchant_id": merchant_id},
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def platform_release_balance_hold(
+        self,
+        id: str,
+        body: Optional[m.ReleaseHoldReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.BalanceHold:
+        """Release a connected merchant balance hold
+
+        Releases an ACTIVE hold the platform placed, like POST /admin/holds/release.
+        """
+        return self._request(
+            "POST",
+            f"/v1/platforms/holds/{quote(id, safe='')}/release",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def create_organization(
+        self,
+        body: m.OrgCreateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrgCreateResp:
+        """Create an organization
+
+        Creates an organization to own several merchants, with owner_email as its owner. The
+        response carries the owner's API key, which is not shown again.
+        """
+        return self._request(
+            "POST",
+            "/v1/organizations",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def list_org_members(self) -> List[m.OrgMember]:
+        """Add or list organization members
+
+        POST adds a member with its own API key, returned once: admins manage members and merchants
+        and have full access to the merchants, viewers read orders and balances. GET lists the
+        members. Adding members needs an owner or admin key.
+        """
+        return self._request("GET", "/v1/organizations/members")
+
+    def create_org_members(
+        self,
+        body: Optional[m.OrgMemberReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrgMember:
+        """Add or list organization members
+
+        POST adds a member with its own API key, returned once: admins manage members and merchants
+        and have full access to the merchants, viewers read orders and balances. GET lists the
+        members. Adding members needs an owner or admin key.
+        """
+        return self._request(
+            "POST",
+            "/v1/organizations/members",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def remove_org_member(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> Dict[str, bool]:
+        """Remove an organization member
+
+        Removes a member; its API key stops working at once. The owner cannot be removed. Needs an
+        owner or admin key.
+        """
+        return self._request(
+            "POST",
+            f"/v1/organizations/members/{quote(id, safe='')}/remove",
+            idempotency_key=idempotency_key,
+        )
+
+    def list_org_merchants(self) -> List[m.OrgMerchant]:
+        """Create or list an organization's merchants
+
+        POST creates a merchant owned by the organization, like POST /merchants (the merchant's own
+        API key is returned once, and the merchant may wait for approval); it needs an owner or
+        admin key. GET lists the organization's merchants.
+        """
+        return self._request("GET", "/v1/organizations/merchants")
+
+    def create_org_merchants(
+        self,
+        body: Optional[m.MerchantCreateReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantCreateResp:
+        """Create or list an organization's merchants
+
+        POST creates a merchant owned by the organization, like POST /merchants (the merchant's own
+        API key is returned once, and the merchant may wait for approval); it needs an owner or
+        admin key. GET lists the organization's merchants.
+        """
+        return self._request(
+            "POST",
+            "/v1/organizations/merchants",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def attach_org_merchant(
+        self,
+        body: m.OrgAttachReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrgMerchant:
+        """Add an existing merchant to the organization
+
+        Moves a merchant that is not in an organization yet into this one; the merchant's primary
+        API key proves it may. The merchant's own keys keep working. Needs an owner or admin key.
+        """
+        return self._request(
+            "POST",
+            "/v1/organizations/merchants/attach",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def detach_org_merchant(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> Dict[str, bool]:
+        """Remove a merchant from the organization
+
+        Takes a merchant out of the organization; the merchant keeps its account and its own keys,
+        but members no longer act for it. Needs an owner or admin key.
+        """
+        return self._request(
+            "POST",
+            f"/v1/organizations/merchants/{quote(id, safe='')}/detach",
+            idempotency_key=idempotency_key,
+        )
+
+    def org_balances(self) -> m.OrgBalancesResp:
+        """Get the organization's balances
+
+        Returns each merchant's balances, as GET /merchants/balances does, and their totals per
+        asset and chain.
+        """
+        return self._request("GET", "/v1/organizations/balances")
+
+    def org_report(
+        self,
+        *,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+    ) -> m.OrgReportResp:
+        """Get the organization's sales report
+
+        Sums, per merchant and asset and over all merchants per asset, the orders created in [from,
+        to), the orders paid and the amount paid in it (by paid_at), and the completed refunds (by
+        created_at). from defaults to 30 days before to, which defaults to now.
+        """
+        return self._request("GET", "/v1/organizations/report", query={"from": from_, "to": to})
+
+    def create_oauth_client(
+        self,
+        body: m.OauthClientCreateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OauthClientCreateResp:
+        """Register an OAuth client
+
+        Registers an OAuth2 client for the calling platform. The client secret is only returned
+        once.
+        """
+        return self._request(
+            "POST",
+            "/v1/oauth/clients",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def oauth_authorize(
+        self,
+        body: m.OauthAuthorizeReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OauthAuthorizeResp:
+        """Authorize an OAuth client
+
+        Called by the merchant (with their primary API key) to grant a platform client the requested
+        scopes. Scoped keys, OAuth tokens and organization member keys are refused. Returns a
+        short-lived authorization code.
+        """
+        return self._request(
+            "POST",
+            "/v1/oauth/authorize",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def oauth_token(
+        self,
+        *,
+        grant_type: str,
+        code: Optional[str] = None,
+        redirect_uri: Optional[str] = None,
+        refresh_token: Optional[str] = None,
+        client_id: Optional[str] = None,
+        client_secret: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OauthTokenResp:
+        """Issue or refresh OAuth tokens
+
+        RFC 6749 token endpoint. Supports grant_type=authorization_code and
+        grant_type=refresh_token; refresh tokens are rotated on use. Client credentials go in HTTP
+        Basic auth or the form body.
+        """
+        return self._request(
+            "POST",
+            "/v1/oauth/token",
+            form={
+                "grant_type": grant_type,
+                "code": code,
+                "redirect_uri": redirect_uri,
+                "refresh_token": refresh_token,
+                "client_id": client_id,
+                "client_secret": client_secret,
+            },
+            idempotency_key=idempotency_key,
+        )
+
+    def oauth_revoke(
+        self,
+        *,
+        token: str,
+        client_id: Optional[str] = None,
+        client_secret: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> Dict[str, bool]:
+        """Revoke an OAuth token
+
+        RFC 7009 revocation. Revoking a refresh token also revokes every access token from the same
+        grant. Unknown tokens are accepted silently.
+        """
+        return self._request(
+            "POST",
+            "/v1/oauth/revoke",
+            form={"token": token, "client_id": client_id, "client_secret": client_secret},
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_timeseries(
+        self,
+        *,
+        metric: str,
+        interval: Optional[str] = None,
+        asset: Optional[str] = None,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> m.TimeseriesResp:
+        """Get a metric as a time series
+
+        Buckets a metric by hour or day over [from, to): orders_created (count), paid_volume (amount
+        paid, by paid_at; needs asset), discounts (coupon discounts given on those payments; needs
+        asset), refunds (amount refunded by completed refunds; needs asset) and conversion_rate
+        (paid share of the orders created in the bucket). Buckets start at hour or midnight
+        boundaries in the merchant's timezone and every bucket in the range is returned,
+        zero-filled. from defaults to 24 hours (hour) or 30 days (day) before to, which defaults to
+        now; a series has at most 744 hourly or 366 daily points. Admins pass merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/stats/timeseries",
+            query={
+                "metric": metric,
+                "interval": interval,
+                "asset": asset,
+                "from": from_,
+                "to": to,
+                "merchant_id": merchant_id,
+            },
+        )
+
+    def admin_merchants(
+        self,
+        *,
+        status: Optional[str] = None,
+        deleted: Optional[bool] = None,
+    ) -> List[m.MerchantRecord]:
+        """List merchants
+
+        Lists merchants, newest first, optionally by status: PENDING_APPROVAL for the applications
+        waiting for a decision, ACTIVE or REJECTED. Deleted merchants are left out; deleted=true
+        lists them instead, for restoring. Admin only.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/merchants",
+            query={"status": status, "deleted": deleted},
+        )
+
+    def admin_approve_merchant(
+        self,
+        id: str,
+        body: Optional[m.MerchantDecisionReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantRecord:
+        """Approve a merchant
+
+        Activates a merchant waiting for approval: its API keys start working and a
+        merchant.approved webhook is sent. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/merchants/{quote(id, safe='')}/approve",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_reject_merchant(
+        self,
+        id: str,
+        body: m.MerchantDecisionReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantRecord:
+        """Reject a merchant
+
+        Rejects a merchant waiting for approval with a reason, which its API key is then refused
+        with; a merchant.rejected webhook is sent. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/merchants/{quote(id, safe='')}/reject",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_delete_merchant(
+        self,
+        id: str,
+        body: Optional[m.SoftDeleteReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantRecord:
+        """Delete a merchant
+
+        Soft-deletes a merchant, e.g. when offboarding it: its API keys, OAuth tokens and platform
+        or organization access are refused with 403 merchant_deleted, it is left out of merchant
+        lists, and its balance is no longer settled. Nothing is removed, the ledger included; POST
+        /admin/merchants/{id}/restore undoes the deletion. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/merchants/{quote(id, safe='')}/delete",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_restore_merchant(
+        self,
+        id: str,
+        body: Optional[m.SoftDeleteReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantRecord:
+        """Restore a deleted merchant
+
+        Undoes the deletion of a merchant: its API keys and tokens work again, and settlement picks
+        up its balance on the next run. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/merchants/{quote(id, safe='')}/restore",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_auth_bans(self) -> List[m.IpBan]:
+        """List IPs banned for failed authentication
+
+        Lists the source IPs this instance bans for too many failed authentications (invalid API
+        keys, tokens or admin keys), longest ban first. Admin only.
+        """
+        return self._request("GET", "/v1/admin/auth/bans")
+
+    def admin_lift_auth_ban(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> Dict[str, bool]:
+        """Lift an IP ban
+
+        Ends the ban of a source IP on this instance and forgets its failed authentications. Admin
+        only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/auth/bans/{quote(id, safe='')}/lift",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_dormant_api_keys(self, *, unused_days: Optional[int] = None) -> List[m.DormantKey]:
+        """List dormant API keys
+
+        Lists the API keys, primary and scoped, that are not revoked and have not been used for
+        unused_days (default 90): those last used before then, and those created before then and
+        never used. Least recently used first; at most 500. Admin only.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/api-keys/dormant",
+            query={"unused_days": unused_days},
+        )
+
+    def admin_merchant_balances(self, *, merchant_id: Optional[str] = None) -> m.BalancesResp:
+        """Get merchant balances
+
+        Returns the merchant's balance per asset and chain, read from the materialized ledger
+        balances: available (settled funds, the settlement bucket), pending (the merchant's share of
+        PAID and PARTIALLY_REFUNDED orders not yet settled, the merchant bucket), held (frozen by
+        open disputes or balance holds, or reserved for fiat payouts) and refundable (overpayments
+        of already paid orders, to send back to customers with POST /overpayments/{id}/refund; not
+        the merchant's funds). Admins pass merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/merchants/balances",
+            query={"merchant_id": merchant_id},
+        )
+
+    def admin_onchain_reconciliation(self) -> m.OnchainReconResp:
+        """Reconcile the books with the chain
+
+        Compares, per chain and asset, what the ledger says the custody wallets hold with their
+        balances on-chain, read now through the chain's RPC endpoint. The books are the net of the
+        custody buckets (merchant, settlement, dispute_hold, balance_hold, offramp_pending,
+        conversion and platform_fee, across merchants) less payouts SENT or EXECUTED and fiat
+        payouts FUNDED, which have left the wallets. drift_minor is the on-chain total less the
+        books, negative when the wallets hold less; drifting is set beyond RECONCILE_TOLERANCE_BPS
+        of the books, and a chain and asset that starts drifting is alerted once
+        (reconciliation.drift). The custody wallets are RECONCILE_WALLETS, or the hot wallet. Admin
+        only.
+        """
+        return self._request("GET", "/v1/admin/reconciliation/onchain")
+
+    def admin_list_ledger_transactions(
+        self,
+        *,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        event_type: Optional[str] = None,
+        order_id: Optional[str] = None,
+        reference_id: Optional[str] = None,
+        limit: Optional[int] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.LedgerTransaction]:
+        """List ledger transactions
+
+        Lists the merchant's ledger entries grouped into transactions, newest first: each
+        transaction holds the legs of one double entry (a payment with its application fee leg, a
+        refund, a settlement of a batch, ...), which net to zero on each asset and chain. from and
+        to (RFC 3339) bound created_at to [from, to); event_type, order_id and reference_id (the
+        refund, batch, conversion, ... that booked it) narrow the list, limit (default 100, at most
+        500) bounds the transactions returned. Entries moved out by the retention job are not
+        listed. Admins pass merchant_id, or leave it out for every merchant.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/ledger/transactions",
+            query={
+                "from": from_,
+                "to": to,
+                "event_type": event_type,
+                "order_id": order_id,
+                "reference_id": reference_id,
+                "limit": limit,
+                "merchant_id": merchant_id,
+            },
+        )
+
+    def admin_get_ledger_transaction(self, id: str) -> m.LedgerTransaction:
+        """Get a ledger transaction
+
+        Returns the legs of one ledger transaction group, as linked by the transaction_group_id of
+        ledger entries and exports.
+        """
+        return self._request("GET", f"/v1/admin/ledger/transactions/{quote(id, safe='')}")
+
+    def admin_create_attestation(
+        self,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.ReserveAttestation:
+        """Issue a reserve attestation
+
+        Reads the custody wallets' balances now and issues a signed reserve attestation: per chain
+        and asset, liabilities_minor (what merchants are owed: their ledger balances less payouts
+        and fiat payouts that have left the wallets) next to reserves_minor (what the custody
+        wallets hold on-chain), with surplus_minor, coverage_bps and fully_backed. Platform fees are
+        not liabilities, so they show as surplus. report is signed as is with the hot wallet's key
+        (EIP-191 personal_sign), so anyone can recover signer from report and signature. An
+        attestation is also issued every ATTESTATION_INTERVAL. Fails with 502 rpc_unavailable rather
+        than attest to part of the reserves when a wallet's balance cannot be read. Admin only.
+        """
+        return self._request("POST", "/v1/admin/attestations", idempotency_key=idempotency_key)
+
+    def admin_list_attestations(
+        self,
+        *,
+        fully_backed: Optional[bool] = None,
+        limit: Optional[int] = None,
+    ) -> List[m.ReserveAttestation]:
+        """List reserve attestations
+
+        Returns the signed reserve attestations, newest first. Each carries the report that was
+        signed, per chain and asset what merchants are owed next to what the custody wallets hold,
+        with its signature and signer; see POST /admin/attestations/generate. fully_backed narrows
+        the list to attestations where every asset was (true) or was not (false) fully backed.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/attestations",
+            query={"fully_backed": fully_backed, "limit": limit},
+        )
+
+    def admin_get_attestation(
+        self,
+        id: str,
+        *,
+        format: Optional[str] = None,
+    ) -> m.ReserveAttestation:
+        """Get a reserve attestation
+
+        Returns a signed reserve attestation. With format=csv the report is returned as CSV instead,
+        one line per chain and asset, for spreadsheets; the signature, which covers the JSON report,
+        is in the X-Attestation-Signature and X-Attestation-Signer headers.
+        """
+        return self._request(
+            "GET",
+            f"/v1/admin/attestations/{quote(id, safe='')}",
+            query={"format": format},
+        )
+
+    def admin_list_cold_sweeps(
+        self,
+        *,
+        status: Optional[str] = None,
+        limit: Optional[int] = None,
+    ) -> List[m.ColdSweep]:
+        """List cold sweeps
+
+        Returns the sweeps of hot wallet balances to the cold wallet, newest first, optionally
+        filtered by status (PENDING_APPROVAL, QUEUED, SENT, EXECUTED, REJECTED, FAILED). A sweep is
+        started when the hot wallet's balance of a token goes over its COLD_SWEEP_LIMITS limit and
+        moves the excess over what the limit keeps; with COLD_SWEEP_APPROVAL on it waits in
+        PENDING_APPROVAL for POST /admin/sweeps/approve. Executed sweeps are booked on the platform
+        account, from hot_wallet to cold_storage. Admin only.
+        """
+        return self._request("GET", "/v1/admin/sweeps", query={"status": status, "limit": limit})
+
+    def admin_approve_cold_sweep(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.ColdSweep:
+        """Approve a cold sweep
+
+        Sends a PENDING_APPROVAL sweep to the cold wallet now. The amount is the one computed when
+        the sweep was started; the hot wallet's balance is not read again. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/sweeps/{quote(id, safe='')}/approve",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_reject_cold_sweep(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.ColdSweep:
+        """Reject a cold sweep
+
+        Rejects a PENDING_APPROVAL sweep, which is not sent. A new sweep is started on the next run
+        while the hot wallet is still over the limit. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/sweeps/{quote(id, safe='')}/reject",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_get_merchant_settings(
+        self,
+        *,
+        merchant_id: Optional[str] = None,
+    ) -> m.MerchantSettings:
+        """Get or update merchant settings
+
+        refund_approval_required makes every refund wait for approval by a second credential.
+        Merchants may turn it on with their primary API key; turning it off, and changing velocity
+        limits, requires the admin key (use /admin/merchants/settings?merchant_id=).
+        late_payment_review holds payments that arrive after an order expired (within the grace
+        window) for review instead of crediting them. payout_mode makes settlements pay out on-chain
+        to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes
+        them as transactions of the multisig at payout_safe_address for its owners to execute; ""
+        keeps settlements ledger only. Payout settings require the admin key. settlement_asset and
+        settlement_chain convert settled funds received in another asset or on another chain (e.g.
+        USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default
+        50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its
+        customer and bank account at the off-ramp partner for fiat payouts (admin key only);
+        kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g.
+        Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles
+        orders by local calendar day: the orders paid on a day settle after local midnight plus the
+        settlement delay. accepted_assets (e.g. [{"asset":"USDT","chain":"BSC"}]) limits new orders
+        to those asset and chain pairs, each a token known on its chain, and orders in any other are
+        rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of
+        each settlement withheld while the merchant's balance on the chain is negative, e.g. after
+        refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the
+        deficit to be netted by new payments only); admin key only.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/merchants/settings",
+            query={"merchant_id": merchant_id},
+        )
+
+    def admin_update_merchant_settings(
+        self,
+        body: Optional[m.MerchantSettings] = None,
+        *,
+        merchant_id: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> m.MerchantSettings:
+        """Get or update merchant settings
+
+        refund_approval_required makes every refund wait for approval by a second credential.
+        Merchants may turn it on with their primary API key; turning it off, and changing velocity
+        limits, requires the admin key (use /admin/merchants/settings?merchant_id=).
+        late_payment_review holds payments that arrive after an order expired (within the grace
+        window) for review instead of crediting them. payout_mode makes settlements pay out on-chain
+        to the merchant wallet: hot_wallet sends them from the platform hot wallet, safe proposes
+        them as transactions of the multisig at payout_safe_address for its owners to execute; ""
+        keeps settlements ledger only. Payout settings require the admin key. settlement_asset and
+        settlement_chain convert settled funds received in another asset or on another chain (e.g.
+        USDT on BSC into USDC on POLYGON) before they are paid out, within max_slippage_bps (default
+        50, at most 1000). offramp_customer_id and offramp_bank_account_id link the merchant to its
+        customer and bank account at the off-ramp partner for fiat payouts (admin key only);
+        kyc_status is the partner's last reported KYC status. timezone (IANA name, e.g.
+        Europe/Berlin) makes daily limits, reports and exports use merchant-local days, and settles
+        orders by local calendar day: the orders paid on a day settle after local midnight plus the
+        settlement delay. accepted_assets (e.g. [{"asset":"USDT","chain":"BSC"}]) limits new orders
+        to those asset and chain pairs, each a token known on its chain, and orders in any other are
+        rejected with asset_not_accepted; [] accepts any again. clawback_max_bps caps the share of
+        each settlement withheld while the merchant's balance on the chain is negative, e.g. after
+        refunding a settled order (default 10000, all of it; 0 withholds nothing, leaving the
+        deficit to be netted by new payments only); admin key only.
+        """
+        return self._request(
+            "POST",
+            "/v1/admin/merchants/settings",
+            query={"merchant_id": merchant_id},
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_approve_refund(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.RefundResp:
+        """Approve a requested refund
+
+        Executes a REQUESTED refund. Must be called with the admin key or a merchant credential
+        holding refunds:approve of a different origin than the one that requested it: the merchant's
+        primary key and the scoped API keys it created count as one, while each organization
+        member's key and each OAuth grant is its own.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/refunds/{quote(id, safe='')}/approve",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_reject_refund(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.RefundResp:
+        """Reject a requested refund
+
+        Rejects a REQUESTED refund, releasing its reserved amount. Same credential rules as
+        approval.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/refunds/{quote(id, safe='')}/reject",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_list_overpayments(
+        self,
+        *,
+        status: Optional[str] = None,
+        order_id: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.OverpaymentRecord]:
+        """List overpayments
+
+        Returns the most recent overpayments (newest first), optionally filtered by status
+        (CONFIRMING, REVIEW, OPEN, REFUND_QUEUED, REFUND_SENT, REFUNDED, REFUND_FAILED) or order_id.
+        An overpayment is a further transfer of the order's asset to the deposit address reported
+        for an order that is already PAID, SETTLED or (partially) refunded, e.g. a customer paying
+        twice. It is credited to the refundable balance rather than the merchant's once its block is
+        final (CONFIRMING until then), and sent back to the sender with POST
+        /overpayments/{id}/refund. Admins see every merchant's, or one with merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/overpayments",
+            query={"status": status, "order_id": order_id, "merchant_id": merchant_id},
+        )
+
+    def admin_refund_overpayment(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OverpaymentRecord:
+        """Refund an overpayment
+
+        Sends an OPEN overpayment back to the address it came from, from the hot wallet; one in
+        REVIEW, whose sender was flagged by screening, only on the admin route. The amount leaves
+        the refundable balance at once and the overpayment is REFUND_QUEUED; it is REFUNDED, with
+        refund_tx_hash, once the transfer is mined (overpayment.refunded). A transfer that fails
+        leaves it REFUND_FAILED with last_error (overpayment.refund_failed); refunding it again
+        sends it again. An overpayment whose sender was erased (see POST /privacy/erasure) cannot be
+        refunded.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/overpayments/{quote(id, safe='')}/refund",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_rate_history(
+        self,
+        *,
+        base: Optional[str] = None,
+        quote: Optional[str] = None,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        limit: Optional[int] = None,
+        merchant_id: Optional[str] = None,
+    ) -> m.RateHistoryResp:
+        """List rates served
+
+        Lists the rates /rates served the merchant, newest first, so the conversion behind a
+        fiat-priced order can be checked after the fact: base and quote narrow it to a pair, from
+        and to (RFC 3339) bound when they were served to [from, to), and limit caps the list
+        (default 100, max 1000). A quote is kept as served, with the provider, its source and when
+        the provider last updated it. Admins see every merchant's quotes, or one merchant's with
+        merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/rates/history",
+            query={
+                "base": base,
+                "quote": quote,
+                "from": from_,
+                "to": to,
+                "limit": limit,
+                "merchant_id": merchant_id,
+            },
+        )
+
+    def admin_list_disputes(
+        self,
+        *,
+        order_id: Optional[str] = None,
+        status: Optional[str] = None,
+    ) -> List[m.DisputeRecord]:
+        """Open or list disputes
+
+        POST (admin only) opens a dispute against a PAID, PARTIALLY_REFUNDED or SETTLED order and
+        freezes the disputed amount of the merchant balance. GET lists disputes with their evidence,
+        optionally filtered by order_id and status; merchants only see their own.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/disputes",
+            query={"order_id": order_id, "status": status},
+        )
+
+    def admin_create_disputes(
+        self,
+        body: Optional[m.DisputeCreateReq] = None,
+        *,
+        order_id: Optional[str] = None,
+        status: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> m.DisputeRecord:
+        """Open or list disputes
+
+        POST (admin only) opens a dispute against a PAID, PARTIALLY_REFUNDED or SETTLED order and
+        freezes the disputed amount of the merchant balance. GET lists disputes with their evidence,
+        optionally filtered by order_id and status; merchants only see their own.
+        """
+        return self._request(
+            "POST",
+            "/v1/admin/disputes",
+            query={"order_id": order_id, "status": status},
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_dispute_evidence(
+        self,
+        id: str,
+        body: m.DisputeEvidenceReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.DisputeEvidence:
+        """Add dispute evidence
+
+        Attaches an evidence note to an open dispute. Available to the merchant and to admins.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/disputes/{quote(id, safe='')}/evidence",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_resolve_dispute(
+        self,
+        id: str,
+        body: m.DisputeResolveReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.DisputeRecord:
+        """Resolve a dispute
+
+        Closes an open dispute. "won" releases the held funds back to the merchant; "lost" returns
+        them to the customer. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/disputes/{quote(id, safe='')}/resolve",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_search_orders(
+        self,
+        *,
+        external_order_id: Optional[str] = None,
+        tx_hash: Optional[str] = None,
+        customer_email: Optional[str] = None,
+        metadata: Optional[Dict[str, str]] = None,
+        tag: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+        limit: Optional[int] = None,
+    ) -> m.OrderListResp:
+        """Search orders
+
+        Finds orders from whatever a customer can tell support: external_order_id, tx_hash,
+        customer_email (the exact address), metadata values, given as metadata.<key>=<value> for
+        top-level metadata keys (compared as text, so metadata.qty=2 matches 2 and "2"), and tag.
+        Every given criterion must match; at least one is required. Archived orders are included.
+        Results are newest first, at most limit (default 50, max 200). Admins may pass merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/orders/search",
+            query={
+                "external_order_id": external_order_id,
+                "tx_hash": tx_hash,
+                "customer_email": customer_email,
+                "metadata": metadata,
+                "tag": tag,
+                "merchant_id": merchant_id,
+                "limit": limit,
+            },
+        )
+
+    def admin_review_order(
+        self,
+        id: str,
+        body: m.OrderReviewReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PaymentDetectedResp:
+        """Release or reject a payment held for review
+
+        Orders whose payer address was flagged during screening wait in REVIEW; late payments for
+        expired orders wait in LATE_PAYMENT when the merchant chose to review them. "approve" marks
+        the order PAID and writes the ledger; "reject" marks it FAILED. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/orders/{quote(id, safe='')}/review",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_extend_order(
+        self,
+        id: str,
+        body: Optional[m.OrderExtendReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderExtendResp:
+        """Extend a pending order
+
+        Pushes out expires_at of a PENDING order by minutes (default 30), counted from now if that
+        is later than the current expiry, so a checkout stays open while the customer is still
+        paying. An order cannot be extended past 24 hours after it was created.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/orders/{quote(id, safe='')}/extend",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_force_order_status(
+        self,
+        id: str,
+        body: m.OrderStatusOverrideReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderStatusOverrideResp:
+        """Force an order's status
+
+        Support override for stuck orders. PAID credits the payment like a verified one (ledger
+        entries, order.paid), optionally recording tx_hash; FAILED or EXPIRED reverses the payment
+        of a PAID order with STATUS_OVERRIDE ledger entries. Orders that were settled, refunded or
+        are disputed, or have refunds awaiting approval, cannot be overridden. reason (at least 10
+        characters) is mandatory and the override is written to the audit log as
+        order_status_forced. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/orders/{quote(id, safe='')}/status",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_create_order_notes(
+        self,
+        id: str,
+        body: Optional[m.OrderNoteReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderNote:
+        """Add or list order notes
+
+        POST adds an internal note (body, at most 4000 characters) to an order, recording the
+        credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible
+        to the merchant's credentials and operators; they are not included in order responses or
+        webhooks.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/orders/{quote(id, safe='')}/notes",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_list_order_notes(self, id: str) -> List[m.OrderNote]:
+        """Add or list order notes
+
+        POST adds an internal note (body, at most 4000 characters) to an order, recording the
+        credential that wrote it. GET lists the order's notes, oldest first. Notes are only visible
+        to the merchant's credentials and operators; they are not included in order responses or
+        webhooks.
+        """
+        return self._request("GET", f"/v1/admin/orders/{quote(id, safe='')}/notes")
+
+    def admin_update_order_tags(
+        self,
+        id: str,
+        body: Optional[m.OrderTagsReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderTagsResp:
+        """Add or list order tags
+
+        POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64
+        characters, trimmed and compared in lower case; tags the order already has are ignored and
+        an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags
+        stay on the order when it is archived.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/orders/{quote(id, safe='')}/tags",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_get_order_tags(self, id: str) -> m.OrderTagsResp:
+        """Add or list order tags
+
+        POST adds tags to an order; GET returns them. Tags are free-form labels of 1 to 64
+        characters, trimmed and compared in lower case; tags the order already has are ignored and
+        an order carries at most 20. List orders with ?tag= to find the orders carrying one. Tags
+        stay on the order when it is archived.
+        """
+        return self._request("GET", f"/v1/admin/orders/{quote(id, safe='')}/tags")
+
+    def admin_remove_order_tags(
+        self,
+        id: str,
+        body: m.OrderTagsReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderTagsResp:
+        """Remove order tags
+
+        Removes tags from an order; tags it does not carry are ignored. Returns the tags left.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/orders/{quote(id, safe='')}/tags/remove",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_order_timeline(self, id: str) -> List[m.TimelineEntry]:
+        """Get an order's timeline
+
+        Returns what happened to an order, oldest first: its creation, the webhook events raised for
+        it and its refunds and disputes (whether or not the merchant subscribes to them), every
+        transaction hash reported as its payment with the outcome of its verification
+        (failure_reason wrong_amount, wrong_token, wrong_recipient, no_transfer, tx_not_found,
+        order_expired, already_paid or verification_error), and the team's notes. Events pruned by
+        OUTBOX_RETENTION_DAYS no longer appear.
+        """
+        return self._request("GET", f"/v1/admin/orders/{quote(id, safe='')}/timeline")
+
+    def admin_delete_order(
+        self,
+        id: str,
+        body: Optional[m.SoftDeleteReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> Dict[str, bool]:
+        """Delete an order
+
+        Soft-deletes an order that never received a payment, e.g. one created by mistake: it is no
+        longer returned by any order read, list or search, a payment reported for it is refused as
+        for an unknown order, and it does not expire or count toward limits. Orders with a payment
+        stay on the books, since their ledger entries cannot be undone; refund them instead (409
+        order_has_payment). So do MISPAID orders and orders with a payment still being verified. The
+        idempotency key stays used. Only an administrator can restore a deleted order, with POST
+        /admin/orders/{id}/restore.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/orders/{quote(id, safe='')}/delete",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_restore_order(
+        self,
+        id: str,
+        body: Optional[m.SoftDeleteReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.OrderGetResp:
+        """Restore a deleted order
+
+        Undoes the deletion of an order and returns it. A pending order past its expiry is expired
+        by the next expiry run. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/orders/{quote(id, safe='')}/restore",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_audit_log(
+        self,
+        *,
+        merchant_id: Optional[str] = None,
+        order_id: Optional[str] = None,
+        action: Optional[str] = None,
+    ) -> List[m.AuditEntry]:
+        """List audit log entries
+
+        Returns the most recent audit entries (newest first), optionally filtered by merchant_id,
+        order_id and action. Admin only.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/audit",
+            query={"merchant_id": merchant_id, "order_id": order_id, "action": action},
+        )
+
+    def admin_list_settlements(
+        self,
+        *,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        asset: Optional[str] = None,
+        format: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.SettlementRecord]:
+        """List settlement batches
+
+        Lists the merchant's settlement batches, newest first, each itemized into the gross volume
+        of its orders, the application fees withheld, the completed refunds and lost disputes
+        netted, what was clawed back against a negative balance or withheld for balance holds, and
+        the net payout (total_amount_minor, what the payout sends). Releasing a hold settles what it
+        withheld in a batch of its own (hold_released_minor). Batches settled before itemization was
+        stored only carry the net. from and to (RFC 3339) bound settled_at to [from, to), asset
+        narrows the list. With format=csv the list is returned as a statement, one line per batch,
+        for accounting. Admins pass merchant_id, or leave it out for every merchant.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/settlements",
+            query={
+                "from": from_,
+                "to": to,
+                "asset": asset,
+                "format": format,
+                "merchant_id": merchant_id,
+            },
+        )
+
+    def admin_list_negative_balances(
+        self,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.NegativeBalance]:
+        """List negative balances
+
+        Lists the times the merchant's balance of an asset on a chain went below zero, newest first:
+        OPEN while it still owes amount_minor, RECOVERED once new payments or clawbacks from
+        settlements brought it back. A balance goes negative when more is taken from it than it
+        holds, e.g. by the refund of an order that was already settled. The tracker checks the
+        balances every NEGATIVE_BALANCE_CHECK_INTERVAL and raises balance.negative and
+        balance.recovered. status narrows the list. Admins pass merchant_id, or leave it out for
+        every merchant.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/negative-balances",
+            query={"status": status, "merchant_id": merchant_id},
+        )
+
+    def admin_list_balance_holds(
+        self,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.BalanceHold]:
+        """Place or list balance holds
+
+        POST (admin key or platform API key) holds amount_minor of a merchant's unsettled balance of
+        asset on chain, for reason, until it is released or expires_at passes. The amount moves from
+        the merchant to the balance_hold ledger bucket (counted as held in the balances) and
+        settlements keep back what the merchant balance then lacks to pay out their orders, so held
+        funds are not paid out; withheld_minor is how much they kept back. The hold may not exceed
+        the unsettled balance on the chain. Platforms can only hold the balances of their connected
+        merchants. GET lists holds, newest first, filtered by status and (admins, platforms)
+        merchant_id; merchants see the holds on their own balance.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/holds",
+            query={"status": status, "merchant_id": merchant_id},
+        )
+
+    def admin_create_balance_holds(
+        self,
+        body: Optional[m.PlaceHoldReq] = None,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> m.BalanceHold:
+        """Place or list balance holds
+
+        POST (admin key or platform API key) holds amount_minor of a merchant's unsettled balance of
+        asset on chain, for reason, until it is released or expires_at passes. The amount moves from
+        the merchant to the balance_hold ledger bucket (counted as held in the balances) and
+        settlements keep back what the merchant balance then lacks to pay out their orders, so held
+        funds are not paid out; withheld_minor is how much they kept back. The hold may not exceed
+        the unsettled balance on the chain. Platforms can only hold the balances of their connected
+        merchants. GET lists holds, newest first, filtered by status and (admins, platforms)
+        merchant_id; merchants see the holds on their own balance.
+        """
+        return self._request(
+            "POST",
+            "/v1/admin/holds",
+            query={"status": status, "merchant_id": merchant_id},
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_release_balance_hold(
+        self,
+        id: str,
+        body: Optional[m.ReleaseHoldReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.BalanceHold:
+        """Release a balance hold
+
+        Releases an ACTIVE hold before it expires: the amount moves back from the balance_hold to
+        the merchant ledger bucket, and what settlements withheld for it (withheld_minor) is settled
+        right away in a batch of its own (release_batch_id, hold_released_minor), paid out like any
+        other settlement. An optional reason is recorded. Platforms can only release the holds they
+        placed; admins any.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/holds/{quote(id, safe='')}/release",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_run_settlement(
+        self,
+        *,
+        merchant_id: Optional[str] = None,
+        idempotency_key: Optional[str] = None,
+    ) -> List[m.SettlementBatch]:
+        """Settle paid orders now
+
+        Settles every PAID or PARTIALLY_REFUNDED order immediately instead of waiting for the
+        scheduler's delay, optionally for one merchant. Orders with pending refunds or open disputes
+        are skipped as usual. Admin only.
+        """
+        return self._request(
+            "POST",
+            "/v1/admin/settlements/run",
+            query={"merchant_id": merchant_id},
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_list_chain_transactions(
+        self,
+        *,
+        status: Optional[str] = None,
+        chain: Optional[str] = None,
+    ) -> List[m.ChainTx]:
+        """List outgoing transactions
+
+        Returns the most recent transactions sent by the hot wallet (newest first), optionally
+        filtered by status (PENDING, CANCELLING, CONFIRMED, FAILED, CANCELLED, DROPPED) and chain,
+        with their fees, any replaced predecessors and which hash was mined. stuck marks
+        transactions pending for over three times their profile's wait. Admin only.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/transactions",
+            query={"status": status, "chain": chain},
+        )
+
+    def admin_bump_chain_transaction(
+        self,
+        id: str,
+        body: Optional[m.ChainTxBumpReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.ChainTx:
+        """Replace a stuck transaction
+
+        Re-signs a PENDING (or CANCELLING) outgoing transaction with the same nonce and fees from
+        the given urgency profile (slow, standard, fast), raised at least 12% over the current ones
+        so nodes accept the replacement, and broadcasts it. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/transactions/{quote(id, safe='')}/bump",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_cancel_chain_transaction(
+        self,
+        id: str,
+        body: Optional[m.ChainTxBumpReq] = None,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.ChainTx:
+        """Cancel a pending transaction
+
+        Sends a zero-value transfer from the hot wallet to itself with the transaction's nonce and
+        higher fees, so the original is dropped once it is mined. The transaction is CANCELLING
+        until one of the two is mined, then CANCELLED or, if the original won, CONFIRMED. Admin
+        only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/transactions/{quote(id, safe='')}/cancel",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_gas_tank(self) -> m.GasTankResp:
+        """Show hot wallet gas balances
+
+        Reads the hot wallet's native-coin balance on every chain and projects how many days it
+        lasts: the average daily payouts of the last 7 days (settlement batches and completed
+        refunds on the chain) priced at the current gas price of a token transfer. low is set when
+        the balance is under the chain's low-water mark (GAS_TANK_LOW_WATER). Admin only.
+        """
+        return self._request("GET", "/v1/admin/gas-tank")
+
+    def admin_list_payouts(
+        self,
+        *,
+        status: Optional[str] = None,
+        batch_id: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.PayoutRecord]:
+        """List on-chain payouts
+
+        Returns the most recent payouts of settlement batches (newest first), optionally filtered by
+        status (QUEUED, SENT, PROPOSED, EXECUTED, FAILED) or batch_id. A queued payout that fails to
+        be sent or proposed is retried as the payout retry policy says (see /admin/retry-policies);
+        attempts counts the failures, and dead_lettered_at is set on a payout held after running out
+        of them. Payouts exist for merchants with a payout_mode: hot_wallet payouts are sent by
+        OSPay, safe payouts are proposed to the merchant's Safe and stay PROPOSED until its owners
+        execute them. Admins see every merchant's, or one with merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/payouts",
+            query={"status": status, "batch_id": batch_id, "merchant_id": merchant_id},
+        )
+
+    def admin_retry_payout(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PayoutRecord:
+        """Retry a held payout
+
+        Sends or proposes again a QUEUED payout held after running out of attempts under the payout
+        retry policy, with its attempts counted from zero. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/payouts/{quote(id, safe='')}/retry",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_list_conversions(
+        self,
+        *,
+        status: Optional[str] = None,
+        batch_id: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.ConversionRecord]:
+        """List settlement conversions
+
+        Returns the most recent conversions (newest first), optionally filtered by status (QUEUED,
+        SUBMITTED, COMPLETED, FAILED) or batch_id. A conversion is queued at settlement when the
+        merchant's settlement_asset or settlement_chain differs from what was received;
+        quoted_out_minor and min_out_minor are the provider's quote, min_out_minor being the floor
+        set by max_slippage_bps. Admins see every merchant's, or one with merchant_id.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/conversions",
+            query={"status": status, "batch_id": batch_id, "merchant_id": merchant_id},
+        )
+
+    def admin_retry_conversion(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.ConversionRecord:
+        """Retry a failed conversion
+
+        Puts a FAILED conversion back in the queue; the next dispatcher pass quotes and sends it
+        again. Check first that the input is back in the hot wallet: a bridge that failed mid-route
+        may still hold it. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/conversions/{quote(id, safe='')}/retry",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_offramp_kyc(self, *, merchant_id: Optional[str] = None) -> m.KycResp:
+        """Show off-ramp KYC status
+
+        Returns the merchant's KYC status at the off-ramp partner (NOT_STARTED, PENDING, APPROVED or
+        REJECTED), fetched fresh from the partner. KYC itself happens with the partner; an
+        administrator links the merchant to the partner's customer and bank account IDs in the
+        merchant settings (offramp_customer_id, offramp_bank_account_id). Admins pass merchant_id.
+        """
+        return self._request("GET", "/v1/admin/offramp/kyc", query={"merchant_id": merchant_id})
+
+    def admin_list_fiat_payouts(
+        self,
+        *,
+        status: Optional[str] = None,
+        merchant_id: Optional[str] = None,
+    ) -> List[m.FiatPayoutRecord]:
+        """Request or list fiat payouts
+
+        POST converts part of the merchant's settled balance of asset into a bank payout through the
+        off-ramp partner; it needs the primary API key and approved KYC. The amount is reserved at
+        once (ledger offramp_pending); the hot wallet then sends the crypto on chain to the
+        partner's deposit address, and the payout becomes PAID once the partner has paid the bank,
+        with the fiat amount and rate it applied. The settled balance is settlement batches and
+        conversions into the asset less what was paid out or converted since. GET lists fiat
+        payouts, newest first.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/offramp/payouts",
+            query={"status": status, "merchant_id": merchant_id},
+        )
+
+    def admin_payout_estimate(
+        self,
+        *,
+        chain: Optional[str] = None,
+        transfers: Optional[int] = None,
+    ) -> m.PayoutEstimateResp:
+        """Estimate payout gas costs
+
+        Returns each chain's current gas price from its RPC endpoint and what the next payouts would
+        cost: the fee of one token transfer, of sending them one by one, and of a single multi-send
+        batch. transfers defaults to the number of settlement payouts currently due on the chain
+        (merchant/asset pairs with paid, unsettled orders). A chain whose RPC endpoint cannot be
+        reached is listed with an error; asking for that chain alone returns 502.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/payouts/estimate",
+            query={"chain": chain, "transfers": transfers},
+        )
+
+    def admin_schedulers(self) -> List[m.SchedulerStatus]:
+        """List background schedulers
+
+        Lists the background schedulers started by this instance with their schedule and next run,
+        whether they are paused or running, and the outcome of their last run: when it started, how
+        long it took, the rows it processed and its error. skipped counts the runs this instance
+        left out because another instance was running the scheduler. Admin only.
+        """
+        return self._request("GET", "/v1/admin/schedulers")
+
+    def admin_pause_scheduler(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.SchedulerStatus:
+        """Pause a scheduler
+
+        Stops a scheduler's runs until it is resumed; a run in progress finishes. The paused state
+        lasts until the process restarts. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/schedulers/{quote(id, safe='')}/pause",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_resume_scheduler(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.SchedulerStatus:
+        """Resume a scheduler
+
+        Lets a paused scheduler run again on its interval. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/schedulers/{quote(id, safe='')}/resume",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_run_scheduler(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.SchedulerStatus:
+        """Run a scheduler now
+
+        Starts a run of the scheduler now, even when it is paused, and returns without waiting for
+        it; poll /admin/schedulers for the outcome. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/schedulers/{quote(id, safe='')}/run",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_jobs(
+        self,
+        *,
+        type: Optional[str] = None,
+        status: Optional[str] = None,
+        limit: Optional[int] = None,
+    ) -> List[m.JobRecord]:
+        """List background jobs
+
+        Returns queued background jobs, most recently updated first, optionally filtered by type
+        (e.g. payment_verification) and status (PENDING, RUNNING, SUCCEEDED, DEAD), with their
+        payload, attempts, next run and last error. DEAD jobs failed permanently or ran out of
+        attempts; retry them with POST /admin/jobs/retry. Admin only.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/jobs",
+            query={"type": type, "status": status, "limit": limit},
+        )
+
+    def admin_retry_policies(self) -> List[m.RetryPolicyResp]:
+        """List retry policies
+
+        Returns the retry policy of each kind of background work: verification (payment_verification
+        jobs), webhook (webhook deliveries) and payout (sending or proposing queued payouts). A
+        failed attempt is retried after base_delay, doubling up to max_delay, with up to jitter of
+        the delay added at random, until max_attempts. Work that runs out of attempts is kept with
+        dead_letter keep (DEAD jobs, DEAD_LETTER events, payouts held until retried) or given up
+        with discard (jobs deleted, events DISCARDED, payouts FAILED). source says whether the
+        policy is the default, from RETRY_POLICY_<TYPE> or set through the admin API. Admin only.
+        """
+        return self._request("GET", "/v1/admin/retry-policies")
+
+    def admin_update_retry_policy(
+        self,
+        id: str,
+        body: m.RetryPolicyUpdateReq,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.RetryPolicyResp:
+        """Change a retry policy
+
+        Sets the retry policy of verification, webhook or payout work. Fields left out keep their
+        current value. The policy is stored in the database, takes precedence over
+        RETRY_POLICY_<TYPE>, applies from the next failed attempt on and reaches every instance
+        within 30 seconds. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/retry-policies/{quote(id, safe='')}",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_reset_retry_policy(
+        self,
+        id: str,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.RetryPolicyResp:
+        """Reset a retry policy
+
+        Drops the policy set through the admin API for verification, webhook or payout work, going
+        back to RETRY_POLICY_<TYPE> or the default. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/retry-policies/{quote(id, safe='')}/reset",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_retry_job(self, id: str, *, idempotency_key: Optional[str] = None) -> m.JobRecord:
+        """Retry a dead job
+
+        Queues a DEAD job again to run now, with its attempts counted from zero. Admin only.
+        """
+        return self._request(
+            "POST",
+            f"/v1/admin/jobs/{quote(id, safe='')}/retry",
+            idempotency_key=idempotency_key,
+        )
+
+    def admin_run_retention(self, *, idempotency_key: Optional[str] = None) -> m.RetentionResult:
+        """Run the retention job now
+
+        Archives terminal orders, refunds and ledger rows past RETENTION_MONTHS and prunes delivered
+        outbox events past OUTBOX_RETENTION_DAYS, into outbox_events_archive with OUTBOX_ARCHIVE=on.
+        Archived ledger rows are replaced by BALANCE_CARRIED entries, so balances do not change.
+        Admin only.
+        """
+        return self._request("POST", "/v1/admin/retention/run", idempotency_key=idempotency_key)
+
+    def admin_backup(self, *, idempotency_key: Optional[str] = None) -> m.BackupResp:
+        """Snapshot the database
+
+        Writes a consistent, integrity-checked copy of the live database to BACKUP_DIR (default
+        ./backups). Restore it with `server restore <file>` while the server is stopped. Admin only.
+        """
+        return self._request("POST", "/v1/admin/backup", idempotency_key=idempotency_key)
+
+    def admin_privacy_export(
+        self,
+        *,
+        customer_wallet_address: Optional[str] = None,
+        customer_email: Optional[str] = None,
+    ) -> m.PrivacyExportResp:
+        """Export a customer's data
+
+        Returns every order (with refunds) tied to the given customer wallet and/or email, the
+        overpayments of those orders or sent from the customer's wallets, and the transfers from
+        those wallets to watched addresses, within the authenticated merchant. Admins see all
+        merchants. The export is recorded in the audit log.
+        """
+        return self._request(
+            "GET",
+            "/v1/admin/privacy/export",
+            query={
+                "customer_wallet_address": customer_wallet_address,
+                "customer_email": customer_email,
+            },
+        )
+
+    def admin_privacy_erasure(
+        self,
+        body: m.PrivacySubject,
+        *,
+        idempotency_key: Optional[str] = None,
+    ) -> m.PrivacyErasureResp:
+        """Erase a customer's data
+
+        Pseudonymizes the customer's wallet address and removes email and metadata from every
+        matching order within the authenticated merchant (admins: all merchants), pseudonymizes the
+        sender of the overpayments of those orders or sent from the customer's wallets and of the
+        transfers from those wallets to watched addresses, and deletes the matching customer
+        profiles. Amounts, statuses, tx hashes and ledger entries are kept. An erased overpayment
+        can no longer be refunded, and the erasure fails with 409 while one of them is being
+        refunded. The erasure is recorded in the audit log.
+        """
+        return self._request(
+            "POST",
+            "/v1/admin/privacy/erasure",
+            body=body,
+            idempotency_key=idempotency_key,
+        )
+
+    def problem_catalog(self) -> List[m.ProblemCatalogEntry]:
+        """List error codes
+
+        Returns the catalog of error codes used in problem+json responses.
+        """
+        return self._request("GET", "/v1/problems")
+
+    def problem(self, code: str) -> m.ProblemCatalogEntry:
+        """Get an error code
+
+        Returns the catalog entry of one error code; the type URI of a problem+json response points
+        here.
+        """
+        return self._request("GET", f"/v1/problems/{quote(code, safe='')}")
+
+    def event_types(self) -> m.EventCatalogResp:
+        """List webhook event types
+
+        Returns every event type with its payload version and a JSON Schema (draft 2020-12) of its
+        data, generated from the types the server encodes, plus the schema of the delivery envelope.
+        A version changes only when a payload changes incompatibly.
+        """
+        return self._request("GET", "/v1/events/types")
@@ -0,0 +1,1699 @@
+# Code generated by sdkgen from docs/swagger.json. DO NOT EDIT.
+
+"""Request and response bodies of the OSPay API.
+
+Bodies are plain dicts decoded from JSON; these TypedDicts describe them for type checkers.
+"""
+
+from typing import Any, Dict, List, Literal, NotRequired, Optional, TypedDict
+
+
+class AcceptedAsset(TypedDict):
+    asset: str
+    chain: str
+
+
+class AddressTransfer(TypedDict):
+    id: str
+    watch_id: str
+    chain: str
+    asset: str
+    # the watched address, which received the transfer
+    address: str
+    from_address: str
+    amount_minor: str
+    tx_hash: str
+    log_index: int
+    block_number: int
+    # when the watcher found it
+    created_at: str
+
+
+class ApiKeyCreateReq(TypedDict):
+    label: NotRequired[str]
+    # space-separated, same vocabulary as OAuth scopes
+    scope: NotRequired[str]
+
+
+class ApiKeyCreateResp(TypedDict):
+    id: str
+    api_key: str
+    label: str
+    scope: str
+
+
+class ApiKeyInfo(TypedDict):
+    id: str
+    label: str
+    scope: str
+    created_at: str
+    revoked_at: NotRequired[str]
+
+
+class ApiKeyUsage(TypedDict):
+    # "primary" for the merchant's own key
+    id: str
+    label: str
+    scope: str
+    created_at: str
+    revoked_at: NotRequired[str]
+    # null if never used
+    last_used_at: Optional[str]
+    last_used_ip: Optional[str]
+    request_count: int
+    # the most recently seen IPs, at most maxKeySources
+    sources: List["KeySource"]
+
+
+class AssetBalance(TypedDict):
+    asset: str
+    chain: NotRequired[str]
+    # settled funds (settlement bucket)
+    available_minor: str
+    # paid orders not yet settled (merchant bucket)
+    pending_minor: str
+    # frozen by open disputes or balance holds, or reserved for fiat payouts
+    held_minor: str
+    # overpayments to return to customers (overpayment bucket)
+    refundable_minor: str
+
+
+class AssetReconciliation(TypedDict):
+    chain: str
+    asset: str
+    # BooksMinor is what the custody wallets should hold: the custody buckets less what was sent out
+    # and is not booked yet (payouts SENT or EXECUTED, fiat payouts FUNDED).
+    books_minor: str
+    # net of each custody bucket
+    buckets: Dict[str, str]
+    paid_out_minor: str
+    offramp_funded_minor: str
+    # sum of the wallets' balances
+    chain_minor: NotRequired[str]
+    wallets: List["WalletBalance"]
+    # DriftMinor is chain_minor - books_minor: negative when the wallets hold less than the books.
+    drift_minor: NotRequired[str]
+    # of books_minor; omitted when the books are zero or the drift is vast
+    drift_bps: NotRequired[int]
+    # beyond the tolerance
+    drifting: bool
+    # a wallet balance could not be read
+    error: NotRequired[str]
+
+
+class AuditEntry(TypedDict):
+    id: str
+    actor: str
+    merchant_id: NotRequired[str]
+    order_id: NotRequired[str]
+    action: str
+    detail: Dict[str, Any]
+    created_at: str
+
+
+class BackupResp(TypedDict):
+    path: str
+    size_bytes: int
+    created_at: str
+
+
+class BalanceHold(TypedDict):
+    id: str
+    merchant_id: str
+    asset: str
+    chain: str
+    amount_minor: str
+    reason: str
+    # ACTIVE, RELEASED or EXPIRED
+    status: str
+    placed_by: str
+    platform_id: NotRequired[str]
+    expires_at: NotRequired[str]
+    # kept back from settlements while active
+    withheld_minor: str
+    created_at: str
+    released_at: NotRequired[str]
+    released_by: NotRequired[str]
+    release_reason: NotRequired[str]
+    # settled the withheld part
+    release_batch_id: NotRequired[str]
+
+
+class BalancesResp(TypedDict):
+    merchant_id: str
+    balances: List["AssetBalance"]
+
+
+class BatchGetOrdersReq(TypedDict):
+    order_ids: List[str]
+
+
+class BatchGetOrdersResp(TypedDict):
+    # in the order of order_ids, each once
+    orders: List["OrderGetResp"]
+    # IDs of no order of the merchant
+    not_found: List[str]
+
+
+BulkRefundReq = Dict[str, Any]
+
+
+class ChainHealth(TypedDict):
+    chain: str
+    # ok | lagging | unreachable
+    status: str
+    # host of the RPC endpoint in use
+    provider: str
+    reachable: bool
+    # of fetching the latest block
+    latency_ms: NotRequired[int]
+    block_height: NotRequired[int]
+    block_time: NotRequired[str]
+    # how far the latest block trails the clock
+    lag_seconds: NotRequired[float]
+    # seconds between blocks
+    expected_block_time_seconds: float
+    # lag beyond which the chain is lagging
+    max_lag_seconds: float
+    error: NotRequired[str]
+
+
+class ChainHealthResp(TypedDict):
+    ok: bool
+    chains: List["ChainHealth"]
+
+
+class ChainTx(TypedDict):
+    id: str
+    chain: str
+    purpose: str
+    reference_id: NotRequired[str]
+    from_address: str
+    nonce: int
+    tx_hash: str
+    replaced_hashes: NotRequired[List[str]]
+    cancel_tx_hash: NotRequired[str]
+    # which of the hashes made it into a block
+    mined_tx_hash: NotRequired[str]
+    urgency: str
+    max_fee_wei: str
+    priority_fee_wei: NotRequired[str]
+    bumps: int
+    status: str
+    last_error: NotRequired[str]
+    submitted_at: str
+    created_at: str
+    confirmed_at: NotRequired[str]
+    # still pending after three times its profile's wait
+    stuck: NotRequired[bool]
+
+
+class ChainTxBumpReq(TypedDict):
+    # fee profile for the replacement; defaults to the transaction's own
+    urgency: NotRequired[str]
+
+
+class ColdSweep(TypedDict):
+    id: str
+    chain: str
+    asset: str
+    amount_minor: str
+    # the hot wallet
+    from_address: str
+    # the cold wallet
+    to_address: str
+    status: str
+    # hot wallet balance that went over the limit
+    balance_minor: str
+    limit_minor: str
+    keep_minor: str
+    tx_hash: NotRequired[str]
+    last_error: NotRequired[str]
+    # who approved or rejected it
+    decided_by: NotRequired[str]
+    created_at: str
+    updated_at: str
+
+
+class ConnectedBalance(TypedDict):
+    merchant_id: str
+    merchant_balance_minor: int
+    platform_fee_minor: int
+
+
+class ConnectedMerchant(TypedDict):
+    id: str
+    name: str
+    merchant_wallet_address: str
+    created_at: str
+
+
+class ConversionRecord(TypedDict):
+    id: str
+    batch_id: str
+    merchant_id: str
+    from_chain: str
+    from_asset: str
+    to_chain: str
+    to_asset: str
+    amount_in_minor: str
+    quoted_out_minor: NotRequired[str]
+    # the swap reverts below it
+    min_out_minor: NotRequired[str]
+    amount_out_minor: NotRequired[str]
+    max_slippage_bps: int
+    provider: NotRequired[str]
+    status: str
+    # the swap on from_chain
+    tx_hash: NotRequired[str]
+    # the delivery on to_chain
+    receive_tx_hash: NotRequired[str]
+    last_error: NotRequired[str]
+    created_at: str
+    updated_at: str
+
+
+class Coupon(TypedDict):
+    id: str
+    code: str
+    type: str
+    percent_off: NotRequired[int]
+    amount_off_minor: NotRequired[str]
+    asset: NotRequired[str]
+    max_redemptions: NotRequired[int]
+    redemptions: int
+    expires_at: NotRequired[str]
+    active: bool
+    created_at: str
+
+
+class CouponCreateReq(TypedDict):
+    code: str
+    type: Literal["percent", "fixed"]
+    # percent coupons
+    percent_off: NotRequired[int]
+    # fixed coupons
+    amount_off_minor: NotRequired[str]
+    # fixed coupons
+    asset: NotRequired[str]
+    # omitted: unlimited
+    max_redemptions: NotRequired[int]
+    # omitted: never
+    expires_at: NotRequired[str]
+
+
+class CouponUpdateReq(TypedDict):
+    max_redemptions: NotRequired[int]
+    expires_at: NotRequired[str]
+    active: NotRequired[bool]
+
+
+class CustomerDetailResp(TypedDict):
+    id: str
+    wallet_address: str
+    payment_count: int
+    first_paid_at: str
+    last_paid_at: str
+    # paid amount per asset
+    totals_minor: Dict[str, str]
+    payments: List["OrderGetResp"]
+
+
+class CustomerListResp(TypedDict):
+    customers: List["CustomerRecord"]
+    # pass as cursor to fetch the next page
+    next_cursor: NotRequired[str]
+
+
+class CustomerRecord(TypedDict):
+    id: str
+    wallet_address: str
+    payment_count: int
+    first_paid_at: str
+    last_paid_at: str
+
+
+class DeadLetterEvent(TypedDict):
+    id: str
+    type: str
+    created_at: str
+    aggregate_type: str
+    aggregate_id: str
+    sequence: NotRequired[int]
+    dead_lettered_at: NotRequired[str]
+    attempts: int
+    last_error: NotRequired[str]
+    replay_of: NotRequired[str]
+    data: Dict[str, Any]
+
+
+class DeadLetterListResp(TypedDict):
+    events: List["DeadLetterEvent"]
+    # pass as cursor to fetch the next page
+    next_cursor: NotRequired[str]
+
+
+class DeadLetterRequeueReq(TypedDict):
+    # at most 1000
+    event_ids: NotRequired[List[str]]
+    # every dead-lettered event, up to 1000 per request
+    all: NotRequired[bool]
+
+
+class DeadLetterRequeueResp(TypedDict):
+    requeued: int
+
+
+DisputeCreateReq = Dict[str, Any]
+
+
+class DisputeEvidence(TypedDict):
+    id: str
+    # "merchant" or "admin"
+    author: str
+    note: str
+    created_at: str
+
+
+class DisputeEvidenceReq(TypedDict):
+    note: str
+
+
+class DisputeRecord(TypedDict):
+    id: str
+    order_id: str
+    merchant_id: str
+    asset: str
+    amount_minor: str
+    reason: str
+    # OPEN | WON | LOST
+    status: str
+    created_at: str
+    resolved_at: NotRequired[str]
+    evidence: NotRequired[List["DisputeEvidence"]]
+
+
+class DisputeResolveReq(TypedDict):
+    # "won" (merchant keeps the funds) or "lost" (funds go back to the customer)
+    outcome: NotRequired[str]
+    note: NotRequired[str]
+
+
+class DormantKey(TypedDict):
+    merchant_id: str
+    # "primary" for the merchant's own key
+    key_id: str
+    label: str
+    created_at: str
+    # null if never used
+    last_used_at: Optional[str]
+    request_count: int
+
+
+ErrorCode = Literal[
+    "bad_request",
+    "internal_error",
+    "db_error",
+    "db_not_initialized",
+    "method_not_allowed",
+    "invalid_json",
+    "missing_fields",
+    "missing_query_param",
+    "missing_idempotency_key",
+    "invalid_metadata",
+    "invalid_amount",
+    "invalid_limit",
+    "limit_exceeded",
+    "api_key_required",
+    "invalid_api_key",
+    "invalid_token",
+    "invalid_platform_key",
+    "api_key_not_found",
+    "primary_key_required",
+    "insufficient_scope",
+    "invalid_scope",
+    "admin_disabled",
+    "invalid_admin_key",
+    "admin_required",
+    "invalid_client",
+    "invalid_redirect_uri",
+    "merchant_not_found",
+    "merchant_mismatch",
+    "merchant_not_connected",
+    "missing_wallet_address",
+    "invalid_application_fee",
+    "order_not_found",
+    "order_not_paid",
+    "order_not_refundable",
+    "order_not_in_review",
+    "order_not_disputable",
+    "onchain_verification_failed",
+    "customer_wallet_unknown",
+    "already_refunded",
+    "cannot_refund_settled",
+    "missing_refund_amount",
+    "invalid_refund_amount",
+    "refund_exceeds_order",
+    "invalid_refund_tx",
+    "refund_tx_already_used",
+    "refund_verification_failed",
+    "refund_not_found",
+    "refund_not_pending",
+    "approver_must_differ",
+    "invalid_decision",
+    "dispute_not_found",
+    "dispute_open",
+    "dispute_closed",
+    "dispute_already_open",
+    "invalid_dispute_amount",
+    "invalid_outcome",
+    "retention_disabled",
+    "invalid_cursor",
+    "invalid_idempotency_key",
+    "idempotency_key_reused",
+    "idempotency_in_progress",
+    "invalid_webhook_url",
+    "webhook_not_configured",
+    "invalid_event_type",
+    "invalid_time_range",
+    "order_expired",
+    "order_not_pending",
+    "extension_limit_exceeded",
+    "customer_not_found",
+    "unsupported_chain",
+    "rpc_unavailable",
+    "gas_tank_not_configured",
+    "transaction_not_found",
+    "transaction_not_pending",
+    "fee_cap_reached",
+    "invalid_payout_mode",
+    "conversion_not_found",
+    "conversion_not_failed",
+    "invalid_settlement_target",
+    "offramp_not_configured",
+    "kyc_not_approved",
+    "insufficient_balance",
+    "invalid_currency",
+    "offramp_unavailable",
+    "rate_unavailable",
+    "stale_rate",
+    "unsupported_rate_pair",
+    "invalid_timezone",
+    "invalid_metric",
+    "scheduler_not_found",
+    "coupon_not_found",
+    "coupon_exists",
+    "coupon_invalid",
+    "invalid_line_items",
+    "hot_wallet_unavailable",
+    "refund_job_not_found",
+    "status_override_not_allowed",
+    "tx_already_used",
+    "invalid_wallet_address",
+    "wallet_challenge_not_found",
+    "wallet_challenge_expired",
+    "wallet_proof_invalid",
+    "wallet_proof_unsupported",
+    "merchant_pending_approval",
+    "merchant_rejected",
+    "merchant_already_decided",
+    "auth_blocked",
+    "auth_ban_not_found",
+    "validation_failed",
+    "request_too_large",
+    "unsupported_media_type",
+    "payment_intent_not_found",
+    "payment_intent_finalized",
+    "job_not_found",
+    "job_not_dead",
+    "retry_policy_not_found",
+    "invalid_retry_policy",
+    "payout_not_found",
+    "payout_not_dead_lettered",
+    "verification_queue_full",
+    "reconciliation_not_configured",
+    "attestation_not_found",
+    "sweep_not_found",
+    "sweep_not_pending",
+    "address_already_watched",
+    "watch_limit_reached",
+    "watched_address_not_found",
+    "invalid_organization_key",
+    "organization_role_forbidden",
+    "member_not_found",
+    "member_already_exists",
+    "owner_not_removable",
+    "merchant_not_in_organization",
+    "merchant_in_organization",
+    "merchant_header_required",
+    "invalid_webhook_secret",
+    "invalid_tag",
+    "too_many_tags",
+    "overpayment_not_found",
+    "overpayment_not_open",
+    "asset_not_accepted",
+    "invalid_accepted_assets",
+    "invalid_rate_quote",
+    "hold_not_found",
+    "hold_not_active",
+    "hold_exceeds_balance",
+    "ledger_transaction_not_found",
+    "merchant_deleted",
+    "merchant_not_deleted",
+    "order_not_deleted",
+    "order_has_payment",
+    "idempotency_response_withheld",
+    "overpayment_refund_in_flight",
+    "overpayment_sender_erased",
+    "not_found",
+]
+
+
+class EventCatalogResp(TypedDict):
+    # schema of the delivered body
+    envelope: Dict[str, Any]
+    types: List["EventTypeInfo"]
+
+
+EventReplayReq = TypedDict(
+    "EventReplayReq",
+    {
+        "order_id": NotRequired[str],
+        "from": NotRequired[str],
+        "to": NotRequired[str],
+    },
+)
+
+
+EventReplayResp = TypedDict(
+    "EventReplayResp",
+    {
+        "replayed": int,
+        "order_id": NotRequired[str],
+        "from": NotRequired[str],
+        "to": NotRequired[str],
+    },
+)
+
+
+class EventTypeInfo(TypedDict):
+    type: str
+    version: int
+    description: str
+    schema: Dict[str, Any]
+
+
+class FiatPayoutRecord(TypedDict):
+    id: str
+    merchant_id: str
+    provider: str
+    # the partner's reference
+    provider_payout_id: NotRequired[str]
+    chain: str
+    asset: str
+    amount_minor: str
+    fiat_currency: str
+    # in cents (the currency's minor unit)
+    fiat_amount_minor: NotRequired[str]
+    rate: NotRequired[str]
+    deposit_address: NotRequired[str]
+    tx_hash: NotRequired[str]
+    status: str
+    last_error: NotRequired[str]
+    created_at: str
+    updated_at: str
+
+
+class FiatPayoutReq(TypedDict):
+    chain: str
+    asset: str
+    amount_minor: str
+    # ISO 4217; default USD
+    fiat_currency: NotRequired[str]
+
+
+class FiatPricing(TypedDict):
+    # price in fiat_currency, as a decimal
+    fiat_amount: str
+    fiat_currency: str
+    rate_quote: "RateResp"
+
+
+class FieldError(TypedDict):
+    # JSON path, e.g. "line_items[0].name"
+    field: str
+    # required | type | max | min | oneof | asset | chain | amount | rfc3339
+    rule: str
+    message: str
+
+
+class GasEstimate(TypedDict):
+    chain: str
+    native_asset: NotRequired[str]
+    gas_price_wei: NotRequired[str]
+    # EIP-1559 chains only
+    base_fee_wei: NotRequired[str]
+    # EIP-1559 chains only
+    priority_fee_wei: NotRequired[str]
+    transfers: int
+    transfer_gas: int
+    fee_per_transfer_wei: NotRequired[str]
+    # in the native asset, e.g. "0.000195"
+    fee_per_transfer: NotRequired[str]
+    individual_fee_wei: NotRequired[str]
+    batch_gas: int
+    batch_fee_wei: NotRequired[str]
+    batch_savings_wei: NotRequired[str]
+    # "batch" or "individual"
+    cheaper: NotRequired[str]
+    fetched_at: NotRequired[str]
+    error: NotRequired[str]
+
+
+class GasTankResp(TypedDict):
+    chains: List["GasTankStatus"]
+
+
+class GasTankStatus(TypedDict):
+    chain: str
+    native_asset: NotRequired[str]
+    wallet_address: str
+    balance_wei: NotRequired[str]
+    # in the native asset
+    balance: NotRequired[str]
+    low_water_wei: NotRequired[str]
+    low: bool
+    checked_at: str
+    # settlement batches and completed refunds on the chain
+    payouts_7d: int
+    fee_per_transfer_wei: NotRequired[str]
+    daily_burn_wei: NotRequired[str]
+    # omitted without recent payouts
+    runway_days: NotRequired[float]
+    error: NotRequired[str]
+
+
+class IpBan(TypedDict):
+    ip: str
+    # in the window that led to the ban
+    failures: int
+    first_failed_at: str
+    last_failed_at: str
+    # how many times the IP was banned, this one included
+    bans: int
+    banned_until: str
+
+
+class JobRecord(TypedDict):
+    id: str
+    type: str
+    # PENDING, RUNNING, SUCCEEDED or DEAD
+    status: str
+    payload: Dict[str, Any]
+    attempts: int
+    # not before; the next retry of a failed job
+    run_at: str
+    locked_by: Optional[str]
+    last_error: Optional[str]
+    created_at: str
+    updated_at: str
+    finished_at: Optional[str]
+
+
+class KeySource(TypedDict):
+    ip: str
+    request_count: int
+    first_used_at: str
+    last_used_at: str
+
+
+class KycResp(TypedDict):
+    provider: str
+    customer_id: NotRequired[str]
+    bank_account_id: NotRequired[str]
+    kyc_status: str
+    checked_at: NotRequired[str]
+
+
+class LedgerExportLine(TypedDict):
+    id: str
+    merchant_id: str
+    order_id: Optional[str]
+    asset: str
+    chain: Optional[str]
+    amount_minor: str
+    bucket: str
+    # credit or debit
+    direction: str
+    event_type: str
+    tx_hash: Optional[str]
+    # refund, batch, conversion, ... that produced the entry
+    reference_id: Optional[str]
+    # rate quote of a fiat-priced order, see /rates/history
+    rate_quote_id: Optional[str]
+    created_at: str
+    # TransactionGroupID is shared by the legs of one double entry, see /ledger/transactions
+    transaction_group_id: Optional[str]
+
+
+class LedgerTransaction(TypedDict):
+    transaction_group_id: str
+    merchant_id: str
+    event_type: str
+    created_at: str
+    entries: List["LedgerExportLine"]
+
+
+class LineItem(TypedDict):
+    name: NotRequired[str]
+    quantity: NotRequired[int]
+    unit_amount_minor: NotRequired[str]
+    amount_minor: NotRequired[str]
+
+
+class MerchantCreateReq(TypedDict):
+    """Request to create a new merchant"""
+    name: str
+    # an address, or an ENS name
+    merchant_wallet_address: str
+
+
+class MerchantCreateResp(TypedDict):
+    """Response after creating a merchant"""
+    id: str
+    api_key: str
+    merchant_wallet_address: str
+    # WalletENSName is the ENS name merchant_wallet_address was resolved from, if one was given.
+    wallet_ens_name: NotRequired[str]
+    # Status is PENDING_APPROVAL when new merchants wait for an administrator; the API key works
+    # once it is ACTIVE.
+    status: str
+
+
+class MerchantDecisionReq(TypedDict):
+    # shown to the merchant; required to reject
+    reason: NotRequired[str]
+
+
+class MerchantRecord(TypedDict):
+    id: str
+    name: str
+    merchant_wallet_address: str
+    platform_id: NotRequired[str]
+    organization_id: NotRequired[str]
+    # ACTIVE, PENDING_APPROVAL or REJECTED
+    status: str
+    reason: NotRequired[str]
+    decided_by: NotRequired[str]
+    decided_at: NotRequired[str]
+    created_at: str
+    deleted_at: NotRequired[str]
+    deleted_by: NotRequired[str]
+
+
+class MerchantSettings(TypedDict):
+    refund_approval_required: NotRequired[bool]
+    # LatePaymentReview holds payments for expired orders in LATE_PAYMENT instead of crediting them.
+    late_payment_review: NotRequired[bool]
+    # Velocity limits; "0" / 0 removes the limit. Only an administrator can change them.
+    max_order_amount_minor: NotRequired[str]
+    # per asset, per day in Timezone
+    max_daily_volume_minor: NotRequired[str]
+    max_wallet_orders_per_hour: NotRequired[int]
+    # PayoutMode sends settlements on-chain: "hot_wallet" or "safe" ("" back to ledger only). Only
+    # an administrator can change it or the Safe address.
+    payout_mode: NotRequired[str]
+    payout_safe_address: NotRequired[str]
+    # Settlements received in another asset or on another chain are converted before payout (""
+    # keeps what was received). MaxSlippageBps limits conversion slippage; default 50.
+    settlement_asset: NotRequired[str]
+    settlement_chain: NotRequired[str]
+    max_slippage_bps: NotRequired[int]
+    # The merchant's customer and bank account at the off-ramp partner, for fiat payouts. Only an
+    # administrator can link them; KYCStatus is read only.
+    offramp_customer_id: NotRequired[str]
+    offramp_bank_account_id: NotRequired[str]
+    kyc_status: NotRequired[str]
+    # Timezone (IANA, e.g. "Europe/Berlin") sets where the merchant's days start for daily limits,
+    # reports and settlement; with one set, orders settle by local calendar day. "" is UTC.
+    timezone: NotRequired[str]
+    # AcceptedAssets restricts the asset and chain pairs of new orders; empty accepts any.
+    accepted_assets: NotRequired[List["AcceptedAsset"]]
+    # ClawbackMaxBps is the most of each settlement withheld while the balance is negative; default
+    # 10000 (all of it), 0 withholds nothing. Only an administrator can change it.
+    clawback_max_bps: NotRequired[int]
+
+
+class Mispayment(TypedDict):
+    # wrong_token | wrong_chain
+    reason: str
+    asset: str
+    chain: str
+    # in the smallest unit of the token sent
+    amount_minor: str
+    from_address: str
+    tx_hash: str
+
+
+class NegativeBalance(TypedDict):
+    id: str
+    merchant_id: str
+    asset: str
+    chain: str
+    # OPEN or RECOVERED
+    status: str
+    # what the merchant owes, positive; 0 once recovered
+    amount_minor: str
+    # the most it owed
+    peak_minor: str
+    opened_at: str
+    updated_at: str
+    recovered_at: NotRequired[str]
+
+
+class OauthAuthorizeReq(TypedDict):
+    client_id: NotRequired[str]
+    # space-separated, e.g. "orders:write balances:read"
+    scope: NotRequired[str]
+    redirect_uri: NotRequired[str]
+    state: NotRequired[str]
+
+
+class OauthAuthorizeResp(TypedDict):
+    code: str
+    expires_in: int
+    # redirect_uri with code and state appended
+    redirect_uri: NotRequired[str]
+
+
+class OauthClientCreateReq(TypedDict):
+    name: str
+    redirect_uri: NotRequired[str]
+
+
+class OauthClientCreateResp(TypedDict):
+    client_id: str
+    client_secret: str
+    redirect_uri: NotRequired[str]
+
+
+class OauthTokenResp(TypedDict):
+    access_token: str
+    token_type: str
+    expires_in: int
+    refresh_token: str
+    scope: str
+
+
+class OnchainReconResp(TypedDict):
+    checked_at: str
+    tolerance_bps: int
+    assets: List["AssetReconciliation"]
+
+
+class OrderCreateReq(TypedDict):
+    merchant_id: NotRequired[str]
+    # String to handle large 18-decimal numbers
+    amount_minor: NotRequired[str]
+    # e.g., "USDC"
+    asset: str
+    # e.g., "POLYGON"
+    chain: str
+    idempotency_key: NotRequired[str]
+    # CustomerWalletAddress is optional; when given, per-wallet velocity limits apply at creation.
+    customer_wallet_address: NotRequired[str]
+    customer_email: NotRequired[str]
+    # free-form JSON object
+    metadata: NotRequired[Dict[str, Any]]
+    # the merchant's own order reference
+    external_order_id: NotRequired[str]
+    # CouponCode is one of the merchant's coupons; its discount is taken off amount_minor.
+    coupon_code: NotRequired[str]
+    # LineItems are what is being bought; amount_minor may then be omitted and defaults to their
+    # sum.
+    line_items: NotRequired[List["LineItem"]]
+    # WebhookURL receives the order's events instead of the merchant's webhook URL, signed with
+    # WebhookSecret; one is generated when it is omitted.
+    webhook_url: NotRequired[str]
+    webhook_secret: NotRequired[str]
+    # RateQuoteID and FiatAmount record a fiat price: the quote_id of the /rates quote between asset
+    # and the fiat currency that amount_minor was converted with, and the price in that currency.
+    # The quote is kept on the order and its ledger entries.
+    rate_quote_id: NotRequired[str]
+    fiat_amount: NotRequired[str]
+
+
+class OrderCreateResp(TypedDict):
+    order_id: str
+    deposit_address: str
+    status: str
+    # amount due, after any discount
+    amount_minor: str
+    # taken off by coupon_code
+    discount_minor: NotRequired[str]
+    # generated for webhook_url; only in the response that created the order
+    webhook_secret: NotRequired[str]
+
+
+class OrderExtendReq(TypedDict):
+    # added to the later of now and the current expiry; defaults to 30
+    minutes: NotRequired[int]
+
+
+class OrderExtendResp(TypedDict):
+    order_id: str
+    status: str
+    expires_at: str
+
+
+class OrderGetResp(TypedDict):
+    id: str
+    merchant_id: str
+    # String to handle large 18-decimal numbers
+    amount_minor: str
+    asset: str
+    chain: str
+    status: str
+    deposit_address: str
+    tx_hash: NotRequired[str]
+    # block that mined the verified payment
+    confirmed_block: NotRequired[int]
+    # and its time
+    block_timestamp: NotRequired[str]
+    paid_at: NotRequired[str]
+    created_at: str
+    # PENDING orders become EXPIRED after this
+    expires_at: NotRequired[str]
+    # CustomerWalletAddress is the sender of the verified payment transfer; refunds go back to it.
+    customer_wallet_address: NotRequired[str]
+    customer_email: NotRequired[str]
+    metadata: NotRequired[Dict[str, Any]]
+    external_order_id: NotRequired[str]
+    # Risk fields are set when the payment is confirmed; REVIEW orders wait for an admin decision.
+    risk_reason: NotRequired[str]
+    risk_score: NotRequired[int]
+    risk_factors: NotRequired[str]
+    application_fee_minor: NotRequired[str]
+    # CouponCode and DiscountMinor are set when a coupon was redeemed; amount_minor is net of the
+    # discount.
+    coupon_code: NotRequired[str]
+    discount_minor: NotRequired[str]
+    # Mispayment is the transfer in the wrong token or on the wrong chain of a MISPAID order.
+    mispayment: NotRequired["Mispayment"]
+    # FiatPricing is the fiat price and rate quote of an order created with rate_quote_id.
+    fiat_pricing: NotRequired["FiatPricing"]
+    line_items: NotRequired[List["LineItem"]]
+    # in list, search and batch-get results
+    tags: NotRequired[List[str]]
+
+
+class OrderListResp(TypedDict):
+    orders: List["OrderGetResp"]
+    # pass as cursor to fetch the next page
+    next_cursor: NotRequired[str]
+
+
+class OrderNote(TypedDict):
+    id: str
+    order_id: str
+    # credential that wrote it, e.g. "key:primary" or "admin"
+    author: str
+    body: str
+    created_at: str
+
+
+class OrderNoteReq(TypedDict):
+    # at most maxNoteLength
+    body: str
+
+
+class OrderReviewReq(TypedDict):
+    # "approve" credits the payment, "reject" fails the order
+    decision: NotRequired[str]
+
+
+class OrderStatusOverrideReq(TypedDict):
+    status: Literal["PAID", "FAILED", "EXPIRED"]
+    # why, e.g. the block explorer link of a payment verified by hand
+    reason: NotRequired[str]
+    # the payment, recorded when forcing PAID
+    tx_hash: NotRequired[str]
+
+
+class OrderStatusOverrideResp(TypedDict):
+    order_id: str
+    override_id: str
+    previous_status: str
+    status: str
+    reason: str
+    # written to credit or reverse the payment
+    ledger_entries: int
+
+
+class OrderTagsReq(TypedDict):
+    tags: List[str]
+
+
+class OrderTagsResp(TypedDict):
+    order_id: str
+    # sorted
+    tags: List[str]
+
+
+class OrgAttachReq(TypedDict):
+    # the merchant's primary API key
+    api_key: str
+
+
+class OrgBalancesResp(TypedDict):
+    organization_id: str
+    # all merchants together, per asset and chain
+    totals: List["AssetBalance"]
+    merchants: List["OrgMerchantBalances"]
+
+
+class OrgCreateReq(TypedDict):
+    name: str
+    owner_email: str
+
+
+class OrgCreateResp(TypedDict):
+    id: str
+    name: str
+    # the owner
+    member_id: str
+    # the owner's; shown once
+    api_key: str
+
+
+class OrgMember(TypedDict):
+    id: str
+    email: str
+    role: str
+    created_at: str
+    # only when the member is added
+    api_key: NotRequired[str]
+
+
+class OrgMemberReq(TypedDict):
+    email: str
+    role: Literal["admin", "viewer"]
+
+
+class OrgMerchant(TypedDict):
+    id: str
+    name: str
+    merchant_wallet_address: str
+    status: str
+    created_at: str
+
+
+class OrgMerchantBalances(TypedDict):
+    merchant_id: str
+    name: str
+    balances: List["AssetBalance"]
+
+
+OrgReportResp = TypedDict(
+    "OrgReportResp",
+    {
+        "organization_id": str,
+        "from": str,
+        "to": str,
+        "totals": List["OrgReportRow"],
+        "merchants": List["OrgReportRow"],
+    },
+)
+
+
+class OrgReportRow(TypedDict):
+    # empty in totals
+    merchant_id: NotRequired[str]
+    asset: str
+    orders_created: int
+    orders_paid: int
+    paid_volume_minor: str
+    # completed refunds
+    refunded_minor: str
+
+
+class OverpaymentRecord(TypedDict):
+    id: str
+    order_id: str
+    merchant_id: str
+    asset: str
+    chain: str
+    amount_minor: str
+    tx_hash: str
+    # the refund goes back here
+    from_address: str
+    # CONFIRMING | REVIEW | OPEN | REFUND_QUEUED | REFUND_SENT | REFUNDED | REFUND_FAILED
+    status: str
+    confirmed_block: NotRequired[int]
+    # why screening flagged the sender
+    risk_reason: NotRequired[str]
+    refund_tx_hash: NotRequired[str]
+    last_error: NotRequired[str]
+    created_at: str
+    updated_at: str
+    refunded_at: NotRequired[str]
+
+
+class PaymentAttempt(TypedDict):
+    id: str
+    tx_hash: str
+    # pending | succeeded | failed
+    status: str
+    # wrong_amount | wrong_token | wrong_chain | wrong_recipient | no_transfer | tx_not_found |
+    # order_expired | already_paid | tx_already_used | multiple_senders | verification_error
+    failure_reason: NotRequired[str]
+    detail: NotRequired[str]
+    # times the hash was reported
+    submissions: int
+    created_at: str
+    updated_at: str
+
+
+class PaymentDetectedReq(TypedDict):
+    order_id: str
+    tx_hash: str
+    # optional override; if nil, use order.amount_minor (string for large numbers)
+    amount_minor: NotRequired[str]
+
+
+class PaymentDetectedResp(TypedDict):
+    order_id: str
+    status: str
+    message: str
+
+
+class PaymentIntent(TypedDict):
+    id: str
+    merchant_id: str
+    amount_minor: str
+    asset: str
+    chain: str
+    # REQUIRES_CAPTURE, CAPTURED or VOIDED
+    status: str
+    metadata: NotRequired[Dict[str, Any]]
+    external_order_id: NotRequired[str]
+    coupon_code: NotRequired[str]
+    # set by capture
+    order_id: NotRequired[str]
+    # before any coupon discount
+    captured_amount_minor: NotRequired[str]
+    void_reason: NotRequired[str]
+    created_at: str
+    updated_at: str
+    captured_at: NotRequired[str]
+    voided_at: NotRequired[str]
+
+
+class PaymentIntentCaptureReq(TypedDict):
+    # AmountMinor captures less than the intent's amount; omitted, all of it is captured.
+    amount_minor: NotRequired[str]
+    customer_wallet_address: NotRequired[str]
+    customer_email: NotRequired[str]
+
+
+class PaymentIntentCaptureResp(TypedDict):
+    payment_intent: "PaymentIntent"
+    order: "OrderCreateResp"
+
+
+class PaymentIntentCreateReq(TypedDict):
+    amount_minor: str
+    asset: str
+    chain: str
+    # copied to the order
+    metadata: NotRequired[Dict[str, Any]]
+    # copied to the order
+    external_order_id: NotRequired[str]
+    # CouponCode is checked now and redeemed when the intent is captured.
+    coupon_code: NotRequired[str]
+
+
+class PaymentIntentUpdateReq(TypedDict):
+    amount_minor: NotRequired[str]
+    metadata: NotRequired[Dict[str, Any]]
+    external_order_id: NotRequired[str]
+
+
+class PaymentIntentVoidReq(TypedDict):
+    reason: NotRequired[str]
+
+
+class PayoutEstimateResp(TypedDict):
+    estimates: List["GasEstimate"]
+
+
+class PayoutRecord(TypedDict):
+    id: str
+    batch_id: str
+    merchant_id: str
+    chain: str
+    asset: str
+    amount_minor: str
+    to_address: str
+    mode: str
+    status: str
+    safe_address: NotRequired[str]
+    safe_nonce: NotRequired[int]
+    safe_tx_hash: NotRequired[str]
+    tx_hash: NotRequired[str]
+    last_error: NotRequired[str]
+    # Failed tries to send or propose the payout, retried as the payout retry policy says
+    attempts: int
+    next_attempt_at: NotRequired[str]
+    # out of attempts, held until retried
+    dead_lettered_at: NotRequired[str]
+    created_at: str
+    updated_at: str
+
+
+class PlaceHoldReq(TypedDict):
+    merchant_id: str
+    asset: str
+    chain: str
+    amount_minor: str
+    # e.g. "risk review"
+    reason: str
+    # omitted: held until released
+    expires_at: NotRequired[str]
+
+
+class PlatformBalancesResp(TypedDict):
+    platform_id: str
+    asset: str
+    platform_fee_total_minor: int
+    merchants: List["ConnectedBalance"]
+
+
+class PlatformCreateReq(TypedDict):
+    name: str
+
+
+class PlatformCreateResp(TypedDict):
+    id: str
+    api_key: str
+
+
+class PlatformOrderCreateReq(TypedDict):
+    merchant_id: str
+    # String to handle large 18-decimal numbers
+    amount_minor: NotRequired[str]
+    asset: str
+    chain: str
+    idempotency_key: NotRequired[str]
+    # withheld for the platform on payment
+    application_fee_minor: NotRequired[str]
+    customer_wallet_address: NotRequired[str]
+    customer_email: NotRequired[str]
+    metadata: NotRequired[Dict[str, Any]]
+    # amount_minor defaults to their sum
+    line_items: NotRequired[List["LineItem"]]
+    external_order_id: NotRequired[str]
+
+
+class PrivacyErasureResp(TypedDict):
+    orders_erased: int
+    overpayments_erased: int
+    address_transfers_erased: int
+    # replaces the wallet address on erased orders, overpayments and transfers
+    pseudonym: NotRequired[str]
+    erased_at: str
+
+
+class PrivacyExportResp(TypedDict):
+    subject: "PrivacySubject"
+    # timestamps are in the merchant's timezone
+    timezone: str
+    exported_at: str
+    orders: List["PrivacyOrder"]
+    # Overpayments of those orders, and any other sent from the subject's wallets
+    overpayments: List["OverpaymentRecord"]
+    # Transfers from the subject's wallets to the merchant's watched addresses
+    address_transfers: List["AddressTransfer"]
+
+
+class PrivacyOrder(TypedDict):
+    id: str
+    merchant_id: str
+    amount_minor: str
+    asset: str
+    chain: str
+    status: str
+    tx_hash: NotRequired[str]
+    customer_wallet_address: NotRequired[str]
+    customer_email: NotRequired[str]
+    metadata: NotRequired[Dict[str, Any]]
+    created_at: str
+    paid_at: NotRequired[str]
+    line_items: NotRequired[List["LineItem"]]
+    refunds: NotRequired[List["RefundRecord"]]
+
+
+class PrivacySubject(TypedDict):
+    customer_wallet_address: NotRequired[str]
+    customer_email: NotRequired[str]
+
+
+class Problem(TypedDict):
+    type: str
+    title: str
+    status: int
+    detail: NotRequired[str]
+    code: "ErrorCode"
+    errors: NotRequired[List["FieldError"]]
+
+
+class ProblemCatalogEntry(TypedDict):
+    code: "ErrorCode"
+    type: str
+    title: str
+
+
+class RateHistoryResp(TypedDict):
+    quotes: List["RateResp"]
+
+
+class RateResp(TypedDict):
+    # prices an order in fiat (rate_quote_id) and finds the rate later
+    quote_id: str
+    base: str
+    quote: str
+    # quote per unit of base, as a decimal
+    rate: str
+    updated_at: str
+    provider: str
+    source: NotRequired[str]
+    # when it was served
+    quoted_at: str
+
+
+class RefundJob(TypedDict):
+    id: str
+    merchant_id: str
+    status: str
+    total: int
+    pending: int
+    refunded: int
+    failed: int
+    items: NotRequired[List["RefundJobItem"]]
+    created_at: str
+    updated_at: str
+    completed_at: NotRequired[str]
+
+
+class RefundJobItem(TypedDict):
+    position: int
+    order_id: str
+    # as requested
+    amount_minor: NotRequired[str]
+    # PENDING | REFUNDED | FAILED
+    status: str
+    error: NotRequired[str]
+    refund_id: NotRequired[str]
+    refund_status: NotRequired[str]
+    execution_status: NotRequired[str]
+    refund_tx_hash: NotRequired[str]
+
+
+class RefundRecord(TypedDict):
+    id: str
+    order_id: str
+    amount_minor: str
+    status: str
+    refund_tx_hash: NotRequired[str]
+    requested_by: NotRequired[str]
+    decided_by: NotRequired[str]
+    decided_at: NotRequired[str]
+    # Set for refunds the hot wallet sends to the customer: QUEUED | SENT | EXECUTED | FAILED
+    execution_status: NotRequired[str]
+    execution_error: NotRequired[str]
+    created_at: str
+
+
+RefundReq = Dict[str, Any]
+
+
+class RefundResp(TypedDict):
+    order_id: str
+    refund_id: NotRequired[str]
+    # order status
+    status: str
+    # REQUESTED | COMPLETED | REJECTED
+    refund_status: NotRequired[str]
+    # this refund
+    amount_minor: NotRequired[str]
+    # all completed refunds on the order
+    refunded_total_minor: NotRequired[str]
+    # what is left to refund
+    refundable_minor: NotRequired[str]
+    message: str
+
+
+class ReleaseHoldReq(TypedDict):
+    reason: NotRequired[str]
+
+
+class ReserveAttestation(TypedDict):
+    id: str
+    generated_at: str
+    # Report is the reserveReport exactly as signed: verify signature against these bytes.
+    report: Dict[str, Any]
+    # EIP-191 personal_sign of report
+    signature: str
+    # address that signed, the hot wallet
+    signer: str
+
+
+class RetentionResult(TypedDict):
+    orders_archived: int
+    refunds_archived: int
+    ledger_entries_archived: int
+    # removed from outbox_events
+    outbox_events_pruned: int
+    # of those, copied to outbox_events_archive
+    outbox_events_archived: int
+
+
+class RetryPolicyResp(TypedDict):
+    # verification, webhook or payout
+    type: str
+    # including the first
+    max_attempts: int
+    # before the first retry, doubling after each, e.g. "30s"
+    base_delay: str
+    max_delay: str
+    # up to this fraction of the delay is added at random
+    jitter: float
+    # keep or discard
+    dead_letter: str
+    # default, config or admin
+    source: str
+
+
+class RetryPolicyUpdateReq(TypedDict):
+    max_attempts: NotRequired[int]
+    # Go duration, e.g. "30s"
+    base_delay: NotRequired[str]
+    max_delay: NotRequired[str]
+    jitter: NotRequired[float]
+    dead_letter: NotRequired[Literal["keep", "discard"]]
+
+
+class SchedulerStatus(TypedDict):
+    name: str
+    schedule: str
+    next_run_at: Optional[str]
+    paused: bool
+    running: bool
+    last_run_at: Optional[str]
+    last_duration_ms: int
+    last_rows: int
+    last_error: Optional[str]
+    runs: int
+    failures: int
+    skipped: int
+
+
+class SeriesPoint(TypedDict):
+    # bucket start in the merchant's timezone
+    start: str
+    # a count, an amount in minor units or a ratio, as a decimal
+    value: str
+
+
+class SettlementBatch(TypedDict):
+    batch_id: str
+    merchant_id: str
+    asset: str
+    orders: int
+    # TotalAmountMinor is the net payout: the gross volume of the orders less the application fees
+    # withheld, the refunds and the lost disputes netted.
+    total_amount_minor: str
+    # amounts of the batch's orders
+    gross_amount_minor: NotRequired[str]
+    # application fees withheld
+    fees_minor: NotRequired[str]
+    # completed refunds netted
+    refunds_minor: NotRequired[str]
+    # lost disputes netted
+    disputes_lost_minor: NotRequired[str]
+    # withheld against a negative balance
+    clawback_minor: NotRequired[str]
+    # withheld for balance holds
+    held_minor: NotRequired[str]
+    # withheld funds of a released hold
+    hold_released_minor: NotRequired[str]
+
+
+class SettlementRecord(TypedDict):
+    batch_id: str
+    merchant_id: str
+    asset: str
+    status: str
+    # net payout
+    total_amount_minor: str
+    # amounts of the batch's orders
+    gross_amount_minor: NotRequired[str]
+    # application fees withheld
+    fees_minor: NotRequired[str]
+    # completed refunds netted
+    refunds_minor: NotRequired[str]
+    # lost disputes netted
+    disputes_lost_minor: NotRequired[str]
+    # withheld against a negative balance
+    clawback_minor: NotRequired[str]
+    # withheld for balance holds
+    held_minor: NotRequired[str]
+    # withheld funds of a released hold
+    hold_released_minor: NotRequired[str]
+    payout_tx_hash: NotRequired[str]
+    settled_at: str
+
+
+class SoftDeleteReq(TypedDict):
+    # kept in the audit log
+    reason: NotRequired[str]
+
+
+class TimelineEntry(TypedDict):
+    at: str
+    # created | event | note | payment_attempt
+    kind: str
+    # webhook event type, for kind event
+    event: NotRequired[str]
+    # order | refund | dispute
+    aggregate_type: NotRequired[str]
+    aggregate_id: NotRequired[str]
+    note: NotRequired["OrderNote"]
+    # PaymentAttempt is a reported transaction hash and why it was not taken, for kind
+    # payment_attempt.
+    payment_attempt: NotRequired["PaymentAttempt"]
+
+
+TimeseriesResp = TypedDict(
+    "TimeseriesResp",
+    {
+        "metric": str,
+        "interval": str,
+        "asset": NotRequired[str],
+        "timezone": str,
+        "from": str,
+        "to": str,
+        "points": List["SeriesPoint"],
+    },
+)
+
+
+class WalletBalance(TypedDict):
+    address: str
+    balance_minor: NotRequired[str]
+    error: NotRequired[str]
+
+
+class WalletChallenge(TypedDict):
+    challenge_id: str
+    wallet_address: str
+    nonce: str
+    # sign with personal_sign (EIP-191)
+    message: str
+    # or with eth_signTypedData_v4 (EIP-712)
+    typed_data: Dict[str, Any]
+    issued_at: str
+    expires_at: str
+
+
+class WalletStatus(TypedDict):
+    wallet_address: str
+    wallet_ens_name: NotRequired[str]
+    # the merchant proved control of wallet_address
+    verified: bool
+    verified_at: NotRequired[str]
+    # eip191 or eip712, in a verify response
+    method: NotRequired[str]
+    # ProofRequired is whether payouts wait until the wallet is verified.
+    proof_required: bool
+
+
+class WalletVerifyReq(TypedDict):
+    challenge_id: NotRequired[str]
+    # 65 bytes, 0x-prefixed hex
+    signature: NotRequired[str]
+
+
+class WatchAddressReq(TypedDict):
+    chain: str
+    address: str
+    # e.g. the invoice the address was handed out for
+    label: NotRequired[str]
+
+
+class WatchedAddress(TypedDict):
+    id: str
+    chain: str
+    address: str
+    label: NotRequired[str]
+    created_at: str
+
+
+class WebhookConfig(TypedDict):
+    url: str
+    events: List[str]
+    secret: NotRequired[str]
+
+
+class WebhookConfigReq(TypedDict):
+    # empty string disables delivery
+    url: NotRequired[str]
+    # event types, or ["*"] for all
+    events: NotRequired[List[str]]
+
+
+class WebhookSecretRotateReq(TypedDict):
+    # how long the previous secret keeps signing; defaults to 24, at most 168, 0 retires it at once
+    grace_period_hours: NotRequired[int]
+
+
+class WebhookSecretRotateResp(TypedDict):
+    secret: str
+    version: int
+    previous_version: int
+    previous_secret_expires_at: str
+
+
+class WebhookTestReq(TypedDict):
+    # defaults to order.paid
+    event_type: NotRequired[str]
+
+
+class WebhookTestResp(TypedDict):
+    url: str
+    event_id: str
+    event_type: str
+    success: bool
+    status_code: NotRequired[int]
+    latency_ms: int
+    error: NotRequired[str]
@@ -0,0 +1,80 @@
+"""Verifying webhook deliveries."""
+
+from __future__ import annotations
+
+import hashlib
+import hmac
+import json
+import time
+from typing import Any, Dict, Optional, Union
+
+SIGNATURE_HEADER = "X-OSPay-Signature"
+"""The header carrying the webhook signature: "t=<unix seconds>,v1=<hex>", where v1 is HMAC-SHA256
+over "<t>.<raw body>" keyed with the merchant's webhook secret. During a secret rotation a delivery
+may carry several v1 values; any one of them matching is enough."""
+
+DEFAULT_TOLERANCE = 300
+"""The maximum accepted age of a webhook signature, in seconds."""
+
+
+class WebhookSignatureError(ValueError):
+    """A delivery was refused; reason is missing (or malformed), invalid or expired."""
+
+    _messages = {
+        "missing": "ospay: webhook signature missing or malformed",
+        "invalid": "ospay: webhook signature does not match",
+        "expired": "ospay: webhook timestamp outside tolerance",
+    }
+
+    def __init__(self, reason: str) -> None:
+        self.reason = reason
+        super().__init__(self._messages[reason])
+
+
+def verify_webhook(
+    payload: Union[bytes, str],
+    header: Optional[str],
+    secret: str,
+    tolerance: float = DEFAULT_TOLERANCE,
+) -> None:
+    """Check the SIGNATURE_HEADER value of a delivery against its raw body.
+
+    Raises WebhookSignatureError if it does not hold. Verify before parsing the body, and pass the
+    bytes as received: re-encoded JSON will not match.
+    """
+    if isinstance(payload, str):
+        payload = payload.encode()
+    ts = ""
+    sigs = []
+    for part in (header or "").split(","):
+        key, _, value = part.strip().partition("=")
+        if key == "t":
+            ts = value
+        elif key == "v1":
+            try:
+                sigs.append(bytes.fromhex(value))
+            except ValueError:
+                pass
+    if not ts.isdigit() or not sigs:
+        raise WebhookSignatureError("missing")
+    if abs(time.time() - int(ts)) > tolerance:
+        raise WebhookSignatureError("expired")
+    want = hmac.new(secret.encode(), ts.encode() + b"." + payload, hashlib.sha256).digest()
+    if not any(hmac.compare_digest(sig, want) for sig in sigs):
+        raise WebhookSignatureError("invalid")
+
+
+def construct_event(
+    payload: Union[bytes, str],
+    header: Optional[str],
+    secret: str,
+    tolerance: float = DEFAULT_TOLERANCE,
+) -> Dict[str, Any]:
+    """Verify a delivery like verify_webhook and return its parsed envelope.
+
+    The envelope has id, type, created_at and data, and for events of an aggregate (an order, a
+    refund, ...) aggregate_type, aggregate_id and sequence. GET /v1/events/types describes the
+    data of each type.
+    """
+    verify_webhook(payload, header, secret, tolerance)
+    return json.loads(payload)
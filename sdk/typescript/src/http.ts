@@ -0,0 +1,247 @@
+/** Options of an OSPayClient. */
+export interface ClientOptions {
+  /** Server URL, e.g. "https://pay.example.com". */
+  baseUrl: string;
+  /** Merchant or platform API key, sent as X-API-Key. Calls such as createMerchant need none. */
+  apiKey?: string;
+  /** OAuth access token, sent instead of the API key. */
+  bearerToken?: string;
+  /** Operator key, sent as X-Admin-Key, for the admin calls. */
+  adminKey?: string;
+  /** How many times a network error, 429 or 5xx response is retried (default 3). */
+  maxRetries?: number;
+  /** First backoff delay in milliseconds (default 250); it doubles on each retry. */
+  backoffMs?: number;
+  /** Timeout of each attempt in milliseconds (default 30000). */
+  timeoutMs?: number;
+  /** fetch implementation (default globalThis.fetch). */
+  fetch?: typeof fetch;
+}
+
+/** Options of one call. */
+export interface RequestOptions {
+  /**
+   * Idempotency-Key of a POST. Left out, one is generated per call and reused by its retries, so
+   * the server replays the first outcome instead of acting twice.
+   */
+  idempotencyKey?: string;
+  signal?: AbortSignal;
+}
+
+/** One missing or invalid field of a request. */
+export interface FieldError {
+  field: string;
+  rule: string;
+  message: string;
+}
+
+/**
+ * A non-2xx API response, decoded from its problem+json body. code is the stable error code (see
+ * GET /v1/problems); title and detail are human-readable.
+ */
+export class OSPayError extends Error {
+  readonly status: number;
+  readonly code: string;
+  readonly title: string;
+  readonly detail: string;
+  /** The request fields at fault, for validation errors. */
+  readonly fields: FieldError[];
+  /** The server's Retry-After, in milliseconds, or 0. */
+  readonly retryAfterMs: number;
+
+  constructor(status: number, problem: Partial<Problem>, retryAfterMs = 0) {
+    // OAuth endpoints answer with RFC 6749 error and error_description instead
+    const code = problem.code || problem.error || String(status);
+    const detail = problem.detail || problem.error_description || "";
+    const text = detail || problem.title;
+    super(text ? `ospay: ${status} ${code}: ${text}` : `ospay: ${status} ${code}`);
+    this.name = "OSPayError";
+    this.status = status;
+    this.code = code;
+    this.title = problem.title ?? "";
+    this.detail = detail;
+    this.fields = problem.errors ?? [];
+    this.retryAfterMs = retryAfterMs;
+  }
+}
+
+interface Problem {
+  code: string;
+  title: string;
+  detail: string;
+  errors: FieldError[];
+  error: string;
+  error_description: string;
+}
+
+type QueryValue = string | number | boolean | Record<string, string> | undefined;
+
+interface Call extends RequestOptions {
+  body?: unknown;
+  /** A body field taking the idempotency key, filled with the call's key when left empty. */
+  idempotencyField?: string;
+  form?: Record<string, string | undefined>;
+  query?: { [name: string]: QueryValue };
+}
+
+/** Sends the requests of an OSPayClient: credentials, idempotency keys, retries and errors. */
+export class Transport {
+  private readonly baseUrl: string;
+  private readonly options: ClientOptions;
+  private readonly fetch: typeof fetch;
+
+  constructor(options: ClientOptions) {
+    this.baseUrl = options.baseUrl.replace(/\/+$/, "");
+    this.options = options;
+    this.fetch = options.fetch ?? globalThis.fetch.bind(globalThis);
+  }
+
+  async request<T>(method: string, path: string, call: Call): Promise<T> {
+    const url = new URL(this.baseUrl + path);
+    for (const [name, value] of Object.entries(call.query ?? {})) {
+      if (value === undefined) {
+        continue;
+      }
+      if (typeof value === "object") {
+        // metadata: { plan: "pro" } is sent as metadata.plan=pro
+        for (const [key, v] of Object.entries(value)) {
+          url.searchParams.append(`${name}.${key}`, v);
+        }
+        continue;
+      }
+      url.searchParams.append(name, String(value));
+    }
+
+    const headers: Record<string, string> = {
+      Accept: "application/json, application/problem+json",
+    };
+    let body = call.body;
+    if (method === "POST") {
+      const key = call.idempotencyKey ?? crypto.randomUUID();
+      headers["Idempotency-Key"] = key;
+      const field = call.idempotencyField;
+      if (field && isRecord(body) && !body[field]) {
+        // Bodies with a key field of their own, like order creation, use the same key
+        body = { ...body, [field]: key };
+      }
+    }
+    let payload: string | undefined;
+    if (call.form) {
+      const form = new URLSearchParams();
+      for (const [name, value] of Object.entries(call.form)) {
+        if (value !== undefined) {
+          form.append(name, value);
+        }
+      }
+      payload = form.toString();
+      headers["Content-Type"] = "application/x-www-form-urlencoded";
+    } else if (body !== undefined) {
+      payload = JSON.stringify(body);
+      headers["Content-Type"] = "application/json";
+    }
+    if (this.options.adminKey) {
+      headers["X-Admin-Key"] = this.options.adminKey;
+    }
+    if (this.options.bearerToken) {
+      headers["Authorization"] = `Bearer ${this.options.bearerToken}`;
+    } else if (this.options.apiKey) {
+      headers["X-API-Key"] = this.options.apiKey;
+    }
+
+    const maxRetries = this.options.maxRetries ?? 3;
+    let delay = this.options.backoffMs ?? 250;
+    for (let attempt = 0; ; attempt++) {
+      try {
+        return await this.send<T>(method, url, headers, payload, call.signal);
+      } catch (err) {
+        if (attempt >= maxRetries || !retryable(err) || call.signal?.aborted) {
+          throw err;
+        }
+        let wait = delay + Math.random() * (delay / 2);
+        if (err instanceof OSPayError && err.retryAfterMs > wait) {
+          wait = err.retryAfterMs;
+        }
+        await sleep(wait, call.signal);
+        delay *= 2;
+      }
+    }
+  }
+
+  private async send<T>(
+    method: string,
+    url: URL,
+    headers: Record<string, string>,
+    payload: string | undefined,
+    signal: AbortSignal | undefined,
+  ): Promise<T> {
+    const timeout = AbortSignal.timeout(this.options.timeoutMs ?? 30_000);
+    let res: Response;
+    try {
+      res = await this.fetch(url, {
+        method,
+        headers,
+        body: payload,
+        signal: signal ? AbortSignal.any([signal, timeout]) : timeout,
+      });
+    } catch (err) {
+      if (signal?.aborted) {
+        throw err;
+      }
+      throw new NetworkError(err);
+    }
+    const text = await res.text();
+    if (!res.ok) {
+      let problem: Partial<Problem> = {};
+      try {
+        problem = JSON.parse(text);
+      } catch {
+        // not problem+json, e.g. from a proxy
+      }
+      const retryAfter = Number(res.headers.get("Retry-After"));
+      const retryAfterMs = Number.isFinite(retryAfter) ? retryAfter * 1000 : 0;
+      throw new OSPayError(res.status, problem, retryAfterMs);
+    }
+    if (text.trim() === "") {
+      return undefined as T;
+    }
+    try {
+      return JSON.parse(text) as T;
+    } catch {
+      // Decode only the first JSON value: some endpoints append a metrics object after the body
+      return JSON.parse(text.slice(0, text.indexOf("\n"))) as T;
+    }
+  }
+}
+
+/** A transport failure, which is always safe to retry. */
+export class NetworkError extends Error {
+  constructor(readonly cause: unknown) {
+    super(`ospay: ${cause instanceof Error ? cause.message : String(cause)}`);
+    this.name = "NetworkError";
+  }
+}
+
+function isRecord(v: unknown): v is Record<string, unknown> {
+  return typeof v === "object" && v !== null && !Array.isArray(v);
+}
+
+function retryable(err: unknown): boolean {
+  if (err instanceof NetworkError) {
+    return true;
+  }
+  return err instanceof OSPayError && (err.status === 429 || err.status >= 500);
+}
+
+function sleep(ms: number, signal: AbortSignal | undefined): Promise<void> {
+  return new Promise((resolve, reject) => {
+    const timer = setTimeout(resolve, ms);
+    signal?.addEventListener(
+      "abort",
+      () => {
+        clearTimeout(timer);
+        reject(signal.reason);
+      },
+      { once: true },
+    );
+  });
+}
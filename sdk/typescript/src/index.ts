@@ -0,0 +1,17 @@
+export { OSPayClient } from "./client.js";
+export {
+  NetworkError,
+  OSPayError,
+  type ClientOptions,
+  type FieldError,
+  type RequestOptions,
+} from "./http.js";
+export {
+  constructEvent,
+  DEFAULT_TOLERANCE_SECONDS,
+  SIGNATURE_HEADER,
+  verifyWebhook,
+  WebhookSignatureError,
+  type WebhookEvent,
+} from "./webhook.js";
+export type * from "./types.js";
@@ -0,0 +1,1713 @@
+// Code generated by sdkgen from docs/swagger.json. DO NOT EDIT.
+
+export interface AcceptedAsset {
+  asset: string;
+  chain: string;
+}
+
+export interface AddressTransfer {
+  id: string;
+  watch_id: string;
+  chain: string;
+  asset: string;
+  /** the watched address, which received the transfer */
+  address: string;
+  from_address: string;
+  amount_minor: string;
+  tx_hash: string;
+  log_index: number;
+  block_number: number;
+  /** when the watcher found it */
+  created_at: string;
+}
+
+export interface ApiKeyCreateReq {
+  label?: string;
+  /** space-separated, same vocabulary as OAuth scopes */
+  scope?: string;
+}
+
+export interface ApiKeyCreateResp {
+  id: string;
+  api_key: string;
+  label: string;
+  scope: string;
+}
+
+export interface ApiKeyInfo {
+  id: string;
+  label: string;
+  scope: string;
+  created_at: string;
+  revoked_at?: string;
+}
+
+export interface ApiKeyUsage {
+  /** "primary" for the merchant's own key */
+  id: string;
+  label: string;
+  scope: string;
+  created_at: string;
+  revoked_at?: string;
+  /** null if never used */
+  last_used_at: string | null;
+  last_used_ip: string | null;
+  request_count: number;
+  /** the most recently seen IPs, at most maxKeySources */
+  sources: KeySource[];
+}
+
+export interface AssetBalance {
+  asset: string;
+  chain?: string;
+  /** settled funds (settlement bucket) */
+  available_minor: string;
+  /** paid orders not yet settled (merchant bucket) */
+  pending_minor: string;
+  /** frozen by open disputes or balance holds, or reserved for fiat payouts */
+  held_minor: string;
+  /** overpayments to return to customers (overpayment bucket) */
+  refundable_minor: string;
+}
+
+export interface AssetReconciliation {
+  chain: string;
+  asset: string;
+  /**
+   * BooksMinor is what the custody wallets should hold: the custody buckets less what was sent out
+   * and is not booked yet (payouts SENT or EXECUTED, fiat payouts FUNDED).
+   */
+  books_minor: string;
+  /** net of each custody bucket */
+  buckets: Record<string, string>;
+  paid_out_minor: string;
+  offramp_funded_minor: string;
+  /** sum of the wallets' balances */
+  chain_minor?: string;
+  wallets: WalletBalance[];
+  /**
+   * DriftMinor is chain_minor - books_minor: negative when the wallets hold less than the books.
+   */
+  drift_minor?: string;
+  /** of books_minor; omitted when the books are zero or the drift is vast */
+  drift_bps?: number;
+  /** beyond the tolerance */
+  drifting: boolean;
+  /** a wallet balance could not be read */
+  error?: string;
+}
+
+export interface AuditEntry {
+  id: string;
+  actor: string;
+  merchant_id?: string;
+  order_id?: string;
+  action: string;
+  detail: Record<string, unknown>;
+  created_at: string;
+}
+
+export interface BackupResp {
+  path: string;
+  size_bytes: number;
+  created_at: string;
+}
+
+export interface BalanceHold {
+  id: string;
+  merchant_id: string;
+  asset: string;
+  chain: string;
+  amount_minor: string;
+  reason: string;
+  /** ACTIVE, RELEASED or EXPIRED */
+  status: string;
+  placed_by: string;
+  platform_id?: string;
+  expires_at?: string;
+  /** kept back from settlements while active */
+  withheld_minor: string;
+  created_at: string;
+  released_at?: string;
+  released_by?: string;
+  release_reason?: string;
+  /** settled the withheld part */
+  release_batch_id?: string;
+}
+
+export interface BalancesResp {
+  merchant_id: string;
+  balances: AssetBalance[];
+}
+
+export interface BatchGetOrdersReq {
+  order_ids: string[];
+}
+
+export interface BatchGetOrdersResp {
+  /** in the order of order_ids, each once */
+  orders: OrderGetResp[];
+  /** IDs of no order of the merchant */
+  not_found: string[];
+}
+
+export type BulkRefundReq = Record<string, unknown>;
+
+export interface ChainHealth {
+  chain: string;
+  /** ok | lagging | unreachable */
+  status: string;
+  /** host of the RPC endpoint in use */
+  provider: string;
+  reachable: boolean;
+  /** of fetching the latest block */
+  latency_ms?: number;
+  block_height?: number;
+  block_time?: string;
+  /** how far the latest block trails the clock */
+  lag_seconds?: number;
+  /** seconds between blocks */
+  expected_block_time_seconds: number;
+  /** lag beyond which the chain is lagging */
+  max_lag_seconds: number;
+  error?: string;
+}
+
+export interface ChainHealthResp {
+  ok: boolean;
+  chains: ChainHealth[];
+}
+
+export interface ChainTx {
+  id: string;
+  chain: string;
+  purpose: string;
+  reference_id?: string;
+  from_address: string;
+  nonce: number;
+  tx_hash: string;
+  replaced_hashes?: string[];
+  cancel_tx_hash?: string;
+  /** which of the hashes made it into a block */
+  mined_tx_hash?: string;
+  urgency: string;
+  max_fee_wei: string;
+  priority_fee_wei?: string;
+  bumps: number;
+  status: string;
+  last_error?: string;
+  submitted_at: string;
+  created_at: string;
+  confirmed_at?: string;
+  /** still pending after three times its profile's wait */
+  stuck?: boolean;
+}
+
+export interface ChainTxBumpReq {
+  /** fee profile for the replacement; defaults to the transaction's own */
+  urgency?: string;
+}
+
+export interface ColdSweep {
+  id: string;
+  chain: string;
+  asset: string;
+  amount_minor: string;
+  /** the hot wallet */
+  from_address: string;
+  /** the cold wallet */
+  to_address: string;
+  status: string;
+  /** hot wallet balance that went over the limit */
+  balance_minor: string;
+  limit_minor: string;
+  keep_minor: string;
+  tx_hash?: string;
+  last_error?: string;
+  /** who approved or rejected it */
+  decided_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ConnectedBalance {
+  merchant_id: string;
+  merchant_balance_minor: number;
+  platform_fee_minor: number;
+}
+
+export interface ConnectedMerchant {
+  id: string;
+  name: string;
+  merchant_wallet_address: string;
+  created_at: string;
+}
+
+export interface ConversionRecord {
+  id: string;
+  batch_id: string;
+  merchant_id: string;
+  from_chain: string;
+  from_asset: string;
+  to_chain: string;
+  to_asset: string;
+  amount_in_minor: string;
+  quoted_out_minor?: string;
+  /** the swap reverts below it */
+  min_out_minor?: string;
+  amount_out_minor?: string;
+  max_slippage_bps: number;
+  provider?: string;
+  status: string;
+  /** the swap on from_chain */
+  tx_hash?: string;
+  /** the delivery on to_chain */
+  receive_tx_hash?: string;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Coupon {
+  id: string;
+  code: string;
+  type: string;
+  percent_off?: number;
+  amount_off_minor?: string;
+  asset?: string;
+  max_redemptions?: number;
+  redemptions: number;
+  expires_at?: string;
+  active: boolean;
+  created_at: string;
+}
+
+export interface CouponCreateReq {
+  code: string;
+  type: "percent" | "fixed";
+  /** percent coupons */
+  percent_off?: number;
+  /** fixed coupons */
+  amount_off_minor?: string;
+  /** fixed coupons */
+  asset?: string;
+  /** omitted: unlimited */
+  max_redemptions?: number;
+  /** omitted: never */
+  expires_at?: string;
+}
+
+export interface CouponUpdateReq {
+  max_redemptions?: number;
+  expires_at?: string;
+  active?: boolean;
+}
+
+export interface CustomerDetailResp {
+  id: string;
+  wallet_address: string;
+  payment_count: number;
+  first_paid_at: string;
+  last_paid_at: string;
+  /** paid amount per asset */
+  totals_minor: Record<string, string>;
+  payments: OrderGetResp[];
+}
+
+export interface CustomerListResp {
+  customers: CustomerRecord[];
+  /** pass as cursor to fetch the next page */
+  next_cursor?: string;
+}
+
+export interface CustomerRecord {
+  id: string;
+  wallet_address: string;
+  payment_count: number;
+  first_paid_at: string;
+  last_paid_at: string;
+}
+
+export interface DeadLetterEvent {
+  id: string;
+  type: string;
+  created_at: string;
+  aggregate_type: string;
+  aggregate_id: string;
+  sequence?: number;
+  dead_lettered_at?: string;
+  attempts: number;
+  last_error?: string;
+  replay_of?: string;
+  data: Record<string, unknown>;
+}
+
+export interface DeadLetterListResp {
+  events: DeadLetterEvent[];
+  /** pass as cursor to fetch the next page */
+  next_cursor?: string;
+}
+
+export interface DeadLetterRequeueReq {
+  /** at most 1000 */
+  event_ids?: string[];
+  /** every dead-lettered event, up to 1000 per request */
+  all?: boolean;
+}
+
+export interface DeadLetterRequeueResp {
+  requeued: number;
+}
+
+export type DisputeCreateReq = Record<string, unknown>;
+
+export interface DisputeEvidence {
+  id: string;
+  /** "merchant" or "admin" */
+  author: string;
+  note: string;
+  created_at: string;
+}
+
+export interface DisputeEvidenceReq {
+  note: string;
+}
+
+export interface DisputeRecord {
+  id: string;
+  order_id: string;
+  merchant_id: string;
+  asset: string;
+  amount_minor: string;
+  reason: string;
+  /** OPEN | WON | LOST */
+  status: string;
+  created_at: string;
+  resolved_at?: string;
+  evidence?: DisputeEvidence[];
+}
+
+export interface DisputeResolveReq {
+  /** "won" (merchant keeps the funds) or "lost" (funds go back to the customer) */
+  outcome?: string;
+  note?: string;
+}
+
+export interface DormantKey {
+  merchant_id: string;
+  /** "primary" for the merchant's own key */
+  key_id: string;
+  label: string;
+  created_at: string;
+  /** null if never used */
+  last_used_at: string | null;
+  request_count: number;
+}
+
+export type ErrorCode =
+  | "bad_request"
+  | "internal_error"
+  | "db_error"
+  | "db_not_initialized"
+  | "method_not_allowed"
+  | "invalid_json"
+  | "missing_fields"
+  | "missing_query_param"
+  | "missing_idempotency_key"
+  | "invalid_metadata"
+  | "invalid_amount"
+  | "invalid_limit"
+  | "limit_exceeded"
+  | "api_key_required"
+  | "invalid_api_key"
+  | "invalid_token"
+  | "invalid_platform_key"
+  | "api_key_not_found"
+  | "primary_key_required"
+  | "insufficient_scope"
+  | "invalid_scope"
+  | "admin_disabled"
+  | "invalid_admin_key"
+  | "admin_required"
+  | "invalid_client"
+  | "invalid_redirect_uri"
+  | "merchant_not_found"
+  | "merchant_mismatch"
+  | "merchant_not_connected"
+  | "missing_wallet_address"
+  | "invalid_application_fee"
+  | "order_not_found"
+  | "order_not_paid"
+  | "order_not_refundable"
+  | "order_not_in_review"
+  | "order_not_disputable"
+  | "onchain_verification_failed"
+  | "customer_wallet_unknown"
+  | "already_refunded"
+  | "cannot_refund_settled"
+  | "missing_refund_amount"
+  | "invalid_refund_amount"
+  | "refund_exceeds_order"
+  | "invalid_refund_tx"
+  | "refund_tx_already_used"
+  | "refund_verification_failed"
+  | "refund_not_found"
+  | "refund_not_pending"
+  | "approver_must_differ"
+  | "invalid_decision"
+  | "dispute_not_found"
+  | "dispute_open"
+  | "dispute_closed"
+  | "dispute_already_open"
+  | "invalid_dispute_amount"
+  | "invalid_outcome"
+  | "retention_disabled"
+  | "invalid_cursor"
+  | "invalid_idempotency_key"
+  | "idempotency_key_reused"
+  | "idempotency_in_progress"
+  | "invalid_webhook_url"
+  | "webhook_not_configured"
+  | "invalid_event_type"
+  | "invalid_time_range"
+  | "order_expired"
+  | "order_not_pending"
+  | "extension_limit_exceeded"
+  | "customer_not_found"
+  | "unsupported_chain"
+  | "rpc_unavailable"
+  | "gas_tank_not_configured"
+  | "transaction_not_found"
+  | "transaction_not_pending"
+  | "fee_cap_reached"
+  | "invalid_payout_mode"
+  | "conversion_not_found"
+  | "conversion_not_failed"
+  | "invalid_settlement_target"
+  | "offramp_not_configured"
+  | "kyc_not_approved"
+  | "insufficient_balance"
+  | "invalid_currency"
+  | "offramp_unavailable"
+  | "rate_unavailable"
+  | "stale_rate"
+  | "unsupported_rate_pair"
+  | "invalid_timezone"
+  | "invalid_metric"
+  | "scheduler_not_found"
+  | "coupon_not_found"
+  | "coupon_exists"
+  | "coupon_invalid"
+  | "invalid_line_items"
+  | "hot_wallet_unavailable"
+  | "refund_job_not_found"
+  | "status_override_not_allowed"
+  | "tx_already_used"
+  | "invalid_wallet_address"
+  | "wallet_challenge_not_found"
+  | "wallet_challenge_expired"
+  | "wallet_proof_invalid"
+  | "wallet_proof_unsupported"
+  | "merchant_pending_approval"
+  | "merchant_rejected"
+  | "merchant_already_decided"
+  | "auth_blocked"
+  | "auth_ban_not_found"
+  | "validation_failed"
+  | "request_too_large"
+  | "unsupported_media_type"
+  | "payment_intent_not_found"
+  | "payment_intent_finalized"
+  | "job_not_found"
+  | "job_not_dead"
+  | "retry_policy_not_found"
+  | "invalid_retry_policy"
+  | "payout_not_found"
+  | "payout_not_dead_lettered"
+  | "verification_queue_full"
+  | "reconciliation_not_configured"
+  | "attestation_not_found"
+  | "sweep_not_found"
+  | "sweep_not_pending"
+  | "address_already_watched"
+  | "watch_limit_reached"
+  | "watched_address_not_found"
+  | "invalid_organization_key"
+  | "organization_role_forbidden"
+  | "member_not_found"
+  | "member_already_exists"
+  | "owner_not_removable"
+  | "merchant_not_in_organization"
+  | "merchant_in_organization"
+  | "merchant_header_required"
+  | "invalid_webhook_secret"
+  | "invalid_tag"
+  | "too_many_tags"
+  | "overpayment_not_found"
+  | "overpayment_not_open"
+  | "asset_not_accepted"
+  | "invalid_accepted_assets"
+  | "invalid_rate_quote"
+  | "hold_not_found"
+  | "hold_not_active"
+  | "hold_exceeds_balance"
+  | "ledger_transaction_not_found"
+  | "merchant_deleted"
+  | "merchant_not_deleted"
+  | "order_not_deleted"
+  | "order_has_payment"
+  | "idempotency_response_withheld"
+  | "overpayment_refund_in_flight"
+  | "overpayment_sender_erased"
+  | "not_found";
+
+export interface EventCatalogResp {
+  /** schema of the delivered body */
+  envelope: Record<string, unknown>;
+  types: EventTypeInfo[];
+}
+
+export interface EventReplayReq {
+  order_id?: string;
+  /** RFC 3339, inclusive */
+  from?: string;
+  /** RFC 3339, exclusive; defaults to now */
+  to?: string;
+}
+
+export interface EventReplayResp {
+  replayed: number;
+  order_id?: string;
+  from?: string;
+  to?: string;
+}
+
+export interface EventTypeInfo {
+  type: string;
+  version: number;
+  description: string;
+  schema: Record<string, unknown>;
+}
+
+export interface FiatPayoutRecord {
+  id: string;
+  merchant_id: string;
+  provider: string;
+  /** the partner's reference */
+  provider_payout_id?: string;
+  chain: string;
+  asset: string;
+  amount_minor: string;
+  fiat_currency: string;
+  /** in cents (the currency's minor unit) */
+  fiat_amount_minor?: string;
+  rate?: string;
+  deposit_address?: string;
+  tx_hash?: string;
+  status: string;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface FiatPayoutReq {
+  chain: string;
+  asset: string;
+  amount_minor: string;
+  /** ISO 4217; default USD */
+  fiat_currency?: string;
+}
+
+export interface FiatPricing {
+  /** price in fiat_currency, as a decimal */
+  fiat_amount: string;
+  fiat_currency: string;
+  rate_quote: RateResp;
+}
+
+export interface FieldError {
+  /** JSON path, e.g. "line_items[0].name" */
+  field: string;
+  /** required | type | max | min | oneof | asset | chain | amount | rfc3339 */
+  rule: string;
+  message: string;
+}
+
+export interface GasEstimate {
+  chain: string;
+  native_asset?: string;
+  gas_price_wei?: string;
+  /** EIP-1559 chains only */
+  base_fee_wei?: string;
+  /** EIP-1559 chains only */
+  priority_fee_wei?: string;
+  transfers: number;
+  transfer_gas: number;
+  fee_per_transfer_wei?: string;
+  /** in the native asset, e.g. "0.000195" */
+  fee_per_transfer?: string;
+  individual_fee_wei?: string;
+  batch_gas: number;
+  batch_fee_wei?: string;
+  batch_savings_wei?: string;
+  /** "batch" or "individual" */
+  cheaper?: string;
+  fetched_at?: string;
+  error?: string;
+}
+
+export interface GasTankResp {
+  chains: GasTankStatus[];
+}
+
+export interface GasTankStatus {
+  chain: string;
+  native_asset?: string;
+  wallet_address: string;
+  balance_wei?: string;
+  /** in the native asset */
+  balance?: string;
+  low_water_wei?: string;
+  low: boolean;
+  checked_at: string;
+  /** settlement batches and completed refunds on the chain */
+  payouts_7d: number;
+  fee_per_transfer_wei?: string;
+  daily_burn_wei?: string;
+  /** omitted without recent payouts */
+  runway_days?: number;
+  error?: string;
+}
+
+export interface IpBan {
+  ip: string;
+  /** in the window that led to the ban */
+  failures: number;
+  first_failed_at: string;
+  last_failed_at: string;
+  /** how many times the IP was banned, this one included */
+  bans: number;
+  banned_until: string;
+}
+
+export interface JobRecord {
+  id: string;
+  type: string;
+  /** PENDING, RUNNING, SUCCEEDED or DEAD */
+  status: string;
+  payload: Record<string, unknown>;
+  attempts: number;
+  /** not before; the next retry of a failed job */
+  run_at: string;
+  locked_by: string | null;
+  last_error: string | null;
+  created_at: string;
+  updated_at: string;
+  finished_at: string | null;
+}
+
+export interface KeySource {
+  ip: string;
+  request_count: number;
+  first_used_at: string;
+  last_used_at: string;
+}
+
+export interface KycResp {
+  provider: string;
+  customer_id?: string;
+  bank_account_id?: string;
+  kyc_status: string;
+  checked_at?: string;
+}
+
+export interface LedgerExportLine {
+  id: string;
+  merchant_id: string;
+  order_id: string | null;
+  asset: string;
+  chain: string | null;
+  amount_minor: string;
+  bucket: string;
+  /** credit or debit */
+  direction: string;
+  event_type: string;
+  tx_hash: string | null;
+  /** refund, batch, conversion, ... that produced the entry */
+  reference_id: string | null;
+  /** rate quote of a fiat-priced order, see /rates/history */
+  rate_quote_id: string | null;
+  created_at: string;
+  /** TransactionGroupID is shared by the legs of one double entry, see /ledger/transactions */
+  transaction_group_id: string | null;
+}
+
+export interface LedgerTransaction {
+  transaction_group_id: string;
+  merchant_id: string;
+  event_type: string;
+  created_at: string;
+  entries: LedgerExportLine[];
+}
+
+export interface LineItem {
+  name?: string;
+  quantity?: number;
+  unit_amount_minor?: string;
+  amount_minor?: string;
+}
+
+/** Request to create a new merchant */
+export interface MerchantCreateReq {
+  name: string;
+  /** an address, or an ENS name */
+  merchant_wallet_address: string;
+}
+
+/** Response after creating a merchant */
+export interface MerchantCreateResp {
+  id: string;
+  api_key: string;
+  merchant_wallet_address: string;
+  /** WalletENSName is the ENS name merchant_wallet_address was resolved from, if one was given. */
+  wallet_ens_name?: string;
+  /**
+   * Status is PENDING_APPROVAL when new merchants wait for an administrator; the API key works once
+   * it is ACTIVE.
+   */
+  status: string;
+}
+
+export interface MerchantDecisionReq {
+  /** shown to the merchant; required to reject */
+  reason?: string;
+}
+
+export interface MerchantRecord {
+  id: string;
+  name: string;
+  merchant_wallet_address: string;
+  platform_id?: string;
+  organization_id?: string;
+  /** ACTIVE, PENDING_APPROVAL or REJECTED */
+  status: string;
+  reason?: string;
+  decided_by?: string;
+  decided_at?: string;
+  created_at: string;
+  deleted_at?: string;
+  deleted_by?: string;
+}
+
+export interface MerchantSettings {
+  refund_approval_required?: boolean;
+  /**
+   * LatePaymentReview holds payments for expired orders in LATE_PAYMENT instead of crediting them.
+   */
+  late_payment_review?: boolean;
+  /** Velocity limits; "0" / 0 removes the limit. Only an administrator can change them. */
+  max_order_amount_minor?: string;
+  /** per asset, per day in Timezone */
+  max_daily_volume_minor?: string;
+  max_wallet_orders_per_hour?: number;
+  /**
+   * PayoutMode sends settlements on-chain: "hot_wallet" or "safe" ("" back to ledger only). Only an
+   * administrator can change it or the Safe address.
+   */
+  payout_mode?: string;
+  payout_safe_address?: string;
+  /**
+   * Settlements received in another asset or on another chain are converted before payout ("" keeps
+   * what was received). MaxSlippageBps limits conversion slippage; default 50.
+   */
+  settlement_asset?: string;
+  settlement_chain?: string;
+  max_slippage_bps?: number;
+  /**
+   * The merchant's customer and bank account at the off-ramp partner, for fiat payouts. Only an
+   * administrator can link them; KYCStatus is read only.
+   */
+  offramp_customer_id?: string;
+  offramp_bank_account_id?: string;
+  kyc_status?: string;
+  /**
+   * Timezone (IANA, e.g. "Europe/Berlin") sets where the merchant's days start for daily limits,
+   * reports and settlement; with one set, orders settle by local calendar day. "" is UTC.
+   */
+  timezone?: string;
+  /** AcceptedAssets restricts the asset and chain pairs of new orders; empty accepts any. */
+  accepted_assets?: AcceptedAsset[];
+  /**
+   * ClawbackMaxBps is the most of each settlement withheld while the balance is negative; default
+   * 10000 (all of it), 0 withholds nothing. Only an administrator can change it.
+   */
+  clawback_max_bps?: number;
+}
+
+export interface Mispayment {
+  /** wrong_token | wrong_chain */
+  reason: string;
+  asset: string;
+  chain: string;
+  /** in the smallest unit of the token sent */
+  amount_minor: string;
+  from_address: string;
+  tx_hash: string;
+}
+
+export interface NegativeBalance {
+  id: string;
+  merchant_id: string;
+  asset: string;
+  chain: string;
+  /** OPEN or RECOVERED */
+  status: string;
+  /** what the merchant owes, positive; 0 once recovered */
+  amount_minor: string;
+  /** the most it owed */
+  peak_minor: string;
+  opened_at: string;
+  updated_at: string;
+  recovered_at?: string;
+}
+
+export interface OauthAuthorizeReq {
+  client_id?: string;
+  /** space-separated, e.g. "orders:write balances:read" */
+  scope?: string;
+  redirect_uri?: string;
+  state?: string;
+}
+
+export interface OauthAuthorizeResp {
+  code: string;
+  expires_in: number;
+  /** redirect_uri with code and state appended */
+  redirect_uri?: string;
+}
+
+export interface OauthClientCreateReq {
+  name: string;
+  redirect_uri?: string;
+}
+
+export interface OauthClientCreateResp {
+  client_id: string;
+  client_secret: string;
+  redirect_uri?: string;
+}
+
+export interface OauthTokenResp {
+  access_token: string;
+  token_type: string;
+  expires_in: number;
+  refresh_token: string;
+  scope: string;
+}
+
+export interface OnchainReconResp {
+  checked_at: string;
+  tolerance_bps: number;
+  assets: AssetReconciliation[];
+}
+
+export interface OrderCreateReq {
+  merchant_id?: string;
+  /** String to handle large 18-decimal numbers */
+  amount_minor?: string;
+  /** e.g., "USDC" */
+  asset: string;
+  /** e.g., "POLYGON" */
+  chain: string;
+  idempotency_key?: string;
+  /**
+   * CustomerWalletAddress is optional; when given, per-wallet velocity limits apply at creation.
+   */
+  customer_wallet_address?: string;
+  customer_email?: string;
+  /** free-form JSON object */
+  metadata?: Record<string, unknown>;
+  /** the merchant's own order reference */
+  external_order_id?: string;
+  /** CouponCode is one of the merchant's coupons; its discount is taken off amount_minor. */
+  coupon_code?: string;
+  /**
+   * LineItems are what is being bought; amount_minor may then be omitted and defaults to their sum.
+   */
+  line_items?: LineItem[];
+  /**
+   * WebhookURL receives the order's events instead of the merchant's webhook URL, signed with
+   * WebhookSecret; one is generated when it is omitted.
+   */
+  webhook_url?: string;
+  webhook_secret?: string;
+  /**
+   * RateQuoteID and FiatAmount record a fiat price: the quote_id of the /rates quote between asset
+   * and the fiat currency that amount_minor was converted with, and the price in that currency. The
+   * quote is kept on the order and its ledger entries.
+   */
+  rate_quote_id?: string;
+  fiat_amount?: string;
+}
+
+export interface OrderCreateResp {
+  order_id: string;
+  deposit_address: string;
+  status: string;
+  /** amount due, after any discount */
+  amount_minor: string;
+  /** taken off by coupon_code */
+  discount_minor?: string;
+  /** generated for webhook_url; only in the response that created the order */
+  webhook_secret?: string;
+}
+
+export interface OrderExtendReq {
+  /** added to the later of now and the current expiry; defaults to 30 */
+  minutes?: number;
+}
+
+export interface OrderExtendResp {
+  order_id: string;
+  status: string;
+  expires_at: string;
+}
+
+export interface OrderGetResp {
+  id: string;
+  merchant_id: string;
+  /** String to handle large 18-decimal numbers */
+  amount_minor: string;
+  asset: string;
+  chain: string;
+  status: string;
+  deposit_address: string;
+  tx_hash?: string;
+  /** block that mined the verified payment */
+  confirmed_block?: number;
+  /** and its time */
+  block_timestamp?: string;
+  paid_at?: string;
+  created_at: string;
+  /** PENDING orders become EXPIRED after this */
+  expires_at?: string;
+  /**
+   * CustomerWalletAddress is the sender of the verified payment transfer; refunds go back to it.
+   */
+  customer_wallet_address?: string;
+  customer_email?: string;
+  metadata?: Record<string, unknown>;
+  external_order_id?: string;
+  /**
+   * Risk fields are set when the payment is confirmed; REVIEW orders wait for an admin decision.
+   */
+  risk_reason?: string;
+  risk_score?: number;
+  risk_factors?: string;
+  application_fee_minor?: string;
+  /**
+   * CouponCode and DiscountMinor are set when a coupon was redeemed; amount_minor is net of the
+   * discount.
+   */
+  coupon_code?: string;
+  discount_minor?: string;
+  /** Mispayment is the transfer in the wrong token or on the wrong chain of a MISPAID order. */
+  mispayment?: Mispayment;
+  /** FiatPricing is the fiat price and rate quote of an order created with rate_quote_id. */
+  fiat_pricing?: FiatPricing;
+  line_items?: LineItem[];
+  /** in list, search and batch-get results */
+  tags?: string[];
+}
+
+export interface OrderListResp {
+  orders: OrderGetResp[];
+  /** pass as cursor to fetch the next page */
+  next_cursor?: string;
+}
+
+export interface OrderNote {
+  id: string;
+  order_id: string;
+  /** credential that wrote it, e.g. "key:primary" or "admin" */
+  author: string;
+  body: string;
+  created_at: string;
+}
+
+export interface OrderNoteReq {
+  /** at most maxNoteLength */
+  body: string;
+}
+
+export interface OrderReviewReq {
+  /** "approve" credits the payment, "reject" fails the order */
+  decision?: string;
+}
+
+export interface OrderStatusOverrideReq {
+  status: "PAID" | "FAILED" | "EXPIRED";
+  /** why, e.g. the block explorer link of a payment verified by hand */
+  reason?: string;
+  /** the payment, recorded when forcing PAID */
+  tx_hash?: string;
+}
+
+export interface OrderStatusOverrideResp {
+  order_id: string;
+  override_id: string;
+  previous_status: string;
+  status: string;
+  reason: string;
+  /** written to credit or reverse the payment */
+  ledger_entries: number;
+}
+
+export interface OrderTagsReq {
+  tags: string[];
+}
+
+export interface OrderTagsResp {
+  order_id: string;
+  /** sorted */
+  tags: string[];
+}
+
+export interface OrgAttachReq {
+  /** the merchant's primary API key */
+  api_key: string;
+}
+
+export interface OrgBalancesResp {
+  organization_id: string;
+  /** all merchants together, per asset and chain */
+  totals: AssetBalance[];
+  merchants: OrgMerchantBalances[];
+}
+
+export interface OrgCreateReq {
+  name: string;
+  owner_email: string;
+}
+
+export interface OrgCreateResp {
+  id: string;
+  name: string;
+  /** the owner */
+  member_id: string;
+  /** the owner's; shown once */
+  api_key: string;
+}
+
+export interface OrgMember {
+  id: string;
+  email: string;
+  role: string;
+  created_at: string;
+  /** only when the member is added */
+  api_key?: string;
+}
+
+export interface OrgMemberReq {
+  email: string;
+  role: "admin" | "viewer";
+}
+
+export interface OrgMerchant {
+  id: string;
+  name: string;
+  merchant_wallet_address: string;
+  status: string;
+  created_at: string;
+}
+
+export interface OrgMerchantBalances {
+  merchant_id: string;
+  name: string;
+  balances: AssetBalance[];
+}
+
+export interface OrgReportResp {
+  organization_id: string;
+  from: string;
+  to: string;
+  /** per asset */
+  totals: OrgReportRow[];
+  merchants: OrgReportRow[];
+}
+
+export interface OrgReportRow {
+  /** empty in totals */
+  merchant_id?: string;
+  asset: string;
+  orders_created: number;
+  orders_paid: number;
+  paid_volume_minor: string;
+  /** completed refunds */
+  refunded_minor: string;
+}
+
+export interface OverpaymentRecord {
+  id: string;
+  order_id: string;
+  merchant_id: string;
+  asset: string;
+  chain: string;
+  amount_minor: string;
+  tx_hash: string;
+  /** the refund goes back here */
+  from_address: string;
+  /** CONFIRMING | REVIEW | OPEN | REFUND_QUEUED | REFUND_SENT | REFUNDED | REFUND_FAILED */
+  status: string;
+  confirmed_block?: number;
+  /** why screening flagged the sender */
+  risk_reason?: string;
+  refund_tx_hash?: string;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+  refunded_at?: string;
+}
+
+export interface PaymentAttempt {
+  id: string;
+  tx_hash: string;
+  /** pending | succeeded | failed */
+  status: string;
+  /**
+   * wrong_amount | wrong_token | wrong_chain | wrong_recipient | no_transfer | tx_not_found |
+   * order_expired | already_paid | tx_already_used | multiple_senders | verification_error
+   */
+  failure_reason?: string;
+  detail?: string;
+  /** times the hash was reported */
+  submissions: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PaymentDetectedReq {
+  order_id: string;
+  tx_hash: string;
+  /** optional override; if nil, use order.amount_minor (string for large numbers) */
+  amount_minor?: string;
+}
+
+export interface PaymentDetectedResp {
+  order_id: string;
+  status: string;
+  message: string;
+}
+
+export interface PaymentIntent {
+  id: string;
+  merchant_id: string;
+  amount_minor: string;
+  asset: string;
+  chain: string;
+  /** REQUIRES_CAPTURE, CAPTURED or VOIDED */
+  status: string;
+  metadata?: Record<string, unknown>;
+  external_order_id?: string;
+  coupon_code?: string;
+  /** set by capture */
+  order_id?: string;
+  /** before any coupon discount */
+  captured_amount_minor?: string;
+  void_reason?: string;
+  created_at: string;
+  updated_at: string;
+  captured_at?: string;
+  voided_at?: string;
+}
+
+export interface PaymentIntentCaptureReq {
+  /** AmountMinor captures less than the intent's amount; omitted, all of it is captured. */
+  amount_minor?: string;
+  customer_wallet_address?: string;
+  customer_email?: string;
+}
+
+export interface PaymentIntentCaptureResp {
+  payment_intent: PaymentIntent;
+  order: OrderCreateResp;
+}
+
+export interface PaymentIntentCreateReq {
+  amount_minor: string;
+  asset: string;
+  chain: string;
+  /** copied to the order */
+  metadata?: Record<string, unknown>;
+  /** copied to the order */
+  external_order_id?: string;
+  /** CouponCode is checked now and redeemed when the intent is captured. */
+  coupon_code?: string;
+}
+
+export interface PaymentIntentUpdateReq {
+  amount_minor?: string;
+  metadata?: Record<string, unknown>;
+  external_order_id?: string;
+}
+
+export interface PaymentIntentVoidReq {
+  reason?: string;
+}
+
+export interface PayoutEstimateResp {
+  estimates: GasEstimate[];
+}
+
+export interface PayoutRecord {
+  id: string;
+  batch_id: string;
+  merchant_id: string;
+  chain: string;
+  asset: string;
+  amount_minor: string;
+  to_address: string;
+  mode: string;
+  status: string;
+  safe_address?: string;
+  safe_nonce?: number;
+  safe_tx_hash?: string;
+  tx_hash?: string;
+  last_error?: string;
+  /** Failed tries to send or propose the payout, retried as the payout retry policy says */
+  attempts: number;
+  next_attempt_at?: string;
+  /** out of attempts, held until retried */
+  dead_lettered_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PlaceHoldReq {
+  merchant_id: string;
+  asset: string;
+  chain: string;
+  amount_minor: string;
+  /** e.g. "risk review" */
+  reason: string;
+  /** omitted: held until released */
+  expires_at?: string;
+}
+
+export interface PlatformBalancesResp {
+  platform_id: string;
+  asset: string;
+  platform_fee_total_minor: number;
+  merchants: ConnectedBalance[];
+}
+
+export interface PlatformCreateReq {
+  name: string;
+}
+
+export interface PlatformCreateResp {
+  id: string;
+  api_key: string;
+}
+
+export interface PlatformOrderCreateReq {
+  merchant_id: string;
+  /** String to handle large 18-decimal numbers */
+  amount_minor?: string;
+  asset: string;
+  chain: string;
+  idempotency_key?: string;
+  /** withheld for the platform on payment */
+  application_fee_minor?: string;
+  customer_wallet_address?: string;
+  customer_email?: string;
+  metadata?: Record<string, unknown>;
+  /** amount_minor defaults to their sum */
+  line_items?: LineItem[];
+  external_order_id?: string;
+}
+
+export interface PrivacyErasureResp {
+  orders_erased: number;
+  overpayments_erased: number;
+  address_transfers_erased: number;
+  /** replaces the wallet address on erased orders, overpayments and transfers */
+  pseudonym?: string;
+  erased_at: string;
+}
+
+export interface PrivacyExportResp {
+  subject: PrivacySubject;
+  /** timestamps are in the merchant's timezone */
+  timezone: string;
+  exported_at: string;
+  orders: PrivacyOrder[];
+  /** Overpayments of those orders, and any other sent from the subject's wallets */
+  overpayments: OverpaymentRecord[];
+  /** Transfers from the subject's wallets to the merchant's watched addresses */
+  address_transfers: AddressTransfer[];
+}
+
+export interface PrivacyOrder {
+  id: string;
+  merchant_id: string;
+  amount_minor: string;
+  asset: string;
+  chain: string;
+  status: string;
+  tx_hash?: string;
+  customer_wallet_address?: string;
+  customer_email?: string;
+  metadata?: Record<string, unknown>;
+  created_at: string;
+  paid_at?: string;
+  line_items?: LineItem[];
+  refunds?: RefundRecord[];
+}
+
+export interface PrivacySubject {
+  customer_wallet_address?: string;
+  customer_email?: string;
+}
+
+export interface Problem {
+  type: string;
+  title: string;
+  status: number;
+  detail?: string;
+  code: ErrorCode;
+  errors?: FieldError[];
+}
+
+export interface ProblemCatalogEntry {
+  code: ErrorCode;
+  type: string;
+  title: string;
+}
+
+export interface RateHistoryResp {
+  quotes: RateResp[];
+}
+
+export interface RateResp {
+  /** prices an order in fiat (rate_quote_id) and finds the rate later */
+  quote_id: string;
+  base: string;
+  quote: string;
+  /** quote per unit of base, as a decimal */
+  rate: string;
+  updated_at: string;
+  provider: string;
+  source?: string;
+  /** when it was served */
+  quoted_at: string;
+}
+
+export interface RefundJob {
+  id: string;
+  merchant_id: string;
+  status: string;
+  total: number;
+  pending: number;
+  refunded: number;
+  failed: number;
+  items?: RefundJobItem[];
+  created_at: string;
+  updated_at: string;
+  completed_at?: string;
+}
+
+export interface RefundJobItem {
+  position: number;
+  order_id: string;
+  /** as requested */
+  amount_minor?: string;
+  /** PENDING | REFUNDED | FAILED */
+  status: string;
+  error?: string;
+  refund_id?: string;
+  refund_status?: string;
+  execution_status?: string;
+  refund_tx_hash?: string;
+}
+
+export interface RefundRecord {
+  id: string;
+  order_id: string;
+  amount_minor: string;
+  status: string;
+  refund_tx_hash?: string;
+  requested_by?: string;
+  decided_by?: string;
+  decided_at?: string;
+  /** Set for refunds the hot wallet sends to the customer: QUEUED | SENT | EXECUTED | FAILED */
+  execution_status?: string;
+  execution_error?: string;
+  created_at: string;
+}
+
+export type RefundReq = Record<string, unknown>;
+
+export interface RefundResp {
+  order_id: string;
+  refund_id?: string;
+  /** order status */
+  status: string;
+  /** REQUESTED | COMPLETED | REJECTED */
+  refund_status?: string;
+  /** this refund */
+  amount_minor?: string;
+  /** all completed refunds on the order */
+  refunded_total_minor?: string;
+  /** what is left to refund */
+  refundable_minor?: string;
+  message: string;
+}
+
+export interface ReleaseHoldReq {
+  reason?: string;
+}
+
+export interface ReserveAttestation {
+  id: string;
+  generated_at: string;
+  /** Report is the reserveReport exactly as signed: verify signature against these bytes. */
+  report: Record<string, unknown>;
+  /** EIP-191 personal_sign of report */
+  signature: string;
+  /** address that signed, the hot wallet */
+  signer: string;
+}
+
+export interface RetentionResult {
+  orders_archived: number;
+  refunds_archived: number;
+  ledger_entries_archived: number;
+  /** removed from outbox_events */
+  outbox_events_pruned: number;
+  /** of those, copied to outbox_events_archive */
+  outbox_events_archived: number;
+}
+
+export interface RetryPolicyResp {
+  /** verification, webhook or payout */
+  type: string;
+  /** including the first */
+  max_attempts: number;
+  /** before the first retry, doubling after each, e.g. "30s" */
+  base_delay: string;
+  max_delay: string;
+  /** up to this fraction of the delay is added at random */
+  jitter: number;
+  /** keep or discard */
+  dead_letter: string;
+  /** default, config or admin */
+  source: string;
+}
+
+export interface RetryPolicyUpdateReq {
+  max_attempts?: number;
+  /** Go duration, e.g. "30s" */
+  base_delay?: string;
+  max_delay?: string;
+  jitter?: number;
+  dead_letter?: "keep" | "discard";
+}
+
+export interface SchedulerStatus {
+  name: string;
+  schedule: string;
+  next_run_at: string | null;
+  paused: boolean;
+  running: boolean;
+  last_run_at: string | null;
+  last_duration_ms: number;
+  last_rows: number;
+  last_error: string | null;
+  runs: number;
+  failures: number;
+  skipped: number;
+}
+
+export interface SeriesPoint {
+  /** bucket start in the merchant's timezone */
+  start: string;
+  /** a count, an amount in minor units or a ratio, as a decimal */
+  value: string;
+}
+
+export interface SettlementBatch {
+  batch_id: string;
+  merchant_id: string;
+  asset: string;
+  orders: number;
+  /**
+   * TotalAmountMinor is the net payout: the gross volume of the orders less the application fees
+   * withheld, the refunds and the lost disputes netted.
+   */
+  total_amount_minor: string;
+  /** amounts of the batch's orders */
+  gross_amount_minor?: string;
+  /** application fees withheld */
+  fees_minor?: string;
+  /** completed refunds netted */
+  refunds_minor?: string;
+  /** lost disputes netted */
+  disputes_lost_minor?: string;
+  /** withheld against a negative balance */
+  clawback_minor?: string;
+  /** withheld for balance holds */
+  held_minor?: string;
+  /** withheld funds of a released hold */
+  hold_released_minor?: string;
+}
+
+export interface SettlementRecord {
+  batch_id: string;
+  merchant_id: string;
+  asset: string;
+  status: string;
+  /** net payout */
+  total_amount_minor: string;
+  /** amounts of the batch's orders */
+  gross_amount_minor?: string;
+  /** application fees withheld */
+  fees_minor?: string;
+  /** completed refunds netted */
+  refunds_minor?: string;
+  /** lost disputes netted */
+  disputes_lost_minor?: string;
+  /** withheld against a negative balance */
+  clawback_minor?: string;
+  /** withheld for balance holds */
+  held_minor?: string;
+  /** withheld funds of a released hold */
+  hold_released_minor?: string;
+  payout_tx_hash?: string;
+  settled_at: string;
+}
+
+export interface SoftDeleteReq {
+  /** kept in the audit log */
+  reason?: string;
+}
+
+export interface TimelineEntry {
+  at: string;
+  /** created | event | note | payment_attempt */
+  kind: string;
+  /** webhook event type, for kind event */
+  event?: string;
+  /** order | refund | dispute */
+  aggregate_type?: string;
+  aggregate_id?: string;
+  note?: OrderNote;
+  /**
+   * PaymentAttempt is a reported transaction hash and why it was not taken, for kind
+   * payment_attempt.
+   */
+  payment_attempt?: PaymentAttempt;
+}
+
+export interface TimeseriesResp {
+  metric: string;
+  interval: string;
+  asset?: string;
+  timezone: string;
+  from: string;
+  to: string;
+  points: SeriesPoint[];
+}
+
+export interface WalletBalance {
+  address: string;
+  balance_minor?: string;
+  error?: string;
+}
+
+export interface WalletChallenge {
+  challenge_id: string;
+  wallet_address: string;
+  nonce: string;
+  /** sign with personal_sign (EIP-191) */
+  message: string;
+  /** or with eth_signTypedData_v4 (EIP-712) */
+  typed_data: Record<string, unknown>;
+  issued_at: string;
+  expires_at: string;
+}
+
+export interface WalletStatus {
+  wallet_address: string;
+  wallet_ens_name?: string;
+  /** the merchant proved control of wallet_address */
+  verified: boolean;
+  verified_at?: string;
+  /** eip191 or eip712, in a verify response */
+  method?: string;
+  /** ProofRequired is whether payouts wait until the wallet is verified. */
+  proof_required: boolean;
+}
+
+export interface WalletVerifyReq {
+  challenge_id?: string;
+  /** 65 bytes, 0x-prefixed hex */
+  signature?: string;
+}
+
+export interface WatchAddressReq {
+  chain: string;
+  address: string;
+  /** e.g. the invoice the address was handed out for */
+  label?: string;
+}
+
+export interface WatchedAddress {
+  id: string;
+  chain: string;
+  address: string;
+  label?: string;
+  created_at: string;
+}
+
+export interface WebhookConfig {
+  url: string;
+  events: string[];
+  secret?: string;
+}
+
+export interface WebhookConfigReq {
+  /** empty string disables delivery */
+  url?: string;
+  /** event types, or ["*"] for all */
+  events?: string[];
+}
+
+export interface WebhookSecretRotateReq {
+  /**
+   * how long the previous secret keeps signing; defaults to 24, at most 168, 0 retires it at once
+   */
+  grace_period_hours?: number;
+}
+
+export interface WebhookSecretRotateResp {
+  secret: string;
+  version: number;
+  previous_version: number;
+  previous_secret_expires_at: string;
+}
+
+export interface WebhookTestReq {
+  /** defaults to order.paid */
+  event_type?: string;
+}
+
+export interface WebhookTestResp {
+  url: string;
+  event_id: string;
+  event_type: string;
+  success: boolean;
+  status_code?: number;
+  latency_ms: number;
+  error?: string;
+}
@@ -0,0 +1,88 @@
+import { createHmac, timingSafeEqual } from "node:crypto";
+
+/**
+ * The header carrying the webhook signature: "t=<unix seconds>,v1=<hex>", where v1 is
+ * HMAC-SHA256 over "<t>.<raw body>" keyed with the merchant's webhook secret. During a secret
+ * rotation a delivery may carry several v1 values; any one of them matching is enough.
+ */
+export const SIGNATURE_HEADER = "X-OSPay-Signature";
+
+/** The maximum accepted age of a webhook signature, in seconds. */
+export const DEFAULT_TOLERANCE_SECONDS = 300;
+
+/** The envelope POSTed to the webhook URL; GET /v1/events/types describes each type's data. */
+export interface WebhookEvent<T = Record<string, unknown>> {
+  id: string;
+  type: string;
+  created_at: string;
+  /** order, refund, dispute, ... */
+  aggregate_type?: string;
+  aggregate_id?: string;
+  /** Numbers an aggregate's events from 1; anything at or below one processed is a duplicate. */
+  sequence?: number;
+  /** Set on sample events from POST /v1/webhooks/test. */
+  test?: boolean;
+  /** The original event's id, on re-deliveries from POST /v1/events/replay. */
+  replay_of?: string;
+  data: T;
+}
+
+/** Why a delivery was refused: its signature is missing or malformed, wrong, or too old. */
+export class WebhookSignatureError extends Error {
+  constructor(readonly reason: "missing" | "invalid" | "expired") {
+    super(
+      {
+        missing: "ospay: webhook signature missing or malformed",
+        invalid: "ospay: webhook signature does not match",
+        expired: "ospay: webhook timestamp outside tolerance",
+      }[reason],
+    );
+    this.name = "WebhookSignatureError";
+  }
+}
+
+/**
+ * Checks the SIGNATURE_HEADER value of a delivery against its raw body and throws a
+ * WebhookSignatureError if it does not hold. Verify before parsing the body, and pass the bytes as
+ * received: re-encoded JSON will not match.
+ */
+export function verifyWebhook(
+  payload: string | Uint8Array,
+  header: string | null | undefined,
+  secret: string,
+  toleranceSeconds = DEFAULT_TOLERANCE_SECONDS,
+): void {
+  let ts = "";
+  const sigs: Buffer[] = [];
+  for (const part of (header ?? "").split(",")) {
+    const [key, value] = part.trim().split("=", 2);
+    if (key === "t" && value !== undefined) {
+      ts = value;
+    } else if (key === "v1" && value && /^[0-9a-f]+$/i.test(value)) {
+      sigs.push(Buffer.from(value, "hex"));
+    }
+  }
+  const unix = Number(ts);
+  if (ts === "" || !Number.isInteger(unix) || sigs.length === 0) {
+    throw new WebhookSignatureError("missing");
+  }
+  if (Math.abs(Date.now() / 1000 - unix) > toleranceSeconds) {
+    throw new WebhookSignatureError("expired");
+  }
+  const want = createHmac("sha256", secret).update(`${ts}.`).update(payload).digest();
+  if (!sigs.some((sig) => sig.length === want.length && timingSafeEqual(sig, want))) {
+    throw new WebhookSignatureError("invalid");
+  }
+}
+
+/** Verifies a delivery like verifyWebhook and returns its parsed envelope. */
+export function constructEvent<T = Record<string, unknown>>(
+  payload: string | Uint8Array,
+  header: string | null | undefined,
+  secret: string,
+  toleranceSeconds = DEFAULT_TOLERANCE_SECONDS,
+): WebhookEvent<T> {
+  verifyWebhook(payload, header, secret, toleranceSeconds);
+  const text = typeof payload === "string" ? payload : new TextDecoder().decode(payload);
+  return JSON.parse(text) as WebhookEvent<T>;
+}